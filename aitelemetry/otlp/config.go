@@ -0,0 +1,81 @@
+// Package otlp implements aitelemetry.TelemetryHandle and a zap core that export logs, events and
+// metrics to an OpenTelemetry collector over OTLP instead of Application Insights.
+package otlp
+
+import (
+	"time"
+)
+
+// Protocol is the OTLP transport used to reach the collector.
+type Protocol string
+
+const (
+	// ProtocolGRPC exports over OTLP/gRPC, usually on port 4317.
+	ProtocolGRPC Protocol = "grpc"
+	// ProtocolHTTP exports protobuf encoded payloads over OTLP/HTTP, usually on port 4318.
+	ProtocolHTTP Protocol = "http"
+)
+
+const (
+	defaultGRPCEndpoint       = "localhost:4317"
+	defaultHTTPEndpoint       = "http://localhost:4318"
+	defaultBatchSize          = 512
+	defaultBatchIntervalInSec = 15
+	defaultExportTimeoutInSec = 10
+	maxQueueSizeMultiplier    = 4
+)
+
+// Config is the OTLP exporter configuration.
+type Config struct {
+	// Endpoint of the collector. host:port for gRPC, a base URL such as http://collector:4318 for HTTP.
+	Endpoint string `json:"Endpoint,omitempty"`
+	// Protocol is grpc (default) or http.
+	Protocol Protocol `json:"Protocol,omitempty"`
+	// Insecure disables TLS on the connection to the collector.
+	Insecure bool `json:"Insecure,omitempty"`
+	// Headers are sent with every export request, e.g. for collector authentication.
+	Headers map[string]string `json:"Headers,omitempty"`
+	// BatchSize is the number of records sent in one export request.
+	BatchSize int `json:"BatchSize,omitempty"`
+	// BatchIntervalInSecs is the maximum delay before queued records are exported.
+	BatchIntervalInSecs int `json:"BatchIntervalInSecs,omitempty"`
+	// ExportTimeoutInSecs bounds a single export request.
+	ExportTimeoutInSecs int `json:"ExportTimeoutInSecs,omitempty"`
+	// AppName and AppVersion are reported as the service.name and service.version resource attributes.
+	AppName    string `json:"-"`
+	AppVersion string `json:"-"`
+}
+
+func (c *Config) setDefaults() {
+	if c.Protocol == "" {
+		c.Protocol = ProtocolGRPC
+	}
+
+	if c.Endpoint == "" {
+		if c.Protocol == ProtocolHTTP {
+			c.Endpoint = defaultHTTPEndpoint
+		} else {
+			c.Endpoint = defaultGRPCEndpoint
+		}
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	if c.BatchIntervalInSecs <= 0 {
+		c.BatchIntervalInSecs = defaultBatchIntervalInSec
+	}
+
+	if c.ExportTimeoutInSecs <= 0 {
+		c.ExportTimeoutInSecs = defaultExportTimeoutInSec
+	}
+}
+
+func (c *Config) batchInterval() time.Duration {
+	return time.Duration(c.BatchIntervalInSecs) * time.Second
+}
+
+func (c *Config) exportTimeout() time.Duration {
+	return time.Duration(c.ExportTimeoutInSecs) * time.Second
+}
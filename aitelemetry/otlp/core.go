@@ -0,0 +1,100 @@
+package otlp
+
+import (
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	loggerNameKey = "logger"
+	callerKey     = "code.caller"
+)
+
+var levelToSeverity = map[zapcore.Level]logspb.SeverityNumber{
+	zapcore.DebugLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	zapcore.InfoLevel:   logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	zapcore.WarnLevel:   logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	zapcore.ErrorLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	zapcore.DPanicLevel: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	zapcore.PanicLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	zapcore.FatalLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+var _ zapcore.Core = (*Core)(nil)
+
+// Core implements zapcore.Core for OTLP, the equivalent of zapai.Core. Entries are converted to OTLP log
+// records, with the zap fields as attributes, and exported in batches by the Telemetry they are written to.
+type Core struct {
+	zapcore.LevelEnabler
+	t      *Telemetry
+	fields []zapcore.Field
+}
+
+// NewCore creates a zap core writing to t.
+func NewCore(le zapcore.LevelEnabler, t *Telemetry) *Core {
+	return &Core{
+		LevelEnabler: le,
+		t:            t,
+	}
+}
+
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := &Core{
+		LevelEnabler: c.LevelEnabler,
+		t:            c.t,
+		fields:       make([]zapcore.Field, 0, len(c.fields)+len(fields)),
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+// Check implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range c.fields {
+		c.fields[i].AddTo(enc)
+	}
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	attrs := make([]*commonpb.KeyValue, 0, len(enc.Fields)+2)
+	for k, v := range enc.Fields {
+		attrs = append(attrs, &commonpb.KeyValue{Key: k, Value: anyValue(v)})
+	}
+	if entry.LoggerName != "" {
+		attrs = append(attrs, stringAttr(loggerNameKey, entry.LoggerName))
+	}
+	if entry.Caller.Defined {
+		attrs = append(attrs, stringAttr(callerKey, entry.Caller.TrimmedPath()))
+	}
+
+	c.t.enqueueLog(&logspb.LogRecord{
+		TimeUnixNano:   uint64(entry.Time.UnixNano()),
+		SeverityNumber: levelToSeverity[entry.Level],
+		SeverityText:   entry.Level.CapitalString(),
+		Body:           stringValue(entry.Message),
+		Attributes:     attrs,
+	})
+	return nil
+}
+
+// Sync exports the queued records.
+func (c *Core) Sync() error {
+	c.t.Flush()
+	return nil
+}
@@ -0,0 +1,167 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	logsPath            = "/v1/logs"
+	metricsPath         = "/v1/metrics"
+	tracesPath          = "/v1/traces"
+	contentTypeProtobuf = "application/x-protobuf"
+)
+
+// exporter sends OTLP export requests to a collector.
+type exporter interface {
+	exportLogs(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error
+	exportMetrics(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error
+	exportTraces(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error
+	close() error
+}
+
+func newExporter(cfg *Config) (exporter, error) {
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		return newGRPCExporter(cfg)
+	case ProtocolHTTP:
+		return newHTTPExporter(cfg), nil
+	default:
+		return nil, errors.Errorf("unsupported OTLP protocol %q", cfg.Protocol)
+	}
+}
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	logs    collogspb.LogsServiceClient
+	metrics colmetricspb.MetricsServiceClient
+	traces  coltracepb.TraceServiceClient
+	headers metadata.MD
+}
+
+func newGRPCExporter(cfg *Config) (*grpcExporter, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+
+	// the connection is established lazily, so a collector which is not up yet doesn't fail the caller.
+	conn, err := grpc.Dial(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create grpc connection to %s", cfg.Endpoint)
+	}
+
+	return &grpcExporter{
+		conn:    conn,
+		logs:    collogspb.NewLogsServiceClient(conn),
+		metrics: colmetricspb.NewMetricsServiceClient(conn),
+		traces:  coltracepb.NewTraceServiceClient(conn),
+		headers: metadata.New(cfg.Headers),
+	}, nil
+}
+
+func (e *grpcExporter) exportLogs(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+	_, err := e.logs.Export(metadata.NewOutgoingContext(ctx, e.headers), req)
+	return errors.Wrap(err, "failed to export logs")
+}
+
+func (e *grpcExporter) exportMetrics(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	_, err := e.metrics.Export(metadata.NewOutgoingContext(ctx, e.headers), req)
+	return errors.Wrap(err, "failed to export metrics")
+}
+
+func (e *grpcExporter) exportTraces(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error {
+	_, err := e.traces.Export(metadata.NewOutgoingContext(ctx, e.headers), req)
+	return errors.Wrap(err, "failed to export traces")
+}
+
+func (e *grpcExporter) close() error {
+	return errors.Wrap(e.conn.Close(), "failed to close grpc connection")
+}
+
+type httpExporter struct {
+	client     *http.Client
+	logsURL    string
+	metricsURL string
+	tracesURL  string
+	headers    map[string]string
+}
+
+func newHTTPExporter(cfg *Config) *httpExporter {
+	base := strings.TrimSuffix(cfg.Endpoint, "/")
+	if !strings.Contains(base, "://") {
+		if cfg.Insecure {
+			base = "http://" + base
+		} else {
+			base = "https://" + base
+		}
+	}
+
+	return &httpExporter{
+		client:     &http.Client{},
+		logsURL:    base + logsPath,
+		metricsURL: base + metricsPath,
+		tracesURL:  base + tracesPath,
+		headers:    cfg.Headers,
+	}
+}
+
+func (e *httpExporter) exportLogs(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+	return e.post(ctx, e.logsURL, req)
+}
+
+func (e *httpExporter) exportMetrics(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	return e.post(ctx, e.metricsURL, req)
+}
+
+func (e *httpExporter) exportTraces(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error {
+	return e.post(ctx, e.tracesURL, req)
+}
+
+func (e *httpExporter) post(ctx context.Context, url string, msg proto.Message) error {
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal export request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set("Content-Type", contentTypeProtobuf)
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to post to %s", url)
+	}
+	defer res.Body.Close()
+	// drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("collector %s responded with http status %d", url, res.StatusCode)
+	}
+	return nil
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,336 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Attribute keys, following the OpenTelemetry semantic conventions where one exists.
+const (
+	serviceNameKey    = "service.name"
+	serviceVersionKey = "service.version"
+	hostNameKey       = "host.name"
+	osTypeKey         = "os.type"
+	eventNameKey      = "event.name"
+	resourceIDKey     = "resource.id"
+	contextKey        = "context"
+	appVersionKey     = "app.version"
+	scopeName         = "github.com/Azure/azure-container-networking/aitelemetry/otlp"
+	defaultCloseInSec = 10
+)
+
+var _ aitelemetry.TelemetryHandle = (*Telemetry)(nil)
+
+// Telemetry is an aitelemetry.TelemetryHandle exporting to an OpenTelemetry collector. Traces and events
+// are sent as OTLP log records, metrics as gauges. Records are queued and exported in batches.
+type Telemetry struct {
+	cfg      Config
+	exporter exporter
+	resource *resourcepb.Resource
+	scope    *commonpb.InstrumentationScope
+	maxQueue int
+
+	mu      sync.Mutex
+	logs    []*logspb.LogRecord
+	metrics []*metricspb.Metric
+	dropped uint64
+
+	batchFull chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a Telemetry exporting to the collector in cfg and starts its batching loop.
+func New(cfg Config) (*Telemetry, error) {
+	cfg.setDefaults()
+
+	exp, err := newExporter(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTelemetry(cfg, exp), nil
+}
+
+func newTelemetry(cfg Config, exp exporter) *Telemetry {
+	t := &Telemetry{
+		cfg:       cfg,
+		exporter:  exp,
+		resource:  newResource(&cfg),
+		scope:     &commonpb.InstrumentationScope{Name: scopeName},
+		maxQueue:  cfg.BatchSize * maxQueueSizeMultiplier,
+		batchFull: make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go t.run()
+	return t
+}
+
+// TrackLog queues the report as a log record with warning severity, like the Application Insights handle.
+func (t *Telemetry) TrackLog(report aitelemetry.Report) {
+	attrs := mapAttrs(report.CustomDimensions)
+	attrs = append(attrs, stringAttr(contextKey, report.Context))
+	if report.AppVersion != "" {
+		attrs = append(attrs, stringAttr(appVersionKey, report.AppVersion))
+	}
+
+	t.enqueueLog(&logspb.LogRecord{
+		TimeUnixNano:   uint64(time.Now().UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		SeverityText:   "WARN",
+		Body:           stringValue(report.Message),
+		Attributes:     attrs,
+	})
+}
+
+// TrackEvent queues the event as a log record carrying the event.name attribute.
+func (t *Telemetry) TrackEvent(event aitelemetry.Event) {
+	attrs := mapAttrs(event.Properties)
+	attrs = append(attrs, stringAttr(eventNameKey, event.EventName), stringAttr(resourceIDKey, event.ResourceID))
+
+	t.enqueueLog(&logspb.LogRecord{
+		TimeUnixNano:   uint64(time.Now().UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:   "INFO",
+		Body:           stringValue(event.EventName),
+		Attributes:     attrs,
+	})
+}
+
+// TrackMetric queues the metric as a gauge with a single data point.
+func (t *Telemetry) TrackMetric(metric aitelemetry.Metric) {
+	attrs := mapAttrs(metric.CustomDimensions)
+	if metric.AppVersion != "" {
+		attrs = append(attrs, stringAttr(appVersionKey, metric.AppVersion))
+	}
+
+	t.enqueueMetric(&metricspb.Metric{
+		Name: metric.Name,
+		Data: &metricspb.Metric_Gauge{
+			Gauge: &metricspb.Gauge{
+				DataPoints: []*metricspb.NumberDataPoint{
+					{
+						Attributes:   attrs,
+						TimeUnixNano: uint64(time.Now().UnixNano()),
+						Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: metric.Value},
+					},
+				},
+			},
+		},
+	})
+}
+
+// Flush exports the queued records.
+func (t *Telemetry) Flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), t.cfg.exportTimeout())
+	defer cancel()
+
+	if err := t.export(ctx); err != nil {
+		log.Errorf("[otlp] %v", err)
+	}
+}
+
+// Close stops the batching loop, exports the queued records within timeout seconds and closes the
+// connection to the collector.
+func (t *Telemetry) Close(timeout int) {
+	if timeout <= 0 {
+		timeout = defaultCloseInSec
+	}
+
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		<-t.done
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+		defer cancel()
+		if err := t.export(ctx); err != nil {
+			log.Errorf("[otlp] %v", err)
+		}
+
+		if err := t.exporter.close(); err != nil {
+			log.Errorf("[otlp] %v", err)
+		}
+	})
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (t *Telemetry) Dropped() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.dropped
+}
+
+func (t *Telemetry) enqueueLog(rec *logspb.LogRecord) {
+	t.mu.Lock()
+	if len(t.logs)+len(t.metrics) >= t.maxQueue {
+		t.dropped++
+		t.mu.Unlock()
+		return
+	}
+	t.logs = append(t.logs, rec)
+	full := len(t.logs)+len(t.metrics) >= t.cfg.BatchSize
+	t.mu.Unlock()
+
+	if full {
+		t.signalBatchFull()
+	}
+}
+
+func (t *Telemetry) enqueueMetric(m *metricspb.Metric) {
+	t.mu.Lock()
+	if len(t.logs)+len(t.metrics) >= t.maxQueue {
+		t.dropped++
+		t.mu.Unlock()
+		return
+	}
+	t.metrics = append(t.metrics, m)
+	full := len(t.logs)+len(t.metrics) >= t.cfg.BatchSize
+	t.mu.Unlock()
+
+	if full {
+		t.signalBatchFull()
+	}
+}
+
+func (t *Telemetry) signalBatchFull() {
+	select {
+	case t.batchFull <- struct{}{}:
+	default:
+	}
+}
+
+func (t *Telemetry) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.cfg.batchInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		case <-t.batchFull:
+		}
+		t.Flush()
+	}
+}
+
+// export sends the queued records to the collector. Records which fail to export are dropped
+// rather than requeued, so a collector outage can't grow the queue without bound.
+func (t *Telemetry) export(ctx context.Context) error {
+	t.mu.Lock()
+	logs, metrics := t.logs, t.metrics
+	t.logs, t.metrics = nil, nil
+	t.mu.Unlock()
+
+	var errs []error
+	for len(logs) > 0 {
+		n := min(len(logs), t.cfg.BatchSize)
+		req := &collogspb.ExportLogsServiceRequest{
+			ResourceLogs: []*logspb.ResourceLogs{
+				{
+					Resource:  t.resource,
+					ScopeLogs: []*logspb.ScopeLogs{{Scope: t.scope, LogRecords: logs[:n]}},
+				},
+			},
+		}
+		if err := t.exporter.exportLogs(ctx, req); err != nil {
+			errs = append(errs, err)
+		}
+		logs = logs[n:]
+	}
+
+	for len(metrics) > 0 {
+		n := min(len(metrics), t.cfg.BatchSize)
+		req := &colmetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{
+				{
+					Resource:     t.resource,
+					ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: t.scope, Metrics: metrics[:n]}},
+				},
+			},
+		}
+		if err := t.exporter.exportMetrics(ctx, req); err != nil {
+			errs = append(errs, err)
+		}
+		metrics = metrics[n:]
+	}
+
+	if len(errs) > 0 {
+		return errors.Errorf("failed to export %d batches, last error: %v", len(errs), errs[len(errs)-1])
+	}
+	return nil
+}
+
+// newResource describes the process sending the telemetry.
+func newResource(cfg *Config) *resourcepb.Resource {
+	hostName, _ := os.Hostname()
+	return &resourcepb.Resource{
+		Attributes: []*commonpb.KeyValue{
+			stringAttr(serviceNameKey, cfg.AppName),
+			stringAttr(serviceVersionKey, cfg.AppVersion),
+			stringAttr(hostNameKey, hostName),
+			stringAttr(osTypeKey, runtime.GOOS),
+		},
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func stringAttr(key, val string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: stringValue(val)}
+}
+
+func mapAttrs(m map[string]string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(m))
+	for k, v := range m {
+		attrs = append(attrs, stringAttr(k, v))
+	}
+	return attrs
+}
+
+// anyValue converts a field value collected by a zapcore.MapObjectEncoder to an OTLP value.
+func anyValue(v interface{}) *commonpb.AnyValue {
+	switch val := v.(type) {
+	case string:
+		return stringValue(val)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: val}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: val}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(val)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: val}}
+	default:
+		return stringValue(fmt.Sprint(val))
+	}
+}
@@ -0,0 +1,340 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/Azure/azure-container-networking/tracing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// receiver is an in-process OTLP collector recording what it is sent.
+type receiver struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu      sync.Mutex
+	logs    []*logspb.LogRecord
+	metrics []*metricspb.Metric
+	spans   []*tracepb.Span
+	headers []string
+}
+
+func (r *receiver) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.addLogs(req, md.Get("x-test-header"))
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (r *receiver) addLogs(req *collogspb.ExportLogsServiceRequest, headers []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headers = append(r.headers, headers...)
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			r.logs = append(r.logs, sl.LogRecords...)
+		}
+	}
+}
+
+func (r *receiver) addMetrics(req *colmetricspb.ExportMetricsServiceRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			r.metrics = append(r.metrics, sm.Metrics...)
+		}
+	}
+}
+
+func (r *receiver) addSpans(req *coltracepb.ExportTraceServiceRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			r.spans = append(r.spans, ss.Spans...)
+		}
+	}
+}
+
+func (r *receiver) snapshotSpans() []*tracepb.Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.spans
+}
+
+func (r *receiver) snapshot() (logs []*logspb.LogRecord, metrics []*metricspb.Metric, headers []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.logs, r.metrics, r.headers
+}
+
+// metricsReceiver adapts receiver to the metrics service, whose Export method has a different signature.
+type metricsReceiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	r *receiver
+}
+
+func (m metricsReceiver) Export(_ context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	m.r.addMetrics(req)
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// traceReceiver adapts receiver to the trace service.
+type traceReceiver struct {
+	coltracepb.UnimplementedTraceServiceServer
+	r *receiver
+}
+
+func (tr traceReceiver) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	tr.r.addSpans(req)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func startGRPCReceiver(t *testing.T) (*receiver, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	r := &receiver{}
+	srv := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(srv, r)
+	colmetricspb.RegisterMetricsServiceServer(srv, metricsReceiver{r: r})
+	coltracepb.RegisterTraceServiceServer(srv, traceReceiver{r: r})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return r, lis.Addr().String()
+}
+
+func startHTTPReceiver(t *testing.T) (*receiver, string) {
+	r := &receiver{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(logsPath, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var msg collogspb.ExportLogsServiceRequest
+		if req.Header.Get("Content-Type") != contentTypeProtobuf || proto.Unmarshal(body, &msg) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.addLogs(&msg, req.Header.Values("X-Test-Header"))
+	})
+	mux.HandleFunc(metricsPath, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var msg colmetricspb.ExportMetricsServiceRequest
+		if proto.Unmarshal(body, &msg) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.addMetrics(&msg)
+	})
+	mux.HandleFunc(tracesPath, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var msg coltracepb.ExportTraceServiceRequest
+		if proto.Unmarshal(body, &msg) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.addSpans(&msg)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return r, srv.URL
+}
+
+func attr(attrs []*commonpb.KeyValue, key string) string {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestTelemetryExport(t *testing.T) {
+	grpcReceiver, grpcEndpoint := startGRPCReceiver(t)
+	httpReceiver, httpEndpoint := startHTTPReceiver(t)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		receiver *receiver
+	}{
+		{
+			name:     "grpc",
+			cfg:      Config{Endpoint: grpcEndpoint, Protocol: ProtocolGRPC, Insecure: true},
+			receiver: grpcReceiver,
+		},
+		{
+			name:     "http",
+			cfg:      Config{Endpoint: httpEndpoint, Protocol: ProtocolHTTP},
+			receiver: httpReceiver,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.AppName = "azure-cns"
+			tt.cfg.Headers = map[string]string{"x-test-header": "secret"}
+			th, err := New(tt.cfg)
+			require.NoError(t, err)
+
+			th.TrackLog(aitelemetry.Report{Message: "hello", Context: "ctx", CustomDimensions: map[string]string{"k": "v"}})
+			th.TrackEvent(aitelemetry.Event{EventName: "NCUpdate", ResourceID: "nc1"})
+			th.TrackMetric(aitelemetry.Metric{Name: "HeartBeat", Value: 1})
+			th.Close(5)
+
+			logs, metrics, headers := tt.receiver.snapshot()
+			require.Len(t, logs, 2)
+			assert.Equal(t, "hello", logs[0].Body.GetStringValue())
+			assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, logs[0].SeverityNumber)
+			assert.Equal(t, "v", attr(logs[0].Attributes, "k"))
+			assert.Equal(t, "ctx", attr(logs[0].Attributes, contextKey))
+			assert.Equal(t, "NCUpdate", attr(logs[1].Attributes, eventNameKey))
+			assert.Equal(t, "nc1", attr(logs[1].Attributes, resourceIDKey))
+
+			require.Len(t, metrics, 1)
+			assert.Equal(t, "HeartBeat", metrics[0].Name)
+			assert.Equal(t, 1.0, metrics[0].GetGauge().DataPoints[0].GetAsDouble())
+
+			assert.Contains(t, headers, "secret")
+		})
+	}
+}
+
+func TestTraceExporter(t *testing.T) {
+	grpcReceiver, grpcEndpoint := startGRPCReceiver(t)
+	httpReceiver, httpEndpoint := startHTTPReceiver(t)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		receiver *receiver
+	}{
+		{
+			name:     "grpc",
+			cfg:      Config{Endpoint: grpcEndpoint, Protocol: ProtocolGRPC, Insecure: true},
+			receiver: grpcReceiver,
+		},
+		{
+			name:     "http",
+			cfg:      Config{Endpoint: httpEndpoint, Protocol: ProtocolHTTP},
+			receiver: httpReceiver,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			exp, err := NewTraceExporter(tt.cfg)
+			require.NoError(t, err)
+
+			tracing.Init(exp)
+			ctx, root := tracing.StartSpan(context.Background(), "root", tracing.WithKind(tracing.SpanKindServer))
+			_, child := tracing.StartSpan(ctx, "child")
+			child.SetAttribute("ip", "10.0.0.4")
+			child.End(errors.New("failed"))
+			root.End(nil)
+			require.NoError(t, tracing.Shutdown(context.Background()))
+
+			spans := tt.receiver.snapshotSpans()
+			require.Len(t, spans, 2)
+			assert.Equal(t, "child", spans[0].Name)
+			assert.Equal(t, spans[1].SpanId, spans[0].ParentSpanId)
+			assert.Equal(t, spans[1].TraceId, spans[0].TraceId)
+			assert.Equal(t, "10.0.0.4", attr(spans[0].Attributes, "ip"))
+			assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, spans[0].Status.Code)
+			assert.Equal(t, "failed", spans[0].Status.Message)
+			assert.Equal(t, tracepb.Span_SPAN_KIND_SERVER, spans[1].Kind)
+			assert.Empty(t, spans[1].ParentSpanId)
+		})
+	}
+}
+
+func TestTelemetryExportsFullBatch(t *testing.T) {
+	r, endpoint := startGRPCReceiver(t)
+	// a long interval, so only a full batch triggers the export.
+	th, err := New(Config{Endpoint: endpoint, Insecure: true, BatchSize: 2, BatchIntervalInSecs: 3600})
+	require.NoError(t, err)
+	defer th.Close(1)
+
+	th.TrackLog(aitelemetry.Report{Message: "one"})
+	th.TrackLog(aitelemetry.Report{Message: "two"})
+
+	assert.Eventually(t, func() bool {
+		logs, _, _ := r.snapshot()
+		return len(logs) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+type nopExporter struct{}
+
+func (nopExporter) exportLogs(context.Context, *collogspb.ExportLogsServiceRequest) error {
+	return nil
+}
+
+func (nopExporter) exportMetrics(context.Context, *colmetricspb.ExportMetricsServiceRequest) error {
+	return nil
+}
+
+func (nopExporter) exportTraces(context.Context, *coltracepb.ExportTraceServiceRequest) error {
+	return nil
+}
+
+func (nopExporter) close() error { return nil }
+
+func TestTelemetryDropsWhenQueueFull(t *testing.T) {
+	cfg := Config{BatchSize: 1, BatchIntervalInSecs: 3600}
+	cfg.setDefaults()
+	th := &Telemetry{cfg: cfg, exporter: nopExporter{}, maxQueue: 2, batchFull: make(chan struct{}, 1)}
+
+	for i := 0; i < 5; i++ {
+		th.TrackMetric(aitelemetry.Metric{Name: "m"})
+	}
+	assert.Equal(t, uint64(3), th.Dropped())
+}
+
+func TestCore(t *testing.T) {
+	r, endpoint := startGRPCReceiver(t)
+	th, err := New(Config{Endpoint: endpoint, Insecure: true})
+	require.NoError(t, err)
+
+	logger := zap.New(NewCore(zapcore.InfoLevel, th)).With(zap.String("component", "test"))
+	logger.Debug("dropped")
+	logger.Error("failed", zap.Int("code", 7), zap.Bool("retry", true))
+	require.NoError(t, logger.Sync())
+	th.Close(5)
+
+	logs, _, _ := r.snapshot()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "failed", logs[0].Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, logs[0].SeverityNumber)
+	assert.Equal(t, "test", attr(logs[0].Attributes, "component"))
+	for _, a := range logs[0].Attributes {
+		switch a.Key {
+		case "code":
+			assert.Equal(t, int64(7), a.Value.GetIntValue())
+		case "retry":
+			assert.True(t, a.Value.GetBoolValue())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package otlp
+
+import (
+	"context"
+
+	"github.com/Azure/azure-container-networking/tracing"
+	"github.com/pkg/errors"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+var _ tracing.Exporter = (*TraceExporter)(nil)
+
+var spanKinds = map[tracing.SpanKind]tracepb.Span_SpanKind{
+	tracing.SpanKindInternal: tracepb.Span_SPAN_KIND_INTERNAL,
+	tracing.SpanKindServer:   tracepb.Span_SPAN_KIND_SERVER,
+	tracing.SpanKindClient:   tracepb.Span_SPAN_KIND_CLIENT,
+}
+
+// TraceExporter is a tracing.Exporter sending spans to an OpenTelemetry collector. Batching is left to
+// the tracing package, so each call is exported as a single request.
+type TraceExporter struct {
+	cfg      Config
+	exporter exporter
+	resource *resourcepb.Resource
+	scope    *commonpb.InstrumentationScope
+}
+
+// NewTraceExporter creates a TraceExporter for the collector in cfg. The batch settings in cfg are ignored.
+func NewTraceExporter(cfg Config) (*TraceExporter, error) {
+	cfg.setDefaults()
+
+	exp, err := newExporter(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TraceExporter{
+		cfg:      cfg,
+		exporter: exp,
+		resource: newResource(&cfg),
+		scope:    &commonpb.InstrumentationScope{Name: scopeName},
+	}, nil
+}
+
+func (e *TraceExporter) ExportSpans(ctx context.Context, spans []tracing.SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	pbSpans := make([]*tracepb.Span, 0, len(spans))
+	for i := range spans {
+		pbSpans = append(pbSpans, toProtoSpan(&spans[i]))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.exportTimeout())
+	defer cancel()
+
+	return e.exporter.exportTraces(ctx, &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{
+			{
+				Resource:   e.resource,
+				ScopeSpans: []*tracepb.ScopeSpans{{Scope: e.scope, Spans: pbSpans}},
+			},
+		},
+	})
+}
+
+func (e *TraceExporter) Shutdown(context.Context) error {
+	return errors.Wrap(e.exporter.close(), "failed to close trace exporter")
+}
+
+func toProtoSpan(s *tracing.SpanData) *tracepb.Span {
+	span := &tracepb.Span{
+		TraceId:           s.SpanContext.TraceID[:],
+		SpanId:            s.SpanContext.SpanID[:],
+		Name:              s.Name,
+		Kind:              spanKinds[s.Kind],
+		StartTimeUnixNano: uint64(s.Start.UnixNano()),
+		EndTimeUnixNano:   uint64(s.End.UnixNano()),
+		Attributes:        mapAttrs(s.Attributes),
+		Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+	}
+	if s.ParentSpanID.IsValid() {
+		span.ParentSpanId = s.ParentSpanID[:]
+	}
+	if s.Err != "" {
+		span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: s.Err}
+	}
+	return span
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// allocations records the containers azure-ipam requested an IP from CNS for, by network, so GC only releases
+// the IPs of the network it is called for. Like the host-local plugin, it keeps one file per container in a
+// directory per network.
+type allocations struct {
+	dir string
+}
+
+var errInvalidAllocation = errors.New("invalid network name or container ID")
+
+// checkName checks that a network name or container ID is a single path element.
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return errors.Wrapf(errInvalidAllocation, "%q", name)
+	}
+	return nil
+}
+
+func (a allocations) path(network, containerID string) (string, error) {
+	if err := checkName(network); err != nil {
+		return "", err
+	}
+	if err := checkName(containerID); err != nil {
+		return "", err
+	}
+	return filepath.Join(a.dir, network, containerID), nil
+}
+
+// add records an IP requested for a container of a network.
+func (a allocations) add(network, containerID string) error {
+	path, err := a.path(network, containerID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil { //nolint:gomnd // owner only
+		return errors.Wrapf(err, "failed to create allocations directory of network %s", network)
+	}
+	return errors.Wrapf(os.WriteFile(path, nil, 0o600), "failed to record allocation of container %s", containerID) //nolint:gomnd // owner only
+}
+
+// remove removes the record of the IP of a container of a network, if there is one.
+func (a allocations) remove(network, containerID string) error {
+	path, err := a.path(network, containerID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove allocation of container %s", containerID)
+	}
+	return nil
+}
+
+// list returns the containers of a network which IPs were requested for.
+func (a allocations) list(network string) ([]string, error) {
+	if err := checkName(network); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(a.dir, network))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list allocations of network %s", network)
+	}
+	containerIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			containerIDs = append(containerIDs, entry.Name())
+		}
+	}
+	return containerIDs, nil
+}
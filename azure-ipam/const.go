@@ -8,6 +8,13 @@ const (
 	pluginName    = "azure-ipam"
 	cnsBaseURL    = "" // fallback to default http://localhost:10090
 	cnsReqTimeout = 15 * time.Second
+	cmdGC         = "GC"
+	cmdStatus     = "STATUS"
+	// allocationsDir holds the records of the containers IPs were requested for, by network
+	allocationsDir = "/var/lib/azure-ipam/networks"
+	// specVersionGCStatus is the version of the CNI spec adding GC and STATUS. Its results are 1.0.0 results,
+	// which the CNI library in use only knows by 1.0.0.
+	specVersionGCStatus = "1.1.0"
 )
 
 // plugin specific error codes
@@ -17,3 +24,6 @@ const (
 	ErrRequestIPConfigFromCNS
 	ErrProcessIPConfigResponse
 )
+
+// ErrPluginNotAvailable is the STATUS error code of a plugin which cannot serve ADD commands
+const ErrPluginNotAvailable uint = 50
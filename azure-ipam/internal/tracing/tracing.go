@@ -0,0 +1,139 @@
+// Package tracing creates the trace context of an azure-ipam invocation and propagates it to CNS in W3C
+// traceparent headers, so the CNS spans of a request join the trace of the invocation. It follows the
+// tracing package of the main module, which azure-ipam can't use until its pin of the module is bumped.
+// Finished spans are written to the azure-ipam log.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the span context between processes.
+const TraceparentHeader = "traceparent"
+
+const (
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// SpanContext is the part of a span propagated to children and to other processes.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats sc as a sampled version 00 traceparent value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flagSampled)
+}
+
+// ParseTraceparent parses a version 00 traceparent value.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 4 || parts[0] != traceparentVersion {
+		return SpanContext{}, fmt.Errorf("unsupported traceparent %q", s)
+	}
+	var sc SpanContext
+	if err := decodeHex(sc.TraceID[:], parts[1]); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace id in traceparent %q: %w", s, err)
+	}
+	if err := decodeHex(sc.SpanID[:], parts[2]); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid span id in traceparent %q: %w", s, err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("traceparent %q has an all-zero id", s)
+	}
+	return sc, nil
+}
+
+// decodeHex decodes s into dst, which it must fill exactly. Only lowercase hex is valid in a traceparent.
+func decodeHex(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return fmt.Errorf("expected %d lowercase hex characters, got %q", hex.EncodedLen(len(dst)), s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err //nolint:wrapcheck // wrapped by the caller
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc as the parent of spans started from it.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context in ctx.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// Span is an operation being traced.
+type Span struct {
+	logger *zap.Logger
+	name   string
+	sc     SpanContext
+	parent [8]byte
+	start  time.Time
+}
+
+// StartSpan starts a span as a child of the span context in ctx, or as the root of a new trace, and
+// returns a context carrying it.
+func StartSpan(ctx context.Context, logger *zap.Logger, name string) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{logger: logger, name: name, sc: parent, start: time.Now()}
+	if parent.IsValid() {
+		span.parent = parent.SpanID
+	} else {
+		_, _ = rand.Read(span.sc.TraceID[:])
+	}
+	_, _ = rand.Read(span.sc.SpanID[:])
+	return ContextWithSpanContext(ctx, span.sc), span
+}
+
+// SpanContext returns the span context of s.
+func (s *Span) SpanContext() SpanContext {
+	return s.sc
+}
+
+// End finishes the span and logs it, with err if it is not nil.
+func (s *Span) End(err error) {
+	fields := []zap.Field{
+		zap.String("traceID", hex.EncodeToString(s.sc.TraceID[:])),
+		zap.String("spanID", hex.EncodeToString(s.sc.SpanID[:])),
+		zap.Duration("duration", time.Since(s.start)),
+	}
+	if s.parent != [8]byte{} {
+		fields = append(fields, zap.String("parentSpanID", hex.EncodeToString(s.parent[:])))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	s.logger.Info("span "+s.name, fields...)
+}
+
+// Transport sets the traceparent header of requests from the span context in their context.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if sc := SpanContextFromContext(req.Context()); sc.IsValid() {
+		req = req.Clone(req.Context())
+		req.Header.Set(TraceparentHeader, sc.Traceparent())
+	}
+	return t.Base.RoundTrip(req) //nolint:wrapcheck // a RoundTripper returns the errors of its base
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	_, span := StartSpan(context.Background(), zap.NewNop(), "test")
+	sc := span.SpanContext()
+	require.True(t, sc.IsValid())
+
+	parsed, err := ParseTraceparent(sc.Traceparent())
+	require.NoError(t, err)
+	require.Equal(t, sc, parsed)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "valid", value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		{name: "unknown version", value: "01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", wantErr: true},
+		{name: "uppercase", value: "00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01", wantErr: true},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-b7ad6b7169203331-01", wantErr: true},
+		{name: "short span id", value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b71-01", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTraceparent(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestStartSpanJoinsParentTrace(t *testing.T) {
+	ctx, root := StartSpan(context.Background(), zap.NewNop(), "root")
+	_, child := StartSpan(ctx, zap.NewNop(), "child")
+
+	require.Equal(t, root.SpanContext().TraceID, child.SpanContext().TraceID)
+	require.NotEqual(t, root.SpanContext().SpanID, child.SpanContext().SpanID)
+	require.Equal(t, root.SpanContext().SpanID, child.parent)
+}
+
+func TestTransportSetsTraceparent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(TraceparentHeader)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &Transport{Base: http.DefaultTransport}}
+
+	// requests without a span context are sent as they are
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, http.NoBody)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Empty(t, got)
+
+	ctx, span := StartSpan(context.Background(), zap.NewNop(), "request")
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, http.NoBody)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, span.SpanContext().Traceparent(), got)
+	require.Empty(t, req.Header.Get(TraceparentHeader), "the caller's request must not be modified")
+}
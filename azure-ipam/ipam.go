@@ -7,8 +7,10 @@ import (
 	"net"
 
 	"github.com/Azure/azure-container-networking/azure-ipam/internal/buildinfo"
+	"github.com/Azure/azure-container-networking/azure-ipam/internal/tracing"
 	"github.com/Azure/azure-container-networking/azure-ipam/ipconfig"
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	types100 "github.com/containernetworking/cni/pkg/types/100"
@@ -19,27 +21,30 @@ import (
 // IPAMPlugin is the struct for the delegated azure-ipam plugin
 // https://www.cni.dev/docs/spec/#section-4-plugin-delegation
 type IPAMPlugin struct {
-	Name      string
-	Version   string
-	Options   map[string]interface{}
-	logger    *zap.Logger
-	cnsClient cnsClient
-	out       io.Writer // indicate the output channel for the plugin
+	Name        string
+	Version     string
+	Options     map[string]interface{}
+	logger      *zap.Logger
+	cnsClient   cnsClient
+	allocations allocations
+	out         io.Writer // indicate the output channel for the plugin
 }
 
 type cnsClient interface {
 	RequestIPAddress(context.Context, cns.IPConfigRequest) (*cns.IPConfigResponse, error)
 	ReleaseIPAddress(context.Context, cns.IPConfigRequest) error
+	GetIPAddressesMatchingStates(context.Context, ...types.IPState) ([]cns.IPConfigurationStatus, error)
 }
 
 // NewPlugin constructs a new IPAM plugin instance with given logger and CNS client
 func NewPlugin(logger *zap.Logger, c cnsClient, out io.Writer) (*IPAMPlugin, error) {
 	plugin := &IPAMPlugin{
-		Name:      pluginName,
-		Version:   buildinfo.Version,
-		logger:    logger,
-		out:       out,
-		cnsClient: c,
+		Name:        pluginName,
+		Version:     buildinfo.Version,
+		logger:      logger,
+		out:         out,
+		cnsClient:   c,
+		allocations: allocations{dir: allocationsDir},
 	}
 	return plugin, nil
 }
@@ -50,8 +55,10 @@ func NewPlugin(logger *zap.Logger, c cnsClient, out io.Writer) (*IPAMPlugin, err
 //
 
 // CmdAdd handles CNI add commands.
-func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
+func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) (err error) {
 	p.logger.Info("ADD called", zap.Any("args", args))
+	ctx, span := tracing.StartSpan(context.Background(), p.logger, "azure-ipam.ADD")
+	defer func() { span.End(err) }()
 
 	// Parsing network conf
 	nwCfg, err := parseNetConf(args.StdinData)
@@ -72,13 +79,20 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 	p.logger.Debug("Making request to CNS")
 	// if this fails, the caller plugin should execute again with cmdDel before returning error.
 	// https://www.cni.dev/docs/spec/#delegated-plugin-execution-procedure
-	resp, err := p.cnsClient.RequestIPAddress(context.TODO(), req)
+	reqCtx, reqSpan := tracing.StartSpan(ctx, p.logger, "azure-ipam.RequestIPAddress")
+	resp, err := p.cnsClient.RequestIPAddress(reqCtx, req)
+	reqSpan.End(err)
 	if err != nil {
 		p.logger.Error("Failed to request IP address from CNS", zap.Error(err), zap.Any("request", req))
 		return cniTypes.NewError(ErrRequestIPConfigFromCNS, err.Error(), "failed to request IP address from CNS")
 	}
 	p.logger.Debug("Received CNS IP config response", zap.Any("response", resp))
 
+	// without the record GC never releases the IP, which is safer than failing after CNS assigned it
+	if err := p.allocations.add(nwCfg.Name, args.ContainerID); err != nil {
+		p.logger.Error("Failed to record IP allocation", zap.Error(err))
+	}
+
 	// Get Pod IP and gateway IP from ip config response
 	podIPNet, gwIP, err := ipconfig.ProcessIPConfigResp(resp)
 	if err != nil {
@@ -109,7 +123,7 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 	}
 
 	// Get versioned result
-	versionedCniResult, err := cniResult.GetAsVersion(nwCfg.CNIVersion)
+	versionedCniResult, err := getAsVersion(cniResult, nwCfg.CNIVersion)
 	if err != nil {
 		p.logger.Error("Failed to interpret CNI result with netconf CNI version", zap.Error(err), zap.Any("cniVersion", nwCfg.CNIVersion))
 		return cniTypes.NewError(cniTypes.ErrIncompatibleCNIVersion, err.Error(), "failed to interpret CNI result with netconf CNI version")
@@ -128,8 +142,10 @@ func (p *IPAMPlugin) CmdAdd(args *cniSkel.CmdArgs) error {
 }
 
 // CmdDel handles CNI delete commands.
-func (p *IPAMPlugin) CmdDel(args *cniSkel.CmdArgs) error {
+func (p *IPAMPlugin) CmdDel(args *cniSkel.CmdArgs) (err error) {
 	p.logger.Info("DEL called", zap.Any("args", args))
+	ctx, span := tracing.StartSpan(context.Background(), p.logger, "azure-ipam.DEL")
+	defer func() { span.End(err) }()
 
 	// Create ip config request from args
 	req, err := ipconfig.CreateIPConfigReq(args)
@@ -141,11 +157,20 @@ func (p *IPAMPlugin) CmdDel(args *cniSkel.CmdArgs) error {
 
 	p.logger.Debug("Making request to CNS")
 	// cnsClient enforces it own timeout
-	if err := p.cnsClient.ReleaseIPAddress(context.TODO(), req); err != nil {
+	reqCtx, reqSpan := tracing.StartSpan(ctx, p.logger, "azure-ipam.ReleaseIPAddress")
+	err = p.cnsClient.ReleaseIPAddress(reqCtx, req)
+	reqSpan.End(err)
+	if err != nil {
 		p.logger.Error("Failed to release IP address from CNS", zap.Error(err), zap.Any("request", req))
 		return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "failed to release IP address from CNS")
 	}
 
+	if nwCfg, err := parseNetConf(args.StdinData); err != nil {
+		p.logger.Error("Failed to parse CNI network config from stdin", zap.Error(err), zap.Any("argStdinData", args.StdinData))
+	} else if err := p.allocations.remove(nwCfg.Name, args.ContainerID); err != nil {
+		p.logger.Error("Failed to remove IP allocation record", zap.Error(err))
+	}
+
 	p.logger.Info("DEL success")
 
 	return nil
@@ -157,6 +182,131 @@ func (p *IPAMPlugin) CmdCheck(args *cniSkel.CmdArgs) error {
 	return nil
 }
 
+// CmdGC handles CNI GC commands. It releases the IPs requested for containers of the network which have no valid
+// attachment. IPs requested by other plugins, or for other networks, are left alone.
+func (p *IPAMPlugin) CmdGC(args *cniSkel.CmdArgs) error {
+	p.logger.Info("GC called")
+
+	gcConf := &gcNetConf{}
+	if err := json.Unmarshal(args.StdinData, gcConf); err != nil {
+		p.logger.Error("Failed to parse CNI network config from stdin", zap.Error(err), zap.Any("argStdinData", args.StdinData))
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "failed to parse CNI network config from stdin")
+	}
+
+	validContainers := make(map[string]bool, len(gcConf.ValidAttachments))
+	for _, attachment := range gcConf.ValidAttachments {
+		validContainers[attachment.ContainerID] = true
+	}
+
+	containerIDs, err := p.allocations.list(gcConf.Name)
+	if err != nil {
+		p.logger.Error("Failed to list IP allocations", zap.Error(err))
+		return cniTypes.NewError(cniTypes.ErrIOFailure, err.Error(), "failed to list IP allocations")
+	}
+	var stale []string
+	for _, containerID := range containerIDs {
+		if !validContainers[containerID] {
+			stale = append(stale, containerID)
+		}
+	}
+	if len(stale) == 0 {
+		p.logger.Info("GC success")
+		return nil
+	}
+
+	assigned, err := p.cnsClient.GetIPAddressesMatchingStates(context.TODO(), types.Assigned)
+	if err != nil {
+		p.logger.Error("Failed to get assigned IP addresses from CNS", zap.Error(err))
+		return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "failed to get assigned IP addresses from CNS")
+	}
+	// azure-ipam requests IPs with the container ID as pod interface ID
+	assignedByContainer := make(map[string]cns.IPConfigurationStatus, len(assigned))
+	for i := range assigned {
+		if podInfo := assigned[i].PodInfo; podInfo != nil && podInfo.InterfaceID() == podInfo.InfraContainerID() {
+			assignedByContainer[podInfo.InfraContainerID()] = assigned[i]
+		}
+	}
+
+	for _, containerID := range stale {
+		if ipConfig, ok := assignedByContainer[containerID]; ok {
+			if err := p.releaseStaleIP(ipConfig); err != nil {
+				return err
+			}
+		}
+		if err := p.allocations.remove(gcConf.Name, containerID); err != nil {
+			p.logger.Error("Failed to remove IP allocation record", zap.Error(err))
+		}
+	}
+
+	p.logger.Info("GC success")
+
+	return nil
+}
+
+// releaseStaleIP releases an IP which CNS assigned to a container without a valid attachment.
+func (p *IPAMPlugin) releaseStaleIP(ipConfig cns.IPConfigurationStatus) error {
+	orchestratorContext, err := ipConfig.PodInfo.OrchestratorContext()
+	if err != nil {
+		p.logger.Error("Failed to get orchestrator context of stale IP", zap.Error(err), zap.String("ip", ipConfig.IPAddress))
+		return cniTypes.NewError(cniTypes.ErrInternal, err.Error(), "failed to get orchestrator context of stale IP")
+	}
+
+	req := cns.IPConfigRequest{
+		DesiredIPAddress:    ipConfig.IPAddress,
+		PodInterfaceID:      ipConfig.PodInfo.InterfaceID(),
+		InfraContainerID:    ipConfig.PodInfo.InfraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	}
+	p.logger.Info("Releasing stale IP", zap.String("ip", ipConfig.IPAddress), zap.String("containerID", req.InfraContainerID))
+	if err := p.cnsClient.ReleaseIPAddress(context.TODO(), req); err != nil {
+		p.logger.Error("Failed to release stale IP address from CNS", zap.Error(err), zap.Any("request", req))
+		return cniTypes.NewError(cniTypes.ErrTryAgainLater, err.Error(), "failed to release stale IP address from CNS")
+	}
+	return nil
+}
+
+// CmdStatus handles CNI status commands. The plugin is available when CNS is reachable and has IPs to assign.
+func (p *IPAMPlugin) CmdStatus(_ *cniSkel.CmdArgs) error {
+	p.logger.Info("STATUS called")
+
+	available, err := p.cnsClient.GetIPAddressesMatchingStates(context.TODO(), types.Available)
+	if err != nil {
+		p.logger.Error("Failed to get available IP addresses from CNS", zap.Error(err))
+		return cniTypes.NewError(ErrPluginNotAvailable, err.Error(), "CNS is not reachable")
+	}
+
+	if len(available) == 0 {
+		p.logger.Error("CNS has no available IP address")
+		return cniTypes.NewError(ErrPluginNotAvailable, "no available IP address", "CNS has no available IP address")
+	}
+
+	p.logger.Info("STATUS success", zap.Int("availableIPs", len(available)))
+
+	return nil
+}
+
+// gcNetConf is the network config of a GC command
+type gcNetConf struct {
+	cniTypes.NetConf
+	ValidAttachments []attachment `json:"cni.dev/valid-attachments,omitempty"`
+}
+
+type attachment struct {
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifname"`
+}
+
+// getAsVersion converts a result to the CNI version of a network config, including 1.1.0,
+// which the CNI library in use can't convert to.
+func getAsVersion(result *types100.Result, version string) (cniTypes.Result, error) {
+	if version != specVersionGCStatus {
+		return result.GetAsVersion(version) //nolint:wrapcheck // the caller wraps the error
+	}
+	converted := *result
+	converted.CNIVersion = version
+	return &converted, nil
+}
+
 // Parse network config from given byte array
 func parseNetConf(b []byte) (*cniTypes.NetConf, error) {
 	netConf := &cniTypes.NetConf{}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/azure-ipam/logger"
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	types100 "github.com/containernetworking/cni/pkg/types/100"
@@ -22,7 +23,11 @@ var (
 )
 
 // MOckCNSClient is a mock implementation of the CNSClient interface
-type MockCNSClient struct{}
+type MockCNSClient struct {
+	ipConfigs          []cns.IPConfigurationStatus
+	failGetIPAddresses bool
+	released           []string
+}
 
 func (c *MockCNSClient) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
 	switch ipconfig.InfraContainerID {
@@ -86,6 +91,7 @@ func (c *MockCNSClient) RequestIPAddress(ctx context.Context, ipconfig cns.IPCon
 }
 
 func (c *MockCNSClient) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) error {
+	c.released = append(c.released, ipconfig.DesiredIPAddress)
 	switch ipconfig.InfraContainerID {
 	case "failRequestCNSReleaseIPArgs":
 		return errFoo
@@ -94,6 +100,22 @@ func (c *MockCNSClient) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPCon
 	}
 }
 
+func (c *MockCNSClient) GetIPAddressesMatchingStates(_ context.Context, states ...types.IPState) ([]cns.IPConfigurationStatus, error) {
+	if c.failGetIPAddresses {
+		return nil, errFoo
+	}
+
+	var ipConfigs []cns.IPConfigurationStatus
+	for _, ipConfig := range c.ipConfigs {
+		for _, state := range states {
+			if ipConfig.GetState() == state {
+				ipConfigs = append(ipConfigs, ipConfig)
+			}
+		}
+	}
+	return ipConfigs, nil
+}
+
 // cniResultsWriter is a helper struct to write CNI results to a byte array
 type cniResultsWriter struct {
 	result *types100.Result
@@ -211,6 +233,7 @@ func TestCmdAdd(t *testing.T) {
 			}
 			defer cleanup()
 			ipamPlugin, _ := NewPlugin(testLogger, mockCNSClient, writer)
+			ipamPlugin.allocations = allocations{dir: t.TempDir()}
 			err = ipamPlugin.CmdAdd(tt.args)
 			if tt.wantErr {
 				require.Error(t, err)
@@ -257,6 +280,7 @@ func TestCmdDel(t *testing.T) {
 			}
 			defer cleanup()
 			ipamPlugin, _ := NewPlugin(testLogger, mockCNSClient, nil)
+			ipamPlugin.allocations = allocations{dir: t.TempDir()}
 			err = ipamPlugin.CmdDel(tt.args)
 			if tt.wantErr {
 				require.Error(t, err)
@@ -278,3 +302,134 @@ func TestCmdCheck(t *testing.T) {
 	err = ipamPlugin.CmdCheck(nil)
 	require.NoError(t, err)
 }
+
+func newIPConfig(ip, containerID string, state types.IPState) cns.IPConfigurationStatus {
+	return newIPConfigWithInterface(ip, containerID, containerID, state)
+}
+
+func newIPConfigWithInterface(ip, containerID, interfaceID string, state types.IPState) cns.IPConfigurationStatus {
+	ipConfig := cns.IPConfigurationStatus{IPAddress: ip}
+	if containerID != "" {
+		ipConfig.PodInfo = cns.NewPodInfo(containerID, interfaceID, "testname-"+containerID, "testns")
+	}
+	ipConfig.SetState(state)
+	return ipConfig
+}
+
+func TestCmdAddRecordsAllocation(t *testing.T) {
+	netConf, err := json.Marshal(&cniTypes.NetConf{CNIVersion: "1.1.0", Name: "azure"})
+	require.NoError(t, err)
+	testLogger, cleanup, err := logger.New(loggerCfg)
+	require.NoError(t, err)
+	defer cleanup()
+
+	writer := &cniResultsWriter{}
+	ipamPlugin, _ := NewPlugin(testLogger, &MockCNSClient{}, writer)
+	ipamPlugin.allocations = allocations{dir: t.TempDir()}
+
+	require.NoError(t, ipamPlugin.CmdAdd(buildArgs("happyArgs", happyPodArgs, netConf)))
+	require.Equal(t, "1.1.0", writer.result.CNIVersion)
+	containerIDs, err := ipamPlugin.allocations.list("azure")
+	require.NoError(t, err)
+	require.Equal(t, []string{"happyArgs"}, containerIDs)
+
+	require.NoError(t, ipamPlugin.CmdDel(buildArgs("happyArgs", happyPodArgs, netConf)))
+	containerIDs, err = ipamPlugin.allocations.list("azure")
+	require.NoError(t, err)
+	require.Empty(t, containerIDs)
+}
+
+func TestCmdGC(t *testing.T) {
+	gcNetConfByteArr, err := json.Marshal(&gcNetConf{
+		NetConf:          cniTypes.NetConf{CNIVersion: "1.1.0", Name: "gcnetconf"},
+		ValidAttachments: []attachment{{ContainerID: "validContainer", IfName: "eth0"}},
+	})
+	require.NoError(t, err)
+
+	testLogger, cleanup, err := logger.New(loggerCfg)
+	require.NoError(t, err)
+	defer cleanup()
+
+	mockCNSClient := &MockCNSClient{
+		ipConfigs: []cns.IPConfigurationStatus{
+			newIPConfig("10.0.1.10", "validContainer", types.Assigned),
+			newIPConfig("10.0.1.11", "staleContainer", types.Assigned),
+			// requested for another network
+			newIPConfig("10.0.1.12", "otherNetworkContainer", types.Assigned),
+			// requested by azure-vnet, which uses an interface ID of its own
+			newIPConfigWithInterface("10.0.1.13", "vnetContainer", "vnetCont-eth0", types.Assigned),
+			newIPConfig("10.0.1.14", "", types.Assigned),
+			newIPConfig("10.0.1.15", "", types.Available),
+		},
+	}
+	ipamPlugin, _ := NewPlugin(testLogger, mockCNSClient, nil)
+	ipamPlugin.allocations = allocations{dir: t.TempDir()}
+	for _, containerID := range []string{"validContainer", "staleContainer", "vnetContainer", "releasedContainer"} {
+		require.NoError(t, ipamPlugin.allocations.add("gcnetconf", containerID))
+	}
+	require.NoError(t, ipamPlugin.allocations.add("othernetconf", "otherNetworkContainer"))
+
+	require.NoError(t, ipamPlugin.CmdGC(&cniSkel.CmdArgs{StdinData: gcNetConfByteArr}))
+	require.Equal(t, []string{"10.0.1.11"}, mockCNSClient.released)
+	containerIDs, err := ipamPlugin.allocations.list("gcnetconf")
+	require.NoError(t, err)
+	require.Equal(t, []string{"validContainer"}, containerIDs)
+
+	// nothing to release
+	mockCNSClient.released = nil
+	require.NoError(t, ipamPlugin.CmdGC(&cniSkel.CmdArgs{StdinData: gcNetConfByteArr}))
+	require.Empty(t, mockCNSClient.released)
+
+	ipamPlugin, _ = NewPlugin(testLogger, &MockCNSClient{failGetIPAddresses: true}, nil)
+	ipamPlugin.allocations = allocations{dir: t.TempDir()}
+	require.NoError(t, ipamPlugin.allocations.add("gcnetconf", "staleContainer"))
+	require.Error(t, ipamPlugin.CmdGC(&cniSkel.CmdArgs{StdinData: gcNetConfByteArr}))
+}
+
+func TestAllocationsRejectPaths(t *testing.T) {
+	a := allocations{dir: t.TempDir()}
+	require.ErrorIs(t, a.add("azure", "../escape"), errInvalidAllocation)
+	require.ErrorIs(t, a.add("..", "container"), errInvalidAllocation)
+	_, err := a.list("")
+	require.ErrorIs(t, err, errInvalidAllocation)
+}
+
+func TestCmdStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    *MockCNSClient
+		wantError bool
+	}{
+		{
+			name:   "Available IPs",
+			client: &MockCNSClient{ipConfigs: []cns.IPConfigurationStatus{newIPConfig("10.0.1.10", "", types.Available)}},
+		},
+		{
+			name:      "No available IPs",
+			client:    &MockCNSClient{ipConfigs: []cns.IPConfigurationStatus{newIPConfig("10.0.1.10", "testContainer", types.Assigned)}},
+			wantError: true,
+		},
+		{
+			name:      "CNS not reachable",
+			client:    &MockCNSClient{failGetIPAddresses: true},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			testLogger, cleanup, err := logger.New(loggerCfg)
+			require.NoError(t, err)
+			defer cleanup()
+			ipamPlugin, _ := NewPlugin(testLogger, tt.client, nil)
+			err = ipamPlugin.CmdStatus(nil)
+			if !tt.wantError {
+				require.NoError(t, err)
+				return
+			}
+			var cniErr *cniTypes.Error
+			require.ErrorAs(t, err, &cniErr)
+			require.Equal(t, ErrPluginNotAvailable, cniErr.Code)
+		})
+	}
+}
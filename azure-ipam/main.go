@@ -1,12 +1,16 @@
 package main
 
 import (
+	"io"
 	"log"
+	"net/http"
 	"os"
 
+	"github.com/Azure/azure-container-networking/azure-ipam/internal/tracing"
 	"github.com/Azure/azure-container-networking/azure-ipam/logger"
 	cnsclient "github.com/Azure/azure-container-networking/cns/client"
 	"github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/version"
 	bv "github.com/containernetworking/plugins/pkg/utils/buildversion"
 	"github.com/pkg/errors"
@@ -35,6 +39,9 @@ func executePlugin() error {
 	pluginLogger.Debug("logger construction succeeded")
 	defer cleanup()
 
+	// The CNS client sends its requests through the default transport, which now adds the traceparent header
+	http.DefaultTransport = &tracing.Transport{Base: http.DefaultTransport}
+
 	// Create CNS client
 	client, err := cnsclient.New(cnsBaseURL, cnsReqTimeout)
 	if err != nil {
@@ -48,8 +55,17 @@ func executePlugin() error {
 		return errors.Wrapf(err, "failed to create IPAM plugin")
 	}
 
+	// The skel package doesn't know the GC and STATUS commands of CNI spec 1.1
+	switch os.Getenv("CNI_COMMAND") {
+	case cmdGC:
+		return executeWithStdin(plugin.CmdGC)
+	case cmdStatus:
+		return executeWithStdin(plugin.CmdStatus)
+	}
+
 	// Execute CNI plugin
-	cniErr := skel.PluginMainWithError(plugin.CmdAdd, plugin.CmdCheck, plugin.CmdDel, version.All, bv.BuildString(pluginName))
+	supportedVersions := version.PluginSupports(append(version.All.SupportedVersions(), specVersionGCStatus)...)
+	cniErr := skel.PluginMainWithError(plugin.CmdAdd, plugin.CmdCheck, plugin.CmdDel, supportedVersions, bv.BuildString(pluginName))
 	if cniErr != nil {
 		cniErr.Print()
 		return cniErr
@@ -57,3 +73,24 @@ func executePlugin() error {
 
 	return nil
 }
+
+// executeWithStdin executes a CNI command which only takes the network config from stdin
+func executeWithStdin(cmd func(*skel.CmdArgs) error) error {
+	stdinData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		cniErr := cniTypes.NewError(cniTypes.ErrIOFailure, err.Error(), "failed to read network config from stdin")
+		cniErr.Print()
+		return cniErr
+	}
+
+	if err := cmd(&skel.CmdArgs{Path: os.Getenv("CNI_PATH"), StdinData: stdinData}); err != nil {
+		var cniErr *cniTypes.Error
+		if !errors.As(err, &cniErr) {
+			cniErr = cniTypes.NewError(cniTypes.ErrInternal, err.Error(), "")
+		}
+		cniErr.Print()
+		return cniErr
+	}
+
+	return nil
+}
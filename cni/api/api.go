@@ -35,3 +35,26 @@ func (a *AzureCNIState) PrintResult() error {
 
 	return nil
 }
+
+// OrphanReport lists the host resources which belong to no endpoint in the CNI state.
+type OrphanReport struct {
+	Links      []string
+	Routes     []string
+	Namespaces []string
+}
+
+func (r *OrphanReport) PrintResult() error {
+	b, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		log.Errorf("Failed to marshal orphan report, err:%v.\n", err)
+	}
+
+	// write result to stdout to be captured by caller
+	_, err = os.Stdout.Write(b)
+	if err != nil {
+		log.Printf("Failed to write response to stdout %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 
 type Client interface {
 	GetEndpointState() (*api.AzureCNIState, error)
+	ReconcileOrphans(deleteOrphans bool) (*api.OrphanReport, error)
 }
 
 var _ (Client) = (*client)(nil)
@@ -50,6 +51,28 @@ func (c *client) GetEndpointState() (*api.AzureCNIState, error) {
 	return state, nil
 }
 
+func (c *client) ReconcileOrphans(deleteOrphans bool) (*api.OrphanReport, error) {
+	cmd := c.exec.Command(platform.CNIBinaryPath)
+
+	envs := os.Environ()
+	cmdenv := fmt.Sprintf("%s=%s", cni.Cmd, cni.CmdReconcileOrphans)
+	log.Printf("Setting cmd to %s", cmdenv)
+	envs = append(envs, cmdenv, fmt.Sprintf("%s=%t", cni.DeleteOrphans, deleteOrphans))
+	cmd.SetEnv(envs)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Azure CNI bin with err: [%w], output: [%s]", err, string(output))
+	}
+
+	report := &api.OrphanReport{}
+	if err := json.Unmarshal(output, report); err != nil {
+		return nil, fmt.Errorf("failed to decode response from Azure CNI when reconciling orphans: [%w], response from CNI: [%s]", err, string(output))
+	}
+
+	return report, nil
+}
+
 func (c *client) GetVersion() (*semver.Version, error) {
 	cmd := c.exec.Command(platform.CNIBinaryPath, "-v")
 
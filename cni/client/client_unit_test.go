@@ -33,6 +33,24 @@ func TestGetState(t *testing.T) {
 	require.Equal(t, res, state)
 }
 
+func TestReconcileOrphans(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"/opt/cni/bin/azure-vnet"}, Stdout: `{"Links":["azv1a2b3c4"],"Routes":["10.241.0.17/32 dev azv1a2b3c4 table 254"],"Namespaces":["az_ns_2"]}`},
+	}
+
+	fakeexec := testutils.GetFakeExecWithScripts(calls)
+
+	c := New(fakeexec)
+	report, err := c.ReconcileOrphans(false)
+	require.NoError(t, err)
+
+	require.Equal(t, &api.OrphanReport{
+		Links:      []string{"azv1a2b3c4"},
+		Routes:     []string{"10.241.0.17/32 dev azv1a2b3c4 table 254"},
+		Namespaces: []string{"az_ns_2"},
+	}, report)
+}
+
 func TestGetVersion(t *testing.T) {
 	calls := []testutils.TestCmd{
 		{Cmd: []string{"/opt/cni/bin/azure-vnet", "-v"}, Stdout: `Azure CNI Version v1.4.0-2-g984c5a5e-dirty`},
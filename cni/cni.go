@@ -20,19 +20,36 @@ const (
 	CmdUpdate = "UPDATE"
 	// CmdVersion - CNI VERSION command.
 	CmdVersion = "VERSION"
+	// CmdGC - CNI GC command.
+	CmdGC = "GC"
+	// CmdStatus - CNI STATUS command.
+	CmdStatus = "STATUS"
 
 	// nonstandard CNI spec command, used to dump CNI state to stdout
 	CmdGetEndpointsState = "GET_ENDPOINT_STATE"
+	// nonstandard CNI spec command, used to report host links and namespaces of unknown endpoints
+	CmdReconcileOrphans = "RECONCILE_ORPHANS"
+	// DeleteOrphans is set to "true" to also remove the orphans found by CmdReconcileOrphans.
+	DeleteOrphans = "AZURE_CNI_DELETE_ORPHANS"
 
 	// CNI errors.
 	ErrRuntime = 100
+	// ErrPluginNotAvailable is returned by STATUS when the plugin cannot serve ADD commands.
+	ErrPluginNotAvailable = 50
 
 	// DefaultVersion is the CNI version used when no version is specified in a network config file.
 	defaultVersion = "0.2.0"
 )
 
 // Supported CNI versions.
-var supportedVersions = []string{"0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0"}
+var supportedVersions = []string{"0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0", specVersionGCStatus}
+
+// specVersionGCStatus is the version of the CNI spec adding the GC and STATUS commands, which runtimes only send
+// to plugins supporting it. Its results are those of spec 1.0.0, which the CNI library in use only knows by 1.0.0.
+const (
+	specVersionGCStatus = "1.1.0"
+	specVersionResult   = "1.0.0"
+)
 
 // CNI contract.
 type PluginApi interface {
@@ -41,3 +58,9 @@ type PluginApi interface {
 	Delete(args *cniSkel.CmdArgs) error
 	Update(args *cniSkel.CmdArgs) error
 }
+
+// GCStatusPluginApi is implemented by the plugins supporting the GC and STATUS commands of CNI spec 1.1.
+type GCStatusPluginApi interface {
+	GC(args *cniSkel.CmdArgs) error
+	Status(args *cniSkel.CmdArgs) error
+}
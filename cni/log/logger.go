@@ -0,0 +1,188 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package log provides the structured logger shared by the azure-vnet CNI plugin and the network package.
+package log
+
+import (
+	"os"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	logFileExtension = ".log"
+
+	// Log file rotation default limits.
+	maxLogFileSizeInMB = 5
+	maxLogFileCount    = 8
+)
+
+// Invocation field keys, shared with the CNS request headers carrying the same values.
+const (
+	InvocationIDKey = "invocationID"
+	CommandKey      = "command"
+	ContainerIDKey  = "containerID"
+	PodNameKey      = "podName"
+	PodNamespaceKey = "podNamespace"
+	ComponentKey    = "component"
+)
+
+// Config is the configuration of the CNI log file.
+type Config struct {
+	Name        string        // log file name without extension, e.g. azure-vnet
+	Directory   string        // defaults to the platform log directory
+	Level       zapcore.Level // Info by default
+	MaxSizeInMB int
+	MaxBackups  int
+}
+
+var (
+	mu sync.RWMutex
+	// base is the core every CNILogger entry is written to. Until Init is called it
+	// forwards to the printf-style log package, so processes which never initialize the
+	// structured logger (CNM, CNMS) keep their existing log output.
+	base zapcore.Core = newLegacyCore()
+	// invocation holds the fields identifying the current CNI invocation.
+	invocation []zapcore.Field
+)
+
+// CNILogger is the structured logger of the CNI plugin. Loggers derived from it with
+// With or Sugar at package initialization pick up the output configured later by Init
+// and the fields set by SetInvocationFields.
+var CNILogger = zap.New(&dynamicCore{}, zap.AddCaller())
+
+// Init directs CNILogger to a JSON log file with size based rotation and returns a
+// function flushing buffered entries.
+func Init(cfg *Config) func() {
+	if cfg.Directory == "" {
+		cfg.Directory = log.LogPath
+	}
+	if cfg.MaxSizeInMB == 0 {
+		cfg.MaxSizeInMB = maxLogFileSizeInMB
+	}
+	if cfg.MaxBackups == 0 {
+		cfg.MaxBackups = maxLogFileCount
+	}
+
+	fileWriter := zapcore.AddSync(&lumberjack.Logger{
+		Filename:   cfg.Directory + cfg.Name + logFileExtension,
+		MaxSize:    cfg.MaxSizeInMB,
+		MaxBackups: cfg.MaxBackups,
+	})
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), fileWriter, cfg.Level).
+		With([]zapcore.Field{zap.Int("pid", os.Getpid())})
+
+	setCore(core)
+
+	return func() {
+		_ = CNILogger.Sync()
+	}
+}
+
+// SetInvocationFields adds fields identifying the current CNI invocation to every
+// entry written through CNILogger and the loggers derived from it. Fields with a key
+// that is already set replace the previous value.
+func SetInvocationFields(fields ...zap.Field) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	merged := make([]zapcore.Field, 0, len(invocation)+len(fields))
+	for _, f := range invocation {
+		if !hasKey(fields, f.Key) {
+			merged = append(merged, f)
+		}
+	}
+	invocation = append(merged, fields...)
+}
+
+// InvocationFields returns the fields set by SetInvocationFields.
+func InvocationFields() []zap.Field {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]zap.Field(nil), invocation...)
+}
+
+func setCore(core zapcore.Core) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	base = core
+}
+
+func hasKey(fields []zap.Field, key string) bool {
+	for i := range fields {
+		if fields[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dynamicCore resolves the base core and the invocation fields at write time, which lets
+// package level loggers be built before Init runs.
+type dynamicCore struct {
+	fields []zapcore.Field
+}
+
+var _ zapcore.Core = (*dynamicCore)(nil)
+
+func (c *dynamicCore) Enabled(level zapcore.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return base.Enabled(level)
+}
+
+func (c *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &dynamicCore{fields: make([]zapcore.Field, 0, len(c.fields)+len(fields))}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+// Check implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *dynamicCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *dynamicCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	mu.RLock()
+	core := base
+	all := make([]zapcore.Field, 0, len(invocation)+len(c.fields)+len(fields))
+	all = append(all, invocation...)
+	mu.RUnlock()
+
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return core.Write(entry, all)
+}
+
+func (c *dynamicCore) Sync() error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return base.Sync()
+}
+
+// newLegacyCore writes through the standard logger of the log package, in its format and
+// honoring its level and module level overrides.
+func newLegacyCore() zapcore.Core {
+	return log.GetStd().ZapCore()
+}
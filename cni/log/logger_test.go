@@ -0,0 +1,49 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDerivedLoggerUsesCoreAndInvocationFields(t *testing.T) {
+	// derive the logger before the core is set, like package level loggers do.
+	logger := CNILogger.With(zap.String(ComponentKey, "test")).Sugar()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	setCore(core)
+	t.Cleanup(func() {
+		setCore(newLegacyCore())
+		invocation = nil
+	})
+
+	SetInvocationFields(zap.String(InvocationIDKey, "id"), zap.String(ContainerIDKey, "c1"))
+	SetInvocationFields(zap.String(ContainerIDKey, "c2"), zap.String(PodNameKey, "pod"))
+	logger.Infof("hello %s", "world")
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "hello world", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		InvocationIDKey: "id",
+		ContainerIDKey:  "c2",
+		PodNameKey:      "pod",
+		ComponentKey:    "test",
+	}, entries[0].ContextMap())
+}
+
+func TestDerivedLoggerHonorsCoreLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	setCore(core)
+	t.Cleanup(func() { setCore(newLegacyCore()) })
+
+	CNILogger.Debug("dropped")
+	CNILogger.Error("kept")
+
+	require.Len(t, logs.All(), 1)
+	assert.Equal(t, "kept", logs.All()[0].Message)
+}
@@ -62,8 +62,12 @@ type NetworkConfig struct {
 	EnableExactMatchForPodName    bool     `json:"enableExactMatchForPodName,omitempty"`
 	DisableHairpinOnHostInterface bool     `json:"disableHairpinOnHostInterface,omitempty"`
 	DisableIPTableLock            bool     `json:"disableIPTableLock,omitempty"`
+	UseNativeOVSClient            bool     `json:"useNativeOVSClient,omitempty"`
 	CNSUrl                        string   `json:"cnsurl,omitempty"`
 	ExecutionMode                 string   `json:"executionMode,omitempty"`
+	MTU                           int      `json:"mtu,omitempty"`
+	Offload                       Offload  `json:"offload,omitempty"`
+	Tracing                       Tracing  `json:"tracing,omitempty"`
 	Ipam                          struct {
 		Mode          string `json:"mode,omitempty"`
 		Type          string `json:"type"`
@@ -77,6 +81,37 @@ type NetworkConfig struct {
 	RuntimeConfig   RuntimeConfig   `json:"runtimeConfig,omitempty"`
 	WindowsSettings WindowsSettings `json:"windowsSettings,omitempty"`
 	AdditionalArgs  []KVPair        `json:"AdditionalArgs,omitempty"`
+	// ValidAttachments are the attachments which GC keeps.
+	ValidAttachments []Attachment `json:"cni.dev/valid-attachments,omitempty"`
+}
+
+// Attachment is a container interface attached by the plugin.
+type Attachment struct {
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifname"`
+}
+
+// Offload turns offloads of the container interfaces on or off, the kernel default is kept when unset.
+type Offload struct {
+	TSO *bool `json:"tso,omitempty"`
+	GSO *bool `json:"gso,omitempty"`
+	GRO *bool `json:"gro,omitempty"`
+}
+
+// Tracing exporters selectable with Tracing.Exporter.
+const (
+	TracingExporterLog  = "log"
+	TracingExporterOTLP = "otlp"
+)
+
+// Tracing configures tracing of the plugin commands, which is off when Exporter is unset.
+type Tracing struct {
+	// Exporter is where spans are sent, log to write them to the plugin log or otlp for a collector.
+	Exporter string `json:"exporter,omitempty"`
+	// Endpoint, Protocol and Insecure configure the OpenTelemetry collector when Exporter is otlp.
+	Endpoint string `json:"endpoint,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
 }
 
 type WindowsSettings struct {
@@ -31,4 +31,13 @@ type IPAMAddResult struct {
 	ipv6Result       *cniTypesCurr.Result
 	ncResponse       *cns.GetNetworkContainerResponse
 	hostSubnetPrefix net.IPNet
+	// secondaryInterfaces are the interfaces of the pod after the primary one, each on an NC of its own.
+	secondaryInterfaces []SecondaryInterfaceInfo
+}
+
+// SecondaryInterfaceInfo holds the IPs, MAC and routes of a secondary interface of a pod.
+type SecondaryInterfaceInfo struct {
+	ncID       string
+	macAddress net.HardwareAddr
+	result     *cniTypesCurr.Result
 }
@@ -10,7 +10,6 @@ import (
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/ipam"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/network"
 	"github.com/Azure/azure-container-networking/platform"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
@@ -98,7 +97,7 @@ func (invoker *AzureIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, er
 func (invoker *AzureIPAMInvoker) deleteIpamState() {
 	cniStateExists, err := platform.CheckIfFileExists(platform.CNIStateFilePath)
 	if err != nil {
-		log.Printf("[cni] Error checking CNI state exist: %v", err)
+		logger.Infof("[cni] Error checking CNI state exist: %v", err)
 		return
 	}
 
@@ -108,15 +107,15 @@ func (invoker *AzureIPAMInvoker) deleteIpamState() {
 
 	ipamStateExists, err := platform.CheckIfFileExists(platform.CNIIpamStatePath)
 	if err != nil {
-		log.Printf("[cni] Error checking IPAM state exist: %v", err)
+		logger.Infof("[cni] Error checking IPAM state exist: %v", err)
 		return
 	}
 
 	if ipamStateExists {
-		log.Printf("[cni] Deleting IPAM state file")
+		logger.Infof("[cni] Deleting IPAM state file")
 		err = os.Remove(platform.CNIIpamStatePath)
 		if err != nil {
-			log.Printf("[cni] Error deleting state file %v", err)
+			logger.Infof("[cni] Error deleting state file %v", err)
 			return
 		}
 	}
@@ -137,10 +136,10 @@ func (invoker *AzureIPAMInvoker) Delete(address *net.IPNet, nwCfg *cni.NetworkCo
 		}
 	} else if len(address.IP.To4()) == 4 {
 		nwCfg.Ipam.Address = address.IP.String()
-		log.Printf("Releasing ipv4 address :%s pool: %s",
+		logger.Infof("Releasing ipv4 address :%s pool: %s",
 			nwCfg.Ipam.Address, nwCfg.Ipam.Subnet)
 		if err := invoker.plugin.DelegateDel(nwCfg.Ipam.Type, nwCfg); err != nil {
-			log.Printf("Failed to release ipv4 address: %v", err)
+			logger.Infof("Failed to release ipv4 address: %v", err)
 			return invoker.plugin.Errorf("Failed to release ipv4 address: %v", err)
 		}
 	} else if len(address.IP.To16()) == 16 {
@@ -152,10 +151,10 @@ func (invoker *AzureIPAMInvoker) Delete(address *net.IPNet, nwCfg *cni.NetworkCo
 			nwCfgIpv6.Ipam.Subnet = invoker.nwInfo.Subnets[1].Prefix.String()
 		}
 
-		log.Printf("Releasing ipv6 address :%s pool: %s",
+		logger.Infof("Releasing ipv6 address :%s pool: %s",
 			nwCfgIpv6.Ipam.Address, nwCfgIpv6.Ipam.Subnet)
 		if err := invoker.plugin.DelegateDel(nwCfgIpv6.Ipam.Type, &nwCfgIpv6); err != nil {
-			log.Printf("Failed to release ipv6 address: %v", err)
+			logger.Infof("Failed to release ipv6 address: %v", err)
 			return invoker.plugin.Errorf("Failed to release ipv6 address: %v", err)
 		}
 	} else {
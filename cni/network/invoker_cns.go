@@ -9,10 +9,11 @@ import (
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/util"
 	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
 	"github.com/Azure/azure-container-networking/iptables"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/network"
 	"github.com/Azure/azure-container-networking/network/networkutils"
+	"github.com/Azure/azure-container-networking/tracing"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
@@ -25,8 +26,11 @@ var (
 )
 
 type CNSIPAMInvoker struct {
+	// ctx carries the trace of the CNI command the invoker runs for.
+	ctx           context.Context
 	podName       string
 	podNamespace  string
+	invocationID  string
 	cnsClient     cnsclient
 	executionMode util.ExecutionMode
 	ipamMode      util.IpamMode
@@ -42,16 +46,33 @@ type IPv4ResultInfo struct {
 	hostGateway        string
 }
 
-func NewCNSInvoker(podName, namespace string, cnsClient cnsclient, executionMode util.ExecutionMode, ipamMode util.IpamMode) *CNSIPAMInvoker {
+func NewCNSInvoker(ctx context.Context, podName, namespace, invocationID string, cnsClient cnsclient, executionMode util.ExecutionMode, ipamMode util.IpamMode) *CNSIPAMInvoker {
 	return &CNSIPAMInvoker{
+		ctx:           ctx,
 		podName:       podName,
 		podNamespace:  namespace,
+		invocationID:  invocationID,
 		cnsClient:     cnsClient,
 		executionMode: executionMode,
 		ipamMode:      ipamMode,
 	}
 }
 
+// requestContext returns the context for CNS requests made on behalf of the given container,
+// carrying the trace and the IDs that correlate the request with this CNI invocation.
+func (invoker *CNSIPAMInvoker) requestContext(containerID string) context.Context {
+	ctx := invoker.ctx
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	return cnscli.WithInvocation(ctx, cnscli.Invocation{
+		ID:           invoker.invocationID,
+		ContainerID:  containerID,
+		PodName:      invoker.podName,
+		PodNamespace: invoker.podNamespace,
+	})
+}
+
 // Add uses the requestipconfig API in cns, and returns ipv4 and a nil ipv6 as CNS doesn't support IPv6 yet
 func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, error) {
 	// Parse Pod arguments.
@@ -60,7 +81,7 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 		PodNamespace: invoker.podNamespace,
 	}
 
-	log.Printf(podInfo.PodName)
+	logger.Infof(podInfo.PodName)
 	orchestratorContext, err := json.Marshal(podInfo)
 	if err != nil {
 		return IPAMAddResult{}, errors.Wrap(err, "Failed to unmarshal orchestrator context during add: %w")
@@ -76,10 +97,13 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 		InfraContainerID:    addConfig.args.ContainerID,
 	}
 
-	log.Printf("Requesting IP for pod %+v using ipconfig %+v", podInfo, ipconfig)
-	response, err := invoker.cnsClient.RequestIPAddress(context.TODO(), ipconfig)
+	logger.Infof("Requesting IP for pod %+v using ipconfig %+v", podInfo, ipconfig)
+	ctx, span := tracing.StartSpan(invoker.requestContext(addConfig.args.ContainerID), "cni.RequestIPAddress",
+		tracing.WithKind(tracing.SpanKindClient))
+	response, err := invoker.cnsClient.RequestIPAddress(ctx, ipconfig)
+	span.End(err)
 	if err != nil {
-		log.Printf("Failed to get IP address from CNS with error %v, response: %v", err, response)
+		logger.Infof("Failed to get IP address from CNS with error %v, response: %v", err, response)
 		return IPAMAddResult{}, errors.Wrap(err, "Failed to get IP address from CNS with error: %w")
 	}
 
@@ -96,7 +120,7 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 	// set the NC Primary IP in options
 	addConfig.options[network.SNATIPKey] = info.ncPrimaryIP
 
-	log.Printf("[cni-invoker-cns] Received info %+v for pod %v", info, podInfo)
+	logger.Infof("[cni-invoker-cns] Received info %+v for pod %v", info, podInfo)
 
 	ncgw := net.ParseIP(info.ncGatewayIPAddress)
 	if ncgw == nil && invoker.ipamMode != util.V4Overlay {
@@ -139,6 +163,14 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 
 	addResult.hostSubnetPrefix = *hostIPNet
 
+	for i := range response.SecondaryInterfaces {
+		secondaryInterface, err := getSecondaryInterfaceInfo(&response.SecondaryInterfaces[i])
+		if err != nil {
+			return IPAMAddResult{}, err
+		}
+		addResult.secondaryInterfaces = append(addResult.secondaryInterfaces, secondaryInterface)
+	}
+
 	// set subnet prefix for host vm
 	// setHostOptions will execute if IPAM mode is not v4 overlay
 	if invoker.ipamMode != util.V4Overlay {
@@ -150,6 +182,44 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 	return addResult, nil
 }
 
+// getSecondaryInterfaceInfo converts a secondary interface of the pod from CNS to the IP, MAC and routes of its endpoint.
+func getSecondaryInterfaceInfo(podIPInfo *cns.PodIpInfo) (SecondaryInterfaceInfo, error) {
+	ip, ipNet, err := net.ParseCIDR(podIPInfo.PodIPConfig.IPAddress + "/" + fmt.Sprint(podIPInfo.PodIPConfig.PrefixLength))
+	if err != nil {
+		return SecondaryInterfaceInfo{}, errors.Wrapf(err, "unable to parse IP %s of NC %s", podIPInfo.PodIPConfig.IPAddress, podIPInfo.NetworkContainerID)
+	}
+
+	macAddress, err := net.ParseMAC(podIPInfo.MacAddress)
+	if err != nil {
+		return SecondaryInterfaceInfo{}, errors.Wrapf(err, "unable to parse MAC address %s of NC %s", podIPInfo.MacAddress, podIPInfo.NetworkContainerID)
+	}
+
+	gw := net.ParseIP(podIPInfo.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+	result := &cniTypesCurr.Result{
+		IPs: []*cniTypesCurr.IPConfig{
+			{
+				Address: net.IPNet{IP: ip, Mask: ipNet.Mask},
+				Gateway: gw,
+			},
+		},
+	}
+
+	// the default route stays on the primary interface, a secondary interface only gets the routes of its NC
+	for _, route := range podIPInfo.Routes {
+		_, dst, err := net.ParseCIDR(route.IPAddress)
+		if err != nil {
+			return SecondaryInterfaceInfo{}, errors.Wrapf(err, "unable to parse route %s of NC %s", route.IPAddress, podIPInfo.NetworkContainerID)
+		}
+		result.Routes = append(result.Routes, &cniTypes.Route{Dst: *dst, GW: net.ParseIP(route.GatewayIPAddress)})
+	}
+
+	return SecondaryInterfaceInfo{
+		ncID:       podIPInfo.NetworkContainerID,
+		macAddress: macAddress,
+		result:     result,
+	}, nil
+}
+
 func setHostOptions(ncSubnetPrefix *net.IPNet, options map[string]interface{}, info *IPv4ResultInfo) error {
 	// get the host ip
 	hostIP := net.ParseIP(info.hostPrimaryIP)
@@ -217,10 +287,14 @@ func (invoker *CNSIPAMInvoker) Delete(address *net.IPNet, nwCfg *cni.NetworkConf
 	if address != nil {
 		req.DesiredIPAddress = address.IP.String()
 	} else {
-		log.Printf("CNS invoker called with empty IP address")
+		logger.Infof("CNS invoker called with empty IP address")
 	}
 
-	if err := invoker.cnsClient.ReleaseIPAddress(context.TODO(), req); err != nil {
+	ctx, span := tracing.StartSpan(invoker.requestContext(args.ContainerID), "cni.ReleaseIPAddress",
+		tracing.WithKind(tracing.SpanKindClient))
+	err = invoker.cnsClient.ReleaseIPAddress(ctx, req)
+	span.End(err)
+	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("failed to release IP %v with err ", address)+"%w")
 	}
 
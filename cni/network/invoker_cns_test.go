@@ -1,18 +1,22 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"net"
 	"testing"
 
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
 	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/network"
+	"github.com/Azure/azure-container-networking/tracing"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 var testPodInfo cns.KubernetesPodInfo
@@ -319,3 +323,42 @@ func Test_setHostOptions(t *testing.T) {
 		})
 	}
 }
+
+// ctxRecordingCNSClient records the context of the CNS requests it fails.
+type ctxRecordingCNSClient struct {
+	MockCNSClient
+	ctx context.Context
+}
+
+func (c *ctxRecordingCNSClient) RequestIPAddress(ctx context.Context, _ cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	c.ctx = ctx
+	return nil, errors.New("failed error from CNS") //nolint "error for ut"
+}
+
+func TestCNSIPAMInvoker_AddPropagatesTrace(t *testing.T) {
+	require := require.New(t) //nolint further usage of require without passing t
+	tracing.Init(tracing.NewLogExporter(zap.NewNop()))
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	ctx, span := tracing.StartSpan(context.Background(), "cni.ADD")
+	defer span.End(nil)
+
+	client := &ctxRecordingCNSClient{}
+	invoker := NewCNSInvoker(ctx, testPodInfo.PodName, testPodInfo.PodNamespace, "testinvocationid", client, "", "")
+	_, err := invoker.Add(IPAMAddConfig{
+		nwCfg:   &cni.NetworkConfig{},
+		args:    &cniSkel.CmdArgs{ContainerID: "testcontainerid", IfName: "testifname"},
+		options: map[string]interface{}{},
+	})
+	require.Error(err)
+
+	// the request carries a client span in the trace of the command.
+	sc := tracing.SpanContextFromContext(client.ctx)
+	require.Equal(span.SpanContext().TraceID, sc.TraceID)
+	require.NotEqual(span.SpanContext().SpanID, sc.SpanID)
+
+	inv, ok := cnscli.InvocationFromContext(client.ctx)
+	require.True(ok)
+	require.Equal("testinvocationid", inv.ID)
+	require.Equal("testcontainerid", inv.ContainerID)
+}
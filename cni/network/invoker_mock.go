@@ -27,6 +27,8 @@ type MockIpamInvoker struct {
 	v4Fail bool
 	v6Fail bool
 	ipMap  map[string]bool
+	// secondaryInterfaces are returned by Add as the secondary interfaces of the pod.
+	secondaryInterfaces []SecondaryInterfaceInfo
 }
 
 func NewMockIpamInvoker(ipv6, v4Fail, v6Fail bool) *MockIpamInvoker {
@@ -57,6 +59,7 @@ func (invoker *MockIpamInvoker) Add(opt IPAMAddConfig) (ipamAddResult IPAMAddRes
 	ipamAddResult.ipv4Result = &current.Result{}
 	ipamAddResult.ipv4Result.IPs = append(ipamAddResult.ipv4Result.IPs, ipConfig)
 	invoker.ipMap[ipnet.String()] = true
+	ipamAddResult.secondaryInterfaces = invoker.secondaryInterfaces
 	if invoker.v6Fail {
 		return ipamAddResult, errV6
 	}
@@ -15,7 +15,6 @@ import (
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/common"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/network"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
@@ -85,7 +84,7 @@ func (m *Multitenancy) DetermineSnatFeatureOnHost(snatFile, nmAgentSupportedApis
 		bytes, _ := io.ReadAll(jsonFile)
 		jsonFile.Close()
 		if retrieveSnatConfigErr = json.Unmarshal(bytes, &snatConfig); retrieveSnatConfigErr != nil {
-			log.Errorf("[cni-net] failed to unmarshal to snatConfig with error %v",
+			logger.Errorf("failed to unmarshal to snatConfig with error %v",
 				retrieveSnatConfigErr)
 		}
 	}
@@ -95,10 +94,10 @@ func (m *Multitenancy) DetermineSnatFeatureOnHost(snatFile, nmAgentSupportedApis
 		var resp *http.Response
 		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, nmAgentSupportedApisURL, nil)
 		if err != nil {
-			log.Errorf("failed creating http request:%+v", err)
+			logger.Errorf("failed creating http request:%+v", err)
 			return false, false, fmt.Errorf("%w", err)
 		}
-		log.Printf("Query nma for dns snat support: %s", nmAgentSupportedApisURL)
+		logger.Infof("Query nma for dns snat support: %s", nmAgentSupportedApisURL)
 		resp, retrieveSnatConfigErr = httpClient.Do(req)
 		if retrieveSnatConfigErr == nil {
 			defer resp.Body.Close()
@@ -118,11 +117,11 @@ func (m *Multitenancy) DetermineSnatFeatureOnHost(snatFile, nmAgentSupportedApis
 					if err == nil {
 						_, err = fp.Write(jsonStr)
 						if err != nil {
-							log.Errorf("DetermineSnatFeatureOnHost: Write to json failed:%+v", err)
+							logger.Errorf("DetermineSnatFeatureOnHost: Write to json failed:%+v", err)
 						}
 						fp.Close()
 					} else {
-						log.Errorf("[cni-net] failed to save snat settings to %s with error: %+v", snatConfigFile, err)
+						logger.Errorf("failed to save snat settings to %s with error: %+v", snatConfigFile, err)
 					}
 				}
 			} else {
@@ -133,20 +132,20 @@ func (m *Multitenancy) DetermineSnatFeatureOnHost(snatFile, nmAgentSupportedApis
 
 	// Log and return the error when we fail acquire snat configuration for host and dns
 	if retrieveSnatConfigErr != nil {
-		log.Errorf("[cni-net] failed to acquire SNAT configuration with error %v",
+		logger.Errorf("failed to acquire SNAT configuration with error %v",
 			retrieveSnatConfigErr)
 		return snatConfig.EnableSnatForDns, snatConfig.EnableSnatOnHost, retrieveSnatConfigErr
 	}
 
-	log.Printf("[cni-net] saved snat settings %+v to %s", snatConfig, snatConfigFile)
+	logger.Infof("saved snat settings %+v to %s", snatConfig, snatConfigFile)
 	if snatConfig.EnableSnatOnHost {
-		log.Printf("[cni-net] enabling SNAT on container host for outbound connectivity")
+		logger.Infof("enabling SNAT on container host for outbound connectivity")
 	}
 	if snatConfig.EnableSnatForDns {
-		log.Printf("[cni-net] enabling SNAT on container host for DNS traffic")
+		logger.Infof("enabling SNAT on container host for DNS traffic")
 	}
 	if !snatConfig.EnableSnatForDns && !snatConfig.EnableSnatOnHost {
-		log.Printf("[cni-net] disabling SNAT on container host")
+		logger.Infof("disabling SNAT on container host")
 	}
 
 	return snatConfig.EnableSnatForDns, snatConfig.EnableSnatOnHost, nil
@@ -162,7 +161,7 @@ func (m *Multitenancy) SetupRoutingForMultitenancy(
 	// Adding default gateway
 	// if snat enabled, add 169.254.128.1 as default gateway
 	if nwCfg.EnableSnatOnHost {
-		log.Printf("add default route for multitenancy.snat on host enabled")
+		logger.Infof("add default route for multitenancy.snat on host enabled")
 		addDefaultRoute(cnsNetworkConfig.LocalIPConfiguration.GatewayIPAddress, epInfo, result)
 	} else {
 		_, defaultIPNet, _ := net.ParseCIDR("0.0.0.0/0")
@@ -172,7 +171,7 @@ func (m *Multitenancy) SetupRoutingForMultitenancy(
 		result.Routes = append(result.Routes, &cniTypes.Route{Dst: dstIP, GW: gwIP})
 
 		if epInfo.EnableSnatForDns {
-			log.Printf("add SNAT for DNS enabled")
+			logger.Infof("add SNAT for DNS enabled")
 			addSnatForDNS(cnsNetworkConfig.LocalIPConfiguration.GatewayIPAddress, epInfo, result)
 		}
 	}
@@ -191,11 +190,11 @@ func (m *Multitenancy) GetContainerNetworkConfiguration(
 		podNameWithoutSuffix = podName
 	}
 
-	log.Printf("Podname without suffix %v", podNameWithoutSuffix)
+	logger.Infof("Podname without suffix %v", podNameWithoutSuffix)
 	ncResponse, hostSubnetPrefix, err := m.getContainerNetworkConfigurationInternal(ctx, podNamespace, podNameWithoutSuffix)
 	if nwCfg.EnableSnatOnHost {
 		if ncResponse.LocalIPConfiguration.IPSubnet.IPAddress == "" {
-			log.Printf("Snat IP is not populated. Got empty string")
+			logger.Infof("Snat IP is not populated. Got empty string")
 			return nil, net.IPNet{}, errSnatIP
 		}
 	}
@@ -213,22 +212,22 @@ func (m *Multitenancy) getContainerNetworkConfigurationInternal(
 
 	orchestratorContext, err := json.Marshal(podInfo)
 	if err != nil {
-		log.Printf("Marshalling KubernetesPodInfo failed with %v", err)
+		logger.Infof("Marshalling KubernetesPodInfo failed with %v", err)
 		return nil, net.IPNet{}, fmt.Errorf("%w", err)
 	}
 
 	networkConfig, err := m.cnsclient.GetNetworkConfiguration(ctx, orchestratorContext)
 	if err != nil {
-		log.Printf("GetNetworkConfiguration failed with %v", err)
+		logger.Infof("GetNetworkConfiguration failed with %v", err)
 		return nil, net.IPNet{}, fmt.Errorf("%w", err)
 	}
 
-	log.Printf("Network config received from cns %+v", networkConfig)
+	logger.Infof("Network config received from cns %+v", networkConfig)
 
 	subnetPrefix := m.netioshim.GetInterfaceSubnetWithSpecificIP(networkConfig.PrimaryInterfaceIdentifier)
 	if subnetPrefix == nil {
 		errBuf := fmt.Errorf("%w %s", errIfaceNotFound, networkConfig.PrimaryInterfaceIdentifier)
-		log.Printf(errBuf.Error())
+		logger.Infof(errBuf.Error())
 		return nil, net.IPNet{}, errBuf
 	}
 
@@ -281,7 +280,7 @@ func getInfraVnetIP(
 		_, ipNet, _ := net.ParseCIDR(infraSubnet)
 		nwCfg.Ipam.Subnet = ipNet.String()
 
-		log.Printf("call ipam to allocate ip from subnet %v", nwCfg.Ipam.Subnet)
+		logger.Infof("call ipam to allocate ip from subnet %v", nwCfg.Ipam.Subnet)
 		ipamAddOpt := IPAMAddConfig{nwCfg: nwCfg, options: make(map[string]interface{})}
 		ipamAddResult, err := plugin.ipamInvoker.Add(ipamAddOpt)
 		if err != nil {
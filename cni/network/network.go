@@ -9,31 +9,40 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/api"
+	cnilog "github.com/Azure/azure-container-networking/cni/log"
 	"github.com/Azure/azure-container-networking/cni/util"
 	"github.com/Azure/azure-container-networking/cns"
 	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/iptables"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network"
 	"github.com/Azure/azure-container-networking/network/policy"
+	"github.com/Azure/azure-container-networking/ovsctl"
 	"github.com/Azure/azure-container-networking/platform"
 	nnscontracts "github.com/Azure/azure-container-networking/proto/nodenetworkservice/3.302.0.744"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/Azure/azure-container-networking/telemetry"
+	"github.com/Azure/azure-container-networking/tracing"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
+var logger = cnilog.CNILogger.With(zap.String(cnilog.ComponentKey, "cni-net")).Sugar()
+
 const (
 	dockerNetworkOption = "com.docker.network.generic"
 	opModeTransparent   = "transparent"
@@ -73,6 +82,7 @@ type NetPlugin struct {
 	tb                 *telemetry.TelemetryBuffer
 	nnsClient          NnsClient
 	multitenancyClient MultitenancyClient
+	invocationID       string
 }
 
 type PolicyArgs struct {
@@ -121,14 +131,29 @@ func NewPlugin(name string,
 
 	config.NetApi = nm
 
+	// A plugin process serves a single CNI command, so the invocation ID identifies the
+	// command in the log entries of every package and in the requests sent to CNS.
+	invocationID := uuid.New().String()
+	cnilog.SetInvocationFields(zap.String(cnilog.InvocationIDKey, invocationID))
+
 	return &NetPlugin{
 		Plugin:             plugin,
 		nm:                 nm,
 		nnsClient:          client,
 		multitenancyClient: multitenancyClient,
+		invocationID:       invocationID,
 	}, nil
 }
 
+// setInvocationFields adds the container and pod the current command runs for to every log entry.
+func setInvocationFields(containerID, podName, podNamespace string) {
+	cnilog.SetInvocationFields(
+		zap.String(cnilog.ContainerIDKey, containerID),
+		zap.String(cnilog.PodNameKey, podName),
+		zap.String(cnilog.PodNamespaceKey, podNamespace),
+	)
+}
+
 func (plugin *NetPlugin) SetCNIReport(report *telemetry.CNIReport, tb *telemetry.TelemetryBuffer) {
 	plugin.report = report
 	plugin.tb = tb
@@ -139,31 +164,31 @@ func (plugin *NetPlugin) Start(config *common.PluginConfig) error {
 	// Initialize base plugin.
 	err := plugin.Initialize(config)
 	if err != nil {
-		log.Printf("[cni-net] Failed to initialize base plugin, err:%v.", err)
+		logger.Infof("Failed to initialize base plugin, err:%v.", err)
 		return err
 	}
 
 	// Log platform information.
-	log.Printf("[cni-net] Plugin %v version %v.", plugin.Name, plugin.Version)
-	log.Printf("[cni-net] Running on %v", platform.GetOSInfo())
+	logger.Infof("Plugin %v version %v.", plugin.Name, plugin.Version)
+	logger.Infof("Running on %v", platform.GetOSInfo())
 	platform.PrintDependencyPackageDetails()
 	common.LogNetworkInterfaces()
 
 	// Initialize network manager. rehyrdration not required on reboot for cni plugin
 	err = plugin.nm.Initialize(config, false)
 	if err != nil {
-		log.Printf("[cni-net] Failed to initialize network manager, err:%v.", err)
+		logger.Infof("Failed to initialize network manager, err:%v.", err)
 		return err
 	}
 
-	log.Printf("[cni-net] Plugin started.")
+	logger.Infof("Plugin started.")
 
 	return nil
 }
 
 // This function for sending CNI metrics to telemetry service
 func logAndSendEvent(plugin *NetPlugin, msg string) {
-	log.Printf(msg)
+	logger.Infof(msg)
 	sendEvent(plugin, msg)
 }
 
@@ -174,6 +199,12 @@ func sendEvent(plugin *NetPlugin, msg string) {
 	telemetry.SendCNIEvent(plugin.tb, plugin.report)
 }
 
+// ReconcileOrphans reports the host links and namespaces which belong to no endpoint in the state,
+// and removes them if deleteOrphans is set.
+func (plugin *NetPlugin) ReconcileOrphans(deleteOrphans bool) (*api.OrphanReport, error) {
+	return plugin.nm.ReconcileOrphans(deleteOrphans) //nolint:wrapcheck // the caller logs the error
+}
+
 func (plugin *NetPlugin) GetAllEndpointState(networkid string) (*api.AzureCNIState, error) {
 	st := api.AzureCNIState{
 		ContainerInterfaces: make(map[string]api.PodNetworkInterfaceInfo),
@@ -181,7 +212,7 @@ func (plugin *NetPlugin) GetAllEndpointState(networkid string) (*api.AzureCNISta
 
 	eps, err := plugin.nm.GetAllEndpoints(networkid)
 	if err == store.ErrStoreEmpty {
-		log.Printf("failed to retrieve endpoint state with err %v", err)
+		logger.Infof("failed to retrieve endpoint state with err %v", err)
 	} else if err != nil {
 		return nil, err
 	}
@@ -206,7 +237,7 @@ func (plugin *NetPlugin) GetAllEndpointState(networkid string) (*api.AzureCNISta
 func (plugin *NetPlugin) Stop() {
 	plugin.nm.Uninitialize()
 	plugin.Uninitialize()
-	log.Printf("[cni-net] Plugin stopped.")
+	logger.Infof("Plugin stopped.")
 }
 
 // FindMasterInterface returns the name of the master interface.
@@ -247,21 +278,21 @@ func GetEndpointID(args *cniSkel.CmdArgs) string {
 func (plugin *NetPlugin) getPodInfo(args string) (name, ns string, err error) {
 	podCfg, err := cni.ParseCniArgs(args)
 	if err != nil {
-		log.Printf("Error while parsing CNI Args %v", err)
+		logger.Infof("Error while parsing CNI Args %v", err)
 		return "", "", err
 	}
 
 	k8sNamespace := string(podCfg.K8S_POD_NAMESPACE)
 	if len(k8sNamespace) == 0 {
 		errMsg := "Pod Namespace not specified in CNI Args"
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return "", "", plugin.Errorf(errMsg)
 	}
 
 	k8sPodName := string(podCfg.K8S_POD_NAME)
 	if len(k8sPodName) == 0 {
 		errMsg := "Pod Name not specified in CNI Args"
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return "", "", plugin.Errorf(errMsg)
 	}
 
@@ -270,7 +301,7 @@ func (plugin *NetPlugin) getPodInfo(args string) (name, ns string, err error) {
 
 func SetCustomDimensions(cniMetric *telemetry.AIMetric, nwCfg *cni.NetworkConfig, err error) {
 	if cniMetric == nil {
-		log.Errorf("[CNI] Unable to set custom dimension. Report is nil")
+		logger.Errorf("[CNI] Unable to set custom dimension. Report is nil")
 		return
 	}
 
@@ -311,7 +342,7 @@ func addNatIPV6SubnetInfo(nwCfg *cni.NetworkConfig,
 			Prefix:  ipv6Subnet,
 			Gateway: resultV6.IPs[0].Gateway,
 		}
-		log.Printf("[net] ipv6 subnet info:%+v", ipv6SubnetInfo)
+		logger.Infof("ipv6 subnet info:%+v", ipv6SubnetInfo)
 		nwInfo.Subnets = append(nwInfo.Subnets, ipv6SubnetInfo)
 	}
 }
@@ -345,8 +376,14 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 	}
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	ovsctl.UseNativeClient = nwCfg.UseNativeOVSClient
 	plugin.setCNIReportDetails(nwCfg, CNI_ADD, "")
 
+	ctx, span := plugin.startTrace(nwCfg, CNI_ADD, args)
+	defer func() {
+		span.End(err)
+	}()
+
 	defer func() {
 		operationTimeMs := time.Since(startTime).Milliseconds()
 		cniMetric.Metric = aitelemetry.Metric{
@@ -372,11 +409,12 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 			ipamAddResult.ipv4Result.IPs = append(ipamAddResult.ipv4Result.IPs, ipamAddResult.ipv6Result.IPs...)
 		}
 
+		addSecondaryInterfaces(ipamAddResult.ipv4Result, ipamAddResult.secondaryInterfaces, args.IfName)
 		addSnatInterface(nwCfg, ipamAddResult.ipv4Result)
 		// Convert result to the requested CNI version.
-		res, vererr := ipamAddResult.ipv4Result.GetAsVersion(nwCfg.CNIVersion)
+		res, vererr := cni.GetAsVersion(ipamAddResult.ipv4Result, nwCfg.CNIVersion)
 		if vererr != nil {
-			log.Printf("GetAsVersion failed with error %v", vererr)
+			logger.Infof("GetAsVersion failed with error %v", vererr)
 			plugin.Error(vererr)
 		}
 
@@ -385,7 +423,7 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 			res.Print()
 		}
 
-		log.Printf("[cni-net] ADD command completed for pod %v with IPs:%+v err:%v.", k8sPodName, ipamAddResult.ipv4Result.IPs, err)
+		logger.Infof("ADD command completed for pod %v with IPs:%+v err:%v.", k8sPodName, ipamAddResult.ipv4Result.IPs, err)
 	}()
 
 	// Parse Pod arguments.
@@ -394,26 +432,29 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		return err
 	}
 
+	setInvocationFields(args.ContainerID, k8sPodName, k8sNamespace)
+	span.SetAttribute(spanAttrPodName, k8sPodName)
+	span.SetAttribute(spanAttrPodNamespace, k8sNamespace)
 	plugin.report.ContainerName = k8sPodName + ":" + k8sNamespace
 
 	k8sContainerID := args.ContainerID
 	if len(k8sContainerID) == 0 {
 		errMsg := "Container ID not specified in CNI Args"
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return plugin.Errorf(errMsg)
 	}
 
 	k8sIfName := args.IfName
 	if len(k8sIfName) == 0 {
 		errMsg := "Interfacename not specified in CNI Args"
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return plugin.Errorf(errMsg)
 	}
 
 	platformInit(nwCfg)
 	if nwCfg.ExecutionMode == string(util.Baremetal) {
 		var res *nnscontracts.ConfigureContainerNetworkingResponse
-		log.Printf("Baremetal mode. Calling vnet agent for ADD")
+		logger.Infof("Baremetal mode. Calling vnet agent for ADD")
 		res, err = plugin.nnsClient.AddContainerNetworking(context.Background(), k8sPodName, args.Netns)
 
 		if err == nil {
@@ -425,7 +466,7 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 
 	for _, ns := range nwCfg.PodNamespaceForDualNetwork {
 		if k8sNamespace == ns {
-			log.Printf("Enable infravnet for this pod %v in namespace %v", k8sPodName, k8sNamespace)
+			logger.Infof("Enable infravnet for this pod %v in namespace %v", k8sPodName, k8sNamespace)
 			enableInfraVnet = true
 			break
 		}
@@ -450,19 +491,19 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 			context.TODO(), nwCfg, k8sPodName, k8sNamespace)
 		if er != nil {
 			er = errors.Wrapf(er, "GetContainerNetworkConfiguration failed for podname %v namespace %v", k8sPodName, k8sNamespace)
-			log.Printf("%+v", er)
+			logger.Infof("%+v", er)
 			return er
 		}
 
 		ipamAddResult.ipv4Result = convertToCniResult(ipamAddResult.ncResponse, args.IfName)
 
-		log.Printf("PrimaryInterfaceIdentifier: %v", ipamAddResult.hostSubnetPrefix.IP.String())
+		logger.Infof("PrimaryInterfaceIdentifier: %v", ipamAddResult.hostSubnetPrefix.IP.String())
 	}
 
 	// Initialize values from network config.
 	networkID, err := plugin.getNetworkName(args.Netns, &ipamAddResult, nwCfg)
 	if err != nil {
-		log.Printf("[cni-net] Failed to extract network name from network config. error: %v", err)
+		logger.Infof("Failed to extract network name from network config. error: %v", err)
 		return err
 	}
 
@@ -478,14 +519,14 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 	 * Issue link: https://github.com/kubernetes/kubernetes/issues/57253
 	 */
 	if nwInfoErr == nil {
-		log.Printf("[cni-net] Found network %v with subnet %v.", networkID, nwInfo.Subnets[0].Prefix.String())
+		logger.Infof("Found network %v with subnet %v.", networkID, nwInfo.Subnets[0].Prefix.String())
 		nwInfo.IPAMType = nwCfg.Ipam.Type
 		options = nwInfo.Options
 
 		var resultSecondAdd *cniTypesCurr.Result
 		resultSecondAdd, err = plugin.handleConsecutiveAdd(args, endpointID, networkID, &nwInfo, nwCfg)
 		if err != nil {
-			log.Printf("handleConsecutiveAdd failed with error %v", err)
+			logger.Infof("handleConsecutiveAdd failed with error %v", err)
 			return err
 		}
 
@@ -499,7 +540,7 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 	if plugin.ipamInvoker == nil {
 		switch nwCfg.Ipam.Type {
 		case network.AzureCNS:
-			plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.Ipam.Mode))
+			plugin.ipamInvoker = NewCNSInvoker(ctx, k8sPodName, k8sNamespace, plugin.invocationID, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.Ipam.Mode))
 
 		default:
 			plugin.ipamInvoker = NewAzureIpamInvoker(plugin, &nwInfo)
@@ -509,7 +550,9 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 	ipamAddConfig := IPAMAddConfig{nwCfg: nwCfg, args: args, options: options}
 	// No need to call Add if we already got IPAMAddResult in multitenancy section via GetContainerNetworkConfiguration
 	if !nwCfg.MultiTenancy {
+		_, ipamSpan := tracing.StartSpan(ctx, "cni.ipamAdd")
 		ipamAddResult, err = plugin.ipamInvoker.Add(ipamAddConfig)
+		ipamSpan.End(err)
 		if err != nil {
 			return fmt.Errorf("IPAM Invoker Add failed with error: %w", err)
 		}
@@ -528,8 +571,11 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		// Network does not exist.
 		logAndSendEvent(plugin, fmt.Sprintf("[cni-net] Creating network %v.", networkID))
 		// opts map needs to get passed in here
-		if nwInfo, err = plugin.createNetworkInternal(networkID, policies, ipamAddConfig, ipamAddResult); err != nil {
-			log.Errorf("Create network failed: %w", err)
+		_, nwSpan := tracing.StartSpan(ctx, "cni.createNetwork", tracing.WithAttributes(map[string]string{spanAttrNetworkID: networkID}))
+		nwInfo, err = plugin.createNetworkInternal(networkID, policies, ipamAddConfig, ipamAddResult)
+		nwSpan.End(err)
+		if err != nil {
+			logger.Errorf("Create network failed: %w", err)
 			return err
 		}
 
@@ -554,12 +600,24 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		enableSnatForDNS: enableSnatForDNS,
 		natInfo:          natInfo,
 	}
+	_, epSpan := tracing.StartSpan(ctx, "cni.createEndpoint", tracing.WithAttributes(map[string]string{spanAttrEndpointID: endpointID}))
 	epInfo, err := plugin.createEndpointInternal(&createEndpointInternalOpt)
+	epSpan.End(err)
 	if err != nil {
-		log.Errorf("Endpoint creation failed:%w", err)
+		logger.Errorf("Endpoint creation failed:%w", err)
 		return err
 	}
 
+	if len(ipamAddResult.secondaryInterfaces) > 0 {
+		if err = plugin.createSecondaryEndpoints(createEndpointInternalOpt, ipamAddResult.secondaryInterfaces); err != nil {
+			logger.Errorf("Secondary endpoint creation failed:%v", err)
+			if delErr := plugin.nm.DeleteEndpoint(networkID, endpointID); delErr != nil {
+				logger.Errorf("Failed to delete endpoint %v on secondary endpoint creation failure:%v", endpointID, delErr)
+			}
+			return err
+		}
+	}
+
 	sendEvent(plugin, fmt.Sprintf("CNI ADD succeeded : IP:%+v, VlanID: %v, podname %v, namespace %v numendpoints:%d",
 		ipamAddResult.ipv4Result.IPs, epInfo.Data[network.VlanIDKey], k8sPodName, k8sNamespace, plugin.nm.GetNumberOfEndpoints("", nwCfg.Name)))
 
@@ -574,12 +632,12 @@ func (plugin *NetPlugin) cleanupAllocationOnError(
 ) {
 	if result != nil && len(result.IPs) > 0 {
 		if er := plugin.ipamInvoker.Delete(&result.IPs[0].Address, nwCfg, args, options); er != nil {
-			log.Errorf("Failed to cleanup ip allocation on failure: %v", er)
+			logger.Errorf("Failed to cleanup ip allocation on failure: %v", er)
 		}
 	}
 	if resultV6 != nil && len(resultV6.IPs) > 0 {
 		if er := plugin.ipamInvoker.Delete(&resultV6.IPs[0].Address, nwCfg, args, options); er != nil {
-			log.Errorf("Failed to cleanup ipv6 allocation on failure: %v", er)
+			logger.Errorf("Failed to cleanup ipv6 allocation on failure: %v", er)
 		}
 	}
 }
@@ -599,7 +657,7 @@ func (plugin *NetPlugin) createNetworkInternal(
 		err := plugin.Errorf("Failed to find the master interface")
 		return nwInfo, err
 	}
-	log.Printf("[cni-net] Found master interface %v.", masterIfName)
+	logger.Infof("Found master interface %v.", masterIfName)
 
 	// Add the master as an external interface.
 	err := plugin.nm.AddExternalInterface(masterIfName, ipamAddResult.hostSubnetPrefix.String())
@@ -614,7 +672,7 @@ func (plugin *NetPlugin) createNetworkInternal(
 		return nwInfo, err
 	}
 
-	log.Printf("[cni-net] nwDNSInfo: %v", nwDNSInfo)
+	logger.Infof("nwDNSInfo: %v", nwDNSInfo)
 
 	var podSubnetPrefix *net.IPNet
 	_, podSubnetPrefix, err = net.ParseCIDR(ipamAddResult.ipv4Result.IPs[0].Address.String())
@@ -645,6 +703,12 @@ func (plugin *NetPlugin) createNetworkInternal(
 		IPV6Mode:                      ipamAddConfig.nwCfg.IPV6Mode,
 		IPAMType:                      ipamAddConfig.nwCfg.Ipam.Type,
 		ServiceCidrs:                  ipamAddConfig.nwCfg.ServiceCidrs,
+		MTU:                           ipamAddConfig.nwCfg.MTU,
+		Offload: network.OffloadSettings{
+			TSO: ipamAddConfig.nwCfg.Offload.TSO,
+			GSO: ipamAddConfig.nwCfg.Offload.GSO,
+			GRO: ipamAddConfig.nwCfg.Offload.GRO,
+		},
 	}
 
 	setNetworkOptions(ipamAddResult.ncResponse, &nwInfo)
@@ -674,6 +738,10 @@ type createEndpointInternalOpt struct {
 	enableInfraVnet  bool
 	enableSnatForDNS bool
 	natInfo          []policy.NATInfo
+	// ifIndex is the index of the pod interface of the endpoint, 0 for the primary interface.
+	ifIndex    int
+	macAddress net.HardwareAddr
+	ncID       string
 }
 
 func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt) (network.EndpointInfo, error) {
@@ -691,7 +759,7 @@ func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt)
 	}
 	endpointPolicies, err := getEndpointPolicies(policyArgs)
 	if err != nil {
-		log.Errorf("Failed to get endpoint policies:%v", err)
+		logger.Errorf("Failed to get endpoint policies:%v", err)
 		return epInfo, err
 	}
 
@@ -703,6 +771,8 @@ func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt)
 		// IT will result in unpredictable behavior if API server decides to
 		// reorder DELETE and ADD call for new incarnation of same POD.
 		vethName = fmt.Sprintf("%s%s%s", opt.nwInfo.Id, opt.args.ContainerID, opt.args.IfName)
+	} else if opt.ifIndex > 0 {
+		vethName = fmt.Sprintf("%s.%s", vethName, opt.args.IfName)
 	}
 
 	epInfo = network.EndpointInfo{
@@ -725,6 +795,8 @@ func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt)
 		VnetCidrs:          opt.nwCfg.VnetCidrs,
 		ServiceCidrs:       opt.nwCfg.ServiceCidrs,
 		NATInfo:            opt.natInfo,
+		MacAddress:         opt.macAddress,
+		NetworkContainerID: opt.ncID,
 	}
 
 	epPolicies := getPoliciesFromRuntimeCfg(opt.nwCfg)
@@ -758,7 +830,7 @@ func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt)
 
 	cnsclient, err := cnscli.New(opt.nwCfg.CNSUrl, defaultRequestTimeout)
 	if err != nil {
-		log.Printf("failed to initialized cns client with URL %s: %v", opt.nwCfg.CNSUrl, err.Error())
+		logger.Infof("failed to initialized cns client with URL %s: %v", opt.nwCfg.CNSUrl, err.Error())
 		return epInfo, plugin.Errorf(err.Error())
 	}
 
@@ -772,6 +844,81 @@ func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt)
 	return epInfo, err
 }
 
+// createSecondaryEndpoints creates an endpoint for each secondary interface of a pod, on the interfaces after the
+// primary one of opt. The endpoints already created are deleted if one fails.
+func (plugin *NetPlugin) createSecondaryEndpoints(opt createEndpointInternalOpt, secondaryInterfaces []SecondaryInterfaceInfo) error {
+	for i, secondaryInterface := range secondaryInterfaces {
+		args := *opt.args
+		args.IfName = secondaryIfName(opt.args.IfName, i+1)
+
+		secondaryOpt := opt
+		secondaryOpt.args = &args
+		secondaryOpt.endpointID = GetEndpointID(&args)
+		secondaryOpt.result = secondaryInterface.result
+		secondaryOpt.resultV6 = nil
+		secondaryOpt.azIpamResult = nil
+		secondaryOpt.cnsNetworkConfig = nil
+		secondaryOpt.natInfo = nil
+		secondaryOpt.ifIndex = i + 1
+		secondaryOpt.macAddress = secondaryInterface.macAddress
+		secondaryOpt.ncID = secondaryInterface.ncID
+
+		if _, err := plugin.createEndpointInternal(&secondaryOpt); err != nil {
+			if delErr := plugin.deleteSecondaryEndpoints(opt.nwInfo.Id, opt.args); delErr != nil {
+				logger.Errorf("Failed to delete secondary endpoints on creation failure:%v", delErr)
+			}
+			return errors.Wrapf(err, "failed to create endpoint of interface %s on NC %s", args.IfName, secondaryInterface.ncID)
+		}
+	}
+
+	return nil
+}
+
+// deleteSecondaryEndpoints deletes the endpoints of the secondary interfaces of a pod, which are on the interfaces
+// following the primary one until the first that has no endpoint.
+func (plugin *NetPlugin) deleteSecondaryEndpoints(networkID string, primaryArgs *cniSkel.CmdArgs) error {
+	for i := 1; ; i++ {
+		args := *primaryArgs
+		args.IfName = secondaryIfName(primaryArgs.IfName, i)
+		endpointID := GetEndpointID(&args)
+
+		if _, err := plugin.nm.GetEndpointInfo(networkID, endpointID); err != nil {
+			return nil
+		}
+
+		logAndSendEvent(plugin, fmt.Sprintf("Deleting secondary endpoint:%v", endpointID))
+		if err := plugin.nm.DeleteEndpoint(networkID, endpointID); err != nil {
+			return errors.Wrapf(err, "failed to delete secondary endpoint %s", endpointID)
+		}
+	}
+}
+
+// secondaryIfName returns the name of the interface at index after the primary interface of a pod, eth1 for
+// index 1 of eth0.
+func secondaryIfName(primaryIfName string, index int) string {
+	prefix := strings.TrimRight(primaryIfName, "0123456789")
+	primaryIndex, _ := strconv.Atoi(primaryIfName[len(prefix):])
+	return prefix + strconv.Itoa(primaryIndex+index)
+}
+
+// addSecondaryInterfaces adds the secondary interfaces of a pod and their IPs to the result of the primary interface.
+func addSecondaryInterfaces(result *cniTypesCurr.Result, secondaryInterfaces []SecondaryInterfaceInfo, primaryIfName string) {
+	for i, secondaryInterface := range secondaryInterfaces {
+		result.Interfaces = append(result.Interfaces, &cniTypesCurr.Interface{
+			Name: secondaryIfName(primaryIfName, i+1),
+			Mac:  secondaryInterface.macAddress.String(),
+		})
+
+		ifIndex := len(result.Interfaces) - 1
+		for _, ipconfig := range secondaryInterface.result.IPs {
+			ip := *ipconfig
+			ip.Interface = &ifIndex
+			result.IPs = append(result.IPs, &ip)
+		}
+		result.Routes = append(result.Routes, secondaryInterface.result.Routes...)
+	}
+}
+
 // Get handles CNI Get commands.
 func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 	var (
@@ -783,7 +930,7 @@ func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 		networkID string
 	)
 
-	log.Printf("[cni-net] Processing GET command with args {ContainerID:%v Netns:%v IfName:%v Args:%v Path:%v}.",
+	logger.Infof("Processing GET command with args {ContainerID:%v Netns:%v IfName:%v Args:%v Path:%v}.",
 		args.ContainerID, args.Netns, args.IfName, args.Args, args.Path)
 
 	defer func() {
@@ -794,9 +941,9 @@ func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 		result.Interfaces = append(result.Interfaces, iface)
 
 		// Convert result to the requested CNI version.
-		res, vererr := result.GetAsVersion(nwCfg.CNIVersion)
+		res, vererr := cni.GetAsVersion(&result, nwCfg.CNIVersion)
 		if vererr != nil {
-			log.Printf("GetAsVersion failed with error %v", vererr)
+			logger.Infof("GetAsVersion failed with error %v", vererr)
 			plugin.Error(vererr)
 		}
 
@@ -805,7 +952,7 @@ func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 			res.Print()
 		}
 
-		log.Printf("[cni-net] GET command completed with result:%+v err:%v.", result, err)
+		logger.Infof("GET command completed with result:%+v err:%v.", result, err)
 	}()
 
 	// Parse network configuration from stdin.
@@ -814,14 +961,15 @@ func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 		return err
 	}
 
-	log.Printf("[cni-net] Read network configuration %+v.", nwCfg)
+	logger.Infof("Read network configuration %+v.", nwCfg)
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	ovsctl.UseNativeClient = nwCfg.UseNativeOVSClient
 
 	// Initialize values from network config.
 	if networkID, err = plugin.getNetworkName(args.Netns, nil, nwCfg); err != nil {
 		// TODO: Ideally we should return from here only.
-		log.Printf("[cni-net] Failed to extract network name from network config. error: %v", err)
+		logger.Infof("Failed to extract network name from network config. error: %v", err)
 	}
 
 	endpointID := GetEndpointID(args)
@@ -880,7 +1028,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 		args.ContainerID, args.Netns, args.IfName, args.Args, args.Path, args.StdinData))
 
 	defer func() {
-		log.Printf("[cni-net] DEL command completed for pod %v with err:%v.", k8sPodName, err)
+		logger.Infof("DEL command completed for pod %v with err:%v.", k8sPodName, err)
 	}()
 
 	// Parse network configuration from stdin.
@@ -891,13 +1039,22 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 
 	// Parse Pod arguments.
 	if k8sPodName, k8sNamespace, err = plugin.getPodInfo(args.Args); err != nil {
-		log.Printf("[cni-net] Failed to get POD info due to error: %v", err)
+		logger.Infof("Failed to get POD info due to error: %v", err)
 	}
+	setInvocationFields(args.ContainerID, k8sPodName, k8sNamespace)
+
+	ctx, span := plugin.startTrace(nwCfg, CNI_DEL, args)
+	span.SetAttribute(spanAttrPodName, k8sPodName)
+	span.SetAttribute(spanAttrPodNamespace, k8sNamespace)
+	defer func() {
+		span.End(err)
+	}()
 
 	plugin.setCNIReportDetails(nwCfg, CNI_DEL, "")
 	plugin.report.ContainerName = k8sPodName + ":" + k8sNamespace
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	ovsctl.UseNativeClient = nwCfg.UseNativeOVSClient
 
 	sendMetricFunc := func() {
 		operationTimeMs := time.Since(startTime).Milliseconds()
@@ -913,10 +1070,10 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 
 	platformInit(nwCfg)
 
-	log.Printf("Execution mode :%s", nwCfg.ExecutionMode)
+	logger.Infof("Execution mode :%s", nwCfg.ExecutionMode)
 	if nwCfg.ExecutionMode == string(util.Baremetal) {
 
-		log.Printf("Baremetal mode. Calling vnet agent for delete container")
+		logger.Infof("Baremetal mode. Calling vnet agent for delete container")
 
 		// schedule send metric before attempting delete
 		defer sendMetricFunc()
@@ -931,10 +1088,10 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 		case network.AzureCNS:
 			cnsClient, cnsErr := cnscli.New("", defaultRequestTimeout)
 			if cnsErr != nil {
-				log.Printf("[cni-net] failed to create cns client:%v", cnsErr)
+				logger.Infof("failed to create cns client:%v", cnsErr)
 				return errors.Wrap(cnsErr, "failed to create cns client")
 			}
-			plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.Ipam.Mode))
+			plugin.ipamInvoker = NewCNSInvoker(ctx, k8sPodName, k8sNamespace, plugin.invocationID, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.Ipam.Mode))
 
 		default:
 			plugin.ipamInvoker = NewAzureIpamInvoker(plugin, &nwInfo)
@@ -944,7 +1101,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	// Initialize values from network config.
 	networkID, err = plugin.getNetworkName(args.Netns, nil, nwCfg)
 	if err != nil {
-		log.Printf("[cni-net] Failed to extract network name from network config. error: %v", err)
+		logger.Infof("Failed to extract network name from network config. error: %v", err)
 		// If error is not found error, then we ignore it, to comply with CNI SPEC.
 		if !network.IsNetworkNotFoundError(err) {
 			err = plugin.Errorf("Failed to extract network name from network config. error: %v", err)
@@ -955,7 +1112,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	// Query the network.
 	if nwInfo, err = plugin.nm.GetNetworkInfo(networkID); err != nil {
 		if !nwCfg.MultiTenancy {
-			log.Printf("[cni-net] Failed to query network:%s: %v", networkID, err)
+			logger.Infof("Failed to query network:%s: %v", networkID, err)
 			// Log the error but return success if the network is not found.
 			// if cni hits this, mostly state file would be missing and it can be reboot scenario where
 			// container runtime tries to delete and create pods which existed before reboot.
@@ -971,7 +1128,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 		if !nwCfg.MultiTenancy {
 			// attempt to release address associated with this Endpoint id
 			// This is to ensure clean up is done even in failure cases
-			log.Printf("[cni-net] Failed to query endpoint %s: %v", endpointID, err)
+			logger.Infof("Failed to query endpoint %s: %v", endpointID, err)
 			logAndSendEvent(plugin, fmt.Sprintf("Release ip by ContainerID (endpoint not found):%v", args.ContainerID))
 			if err = plugin.ipamInvoker.Delete(nil, nwCfg, args, nwInfo.Options); err != nil {
 				return plugin.RetriableError(fmt.Errorf("failed to release address(no endpoint): %w", err))
@@ -994,6 +1151,10 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 		return plugin.RetriableError(fmt.Errorf("failed to delete endpoint: %w", err))
 	}
 
+	if err = plugin.deleteSecondaryEndpoints(networkID, args); err != nil {
+		return plugin.RetriableError(err)
+	}
+
 	if !nwCfg.MultiTenancy {
 		// Call into IPAM plugin to release the endpoint's addresses.
 		for _, address := range epInfo.IPAddresses {
@@ -1017,6 +1178,135 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	return err
 }
 
+// GC handles CNI GC commands. It deletes the endpoints of the network whose container has no valid attachment,
+// including the endpoints of the secondary interfaces of the container, and releases their IPs.
+func (plugin *NetPlugin) GC(args *cniSkel.CmdArgs) error {
+	nwCfg, err := cni.ParseNetworkConfig(args.StdinData)
+	if err != nil {
+		return plugin.Errorf("Failed to parse network configuration: %v", err)
+	}
+
+	logger.Infof("Processing GC command for network %v with %d valid attachments.", nwCfg.Name, len(nwCfg.ValidAttachments))
+	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	ovsctl.UseNativeClient = nwCfg.UseNativeOVSClient
+	platformInit(nwCfg)
+
+	validContainers := make(map[string]bool)
+	for _, attachment := range nwCfg.ValidAttachments {
+		validContainers[attachment.ContainerID] = true
+	}
+
+	// only the network of the config is collected, the networks of multitenant pods are named after their NC
+	networkID := nwCfg.Name
+	nwInfo, err := plugin.nm.GetNetworkInfo(networkID)
+	if err != nil {
+		logger.Infof("No network %v to collect: %v", networkID, err)
+		return nil
+	}
+
+	endpoints, err := plugin.nm.GetAllEndpoints(networkID)
+	if err != nil {
+		return plugin.RetriableError(fmt.Errorf("failed to get endpoints of network %s: %w", networkID, err))
+	}
+
+	for endpointID, epInfo := range endpoints {
+		if validContainers[epInfo.ContainerID] {
+			continue
+		}
+
+		logAndSendEvent(plugin, fmt.Sprintf("[cni-net] GC deleting endpoint %v of container %v", endpointID, epInfo.ContainerID))
+		if err = plugin.nm.DeleteEndpoint(networkID, endpointID); err != nil {
+			return plugin.RetriableError(fmt.Errorf("failed to delete endpoint %s: %w", endpointID, err))
+		}
+
+		// the IPs of multitenant pods and secondary interfaces are the ones of their NC
+		if nwCfg.MultiTenancy || epInfo.NetworkContainerID != "" {
+			continue
+		}
+
+		if err = plugin.releaseEndpointAddresses(nwCfg, &nwInfo, epInfo); err != nil {
+			return plugin.RetriableError(err)
+		}
+	}
+
+	return nil
+}
+
+// releaseEndpointAddresses releases the IPs of an endpoint deleted without a CNI DEL.
+func (plugin *NetPlugin) releaseEndpointAddresses(nwCfg *cni.NetworkConfig, nwInfo *network.NetworkInfo, epInfo *network.EndpointInfo) error {
+	// the interface name is the end of the endpoint ID built by GetEndpointID
+	args := &cniSkel.CmdArgs{
+		ContainerID: epInfo.ContainerID,
+		Netns:       epInfo.NetNsPath,
+		StdinData:   nwCfg.Serialize(),
+	}
+	args.IfName = strings.TrimPrefix(epInfo.Id, GetEndpointID(args))
+
+	ipamInvoker := plugin.ipamInvoker
+	if ipamInvoker == nil {
+		switch nwCfg.Ipam.Type {
+		case network.AzureCNS:
+			cnsClient, err := cnscli.New(nwCfg.CNSUrl, defaultRequestTimeout)
+			if err != nil {
+				return errors.Wrap(err, "failed to create cns client")
+			}
+			ipamInvoker = NewCNSInvoker(context.TODO(), epInfo.PODName, epInfo.PODNameSpace, plugin.invocationID, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.Ipam.Mode))
+
+		default:
+			ipamInvoker = NewAzureIpamInvoker(plugin, nwInfo)
+		}
+	}
+
+	for i := range epInfo.IPAddresses {
+		logAndSendEvent(plugin, fmt.Sprintf("GC release ip:%s", epInfo.IPAddresses[i].IP.String()))
+		if err := ipamInvoker.Delete(&epInfo.IPAddresses[i], nwCfg, args, nwInfo.Options); err != nil {
+			return fmt.Errorf("failed to release address %s of endpoint %s: %w", epInfo.IPAddresses[i].String(), epInfo.Id, err)
+		}
+	}
+
+	return nil
+}
+
+// cnsIPStateClient is the part of the CNS client which STATUS uses.
+type cnsIPStateClient interface {
+	GetIPAddressesMatchingStates(ctx context.Context, stateFilter ...types.IPState) ([]cns.IPConfigurationStatus, error)
+}
+
+// Status handles CNI STATUS commands. The plugin is available unless its IPAM is CNS and CNS is not reachable or
+// has no IP to assign.
+func (plugin *NetPlugin) Status(args *cniSkel.CmdArgs) error {
+	nwCfg, err := cni.ParseNetworkConfig(args.StdinData)
+	if err != nil {
+		return plugin.Errorf("Failed to parse network configuration: %v", err)
+	}
+
+	if nwCfg.Ipam.Type != network.AzureCNS {
+		return nil
+	}
+
+	cnsClient, err := cnscli.New(nwCfg.CNSUrl, defaultRequestTimeout)
+	if err != nil {
+		return plugin.Errorf("Failed to create cns client: %v", err)
+	}
+
+	return cnsStatus(context.TODO(), cnsClient)
+}
+
+// cnsStatus returns an ErrPluginNotAvailable error unless CNS is reachable and has available IPs.
+func cnsStatus(ctx context.Context, client cnsIPStateClient) error {
+	available, err := client.GetIPAddressesMatchingStates(ctx, types.Available)
+	if err != nil {
+		return cniTypes.NewError(cni.ErrPluginNotAvailable, "CNS is not reachable", err.Error())
+	}
+
+	if len(available) == 0 {
+		return cniTypes.NewError(cni.ErrPluginNotAvailable, "CNS has no available IP", "")
+	}
+
+	logger.Infof("CNS has %d available IPs.", len(available))
+	return nil
+}
+
 // Update handles CNI update commands.
 // Update is only supported for multitenancy and to update routes.
 func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
@@ -1033,7 +1323,7 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 
 	startTime := time.Now()
 
-	log.Printf("[cni-net] Processing UPDATE command with args {Netns:%v Args:%v Path:%v}.",
+	logger.Infof("Processing UPDATE command with args {Netns:%v Args:%v Path:%v}.",
 		args.Netns, args.Args, args.Path)
 
 	// Parse network configuration from stdin.
@@ -1042,9 +1332,10 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 		return err
 	}
 
-	log.Printf("[cni-net] Read network configuration %+v.", nwCfg)
+	logger.Infof("Read network configuration %+v.", nwCfg)
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	ovsctl.UseNativeClient = nwCfg.UseNativeOVSClient
 	plugin.setCNIReportDetails(nwCfg, CNI_UPDATE, "")
 
 	defer func() {
@@ -1063,9 +1354,9 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 		}
 
 		// Convert result to the requested CNI version.
-		res, vererr := result.GetAsVersion(nwCfg.CNIVersion)
+		res, vererr := cni.GetAsVersion(result, nwCfg.CNIVersion)
 		if vererr != nil {
-			log.Printf("GetAsVersion failed with error %v", vererr)
+			logger.Infof("GetAsVersion failed with error %v", vererr)
 			plugin.Error(vererr)
 		}
 
@@ -1074,36 +1365,38 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 			res.Print()
 		}
 
-		log.Printf("[cni-net] UPDATE command completed with result:%+v err:%v.", result, err)
+		logger.Infof("UPDATE command completed with result:%+v err:%v.", result, err)
 	}()
 
 	// Parse Pod arguments.
 	if podCfg, err = cni.ParseCniArgs(args.Args); err != nil {
-		log.Printf("[cni-net] Error while parsing CNI Args during UPDATE %v", err)
+		logger.Infof("Error while parsing CNI Args during UPDATE %v", err)
 		return err
 	}
 
 	k8sNamespace := string(podCfg.K8S_POD_NAMESPACE)
 	if len(k8sNamespace) == 0 {
 		errMsg := "Required parameter Pod Namespace not specified in CNI Args during UPDATE"
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return plugin.Errorf(errMsg)
 	}
 
 	k8sPodName := string(podCfg.K8S_POD_NAME)
 	if len(k8sPodName) == 0 {
 		errMsg := "Required parameter Pod Name not specified in CNI Args during UPDATE"
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return plugin.Errorf(errMsg)
 	}
 
+	setInvocationFields(args.ContainerID, k8sPodName, k8sNamespace)
+
 	// Initialize values from network config.
 	networkID := nwCfg.Name
 
 	// Query the network.
 	if _, err = plugin.nm.GetNetworkInfo(networkID); err != nil {
 		errMsg := fmt.Sprintf("Failed to query network during CNI UPDATE: %v", err)
-		log.Printf(errMsg)
+		logger.Infof(errMsg)
 		return plugin.Errorf(errMsg)
 	}
 
@@ -1115,10 +1408,10 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 		return err
 	}
 
-	log.Printf("Retrieved existing endpoint from state that may get update: %+v", existingEpInfo)
+	logger.Infof("Retrieved existing endpoint from state that may get update: %+v", existingEpInfo)
 
 	// now query CNS to get the target routes that should be there in the networknamespace (as a result of update)
-	log.Printf("Going to collect target routes for [name=%v, namespace=%v] from CNS.", k8sPodName, k8sNamespace)
+	logger.Infof("Going to collect target routes for [name=%v, namespace=%v] from CNS.", k8sPodName, k8sNamespace)
 
 	// create struct with info for target POD
 	podInfo := cns.KubernetesPodInfo{
@@ -1126,61 +1419,61 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 		PodNamespace: k8sNamespace,
 	}
 	if orchestratorContext, err = json.Marshal(podInfo); err != nil {
-		log.Printf("Marshalling KubernetesPodInfo failed with %v", err)
+		logger.Infof("Marshalling KubernetesPodInfo failed with %v", err)
 		return plugin.Errorf(err.Error())
 	}
 
 	cnsclient, err := cnscli.New(nwCfg.CNSUrl, defaultRequestTimeout)
 	if err != nil {
-		log.Printf("failed to initialized cns client with URL %s: %v", nwCfg.CNSUrl, err.Error())
+		logger.Infof("failed to initialized cns client with URL %s: %v", nwCfg.CNSUrl, err.Error())
 		return plugin.Errorf(err.Error())
 	}
 
 	if targetNetworkConfig, err = cnsclient.GetNetworkConfiguration(context.TODO(), orchestratorContext); err != nil {
-		log.Printf("GetNetworkConfiguration failed with %v", err)
+		logger.Infof("GetNetworkConfiguration failed with %v", err)
 		return plugin.Errorf(err.Error())
 	}
 
-	log.Printf("Network config received from cns for [name=%v, namespace=%v] is as follows -> %+v", k8sPodName, k8sNamespace, targetNetworkConfig)
+	logger.Infof("Network config received from cns for [name=%v, namespace=%v] is as follows -> %+v", k8sPodName, k8sNamespace, targetNetworkConfig)
 	targetEpInfo := &network.EndpointInfo{}
 
 	// get the target routes that should replace existingEpInfo.Routes inside the network namespace
-	log.Printf("Going to collect target routes for [name=%v, namespace=%v] from targetNetworkConfig.", k8sPodName, k8sNamespace)
+	logger.Infof("Going to collect target routes for [name=%v, namespace=%v] from targetNetworkConfig.", k8sPodName, k8sNamespace)
 	if targetNetworkConfig.Routes != nil && len(targetNetworkConfig.Routes) > 0 {
 		for _, route := range targetNetworkConfig.Routes {
-			log.Printf("Adding route from routes to targetEpInfo %+v", route)
+			logger.Infof("Adding route from routes to targetEpInfo %+v", route)
 			_, dstIPNet, _ := net.ParseCIDR(route.IPAddress)
 			gwIP := net.ParseIP(route.GatewayIPAddress)
 			targetEpInfo.Routes = append(targetEpInfo.Routes, network.RouteInfo{Dst: *dstIPNet, Gw: gwIP, DevName: existingEpInfo.IfName})
-			log.Printf("Successfully added route from routes to targetEpInfo %+v", route)
+			logger.Infof("Successfully added route from routes to targetEpInfo %+v", route)
 		}
 	}
 
-	log.Printf("Going to collect target routes based on Cnetaddressspace for [name=%v, namespace=%v] from targetNetworkConfig.", k8sPodName, k8sNamespace)
+	logger.Infof("Going to collect target routes based on Cnetaddressspace for [name=%v, namespace=%v] from targetNetworkConfig.", k8sPodName, k8sNamespace)
 	ipconfig := targetNetworkConfig.IPConfiguration
 	for _, ipRouteSubnet := range targetNetworkConfig.CnetAddressSpace {
-		log.Printf("Adding route from cnetAddressspace to targetEpInfo %+v", ipRouteSubnet)
+		logger.Infof("Adding route from cnetAddressspace to targetEpInfo %+v", ipRouteSubnet)
 		dstIPNet := net.IPNet{IP: net.ParseIP(ipRouteSubnet.IPAddress), Mask: net.CIDRMask(int(ipRouteSubnet.PrefixLength), 32)}
 		gwIP := net.ParseIP(ipconfig.GatewayIPAddress)
 		route := network.RouteInfo{Dst: dstIPNet, Gw: gwIP, DevName: existingEpInfo.IfName}
 		targetEpInfo.Routes = append(targetEpInfo.Routes, route)
-		log.Printf("Successfully added route from cnetAddressspace to targetEpInfo %+v", ipRouteSubnet)
+		logger.Infof("Successfully added route from cnetAddressspace to targetEpInfo %+v", ipRouteSubnet)
 	}
 
-	log.Printf("Finished collecting new routes in targetEpInfo as follows: %+v", targetEpInfo.Routes)
-	log.Printf("Now saving existing infravnetaddress space if needed.")
+	logger.Infof("Finished collecting new routes in targetEpInfo as follows: %+v", targetEpInfo.Routes)
+	logger.Infof("Now saving existing infravnetaddress space if needed.")
 	for _, ns := range nwCfg.PodNamespaceForDualNetwork {
 		if k8sNamespace == ns {
 			targetEpInfo.EnableInfraVnet = true
 			targetEpInfo.InfraVnetAddressSpace = nwCfg.InfraVnetAddressSpace
-			log.Printf("Saving infravnet address space %s for [%s-%s]",
+			logger.Infof("Saving infravnet address space %s for [%s-%s]",
 				targetEpInfo.InfraVnetAddressSpace, existingEpInfo.PODNameSpace, existingEpInfo.PODName)
 			break
 		}
 	}
 
 	// Update the endpoint.
-	log.Printf("Now updating existing endpoint %v with targetNetworkConfig %+v.", existingEpInfo.Id, targetNetworkConfig)
+	logger.Infof("Now updating existing endpoint %v with targetNetworkConfig %+v.", existingEpInfo.Id, targetNetworkConfig)
 	if err = plugin.nm.UpdateEndpoint(networkID, existingEpInfo, targetEpInfo); err != nil {
 		err = plugin.Errorf("Failed to update endpoint: %v", err)
 		return err
@@ -1213,7 +1506,7 @@ func convertNnsToCniResult(
 				ipWithPrefix := fmt.Sprintf("%s/%s", ip.Ip, ip.PrefixLength)
 				_, ipNet, err := net.ParseCIDR(ipWithPrefix)
 				if err != nil {
-					log.Printf("Error while converting to cni result for %s operation on pod %s. %s",
+					logger.Infof("Error while converting to cni result for %s operation on pod %s. %s",
 						operationName, podName, err)
 					continue
 				}
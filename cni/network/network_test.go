@@ -1,6 +1,8 @@
 package network
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -9,11 +11,15 @@ import (
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/api"
 	"github.com/Azure/azure-container-networking/cni/util"
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/common"
 	acnnetwork "github.com/Azure/azure-container-networking/network"
 	"github.com/Azure/azure-container-networking/nns"
 	"github.com/Azure/azure-container-networking/telemetry"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -939,6 +945,108 @@ func TestPluginAKSSwiftDelete(t *testing.T) {
 	}
 }
 
+func TestPluginSecondaryInterfacesAddDelete(t *testing.T) {
+	plugin := GetTestResources()
+	mac, _ := net.ParseMAC("12:34:56:78:9a:bc")
+	plugin.ipamInvoker = &MockIpamInvoker{
+		ipMap: make(map[string]bool),
+		secondaryInterfaces: []SecondaryInterfaceInfo{
+			{
+				ncID:       "nc1",
+				macAddress: mac,
+				result: &cniTypesCurr.Result{
+					IPs: []*cniTypesCurr.IPConfig{{Address: net.IPNet{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(24, 32)}}},
+				},
+			},
+		},
+	}
+
+	args := &cniSkel.CmdArgs{
+		StdinData:   nwCfg.Serialize(),
+		ContainerID: "test-container",
+		Netns:       "test-container",
+		Args:        fmt.Sprintf("K8S_POD_NAME=%v;K8S_POD_NAMESPACE=%v", "test-pod", "test-pod-ns"),
+		IfName:      eth0IfName,
+	}
+
+	require.NoError(t, plugin.Add(args))
+	endpoints, _ := plugin.nm.GetAllEndpoints(nwCfg.Name)
+	require.Len(t, endpoints, 2)
+	secondaryEndpoint, ok := endpoints["test-con-eth1"]
+	require.True(t, ok, "expected an endpoint for eth1 in %v", endpoints)
+	require.Equal(t, mac, secondaryEndpoint.MacAddress)
+	require.Equal(t, "nc1", secondaryEndpoint.NetworkContainerID)
+
+	require.NoError(t, plugin.Delete(args))
+	endpoints, _ = plugin.nm.GetAllEndpoints(nwCfg.Name)
+	require.Empty(t, endpoints)
+}
+
+func TestSecondaryIfName(t *testing.T) {
+	require.Equal(t, "eth1", secondaryIfName("eth0", 1))
+	require.Equal(t, "eth3", secondaryIfName("eth1", 2))
+	require.Equal(t, "net1", secondaryIfName("net", 1))
+}
+
+func TestPluginGC(t *testing.T) {
+	plugin := GetTestResources()
+
+	addArgs := func(containerID, podName string) *cniSkel.CmdArgs {
+		return &cniSkel.CmdArgs{
+			StdinData:   nwCfg.Serialize(),
+			ContainerID: containerID,
+			Netns:       containerID,
+			Args:        fmt.Sprintf("K8S_POD_NAME=%v;K8S_POD_NAMESPACE=%v", podName, "test-pod-ns"),
+			IfName:      eth0IfName,
+		}
+	}
+	require.NoError(t, plugin.Add(addArgs("valid-container", "valid-pod")))
+	require.NoError(t, plugin.Add(addArgs("stale-container", "stale-pod")))
+
+	gcCfg := nwCfg
+	gcCfg.ValidAttachments = []cni.Attachment{{ContainerID: "valid-container", IfName: eth0IfName}}
+	require.NoError(t, plugin.GC(&cniSkel.CmdArgs{StdinData: gcCfg.Serialize()}))
+
+	endpoints, _ := plugin.nm.GetAllEndpoints(nwCfg.Name)
+	require.Len(t, endpoints, 1)
+	for _, ep := range endpoints {
+		require.Equal(t, "valid-container", ep.ContainerID)
+	}
+
+	// only the IP of the valid endpoint is still allocated
+	require.Len(t, plugin.ipamInvoker.(*MockIpamInvoker).ipMap, 1)
+}
+
+type fakeIPStateClient struct {
+	ips []cns.IPConfigurationStatus
+	err error
+}
+
+func (c *fakeIPStateClient) GetIPAddressesMatchingStates(context.Context, ...types.IPState) ([]cns.IPConfigurationStatus, error) {
+	return c.ips, c.err
+}
+
+func TestCNSStatus(t *testing.T) {
+	require.NoError(t, cnsStatus(context.TODO(), &fakeIPStateClient{ips: []cns.IPConfigurationStatus{{IPAddress: "10.0.0.4"}}}))
+
+	tests := []struct {
+		name   string
+		client *fakeIPStateClient
+	}{
+		{name: "CNS not reachable", client: &fakeIPStateClient{err: errors.New("connection refused")}},
+		{name: "No available IP", client: &fakeIPStateClient{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := cnsStatus(context.TODO(), tt.client)
+			var cniErr *cniTypes.Error
+			require.ErrorAs(t, err, &cniErr)
+			require.Equal(t, uint(cni.ErrPluginNotAvailable), cniErr.Code)
+		})
+	}
+}
+
 func TestNewPlugin(t *testing.T) {
 	tests := []struct {
 		name    string
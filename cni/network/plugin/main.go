@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/api"
+	cnilog "github.com/Azure/azure-container-networking/cni/log"
 	"github.com/Azure/azure-container-networking/cni/network"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
@@ -21,11 +23,15 @@ import (
 	"github.com/Azure/azure-container-networking/platform"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/Azure/azure-container-networking/telemetry"
+	"github.com/Azure/azure-container-networking/tracing"
 	"github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
+var logger = cnilog.CNILogger.With(zap.String(cnilog.ComponentKey, "cni-main")).Sugar()
+
 const (
 	hostNetAgentURL                 = "http://168.63.129.16/machine/plugins?comp=netagent&type=cnireport"
 	ipamQueryURL                    = "http://168.63.129.16/machine/plugins?comp=nmagent&type=getinterfaceinfov1"
@@ -33,6 +39,7 @@ const (
 	telemetryNumRetries             = 5
 	telemetryWaitTimeInMilliseconds = 200
 	name                            = "azure-vnet"
+	tracingShutdownTimeout          = 2 * time.Second
 )
 
 // Version is populated by make during build.
@@ -56,11 +63,11 @@ func printVersion() {
 
 // send error report to hostnetagent if CNI encounters any error.
 func reportPluginError(reportManager *telemetry.ReportManager, tb *telemetry.TelemetryBuffer, err error) {
-	log.Printf("Report plugin error")
+	logger.Infof("Report plugin error")
 	reflect.ValueOf(reportManager.Report).Elem().FieldByName("ErrorMessage").SetString(err.Error())
 
 	if err := reportManager.SendReport(tb); err != nil {
-		log.Errorf("SendReport failed due to %v", err)
+		logger.Errorf("SendReport failed due to %v", err)
 	}
 }
 
@@ -78,7 +85,7 @@ func validateConfig(jsonBytes []byte) error {
 }
 
 func getCmdArgsFromEnv() (string, *skel.CmdArgs, error) {
-	log.Printf("Going to read from stdin")
+	logger.Infof("Going to read from stdin")
 	stdinData, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return "", nil, fmt.Errorf("error reading from stdin: %v", err)
@@ -104,24 +111,24 @@ func handleIfCniUpdate(update func(*skel.CmdArgs) error) (bool, error) {
 		return false, nil
 	}
 
-	log.Printf("CNI UPDATE received.")
+	logger.Infof("CNI UPDATE received.")
 
 	_, cmdArgs, err := getCmdArgsFromEnv()
 	if err != nil {
-		log.Printf("Received error while retrieving cmds from environment: %+v", err)
+		logger.Infof("Received error while retrieving cmds from environment: %+v", err)
 		return isupdate, err
 	}
 
-	log.Printf("Retrieved command args for update +%v", cmdArgs)
+	logger.Infof("Retrieved command args for update +%v", cmdArgs)
 	err = validateConfig(cmdArgs.StdinData)
 	if err != nil {
-		log.Printf("Failed to handle CNI UPDATE, err:%v.", err)
+		logger.Infof("Failed to handle CNI UPDATE, err:%v.", err)
 		return isupdate, err
 	}
 
 	err = update(cmdArgs)
 	if err != nil {
-		log.Printf("Failed to handle CNI UPDATE, err:%v.", err)
+		logger.Infof("Failed to handle CNI UPDATE, err:%v.", err)
 		return isupdate, err
 	}
 
@@ -129,7 +136,7 @@ func handleIfCniUpdate(update func(*skel.CmdArgs) error) (bool, error) {
 }
 
 func printCNIError(msg string) {
-	log.Errorf(msg)
+	logger.Errorf(msg)
 	cniErr := &cniTypes.Error{
 		Code: cniTypes.ErrTryAgainLater,
 		Msg:  msg,
@@ -173,7 +180,7 @@ func rootExecute() error {
 	cniCmd := os.Getenv(cni.Cmd)
 
 	if cniCmd != cni.CmdVersion {
-		log.Printf("CNI_COMMAND environment variable set to %s", cniCmd)
+		logger.Infof("CNI_COMMAND environment variable set to %s", cniCmd)
 
 		cniReport.GetReport(pluginName, version, ipamQueryURL)
 
@@ -189,7 +196,7 @@ func rootExecute() error {
 
 			tb = telemetry.NewTelemetryBuffer()
 			if tberr := tb.Connect(); tberr != nil {
-				log.Errorf("Cannot connect to telemetry service:%v", tberr)
+				logger.Errorf("Cannot connect to telemetry service:%v", tberr)
 				return errors.Wrap(err, "lock acquire error")
 			}
 
@@ -204,7 +211,7 @@ func rootExecute() error {
 				}
 				sendErr := telemetry.SendCNIMetric(&cniMetric, tb)
 				if sendErr != nil {
-					log.Errorf("Couldn't send cnilocktimeout metric: %v", sendErr)
+					logger.Errorf("Couldn't send cnilocktimeout metric: %v", sendErr)
 				}
 			}
 
@@ -214,7 +221,7 @@ func rootExecute() error {
 
 		defer func() {
 			if errUninit := netPlugin.Plugin.UninitializeKeyValueStore(); errUninit != nil {
-				log.Errorf("Failed to uninitialize key-value store of network plugin, err:%v.\n", errUninit)
+				logger.Errorf("Failed to uninitialize key-value store of network plugin, err:%v.", errUninit)
 			}
 
 			if recover() != nil {
@@ -241,28 +248,51 @@ func rootExecute() error {
 
 		// used to dump state
 		if cniCmd == cni.CmdGetEndpointsState {
-			log.Printf("Retrieving state")
+			logger.Infof("Retrieving state")
 			var simpleState *api.AzureCNIState
 			simpleState, err = netPlugin.GetAllEndpointState("azure")
 			if err != nil {
-				log.Errorf("Failed to get Azure CNI state, err:%v.\n", err)
+				logger.Errorf("Failed to get Azure CNI state, err:%v.", err)
 				return errors.Wrap(err, "Get all endpoints error")
 			}
 
 			err = simpleState.PrintResult()
 			if err != nil {
-				log.Errorf("Failed to print state result to stdout with err %v\n", err)
+				logger.Errorf("Failed to print state result to stdout with err %v", err)
 			}
 
 			return errors.Wrap(err, "Get cni state printresult error")
 		}
+
+		// used to find, and on request clean up, host links and namespaces of endpoints which aren't in the state
+		if cniCmd == cni.CmdReconcileOrphans {
+			deleteOrphans := os.Getenv(cni.DeleteOrphans) == "true"
+			logger.Infof("Reconciling orphans, delete: %t", deleteOrphans)
+			var report *api.OrphanReport
+			report, err = netPlugin.ReconcileOrphans(deleteOrphans)
+			if err != nil {
+				logger.Errorf("Failed to reconcile orphans, err:%v.", err)
+				if report == nil {
+					return errors.Wrap(err, "Reconcile orphans error")
+				}
+			}
+
+			if printErr := report.PrintResult(); printErr != nil {
+				logger.Errorf("Failed to print orphan report to stdout with err %v", printErr)
+				if err == nil {
+					err = printErr
+				}
+			}
+
+			return errors.Wrap(err, "Reconcile orphans error")
+		}
 	}
 
 	handled, _ := handleIfCniUpdate(netPlugin.Update)
 	if handled {
-		log.Printf("CNI UPDATE finished.")
+		logger.Infof("CNI UPDATE finished.")
 	} else if err = netPlugin.Execute(cni.PluginApi(netPlugin)); err != nil {
-		log.Errorf("Failed to execute network plugin, err:%v.\n", err)
+		logger.Errorf("Failed to execute network plugin, err:%v.", err)
 	}
 
 	if cniCmd == cni.CmdVersion {
@@ -289,7 +319,12 @@ func main() {
 		os.Exit(0)
 	}
 
-	log.SetName(name)
+	syncLogs := cnilog.Init(&cnilog.Config{Name: name})
+	cnilog.SetInvocationFields(zap.String(cnilog.CommandKey, os.Getenv(cni.Cmd)))
+
+	// Shared packages which do not log through the structured logger yet keep writing
+	// printf-style lines, to a separate file so they don't interleave with the JSON log.
+	log.SetName(name + "-common")
 	log.SetLevel(log.LevelInfo)
 	if err := log.SetTargetLogDirectory(log.TargetLogfile, ""); err != nil {
 		fmt.Printf("Failed to setup cni logging: %v\n", err)
@@ -298,6 +333,14 @@ func main() {
 
 	err := rootExecute()
 
+	// export the spans of the command before the process exits.
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if shutdownErr := tracing.Shutdown(ctx); shutdownErr != nil {
+		logger.Errorf("Failed to export spans: %v", shutdownErr)
+	}
+	cancel()
+
+	syncLogs()
 	log.Close()
 	if err != nil {
 		os.Exit(1)
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/aitelemetry/otlp"
+	"github.com/Azure/azure-container-networking/cni"
+	cnilog "github.com/Azure/azure-container-networking/cni/log"
+	"github.com/Azure/azure-container-networking/tracing"
+	cniSkel "github.com/containernetworking/cni/pkg/skel"
+)
+
+// Span attribute keys, following the OpenTelemetry semantic conventions where one exists.
+const (
+	spanAttrInvocationID = "cni.invocation_id"
+	spanAttrContainerID  = "container.id"
+	spanAttrPodName      = "k8s.pod.name"
+	spanAttrPodNamespace = "k8s.pod.namespace"
+	spanAttrNetworkID    = "cni.network_id"
+	spanAttrEndpointID   = "cni.endpoint_id"
+)
+
+// startTrace enables tracing as configured in nwCfg and starts the root span of the command. Spans
+// started from the returned context, including those of the CNS requests it is passed to, join its trace.
+func (plugin *NetPlugin) startTrace(nwCfg *cni.NetworkConfig, command string, args *cniSkel.CmdArgs) (context.Context, *tracing.Span) {
+	if err := plugin.initTracing(&nwCfg.Tracing); err != nil {
+		logger.Errorf("Failed to initialize tracing: %v", err)
+	}
+
+	return tracing.StartSpan(context.Background(), "cni."+command, tracing.WithAttributes(map[string]string{
+		spanAttrInvocationID: plugin.invocationID,
+		spanAttrContainerID:  args.ContainerID,
+	}))
+}
+
+func (plugin *NetPlugin) initTracing(cfg *cni.Tracing) error {
+	switch cfg.Exporter {
+	case "":
+		return nil
+	case cni.TracingExporterLog:
+		tracing.Init(tracing.NewLogExporter(cnilog.CNILogger.Named("tracing")))
+	case cni.TracingExporterOTLP:
+		exp, err := otlp.NewTraceExporter(otlp.Config{
+			Endpoint:   cfg.Endpoint,
+			Protocol:   otlp.Protocol(cfg.Protocol),
+			Insecure:   cfg.Insecure,
+			AppName:    plugin.Name,
+			AppVersion: plugin.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		tracing.Init(exp)
+	default:
+		return fmt.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+	}
+	return nil
+}
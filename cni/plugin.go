@@ -6,6 +6,7 @@ package cni
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 
@@ -77,6 +78,16 @@ func (plugin *Plugin) Execute(api PluginApi) (err error) {
 		}
 	}()
 
+	// The skel package doesn't know the GC and STATUS commands of CNI spec 1.1.
+	if gcStatusAPI, ok := api.(GCStatusPluginApi); ok {
+		switch os.Getenv(Cmd) {
+		case CmdGC:
+			return plugin.executeWithStdin(gcStatusAPI.GC)
+		case CmdStatus:
+			return plugin.executeWithStdin(gcStatusAPI.Status)
+		}
+	}
+
 	// Set supported CNI versions.
 	pluginInfo := cniVers.PluginSupports(supportedVersions...)
 
@@ -90,6 +101,28 @@ func (plugin *Plugin) Execute(api PluginApi) (err error) {
 	return nil
 }
 
+// executeWithStdin executes a CNI command which takes no container arguments, only the network configuration.
+func (plugin *Plugin) executeWithStdin(cmd func(args *cniSkel.CmdArgs) error) error {
+	stdinData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		cniErr := plugin.Errorf("Failed to read network configuration from stdin: %v", err)
+		cniErr.Print()
+		return cniErr
+	}
+
+	args := &cniSkel.CmdArgs{
+		Path:      os.Getenv("CNI_PATH"),
+		StdinData: stdinData,
+	}
+	if err = cmd(args); err != nil {
+		cniErr := plugin.Error(err)
+		cniErr.Print()
+		return cniErr
+	}
+
+	return nil
+}
+
 // DelegateAdd calls the given plugin's ADD command and returns the result.
 func (plugin *Plugin) DelegateAdd(pluginName string, nwCfg *NetworkConfig) (*cniTypesCurr.Result, error) {
 	var result *cniTypesCurr.Result
@@ -100,7 +133,7 @@ func (plugin *Plugin) DelegateAdd(pluginName string, nwCfg *NetworkConfig) (*cni
 
 	os.Setenv(Cmd, CmdAdd)
 
-	res, err := cniInvoke.DelegateAdd(context.TODO(), pluginName, nwCfg.Serialize(), nil)
+	res, err := cniInvoke.DelegateAdd(context.TODO(), pluginName, delegateConfig(nwCfg), nil)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to delegate: %v", err)
 	}
@@ -122,7 +155,7 @@ func (plugin *Plugin) DelegateDel(pluginName string, nwCfg *NetworkConfig) error
 
 	os.Setenv(Cmd, CmdDel)
 
-	err = cniInvoke.DelegateDel(context.TODO(), pluginName, nwCfg.Serialize(), nil)
+	err = cniInvoke.DelegateDel(context.TODO(), pluginName, delegateConfig(nwCfg), nil)
 	if err != nil {
 		return fmt.Errorf("Failed to delegate: %v", err)
 	}
@@ -130,6 +163,28 @@ func (plugin *Plugin) DelegateDel(pluginName string, nwCfg *NetworkConfig) error
 	return nil
 }
 
+// delegateConfig returns the network configuration passed to a delegated plugin, whose result is parsed
+// by the CNI library in use and thus can't be of version 1.1.0.
+func delegateConfig(nwCfg *NetworkConfig) []byte {
+	if nwCfg.CNIVersion != specVersionGCStatus {
+		return nwCfg.Serialize()
+	}
+	cfg := *nwCfg
+	cfg.CNIVersion = specVersionResult
+	return cfg.Serialize()
+}
+
+// GetAsVersion converts a result to the CNI version of a network configuration, including 1.1.0,
+// which the CNI library in use can't convert to.
+func GetAsVersion(result *cniTypesCurr.Result, version string) (cniTypes.Result, error) {
+	if version != specVersionGCStatus {
+		return result.GetAsVersion(version) //nolint:wrapcheck // the caller wraps the error
+	}
+	converted := *result
+	converted.CNIVersion = version
+	return &converted, nil
+}
+
 // Error creates and logs a structured CNI error.
 func (plugin *Plugin) Error(err error) *cniTypes.Error {
 	var cniErr *cniTypes.Error
@@ -0,0 +1,143 @@
+package cni
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/stretchr/testify/require"
+)
+
+const testGCConfig = `{"cniVersion":"1.1.0","name":"azure","type":"azure-vnet","cni.dev/valid-attachments":[{"containerID":"c1","ifname":"eth0"}]}`
+
+// fakePluginAPI records the commands a plugin dispatches to it.
+type fakePluginAPI struct {
+	called string
+	args   *cniSkel.CmdArgs
+	err    error
+}
+
+func (f *fakePluginAPI) Add(args *cniSkel.CmdArgs) error    { return f.record(CmdAdd, args) }
+func (f *fakePluginAPI) Get(args *cniSkel.CmdArgs) error    { return f.record(CmdGet, args) }
+func (f *fakePluginAPI) Delete(args *cniSkel.CmdArgs) error { return f.record(CmdDel, args) }
+func (f *fakePluginAPI) Update(args *cniSkel.CmdArgs) error { return f.record(CmdUpdate, args) }
+func (f *fakePluginAPI) GC(args *cniSkel.CmdArgs) error     { return f.record(CmdGC, args) }
+func (f *fakePluginAPI) Status(args *cniSkel.CmdArgs) error { return f.record(CmdStatus, args) }
+
+func (f *fakePluginAPI) record(cmd string, args *cniSkel.CmdArgs) error {
+	f.called = cmd
+	f.args = args
+	return f.err
+}
+
+// setStdio runs the plugin with stdin as its standard input and returns a function reading its standard output.
+func setStdio(t *testing.T, stdin string) func() string {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "stdin")
+	require.NoError(t, os.WriteFile(in, []byte(stdin), 0o600))
+	inFile, err := os.Open(in)
+	require.NoError(t, err)
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	require.NoError(t, err)
+
+	origStdin, origStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = inFile, outFile
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = origStdin, origStdout
+		inFile.Close()
+		outFile.Close()
+	})
+
+	return func() string {
+		out, err := os.ReadFile(outFile.Name())
+		require.NoError(t, err)
+		return string(out)
+	}
+}
+
+func TestExecuteDispatchesGCAndStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+	}{
+		{name: "GC", cmd: CmdGC},
+		{name: "STATUS", cmd: CmdStatus},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(Cmd, tt.cmd)
+			t.Setenv("CNI_PATH", "/opt/cni/bin")
+			setStdio(t, testGCConfig)
+
+			plugin, err := NewPlugin("test", "v1.0.0")
+			require.NoError(t, err)
+			api := &fakePluginAPI{}
+			require.NoError(t, plugin.Execute(api))
+			require.Equal(t, tt.cmd, api.called)
+			require.Equal(t, "/opt/cni/bin", api.args.Path)
+			require.JSONEq(t, testGCConfig, string(api.args.StdinData))
+		})
+	}
+}
+
+func TestExecuteGCError(t *testing.T) {
+	t.Setenv(Cmd, CmdStatus)
+	stdout := setStdio(t, testGCConfig)
+
+	plugin, err := NewPlugin("test", "v1.0.0")
+	require.NoError(t, err)
+	err = plugin.Execute(&fakePluginAPI{err: &cniTypes.Error{Code: ErrPluginNotAvailable, Msg: "CNS is not reachable"}})
+
+	var cniErr *cniTypes.Error
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, uint(ErrPluginNotAvailable), cniErr.Code)
+	require.Contains(t, stdout(), "CNS is not reachable")
+
+	// other errors are runtime errors
+	err = plugin.Execute(&fakePluginAPI{err: errors.New("failed")})
+	require.ErrorAs(t, err, &cniErr)
+	require.Equal(t, uint(ErrRuntime), cniErr.Code)
+}
+
+func TestExecuteAdvertisesGCStatusVersion(t *testing.T) {
+	t.Setenv(Cmd, "VERSION")
+	stdout := setStdio(t, `{"cniVersion":"1.1.0"}`)
+
+	plugin, err := NewPlugin("test", "v1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, plugin.Execute(&fakePluginAPI{}))
+
+	var info struct {
+		SupportedVersions []string `json:"supportedVersions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout()), &info))
+	require.Contains(t, info.SupportedVersions, "1.1.0")
+}
+
+func TestGetAsVersion(t *testing.T) {
+	result := &cniTypesCurr.Result{CNIVersion: "1.0.0"}
+
+	res, err := GetAsVersion(result, "1.1.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", res.Version())
+	require.Equal(t, "1.0.0", result.CNIVersion)
+
+	res, err = GetAsVersion(result, "0.4.0")
+	require.NoError(t, err)
+	require.Equal(t, "0.4.0", res.Version())
+}
+
+func TestDelegateConfig(t *testing.T) {
+	nwCfg := &NetworkConfig{CNIVersion: "1.1.0", Name: "azure"}
+
+	var delegated NetworkConfig
+	require.NoError(t, json.Unmarshal(delegateConfig(nwCfg), &delegated))
+	require.Equal(t, "1.0.0", delegated.CNIVersion)
+	require.Equal(t, "1.1.0", nwCfg.CNIVersion)
+}
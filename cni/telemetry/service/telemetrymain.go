@@ -55,6 +55,17 @@ var args = acn.ArgumentList{
 			acn.OptLogMultiWrite:   log.TargetStdOutAndLogFile,
 		},
 	},
+	{
+		Name:         acn.OptLogFormat,
+		Shorthand:    acn.OptLogFormatAlias,
+		Description:  "Set the logging format",
+		Type:         "int",
+		DefaultValue: acn.OptLogFormatText,
+		ValueMap: map[string]interface{}{
+			acn.OptLogFormatText: log.FormatText,
+			acn.OptLogFormatJSON: log.FormatJSON,
+		},
+	},
 	{
 		Name:         acn.OptLogLocation,
 		Shorthand:    acn.OptLogLocationAlias,
@@ -118,6 +129,7 @@ func main() {
 
 	acn.ParseArgs(&args, printVersion)
 	logTarget := acn.GetArg(acn.OptLogTarget).(int)
+	logFormat := acn.GetArg(acn.OptLogFormat).(int)
 	logDirectory := acn.GetArg(acn.OptLogLocation).(string)
 	logLevel := acn.GetArg(acn.OptLogLevel).(int)
 	configDirectory := acn.GetArg(acn.OptTelemetryConfigDir).(string)
@@ -135,6 +147,10 @@ func main() {
 		fmt.Printf("Failed to configure logging: %v\n", err)
 		return
 	}
+	if err = log.SetFormat(logFormat); err != nil {
+		fmt.Printf("Failed to configure logging: %v\n", err)
+		return
+	}
 
 	log.Logf("args %+v", os.Args)
 
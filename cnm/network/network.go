@@ -244,7 +244,7 @@ func (plugin *netPlugin) createEndpoint(w http.ResponseWriter, r *http.Request)
 
 	cnscli, err := cnsclient.New("", defaultCNSTimeout)
 	if err != nil {
-		log.Errorf("failed to init CNS client", err)
+		log.Errorf("failed to init CNS client: %v", err)
 	}
 	err = plugin.nm.CreateEndpoint(cnscli, req.NetworkID, &epInfo)
 	if err != nil {
@@ -57,6 +57,17 @@ var args = acn.ArgumentList{
 			acn.OptLogTargetFile:   log.TargetLogfile,
 		},
 	},
+	{
+		Name:         acn.OptLogFormat,
+		Shorthand:    acn.OptLogFormatAlias,
+		Description:  "Set the logging format",
+		Type:         "int",
+		DefaultValue: acn.OptLogFormatText,
+		ValueMap: map[string]interface{}{
+			acn.OptLogFormatText: log.FormatText,
+			acn.OptLogFormatJSON: log.FormatJSON,
+		},
+	},
 	{
 		Name:         acn.OptLogLocation,
 		Shorthand:    acn.OptLogLocationAlias,
@@ -92,6 +103,7 @@ func main() {
 	acn.ParseArgs(&args, printVersion)
 	logLevel := acn.GetArg(acn.OptLogLevel).(int)
 	logTarget := acn.GetArg(acn.OptLogTarget).(int)
+	logFormat := acn.GetArg(acn.OptLogFormat).(int)
 	logDirectory := acn.GetArg(acn.OptLogLocation).(string)
 	timeout := acn.GetArg(acn.OptIntervalTime).(int)
 	vers := acn.GetArg(acn.OptVersion).(bool)
@@ -115,6 +127,10 @@ func main() {
 		fmt.Printf("[monitor] Failed to configure logging: %v\n", err)
 		return
 	}
+	if err := log.SetFormat(logFormat); err != nil {
+		fmt.Printf("[monitor] Failed to configure logging: %v\n", err)
+		return
+	}
 
 	// Log platform information.
 	log.Printf("[monitor] Running on %v", platform.GetOSInfo())
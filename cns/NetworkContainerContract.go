@@ -6,6 +6,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/pkg/errors"
@@ -29,6 +30,9 @@ const (
 	PathDebugIPAddresses                     = "/debug/ipaddresses"
 	PathDebugPodContext                      = "/debug/podcontext"
 	PathDebugRestData                        = "/debug/restdata"
+	PathDebugPod                             = "/debug/pod"
+	PathDebugReassignIPAddress               = "/debug/ipaddresses/reassign"
+	PathDebugLogLevel                        = "/debug/loglevel"
 )
 
 // NetworkContainer Prefixes
@@ -89,6 +93,8 @@ type CreateNetworkContainerRequest struct {
 	AllowHostToNCCommunication bool
 	AllowNCToHostCommunication bool
 	EndpointPolicies           []NetworkContainerRequestPolicies
+	// MacAddress is set for the NCs of the secondary interfaces of a pod, and is the MAC of the pod interface on the NC.
+	MacAddress string `json:",omitempty"`
 }
 
 // CreateNetworkContainerRequest implements fmt.Stringer for logging
@@ -376,6 +382,10 @@ type PodIpInfo struct {
 	PodIPConfig                     IPSubnet
 	NetworkContainerPrimaryIPConfig IPConfiguration
 	HostPrimaryIPInfo               HostIPInfo
+	// NetworkContainerID, MacAddress and Routes are only set for the secondary interfaces of a pod.
+	NetworkContainerID string  `json:",omitempty"`
+	MacAddress         string  `json:",omitempty"`
+	Routes             []Route `json:",omitempty"`
 }
 
 // DeleteNetworkContainerRequest specifies the details about the request to delete a specifc network container.
@@ -401,7 +411,9 @@ func (i IPConfigRequest) String() string {
 // IPConfigResponse is used in CNS IPAM mode as a response to CNI ADD
 type IPConfigResponse struct {
 	PodIpInfo PodIpInfo
-	Response  Response
+	// SecondaryInterfaces are the interfaces of the pod on NCs of their own, after the one of PodIpInfo.
+	SecondaryInterfaces []PodIpInfo `json:",omitempty"`
+	Response            Response
 }
 
 // GetIPAddressesRequest is used in CNS IPAM mode to get the states of IPConfigs
@@ -428,6 +440,54 @@ type GetPodContextResponse struct {
 	Response   Response
 }
 
+// IPStateTransition is a state change of an IP. CNS keeps the latest transitions of each IP to debug IPs stuck in a state.
+type IPStateTransition struct {
+	From types.IPState
+	To   types.IPState
+	// Pod is the namespace/name of the pod the IP was assigned to before or after the transition, if any.
+	Pod  string
+	Time time.Time
+}
+
+// GetPodDebugRequest is used in CNS Client debug mode to get the IPs of a pod.
+type GetPodDebugRequest struct {
+	PodName      string
+	PodNamespace string
+}
+
+// PodIPDebugInfo is an IP of a pod, with the NC it came from and its latest state transitions.
+type PodIPDebugInfo struct {
+	IPConfigurationStatus IPConfigurationStatus
+	// PodInterfaceID and InfraContainerID are the pod interface the IP is assigned to, empty if it is no longer assigned to the pod.
+	PodInterfaceID   string
+	InfraContainerID string
+	NetworkContainer NetworkContainerDebugInfo
+	History          []IPStateTransition
+}
+
+// NetworkContainerDebugInfo describes the NC an IP came from.
+type NetworkContainerDebugInfo struct {
+	ID          string
+	Version     string
+	HostVersion string
+	Subnet      IPSubnet
+	Gateway     string
+}
+
+// GetPodDebugResponse is used in CNS Client debug mode to return the IPs which are or were recently assigned to a pod.
+type GetPodDebugResponse struct {
+	IPs      []PodIPDebugInfo
+	Response Response
+}
+
+// ReassignIPRequest is used in CNS Client debug mode to move an assigned IP to another pod interface, e.g. when the pod
+// sandbox was recreated and CNS still has the IP assigned to the old one.
+type ReassignIPRequest struct {
+	IPAddress string
+	// Pod is the pod interface the IP is reassigned to, as the CNI sends it to request an IP.
+	Pod IPConfigRequest
+}
+
 // IPAddressState Only used in the GetIPConfig API to return IPs that match a filter
 type IPAddressState struct {
 	IPAddress string
@@ -36,6 +36,15 @@ const (
 	V2Prefix                      = "/v0.2"
 )
 
+// Request headers identifying the CNI invocation a request is made for, used to correlate CNS logs
+// with the CNI and IPAM plugin logs.
+const (
+	HeaderInvocationID = "X-Azure-CNI-Invocation-Id"
+	HeaderContainerID  = "X-Azure-CNI-Container-Id"
+	HeaderPodName      = "X-Azure-CNI-Pod-Name"
+	HeaderPodNamespace = "X-Azure-CNI-Pod-Namespace"
+)
+
 // HTTPService describes the min API interface that every service should have.
 type HTTPService interface {
 	common.ServiceAPI
@@ -13,6 +13,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -33,6 +34,8 @@ var clientPaths = []string{
 	cns.PathDebugIPAddresses,
 	cns.PathDebugPodContext,
 	cns.PathDebugRestData,
+	cns.PathDebugPod,
+	cns.PathDebugReassignIPAddress,
 	cns.UnpublishNetworkContainer,
 	cns.PublishNetworkContainer,
 	cns.CreateOrUpdateNetworkContainer,
@@ -43,6 +46,48 @@ type do interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// Invocation identifies the CNI invocation a CNS request is made for.
+type Invocation struct {
+	ID           string
+	ContainerID  string
+	PodName      string
+	PodNamespace string
+}
+
+type invocationKey struct{}
+
+// WithInvocation returns a copy of ctx carrying inv. Requests made with the returned
+// context send inv to CNS in the invocation headers.
+func WithInvocation(ctx context.Context, inv Invocation) context.Context {
+	return context.WithValue(ctx, invocationKey{}, inv)
+}
+
+// InvocationFromContext returns the Invocation stored in ctx by WithInvocation.
+func InvocationFromContext(ctx context.Context) (Invocation, bool) {
+	inv, ok := ctx.Value(invocationKey{}).(Invocation)
+	return inv, ok
+}
+
+// setContextHeaders sets the trace context and invocation headers from the request context, if any.
+func setContextHeaders(req *http.Request) {
+	tracing.Inject(req.Context(), req.Header)
+
+	inv, ok := InvocationFromContext(req.Context())
+	if !ok {
+		return
+	}
+	for header, val := range map[string]string{
+		cns.HeaderInvocationID: inv.ID,
+		cns.HeaderContainerID:  inv.ContainerID,
+		cns.HeaderPodName:      inv.PodName,
+		cns.HeaderPodNamespace: inv.PodNamespace,
+	} {
+		if val != "" {
+			req.Header.Set(header, val)
+		}
+	}
+}
+
 // Client specifies a client to connect to Ipam Plugin.
 type Client struct {
 	client do
@@ -108,6 +153,7 @@ func (c *Client) GetNetworkConfiguration(ctx context.Context, orchestratorContex
 		return nil, errors.Wrap(err, "failed to build request")
 	}
 	req.Header.Set(headerContentType, contentTypeJSON)
+	setContextHeaders(req)
 	res, err := c.client.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "http request failed")
@@ -157,6 +203,7 @@ func (c *Client) CreateHostNCApipaEndpoint(ctx context.Context, networkContainer
 		return "", errors.Wrap(err, "failed to build request")
 	}
 	req.Header.Set(headerContentType, contentTypeJSON)
+	setContextHeaders(req)
 	res, err := c.client.Do(req)
 	if err != nil {
 		return "", errors.Wrap(err, "http request failed")
@@ -198,6 +245,7 @@ func (c *Client) DeleteHostNCApipaEndpoint(ctx context.Context, networkContainer
 		return errors.Wrap(err, "failed to build request")
 	}
 	req.Header.Set(headerContentType, contentTypeJSON)
+	setContextHeaders(req)
 	res, err := c.client.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "http request failed")
@@ -246,6 +294,7 @@ func (c *Client) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 		return nil, errors.Wrap(err, "failed to build request")
 	}
 	req.Header.Set(headerContentType, contentTypeJSON)
+	setContextHeaders(req)
 	res, err := c.client.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "http request failed")
@@ -284,6 +333,7 @@ func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 		return errors.Wrap(err, "failed to build request")
 	}
 	req.Header.Set(headerContentType, contentTypeJSON)
+	setContextHeaders(req)
 	res, err := c.client.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "http request failed")
@@ -330,6 +380,7 @@ func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter .
 		return nil, errors.Wrap(err, "failed to build request")
 	}
 	req.Header.Set(headerContentType, contentTypeJSON)
+	setContextHeaders(req)
 	res, err := c.client.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "http request failed")
@@ -416,6 +467,81 @@ func (c *Client) GetHTTPServiceData(ctx context.Context) (*restserver.GetHTTPSer
 	return &resp, nil
 }
 
+// GetPodDebugInfo returns the IPs which are or were recently assigned to a pod, with their NC and state history
+func (c *Client) GetPodDebugInfo(ctx context.Context, podName, podNamespace string) ([]cns.PodIPDebugInfo, error) {
+	payload := cns.GetPodDebugRequest{
+		PodName:      podName,
+		PodNamespace: podNamespace,
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return nil, errors.Wrap(err, "failed to encode GetPodDebugRequest")
+	}
+
+	u := c.routes[cns.PathDebugPod]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.GetPodDebugResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode GetPodDebugResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return resp.IPs, nil
+}
+
+// ReassignIPAddress moves an assigned IP to another pod interface
+func (c *Client) ReassignIPAddress(ctx context.Context, reassign cns.ReassignIPRequest) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(reassign); err != nil {
+		return errors.Wrap(err, "failed to encode ReassignIPRequest")
+	}
+
+	u := c.routes[cns.PathDebugReassignIPAddress]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set(headerContentType, contentTypeJSON)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.Response
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return errors.Wrap(err, "failed to decode Response")
+	}
+
+	if resp.ReturnCode != 0 {
+		return errors.New(resp.Message)
+	}
+
+	return nil
+}
+
 // DeleteNetworkContainer destroys the requested network container matching the
 // provided ID.
 func (c *Client) DeleteNetworkContainer(ctx context.Context, ncID string) error {
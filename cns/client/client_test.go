@@ -23,6 +23,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/tracing"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -1572,6 +1573,70 @@ func TestRequestIPAddress(t *testing.T) {
 	}
 }
 
+type headerRecorder struct {
+	mockdo
+	header http.Header
+}
+
+func (h *headerRecorder) Do(req *http.Request) (*http.Response, error) {
+	h.header = req.Header.Clone()
+	return h.mockdo.Do(req)
+}
+
+func TestRequestIPAddressInvocationHeaders(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	recorder := &headerRecorder{
+		mockdo: mockdo{
+			objToReturn:            &cns.IPConfigResponse{},
+			httpStatusCodeToReturn: http.StatusOK,
+		},
+	}
+	client := &Client{
+		client: recorder,
+		routes: emptyRoutes,
+	}
+
+	ctx := WithInvocation(context.TODO(), Invocation{
+		ID:           "testinvocationid",
+		ContainerID:  "testcontainerid",
+		PodName:      "testpod",
+		PodNamespace: "testpodnamespace",
+	})
+	_, err := client.RequestIPAddress(ctx, cns.IPConfigRequest{InfraContainerID: "testcontainerid"})
+	require.NoError(t, err)
+	assert.Equal(t, "testinvocationid", recorder.header.Get(cns.HeaderInvocationID))
+	assert.Equal(t, "testcontainerid", recorder.header.Get(cns.HeaderContainerID))
+	assert.Equal(t, "testpod", recorder.header.Get(cns.HeaderPodName))
+	assert.Equal(t, "testpodnamespace", recorder.header.Get(cns.HeaderPodNamespace))
+
+	_, err = client.RequestIPAddress(context.TODO(), cns.IPConfigRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, recorder.header.Get(cns.HeaderInvocationID))
+	assert.Empty(t, recorder.header.Get(tracing.TraceparentHeader))
+}
+
+func TestRequestIPAddressTraceparent(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	recorder := &headerRecorder{
+		mockdo: mockdo{
+			objToReturn:            &cns.IPConfigResponse{},
+			httpStatusCodeToReturn: http.StatusOK,
+		},
+	}
+	client := &Client{
+		client: recorder,
+		routes: emptyRoutes,
+	}
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := tracing.ParseTraceparent(traceparent)
+	require.NoError(t, err)
+
+	_, err = client.RequestIPAddress(tracing.ContextWithSpanContext(context.TODO(), sc), cns.IPConfigRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, traceparent, recorder.header.Get(tracing.TraceparentHeader))
+}
+
 func TestReleaseIPAddress(t *testing.T) {
 	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
 	tests := []struct {
@@ -2009,3 +2074,164 @@ func TestGetHTTPServiceData(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPodDebugInfo(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	ips := []cns.PodIPDebugInfo{
+		{
+			PodInterfaceID: "testpodinterfaceid",
+			History:        []cns.IPStateTransition{{From: types.Available, To: types.Assigned, Pod: "testns/testpod"}},
+		},
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		mockdo  *mockdo
+		want    []cns.PodIPDebugInfo
+		wantErr bool
+	}{
+		{
+			name: "happy case",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				objToReturn:            &cns.GetPodDebugResponse{IPs: ips},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+			want: ips,
+		},
+		{
+			name: "bad request",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				errToReturn:            errBadRequest,
+				httpStatusCodeToReturn: http.StatusBadRequest,
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad decoding",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				objToReturn:            []cns.GetPodDebugResponse{},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+			wantErr: true,
+		},
+		{
+			name: "http status not ok",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				httpStatusCodeToReturn: http.StatusInternalServerError,
+			},
+			wantErr: true,
+		},
+		{
+			name: "cns return code not zero",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				objToReturn: &cns.GetPodDebugResponse{
+					Response: cns.Response{
+						ReturnCode: types.UnexpectedError,
+					},
+				},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "nil context",
+			ctx:     nil,
+			mockdo:  &mockdo{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				client: tt.mockdo,
+				routes: emptyRoutes,
+			}
+			got, err := client.GetPodDebugInfo(tt.ctx, "testpod", "testns")
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, len(tt.want), len(got))
+			for i := range tt.want {
+				assert.Equal(t, tt.want[i].PodInterfaceID, got[i].PodInterfaceID)
+				assert.Equal(t, tt.want[i].History[0].Pod, got[i].History[0].Pod)
+			}
+		})
+	}
+}
+
+func TestReassignIPAddress(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	reassign := cns.ReassignIPRequest{
+		IPAddress: "10.0.0.4",
+		Pod: cns.IPConfigRequest{
+			PodInterfaceID:   "testpodinterfaceid",
+			InfraContainerID: "testcontainerid",
+		},
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		mockdo  *mockdo
+		wantErr bool
+	}{
+		{
+			name: "happy case",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				objToReturn:            &cns.Response{},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+		},
+		{
+			name: "bad request",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				errToReturn:            errBadRequest,
+				httpStatusCodeToReturn: http.StatusBadRequest,
+			},
+			wantErr: true,
+		},
+		{
+			name: "http status not ok",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				httpStatusCodeToReturn: http.StatusInternalServerError,
+			},
+			wantErr: true,
+		},
+		{
+			name: "cns return code not zero",
+			ctx:  context.TODO(),
+			mockdo: &mockdo{
+				objToReturn: &cns.Response{
+					ReturnCode: types.UnexpectedError,
+				},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				client: tt.mockdo,
+				routes: emptyRoutes,
+			}
+			err := client.ReassignIPAddress(tt.ctx, reassign)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
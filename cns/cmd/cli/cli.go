@@ -13,11 +13,17 @@ import (
 )
 
 const (
-	envCNSIPAddress = "CNSIpAddress"
-	envCNSPort      = "CNSPort"
-	getCmdArg       = "get"
-	getInMemoryData = "getInMemory"
-	getPodCmdArg    = "getPodContexts"
+	envCNSIPAddress         = "CNSIpAddress"
+	envCNSPort              = "CNSPort"
+	envCNIStateFile         = "CNIStateFile"
+	envCNSEndpointStateFile = "CNSEndpointStateFile"
+	envConfirm              = "CNSDebugConfirm"
+	getCmdArg               = "get"
+	getInMemoryData         = "getInMemory"
+	getPodCmdArg            = "getPodContexts"
+	podCmdArg               = "pod"
+	releaseCmdArg           = "release"
+	reassignCmdArg          = "reassign"
 )
 
 func HandleCNSClientCommands(ctx context.Context, cmd string, arg string) error {
@@ -36,8 +42,14 @@ func HandleCNSClientCommands(ctx context.Context, cmd string, arg string) error
 		return getPodCmd(ctx, cnsClient)
 	case strings.EqualFold(getInMemoryData, cmd):
 		return getInMemory(ctx, cnsClient)
+	case strings.EqualFold(podCmdArg, cmd):
+		return podCmd(ctx, cnsClient, arg)
+	case strings.EqualFold(releaseCmdArg, cmd):
+		return releaseCmd(ctx, cnsClient, arg)
+	case strings.EqualFold(reassignCmdArg, cmd):
+		return reassignCmd(ctx, cnsClient, arg)
 	default:
-		return fmt.Errorf("No debug cmd supplied, options are: %v", getCmdArg)
+		return fmt.Errorf("No debug cmd supplied, options are: %v", []string{getCmdArg, getPodCmdArg, getInMemoryData, podCmdArg, releaseCmdArg, reassignCmdArg})
 	}
 }
 
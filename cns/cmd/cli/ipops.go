@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+var errNotConfirmed = errors.New("not confirmed, nothing changed")
+
+// confirm asks to type ip to go ahead with a change, unless envConfirm is set to yes for scripted use.
+func confirm(in io.Reader, ip string) error {
+	if strings.EqualFold(os.Getenv(envConfirm), "yes") {
+		return nil
+	}
+	fmt.Printf("Type %s to confirm: ", ip)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, "failed to read confirmation")
+	}
+	if strings.TrimSpace(line) != ip {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+func assignedIP(ctx context.Context, cnsClient *client.Client, ip string) (*cns.IPConfigurationStatus, error) {
+	assigned, err := cnsClient.GetIPAddressesMatchingStates(ctx, types.Assigned)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the assigned IPs from CNS")
+	}
+	for i := range assigned {
+		if assigned[i].IPAddress == ip && assigned[i].PodInfo != nil {
+			return &assigned[i], nil
+		}
+	}
+	return nil, errors.Errorf("%s is not assigned to a pod by CNS", ip)
+}
+
+// releaseCmd releases an IP stuck assigned to a pod, e.g. one leaked by a failed CNI DEL, as the CNI would on DEL.
+func releaseCmd(ctx context.Context, cnsClient *client.Client, ip string) error {
+	ipConfig, err := assignedIP(ctx, cnsClient, ip)
+	if err != nil {
+		return err
+	}
+	podInfo := ipConfig.PodInfo
+	orchestratorContext, err := podInfo.OrchestratorContext()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal orchestrator context")
+	}
+
+	fmt.Printf("Releasing %s of NC %s from pod %s/%s interface [%s] infra container [%s]\n", ip, ipConfig.NCID,
+		podInfo.Namespace(), podInfo.Name(), podInfo.InterfaceID(), podInfo.InfraContainerID())
+	if err := confirm(os.Stdin, ip); err != nil {
+		return err
+	}
+	err = cnsClient.ReleaseIPAddress(ctx, cns.IPConfigRequest{
+		PodInterfaceID:      podInfo.InterfaceID(),
+		InfraContainerID:    podInfo.InfraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to release %s", ip)
+	}
+	fmt.Printf("Released %s\n", ip)
+	return nil
+}
+
+// reassignCmd reassigns an assigned IP to the pod whose CNI endpoint uses it, e.g. when the pod sandbox was recreated
+// and CNS still has the IP assigned to the old one.
+func reassignCmd(ctx context.Context, cnsClient *client.Client, ip string) error {
+	ipConfig, err := assignedIP(ctx, cnsClient, ip)
+	if err != nil {
+		return err
+	}
+	cniState, err := loadCNIState()
+	if err != nil {
+		return err
+	}
+	if cniState == nil {
+		return errors.New("no CNI state, reassign needs the CNI endpoint using the IP")
+	}
+
+	var podInfo cns.PodInfo
+	for _, nw := range cniState.Networks() {
+		for _, ep := range nw.Endpoints {
+			for _, epIP := range ep.IPAddresses {
+				if epIP.IP.String() == ip {
+					podInfo = cns.NewPodInfo(ep.ContainerID, ep.ID, ep.PodName, ep.PodNamespace)
+				}
+			}
+		}
+	}
+	if podInfo == nil {
+		return errors.Errorf("no CNI endpoint uses %s", ip)
+	}
+	orchestratorContext, err := podInfo.OrchestratorContext()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal orchestrator context")
+	}
+
+	current := ipConfig.PodInfo
+	fmt.Printf("Reassigning %s of NC %s\n  from pod %s/%s interface [%s] infra container [%s]\n  to pod %s/%s interface [%s] infra container [%s]\n",
+		ip, ipConfig.NCID, current.Namespace(), current.Name(), current.InterfaceID(), current.InfraContainerID(),
+		podInfo.Namespace(), podInfo.Name(), podInfo.InterfaceID(), podInfo.InfraContainerID())
+	if err := confirm(os.Stdin, ip); err != nil {
+		return err
+	}
+	err = cnsClient.ReassignIPAddress(ctx, cns.ReassignIPRequest{
+		IPAddress: ip,
+		Pod: cns.IPConfigRequest{
+			PodInterfaceID:      podInfo.InterfaceID(),
+			InfraContainerID:    podInfo.InfraContainerID(),
+			OrchestratorContext: orchestratorContext,
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to reassign %s", ip)
+	}
+	fmt.Printf("Reassigned %s\n", ip)
+	return nil
+}
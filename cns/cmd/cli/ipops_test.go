@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirm(t *testing.T) {
+	const ip = "10.0.0.1"
+	tests := []struct {
+		name    string
+		env     string
+		input   string
+		wantErr error
+	}{
+		{name: "typed ip", input: ip + "\n"},
+		{name: "typed ip without newline", input: ip},
+		{name: "typed ip with spaces", input: "  " + ip + "  \n"},
+		{name: "wrong ip", input: "10.0.0.2\n", wantErr: errNotConfirmed},
+		{name: "yes is not the ip", input: "yes\n", wantErr: errNotConfirmed},
+		{name: "no input", wantErr: errNotConfirmed},
+		{name: "confirmed by env", env: "yes"},
+		{name: "confirmed by env in any case", env: "YES"},
+		{name: "env not yes", env: "no", input: "10.0.0.2\n", wantErr: errNotConfirmed},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envConfirm, tt.env)
+			err := confirm(strings.NewReader(tt.input), ip)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
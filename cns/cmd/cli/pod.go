@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/network"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	defaultCNSEndpointStateFile = "/var/run/azure-cns/azure-endpoints.json"
+	cniLockName                 = "azure-vnet"
+)
+
+// podEndpoint is the endpoint record of a pod, from the CNI state or from the endpoint state CNS keeps when it manages it.
+type podEndpoint struct {
+	source      string
+	id          string
+	containerID string
+	hostIfName  string
+	ips         []net.IPNet
+}
+
+func parsePod(arg string) (name, namespace string, err error) {
+	parts := strings.Split(arg, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return parts[0], "default", nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "": //nolint:gomnd // namespace/name
+		return parts[1], parts[0], nil
+	}
+	return "", "", errors.Errorf("pod %q is not namespace/name", arg)
+}
+
+// podCmd prints what CNS, the CNI, the host and NPM know about the networking of a pod. Each section is best effort,
+// so a missing state file or unreachable API server doesn't hide the others.
+func podCmd(ctx context.Context, cnsClient *client.Client, arg string) error {
+	name, namespace, err := parsePod(arg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("CNS IPs of %s/%s:\n", namespace, name)
+	ips, err := cnsClient.GetPodDebugInfo(ctx, name, namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the pod IPs from CNS")
+	}
+	if len(ips) == 0 {
+		fmt.Println("  none, CNS has no IP assigned to the pod in its kept history")
+	}
+	for i := range ips {
+		ip := &ips[i]
+		nc := ip.NetworkContainer
+		fmt.Printf("  %s %s interface [%s] infra container [%s]\n", ip.IPConfigurationStatus.IPAddress, ip.IPConfigurationStatus.GetState(),
+			ip.PodInterfaceID, ip.InfraContainerID)
+		fmt.Printf("    NC %s version %s host version %s subnet %s/%d gateway %s\n", nc.ID, nc.Version, nc.HostVersion,
+			nc.Subnet.IPAddress, nc.Subnet.PrefixLength, nc.Gateway)
+		for _, transition := range ip.History {
+			fmt.Printf("    %s %s -> %s %s\n", transition.Time.Format("2006-01-02T15:04:05.000Z07:00"), transition.From, transition.To, transition.Pod)
+		}
+	}
+
+	fmt.Println("Endpoints:")
+	endpoints, errs := podEndpoints(name, namespace)
+	for _, err := range errs {
+		fmt.Printf("  ! %v\n", err)
+	}
+	if len(endpoints) == 0 {
+		fmt.Println("  none, neither the CNI nor CNS has an endpoint record for the pod")
+	}
+	var podIPs []net.IPNet
+	for _, ep := range endpoints {
+		fmt.Printf("  %s %s container %s host interface [%s] IPs %v\n", ep.source, ep.id, ep.containerID, ep.hostIfName, ep.ips)
+		podIPs = append(podIPs, ep.ips...)
+	}
+
+	fmt.Println("Host links and routes:")
+	for i := range podIPs {
+		routes, err := hostRoutes(podIPs[i].IP)
+		if err != nil {
+			fmt.Printf("  ! %v\n", err)
+			continue
+		}
+		if len(routes) == 0 {
+			fmt.Printf("  none to %s\n", podIPs[i].IP)
+		}
+		for _, route := range routes {
+			fmt.Printf("  %s\n", route)
+		}
+	}
+
+	fmt.Println("NetworkPolicies selecting the pod:")
+	policies, err := selectingPolicies(ctx, name, namespace)
+	if err != nil {
+		fmt.Printf("  ! %v\n", err)
+		return nil
+	}
+	if len(policies) == 0 {
+		fmt.Println("  none, the pod isn't isolated")
+	}
+	for _, policy := range policies {
+		fmt.Printf("  %s\n", policy)
+	}
+	return nil
+}
+
+// readOnlyStore opens a state file to read it. The store replaces the file atomically when it writes it, so it is read
+// without taking the lock its owner holds while changing it.
+func readOnlyStore(path, lockName string) (store.KeyValueStore, error) {
+	lock, err := processlock.NewFileLock(platform.CNILockPath + lockName + store.LockExtension)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create lock for %s", path)
+	}
+	st, err := store.NewJsonFileStore(path, lock)
+	return st, errors.Wrapf(err, "failed to open %s", path)
+}
+
+// podEndpoints returns the endpoint records of the pod from the CNI state and the CNS endpoint state, and the errors
+// reading the state files which exist.
+func podEndpoints(name, namespace string) ([]podEndpoint, []error) {
+	var (
+		endpoints []podEndpoint
+		errs      []error
+	)
+
+	cniState, err := loadCNIState()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if cniState != nil {
+		for _, nw := range cniState.Networks() {
+			for _, ep := range nw.Endpoints {
+				if ep.PodName == name && ep.PodNamespace == namespace {
+					endpoints = append(endpoints, podEndpoint{
+						source:      "CNI network " + nw.ID,
+						id:          ep.ID,
+						containerID: ep.ContainerID,
+						hostIfName:  ep.HostIfName,
+						ips:         ep.IPAddresses,
+					})
+				}
+			}
+		}
+	}
+
+	path := envOrDefault(envCNSEndpointStateFile, defaultCNSEndpointStateFile)
+	if _, err := os.Stat(path); err != nil {
+		return endpoints, errs
+	}
+	st, err := readOnlyStore(path, strings.TrimSuffix(filepath.Base(path), ".json"))
+	if err != nil {
+		return endpoints, append(errs, err)
+	}
+	cnsEndpoints := map[string]*restserver.EndpointInfo{}
+	if err := st.Read(restserver.EndpointStoreKey, &cnsEndpoints); err != nil && !errors.Is(err, store.ErrKeyNotFound) && !errors.Is(err, store.ErrStoreEmpty) {
+		return endpoints, append(errs, errors.Wrapf(err, "failed to read CNS endpoint state %s", path))
+	}
+	for containerID, ep := range cnsEndpoints {
+		if ep.PodName != name || ep.PodNamespace != namespace {
+			continue
+		}
+		for ifName, ipInfo := range ep.IfnameToIPMap {
+			endpoints = append(endpoints, podEndpoint{
+				source:      "CNS",
+				id:          ifName,
+				containerID: containerID,
+				ips:         append(append([]net.IPNet(nil), ipInfo.IPv4...), ipInfo.IPv6...),
+			})
+		}
+	}
+	return endpoints, errs
+}
+
+// loadCNIState loads the CNI state, or returns nil if it doesn't exist, e.g. when CNS manages the endpoint state.
+func loadCNIState() (*network.State, error) {
+	path := envOrDefault(envCNIStateFile, platform.CNIStateFilePath)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	st, err := readOnlyStore(path, cniLockName)
+	if err != nil {
+		return nil, err
+	}
+	cniState, err := network.LoadState(st)
+	return cniState, errors.Wrapf(err, "failed to load CNI state %s", path)
+}
+
+func envOrDefault(env, def string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return def
+}
+
+// selectingPolicies returns the NetworkPolicies whose podSelector selects the pod, read from the API server.
+func selectingPolicies(ctx context.Context, name, namespace string) ([]string, error) {
+	kubeConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kubeconfig")
+	}
+	clientset, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build clientset")
+	}
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get pod %s/%s", namespace, name)
+	}
+	policies, err := clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list NetworkPolicies in %s", namespace)
+	}
+	return matchPolicies(pod.Labels, policies.Items)
+}
+
+func matchPolicies(podLabels map[string]string, policies []networkingv1.NetworkPolicy) ([]string, error) {
+	var selecting []string
+	for i := range policies {
+		selector, err := metav1.LabelSelectorAsSelector(&policies[i].Spec.PodSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid podSelector in NetworkPolicy %s", policies[i].Name)
+		}
+		if !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+		policyTypes := make([]string, len(policies[i].Spec.PolicyTypes))
+		for j, t := range policies[i].Spec.PolicyTypes {
+			policyTypes[j] = string(t)
+		}
+		selecting = append(selecting, fmt.Sprintf("%s [%s]", policies[i].Name, strings.Join(policyTypes, ",")))
+	}
+	return selecting, nil
+}
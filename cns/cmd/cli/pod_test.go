@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePod(t *testing.T) {
+	tests := []struct {
+		name          string
+		arg           string
+		wantName      string
+		wantNamespace string
+		wantErr       bool
+	}{
+		{name: "name only", arg: "nginx", wantName: "nginx", wantNamespace: "default"},
+		{name: "namespace and name", arg: "kube-system/coredns", wantName: "coredns", wantNamespace: "kube-system"},
+		{name: "empty", arg: "", wantErr: true},
+		{name: "no name", arg: "kube-system/", wantErr: true},
+		{name: "no namespace", arg: "/coredns", wantErr: true},
+		{name: "too many parts", arg: "a/b/c", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			name, namespace, err := parsePod(tt.arg)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantName, name)
+			require.Equal(t, tt.wantNamespace, namespace)
+		})
+	}
+}
+
+func networkPolicy(name string, selector metav1.LabelSelector, policyTypes ...networkingv1.PolicyType) networkingv1.NetworkPolicy {
+	return networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: selector,
+			PolicyTypes: policyTypes,
+		},
+	}
+}
+
+func TestMatchPolicies(t *testing.T) {
+	podLabels := map[string]string{"app": "web", "tier": "frontend"}
+	tests := []struct {
+		name     string
+		policies []networkingv1.NetworkPolicy
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "empty selector selects every pod",
+			policies: []networkingv1.NetworkPolicy{
+				networkPolicy("deny-all", metav1.LabelSelector{}, networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress),
+			},
+			want: []string{"deny-all [Ingress,Egress]"},
+		},
+		{
+			name: "match labels",
+			policies: []networkingv1.NetworkPolicy{
+				networkPolicy("web", metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}, networkingv1.PolicyTypeIngress),
+				networkPolicy("db", metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}, networkingv1.PolicyTypeIngress),
+			},
+			want: []string{"web [Ingress]"},
+		},
+		{
+			name: "match expressions",
+			policies: []networkingv1.NetworkPolicy{
+				networkPolicy("not-backend", metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"backend"}},
+				}}, networkingv1.PolicyTypeEgress),
+				networkPolicy("backend", metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"backend"}},
+				}}),
+			},
+			want: []string{"not-backend [Egress]"},
+		},
+		{
+			name: "no policies",
+		},
+		{
+			name: "invalid selector",
+			policies: []networkingv1.NetworkPolicy{
+				networkPolicy("invalid", metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: "Unknown"},
+				}}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchPolicies(podLabels, tt.policies)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
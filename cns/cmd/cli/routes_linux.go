@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// hostRoutes describes the host routes to ip in the main table, other than the default route, and the links they go through.
+// In transparent mode, the route to a pod goes through its host veth.
+func hostRoutes(ip net.IP) ([]string, error) {
+	family := unix.AF_INET
+	if ip.To4() == nil {
+		family = unix.AF_INET6
+	}
+	routes, err := netlink.NewNetlink().GetIPRoute(&netlink.Route{Family: family})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list host routes")
+	}
+
+	var described []string
+	for _, route := range routes {
+		if route.Dst == nil || !route.Dst.Contains(ip) {
+			continue
+		}
+		if ones, _ := route.Dst.Mask.Size(); ones == 0 {
+			continue
+		}
+		link := fmt.Sprintf("link %d", route.LinkIndex)
+		if iface, err := net.InterfaceByIndex(route.LinkIndex); err == nil {
+			link = fmt.Sprintf("%s mtu %d %s", iface.Name, iface.MTU, iface.Flags)
+		}
+		described = append(described, fmt.Sprintf("route %s dev %s", route.Dst, link))
+	}
+	return described, nil
+}
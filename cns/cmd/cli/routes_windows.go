@@ -0,0 +1,12 @@
+package cli
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// hostRoutes is not supported on Windows, where the pod endpoints are HNS endpoints.
+func hostRoutes(net.IP) ([]string, error) {
+	return nil, errors.New("host routes are not supported on Windows, use Get-HnsEndpoint")
+}
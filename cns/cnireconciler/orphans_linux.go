@@ -0,0 +1,29 @@
+package cnireconciler
+
+import (
+	"github.com/Azure/azure-container-networking/cni/client"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"k8s.io/utils/exec"
+)
+
+// ReconcileOrphans asks the CNI to report the host veths, vlan links and namespaces which belong to none
+// of its endpoints, and to remove them if deleteOrphans is set. It needs a CNI which can dump its state.
+func ReconcileOrphans(deleteOrphans bool) {
+	isGoodVer, err := IsDumpStateVer()
+	if err != nil || !isGoodVer {
+		logger.Errorf("Not reconciling orphaned endpoint links since CNI can't dump its state, err: %v", err)
+		return
+	}
+
+	report, err := client.New(exec.New()).ReconcileOrphans(deleteOrphans)
+	if err != nil {
+		logger.Errorf("Failed to reconcile orphaned endpoint links: %v", err)
+		return
+	}
+	verb := "Found"
+	if deleteOrphans {
+		verb = "Removed"
+	}
+	logger.Printf("%s %d orphaned links and %d orphaned namespaces of CNI endpoints: %+v",
+		verb, len(report.Links), len(report.Namespaces), report)
+}
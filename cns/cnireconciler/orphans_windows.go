@@ -0,0 +1,10 @@
+package cnireconciler
+
+import (
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// ReconcileOrphans does nothing on Windows, where HNS owns the endpoint resources.
+func ReconcileOrphans(_ bool) {
+	logger.Printf("Reconciling orphaned endpoint links is not supported on Windows")
+}
@@ -0,0 +1,40 @@
+package cnireconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/network/rulegc"
+	"k8s.io/utils/exec"
+)
+
+// StartEndpointRuleGC removes the iptables rules and ebtables chains of endpoints which are not
+// in the CNI state every interval, until ctx is done. It needs a CNI which can dump its state.
+func StartEndpointRuleGC(ctx context.Context, interval time.Duration) {
+	isGoodVer, err := IsDumpStateVer()
+	if err != nil || !isGoodVer {
+		logger.Errorf("Not starting endpoint rule GC since CNI can't dump its state, err: %v", err)
+		return
+	}
+
+	gc := rulegc.New()
+	endpointIDs := rulegc.CNIEndpointIDs(exec.New())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := gc.Run(endpointIDs, false)
+			if err != nil {
+				logger.Errorf("Endpoint rule GC failed: %v", err)
+			}
+			if result != nil && (len(result.IPTablesRules) > 0 || len(result.EbtablesChains) > 0) {
+				logger.Printf("Endpoint rule GC removed %d iptables rules and %d ebtables chains of deleted endpoints",
+					len(result.IPTablesRules), len(result.EbtablesChains))
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package cnireconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// StartEndpointRuleGC does nothing on Windows, where CNI doesn't program iptables or ebtables rules.
+func StartEndpointRuleGC(_ context.Context, _ time.Duration) {
+	logger.Printf("Endpoint rule GC is not supported on Windows")
+}
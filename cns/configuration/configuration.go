@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/Azure/azure-container-networking/aitelemetry/otlp"
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
@@ -18,6 +19,12 @@ const (
 	defaultConfigName = "cns_config.json"
 )
 
+// Telemetry exporters selectable with TelemetrySettings.Exporter.
+const (
+	TelemetryExporterAppInsights = "appinsights"
+	TelemetryExporterOTLP        = "otlp"
+)
+
 type CNSConfig struct {
 	ChannelMode                 string
 	EnablePprof                 bool
@@ -38,6 +45,12 @@ type CNSConfig struct {
 	MSISettings                 MSISettings
 	ProgramSNATIPTables         bool
 	ManageEndpointState         bool
+	// EndpointRuleGCIntervalInMins enables removing the rules of deleted CNI endpoints (Linux only)
+	EndpointRuleGCIntervalInMins int
+	// ReconcileOrphanedEndpoints enables reporting the host links and namespaces of unknown CNI endpoints at startup (Linux only)
+	ReconcileOrphanedEndpoints bool
+	// DeleteOrphanedEndpoints makes the reconciliation at startup remove the links and namespaces it reports
+	DeleteOrphanedEndpoints bool
 }
 
 type TelemetrySettings struct {
@@ -63,6 +76,13 @@ type TelemetrySettings struct {
 	DebugMode bool
 	// Interval for sending snapshot events.
 	SnapshotIntervalInMins int
+	// Exporter selects where telemetry is sent, appinsights (default) or otlp.
+	Exporter string
+	// OTLP configures the OpenTelemetry collector telemetry is exported to when Exporter is otlp.
+	OTLP otlp.Config
+	// Flag to enable tracing of IP requests. Spans are exported to the OTLP collector when Exporter
+	// is otlp and logged otherwise.
+	EnableTracing bool
 }
 
 type ManagedSettings struct {
@@ -31,6 +31,11 @@ func NewCNSLogger(fileName string, logLevel, logTarget int, logDir string) (*CNS
 	return &CNSLogger{logger: l}, nil
 }
 
+// SetFormat sets the format of the log records, log.FormatText or log.FormatJSON.
+func (c *CNSLogger) SetFormat(format int) error {
+	return errors.Wrap(c.logger.SetFormat(format), "could not set log format")
+}
+
 func (c *CNSLogger) InitAI(aiConfig aitelemetry.AIConfig, disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
 	th, err := aitelemetry.NewAITelemetry("", aiMetadata, aiConfig)
 	if err != nil {
@@ -38,8 +43,13 @@ func (c *CNSLogger) InitAI(aiConfig aitelemetry.AIConfig, disableTraceLogging, d
 		return
 	}
 
-	c.th = th
 	c.logger.Printf("AI Telemetry Handle created")
+	c.InitTelemetry(th, disableTraceLogging, disableMetricLogging, disableEventLogging)
+}
+
+// InitTelemetry sends traces, metrics and events through th, which may be any TelemetryHandle implementation.
+func (c *CNSLogger) InitTelemetry(th aitelemetry.TelemetryHandle, disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
+	c.th = th
 	c.DisableMetricLogging = disableMetricLogging
 	c.DisableTraceLogging = disableTraceLogging
 	c.DisableEventLogging = disableEventLogging
@@ -21,10 +21,18 @@ func InitLogger(fileName string, logLevel, logTarget int, logDir string) {
 	Log, _ = NewCNSLogger(fileName, logLevel, logTarget, logDir)
 }
 
+func SetFormat(format int) error {
+	return Log.SetFormat(format)
+}
+
 func InitAI(aiConfig aitelemetry.AIConfig, disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
 	Log.InitAI(aiConfig, disableTraceLogging, disableMetricLogging, disableEventLogging)
 }
 
+func InitTelemetry(th aitelemetry.TelemetryHandle, disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
+	Log.InitTelemetry(th, disableTraceLogging, disableMetricLogging, disableEventLogging)
+}
+
 func SetContextDetails(orchestrator, nodeID string) {
 	Log.SetContextDetails(orchestrator, nodeID)
 }
@@ -13,6 +13,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -29,9 +30,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-const (
-	cnsJsonFileName = "azure-cns.json"
-)
+// cnsJsonFileName is the state file of the test service, in a temporary directory so tests don't write into the source tree.
+var cnsJsonFileName string
 
 type IPAddress struct {
 	XMLName   xml.Name `xml:"IPAddress"`
@@ -133,6 +133,13 @@ func TestMain(m *testing.M) {
 	var err error
 	logger.InitLogger("testlogs", 0, 0, "./")
 
+	stateDir, err := os.MkdirTemp("", "cns-restserver")
+	if err != nil {
+		fmt.Printf("Failed to create state directory. Error: %v", err)
+		os.Exit(1)
+	}
+	cnsJsonFileName = filepath.Join(stateDir, "azure-cns.json")
+
 	// Create the service.
 	if err = startService(); err != nil {
 		fmt.Printf("Failed to start CNS Service. Error: %v", err)
@@ -166,6 +173,7 @@ func TestMain(m *testing.M) {
 	// Cleanup.
 	service.Stop()
 	nmAgentServer.Stop()
+	os.RemoveAll(stateDir)
 
 	os.Exit(exitCode)
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -14,14 +15,27 @@ import (
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/tracing"
 	"github.com/pkg/errors"
 )
 
+// logInvocation logs the CNI invocation headers sent with the request, so that the CNS
+// log lines of the request can be matched with the CNI plugin logs of the same invocation.
+func logInvocation(tag string, r *http.Request) {
+	invocationID := r.Header.Get(cns.HeaderInvocationID)
+	if invocationID == "" {
+		return
+	}
+	logger.Printf("[%s] invocationID: %s containerID: %s pod: %s/%s", tag, invocationID,
+		r.Header.Get(cns.HeaderContainerID), r.Header.Get(cns.HeaderPodNamespace), r.Header.Get(cns.HeaderPodName))
+}
+
 // used to request an IPConfig from the CNS state
 func (service *HTTPRestService) requestIPConfigHandler(w http.ResponseWriter, r *http.Request) {
 	var ipconfigRequest cns.IPConfigRequest
 	err := service.Listener.Decode(w, r, &ipconfigRequest)
 	operationName := "requestIPConfigHandler"
+	logInvocation(service.Name+operationName, r)
 	logger.Request(service.Name+operationName, ipconfigRequest, err)
 	if err != nil {
 		return
@@ -45,7 +59,14 @@ func (service *HTTPRestService) requestIPConfigHandler(w http.ResponseWriter, r
 	// record a pod requesting an IP
 	service.podsPendingIPAssignment.Push(podInfo.Key())
 
+	_, span := tracing.StartSpan(r.Context(), "cns.assignIPConfig", tracing.WithAttributes(map[string]string{
+		"k8s.pod.name":      podInfo.Name(),
+		"k8s.pod.namespace": podInfo.Namespace(),
+		"container.id":      ipconfigRequest.InfraContainerID,
+	}))
 	podIPInfo, err := requestIPConfigHelper(service, ipconfigRequest)
+	span.SetAttribute("ip", podIPInfo.PodIPConfig.IPAddress)
+	span.End(err)
 	if err != nil {
 		reserveResp := &cns.IPConfigResponse{
 			Response: cns.Response{
@@ -70,7 +91,9 @@ func (service *HTTPRestService) requestIPConfigHandler(w http.ResponseWriter, r
 
 	// Check if http rest service managed endpoint state is set
 	if service.Options[common.OptManageEndpointState] == true {
+		_, span := tracing.StartSpan(r.Context(), "cns.updateEndpointState")
 		err = service.updateEndpointState(ipconfigRequest, podInfo, podIPInfo)
+		span.End(err)
 		if err != nil {
 			reserveResp := &cns.IPConfigResponse{
 				Response: cns.Response{
@@ -90,7 +113,8 @@ func (service *HTTPRestService) requestIPConfigHandler(w http.ResponseWriter, r
 		Response: cns.Response{
 			ReturnCode: types.Success,
 		},
-		PodIpInfo: podIPInfo,
+		PodIpInfo:           podIPInfo,
+		SecondaryInterfaces: service.getSecondaryInterfaces(podInfo),
 	}
 	w.Header().Set(cnsReturnCode, reserveResp.Response.ReturnCode.String())
 	err = service.Listener.Encode(w, &reserveResp)
@@ -167,6 +191,7 @@ func (service *HTTPRestService) updateEndpointState(ipconfigRequest cns.IPConfig
 func (service *HTTPRestService) releaseIPConfigHandler(w http.ResponseWriter, r *http.Request) {
 	var req cns.IPConfigRequest
 	err := service.Listener.Decode(w, r, &req)
+	logInvocation(service.Name+"releaseIPConfigHandler", r)
 	logger.Request(service.Name+"releaseIPConfigHandler", req, err)
 	if err != nil {
 		resp := cns.Response{
@@ -184,7 +209,10 @@ func (service *HTTPRestService) releaseIPConfigHandler(w http.ResponseWriter, r
 
 	// Check if http rest service managed endpoint state is set
 	if service.Options[common.OptManageEndpointState] == true {
-		if err = service.removeEndpointState(podInfo); err != nil {
+		_, span := tracing.StartSpan(r.Context(), "cns.removeEndpointState")
+		err = service.removeEndpointState(podInfo)
+		span.End(err)
+		if err != nil {
 			resp := cns.Response{
 				ReturnCode: types.UnexpectedError,
 				Message:    err.Error(),
@@ -197,7 +225,10 @@ func (service *HTTPRestService) releaseIPConfigHandler(w http.ResponseWriter, r
 		}
 	}
 
-	if err = service.releaseIPConfig(podInfo); err != nil {
+	_, span := tracing.StartSpan(r.Context(), "cns.releaseIPConfig")
+	err = service.releaseDesiredIPConfig(podInfo, req.DesiredIPAddress)
+	span.End(err)
+	if err != nil {
 		returnCode = types.UnexpectedError
 		message = err.Error()
 		logger.Errorf("releaseIPConfigHandler releaseIPConfig failed because %v, release IP config info %s", message, req)
@@ -274,6 +305,11 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState types.IPState, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) {
 	if ipConfig, found := service.PodIPConfigState[ipID]; found {
 		logger.Printf("[updateIPConfigState] Changing IpId [%s] state to [%s], podInfo [%+v]. Current config [%+v]", ipID, updatedState, podInfo, ipConfig)
+		pod := podInfo
+		if pod == nil {
+			pod = ipConfig.PodInfo
+		}
+		service.recordIPTransition(ipID, ipConfig.GetState(), updatedState, pod)
 		ipConfig.SetState(updatedState)
 		ipConfig.PodInfo = podInfo
 		service.PodIPConfigState[ipID] = ipConfig
@@ -423,19 +459,32 @@ func (service *HTTPRestService) unassignIPConfig(ipconfig cns.IPConfigurationSta
 		return cns.IPConfigurationStatus{}, err
 	}
 
+	// the pod may have been indexed to another IP since this one was assigned, that one stays indexed.
+	if service.PodIPIDByPodInterfaceKey[podInfo.Key()] != ipconfig.ID {
+		return ipconfig, nil
+	}
 	delete(service.PodIPIDByPodInterfaceKey, podInfo.Key())
 	logger.Printf("[setIPConfigAsAvailable] Deleted outdated pod info %s from PodIPIDByOrchestratorContext since IP %s with ID %s will be released and set as Available",
 		podInfo.Key(), ipconfig.IPAddress, ipconfig.ID)
 	return ipconfig, nil
 }
 
-// Todo - CNI should also pass the IPAddress which needs to be released to validate if that is the right IP allcoated
-// in the first place.
+// releaseIPConfig releases the IP assigned to the pod.
 func (service *HTTPRestService) releaseIPConfig(podInfo cns.PodInfo) error {
+	return service.releaseDesiredIPConfig(podInfo, "")
+}
+
+// releaseDesiredIPConfig releases desiredIP if it is assigned to the pod, else the IP the pod is indexed to.
+// The caller names the IP it is done with, which may not be the indexed one if the IP leaked.
+func (service *HTTPRestService) releaseDesiredIPConfig(podInfo cns.PodInfo, desiredIP string) error {
+	// flush the connections of the pod before the IP can be assigned to another pod,
+	// without holding the lock while the conntrack table is walked
+	service.deleteConntrackEntries(podInfo, desiredIP)
+
 	service.Lock()
 	defer service.Unlock()
 
-	ipID := service.PodIPIDByPodInterfaceKey[podInfo.Key()]
+	ipID := service.podIPConfigID(podInfo, desiredIP)
 	if ipID != "" {
 		if ipconfig, isExist := service.PodIPConfigState[ipID]; isExist {
 			logger.Printf("[releaseIPConfig] Releasing IP %+v for pod %+v", ipconfig.IPAddress, podInfo)
@@ -457,6 +506,34 @@ func (service *HTTPRestService) releaseIPConfig(podInfo cns.PodInfo) error {
 	return nil
 }
 
+// podIPConfigID returns the ID of desiredIP if it is assigned to the pod, else of the IP the pod is indexed to.
+// It does not take a lock.
+func (service *HTTPRestService) podIPConfigID(podInfo cns.PodInfo, desiredIP string) string {
+	if desiredIP != "" {
+		for id, ipconfig := range service.PodIPConfigState {
+			if ipconfig.IPAddress == desiredIP && ipconfig.GetState() == types.Assigned &&
+				ipconfig.PodInfo != nil && ipconfig.PodInfo.Key() == podInfo.Key() {
+				return id
+			}
+		}
+	}
+	return service.PodIPIDByPodInterfaceKey[podInfo.Key()]
+}
+
+// deleteConntrackEntries deletes the conntrack entries of the IP which is released for the pod, if it has one.
+func (service *HTTPRestService) deleteConntrackEntries(podInfo cns.PodInfo, desiredIP string) {
+	service.RLock()
+	ipconfig, isExist := service.PodIPConfigState[service.podIPConfigID(podInfo, desiredIP)]
+	service.RUnlock()
+	if !isExist {
+		return
+	}
+
+	if _, err := service.netlink.DeleteConntrackEntries(net.ParseIP(ipconfig.IPAddress)); err != nil {
+		logger.Errorf("[releaseIPConfig] Failed to delete conntrack entries of IP %s: %v", ipconfig.IPAddress, err)
+	}
+}
+
 // MarkExistingIPsAsPendingRelease is called when CNS is starting up and there are existing ipconfigs in the CRD that are marked as pending.
 func (service *HTTPRestService) MarkExistingIPsAsPendingRelease(pendingIPIDs []string) error {
 	service.Lock()
@@ -469,6 +546,7 @@ func (service *HTTPRestService) MarkExistingIPsAsPendingRelease(pendingIPIDs []s
 			}
 
 			logger.Printf("[MarkExistingIPsAsPending]: Marking IP [%+v] to PendingRelease", ipconfig)
+			service.recordIPTransition(id, ipconfig.GetState(), types.PendingRelease, ipconfig.PodInfo)
 			ipconfig.SetState(types.PendingRelease)
 			service.PodIPConfigState[id] = ipconfig
 		} else {
@@ -576,3 +654,40 @@ func requestIPConfigHelper(service *HTTPRestService, req cns.IPConfigRequest) (c
 	// return any free IPConfig
 	return service.AssignAnyAvailableIPConfig(podInfo)
 }
+
+// getSecondaryInterfaces returns the secondary interfaces of a pod, which are the NCs with a MAC address created for it.
+// They are sorted by NC ID so that the pod gets them on the same interfaces on every request.
+func (service *HTTPRestService) getSecondaryInterfaces(podInfo cns.PodInfo) []cns.PodIpInfo {
+	service.RLock()
+	defer service.RUnlock()
+
+	var interfaces []cns.PodIpInfo
+	for ncID, ncStatus := range service.state.ContainerStatus {
+		req := ncStatus.CreateNetworkContainerRequest
+		if req.MacAddress == "" || len(req.OrchestratorContext) == 0 {
+			continue
+		}
+
+		ncPodInfo, err := cns.UnmarshalPodInfo(req.OrchestratorContext)
+		if err != nil {
+			logger.Errorf("Failed to unmarshal orchestrator context of NC %s: %v", ncID, err)
+			continue
+		}
+		if ncPodInfo.Name() != podInfo.Name() || ncPodInfo.Namespace() != podInfo.Namespace() {
+			continue
+		}
+
+		interfaces = append(interfaces, cns.PodIpInfo{
+			PodIPConfig:                     req.IPConfiguration.IPSubnet,
+			NetworkContainerPrimaryIPConfig: req.IPConfiguration,
+			NetworkContainerID:              ncID,
+			MacAddress:                      req.MacAddress,
+			Routes:                          req.Routes,
+		})
+	}
+
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].NetworkContainerID < interfaces[j].NetworkContainerID
+	})
+	return interfaces
+}
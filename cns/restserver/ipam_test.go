@@ -4,6 +4,7 @@
 package restserver
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/fakes"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -43,6 +45,7 @@ func getTestService() *HTTPRestService {
 	httpsvc, _ := NewHTTPRestService(&config, &fakes.WireserverClientFake{}, &fakes.NMAgentClientFake{}, store.NewMockStore(""))
 	svc = httpsvc.(*HTTPRestService)
 	svc.IPAMPoolMonitor = &fakes.MonitorFake{}
+	svc.netlink = netlink.NewMockNetlink(false, "")
 	setOrchestratorTypeInternal(cns.KubernetesCRD)
 
 	return svc
@@ -507,6 +510,55 @@ func TestIPAMReleaseIPIdempotency(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unexpected failure releasing IP: %+v", err)
 	}
+
+	// the conntrack entries are flushed once, when the IP is released
+	if deleted := svc.netlink.(*netlink.MockNetlink).ConntrackDeletedIPs; len(deleted) != 1 || !deleted[0].Equal(net.ParseIP(testIP1)) {
+		t.Fatalf("Expected conntrack entries of %s to be deleted once, got %v", testIP1, deleted)
+	}
+}
+
+func TestIPAMReleaseDesiredIP(t *testing.T) {
+	svc := getTestService()
+	// testIP1 is left over from an earlier ADD of the pod, the index points to testIP2
+	state1, _ := NewPodStateWithOrchestratorContext(testIP1, testPod1GUID, testNCID, types.Assigned, 24, 0, testPod1Info)
+	state2, _ := NewPodStateWithOrchestratorContext(testIP2, testPod2GUID, testNCID, types.Assigned, 24, 0, testPod1Info)
+	ipconfigs := map[string]cns.IPConfigurationStatus{
+		state1.ID: state1,
+		state2.ID: state2,
+	}
+
+	err := UpdatePodIpConfigState(t, svc, ipconfigs)
+	if err != nil {
+		t.Fatalf("Expected to not fail adding IPs to state: %+v", err)
+	}
+	svc.PodIPIDByPodInterfaceKey[testPod1Info.Key()] = state2.ID
+
+	// Release the desired IP, not the indexed one
+	err = svc.releaseDesiredIPConfig(testPod1Info, testIP1)
+	if err != nil {
+		t.Fatalf("Unexpected failure releasing IP: %+v", err)
+	}
+	if ipconfig := svc.PodIPConfigState[state1.ID]; ipconfig.GetState() != types.Available {
+		t.Fatalf("Expected %s to be released, got state %s", testIP1, ipconfig.GetState())
+	}
+	if ipconfig := svc.PodIPConfigState[state2.ID]; ipconfig.GetState() != types.Assigned {
+		t.Fatalf("Expected %s to stay assigned, got state %s", testIP2, ipconfig.GetState())
+	}
+	if id := svc.PodIPIDByPodInterfaceKey[testPod1Info.Key()]; id != state2.ID {
+		t.Fatalf("Expected the pod to still be indexed to %s, got %s", state2.ID, id)
+	}
+
+	// a desired IP which is not assigned to the pod falls back to the indexed IP
+	err = svc.releaseDesiredIPConfig(testPod1Info, testIP3)
+	if err != nil {
+		t.Fatalf("Unexpected failure releasing IP: %+v", err)
+	}
+	if ipconfig := svc.PodIPConfigState[state2.ID]; ipconfig.GetState() != types.Available {
+		t.Fatalf("Expected %s to be released, got state %s", testIP2, ipconfig.GetState())
+	}
+	if _, ok := svc.PodIPIDByPodInterfaceKey[testPod1Info.Key()]; ok {
+		t.Fatal("Expected the pod to be removed from the index")
+	}
 }
 
 func TestIPAMAllocateIPIdempotency(t *testing.T) {
@@ -782,3 +834,46 @@ func TestIPAMMarkExistingIPConfigAsPending(t *testing.T) {
 		t.Fatalf("Expected to see ID %v in pending release ipconfigs, actual %+v", testPod1GUID, assignedIPConfigs)
 	}
 }
+
+func TestIPAMGetSecondaryInterfaces(t *testing.T) {
+	svc := getTestService()
+
+	pod1Context, err := testPod1Info.OrchestratorContext()
+	assert.NoError(t, err)
+	pod2Context, err := testPod2Info.OrchestratorContext()
+	assert.NoError(t, err)
+
+	secondaryNC := func(ncID, ip, mac string, orchestratorContext json.RawMessage) containerstatus {
+		return containerstatus{
+			ID: ncID,
+			CreateNetworkContainerRequest: cns.CreateNetworkContainerRequest{
+				NetworkContainerid:  ncID,
+				OrchestratorContext: orchestratorContext,
+				IPConfiguration: cns.IPConfiguration{
+					IPSubnet:         cns.IPSubnet{IPAddress: ip, PrefixLength: 24},
+					GatewayIPAddress: "192.168.0.1",
+				},
+				Routes:     []cns.Route{{IPAddress: "192.168.0.0/16", GatewayIPAddress: "192.168.0.1"}},
+				MacAddress: mac,
+			},
+		}
+	}
+	svc.state.ContainerStatus = map[string]containerstatus{
+		"nc-b": secondaryNC("nc-b", "192.168.1.4", "12:34:56:78:9a:02", pod1Context),
+		"nc-a": secondaryNC("nc-a", "192.168.2.4", "12:34:56:78:9a:01", pod1Context),
+		"nc-c": secondaryNC("nc-c", "192.168.3.4", "12:34:56:78:9a:03", pod2Context),
+		// NCs without a MAC address are not secondary interfaces.
+		"nc-d": secondaryNC("nc-d", "192.168.4.4", "", pod1Context),
+	}
+
+	interfaces := svc.getSecondaryInterfaces(testPod1Info)
+	assert.Len(t, interfaces, 2)
+	assert.Equal(t, "nc-a", interfaces[0].NetworkContainerID)
+	assert.Equal(t, "192.168.2.4", interfaces[0].PodIPConfig.IPAddress)
+	assert.Equal(t, "12:34:56:78:9a:01", interfaces[0].MacAddress)
+	assert.Equal(t, "192.168.0.1", interfaces[0].NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+	assert.Len(t, interfaces[0].Routes, 1)
+	assert.Equal(t, "nc-b", interfaces[1].NetworkContainerID)
+
+	assert.Empty(t, svc.getSecondaryInterfaces(testPod3Info))
+}
@@ -0,0 +1,178 @@
+package restserver
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/pkg/errors"
+)
+
+// ipHistorySize is the number of state transitions kept per IP for the debug API.
+const ipHistorySize = 16
+
+func podName(podInfo cns.PodInfo) string {
+	if podInfo == nil {
+		return ""
+	}
+	return podInfo.Namespace() + "/" + podInfo.Name()
+}
+
+// recordIPTransition keeps the transition of an IP for the debug API, dropping the oldest one once ipHistorySize are kept.
+// pod is the pod the IP is assigned to after the transition, or was assigned to before it when it is released.
+// Caller holds the service lock.
+func (service *HTTPRestService) recordIPTransition(ipID string, from, to types.IPState, pod cns.PodInfo) {
+	if service.ipHistory == nil {
+		service.ipHistory = make(map[string][]cns.IPStateTransition)
+	}
+	history := append(service.ipHistory[ipID], cns.IPStateTransition{From: from, To: to, Pod: podName(pod), Time: time.Now()})
+	if len(history) > ipHistorySize {
+		history = history[len(history)-ipHistorySize:]
+	}
+	service.ipHistory[ipID] = history
+}
+
+func (service *HTTPRestService) handleDebugPod(w http.ResponseWriter, r *http.Request) {
+	var req cns.GetPodDebugRequest
+	if err := service.Listener.Decode(w, r, &req); err != nil {
+		resp := cns.GetPodDebugResponse{
+			Response: cns.Response{
+				ReturnCode: types.UnexpectedError,
+				Message:    err.Error(),
+			},
+		}
+		err = service.Listener.Encode(w, &resp)
+		logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
+		return
+	}
+	resp := cns.GetPodDebugResponse{
+		IPs: service.getPodDebugInfo(req.PodName, req.PodNamespace),
+	}
+	err := service.Listener.Encode(w, &resp)
+	logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
+}
+
+// getPodDebugInfo returns the IPs which are assigned to the pod, or were assigned to it in their kept history, sorted by IP.
+func (service *HTTPRestService) getPodDebugInfo(name, namespace string) []cns.PodIPDebugInfo {
+	service.RLock()
+	defer service.RUnlock()
+
+	pod := namespace + "/" + name
+	ips := []cns.PodIPDebugInfo{}
+	for ipID, ipConfig := range service.PodIPConfigState {
+		assigned := ipConfig.GetState() == types.Assigned && podName(ipConfig.PodInfo) == pod
+		history := service.ipHistory[ipID]
+		if !assigned && !historyHasPod(history, pod) {
+			continue
+		}
+
+		info := cns.PodIPDebugInfo{
+			IPConfigurationStatus: ipConfig,
+			History:               append([]cns.IPStateTransition(nil), history...),
+			NetworkContainer:      cns.NetworkContainerDebugInfo{ID: ipConfig.NCID},
+		}
+		if assigned {
+			info.PodInterfaceID = ipConfig.PodInfo.InterfaceID()
+			info.InfraContainerID = ipConfig.PodInfo.InfraContainerID()
+		}
+		if ncStatus, ok := service.state.ContainerStatus[ipConfig.NCID]; ok {
+			info.NetworkContainer.Version = ncStatus.CreateNetworkContainerRequest.Version
+			info.NetworkContainer.HostVersion = ncStatus.HostVersion
+			info.NetworkContainer.Subnet = ncStatus.CreateNetworkContainerRequest.IPConfiguration.IPSubnet
+			info.NetworkContainer.Gateway = ncStatus.CreateNetworkContainerRequest.IPConfiguration.GatewayIPAddress
+		}
+		ips = append(ips, info)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].IPConfigurationStatus.IPAddress < ips[j].IPConfigurationStatus.IPAddress
+	})
+	return ips
+}
+
+func historyHasPod(history []cns.IPStateTransition, pod string) bool {
+	for i := range history {
+		if history[i].Pod == pod {
+			return true
+		}
+	}
+	return false
+}
+
+func (service *HTTPRestService) handleDebugReassignIPAddress(w http.ResponseWriter, r *http.Request) {
+	var req cns.ReassignIPRequest
+	err := service.Listener.Decode(w, r, &req)
+	logger.Request(service.Name+"handleDebugReassignIPAddress", req, err)
+	if err != nil {
+		return
+	}
+
+	podInfo, returnCode, message := service.validateIPConfigRequest(req.Pod)
+	if returnCode == types.Success {
+		if err := service.reassignIPConfig(req.IPAddress, podInfo); err != nil {
+			returnCode = types.UnexpectedError
+			message = err.Error()
+			logger.Errorf("handleDebugReassignIPAddress failed because %v, request %+v", message, req)
+		}
+	}
+	resp := cns.Response{
+		ReturnCode: returnCode,
+		Message:    message,
+	}
+	w.Header().Set(cnsReturnCode, resp.ReturnCode.String())
+	err = service.Listener.Encode(w, &resp)
+	logger.ResponseEx(service.Name, req, resp, resp.ReturnCode, err)
+}
+
+// reassignIPConfig moves an assigned IP to the pod interface of podInfo, with the endpoint state CNS keeps for it.
+// podInfo is either a recreated sandbox of the pod the IP is assigned to, or another pod without an IP.
+func (service *HTTPRestService) reassignIPConfig(ipAddress string, podInfo cns.PodInfo) error {
+	service.Lock()
+	defer service.Unlock()
+
+	var ipConfig *cns.IPConfigurationStatus
+	for id := range service.PodIPConfigState {
+		if c := service.PodIPConfigState[id]; c.IPAddress == ipAddress {
+			ipConfig = &c
+			break
+		}
+	}
+	if ipConfig == nil {
+		return errors.Errorf("IP %s not found in pool", ipAddress)
+	}
+	if ipConfig.GetState() != types.Assigned || ipConfig.PodInfo == nil {
+		return errors.Errorf("IP %s is %s, only an assigned IP can be reassigned", ipAddress, ipConfig.GetState())
+	}
+	previous := ipConfig.PodInfo
+	if previous.Key() == podInfo.Key() && previous.InterfaceID() == podInfo.InterfaceID() {
+		logger.Printf("[reassignIPConfig] IP %s is already assigned to pod %+v", ipAddress, podInfo)
+		return nil
+	}
+	if ipID, ok := service.PodIPIDByPodInterfaceKey[podInfo.Key()]; ok && ipID != ipConfig.ID {
+		return errors.Errorf("pod %s already has IP %s assigned, release it first", podInfo.Key(), service.PodIPConfigState[ipID].IPAddress)
+	}
+
+	logger.Printf("[reassignIPConfig] Reassigning IP %s from pod %+v to pod %+v", ipAddress, previous, podInfo)
+	if err := service.assignIPConfig(*ipConfig, podInfo); err != nil {
+		return err
+	}
+	// the previous pod is unindexed once the IP is assigned, so a failure leaves it indexed to the IP it still has.
+	if previous.Key() != podInfo.Key() {
+		delete(service.PodIPIDByPodInterfaceKey, previous.Key())
+	}
+
+	if service.Options[common.OptManageEndpointState] == true && service.EndpointStateStore != nil {
+		if endpoint, ok := service.EndpointState[previous.InfraContainerID()]; ok {
+			delete(service.EndpointState, previous.InfraContainerID())
+			endpoint.PodName, endpoint.PodNamespace = podInfo.Name(), podInfo.Namespace()
+			service.EndpointState[podInfo.InfraContainerID()] = endpoint
+			if err := service.EndpointStateStore.Write(EndpointStoreKey, service.EndpointState); err != nil {
+				return errors.Wrap(err, "failed to write endpoint state to store")
+			}
+		}
+	}
+	return nil
+}
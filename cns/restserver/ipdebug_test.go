@@ -0,0 +1,101 @@
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ipConfigRequest(t *testing.T, podInfo cns.PodInfo) cns.IPConfigRequest {
+	b, err := podInfo.OrchestratorContext()
+	require.NoError(t, err)
+	return cns.IPConfigRequest{
+		PodInterfaceID:      podInfo.InterfaceID(),
+		InfraContainerID:    podInfo.InfraContainerID(),
+		OrchestratorContext: b,
+	}
+}
+
+func historyStates(history []cns.IPStateTransition) []types.IPState {
+	states := make([]types.IPState, len(history))
+	for i := range history {
+		states[i] = history[i].To
+	}
+	return states
+}
+
+func TestGetPodDebugInfo(t *testing.T) {
+	svc := getTestService()
+	state := NewPodState(testIP1, 24, testPod1GUID, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIpConfigState(t, svc, map[string]cns.IPConfigurationStatus{state.ID: state}))
+
+	_, err := requestIPConfigHelper(svc, ipConfigRequest(t, testPod1Info))
+	require.NoError(t, err)
+
+	ips := svc.getPodDebugInfo(testPod1Info.Name(), testPod1Info.Namespace())
+	require.Len(t, ips, 1)
+	assert.Equal(t, testIP1, ips[0].IPConfigurationStatus.IPAddress)
+	assert.Equal(t, testPod1Info.InterfaceID(), ips[0].PodInterfaceID)
+	assert.Equal(t, testNCID, ips[0].NetworkContainer.ID)
+	assert.Equal(t, "-1", ips[0].NetworkContainer.Version)
+	assert.Equal(t, []types.IPState{types.Available, types.Assigned}, historyStates(ips[0].History))
+	assert.Equal(t, "testpod1namespace/testpod1", ips[0].History[1].Pod)
+	assert.Empty(t, svc.getPodDebugInfo(testPod2Info.Name(), testPod2Info.Namespace()))
+
+	// a released IP is still found by its history, but is no longer assigned to the pod
+	require.NoError(t, svc.releaseIPConfig(testPod1Info))
+	ips = svc.getPodDebugInfo(testPod1Info.Name(), testPod1Info.Namespace())
+	require.Len(t, ips, 1)
+	assert.Empty(t, ips[0].PodInterfaceID)
+	assert.Equal(t, []types.IPState{types.Available, types.Assigned, types.Available}, historyStates(ips[0].History))
+	assert.Equal(t, "testpod1namespace/testpod1", ips[0].History[2].Pod)
+}
+
+func TestRecordIPTransitionBounded(t *testing.T) {
+	svc := getTestService()
+	for i := 0; i < ipHistorySize+5; i++ {
+		svc.recordIPTransition(testPod1GUID, types.Available, types.Assigned, testPod1Info)
+	}
+	assert.Len(t, svc.ipHistory[testPod1GUID], ipHistorySize)
+}
+
+func TestReassignIPConfig(t *testing.T) {
+	svc := getTestService()
+	state1, _ := NewPodStateWithOrchestratorContext(testIP1, testPod1GUID, testNCID, types.Assigned, 24, 0, testPod1Info)
+	state2, _ := NewPodStateWithOrchestratorContext(testIP2, testPod2GUID, testNCID, types.Assigned, 24, 0, testPod2Info)
+	state3 := NewPodState(testIP3, 24, testPod3GUID, testNCID, types.Available, 0)
+	require.NoError(t, UpdatePodIpConfigState(t, svc, map[string]cns.IPConfigurationStatus{
+		state1.ID: state1,
+		state2.ID: state2,
+		state3.ID: state3,
+	}))
+
+	// the sandbox of pod 1 was recreated
+	recreated := cns.NewPodInfo("5c3f1a-eth0", "5c3f1a-eth0", testPod1Info.Name(), testPod1Info.Namespace())
+
+	require.Error(t, svc.reassignIPConfig("10.0.0.100", recreated), "unknown IP")
+	require.Error(t, svc.reassignIPConfig(testIP3, recreated), "available IP")
+	require.Error(t, svc.reassignIPConfig(testIP1, testPod2Info), "pod 2 already has an IP")
+
+	require.NoError(t, svc.reassignIPConfig(testIP1, recreated))
+	assert.Equal(t, testPod1GUID, svc.PodIPIDByPodInterfaceKey[recreated.Key()])
+	ipConfig := svc.PodIPConfigState[testPod1GUID]
+	assert.Equal(t, recreated, ipConfig.PodInfo)
+	assert.Equal(t, types.Assigned, ipConfig.GetState())
+	require.NoError(t, svc.reassignIPConfig(testIP1, recreated), "reassigning to the same sandbox is a no-op")
+
+	// the recreated sandbox gets the reassigned IP
+	podIPInfo, err := requestIPConfigHelper(svc, ipConfigRequest(t, recreated))
+	require.NoError(t, err)
+	assert.Equal(t, testIP1, podIPInfo.PodIPConfig.IPAddress)
+
+	require.NoError(t, svc.reassignIPConfig(testIP1, testPod3Info))
+	assert.Equal(t, testPod1GUID, svc.PodIPIDByPodInterfaceKey[testPod3Info.Key()])
+	assert.NotContains(t, svc.PodIPIDByPodInterfaceKey, recreated.Key())
+	ips := svc.getPodDebugInfo(testPod3Info.Name(), testPod3Info.Namespace())
+	require.Len(t, ips, 1)
+	assert.Equal(t, "testpod3namespace/testpod3", ips[0].History[len(ips[0].History)-1].Pod)
+}
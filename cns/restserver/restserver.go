@@ -18,6 +18,8 @@ import (
 	"github.com/Azure/azure-container-networking/cns/types/bounded"
 	"github.com/Azure/azure-container-networking/cns/wireserver"
 	acn "github.com/Azure/azure-container-networking/common"
+	acnlog "github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/pkg/errors"
 )
@@ -53,11 +55,13 @@ type HTTPRestService struct {
 	networkContainer         *networkcontainers.NetworkContainers
 	PodIPIDByPodInterfaceKey map[string]string                    // PodInterfaceId is key and value is Pod IP (SecondaryIP) uuid.
 	PodIPConfigState         map[string]cns.IPConfigurationStatus // Secondary IP ID(uuid) is key
+	ipHistory                map[string][]cns.IPStateTransition   // Secondary IP ID(uuid) is key, latest state transitions for debugging
 	IPAMPoolMonitor          cns.IPAMPoolMonitor
 	routingTable             *routes.RoutingTable
 	store                    store.KeyValueStore
 	state                    *httpRestServiceState
 	podsPendingIPAssignment  *bounded.TimedSet
+	netlink                  netlink.NetlinkInterface // flushes the conntrack entries of released IPs
 	sync.RWMutex
 	dncPartitionKey    string
 	EndpointState      map[string]*EndpointInfo // key : container id
@@ -172,6 +176,7 @@ func NewHTTPRestService(config *common.ServiceConfig, wscli interfaceGetter, nma
 		routingTable:             routingTable,
 		state:                    serviceState,
 		podsPendingIPAssignment:  bounded.NewTimedSet(250), // nolint:gomnd // maxpods
+		netlink:                  netlink.NewNetlink(),
 		EndpointStateStore:       endpointStateStore,
 		EndpointState:            make(map[string]*EndpointInfo),
 	}, nil
@@ -223,6 +228,9 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	listener.AddHandler(cns.PathDebugIPAddresses, service.handleDebugIPAddresses)
 	listener.AddHandler(cns.PathDebugPodContext, service.handleDebugPodContext)
 	listener.AddHandler(cns.PathDebugRestData, service.handleDebugRestData)
+	listener.AddHandler(cns.PathDebugPod, service.handleDebugPod)
+	listener.AddHandler(cns.PathDebugReassignIPAddress, service.handleDebugReassignIPAddress)
+	listener.AddHandler(cns.PathDebugLogLevel, acnlog.LevelHandler().ServeHTTP)
 
 	// handlers for v0.2
 	listener.AddHandler(cns.V2Prefix+cns.SetEnvironmentPath, service.setEnvironment)
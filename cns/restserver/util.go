@@ -306,6 +306,7 @@ func (service *HTTPRestService) addIPConfigStateUntransacted(ncID string, hostVe
 			PodInfo:   nil,
 		}
 		ipconfigStatus.WithStateMiddleware(stateTransitionMiddleware)
+		service.recordIPTransition(ipID, "", newIPCNSStatus, nil)
 		ipconfigStatus.SetState(newIPCNSStatus)
 		logger.Printf("[Azure-Cns] Add IP %s as %s", ipconfig.IPAddress, newIPCNSStatus)
 
@@ -348,6 +349,7 @@ func (service *HTTPRestService) removeToBeDeletedIPStateUntransacted(
 		ipID,
 		service.PodIPConfigState[ipID])
 	delete(service.PodIPConfigState, ipID)
+	delete(service.ipHistory, ipID)
 	return 0, ""
 }
 
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/Azure/azure-container-networking/aitelemetry/otlp"
 	"github.com/Azure/azure-container-networking/cnm/ipam"
 	"github.com/Azure/azure-container-networking/cnm/network"
 	"github.com/Azure/azure-container-networking/cns"
@@ -50,9 +51,11 @@ import (
 	localtls "github.com/Azure/azure-container-networking/server/tls"
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/Azure/azure-container-networking/telemetry"
+	"github.com/Azure/azure-container-networking/tracing"
 	"github.com/avast/retry-go/v3"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
@@ -132,6 +135,17 @@ var args = acn.ArgumentList{
 			acn.OptLogMultiWrite:   log.TargetStdOutAndLogFile,
 		},
 	},
+	{
+		Name:         acn.OptLogFormat,
+		Shorthand:    acn.OptLogFormatAlias,
+		Description:  "Set the logging format",
+		Type:         "int",
+		DefaultValue: acn.OptLogFormatText,
+		ValueMap: map[string]interface{}{
+			acn.OptLogFormatText: log.FormatText,
+			acn.OptLogFormatJSON: log.FormatJSON,
+		},
+	},
 	{
 		Name:         acn.OptLogLocation,
 		Shorthand:    acn.OptLogLocationAlias,
@@ -254,14 +268,14 @@ var args = acn.ArgumentList{
 	{
 		Name:         acn.OptDebugCmd,
 		Shorthand:    acn.OptDebugCmdAlias,
-		Description:  "Debug flag to retrieve IPconfigs, available values: assigned, available, all",
+		Description:  "Debug command to run against a running CNS, available values: get, getPodContexts, getInMemory, pod, release, reassign",
 		Type:         "string",
 		DefaultValue: "",
 	},
 	{
 		Name:         acn.OptDebugArg,
 		Shorthand:    acn.OptDebugArgAlias,
-		Description:  "Argument flag to be paired with the 'debugcmd' flag.",
+		Description:  "Argument flag to be paired with the 'debugcmd' flag: the IP state for get, namespace/name for pod, the IP for release and reassign.",
 		Type:         "string",
 		DefaultValue: "",
 	},
@@ -380,13 +394,21 @@ func sendRegisterNodeRequest(httpc *http.Client, httpRestService cns.HTTPService
 	return nil
 }
 
-func startTelemetryService(ctx context.Context) {
-	var config aitelemetry.AIConfig
-
-	err := telemetry.CreateAITelemetryHandle(config, false, false, false)
-	if err != nil {
-		log.Errorf("AI telemetry handle creation failed..:%w", err)
-		return
+// startTelemetryService sends the CNI telemetry to the exporter selected in the telemetry settings.
+// With the OTLP exporter it goes through otlpHandle, the handle CNS exports its own telemetry with.
+func startTelemetryService(ctx context.Context, ts configuration.TelemetrySettings, otlpHandle aitelemetry.TelemetryHandle) {
+	if ts.Exporter == configuration.TelemetryExporterOTLP {
+		if otlpHandle == nil {
+			log.Errorf("OTLP telemetry handle not available, not starting the telemetry service")
+			return
+		}
+		telemetry.SetTelemetryHandle(otlpHandle, false, false)
+	} else {
+		var config aitelemetry.AIConfig
+		if err := telemetry.CreateAITelemetryHandle(config, false, false, false); err != nil {
+			log.Errorf("AI telemetry handle creation failed..:%v", err)
+			return
+		}
 	}
 
 	tbtemp := telemetry.NewTelemetryBuffer()
@@ -394,9 +416,9 @@ func startTelemetryService(ctx context.Context) {
 	tbtemp.Cleanup(telemetry.FdName)
 
 	tb := telemetry.NewTelemetryBuffer()
-	err = tb.StartServer()
+	err := tb.StartServer()
 	if err != nil {
-		log.Errorf("Telemetry service failed to start: %w", err)
+		log.Errorf("Telemetry service failed to start: %v", err)
 		return
 	}
 	tb.PushData(rootCtx)
@@ -414,6 +436,7 @@ func main() {
 	cnsURL := acn.GetArg(acn.OptCnsURL).(string)
 	logLevel := acn.GetArg(acn.OptLogLevel).(int)
 	logTarget := acn.GetArg(acn.OptLogTarget).(int)
+	logFormat := acn.GetArg(acn.OptLogFormat).(int)
 	logDirectory := acn.GetArg(acn.OptLogLocation).(string)
 	ipamQueryUrl := acn.GetArg(acn.OptIpamQueryUrl).(string)
 	ipamQueryInterval := acn.GetArg(acn.OptIpamQueryInterval).(int)
@@ -452,6 +475,10 @@ func main() {
 
 	// Create logging provider.
 	logger.InitLogger(name, logLevel, logTarget, logDirectory)
+	if err = logger.SetFormat(logFormat); err != nil {
+		fmt.Printf("Failed to configure logging: %v\n", err)
+		return
+	}
 
 	if clientDebugCmd != "" {
 		err := cnscli.HandleCNSClientCommands(rootCtx, clientDebugCmd, clientDebugArg)
@@ -475,9 +502,7 @@ func main() {
 	configuration.SetCNSConfigDefaults(cnsconfig)
 	logger.Printf("[Azure CNS] Read config :%+v", cnsconfig)
 
-	// start the health server
 	z, _ := zap.NewProduction()
-	go healthserver.Start(z, cnsconfig.MetricsBindAddress)
 
 	if cnsconfig.WireserverIP != "" {
 		nmagent.WireserverIP = cnsconfig.WireserverIP
@@ -496,24 +521,53 @@ func main() {
 		config.ChannelMode = cns.Managed
 	}
 
+	// otlpHandle is shared with the telemetry service, so CNI telemetry goes to the same collector
+	var otlpHandle aitelemetry.TelemetryHandle
 	disableTelemetry := cnsconfig.TelemetrySettings.DisableAll
 	if !disableTelemetry {
 		ts := cnsconfig.TelemetrySettings
-		aiConfig := aitelemetry.AIConfig{
-			AppName:                      name,
-			AppVersion:                   version,
-			BatchSize:                    ts.TelemetryBatchSizeBytes,
-			BatchInterval:                ts.TelemetryBatchIntervalInSecs,
-			RefreshTimeout:               ts.RefreshIntervalInSecs,
-			DisableMetadataRefreshThread: ts.DisableMetadataRefreshThread,
-			DebugMode:                    ts.DebugMode,
+		switch ts.Exporter {
+		case configuration.TelemetryExporterOTLP:
+			otlpConfig := ts.OTLP
+			otlpConfig.AppName = name
+			otlpConfig.AppVersion = version
+			th, err := otlp.New(otlpConfig)
+			if err != nil {
+				logger.Errorf("Error initializing OTLP telemetry:%v", err)
+				break
+			}
+			logger.Printf("OTLP telemetry handle created for %s", ts.OTLP.Endpoint)
+			otlpHandle = th
+			logger.InitTelemetry(th, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
+			if !ts.DisableTrace {
+				z = z.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+					return zapcore.NewTee(core, otlp.NewCore(zapcore.InfoLevel, th))
+				}))
+			}
+		default:
+			aiConfig := aitelemetry.AIConfig{
+				AppName:                      name,
+				AppVersion:                   version,
+				BatchSize:                    ts.TelemetryBatchSizeBytes,
+				BatchInterval:                ts.TelemetryBatchIntervalInSecs,
+				RefreshTimeout:               ts.RefreshIntervalInSecs,
+				DisableMetadataRefreshThread: ts.DisableMetadataRefreshThread,
+				DebugMode:                    ts.DebugMode,
+			}
+
+			logger.InitAI(aiConfig, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
 		}
 
-		logger.InitAI(aiConfig, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
+		if ts.EnableTracing {
+			initTracing(&ts, z)
+		}
 	}
 
+	// start the health server
+	go healthserver.Start(z, cnsconfig.MetricsBindAddress)
+
 	if telemetryDaemonEnabled {
-		go startTelemetryService(rootCtx)
+		go startTelemetryService(rootCtx, cnsconfig.TelemetrySettings, otlpHandle)
 	}
 
 	// Log platform information.
@@ -689,6 +743,14 @@ func main() {
 		}
 	}
 
+	if cnsconfig.ReconcileOrphanedEndpoints {
+		go cnireconciler.ReconcileOrphans(cnsconfig.DeleteOrphanedEndpoints)
+	}
+
+	if cnsconfig.EndpointRuleGCIntervalInMins > 0 {
+		go cnireconciler.StartEndpointRuleGC(rootCtx, time.Duration(cnsconfig.EndpointRuleGCIntervalInMins)*time.Minute)
+	}
+
 	if !disableTelemetry {
 		go logger.SendHeartBeat(rootCtx, cnsconfig.TelemetrySettings.HeartBeatIntervalInMins)
 		go httpRestService.SendNCSnapShotPeriodically(rootCtx, cnsconfig.TelemetrySettings.SnapshotIntervalInMins)
@@ -823,10 +885,37 @@ func main() {
 		log.Errorf("lockclient cns unlock error:%v", err)
 	}
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err = tracing.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("Failed to shut down tracing: %v", err)
+	}
+	cancel()
+
 	logger.Printf("CNS exited")
 	logger.Close()
 }
 
+// initTracing enables tracing of the requests served by CNS, exporting spans to the OTLP collector
+// configured for telemetry, or to the zap logger if telemetry is sent to Application Insights.
+func initTracing(ts *configuration.TelemetrySettings, z *zap.Logger) {
+	if ts.Exporter != configuration.TelemetryExporterOTLP {
+		tracing.Init(tracing.NewLogExporter(z.Named("tracing")))
+		logger.Printf("Tracing enabled, logging spans")
+		return
+	}
+
+	otlpConfig := ts.OTLP
+	otlpConfig.AppName = name
+	otlpConfig.AppVersion = version
+	exp, err := otlp.NewTraceExporter(otlpConfig)
+	if err != nil {
+		logger.Errorf("Error initializing OTLP trace exporter:%v", err)
+		return
+	}
+	tracing.Init(exp)
+	logger.Printf("Tracing enabled, exporting spans to %s", ts.OTLP.Endpoint)
+}
+
 func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HTTPService, cnsconfig configuration.CNSConfig) error {
 	var multiTenantController multitenantcontroller.RequestController
 	kubeConfig, err := ctrl.GetConfig()
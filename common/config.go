@@ -34,6 +34,12 @@ const (
 	OptLogStdout       = "stdout"
 	OptLogMultiWrite   = "stdoutfile"
 
+	// Logging format.
+	OptLogFormat      = "log-format"
+	OptLogFormatAlias = "lf"
+	OptLogFormatText  = "text"
+	OptLogFormatJSON  = "json"
+
 	// Logging location
 	OptLogLocation      = "log-location"
 	OptLogLocationAlias = "o"
@@ -12,6 +12,7 @@ import (
 	"os"
 
 	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -125,9 +126,10 @@ func (l *Listener) AddEndpoint(endpoint string) {
 	l.endpoints = append(l.endpoints, endpoint)
 }
 
-// AddHandler registers a protocol handler.
+// AddHandler registers a protocol handler. Requests are traced as server spans named after the path,
+// continuing the caller's trace, when tracing is enabled.
 func (l *Listener) AddHandler(path string, handler http.HandlerFunc) {
-	l.mux.HandleFunc(path, handler)
+	l.mux.HandleFunc(path, tracing.Middleware(path, handler))
 }
 
 // todo: Decode and Encode below should not be methods, just functions. They make no use of Listener fields.
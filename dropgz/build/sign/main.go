@@ -0,0 +1,121 @@
+// sign writes the signed manifest of the dropgz payload. It runs at build time, after the payload
+// files are compressed in to the embed directory and before dropgz is compiled.
+package main
+
+import (
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/dropgz/pkg/manifest"
+	"github.com/pkg/errors"
+)
+
+var (
+	dir         = flag.String("dir", "pkg/embed/fs", "embed directory holding the compressed payload")
+	version     = flag.String("version", "", "dropgz version the manifest is signed for")
+	keyFile     = flag.String("key", "", "file holding the base64 ed25519 private key or seed")
+	generateKey = flag.Bool("generate-key", false, "print a new base64 key pair and exit")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *generateKey {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate key")
+		}
+		fmt.Printf("private: %s\npublic: %s\n", base64.StdEncoding.EncodeToString(priv.Seed()), base64.StdEncoding.EncodeToString(pub))
+		return nil
+	}
+
+	b, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read signing key")
+	}
+	key, err := manifest.ParsePrivateKey(strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+
+	m, err := generate(*dir, *version)
+	if err != nil {
+		return err
+	}
+	data, sig, err := m.Sign(key)
+	if err != nil {
+		return err
+	}
+	if err := writeCompressed(filepath.Join(*dir, manifest.FileName), data); err != nil {
+		return err
+	}
+	return writeCompressed(filepath.Join(*dir, manifest.SignatureFileName), sig)
+}
+
+// generate describes the decompressed contents of the payload files in dir.
+func generate(dir, version string) (*manifest.Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", dir)
+	}
+	m := &manifest.Manifest{Version: version}
+	for _, e := range entries {
+		name := e.Name()
+		// _ prefixed files are not embedded.
+		if e.IsDir() || strings.HasPrefix(name, "_") || name == manifest.FileName || name == manifest.SignatureFileName {
+			continue
+		}
+		f, err := describe(filepath.Join(dir, name), name)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, f)
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+	return m, nil
+}
+
+func describe(path, name string) (manifest.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return manifest.File{}, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest.File{}, errors.Wrapf(err, "failed to decompress %s", path)
+	}
+	defer r.Close()
+	return manifest.Describe(name, r)
+}
+
+// writeCompressed writes b to path compressed like the payload, as dropgz decompresses every embedded file.
+func writeCompressed(path string, b []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", path)
+	}
+	defer f.Close()
+	w, _ := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if _, err := w.Write(b); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return errors.Wrapf(f.Close(), "failed to close %s", path)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/dropgz/pkg/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func readCompressed(t *testing.T, path string) []byte {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return b
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	payload := map[string][]byte{
+		"azure-vnet":      []byte("azure-vnet binary"),
+		"azure-vnet-ipam": []byte("azure-vnet-ipam binary"),
+	}
+	for name, contents := range payload {
+		require.NoError(t, writeCompressed(filepath.Join(dir, name), contents))
+	}
+	// files which are not embedded, and a previous manifest, are not described.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "_README"), []byte("readme"), 0o600))
+	require.NoError(t, writeCompressed(filepath.Join(dir, manifest.FileName), []byte("{}")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0o700))
+
+	m, err := generate(dir, "v1.4.35")
+	require.NoError(t, err)
+	require.Equal(t, "v1.4.35", m.Version)
+	require.Len(t, m.Files, len(payload))
+	require.Equal(t, "azure-vnet", m.Files[0].Name)
+	require.Equal(t, "azure-vnet-ipam", m.Files[1].Name)
+	for _, f := range m.Files {
+		// files are described decompressed, as they are written on the host.
+		want, err := manifest.Describe(f.Name, bytes.NewReader(payload[f.Name]))
+		require.NoError(t, err)
+		require.Equal(t, want, f)
+	}
+}
+
+func TestGenerateRejectsUncompressedPayload(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "azure-vnet"), []byte("not compressed"), 0o600))
+
+	_, err := generate(dir, "v1.4.35")
+	require.Error(t, err)
+}
+
+func TestSignedManifestVerifies(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, writeCompressed(filepath.Join(dir, "azure-vnet"), []byte("azure-vnet binary")))
+
+	m, err := generate(dir, "v1.4.35")
+	require.NoError(t, err)
+	data, sig, err := m.Sign(priv)
+	require.NoError(t, err)
+	require.NoError(t, writeCompressed(filepath.Join(dir, manifest.FileName), data))
+	require.NoError(t, writeCompressed(filepath.Join(dir, manifest.SignatureFileName), sig))
+
+	// dropgz verifies the embedded files as they are decompressed.
+	got, err := manifest.Verify(readCompressed(t, filepath.Join(dir, manifest.FileName)),
+		readCompressed(t, filepath.Join(dir, manifest.SignatureFileName)), pub)
+	require.NoError(t, err)
+	require.Equal(t, m, got)
+	require.NoError(t, got.CheckVersion("v1.4.35"))
+}
@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
+	"github.com/Azure/azure-container-networking/dropgz/internal/buildinfo"
 	"github.com/Azure/azure-container-networking/dropgz/pkg/embed"
 	"github.com/Azure/azure-container-networking/dropgz/pkg/hash"
+	"github.com/Azure/azure-container-networking/dropgz/pkg/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -28,19 +31,102 @@ var list = &cobra.Command{
 	},
 }
 
-func checksum(srcs, dests []string) error {
-	if len(srcs) != len(dests) {
-		return errors.Wrapf(embed.ErrArgsMismatched, "%d and %d", len(srcs), len(dests))
-	}
+// loadChecksums parses the embedded checksum file.
+func loadChecksums() (hash.Checksums, error) {
 	rc, err := embed.Extract("sum.txt")
 	if err != nil {
-		return errors.Wrap(err, "failed to extract checksum file")
+		return nil, errors.Wrap(err, "failed to extract checksum file")
 	}
 	defer rc.Close()
 
 	checksums, err := hash.Parse(rc)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse checksums")
+		return nil, errors.Wrap(err, "failed to parse checksums")
+	}
+	return checksums, nil
+}
+
+func extractAll(path string) ([]byte, error) {
+	rc, err := embed.Extract(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to extract %s", path)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	return b, errors.Wrapf(err, "failed to read %s", path)
+}
+
+// loadManifest extracts the embedded manifest and verifies it was signed for this build by the
+// owner of publicKey.
+func loadManifest() (*manifest.Manifest, error) {
+	key, err := manifest.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := extractAll(manifest.FileName)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := extractAll(manifest.SignatureFileName)
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifest.Verify(data, sig, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CheckVersion(buildinfo.Version); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// loadVerifiedChecksums returns the checksums of the signed manifest if a public key is configured,
+// or else the unsigned embedded checksums, which only protect against corruption.
+func loadVerifiedChecksums(log *zap.Logger) (hash.Checksums, error) {
+	if publicKey == "" {
+		log.Warn("no manifest public key configured, verifying against unsigned checksums")
+		return loadChecksums()
+	}
+	m, err := loadManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load signed manifest")
+	}
+	log.Info("verified manifest signature", zap.String("version", m.Version))
+	return m.Checksums(), nil
+}
+
+// drift checks the installed dests against the signed manifest and reports each file which drifted.
+func drift(log *zap.Logger, srcs, dests []string) error {
+	m, err := loadManifest()
+	if err != nil {
+		return errors.Wrap(err, "failed to load signed manifest")
+	}
+	drifted := 0
+	for i := range srcs {
+		d, err := m.Check(srcs[i], dests[i])
+		if err != nil {
+			return errors.Wrapf(err, "failed to check file at %s", dests[i])
+		}
+		if d != nil {
+			drifted++
+			log.Warn("file drifted from manifest", zap.String("src", d.Name), zap.String("dest", d.Path),
+				zap.String("reason", d.Reason), zap.String("want", d.Want), zap.String("have", d.Have))
+		}
+	}
+	if drifted > 0 {
+		return errors.Errorf("%d of %d files drifted from manifest version %s", drifted, len(srcs), m.Version)
+	}
+	return nil
+}
+
+func checksum(srcs, dests []string) error {
+	if len(srcs) != len(dests) {
+		return errors.Wrapf(embed.ErrArgsMismatched, "%d and %d", len(srcs), len(dests))
+	}
+	checksums, err := loadChecksums()
+	if err != nil {
+		return err
 	}
 	for i := range srcs {
 		valid, err := checksums.Check(srcs[i], dests[i])
@@ -57,6 +143,7 @@ func checksum(srcs, dests []string) error {
 var (
 	skipVerify bool
 	outs       []string
+	publicKey  string
 )
 
 // deploy subcommand
@@ -73,17 +160,39 @@ var deploy = &cobra.Command{
 			return errors.Wrapf(embed.ErrArgsMismatched, "%d files, %d outputs", len(srcs), len(outs))
 		}
 		log := z.With(zap.Strings("sources", srcs), zap.Strings("outputs", outs), zap.String("cmd", "deploy"))
-		if err := embed.Deploy(log, srcs, outs); err != nil {
+		// files are verified before they replace the outputs, so a corrupt payload is never deployed.
+		var checksums hash.Checksums
+		if !skipVerify {
+			var err error
+			if checksums, err = loadVerifiedChecksums(log); err != nil {
+				return err
+			}
+		}
+		if err := embed.Deploy(log, srcs, outs, checksums); err != nil {
 			return errors.Wrapf(err, "failed to deploy %s", srcs)
 		}
 		log.Info("successfully wrote files")
-		if skipVerify {
-			return nil
-		}
-		if err := checksum(srcs, outs); err != nil {
+		return nil
+	},
+	Args: cobra.OnlyValidArgs,
+}
+
+// rollback subcommand
+var rollback = &cobra.Command{
+	Use:   "rollback",
+	Short: "restore the versions of the outputs replaced by the last deploy",
+	RunE: func(_ *cobra.Command, srcs []string) error {
+		if err := setLogLevel(); err != nil {
 			return err
 		}
-		log.Info("verified file integrity")
+		if len(outs) == 0 {
+			outs = srcs
+		}
+		log := z.With(zap.Strings("outputs", outs), zap.String("cmd", "rollback"))
+		if err := embed.Rollback(log, outs); err != nil {
+			return errors.Wrapf(err, "failed to roll back %s", outs)
+		}
+		log.Info("successfully rolled back files")
 		return nil
 	},
 	Args: cobra.OnlyValidArgs,
@@ -91,7 +200,8 @@ var deploy = &cobra.Command{
 
 // verify subcommand
 var verify = &cobra.Command{
-	Use: "verify",
+	Use:   "verify",
+	Short: "check the installed outputs against the payload manifest and report drift",
 	RunE: func(_ *cobra.Command, srcs []string) error {
 		if err := setLogLevel(); err != nil {
 			return err
@@ -103,7 +213,12 @@ var verify = &cobra.Command{
 			return errors.Wrapf(embed.ErrArgsMismatched, "%d sources, %d destinations", len(srcs), len(outs))
 		}
 		log := z.With(zap.Strings("sources", srcs), zap.Strings("outputs", outs), zap.String("cmd", "verify"))
-		if err := checksum(srcs, outs); err != nil {
+		if publicKey == "" {
+			log.Warn("no manifest public key configured, verifying against unsigned checksums")
+			if err := checksum(srcs, outs); err != nil {
+				return err
+			}
+		} else if err := drift(log, srcs, outs); err != nil {
 			return err
 		}
 		log.Info("verified files")
@@ -117,10 +232,16 @@ func init() {
 
 	verify.ValidArgs, _ = embed.Contents()
 	verify.Flags().StringSliceVarP(&outs, "output", "o", []string{}, "output file path")
+	verify.Flags().StringVar(&publicKey, "public-key", buildinfo.ManifestPublicKey, "base64 ed25519 key to verify the payload manifest with")
 	root.AddCommand(verify)
 
 	deploy.ValidArgs, _ = embed.Contents() // setting this after the command is initialized is required
 	deploy.Flags().BoolVar(&skipVerify, "skip-verify", false, "set to disable checksum validation")
 	deploy.Flags().StringSliceVarP(&outs, "output", "o", []string{}, "output file path")
+	deploy.Flags().StringVar(&publicKey, "public-key", buildinfo.ManifestPublicKey, "base64 ed25519 key to verify the payload manifest with")
 	root.AddCommand(deploy)
+
+	rollback.ValidArgs, _ = embed.Contents()
+	rollback.Flags().StringSliceVarP(&outs, "output", "o", []string{}, "output file path")
+	root.AddCommand(rollback)
 }
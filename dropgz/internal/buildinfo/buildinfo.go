@@ -1,3 +1,6 @@
 package buildinfo
 
 var Version string
+
+// ManifestPublicKey is the base64 encoded ed25519 key the payload manifest is verified with.
+var ManifestPublicKey string
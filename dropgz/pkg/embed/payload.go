@@ -3,6 +3,7 @@ package embed
 import (
 	"bufio"
 	"compress/gzip"
+	"crypto/sha256"
 	"embed"
 	"io"
 	"io/fs"
@@ -10,6 +11,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Azure/azure-container-networking/dropgz/pkg/hash"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -80,32 +82,144 @@ func Extract(path string) (*compoundReadCloser, error) {
 	return &compoundReadCloser{closer: f, readcloser: r}, nil
 }
 
-func deploy(src, dest string) error {
+// BackupSuffix is appended to the path of a deployed file to name the copy of the version it replaced.
+const BackupSuffix = ".prev"
+
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrNoBackup         = errors.New("no previous version to roll back to")
+)
+
+// deploy extracts src to a temp file next to dest, verifies it against sums if they are set, and
+// atomically moves it over dest, so dest is never observed partially written, and a running binary
+// at dest is replaced instead of overwritten. The version it replaces is kept as a backup.
+func deploy(src, dest string, sums hash.Checksums) error {
 	rc, err := Extract(src)
 	if err != nil {
 		return err
 	}
 	defer rc.Close()
-	target, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o755) //nolint:gomnd // executable file bitmask
+	return deployFrom(rc, src, dest, sums)
+}
+
+// deployFrom deploys the contents of src, read from r, to dest.
+func deployFrom(r io.Reader, src, dest string, sums hash.Checksums) error {
+	tmp, err := writeTemp(dest, r, sums, src)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create file %s", dest)
+		return err
+	}
+	if err := replace(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeTemp writes r to a temp file in the directory of dest with the mode and ownership of dest,
+// or an executable mode if it doesn't exist yet, and returns its path. If sums are set, the
+// contents are verified against the checksum of src.
+func writeTemp(dest string, r io.Reader, sums hash.Checksums, src string) (string, error) {
+	dir, base := filepath.Split(dest)
+	if dir == "" {
+		dir = "."
+	}
+	target, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create temp file for %s", dest)
+	}
+	tmp := target.Name()
+	fail := func(err error) (string, error) {
+		target.Close()
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	sha := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(target, sha))
+	if _, err := io.Copy(w, r); err != nil {
+		return fail(errors.Wrapf(err, "failed to copy %s to %s", src, tmp))
+	}
+	if err := w.Flush(); err != nil {
+		return fail(errors.Wrapf(err, "failed to write %s", tmp))
+	}
+	if sums != nil {
+		valid, err := sums.Match(src, sha.Sum(nil))
+		if err != nil {
+			return fail(errors.Wrapf(err, "failed to validate %s", src))
+		}
+		if !valid {
+			return fail(errors.Wrapf(ErrChecksumMismatch, "%s extracted for %s", src, dest))
+		}
+	}
+
+	mode := fs.FileMode(0o755) //nolint:gomnd // executable file bitmask
+	if info, err := os.Stat(dest); err == nil {
+		mode = info.Mode().Perm()
+		if err := chown(target, info); err != nil {
+			return fail(errors.Wrapf(err, "failed to preserve ownership of %s", dest))
+		}
+	}
+	if err := target.Chmod(mode); err != nil {
+		return fail(errors.Wrapf(err, "failed to set mode of %s", tmp))
 	}
-	defer target.Close()
-	_, err = io.Copy(bufio.NewWriter(target), rc)
-	return errors.Wrapf(err, "failed to copy %s to %s", src, dest)
+	// flush to disk before the rename, so a crash can't leave dest renamed over an empty file.
+	if err := target.Sync(); err != nil {
+		return fail(errors.Wrapf(err, "failed to sync %s", tmp))
+	}
+	if err := target.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return "", errors.Wrapf(err, "failed to close %s", tmp)
+	}
+	return tmp, nil
 }
 
-func Deploy(log *zap.Logger, srcs, dests []string) error {
+// Deploy writes each of srcs to the matching path in dests. When sums are set, each file is verified
+// against them before it replaces the existing file, and a file failing verification is not deployed.
+func Deploy(log *zap.Logger, srcs, dests []string, sums hash.Checksums) error {
 	if len(srcs) != len(dests) {
 		return errors.Wrapf(ErrArgsMismatched, "%d and %d", len(srcs), len(dests))
 	}
 	for i := range srcs {
 		src := srcs[i]
 		dest := dests[i]
-		if err := deploy(src, dest); err != nil {
+		if err := deploy(src, dest, sums); err != nil {
+			return err
+		}
+		log.Info("wrote file", zap.String("src", src), zap.String("dest", dest), zap.Bool("verified", sums != nil))
+	}
+	return nil
+}
+
+// Rollback restores the versions of dests replaced by their last deploy. The rolled back versions
+// become the backups, so a rollback can be undone by rolling back again.
+func Rollback(log *zap.Logger, dests []string) error {
+	for _, dest := range dests {
+		if err := rollback(dest); err != nil {
 			return err
 		}
-		log.Info("wrote file", zap.String("src", src), zap.String("dest", dest))
+		log.Info("rolled back file", zap.String("dest", dest))
+	}
+	return nil
+}
+
+func rollback(dest string) error {
+	backup, err := os.Open(dest + BackupSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Wrapf(ErrNoBackup, "%s", dest)
+		}
+		return errors.Wrapf(err, "failed to open backup of %s", dest)
+	}
+	defer backup.Close()
+
+	// copy rather than rename the backup, so the replaced version becomes the new backup.
+	tmp, err := writeTemp(dest, backup, nil, dest+BackupSuffix)
+	if err != nil {
+		return err
+	}
+	if err := replace(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,143 @@
+package embed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/dropgz/pkg/hash"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testSrc = "azure-vnet"
+
+var (
+	oldContents = []byte("old version")
+	newContents = []byte("new version")
+)
+
+func sumsOf(contents []byte) hash.Checksums {
+	return hash.Checksums{testSrc: fmt.Sprintf("%x", sha256.Sum256(contents))}
+}
+
+// writeDest creates the file deployed over in a temp dir and returns its path.
+func writeDest(t *testing.T, contents []byte) string {
+	dest := filepath.Join(t.TempDir(), testSrc)
+	require.NoError(t, os.WriteFile(dest, contents, 0o600))
+	return dest
+}
+
+// tempFiles returns the temp files left next to dest.
+func tempFiles(t *testing.T, dest string) []string {
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp-*"))
+	require.NoError(t, err)
+	return matches
+}
+
+func TestDeployFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		sums     hash.Checksums
+		wantErr  error
+		wantDest []byte
+	}{
+		{
+			name:     "verified",
+			sums:     sumsOf(newContents),
+			wantDest: newContents,
+		},
+		{
+			name:     "not verified",
+			wantDest: newContents,
+		},
+		{
+			name:     "checksum mismatch",
+			sums:     sumsOf([]byte("other version")),
+			wantErr:  ErrChecksumMismatch,
+			wantDest: oldContents,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dest := writeDest(t, oldContents)
+
+			err := deployFrom(bytes.NewReader(newContents), testSrc, dest, tt.sums)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				_, err = os.Stat(dest + BackupSuffix)
+				require.True(t, os.IsNotExist(err), "a failed deploy must not touch the backup")
+			} else {
+				require.NoError(t, err)
+			}
+
+			got, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDest, got)
+			require.Empty(t, tempFiles(t, dest))
+		})
+	}
+}
+
+func TestDeployFromCreatesBackup(t *testing.T) {
+	dest := writeDest(t, oldContents)
+
+	require.NoError(t, deployFrom(bytes.NewReader(newContents), testSrc, dest, nil))
+
+	backup, err := os.ReadFile(dest + BackupSuffix)
+	require.NoError(t, err)
+	require.Equal(t, oldContents, backup)
+}
+
+func TestDeployFromNewFile(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), testSrc)
+
+	require.NoError(t, deployFrom(bytes.NewReader(newContents), testSrc, dest, nil))
+
+	got, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, newContents, got)
+	_, err = os.Stat(dest + BackupSuffix)
+	require.True(t, os.IsNotExist(err), "there is nothing to back up")
+}
+
+func TestRollback(t *testing.T) {
+	dest := writeDest(t, oldContents)
+	require.NoError(t, os.Chmod(dest, 0o750))
+	before, err := os.Stat(dest)
+	require.NoError(t, err)
+
+	require.NoError(t, deployFrom(bytes.NewReader(newContents), testSrc, dest, nil))
+	require.NoError(t, Rollback(zap.NewNop(), []string{dest}))
+
+	got, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, oldContents, got)
+	after, err := os.Stat(dest)
+	require.NoError(t, err)
+	require.Equal(t, before.Mode(), after.Mode())
+
+	// the rolled back version becomes the backup, so rolling back again undoes the rollback.
+	require.NoError(t, Rollback(zap.NewNop(), []string{dest}))
+	got, err = os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, newContents, got)
+}
+
+func TestRollbackWithoutBackup(t *testing.T) {
+	dest := writeDest(t, oldContents)
+
+	require.ErrorIs(t, Rollback(zap.NewNop(), []string{dest}), ErrNoBackup)
+
+	got, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, oldContents, got)
+}
+
+func TestDeployArgsMismatched(t *testing.T) {
+	require.ErrorIs(t, Deploy(zap.NewNop(), []string{testSrc}, nil, nil), ErrArgsMismatched)
+}
@@ -0,0 +1,48 @@
+package embed
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// replace keeps a hard link to dest as its backup and renames tmp over dest. The rename is atomic, so
+// dest always names either the complete old or the complete new file, and processes executing the old
+// file keep running it.
+func replace(tmp, dest string) error {
+	backup := dest + BackupSuffix
+	if _, err := os.Stat(dest); err == nil {
+		if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove old backup %s", backup)
+		}
+		if err := os.Link(dest, backup); err != nil {
+			return errors.Wrapf(err, "failed to back up %s", dest)
+		}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return errors.Wrapf(err, "failed to move %s to %s", tmp, dest)
+	}
+	return syncDir(filepath.Dir(dest))
+}
+
+// syncDir flushes the directory entries, so the rename survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", dir)
+	}
+	defer d.Close()
+	return errors.Wrapf(d.Sync(), "failed to sync %s", dir)
+}
+
+// chown gives f the owner and group in info.
+func chown(f *os.File, info fs.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return errors.Wrap(f.Chown(int(st.Uid), int(st.Gid)), "failed to chown")
+}
@@ -0,0 +1,53 @@
+package embed
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployFromPreservesModeAndOwner(t *testing.T) {
+	dest := writeDest(t, oldContents)
+	require.NoError(t, os.Chmod(dest, 0o640))
+	const uid, gid = 1234, 5678
+	chowned := os.Geteuid() == 0
+	if chowned {
+		require.NoError(t, os.Chown(dest, uid, gid))
+	}
+
+	require.NoError(t, deployFrom(bytes.NewReader(newContents), testSrc, dest, nil))
+
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	if chowned {
+		st := info.Sys().(*syscall.Stat_t)
+		require.Equal(t, uint32(uid), st.Uid)
+		require.Equal(t, uint32(gid), st.Gid)
+	}
+}
+
+func TestDeployFromReplacesAtomically(t *testing.T) {
+	dest := writeDest(t, oldContents)
+	// a process executing dest keeps the file it opened.
+	running, err := os.Open(dest)
+	require.NoError(t, err)
+	defer running.Close()
+
+	require.NoError(t, deployFrom(bytes.NewReader(newContents), testSrc, dest, nil))
+
+	var got bytes.Buffer
+	_, err = got.ReadFrom(running)
+	require.NoError(t, err)
+	require.Equal(t, oldContents, got.Bytes())
+
+	// the backup is a link to the replaced file rather than a copy.
+	prev, err := os.Stat(dest + BackupSuffix)
+	require.NoError(t, err)
+	orig, err := running.Stat()
+	require.NoError(t, err)
+	require.True(t, os.SameFile(orig, prev))
+}
@@ -0,0 +1,33 @@
+package embed
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// replace moves dest to its backup and tmp to dest. A running executable can't be replaced on Windows,
+// but it can be renamed, so dest is moved out of the way first and is briefly missing.
+func replace(tmp, dest string) error {
+	backup := dest + BackupSuffix
+	if _, err := os.Stat(dest); err == nil {
+		if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove old backup %s", backup)
+		}
+		if err := os.Rename(dest, backup); err != nil {
+			return errors.Wrapf(err, "failed to back up %s", dest)
+		}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		// put the previous version back, so a failed deploy leaves dest in place.
+		_ = os.Rename(backup, dest)
+		return errors.Wrapf(err, "failed to move %s to %s", tmp, dest)
+	}
+	return nil
+}
+
+// chown is a no-op, file ownership is inherited from the directory on Windows.
+func chown(*os.File, fs.FileInfo) error {
+	return nil
+}
@@ -30,14 +30,19 @@ func Parse(r io.Reader) (Checksums, error) {
 }
 
 func (sums Checksums) Check(src, dst string) (bool, error) {
-	want, ok := sums[src]
-	if !ok {
-		return false, errors.Errorf("unknown path %s", src)
-	}
 	buf, err := os.ReadFile(dst)
 	if err != nil {
 		return false, errors.Wrapf(err, "unable to read file %s", dst)
 	}
 	have := sha256.Sum256(buf)
-	return want == fmt.Sprintf("%x", have), nil
+	return sums.Match(src, have[:])
+}
+
+// Match reports whether sum is the sha256 checksum of src.
+func (sums Checksums) Match(src string, sum []byte) (bool, error) {
+	want, ok := sums[src]
+	if !ok {
+		return false, errors.Errorf("unknown path %s", src)
+	}
+	return want == fmt.Sprintf("%x", sum), nil
 }
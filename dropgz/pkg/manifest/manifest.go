@@ -0,0 +1,175 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/azure-container-networking/dropgz/pkg/hash"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FileName is the name of the embedded manifest.
+	FileName = "manifest.json"
+	// SignatureFileName is the name of the embedded ed25519 signature of the manifest.
+	SignatureFileName = "manifest.sig"
+)
+
+var (
+	ErrInvalidKey       = errors.New("invalid ed25519 key")
+	ErrInvalidSignature = errors.New("manifest signature verification failed")
+	ErrUnknownFile      = errors.New("file not in manifest")
+	ErrVersionMismatch  = errors.New("manifest version mismatch")
+)
+
+// File describes a payload file as it is written on the host.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the payload files embedded in a dropgz build. It is signed at build time, so a
+// payload which was modified after the build can't be deployed by a dropgz configured with the key.
+type Manifest struct {
+	Version string `json:"version"`
+	Files   []File `json:"files"`
+}
+
+// ParsePublicKey decodes a base64 encoded ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode public key")
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, errors.Wrapf(ErrInvalidKey, "public key is %d bytes, expected %d", len(b), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParsePrivateKey decodes a base64 encoded ed25519 private key or seed.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode private key")
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidKey, "private key is %d bytes", len(b))
+	}
+}
+
+// Sign encodes the manifest and returns the encoding and its signature. The signature covers the
+// exact bytes returned, which must be embedded as they are.
+func (m *Manifest) Sign(key ed25519.PrivateKey) (data, sig []byte, err error) {
+	data, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to encode manifest")
+	}
+	return data, ed25519.Sign(key, data), nil
+}
+
+// Verify checks sig is the signature of data by key, and only then decodes the manifest from data.
+func Verify(data, sig []byte, key ed25519.PublicKey) (*Manifest, error) {
+	if !ed25519.Verify(key, data, sig) {
+		return nil, ErrInvalidSignature
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifest")
+	}
+	return m, nil
+}
+
+// CheckVersion checks the manifest was signed for version, since a manifest signed for another build may
+// not match the embedded payload. Any version is accepted if version is empty, as in development builds.
+func (m *Manifest) CheckVersion(version string) error {
+	if version != "" && m.Version != version {
+		return errors.Wrapf(ErrVersionMismatch, "manifest is for version %s, this is version %s", m.Version, version)
+	}
+	return nil
+}
+
+// Lookup returns the entry for the payload file name.
+func (m *Manifest) Lookup(name string) (File, bool) {
+	for _, f := range m.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Checksums returns the hashes of the manifest files, to verify files as they are deployed.
+func (m *Manifest) Checksums() hash.Checksums {
+	sums := hash.Checksums{}
+	for _, f := range m.Files {
+		sums[f.Name] = f.SHA256
+	}
+	return sums
+}
+
+// Describe reads r and returns its entry as the payload file name.
+func Describe(name string, r io.Reader) (File, error) {
+	sha := sha256.New()
+	n, err := io.Copy(sha, r)
+	if err != nil {
+		return File{}, errors.Wrapf(err, "failed to read %s", name)
+	}
+	return File{Name: name, Size: n, SHA256: fmt.Sprintf("%x", sha.Sum(nil))}, nil
+}
+
+// Drift describes how an installed file differs from its manifest entry.
+type Drift struct {
+	Name   string
+	Path   string
+	Reason string
+	Want   string
+	Have   string
+}
+
+func (d *Drift) String() string {
+	if d.Want == "" && d.Have == "" {
+		return fmt.Sprintf("%s at %s: %s", d.Name, d.Path, d.Reason)
+	}
+	return fmt.Sprintf("%s at %s: %s, want %s, have %s", d.Name, d.Path, d.Reason, d.Want, d.Have)
+}
+
+// Check compares the file installed at path with the entry for the payload file name, and returns
+// how it drifted, or nil if it matches.
+func (m *Manifest) Check(name, path string) (*Drift, error) {
+	want, ok := m.Lookup(name)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownFile, "%s", name)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Drift{Name: name, Path: path, Reason: "missing"}, nil
+		}
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	have, err := Describe(name, f)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case have.Size != want.Size:
+		return &Drift{Name: name, Path: path, Reason: "size", Want: fmt.Sprint(want.Size), Have: fmt.Sprint(have.Size)}, nil
+	case have.SHA256 != want.SHA256:
+		return &Drift{Name: name, Path: path, Reason: "sha256", Want: want.SHA256, Have: have.SHA256}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,156 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testContents = []byte("azure-vnet binary")
+
+func testManifest(t *testing.T) *Manifest {
+	f, err := Describe("azure-vnet", bytes.NewReader(testContents))
+	require.NoError(t, err)
+	return &Manifest{Version: "v1.4.35", Files: []File{f}}
+}
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return pub, priv
+}
+
+func TestVerify(t *testing.T) {
+	pub, priv := generateKey(t)
+	otherPub, _ := generateKey(t)
+	data, sig, err := testManifest(t).Sign(priv)
+	require.NoError(t, err)
+
+	tampered := bytes.Replace(data, []byte("v1.4.35"), []byte("v1.4.36"), 1)
+	require.NotEqual(t, data, tampered)
+	badSig := append([]byte{}, sig...)
+	badSig[0] ^= 0xff
+
+	tests := []struct {
+		name    string
+		data    []byte
+		sig     []byte
+		key     ed25519.PublicKey
+		wantErr bool
+	}{
+		{name: "valid", data: data, sig: sig, key: pub},
+		{name: "tampered manifest", data: tampered, sig: sig, key: pub, wantErr: true},
+		{name: "tampered signature", data: data, sig: badSig, key: pub, wantErr: true},
+		{name: "wrong public key", data: data, sig: sig, key: otherPub, wantErr: true},
+		{name: "no signature", data: data, key: pub, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Verify(tt.data, tt.sig, tt.key)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidSignature)
+				require.Nil(t, m)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testManifest(t), m)
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	m := testManifest(t)
+	tests := []struct {
+		name    string
+		version string
+		wantErr bool
+	}{
+		{name: "same version", version: "v1.4.35"},
+		{name: "development build", version: ""},
+		{name: "version mismatch", version: "v1.4.36", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.CheckVersion(tt.version)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrVersionMismatch)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	m := testManifest(t)
+	want := m.Files[0]
+	sameSize := bytes.ToUpper(testContents)
+
+	tests := []struct {
+		name       string
+		file       string
+		contents   []byte
+		wantReason string
+		wantErr    error
+	}{
+		{name: "matches", file: "azure-vnet", contents: testContents},
+		{name: "missing", file: "azure-vnet", wantReason: "missing"},
+		{name: "size drift", file: "azure-vnet", contents: testContents[1:], wantReason: "size"},
+		{name: "sha drift", file: "azure-vnet", contents: sameSize, wantReason: "sha256"},
+		{name: "unknown file", file: "azure-vnet-ipam", contents: testContents, wantErr: ErrUnknownFile},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if tt.contents != nil {
+				require.NoError(t, os.WriteFile(path, tt.contents, 0o600))
+			}
+
+			d, err := m.Check(tt.file, path)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantReason == "" {
+				require.Nil(t, d)
+				return
+			}
+			require.NotNil(t, d)
+			require.Equal(t, tt.wantReason, d.Reason)
+			require.Equal(t, path, d.Path)
+			if tt.wantReason == "sha256" {
+				require.Equal(t, want.SHA256, d.Want)
+				require.NotEqual(t, want.SHA256, d.Have)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	pub, priv := generateKey(t)
+
+	gotPub, err := ParsePublicKey(base64.StdEncoding.EncodeToString(pub))
+	require.NoError(t, err)
+	require.Equal(t, pub, gotPub)
+	_, err = ParsePublicKey(base64.StdEncoding.EncodeToString(pub[1:]))
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	// both the seed and the full private key are accepted.
+	for _, b := range [][]byte{priv.Seed(), priv} {
+		gotPriv, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(b))
+		require.NoError(t, err)
+		require.Equal(t, priv, gotPriv)
+	}
+	_, err = ParsePrivateKey(base64.StdEncoding.EncodeToString(pub[1:]))
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
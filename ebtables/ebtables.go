@@ -46,12 +46,16 @@ func SetSnatForInterface(interfaceName string, macAddress net.HardwareAddr, acti
 func SetArpReply(ipAddress net.IP, macAddress net.HardwareAddr, action string) error {
 	table := Nat
 	chain := PreRouting
-	rule := fmt.Sprintf("-p ARP --arp-op Request --arp-ip-dst %s -j arpreply --arpreply-mac %s --arpreply-target DROP",
-		ipAddress, macAddress.String())
+	rule := arpReplyRule(ipAddress, macAddress)
 
 	return runEbCmd(table, action, chain, rule)
 }
 
+func arpReplyRule(ipAddress net.IP, macAddress net.HardwareAddr) string {
+	return fmt.Sprintf("-p ARP --arp-op Request --arp-ip-dst %s -j arpreply --arpreply-mac %s --arpreply-target DROP",
+		ipAddress, macAddress.String())
+}
+
 // SetBrouteAccept sets an EB rule.
 func SetBrouteAccept(ipAddress, action string) error {
 	table := Broute
@@ -92,6 +96,14 @@ func SetVepaMode(bridgeName string, downstreamIfNamePrefix string, upstreamMacAd
 
 // SetDnatForIPAddress sets a MAC DNAT rule for an IP address.
 func SetDnatForIPAddress(interfaceName string, ipAddress net.IP, macAddress net.HardwareAddr, action string) error {
+	table := Nat
+	chain := PreRouting
+	rule := dnatForIPAddressRule(interfaceName, ipAddress, macAddress)
+
+	return runEbCmd(table, action, chain, rule)
+}
+
+func dnatForIPAddressRule(interfaceName string, ipAddress net.IP, macAddress net.HardwareAddr) string {
 	protocol := "IPv4"
 	dst := "--ip-dst"
 	if ipAddress.To4() == nil {
@@ -99,12 +111,8 @@ func SetDnatForIPAddress(interfaceName string, ipAddress net.IP, macAddress net.
 		dst = "--ip6-dst"
 	}
 
-	table := Nat
-	chain := PreRouting
-	rule := fmt.Sprintf("-p %s -i %s %s %s -j dnat --to-dst %s --dnat-target ACCEPT",
+	return fmt.Sprintf("-p %s -i %s %s %s -j dnat --to-dst %s --dnat-target ACCEPT",
 		protocol, interfaceName, dst, ipAddress.String(), macAddress.String())
-
-	return runEbCmd(table, action, chain, rule)
 }
 
 // Drop Icmpv6 discovery messages going out of interface
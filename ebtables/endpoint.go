@@ -0,0 +1,115 @@
+package ebtables
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/platform"
+)
+
+const (
+	// endpointChainPrefix starts the names of the nat chains which hold the rules of an endpoint
+	endpointChainPrefix = "AZCNI-EP-"
+	// endpointChainHashLength keeps endpoint chain names within the 31 characters ebtables allows
+	endpointChainHashLength = 16
+	chainTitlePrefix        = "Bridge chain: "
+)
+
+var ErrNotEndpointChain = errors.New("not an endpoint chain")
+
+// EndpointChainName returns the nat chain which holds the rules of an endpoint.
+// ebtables can't comment rules, so the chain name marks which endpoint owns them.
+func EndpointChainName(endpointID string) string {
+	hash := sha256.Sum256([]byte(endpointID))
+	return endpointChainPrefix + hex.EncodeToString(hash[:])[:endpointChainHashLength]
+}
+
+// AddEndpointChain creates the nat chain of an endpoint and jumps to it from PREROUTING.
+func AddEndpointChain(endpointID string) error {
+	chain := EndpointChainName(endpointID)
+	if chainExists(Nat, chain) {
+		return nil
+	}
+
+	if err := runEbCmd(Nat, "-N", chain, "-P RETURN"); err != nil {
+		return err
+	}
+	return runEbCmd(Nat, Append, PreRouting, "-j "+chain)
+}
+
+// DeleteEndpointChain removes the nat chain of an endpoint with its rules.
+// It returns false if the endpoint has no chain, e.g. because it was created before endpoint chains were used.
+func DeleteEndpointChain(endpointID string) (bool, error) {
+	chain := EndpointChainName(endpointID)
+	if !chainExists(Nat, chain) {
+		return false, nil
+	}
+	return true, RemoveEndpointChain(chain)
+}
+
+// RemoveEndpointChain removes an endpoint chain returned by ListEndpointChains.
+func RemoveEndpointChain(chain string) error {
+	if !strings.HasPrefix(chain, endpointChainPrefix) {
+		return fmt.Errorf("%w: %s", ErrNotEndpointChain, chain)
+	}
+
+	// the jump may be missing if a previous removal failed halfway
+	jump := "-j " + chain
+	if exists, err := EbTableRuleExists(Nat, PreRouting, jump); err != nil {
+		return err
+	} else if exists {
+		if err := runEbCmd(Nat, Delete, PreRouting, jump); err != nil {
+			return err
+		}
+	}
+	if err := runEbCmd(Nat, "-F", chain, ""); err != nil {
+		return err
+	}
+	return runEbCmd(Nat, "-X", chain, "")
+}
+
+// ListEndpointChains returns the names of the nat chains made by AddEndpointChain.
+func ListEndpointChains() ([]string, error) {
+	p := platform.NewExecClient()
+	out, err := p.ExecuteCommand(fmt.Sprintf("ebtables -t %s -L", Nat))
+	if err != nil {
+		return nil, err
+	}
+	return parseEndpointChains(out), nil
+}
+
+func parseEndpointChains(out string) []string {
+	chains := make([]string, 0)
+	for _, line := range strings.Split(out, "\n") {
+		// e.g. "Bridge chain: AZCNI-EP-0123456789abcdef, entries: 2, policy: RETURN"
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, chainTitlePrefix) {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(line, chainTitlePrefix), ",")
+		if strings.HasPrefix(name, endpointChainPrefix) {
+			chains = append(chains, name)
+		}
+	}
+	return chains
+}
+
+// SetEndpointArpReply sets an ARP reply rule in the chain of an endpoint.
+func SetEndpointArpReply(endpointID string, ipAddress net.IP, macAddress net.HardwareAddr, action string) error {
+	return runEbCmd(Nat, action, EndpointChainName(endpointID), arpReplyRule(ipAddress, macAddress))
+}
+
+// SetEndpointDnatForIPAddress sets a MAC DNAT rule for an IP address in the chain of an endpoint.
+func SetEndpointDnatForIPAddress(endpointID, interfaceName string, ipAddress net.IP, macAddress net.HardwareAddr, action string) error {
+	return runEbCmd(Nat, action, EndpointChainName(endpointID), dnatForIPAddressRule(interfaceName, ipAddress, macAddress))
+}
+
+func chainExists(table, chain string) bool {
+	p := platform.NewExecClient()
+	_, err := p.ExecuteCommand(fmt.Sprintf("ebtables -t %s -L %s", table, chain))
+	return err == nil
+}
@@ -183,8 +183,15 @@ func AppendIptableRule(version, tableName, chainName, match, target string) erro
 	return RunCmd(version, cmd.Params)
 }
 
+func GetDeleteIptableRuleCmd(version, tableName, chainName, match, target string) IPTableEntry {
+	return IPTableEntry{
+		Version: version,
+		Params:  fmt.Sprintf("-t %s -D %s %s -j %s", tableName, chainName, match, target),
+	}
+}
+
 // Delete matched iptable rule
 func DeleteIptableRule(version, tableName, chainName, match, target string) error {
-	params := fmt.Sprintf("-t %s -D %s %s -j %s", tableName, chainName, match, target)
-	return RunCmd(version, params)
+	cmd := GetDeleteIptableRuleCmd(version, tableName, chainName, match, target)
+	return RunCmd(version, cmd.Params)
 }
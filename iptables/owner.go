@@ -0,0 +1,95 @@
+package iptables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/log"
+	utilexec "k8s.io/utils/exec"
+)
+
+// endpointCommentPrefix starts the comment which marks the rules owned by an endpoint
+const endpointCommentPrefix = "azure-cni-endpoint:"
+
+// EndpointRule is a rule marked with the ID of the endpoint which owns it.
+type EndpointRule struct {
+	Version    string
+	Table      string
+	Chain      string
+	EndpointID string
+	RuleSpec   string
+}
+
+// GetEndpointComment returns the match which marks a rule as owned by an endpoint.
+// It returns an empty match for an empty endpoint ID.
+func GetEndpointComment(endpointID string) string {
+	if endpointID == "" {
+		return ""
+	}
+	return fmt.Sprintf("-m comment --comment %s%s", endpointCommentPrefix, endpointID)
+}
+
+// GetDeleteCmd returns the entry which deletes the rule.
+func (r EndpointRule) GetDeleteCmd() IPTableEntry {
+	return IPTableEntry{
+		Version: r.Version,
+		Params:  fmt.Sprintf("-t %s -D %s %s", r.Table, r.Chain, r.RuleSpec),
+	}
+}
+
+// ListEndpointRules returns the rules of both IP families which are marked with GetEndpointComment.
+// IPv6 rules are skipped if ip6tables-save fails.
+func ListEndpointRules(exec utilexec.Interface) ([]EndpointRule, error) {
+	rules := make([]EndpointRule, 0)
+	for _, version := range []string{V4, V6} {
+		cmd := iptablesSave
+		if version == V6 {
+			cmd = ip6tablesSave
+		}
+
+		out, err := exec.Command(cmd).Output()
+		if err != nil {
+			if version == V6 {
+				log.Printf("Skipping IPv6 endpoint rules since %s failed: %v", cmd, err)
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s: %v", ErrSnapshotFailed, cmd, err)
+		}
+		rules = append(rules, parseEndpointRules(version, string(out))...)
+	}
+	return rules, nil
+}
+
+func parseEndpointRules(version, save string) []EndpointRule {
+	rules := make([]EndpointRule, 0)
+	table := ""
+	for _, line := range strings.Split(save, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			table = line[1:]
+			continue
+		}
+		if table == "" || !strings.HasPrefix(line, "-A ") {
+			continue
+		}
+
+		tokens := tokenize(line)
+		if len(tokens) < 3 {
+			continue
+		}
+		for i := 2; i+1 < len(tokens); i++ {
+			if tokens[i] != "--comment" || !strings.HasPrefix(tokens[i+1], endpointCommentPrefix) {
+				continue
+			}
+			rules = append(rules, EndpointRule{
+				Version:    version,
+				Table:      table,
+				Chain:      tokens[1],
+				EndpointID: strings.TrimPrefix(tokens[i+1], endpointCommentPrefix),
+				RuleSpec:   joinTokens(tokens[2:]),
+			})
+			break
+		}
+	}
+	return rules
+}
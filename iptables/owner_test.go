@@ -0,0 +1,34 @@
+package iptables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointCommentMatchesSavedRule(t *testing.T) {
+	save := `*filter
+:AZURECNIINPUT - [0:0]
+-A AZURECNIINPUT -s 169.254.0.4/32 -d 169.254.0.1/32 -m comment --comment "azure-cni-endpoint:abc-eth0" -j ACCEPT
+-A AZURECNIINPUT -s 169.254.0.5/32 -d 169.254.0.1/32 -j ACCEPT
+COMMIT
+`
+	rules := parseEndpointRules(V4, save)
+	require.Equal(t, []EndpointRule{
+		{
+			Version:    V4,
+			Table:      Filter,
+			Chain:      CNIInputChain,
+			EndpointID: "abc-eth0",
+			RuleSpec:   "-s 169.254.0.4/32 -d 169.254.0.1/32 -m comment --comment azure-cni-endpoint:abc-eth0 -j ACCEPT",
+		},
+	}, rules)
+
+	// the rule as programmed by the endpoint is found in the snapshot
+	f := newFakeIPTables(t, save, 0, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.InsertRule(V4, Filter, CNIInputChain, "-s 169.254.0.4 -d 169.254.0.1 "+GetEndpointComment("abc-eth0"), Accept)
+	require.NoError(t, tx.Apply())
+
+	require.Empty(t, GetEndpointComment(""))
+}
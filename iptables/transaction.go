@@ -0,0 +1,643 @@
+package iptables
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/log"
+	utilexec "k8s.io/utils/exec"
+)
+
+const (
+	iptablesSave     = "iptables-save"
+	ip6tablesSave    = "ip6tables-save"
+	iptablesRestore  = "iptables-restore"
+	ip6tablesRestore = "ip6tables-restore"
+)
+
+var (
+	ErrUnsupportedEntry = errors.New("unsupported iptables entry")
+	ErrSnapshotFailed   = errors.New("failed to read iptables snapshot")
+	ErrRestoreFailed    = errors.New("failed to restore iptables rules")
+)
+
+type opKind int
+
+const (
+	opCreateChain opKind = iota
+	opInsert
+	opAppend
+	opDelete
+)
+
+// operation is an IPTableEntry made by one of the Get*Cmd functions, split into its parts.
+type operation struct {
+	version  string
+	table    string
+	kind     opKind
+	chain    string
+	position string
+	ruleSpec []string
+}
+
+// Transaction collects iptables operations and applies them all at once.
+// Apply reads one iptables-save snapshot per IP family to skip chains and rules that already exist
+// (and deletes of rules that don't), then writes the remaining operations with one
+// iptables-restore --noflush per table and IP family.
+// This replaces forking one iptables process per change while holding the xtables lock each time.
+//
+// Rules are compared against the snapshot after normalizing them the way iptables-save prints them,
+// e.g. "-s 10.0.0.1" becomes "-s 10.0.0.1/32", "--set-mark 1" becomes "--set-xmark 0x1/0xffffffff" and
+// "-p tcp -m tcp --dport 80" becomes "-p tcp --dport 80", for both the legacy and nft backends.
+type Transaction struct {
+	exec    utilexec.Interface
+	entries []IPTableEntry
+}
+
+// NewTransaction creates an empty Transaction which runs the iptables binaries of the host.
+func NewTransaction() *Transaction {
+	return NewTransactionWithExec(utilexec.New())
+}
+
+// NewTransactionWithExec creates an empty Transaction which runs iptables commands with exec.
+func NewTransactionWithExec(exec utilexec.Interface) *Transaction {
+	return &Transaction{exec: exec}
+}
+
+// Add queues entries made by GetCreateChainCmd, GetInsertIptableRuleCmd, GetAppendIptableRuleCmd, or GetDeleteIptableRuleCmd.
+func (t *Transaction) Add(entries ...IPTableEntry) *Transaction {
+	t.entries = append(t.entries, entries...)
+	return t
+}
+
+// CreateChain queues the creation of a chain if it doesn't exist.
+func (t *Transaction) CreateChain(version, tableName, chainName string) *Transaction {
+	return t.Add(GetCreateChainCmd(version, tableName, chainName))
+}
+
+// InsertRule queues a rule at the beginning of a chain if it doesn't exist.
+func (t *Transaction) InsertRule(version, tableName, chainName, match, target string) *Transaction {
+	return t.Add(GetInsertIptableRuleCmd(version, tableName, chainName, match, target))
+}
+
+// AppendRule queues a rule at the end of a chain if it doesn't exist.
+func (t *Transaction) AppendRule(version, tableName, chainName, match, target string) *Transaction {
+	return t.Add(GetAppendIptableRuleCmd(version, tableName, chainName, match, target))
+}
+
+// DeleteRule queues the deletion of a rule if it exists.
+func (t *Transaction) DeleteRule(version, tableName, chainName, match, target string) *Transaction {
+	return t.Add(GetDeleteIptableRuleCmd(version, tableName, chainName, match, target))
+}
+
+// Len returns the number of queued entries.
+func (t *Transaction) Len() int {
+	return len(t.entries)
+}
+
+// restoreBatch is the iptables-restore input for one table of one IP family.
+type restoreBatch struct {
+	version string
+	table   string
+	chains  []string
+	lines   []string
+}
+
+func (b *restoreBatch) input() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "*%s\n", b.table)
+	for _, chain := range b.chains {
+		fmt.Fprintf(&buf, ":%s - [0:0]\n", chain)
+	}
+	for _, line := range b.lines {
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("COMMIT\n")
+	return buf.String()
+}
+
+// Apply programs the queued entries and clears the Transaction.
+// Tables are restored one at a time, so if a restore fails, the tables before it stay programmed.
+func (t *Transaction) Apply() error {
+	ops := make([]*operation, 0, len(t.entries))
+	for _, entry := range t.entries {
+		op, err := parseEntry(entry)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+	t.entries = nil
+
+	snapshots := make(map[string]*snapshot)
+	batchIndex := make(map[string]*restoreBatch)
+	batches := make([]*restoreBatch, 0)
+	for _, op := range ops {
+		snap, ok := snapshots[op.version]
+		if !ok {
+			var err error
+			snap, err = t.save(op.version)
+			if err != nil {
+				return err
+			}
+			snapshots[op.version] = snap
+		}
+
+		key := op.version + "/" + op.table
+		batch, ok := batchIndex[key]
+		if !ok {
+			batch = &restoreBatch{version: op.version, table: op.table}
+			batchIndex[key] = batch
+			batches = append(batches, batch)
+		}
+		snap.table(op.table).queue(op, batch)
+	}
+
+	for _, batch := range batches {
+		if len(batch.chains) == 0 && len(batch.lines) == 0 {
+			continue
+		}
+		if err := t.restore(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Transaction) save(version string) (*snapshot, error) {
+	cmd := iptablesSave
+	if version == V6 {
+		cmd = ip6tablesSave
+	}
+
+	out, err := t.exec.Command(cmd).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSnapshotFailed, cmd, err)
+	}
+	return parseSnapshot(version, string(out)), nil
+}
+
+func (t *Transaction) restore(batch *restoreBatch) error {
+	cmd := iptablesRestore
+	if batch.version == V6 {
+		cmd = ip6tablesRestore
+	}
+
+	args := []string{"--noflush"}
+	if !DisableIPTableLock {
+		args = append(args, "-w", strconv.Itoa(lockTimeout))
+	}
+
+	input := batch.input()
+	restore := t.exec.Command(cmd, args...)
+	restore.SetStdin(bytes.NewBufferString(input))
+	if out, err := restore.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s for table %s: %v: %s", ErrRestoreFailed, cmd, batch.table, err, string(out))
+	}
+	return nil
+}
+
+// parseEntry splits the params of an entry made by one of the Get*Cmd functions.
+func parseEntry(entry IPTableEntry) (*operation, error) {
+	tokens := tokenize(entry.Params)
+	if len(tokens) < 4 || tokens[0] != "-t" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEntry, entry.Params)
+	}
+
+	op := &operation{
+		version:  entry.Version,
+		table:    tokens[1],
+		chain:    tokens[3],
+		ruleSpec: tokens[4:],
+	}
+	switch tokens[2] {
+	case "-N":
+		op.kind = opCreateChain
+		if len(op.ruleSpec) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEntry, entry.Params)
+		}
+	case "-" + Insert:
+		op.kind = opInsert
+		op.position = "1"
+		if len(op.ruleSpec) > 0 {
+			if _, err := strconv.Atoi(op.ruleSpec[0]); err == nil {
+				op.position = op.ruleSpec[0]
+				op.ruleSpec = op.ruleSpec[1:]
+			}
+		}
+	case "-" + Append:
+		op.kind = opAppend
+	case "-" + Delete:
+		op.kind = opDelete
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEntry, entry.Params)
+	}
+	return op, nil
+}
+
+// snapshot holds the chains and rules of iptables-save, by table.
+type snapshot struct {
+	version string
+	tables  map[string]*tableSnapshot
+}
+
+type tableSnapshot struct {
+	version string
+	chains  map[string]bool
+	// rules counts the normalized rules of each chain
+	rules map[string]map[string]int
+}
+
+func (s *snapshot) table(name string) *tableSnapshot {
+	tbl, ok := s.tables[name]
+	if !ok {
+		tbl = &tableSnapshot{
+			version: s.version,
+			chains:  make(map[string]bool),
+			rules:   make(map[string]map[string]int),
+		}
+		s.tables[name] = tbl
+	}
+	return tbl
+}
+
+func parseSnapshot(version, save string) *snapshot {
+	snap := &snapshot{version: version, tables: make(map[string]*tableSnapshot)}
+	var tbl *tableSnapshot
+	for _, line := range strings.Split(save, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "*"):
+			tbl = snap.table(line[1:])
+		case tbl == nil:
+			continue
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(line[1:])
+			if len(fields) > 0 {
+				tbl.chains[fields[0]] = true
+			}
+		case strings.HasPrefix(line, "-A "):
+			tokens := tokenize(line)
+			if len(tokens) < 2 {
+				continue
+			}
+			tbl.chains[tokens[1]] = true
+			tbl.addRule(tokens[1], tokens[2:])
+		}
+	}
+	return snap
+}
+
+func (tbl *tableSnapshot) ruleKey(ruleSpec []string) string {
+	return strings.Join(normalizeRuleSpec(tbl.version, ruleSpec), " ")
+}
+
+func (tbl *tableSnapshot) hasRule(chain string, ruleSpec []string) bool {
+	return tbl.rules[chain][tbl.ruleKey(ruleSpec)] > 0
+}
+
+func (tbl *tableSnapshot) addRule(chain string, ruleSpec []string) {
+	if tbl.rules[chain] == nil {
+		tbl.rules[chain] = make(map[string]int)
+	}
+	tbl.rules[chain][tbl.ruleKey(ruleSpec)]++
+}
+
+func (tbl *tableSnapshot) removeRule(chain string, ruleSpec []string) {
+	key := tbl.ruleKey(ruleSpec)
+	if tbl.rules[chain][key] > 0 {
+		tbl.rules[chain][key]--
+	}
+}
+
+// queue adds op to batch unless the snapshot already has its result,
+// and updates the snapshot so later operations see it.
+func (tbl *tableSnapshot) queue(op *operation, batch *restoreBatch) {
+	rule := joinTokens(op.ruleSpec)
+	switch op.kind {
+	case opCreateChain:
+		if tbl.chains[op.chain] {
+			log.Printf("%s Chain exists in table %s", op.chain, op.table)
+			return
+		}
+		tbl.chains[op.chain] = true
+		batch.chains = append(batch.chains, op.chain)
+	case opInsert, opAppend:
+		if tbl.hasRule(op.chain, op.ruleSpec) {
+			log.Printf("Rule already exists in chain %s: %s", op.chain, rule)
+			return
+		}
+		tbl.addRule(op.chain, op.ruleSpec)
+		if op.kind == opInsert {
+			batch.lines = append(batch.lines, fmt.Sprintf("-I %s %s %s", op.chain, op.position, rule))
+		} else {
+			batch.lines = append(batch.lines, fmt.Sprintf("-A %s %s", op.chain, rule))
+		}
+	case opDelete:
+		if !tbl.hasRule(op.chain, op.ruleSpec) {
+			log.Printf("Rule doesn't exist in chain %s: %s", op.chain, rule)
+			return
+		}
+		tbl.removeRule(op.chain, op.ruleSpec)
+		batch.lines = append(batch.lines, fmt.Sprintf("-D %s %s", op.chain, rule))
+	}
+}
+
+// tokenize splits params on whitespace, keeping double quoted strings together without their quotes.
+func tokenize(params string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+	inToken, inQuotes := false, false
+	for _, r := range params {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inToken = true
+		case !inQuotes && (r == ' ' || r == '\t'):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+func joinTokens(tokens []string) string {
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		if token == "" || strings.ContainsAny(token, " \t") {
+			quoted[i] = strconv.Quote(token)
+		} else {
+			quoted[i] = token
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// option is a flag with its arguments, e.g. "! --dst-type LOCAL".
+type option struct {
+	negated bool
+	flag    string
+	args    []string
+}
+
+func (o option) tokens() []string {
+	tokens := make([]string, 0, len(o.args)+2)
+	if o.negated {
+		tokens = append(tokens, "!")
+	}
+	tokens = append(tokens, o.flag)
+	return append(tokens, o.args...)
+}
+
+// flagAliases maps flags to the spelling iptables-save prints.
+var flagAliases = map[string]string{
+	"--source":            "-s",
+	"--src":               "-s",
+	"--destination":       "-d",
+	"--dst":               "-d",
+	"--in-interface":      "-i",
+	"--out-interface":     "-o",
+	"--protocol":          "-p",
+	"--match":             "-m",
+	"--jump":              "-j",
+	"--goto":              "-g",
+	"--source-port":       "--sport",
+	"--destination-port":  "--dport",
+	"--source-ports":      "--sports",
+	"--destination-ports": "--dports",
+	// the state match is printed as the conntrack match, see normalizeOption
+	"--state": "--ctstate",
+}
+
+// basicFlagOrder is the order iptables-save prints the basic flags in, before any match, regardless of where they were given.
+var basicFlagOrder = []string{"-s", "-d", "-i", "-o", "-p"}
+
+// protocolNames are the names iptables-save prints for protocol numbers and aliases.
+var protocolNames = map[string]string{
+	"0":      "all",
+	"1":      "icmp",
+	"6":      "tcp",
+	"17":     "udp",
+	"58":     "ipv6-icmp",
+	"132":    "sctp",
+	"icmpv6": "ipv6-icmp",
+}
+
+// fullMark is the mask of a mark without one.
+const fullMark = 0xffffffff
+
+func isFlag(token string) bool {
+	return len(token) > 1 && strings.HasPrefix(token, "-")
+}
+
+// parseOptions splits a rule spec into its options, with their flags spelled the way iptables-save prints them.
+func parseOptions(ruleSpec []string) []option {
+	options := make([]option, 0, len(ruleSpec))
+	for i := 0; i < len(ruleSpec); i++ {
+		o := option{}
+		if ruleSpec[i] == "!" && i+1 < len(ruleSpec) {
+			o.negated = true
+			i++
+		}
+		o.flag = ruleSpec[i]
+		if alias, ok := flagAliases[o.flag]; ok {
+			o.flag = alias
+		}
+		// the deprecated "--flag ! arg" form negates the option too
+		if i+2 < len(ruleSpec) && ruleSpec[i+1] == "!" && !isFlag(ruleSpec[i+2]) {
+			o.negated = true
+			i++
+		}
+		for i+1 < len(ruleSpec) && ruleSpec[i+1] != "!" && !isFlag(ruleSpec[i+1]) {
+			i++
+			o.args = append(o.args, ruleSpec[i])
+		}
+		options = append(options, o)
+	}
+	return options
+}
+
+// normalizeRuleSpec rewrites a rule spec the way iptables-save prints it: the basic flags first in a fixed order,
+// then the matches in the order they were given, then the target with its options.
+func normalizeRuleSpec(version string, ruleSpec []string) []string {
+	basic := make(map[string]option)
+	matches := make([]option, 0)
+	targets := make([]option, 0)
+	inTarget := false
+	target := ""
+	for _, o := range parseOptions(ruleSpec) {
+		switch o.flag {
+		case "-s", "-d", "-i", "-o", "-p":
+			if o, ok := normalizeBasicOption(version, o); ok {
+				basic[o.flag] = o
+			}
+			continue
+		case "-m":
+			inTarget = false
+		case "-j", "-g":
+			inTarget = true
+			if len(o.args) > 0 {
+				target = o.args[0]
+			}
+		}
+		o = normalizeOption(o, target)
+		if inTarget {
+			targets = append(targets, o)
+		} else {
+			matches = append(matches, o)
+		}
+	}
+
+	normalized := make([]string, 0, len(ruleSpec))
+	for _, flag := range basicFlagOrder {
+		if o, ok := basic[flag]; ok {
+			normalized = append(normalized, o.tokens()...)
+		}
+	}
+	protocol := ""
+	if o, ok := basic["-p"]; ok && len(o.args) > 0 {
+		protocol = o.args[0]
+	}
+	for _, o := range matches {
+		// iptables loads the match of the protocol implicitly for options like --dport
+		if o.flag == "-m" && len(o.args) == 1 && o.args[0] == protocol {
+			continue
+		}
+		normalized = append(normalized, o.tokens()...)
+	}
+	for _, o := range targets {
+		normalized = append(normalized, o.tokens()...)
+	}
+	return normalized
+}
+
+// normalizeOption rewrites the arguments of a match or target option, target is the target of the rule so far.
+func normalizeOption(o option, target string) option {
+	if len(o.args) == 0 {
+		return o
+	}
+	switch o.flag {
+	case "-m":
+		// iptables-nft prints the state match as the conntrack match it is implemented with
+		if len(o.args) == 1 && o.args[0] == "state" {
+			o.args = []string{"conntrack"}
+		}
+	case "--ctstate":
+		states := strings.Split(strings.ToUpper(o.args[0]), ",")
+		sort.Strings(states)
+		o.args = []string{strings.Join(states, ",")}
+	case "--dst-type", "--src-type":
+		for j := range o.args {
+			o.args[j] = strings.ToUpper(o.args[j])
+		}
+	case "--mark":
+		if value, mask, err := parseMark(o.args[0]); err == nil {
+			if mask == fullMark {
+				o.args = []string{fmt.Sprintf("0x%x", value)}
+			} else {
+				o.args = []string{fmt.Sprintf("0x%x/0x%x", value, mask)}
+			}
+		}
+	case "--set-mark":
+		// the MARK target clears the bits of the mask and sets those of the value
+		if value, mask, err := parseMark(o.args[0]); err == nil {
+			o.flag = "--set-xmark"
+			o.args = []string{fmt.Sprintf("0x%x/0x%x", value, value|mask)}
+		}
+	case "--set-xmark":
+		if value, mask, err := parseMark(o.args[0]); err == nil {
+			o.args = []string{fmt.Sprintf("0x%x/0x%x", value, mask)}
+		}
+	case "--to":
+		switch target {
+		case "SNAT":
+			o.flag = "--to-source"
+		case "DNAT":
+			o.flag = "--to-destination"
+		}
+	}
+	return o
+}
+
+// parseMark parses a mark given as value[/mask].
+func parseMark(arg string) (value, mask uint64, err error) {
+	valueArg, maskArg, hasMask := strings.Cut(arg, "/")
+	value, err = strconv.ParseUint(valueArg, 0, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !hasMask {
+		return value, fullMark, nil
+	}
+	mask, err = strconv.ParseUint(maskArg, 0, 32)
+	return value, mask, err
+}
+
+// normalizeBasicOption rewrites the argument of a basic option, and returns false if iptables-save omits the option
+// because it matches every packet.
+func normalizeBasicOption(version string, o option) (option, bool) {
+	if len(o.args) == 0 {
+		return o, true
+	}
+	switch o.flag {
+	case "-p":
+		protocol := strings.ToLower(o.args[0])
+		if name, ok := protocolNames[protocol]; ok {
+			protocol = name
+		}
+		o.args = []string{protocol}
+		return o, protocol != "all" || o.negated
+	case "-s", "-d":
+		ipNet := parseAddress(version, o.args[0])
+		if ipNet == nil {
+			return o, true
+		}
+		o.args = []string{ipNet.String()}
+		ones, _ := ipNet.Mask.Size()
+		return o, ones != 0 || o.negated
+	}
+	return o, true
+}
+
+// parseAddress parses an address given as ip, ip/prefix or ip/netmask, or returns nil if it isn't one.
+func parseAddress(version, arg string) *net.IPNet {
+	if !strings.Contains(arg, "/") {
+		ip := net.ParseIP(arg)
+		if ip == nil {
+			return nil
+		}
+		if ip4 := ip.To4(); ip4 != nil && version != V6 {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)} //nolint:gomnd // host prefix
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)} //nolint:gomnd // host prefix
+	}
+	if _, ipNet, err := net.ParseCIDR(arg); err == nil {
+		return ipNet
+	}
+	ipArg, maskArg, _ := strings.Cut(arg, "/")
+	ip, mask := net.ParseIP(ipArg).To4(), net.ParseIP(maskArg).To4()
+	if ip == nil || mask == nil {
+		return nil
+	}
+	ipMask := net.IPMask(mask)
+	if _, bits := ipMask.Size(); bits == 0 {
+		// iptables-save prints a netmask which isn't a prefix as it is
+		return nil
+	}
+	return &net.IPNet{IP: ip.Mask(ipMask), Mask: ipMask}
+}
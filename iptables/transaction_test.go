@@ -0,0 +1,321 @@
+package iptables
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	utilexec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+const testSave = `# Generated by iptables-save v1.8.4 on Tue Oct 18 10:00:00 2022
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:AZURECNIINPUT - [0:0]
+-A INPUT -j AZURECNIINPUT
+-A AZURECNIINPUT -i azSnatbr -m state --state RELATED,ESTABLISHED -j ACCEPT
+COMMIT
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+:SWIFT - [0:0]
+-A POSTROUTING -j SWIFT
+-A SWIFT -s 10.0.0.0/24 -d 168.63.129.16/32 -p udp -m addrtype ! --dst-type LOCAL -m udp --dport 53 -j SNAT --to-source 10.0.0.4
+COMMIT
+*mangle
+:PREROUTING ACCEPT [0:0]
+-A PREROUTING -j MARK --set-xmark 0x14d/0xffffffff
+COMMIT
+`
+
+type fakeIPTables struct {
+	fexec    *testingexec.FakeExec
+	commands [][]string
+	stdins   []string
+}
+
+// maxFakeCommands bounds the iptables calls of a test
+const maxFakeCommands = 32
+
+// newFakeIPTables expects one iptables-save followed by the given number of iptables-restore calls
+func newFakeIPTables(t *testing.T, save string, restores int, restoreErr error) *fakeIPTables {
+	f := &fakeIPTables{fexec: &testingexec.FakeExec{}}
+	for i := 0; i < maxFakeCommands; i++ {
+		f.fexec.CommandScript = append(f.fexec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+			fcmd := &testingexec.FakeCmd{}
+			switch {
+			case strings.HasSuffix(cmd, "-save"):
+				f.commands = append(f.commands, append([]string{cmd}, args...))
+				fcmd.OutputScript = []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(save), nil, nil },
+				}
+			case strings.HasSuffix(cmd, "-restore"):
+				f.commands = append(f.commands, append([]string{cmd}, args...))
+				require.LessOrEqual(t, len(f.stdins), restores, "unexpected %s", cmd)
+				fcmd.CombinedOutputScript = []testingexec.FakeAction{
+					func() ([]byte, []byte, error) {
+						stdin, err := io.ReadAll(fcmd.Stdin)
+						require.NoError(t, err)
+						f.stdins = append(f.stdins, string(stdin))
+						return nil, nil, restoreErr
+					},
+				}
+			default:
+				t.Errorf("unexpected command %s %v", cmd, args)
+			}
+			return testingexec.InitFakeCmd(fcmd, cmd, args...)
+		})
+	}
+	return f
+}
+
+func TestTransactionSkipsExistingChainsAndRules(t *testing.T) {
+	f := newFakeIPTables(t, testSave, 0, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.CreateChain(V4, Filter, CNIInputChain).
+		InsertRule(V4, Filter, Input, "", CNIInputChain).
+		InsertRule(V4, Filter, CNIInputChain, " -i azSnatbr -m state --state ESTABLISHED,RELATED", Accept).
+		DeleteRule(V4, Filter, CNIInputChain, "-s 169.254.0.1 -d 169.254.0.4", Accept).
+		Add(
+			GetCreateChainCmd(V4, Nat, Swift),
+			GetAppendIptableRuleCmd(V4, Nat, Postrouting, "", Swift),
+			GetInsertIptableRuleCmd(V4, Nat, Swift, " -m addrtype ! --dst-type local -s 10.0.0.0/24 -d 168.63.129.16 -p udp --dport 53", "SNAT --to 10.0.0.4"),
+		).
+		InsertRule(V4, Mangle, Prerouting, "", "MARK --set-mark 333")
+	require.Equal(t, 8, tx.Len())
+
+	require.NoError(t, tx.Apply())
+	require.Equal(t, [][]string{{"iptables-save"}}, f.commands)
+	require.Equal(t, 0, tx.Len())
+}
+
+func TestTransactionRestoresPerTable(t *testing.T) {
+	f := newFakeIPTables(t, testSave, 2, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.CreateChain(V4, Filter, CNIOutputChain).
+		InsertRule(V4, Filter, Output, "", CNIOutputChain).
+		InsertRule(V4, Filter, CNIOutputChain, "-s 169.254.0.1 -d 169.254.0.4", Accept).
+		// queued twice in the same transaction, so only restored once
+		InsertRule(V4, Filter, CNIOutputChain, "-s 169.254.0.1 -d 169.254.0.4", Accept).
+		DeleteRule(V4, Filter, CNIInputChain, "-i azSnatbr -m state --state RELATED,ESTABLISHED", Accept).
+		AppendRule(V4, Nat, Postrouting, "-s 169.254.0.0/16", Masquerade).
+		AppendRule(V4, Filter, Forward, "", Accept)
+
+	require.NoError(t, tx.Apply())
+	require.Equal(t, [][]string{
+		{"iptables-save"},
+		{"iptables-restore", "--noflush", "-w", "60"},
+		{"iptables-restore", "--noflush", "-w", "60"},
+	}, f.commands)
+	require.Equal(t, []string{
+		`*filter
+:AZURECNIOUTPUT - [0:0]
+-I OUTPUT 1 -j AZURECNIOUTPUT
+-I AZURECNIOUTPUT 1 -s 169.254.0.1 -d 169.254.0.4 -j ACCEPT
+-D AZURECNIINPUT -i azSnatbr -m state --state RELATED,ESTABLISHED -j ACCEPT
+-A FORWARD -j ACCEPT
+COMMIT
+`,
+		`*nat
+-A POSTROUTING -s 169.254.0.0/16 -j MASQUERADE
+COMMIT
+`,
+	}, f.stdins)
+}
+
+// testNftSave is iptables-save output of iptables-nft, which prints the state match as the conntrack match
+// and keeps the explicit protocol match
+const testNftSave = `# Generated by iptables-save v1.8.7 on Tue Oct 18 10:00:00 2022
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:AZURECNIINPUT - [0:0]
+-A INPUT -j AZURECNIINPUT
+-A AZURECNIINPUT -i azSnatbr -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A AZURECNIINPUT -i azSnatbr -p tcp -m tcp --dport 53 -m comment --comment "azure dns" -j ACCEPT
+COMMIT
+# Completed on Tue Oct 18 10:00:00 2022
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+:SWIFT - [0:0]
+-A POSTROUTING -j SWIFT
+-A SWIFT -s 10.0.0.0/24 -d 168.63.129.16/32 -p udp -m addrtype ! --dst-type LOCAL -m udp --dport 53 -j SNAT --to-source 10.0.0.4
+COMMIT
+# Completed on Tue Oct 18 10:00:00 2022
+`
+
+func TestTransactionNftSave(t *testing.T) {
+	f := newFakeIPTables(t, testNftSave, 0, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.CreateChain(V4, Filter, CNIInputChain).
+		InsertRule(V4, Filter, CNIInputChain, "-i azSnatbr -m state --state ESTABLISHED,RELATED", Accept).
+		InsertRule(V4, Filter, CNIInputChain, `-i azSnatbr -p tcp --dport 53 -m comment --comment "azure dns"`, Accept).
+		InsertRule(V4, Nat, Swift, "-m addrtype ! --dst-type local -s 10.0.0.0/24 -d 168.63.129.16 -p udp --dport 53", "SNAT --to 10.0.0.4")
+
+	require.NoError(t, tx.Apply())
+	require.Equal(t, [][]string{{"iptables-save"}}, f.commands)
+}
+
+func TestTransactionMatchesRulesAsSaved(t *testing.T) {
+	// the rule is given in forms iptables-save prints differently, it's still found in the snapshot
+	const match = "-s 10.0.0.0/255.255.255.0 -d 0.0.0.0/0 -p 17 -m mark --mark 1 -m addrtype --dst-type ! local"
+	f := newFakeIPTables(t, `*nat
+:POSTROUTING ACCEPT [0:0]
+-A POSTROUTING -s 10.0.0.0/24 -p udp -m mark --mark 0x1 -m addrtype ! --dst-type LOCAL -j SNAT --to-source 10.0.0.4
+COMMIT
+`, 1, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.InsertRule(V4, Nat, Postrouting, match, "SNAT --to 10.0.0.4").
+		DeleteRule(V4, Nat, Postrouting, match, "SNAT --to 10.0.0.4").
+		DeleteRule(V4, Nat, Postrouting, match, "SNAT --to 10.0.0.4")
+
+	require.NoError(t, tx.Apply())
+	// the insert is skipped and the rule is deleted once
+	require.Equal(t, []string{"*nat\n-D POSTROUTING " + match + " -j SNAT --to 10.0.0.4\nCOMMIT\n"}, f.stdins)
+}
+
+func TestTransactionReinsertsDeletedRule(t *testing.T) {
+	f := newFakeIPTables(t, testSave, 1, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.DeleteRule(V4, Filter, Input, "", CNIInputChain).
+		InsertRule(V4, Filter, Input, "", CNIInputChain)
+
+	require.NoError(t, tx.Apply())
+	require.Equal(t, []string{"*filter\n-D INPUT -j AZURECNIINPUT\n-I INPUT 1 -j AZURECNIINPUT\nCOMMIT\n"}, f.stdins)
+}
+
+func TestTransactionIPv6(t *testing.T) {
+	f := newFakeIPTables(t, "*mangle\n:POSTROUTING ACCEPT [0:0]\nCOMMIT\n", 1, nil)
+	tx := NewTransactionWithExec(f.fexec)
+	tx.InsertRule(V6, Mangle, Postrouting, "", "MARK --set-mark 0x0")
+
+	require.NoError(t, tx.Apply())
+	require.Equal(t, "ip6tables-save", f.commands[0][0])
+	require.Equal(t, "ip6tables-restore", f.commands[1][0])
+	require.Equal(t, []string{"*mangle\n-I POSTROUTING 1 -j MARK --set-mark 0x0\nCOMMIT\n"}, f.stdins)
+}
+
+func TestTransactionErrors(t *testing.T) {
+	tx := NewTransactionWithExec(&testingexec.FakeExec{})
+	tx.Add(IPTableEntry{Version: V4, Params: "-t filter -L INPUT"})
+	require.ErrorIs(t, tx.Apply(), ErrUnsupportedEntry)
+
+	f := newFakeIPTables(t, testSave, 1, errors.New("exit status 1"))
+	tx = NewTransactionWithExec(f.fexec)
+	tx.CreateChain(V4, Filter, CNIOutputChain)
+	require.ErrorIs(t, tx.Apply(), ErrRestoreFailed)
+}
+
+func TestNormalizeRuleSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		ruleSpec string
+		want     string
+	}{
+		{
+			name:     "hosts get a prefix length",
+			ruleSpec: "-d 10.0.0.1 -s 10.0.0.0/8 -j ACCEPT",
+			want:     "-s 10.0.0.0/8 -d 10.0.0.1/32 -j ACCEPT",
+		},
+		{
+			name:     "implicit protocol match",
+			ruleSpec: "-p TCP --dport 80 -j DROP",
+			want:     "-p tcp --dport 80 -j DROP",
+		},
+		{
+			name:     "explicit protocol match",
+			ruleSpec: "-p tcp -m tcp --dport 80 -j DROP",
+			want:     "-p tcp --dport 80 -j DROP",
+		},
+		{
+			name:     "comments keep their spaces",
+			ruleSpec: `-m comment --comment "allow all" -j ACCEPT`,
+			want:     "-m comment --comment allow all -j ACCEPT",
+		},
+		{
+			name:     "state match",
+			ruleSpec: "-m state --state RELATED,ESTABLISHED -j ACCEPT",
+			want:     "-m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT",
+		},
+		{
+			name:     "dnat",
+			ruleSpec: "-j DNAT --to 10.0.0.1:80",
+			want:     "-j DNAT --to-destination 10.0.0.1:80",
+		},
+		{
+			name:     "long flags",
+			ruleSpec: "--source 10.0.0.1 --protocol tcp --match tcp --destination-port 80 --jump ACCEPT",
+			want:     "-s 10.0.0.1/32 -p tcp --dport 80 -j ACCEPT",
+		},
+		{
+			name:     "netmask and host bits",
+			ruleSpec: "-s 10.0.0.5/255.255.255.0 -d 10.1.2.3/16 -j ACCEPT",
+			want:     "-s 10.0.0.0/24 -d 10.1.0.0/16 -j ACCEPT",
+		},
+		{
+			name:     "any address and protocol are omitted",
+			ruleSpec: "-s 0.0.0.0/0 -p all -j ACCEPT",
+			want:     "-j ACCEPT",
+		},
+		{
+			name:     "negated any address is kept",
+			ruleSpec: "! -d 0.0.0.0/0 -j ACCEPT",
+			want:     "! -d 0.0.0.0/0 -j ACCEPT",
+		},
+		{
+			name:     "protocol number",
+			ruleSpec: "-p 6 --dport 443 -j ACCEPT",
+			want:     "-p tcp --dport 443 -j ACCEPT",
+		},
+		{
+			name:     "deprecated negation",
+			ruleSpec: "-m addrtype --dst-type ! local -j RETURN",
+			want:     "-m addrtype ! --dst-type LOCAL -j RETURN",
+		},
+		{
+			name:     "mark match",
+			ruleSpec: "-m mark --mark 16 -j ACCEPT",
+			want:     "-m mark --mark 0x10 -j ACCEPT",
+		},
+		{
+			name:     "masked mark match",
+			ruleSpec: "-m mark --mark 0x10/0xF0 -j ACCEPT",
+			want:     "-m mark --mark 0x10/0xf0 -j ACCEPT",
+		},
+		{
+			name:     "set mark",
+			ruleSpec: "-j MARK --set-mark 0x10/0xf0",
+			want:     "-j MARK --set-xmark 0x10/0xf0",
+		},
+		{
+			name:     "set xmark",
+			ruleSpec: "-j MARK --set-xmark 16",
+			want:     "-j MARK --set-xmark 0x10/0xffffffff",
+		},
+		{
+			name:     "matches after the target",
+			ruleSpec: "-j ACCEPT -m comment --comment azure -s 10.0.0.1",
+			want:     "-s 10.0.0.1/32 -m comment --comment azure -j ACCEPT",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, joinNormalized(V4, tt.ruleSpec))
+		})
+	}
+	require.Equal(t, "-s fd00::1/128 -j ACCEPT", joinNormalized(V6, "-s fd00::1 -j ACCEPT"))
+	require.Equal(t, "-d fd00::/64 -p ipv6-icmp -j ACCEPT", joinNormalized(V6, "-d fd00:0::5/64 -p icmpv6 -j ACCEPT"))
+}
+
+func joinNormalized(version, ruleSpec string) string {
+	tbl := (&snapshot{version: version, tables: map[string]*tableSnapshot{}}).table(Filter)
+	return tbl.ruleKey(tokenize(ruleSpec))
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// ModuleLevel is a log level override of a module. An empty level removes the override.
+type ModuleLevel struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+// LevelHandler serves the log level overrides by module on GET, and sets the override of a
+// ModuleLevel in the request body on PUT or POST, replying with the overrides.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req ModuleLevel
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Module == "" {
+				http.Error(w, "invalid request: module is required", http.StatusBadRequest)
+				return
+			}
+			if req.Level == "" {
+				ResetModuleLevel(req.Module)
+				Printf("[log] Reset log level of module %s", req.Module)
+				break
+			}
+			level, err := ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			SetModuleLevel(req.Module, level)
+			Printf("[log] Set log level of module %s to %s", req.Module, LevelName(level))
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		levels := ModuleLevels()
+		resp := make([]ModuleLevel, 0, len(levels))
+		for module, level := range levels {
+			resp = append(resp, ModuleLevel{Module: module, Level: LevelName(level)})
+		}
+		sort.Slice(resp, func(i, j int) bool { return resp[i].Module < resp[j].Module })
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
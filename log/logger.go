@@ -10,6 +10,8 @@ import (
 	"os"
 	"path"
 	"sync"
+
+	"go.uber.org/zap/zapcore"
 )
 
 // Log level
@@ -54,6 +56,12 @@ type Logger struct {
 	callCount    int
 	directory    string
 	mutex        *sync.Mutex
+	format       int
+
+	// parent, module and fields are set on the loggers returned by Module and With, which write through parent.
+	parent *Logger
+	module string
+	fields []zapcore.Field
 }
 
 var pid = os.Getpid()
@@ -103,27 +111,28 @@ func (logger *Logger) SetName(name string) {
 	logger.name = name
 }
 
-// SetLevel sets the log chattiness.
+// SetLevel sets the log chattiness, unless overridden for the module with SetModuleLevel.
 func (logger *Logger) SetLevel(level int) {
-	logger.level = level
+	logger.root().level = level
 }
 
 // SetLogFileLimits sets the log file limits.
 func (logger *Logger) SetLogFileLimits(maxFileSize int, maxFileCount int) {
-	logger.maxFileSize = maxFileSize
-	logger.maxFileCount = maxFileCount
+	root := logger.root()
+	root.maxFileSize = maxFileSize
+	root.maxFileCount = maxFileCount
 }
 
 // Close closes the log stream.
 func (logger *Logger) Close() {
-	if logger.out != nil {
-		logger.out.Close()
+	if root := logger.root(); root.out != nil {
+		root.out.Close()
 	}
 }
 
 // SetTargetLogDirectory sets the directory location where logs should be stored along with the target
 func (logger *Logger) SetTargetLogDirectory(target int, logDirectory string) error {
-	logger.directory = logDirectory
+	logger.root().directory = logDirectory
 	return logger.SetTarget(target)
 }
 
@@ -160,7 +169,7 @@ func (logger *Logger) rotate() {
 	fileName := logger.getLogFileName()
 	fileInfo, err := os.Stat(fileName)
 	if err != nil {
-		logger.Logf("[log] Failed to query log file info %+v.", err)
+		logger.write(logger.entry(LevelError, fmt.Sprintf("[log] Failed to query log file info %+v.", err)), nil)
 		return
 	}
 
@@ -218,51 +227,44 @@ func (logger *Logger) ResponseEx(tag string, request interface{}, response inter
 	}
 }
 
-// logf logs a formatted string.
-func (logger *Logger) logf(format string, args ...interface{}) {
-	if logger.callCount%rotationCheckFrq == 0 {
-		logger.rotate()
-	}
-	format = fmt.Sprintf("[%v] %s", pid, format)
-	logger.callCount++
-	logger.l.Printf(format, args...)
+// logf logs a formatted string at level.
+func (logger *Logger) logf(level int, format string, args ...interface{}) {
+	logger.log(logger.entry(level, fmt.Sprintf(format, args...)), nil)
 }
 
-// Logf wraps logf.
+// Logf logs a formatted string regardless of the log level.
 func (logger *Logger) Logf(format string, args ...interface{}) {
-	logger.mutex.Lock()
-	logger.logf(format, args...)
-	logger.mutex.Unlock()
+	logger.logf(LevelInfo, format, args...)
 }
 
 // Printf logs a formatted string at info level.
 func (logger *Logger) Printf(format string, args ...interface{}) {
-	if logger.level < LevelInfo {
+	if !logger.enabled(LevelInfo) {
 		return
 	}
 
-	logger.Logf(format, args...)
+	logger.logf(LevelInfo, format, args...)
 }
 
-// Debugf logs a formatted string at info level.
+// Debugf logs a formatted string at debug level.
 func (logger *Logger) Debugf(format string, args ...interface{}) {
-	if logger.level < LevelDebug {
+	if !logger.enabled(LevelDebug) {
 		return
 	}
 
-	logger.Logf(format, args...)
+	logger.logf(LevelDebug, format, args...)
 }
 
-// Errorf logs a formatted string at info level and sends the string to TelemetryBuffer.
+// Errorf logs a formatted string at error level regardless of the log level.
 func (logger *Logger) Errorf(format string, args ...interface{}) {
-	logger.Logf(format, args...)
+	logger.logf(LevelError, format, args...)
 }
 
-// Warnf logs a formatted string at warninglevel
+// Warnf logs a formatted string at warning level.
 func (logger *Logger) Warnf(format string, args ...interface{}) {
-	if logger.level < LevelWarning {
+	if !logger.enabled(LevelWarning) {
 		return
 	}
 
-	logger.Logf(format, args...)
+	logger.logf(LevelWarning, format, args...)
 }
@@ -4,11 +4,14 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"log/syslog"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 const (
@@ -18,6 +21,8 @@ const (
 
 // SetTarget sets the log target.
 func (logger *Logger) SetTarget(target int) error {
+	// the loggers returned by Module and With write through their root logger.
+	logger = logger.root()
 	var err error
 
 	switch target {
@@ -52,3 +57,21 @@ func (logger *Logger) SetTarget(target int) error {
 
 	return err
 }
+
+// ToggleDebugOnSignal toggles debug logging of all modules with ToggleDebug each time the process
+// receives SIGUSR1, until ctx is done.
+func ToggleDebugOnSignal(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigCh:
+				Logf("[log] Received SIGUSR1, debug logging of all modules is %v.", ToggleDebug())
+			}
+		}
+	}()
+}
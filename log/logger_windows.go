@@ -4,6 +4,7 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -16,6 +17,8 @@ const (
 
 // SetTarget sets the log target.
 func (logger *Logger) SetTarget(target int) error {
+	// the loggers returned by Module and With write through their root logger.
+	logger = logger.root()
 	var err error
 
 	switch target {
@@ -47,3 +50,7 @@ func (logger *Logger) SetTarget(target int) error {
 
 	return err
 }
+
+// ToggleDebugOnSignal does nothing, Windows has no SIGUSR1. Use LevelHandler to change the log
+// levels at runtime.
+func ToggleDebugOnSignal(context.Context) {}
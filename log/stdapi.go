@@ -20,6 +20,10 @@ func SetLevel(level int) {
 	stdLog.SetLevel(level)
 }
 
+func SetFormat(format int) error {
+	return stdLog.SetFormat(format)
+}
+
 func SetLogFileLimits(maxFileSize int, maxFileCount int) {
 	stdLog.SetLogFileLimits(maxFileSize, maxFileCount)
 }
@@ -61,3 +65,25 @@ func Debugf(format string, args ...interface{}) {
 func Errorf(format string, args ...interface{}) {
 	stdLog.Errorf(format, args...)
 }
+
+func Warnf(format string, args ...interface{}) {
+	stdLog.Warnf(format, args...)
+}
+
+// Module returns a logger writing through the standard logger for a module of the component.
+func Module(module string) *Logger {
+	return stdLog.Module(module)
+}
+
+// With returns a logger writing through the standard logger which adds the key/value pairs to every record.
+func With(keysAndValues ...interface{}) *Logger {
+	return stdLog.With(keysAndValues...)
+}
+
+func Infow(msg string, keysAndValues ...interface{}) {
+	stdLog.Infow(msg, keysAndValues...)
+}
+
+func Errorw(msg string, keysAndValues ...interface{}) {
+	stdLog.Errorw(msg, keysAndValues...)
+}
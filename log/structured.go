@@ -0,0 +1,314 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log format
+const (
+	// FormatText writes printf style lines prefixed with the time and pid.
+	FormatText = iota
+	// FormatJSON writes one JSON record per line, in the format of the zap JSON encoder used by the CNI.
+	FormatJSON
+)
+
+// Keys of the fields every JSON record has besides the zap time, level and message keys.
+const (
+	ComponentKey = "component"
+	ModuleKey    = "module"
+	PidKey       = "pid"
+)
+
+// AllModules is the module name whose level override applies to the modules without one of their own.
+const AllModules = "*"
+
+var levelNames = map[int]string{
+	LevelAlert:   "alert",
+	LevelError:   "error",
+	LevelWarning: "warning",
+	LevelInfo:    "info",
+	LevelDebug:   "debug",
+}
+
+var (
+	moduleLevelsMutex sync.RWMutex
+	moduleLevels      = map[string]int{}
+)
+
+// LevelName returns the name of a log level.
+func LevelName(level int) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", level)
+}
+
+// ParseLevel returns the log level named name.
+func ParseLevel(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warn" {
+		return LevelWarning, nil
+	}
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %q", name)
+}
+
+// SetModuleLevel overrides the log level of the loggers of module, or of all modules without
+// an override of their own for AllModules. The module of a logger is its name unless it was
+// returned by Module.
+func SetModuleLevel(module string, level int) {
+	moduleLevelsMutex.Lock()
+	defer moduleLevelsMutex.Unlock()
+
+	moduleLevels[module] = level
+}
+
+// ResetModuleLevel removes the log level override of module.
+func ResetModuleLevel(module string) {
+	moduleLevelsMutex.Lock()
+	defer moduleLevelsMutex.Unlock()
+
+	delete(moduleLevels, module)
+}
+
+// ModuleLevels returns the log level overrides by module.
+func ModuleLevels() map[string]int {
+	moduleLevelsMutex.RLock()
+	defer moduleLevelsMutex.RUnlock()
+
+	levels := make(map[string]int, len(moduleLevels))
+	for module, level := range moduleLevels {
+		levels[module] = level
+	}
+	return levels
+}
+
+// ToggleDebug overrides the level of all modules to debug, or removes that override if it is
+// set, and returns whether it is set.
+func ToggleDebug() bool {
+	moduleLevelsMutex.Lock()
+	defer moduleLevelsMutex.Unlock()
+
+	if level, ok := moduleLevels[AllModules]; ok && level == LevelDebug {
+		delete(moduleLevels, AllModules)
+		return false
+	}
+	moduleLevels[AllModules] = LevelDebug
+	return true
+}
+
+// SetFormat sets the format of the log records.
+func (logger *Logger) SetFormat(format int) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("invalid log format %d", format)
+	}
+	root := logger.root()
+	root.mutex.Lock()
+	root.format = format
+	root.mutex.Unlock()
+	return nil
+}
+
+// Module returns a logger writing through logger for a module of the component, whose level
+// can be overridden with SetModuleLevel.
+func (logger *Logger) Module(module string) *Logger {
+	return &Logger{parent: logger.root(), module: module, fields: logger.fields}
+}
+
+// With returns a logger writing through logger which adds the key/value pairs to every record.
+func (logger *Logger) With(keysAndValues ...interface{}) *Logger {
+	fields := make([]zapcore.Field, 0, len(logger.fields)+len(keysAndValues)/2) //nolint:gomnd // key/value pairs
+	fields = append(fields, logger.fields...)
+	fields = append(fields, toFields(keysAndValues)...)
+	return &Logger{parent: logger.root(), module: logger.module, fields: fields}
+}
+
+// Debugw logs a message with key/value pairs at debug level.
+func (logger *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	logger.logw(LevelDebug, msg, keysAndValues)
+}
+
+// Infow logs a message with key/value pairs at info level.
+func (logger *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	logger.logw(LevelInfo, msg, keysAndValues)
+}
+
+// Warnw logs a message with key/value pairs at warning level.
+func (logger *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	logger.logw(LevelWarning, msg, keysAndValues)
+}
+
+// Errorw logs a message with key/value pairs at error level regardless of the log level.
+func (logger *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	logger.log(logger.entry(LevelError, msg), toFields(keysAndValues))
+}
+
+func (logger *Logger) logw(level int, msg string, keysAndValues []interface{}) {
+	if !logger.enabled(level) {
+		return
+	}
+	logger.log(logger.entry(level, msg), toFields(keysAndValues))
+}
+
+// toFields converts key/value pairs to fields. A key which isn't a string, or misses its value,
+// is logged as the value of an "ignored" field rather than dropped.
+func toFields(keysAndValues []interface{}) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(keysAndValues)/2) //nolint:gomnd // key/value pairs
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			fields = append(fields, zap.Any("ignored", keysAndValues[i]))
+			continue
+		}
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+	}
+	return fields
+}
+
+func (logger *Logger) root() *Logger {
+	if logger.parent != nil {
+		return logger.parent
+	}
+	return logger
+}
+
+func (logger *Logger) moduleName() string {
+	if logger.module != "" {
+		return logger.module
+	}
+	return logger.root().name
+}
+
+// enabled returns whether records at level are logged, by the level override of the module if
+// there is one, else by the logger level.
+func (logger *Logger) enabled(level int) bool {
+	moduleLevelsMutex.RLock()
+	override, ok := moduleLevels[logger.moduleName()]
+	if !ok {
+		override, ok = moduleLevels[AllModules]
+	}
+	moduleLevelsMutex.RUnlock()
+
+	if ok {
+		return level <= override
+	}
+	return level <= logger.root().level
+}
+
+func (logger *Logger) entry(level int, msg string) zapcore.Entry {
+	return zapcore.Entry{Level: zapLevel(level), Time: time.Now(), Message: msg}
+}
+
+// log writes a record with the fields of the logger and fields, rotating the log file when needed.
+func (logger *Logger) log(entry zapcore.Entry, fields []zapcore.Field) {
+	if len(logger.fields) > 0 {
+		fields = append(append(make([]zapcore.Field, 0, len(logger.fields)+len(fields)), logger.fields...), fields...)
+	}
+	fields = append(fields, zap.String(ModuleKey, logger.moduleName()))
+
+	root := logger.root()
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+
+	root.callCount++
+	if root.callCount%rotationCheckFrq == 1 {
+		root.rotate()
+	}
+	root.write(entry, fields)
+}
+
+// write writes a record in the log format. Caller holds the logger mutex.
+func (logger *Logger) write(entry zapcore.Entry, fields []zapcore.Field) {
+	if logger.format != FormatJSON {
+		logger.l.Printf("[%v] %s%s", pid, entry.Message, textFields(fields))
+		return
+	}
+
+	all := make([]zapcore.Field, 0, len(fields)+2) //nolint:gomnd // component and pid
+	if !hasKey(fields, ComponentKey) {
+		all = append(all, zap.String(ComponentKey, logger.name))
+	}
+	all = append(all, zap.Int(PidKey, pid))
+	all = append(all, fields...)
+
+	buf, err := jsonEncoder.EncodeEntry(entry, all)
+	if err != nil {
+		logger.l.Printf("[%v] [log] Failed to encode record %q: %v", pid, entry.Message, err)
+		return
+	}
+	defer buf.Free()
+	_, _ = logger.l.Writer().Write(buf.Bytes())
+}
+
+var jsonEncoder = func() zapcore.Encoder {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	return zapcore.NewJSONEncoder(encoderConfig)
+}()
+
+// textFields renders fields as key=value pairs appended to a text line. The module field is
+// left out, text lines never had it.
+func textFields(fields []zapcore.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	var b strings.Builder
+	for i := range fields {
+		if fields[i].Key == ModuleKey {
+			continue
+		}
+		fields[i].AddTo(enc)
+		fmt.Fprintf(&b, " %s=%v", fields[i].Key, enc.Fields[fields[i].Key])
+	}
+	return b.String()
+}
+
+func hasKey(fields []zapcore.Field, key string) bool {
+	for i := range fields {
+		if fields[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func zapLevel(level int) zapcore.Level {
+	switch {
+	case level <= LevelAlert:
+		return zapcore.DPanicLevel
+	case level == LevelError:
+		return zapcore.ErrorLevel
+	case level == LevelWarning:
+		return zapcore.WarnLevel
+	case level == LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+func fromZapLevel(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return LevelDebug
+	case level == zapcore.InfoLevel:
+		return LevelInfo
+	case level == zapcore.WarnLevel:
+		return LevelWarning
+	case level == zapcore.ErrorLevel:
+		return LevelError
+	default:
+		return LevelAlert
+	}
+}
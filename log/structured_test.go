@@ -0,0 +1,168 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newBufferLogger returns a logger writing to a buffer instead of its target.
+func newBufferLogger(t *testing.T, level, format int) (*Logger, *bytes.Buffer) {
+	l := NewLogger(logName, level, TargetStderr, "")
+	if err := l.SetFormat(format); err != nil {
+		t.Fatalf("Failed to set format: %v", err)
+	}
+	var buf bytes.Buffer
+	l.l.SetOutput(&buf)
+	t.Cleanup(func() {
+		for module := range ModuleLevels() {
+			ResetModuleLevel(module)
+		}
+	})
+	return l, &buf
+}
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to decode record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJSONFormat(t *testing.T) {
+	l, buf := newBufferLogger(t, LevelInfo, FormatJSON)
+
+	l.Printf("hello %s", "world")
+	l.Module("ipam").With("ip", "10.0.0.4").Warnw("released", "pool", 2)
+	l.Debugf("dropped")
+
+	records := decodeRecords(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %v", records)
+	}
+	if records[0]["msg"] != "hello world" || records[0]["level"] != "info" || records[0][ComponentKey] != logName ||
+		records[0][ModuleKey] != logName || records[0][PidKey] == nil {
+		t.Errorf("Unexpected record %v", records[0])
+	}
+	if records[1]["msg"] != "released" || records[1]["level"] != "warn" || records[1][ComponentKey] != logName ||
+		records[1][ModuleKey] != "ipam" || records[1]["ip"] != "10.0.0.4" || records[1]["pool"] != float64(2) {
+		t.Errorf("Unexpected record %v", records[1])
+	}
+}
+
+func TestTextFormatFields(t *testing.T) {
+	l, buf := newBufferLogger(t, LevelInfo, FormatText)
+
+	l.With("ip", "10.0.0.4").Infow("released", "pool", 2, 3)
+
+	if !strings.Contains(buf.String(), "released ip=10.0.0.4 pool=2 ignored=3\n") {
+		t.Errorf("Unexpected log: %s", buf.String())
+	}
+}
+
+func TestModuleLevel(t *testing.T) {
+	l, buf := newBufferLogger(t, LevelInfo, FormatJSON)
+	ipam := l.Module("ipam")
+
+	SetModuleLevel("ipam", LevelDebug)
+	ipam.Debugf("ipam debug")
+	l.Debugf("dropped")
+
+	SetModuleLevel(logName, LevelError)
+	l.Printf("dropped")
+	ResetModuleLevel(logName)
+
+	if !ToggleDebug() {
+		t.Fatal("Expected debug logging of all modules to be on")
+	}
+	l.Debugf("all debug")
+	if ToggleDebug() {
+		t.Fatal("Expected debug logging of all modules to be off")
+	}
+	l.Debugf("dropped")
+
+	records := decodeRecords(t, buf)
+	if len(records) != 2 || records[0]["msg"] != "ipam debug" || records[1]["msg"] != "all debug" {
+		t.Errorf("Unexpected records %v", records)
+	}
+}
+
+func TestModuleSetsRootTarget(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLogger(logName, LevelInfo, TargetStderr, dir)
+	ipam := l.Module("ipam").With("ip", "10.0.0.4")
+
+	ipam.SetLogFileLimits(1024, 2)
+	if l.maxFileSize != 1024 || l.maxFileCount != 2 {
+		t.Errorf("Expected the limits to be set on the root logger, got %d %d", l.maxFileSize, l.maxFileCount)
+	}
+	if err := ipam.SetTarget(TargetLogfile); err != nil {
+		t.Fatalf("Failed to set target: %v", err)
+	}
+	ipam.Printf("released")
+	ipam.Close()
+
+	b, err := os.ReadFile(l.getLogFileName())
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(b), "released ip=10.0.0.4\n") {
+		t.Errorf("Unexpected log: %s", b)
+	}
+}
+
+func TestZapCore(t *testing.T) {
+	l, buf := newBufferLogger(t, LevelInfo, FormatJSON)
+	z := zap.New(l.ZapCore()).With(zap.String(ComponentKey, "cni"))
+
+	z.Debug("dropped")
+	z.Info("from zap", zap.String("containerID", "c1"))
+
+	records := decodeRecords(t, buf)
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %v", records)
+	}
+	if records[0]["msg"] != "from zap" || records[0][ComponentKey] != "cni" || records[0]["containerID"] != "c1" {
+		t.Errorf("Unexpected record %v", records[0])
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	newBufferLogger(t, LevelInfo, FormatText)
+	handler := LevelHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"module":"ipam","level":"debug"}`)))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `[{"module":"ipam","level":"debug"}]` {
+		t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"module":"ipam","level":"loud"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected bad request for an invalid level, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"module":"ipam"}`)))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `[]` {
+		t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
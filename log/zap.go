@@ -0,0 +1,58 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapCore returns a zap core writing through logger, so components logging with zap share the
+// output, format, level and module level overrides of the components using this package.
+func (logger *Logger) ZapCore() zapcore.Core {
+	return &zapCore{logger: logger}
+}
+
+type zapCore struct {
+	logger *Logger
+	fields []zapcore.Field
+}
+
+var _ zapcore.Core = (*zapCore)(nil)
+
+func (c *zapCore) Enabled(level zapcore.Level) bool {
+	// errors are logged regardless of the log level, like Errorf does.
+	return level >= zapcore.ErrorLevel || c.logger.enabled(fromZapLevel(level))
+}
+
+func (c *zapCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &zapCore{logger: c.logger, fields: make([]zapcore.Field, 0, len(c.fields)+len(fields))}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+// Check implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *zapCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *zapCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	c.logger.log(entry, all)
+	return nil
+}
+
+func (c *zapCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package netlink
+
+import (
+	"net"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	vishnetlink "github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// conntrackIPFilter matches the conntrack entries with an IP address in either direction,
+// which includes the entries of connections SNATed or DNATed to or from it.
+type conntrackIPFilter struct {
+	ip net.IP
+}
+
+func (f conntrackIPFilter) MatchConntrackFlow(flow *vishnetlink.ConntrackFlow) bool {
+	return f.ip.Equal(flow.Forward.SrcIP) || f.ip.Equal(flow.Forward.DstIP) ||
+		f.ip.Equal(flow.Reverse.SrcIP) || f.ip.Equal(flow.Reverse.DstIP)
+}
+
+// DeleteConntrackEntries deletes the conntrack entries of the current namespace which have the IP address
+// as source or destination, through ctnetlink, and returns how many it deleted.
+func (Netlink) DeleteConntrackEntries(ip net.IP) (uint, error) {
+	family := vishnetlink.InetFamily(unix.AF_INET6)
+	if ip.To4() != nil {
+		family = vishnetlink.InetFamily(unix.AF_INET)
+	}
+
+	n, err := vishnetlink.ConntrackDeleteFilter(vishnetlink.ConntrackTable, family, conntrackIPFilter{ip: ip})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to delete conntrack entries of %s", ip)
+	}
+	log.Printf("[netlink] Deleted %d conntrack entries of %s", n, ip)
+	return n, nil
+}
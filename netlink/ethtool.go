@@ -0,0 +1,26 @@
+package netlink
+
+// OffloadFeature is an offload of a network interface which ethtool can turn on or off.
+type OffloadFeature int
+
+const (
+	// TCP segmentation offload.
+	OffloadTSO OffloadFeature = iota
+	// Generic segmentation offload.
+	OffloadGSO
+	// Generic receive offload.
+	OffloadGRO
+)
+
+func (f OffloadFeature) String() string {
+	switch f {
+	case OffloadTSO:
+		return "tso"
+	case OffloadGSO:
+		return "gso"
+	case OffloadGRO:
+		return "gro"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,65 @@
+package netlink
+
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Legacy ethtool commands setting a single offload, from linux/ethtool.h.
+var ethtoolSetCmds = map[OffloadFeature]uint32{
+	OffloadTSO: 0x0000001f, // ETHTOOL_STSO
+	OffloadGSO: 0x00000024, // ETHTOOL_SGSO
+	OffloadGRO: 0x0000002c, // ETHTOOL_SGRO
+}
+
+// ethtoolValue is struct ethtool_value.
+type ethtoolValue struct {
+	cmd  uint32
+	data uint32
+}
+
+// ethtoolIfreq is struct ifreq with the ifr_data member of the union.
+type ethtoolIfreq struct {
+	name [unix.IFNAMSIZ]byte
+	data uintptr
+	_    [16]byte
+}
+
+// SetLinkOffload turns an offload of a network interface of the current namespace on or off
+// with the SIOCETHTOOL ioctl.
+func (Netlink) SetLinkOffload(name string, feature OffloadFeature, on bool) error {
+	cmd, ok := ethtoolSetCmds[feature]
+	if !ok {
+		return errors.Errorf("unknown offload feature %d", feature)
+	}
+	if name == "" || len(name) >= unix.IFNAMSIZ {
+		return errors.Errorf("invalid interface name %q", name)
+	}
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return errors.Wrap(err, "failed to open ethtool socket")
+	}
+	defer unix.Close(fd)
+
+	value := ethtoolValue{cmd: cmd}
+	if on {
+		value.data = 1
+	}
+	var ifr ethtoolIfreq
+	copy(ifr.name[:], name)
+	ifr.data = uintptr(unsafe.Pointer(&value))
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	runtime.KeepAlive(&value)
+	if errno != 0 {
+		return errors.Wrapf(errno, "failed to set %s of %s to %t", feature, name, on)
+	}
+
+	log.Printf("[netlink] Set %s of %s to %t", feature, name, on)
+	return nil
+}
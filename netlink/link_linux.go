@@ -53,6 +53,8 @@ type LinkInfo struct {
 	ParentIndex int
 	MacAddress  net.HardwareAddr
 	IPAddr      net.IP
+	// NetNsFd is the fd of the network namespace to create the link in, the current one if 0.
+	NetNsFd uintptr
 }
 
 func (linkInfo *LinkInfo) Info() *LinkInfo {
@@ -125,6 +127,11 @@ func (Netlink) AddLink(link Link) error {
 		req.addPayload(newAttributeUint32(unix.IFLA_LINK, uint32(info.ParentIndex)))
 	}
 
+	// Set the network namespace.
+	if info.NetNsFd != 0 {
+		req.addPayload(newAttributeUint32(IFLA_NET_NS_FD, uint32(info.NetNsFd)))
+	}
+
 	// Set the mac address on the interface
 	if info.MacAddress != nil {
 		req.addPayload(newRtAttr(unix.IFLA_ADDRESS, []byte(info.MacAddress)))
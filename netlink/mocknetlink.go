@@ -16,6 +16,8 @@ func newErrorMockNetlink(errStr string) error {
 type MockNetlink struct {
 	returnError bool
 	errorString string
+	// ConntrackDeletedIPs lists the IP addresses passed to DeleteConntrackEntries.
+	ConntrackDeletedIPs []net.IP
 }
 
 func NewMockNetlink(returnError bool, errorString string) *MockNetlink {
@@ -95,3 +97,12 @@ func (f *MockNetlink) AddIPRoute(*Route) error {
 func (f *MockNetlink) DeleteIPRoute(*Route) error {
 	return f.error()
 }
+
+func (f *MockNetlink) DeleteConntrackEntries(ip net.IP) (uint, error) {
+	f.ConntrackDeletedIPs = append(f.ConntrackDeletedIPs, ip)
+	return 0, f.error()
+}
+
+func (f *MockNetlink) SetLinkOffload(string, OffloadFeature, bool) error {
+	return f.error()
+}
@@ -85,3 +85,11 @@ func (Netlink) AddIPRoute(route *Route) error {
 func (Netlink) DeleteIPRoute(route *Route) error {
 	return nil
 }
+
+func (Netlink) DeleteConntrackEntries(ip net.IP) (uint, error) {
+	return 0, nil
+}
+
+func (Netlink) SetLinkOffload(name string, feature OffloadFeature, on bool) error {
+	return nil
+}
@@ -24,4 +24,6 @@ type NetlinkInterface interface {
 	GetIPRoute(filter *Route) ([]*Route, error)
 	AddIPRoute(route *Route) error
 	DeleteIPRoute(route *Route) error
+	DeleteConntrackEntries(ip net.IP) (uint, error)
+	SetLinkOffload(name string, feature OffloadFeature, on bool) error
 }
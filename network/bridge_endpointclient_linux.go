@@ -5,7 +5,6 @@ import (
 	"net"
 
 	"github.com/Azure/azure-container-networking/ebtables"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -64,6 +63,14 @@ func (client *LinuxBridgeEndpointClient) AddEndpoints(epInfo *EndpointInfo) erro
 		return err
 	}
 
+	if err := setVethMTU(client.netlink, epInfo.MTU, client.hostVethName, client.containerVethName); err != nil {
+		return err
+	}
+
+	if err := setContainerVethMac(client.netlink, epInfo.MacAddress, client.containerVethName); err != nil {
+		return err
+	}
+
 	containerIf, err := net.InterfaceByName(client.containerVethName)
 	if err != nil {
 		return err
@@ -76,28 +83,34 @@ func (client *LinuxBridgeEndpointClient) AddEndpoints(epInfo *EndpointInfo) erro
 func (client *LinuxBridgeEndpointClient) AddEndpointRules(epInfo *EndpointInfo) error {
 	var err error
 
-	log.Printf("[net] Setting link %v master %v.", client.hostVethName, client.bridgeName)
+	logger.Infof("Setting link %v master %v.", client.hostVethName, client.bridgeName)
 	if err := client.netlink.SetLinkMaster(client.hostVethName, client.bridgeName); err != nil {
 		return err
 	}
 
+	// The ebtables rules of the endpoint go into its own chain, which marks them as owned by the endpoint.
+	logger.Infof("Adding ebtables chain %v for endpoint %v", ebtables.EndpointChainName(epInfo.Id), epInfo.Id)
+	if err = ebtables.AddEndpointChain(epInfo.Id); err != nil {
+		return err
+	}
+
 	for _, ipAddr := range epInfo.IPAddresses {
 		if ipAddr.IP.To4() != nil {
 			// Add ARP reply rule.
-			log.Printf("[net] Adding ARP reply rule for IP address %v", ipAddr.String())
-			if err = ebtables.SetArpReply(ipAddr.IP, client.getArpReplyAddress(client.containerMac), ebtables.Append); err != nil {
+			logger.Infof("Adding ARP reply rule for IP address %v", ipAddr.String())
+			if err = ebtables.SetEndpointArpReply(epInfo.Id, ipAddr.IP, client.getArpReplyAddress(client.containerMac), ebtables.Append); err != nil {
 				return err
 			}
 		}
 
 		// Add MAC address translation rule.
-		log.Printf("[net] Adding MAC DNAT rule for IP address %v", ipAddr.String())
-		if err := ebtables.SetDnatForIPAddress(client.hostPrimaryIfName, ipAddr.IP, client.containerMac, ebtables.Append); err != nil {
+		logger.Infof("Adding MAC DNAT rule for IP address %v", ipAddr.String())
+		if err := ebtables.SetEndpointDnatForIPAddress(epInfo.Id, client.hostPrimaryIfName, ipAddr.IP, client.containerMac, ebtables.Append); err != nil {
 			return err
 		}
 
 		if client.mode != opModeTunnel && ipAddr.IP.To4() != nil {
-			log.Printf("[net] Adding static arp for IP address %v and MAC %v in VM", ipAddr.String(), client.containerMac.String())
+			logger.Infof("Adding static arp for IP address %v and MAC %v in VM", ipAddr.String(), client.containerMac.String())
 			linkInfo := netlink.LinkInfo{
 				Name:       client.bridgeName,
 				IPAddr:     ipAddr.IP,
@@ -105,16 +118,16 @@ func (client *LinuxBridgeEndpointClient) AddEndpointRules(epInfo *EndpointInfo)
 			}
 
 			if err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT); err != nil {
-				log.Printf("Failed setting arp in vm: %v", err)
+				logger.Infof("Failed setting arp in vm: %v", err)
 			}
 		}
 	}
 
 	addRuleToRouteViaHost(epInfo)
 
-	log.Printf("[net] Setting hairpin for hostveth %v", client.hostVethName)
+	logger.Infof("Setting hairpin for hostveth %v", client.hostVethName)
 	if err := client.netlink.SetLinkHairpin(client.hostVethName, true); err != nil {
-		log.Printf("Setting up hairpin failed for interface %v error %v", client.hostVethName, err)
+		logger.Infof("Setting up hairpin failed for interface %v error %v", client.hostVethName, err)
 		return err
 	}
 
@@ -122,26 +135,22 @@ func (client *LinuxBridgeEndpointClient) AddEndpointRules(epInfo *EndpointInfo)
 }
 
 func (client *LinuxBridgeEndpointClient) DeleteEndpointRules(ep *endpoint) {
+	// Delete the ebtables chain of the endpoint with its rules.
+	// Endpoints created before endpoint chains were used have their rules in PREROUTING instead.
+	logger.Infof("Deleting ebtables chain %v of endpoint %v.", ebtables.EndpointChainName(ep.Id), ep.Id)
+	deletedChain, err := ebtables.DeleteEndpointChain(ep.Id)
+	if err != nil {
+		logger.Infof("Failed to delete ebtables chain of endpoint %v: %v.", ep.Id, err)
+	}
+
 	// Delete rules for IP addresses on the container interface.
 	for _, ipAddr := range ep.IPAddresses {
-		if ipAddr.IP.To4() != nil {
-			// Delete ARP reply rule.
-			log.Printf("[net] Deleting ARP reply rule for IP address %v on %v.", ipAddr.String(), ep.Id)
-			err := ebtables.SetArpReply(ipAddr.IP, client.getArpReplyAddress(ep.MacAddress), ebtables.Delete)
-			if err != nil {
-				log.Printf("[net] Failed to delete ARP reply rule for IP address %v: %v.", ipAddr.String(), err)
-			}
-		}
-
-		// Delete MAC address translation rule.
-		log.Printf("[net] Deleting MAC DNAT rule for IP address %v on %v.", ipAddr.String(), ep.Id)
-		err := ebtables.SetDnatForIPAddress(client.hostPrimaryIfName, ipAddr.IP, ep.MacAddress, ebtables.Delete)
-		if err != nil {
-			log.Printf("[net] Failed to delete MAC DNAT rule for IP address %v: %v.", ipAddr.String(), err)
+		if !deletedChain {
+			client.deleteLegacyEndpointRules(ep, ipAddr)
 		}
 
 		if client.mode != opModeTunnel && ipAddr.IP.To4() != nil {
-			log.Printf("[net] Removing static arp for IP address %v and MAC %v from VM", ipAddr.String(), ep.MacAddress.String())
+			logger.Infof("Removing static arp for IP address %v and MAC %v from VM", ipAddr.String(), ep.MacAddress.String())
 			linkInfo := netlink.LinkInfo{
 				Name:       client.bridgeName,
 				IPAddr:     ipAddr.IP,
@@ -149,12 +158,31 @@ func (client *LinuxBridgeEndpointClient) DeleteEndpointRules(ep *endpoint) {
 			}
 			err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.REMOVE, netlink.NUD_INCOMPLETE)
 			if err != nil {
-				log.Printf("Failed removing arp from vm: %v", err)
+				logger.Infof("Failed removing arp from vm: %v", err)
 			}
 		}
 	}
 }
 
+// deleteLegacyEndpointRules deletes the ebtables rules of an endpoint which has no endpoint chain.
+func (client *LinuxBridgeEndpointClient) deleteLegacyEndpointRules(ep *endpoint, ipAddr net.IPNet) {
+	if ipAddr.IP.To4() != nil {
+		// Delete ARP reply rule.
+		logger.Infof("Deleting ARP reply rule for IP address %v on %v.", ipAddr.String(), ep.Id)
+		err := ebtables.SetArpReply(ipAddr.IP, client.getArpReplyAddress(ep.MacAddress), ebtables.Delete)
+		if err != nil {
+			logger.Infof("Failed to delete ARP reply rule for IP address %v: %v.", ipAddr.String(), err)
+		}
+	}
+
+	// Delete MAC address translation rule.
+	logger.Infof("Deleting MAC DNAT rule for IP address %v on %v.", ipAddr.String(), ep.Id)
+	err := ebtables.SetDnatForIPAddress(client.hostPrimaryIfName, ipAddr.IP, ep.MacAddress, ebtables.Delete)
+	if err != nil {
+		logger.Infof("Failed to delete MAC DNAT rule for IP address %v: %v.", ipAddr.String(), err)
+	}
+}
+
 // getArpReplyAddress returns the MAC address to use in ARP replies.
 func (client *LinuxBridgeEndpointClient) getArpReplyAddress(epMacAddress net.HardwareAddr) net.HardwareAddr {
 	var macAddress net.HardwareAddr
@@ -172,7 +200,7 @@ func (client *LinuxBridgeEndpointClient) getArpReplyAddress(epMacAddress net.Har
 
 func (client *LinuxBridgeEndpointClient) MoveEndpointsToContainerNS(epInfo *EndpointInfo, nsID uintptr) error {
 	// Move the container interface to container's network namespace.
-	log.Printf("[net] Setting link %v netns %v.", client.containerVethName, epInfo.NetNsPath)
+	logger.Infof("Setting link %v netns %v.", client.containerVethName, epInfo.NetNsPath)
 	if err := client.netlink.SetLinkNetNs(client.containerVethName, nsID); err != nil {
 		return newErrorLinuxBridgeClient(err.Error())
 	}
@@ -211,13 +239,14 @@ func (client *LinuxBridgeEndpointClient) ConfigureContainerInterfacesAndRoutes(e
 }
 
 func (client *LinuxBridgeEndpointClient) DeleteEndpoints(ep *endpoint) error {
-	log.Printf("[net] Deleting veth pair %v %v.", ep.HostIfName, ep.IfName)
+	logger.Infof("Deleting veth pair %v %v.", ep.HostIfName, ep.IfName)
 	err := client.netlink.DeleteLink(ep.HostIfName)
 	if err != nil {
-		log.Printf("[net] Failed to delete veth pair %v: %v.", ep.HostIfName, err)
+		logger.Infof("Failed to delete veth pair %v: %v.", ep.HostIfName, err)
 		return err
 	}
 
+	deleteConntrackEntries(client.netlink, ep.IPAddresses)
 	return nil
 }
 
@@ -228,21 +257,21 @@ func addRuleToRouteViaHost(epInfo *EndpointInfo) error {
 		rule := fmt.Sprintf("-p IPv4 --ip-dst %s -j redirect", ipAddr)
 
 		// Check if EB rule exists
-		log.Printf("[net] Checking if EB rule %s already exists in table %s chain %s", rule, tableName, chainName)
+		logger.Infof("Checking if EB rule %s already exists in table %s chain %s", rule, tableName, chainName)
 		exists, err := ebtables.EbTableRuleExists(tableName, chainName, rule)
 		if err != nil {
-			log.Printf("[net] Failed to check if EB table rule exists: %v", err)
+			logger.Infof("Failed to check if EB table rule exists: %v", err)
 			return err
 		}
 
 		if exists {
 			// EB rule already exists.
-			log.Printf("[net] EB rule %s already exists in table %s chain %s.", rule, tableName, chainName)
+			logger.Infof("EB rule %s already exists in table %s chain %s.", rule, tableName, chainName)
 		} else {
 			// Add EB rule to route via host.
-			log.Printf("[net] Adding EB rule to route via host for IP address %v", ipAddr)
+			logger.Infof("Adding EB rule to route via host for IP address %v", ipAddr)
 			if err := ebtables.SetBrouteAccept(ipAddr, ebtables.Append); err != nil {
-				log.Printf("[net] Failed to add EB rule to route via host: %v", err)
+				logger.Infof("Failed to add EB rule to route via host: %v", err)
 				return err
 			}
 		}
@@ -287,7 +316,7 @@ func (client *LinuxBridgeEndpointClient) setupIPV6Routes(epInfo *EndpointInfo) e
 		routes = append(routes, vmV6Route)
 		routes = append(routes, defaultV6Route)
 
-		log.Printf("[net] Adding ipv6 routes in container %+v", routes)
+		logger.Infof("Adding ipv6 routes in container %+v", routes)
 		if err := addRoutes(client.netlink, client.netioshim, client.containerVethName, routes); err != nil {
 			return nil
 		}
@@ -298,7 +327,7 @@ func (client *LinuxBridgeEndpointClient) setupIPV6Routes(epInfo *EndpointInfo) e
 
 func (client *LinuxBridgeEndpointClient) setIPV6NeighEntry(epInfo *EndpointInfo) error {
 	if epInfo.IPV6Mode != "" {
-		log.Printf("[net] Add neigh entry for host gw ip")
+		logger.Infof("Add neigh entry for host gw ip")
 		hardwareAddr, _ := net.ParseMAC(defaultHostGwMac)
 		hostGwIp := net.ParseIP(defaultV6HostGw)
 		linkInfo := netlink.LinkInfo{
@@ -307,7 +336,7 @@ func (client *LinuxBridgeEndpointClient) setIPV6NeighEntry(epInfo *EndpointInfo)
 			MacAddress: hardwareAddr,
 		}
 		if err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT); err != nil {
-			log.Printf("Failed setting neigh entry in container: %v", err)
+			logger.Infof("Failed setting neigh entry in container: %v", err)
 			return err
 		}
 	}
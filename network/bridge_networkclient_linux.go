@@ -6,7 +6,6 @@ import (
 	"net"
 
 	"github.com/Azure/azure-container-networking/ebtables"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
 	"github.com/Azure/azure-container-networking/platform"
@@ -49,7 +48,7 @@ func NewLinuxBridgeClient(
 }
 
 func (client *LinuxBridgeClient) CreateBridge() error {
-	log.Printf("[net] Creating bridge %v.", client.bridgeName)
+	logger.Infof("Creating bridge %v.", client.bridgeName)
 
 	link := netlink.BridgeLink{
 		LinkInfo: netlink.LinkInfo{
@@ -73,13 +72,13 @@ func (client *LinuxBridgeClient) DeleteBridge() error {
 	// Disconnect external interface from its bridge.
 	err := client.netlink.SetLinkMaster(client.hostInterfaceName, "")
 	if err != nil {
-		log.Printf("[net] Failed to disconnect interface %v from bridge, err:%v.", client.hostInterfaceName, err)
+		logger.Infof("Failed to disconnect interface %v from bridge, err:%v.", client.hostInterfaceName, err)
 	}
 
 	// Delete the bridge.
 	err = client.netlink.DeleteLink(client.bridgeName)
 	if err != nil {
-		log.Printf("[net] Failed to delete bridge %v, err:%v.", client.bridgeName, err)
+		logger.Infof("Failed to delete bridge %v, err:%v.", client.bridgeName, err)
 	}
 
 	return nil
@@ -92,7 +91,7 @@ func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
 	}
 
 	// Add SNAT rule to translate container egress traffic.
-	log.Printf("[net] Adding SNAT rule for egress traffic on %v.", client.hostInterfaceName)
+	logger.Infof("Adding SNAT rule for egress traffic on %v.", client.hostInterfaceName)
 	if err := ebtables.SetSnatForInterface(client.hostInterfaceName, hostIf.HardwareAddr, ebtables.Append); err != nil {
 		return err
 	}
@@ -101,13 +100,13 @@ func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
 	// ARP requests for all IP addresses are forwarded to the SDN fabric, but fabric
 	// doesn't respond to ARP requests from the VM for its own primary IP address.
 	primary := extIf.IPAddresses[0].IP
-	log.Printf("[net] Adding ARP reply rule for primary IP address %v.", primary)
+	logger.Infof("Adding ARP reply rule for primary IP address %v.", primary)
 	if err := ebtables.SetArpReply(primary, hostIf.HardwareAddr, ebtables.Append); err != nil {
 		return err
 	}
 
 	// Add DNAT rule to forward ARP replies to container interfaces.
-	log.Printf("[net] Adding DNAT rule for ingress ARP traffic on interface %v.", client.hostInterfaceName)
+	logger.Infof("Adding DNAT rule for ingress ARP traffic on interface %v.", client.hostInterfaceName)
 	if err := ebtables.SetDnatForArpReplies(client.hostInterfaceName, ebtables.Append); err != nil {
 		return err
 	}
@@ -138,7 +137,7 @@ func (client *LinuxBridgeClient) AddL2Rules(extIf *externalInterface) error {
 
 	// Enable VEPA for host policy enforcement if necessary.
 	if client.nwInfo.Mode == opModeTunnel {
-		log.Printf("[net] Enabling VEPA mode for %v.", client.hostInterfaceName)
+		logger.Infof("Enabling VEPA mode for %v.", client.hostInterfaceName)
 		if err := ebtables.SetVepaMode(client.bridgeName, commonInterfacePrefix, virtualMacAddress, ebtables.Append); err != nil {
 			return err
 		}
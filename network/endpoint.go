@@ -9,7 +9,6 @@ import (
 	"net"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/policy"
 	"github.com/Azure/azure-container-networking/platform"
@@ -82,6 +81,7 @@ type EndpointInfo struct {
 	VnetCidrs                string
 	ServiceCidrs             string
 	NATInfo                  []policy.NATInfo
+	MTU                      int
 }
 
 // RouteInfo contains information about an IP route.
@@ -114,7 +114,7 @@ func (nw *network) newEndpoint(cli apipaClient, nl netlink.NetlinkInterface, plc
 
 	defer func() {
 		if err != nil {
-			log.Printf("[net] Failed to create endpoint %v, err:%v.", epInfo.Id, err)
+			logger.Infof("Failed to create endpoint %v, err:%v.", epInfo.Id, err)
 		}
 	}()
 
@@ -125,7 +125,7 @@ func (nw *network) newEndpoint(cli apipaClient, nl netlink.NetlinkInterface, plc
 	}
 
 	nw.Endpoints[epInfo.Id] = ep
-	log.Printf("[net] Created endpoint %+v.", ep)
+	logger.Infof("Created endpoint %+v.", ep)
 
 	return ep, nil
 }
@@ -134,17 +134,17 @@ func (nw *network) newEndpoint(cli apipaClient, nl netlink.NetlinkInterface, plc
 func (nw *network) deleteEndpoint(nl netlink.NetlinkInterface, plc platform.ExecClient, endpointID string) error {
 	var err error
 
-	log.Printf("[net] Deleting endpoint %v from network %v.", endpointID, nw.Id)
+	logger.Infof("Deleting endpoint %v from network %v.", endpointID, nw.Id)
 	defer func() {
 		if err != nil {
-			log.Printf("[net] Failed to delete endpoint %v, err:%v.", endpointID, err)
+			logger.Infof("Failed to delete endpoint %v, err:%v.", endpointID, err)
 		}
 	}()
 
 	// Look up the endpoint.
 	ep, err := nw.getEndpoint(endpointID)
 	if err != nil {
-		log.Printf("[net] Endpoint %v not found. Not Returning error", endpointID)
+		logger.Infof("Endpoint %v not found. Not Returning error", endpointID)
 		return nil
 	}
 
@@ -157,7 +157,7 @@ func (nw *network) deleteEndpoint(nl netlink.NetlinkInterface, plc platform.Exec
 	// Remove the endpoint object.
 	delete(nw.Endpoints, endpointID)
 
-	log.Printf("[net] Deleted endpoint %+v.", ep)
+	logger.Infof("Deleted endpoint %+v.", ep)
 
 	return nil
 }
@@ -175,7 +175,7 @@ func (nw *network) getEndpoint(endpointId string) (*endpoint, error) {
 
 // GetEndpointByPOD returns the endpoint with the given ID.
 func (nw *network) getEndpointByPOD(podName string, podNameSpace string, doExactMatchForPodName bool) (*endpoint, error) {
-	log.Printf("Trying to retrieve endpoint for pod name: %v in namespace: %v", podName, podNameSpace)
+	logger.Infof("Trying to retrieve endpoint for pod name: %v in namespace: %v", podName, podNameSpace)
 
 	var ep *endpoint
 
@@ -252,7 +252,7 @@ func (ep *endpoint) attach(sandboxKey string) error {
 
 	ep.SandboxKey = sandboxKey
 
-	log.Printf("[net] Attached endpoint %v to sandbox %v.", ep.Id, sandboxKey)
+	logger.Infof("Attached endpoint %v to sandbox %v.", ep.Id, sandboxKey)
 
 	return nil
 }
@@ -263,7 +263,7 @@ func (ep *endpoint) detach() error {
 		return errEndpointNotInUse
 	}
 
-	log.Printf("[net] Detached endpoint %v from sandbox %v.", ep.Id, ep.SandboxKey)
+	logger.Infof("Detached endpoint %v from sandbox %v.", ep.Id, ep.SandboxKey)
 
 	ep.SandboxKey = ""
 
@@ -274,21 +274,21 @@ func (ep *endpoint) detach() error {
 func (nm *networkManager) updateEndpoint(nw *network, exsitingEpInfo *EndpointInfo, targetEpInfo *EndpointInfo) error {
 	var err error
 
-	log.Printf("[net] Updating existing endpoint [%+v] in network %v to target [%+v].", exsitingEpInfo, nw.Id, targetEpInfo)
+	logger.Infof("Updating existing endpoint [%+v] in network %v to target [%+v].", exsitingEpInfo, nw.Id, targetEpInfo)
 	defer func() {
 		if err != nil {
-			log.Printf("[net] Failed to update endpoint %v, err:%v.", exsitingEpInfo.Id, err)
+			logger.Infof("Failed to update endpoint %v, err:%v.", exsitingEpInfo.Id, err)
 		}
 	}()
 
-	log.Printf("Trying to retrieve endpoint id %v", exsitingEpInfo.Id)
+	logger.Infof("Trying to retrieve endpoint id %v", exsitingEpInfo.Id)
 
 	ep := nw.Endpoints[exsitingEpInfo.Id]
 	if ep == nil {
 		return errEndpointNotFound
 	}
 
-	log.Printf("[net] Retrieved endpoint to update %+v.", ep)
+	logger.Infof("Retrieved endpoint to update %+v.", ep)
 
 	// Call the platform implementation.
 	ep, err = nm.updateEndpointImpl(nw, exsitingEpInfo, targetEpInfo)
@@ -304,13 +304,13 @@ func (nm *networkManager) updateEndpoint(nw *network, exsitingEpInfo *EndpointIn
 
 func GetPodNameWithoutSuffix(podName string) string {
 	nameSplit := strings.Split(podName, "-")
-	log.Printf("namesplit %v", nameSplit)
+	logger.Infof("namesplit %v", nameSplit)
 	if len(nameSplit) > 2 {
 		nameSplit = nameSplit[:len(nameSplit)-2]
 	} else {
 		return podName
 	}
 
-	log.Printf("Pod name after splitting based on - : %v", nameSplit)
+	logger.Infof("Pod name after splitting based on - : %v", nameSplit)
 	return strings.Join(nameSplit, "-")
 }
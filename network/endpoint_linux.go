@@ -10,7 +10,6 @@ import (
 	"net"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -38,7 +37,7 @@ func ConstructEndpointID(containerID string, _ string, ifName string) (string, s
 	if len(containerID) > 8 {
 		containerID = containerID[:8]
 	} else {
-		log.Printf("Container ID is not greater than 8 ID: %v", containerID)
+		logger.Infof("Container ID is not greater than 8 ID: %v", containerID)
 		return "", ""
 	}
 
@@ -60,11 +59,15 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 	var vlanid int = 0
 
 	if nw.Endpoints[epInfo.Id] != nil {
-		log.Printf("[net] Endpoint alreday exists.")
+		logger.Infof("Endpoint alreday exists.")
 		err = errEndpointExists
 		return nil, err
 	}
 
+	if epInfo.MTU == 0 {
+		epInfo.MTU = nw.MTU
+	}
+
 	if epInfo.Data != nil {
 		if _, ok := epInfo.Data[VlanIDKey]; ok {
 			vlanid = epInfo.Data[VlanIDKey].(int)
@@ -77,26 +80,26 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 
 	if _, ok := epInfo.Data[OptVethName]; ok {
 		key := epInfo.Data[OptVethName].(string)
-		log.Printf("Generate veth name based on the key provided %v", key)
+		logger.Infof("Generate veth name based on the key provided %v", key)
 		vethname := generateVethName(key)
 		hostIfName = fmt.Sprintf("%s%s", hostVEthInterfacePrefix, vethname)
 		contIfName = fmt.Sprintf("%s%s2", hostVEthInterfacePrefix, vethname)
 	} else {
 		// Create a veth pair.
-		log.Printf("Generate veth name based on endpoint id")
+		logger.Infof("Generate veth name based on endpoint id")
 		hostIfName = fmt.Sprintf("%s%s", hostVEthInterfacePrefix, epInfo.Id[:7])
 		contIfName = fmt.Sprintf("%s%s-2", hostVEthInterfacePrefix, epInfo.Id[:7])
 	}
 
 	if vlanid != 0 {
 		if nw.Mode == opModeTransparentVlan {
-			log.Printf("Transparent vlan client")
+			logger.Infof("Transparent vlan client")
 			if _, ok := epInfo.Data[SnatBridgeIPKey]; ok {
 				nw.SnatBridgeIP = epInfo.Data[SnatBridgeIPKey].(string)
 			}
 			epClient = NewTransparentVlanEndpointClient(nw, epInfo, hostIfName, contIfName, vlanid, localIP, nl, plc)
 		} else {
-			log.Printf("OVS client")
+			logger.Infof("OVS client")
 			if _, ok := epInfo.Data[SnatBridgeIPKey]; ok {
 				nw.SnatBridgeIP = epInfo.Data[SnatBridgeIPKey].(string)
 			}
@@ -109,21 +112,24 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 				vlanid,
 				localIP,
 				nl,
-				ovsctl.NewOvsctl(),
+				ovsctl.New(),
 				plc)
 		}
+	} else if isIPVlanMode(nw.Mode) {
+		logger.Infof("IPVlan client")
+		epClient = NewIPVlanEndpointClient(nw.extIf, contIfName, nw.Mode, nl, plc)
 	} else if nw.Mode != opModeTransparent {
-		log.Printf("Bridge client")
+		logger.Infof("Bridge client")
 		epClient = NewLinuxBridgeEndpointClient(nw.extIf, hostIfName, contIfName, nw.Mode, nl, plc)
 	} else {
-		log.Printf("Transparent client")
+		logger.Infof("Transparent client")
 		epClient = NewTransparentEndpointClient(nw.extIf, hostIfName, contIfName, nw.Mode, nl, plc)
 	}
 
 	// Cleanup on failure.
 	defer func() {
 		if err != nil {
-			log.Printf("CNI error. Delete Endpoint %v and rules that are created.", contIfName)
+			logger.Infof("CNI error. Delete Endpoint %v and rules that are created.", contIfName)
 			endpt := &endpoint{
 				Id:                       epInfo.Id,
 				IfName:                   contIfName,
@@ -152,7 +158,12 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 		return nil, err
 	}
 
-	containerIf, err = net.InterfaceByName(contIfName)
+	// an ipvlan slave is created in the container netns and shares the MAC address of the master
+	if isIPVlanMode(nw.Mode) {
+		containerIf, err = net.InterfaceByName(nw.extIf.Name)
+	} else {
+		containerIf, err = net.InterfaceByName(contIfName)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +176,7 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 	// If a network namespace for the container interface is specified...
 	if epInfo.NetNsPath != "" {
 		// Open the network namespace.
-		log.Printf("[net] Opening netns %v.", epInfo.NetNsPath)
+		logger.Infof("Opening netns %v.", epInfo.NetNsPath)
 		ns, err = OpenNamespace(epInfo.NetNsPath)
 		if err != nil {
 			return nil, err
@@ -177,23 +188,23 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 		}
 
 		// Enter the container network namespace.
-		log.Printf("[net] Entering netns %v.", epInfo.NetNsPath)
+		logger.Infof("Entering netns %v.", epInfo.NetNsPath)
 		if err = ns.Enter(); err != nil {
 			return nil, err
 		}
 
 		// Return to host network namespace.
 		defer func() {
-			log.Printf("[net] Exiting netns %v.", epInfo.NetNsPath)
+			logger.Infof("Exiting netns %v.", epInfo.NetNsPath)
 			if err := ns.Exit(); err != nil {
-				log.Printf("[net] Failed to exit netns, err:%v.", err)
+				logger.Infof("Failed to exit netns, err:%v.", err)
 			}
 		}()
 	}
 
 	if epInfo.IPV6Mode != "" {
 		// Enable ipv6 setting in container
-		log.Printf("Enable ipv6 setting in container.")
+		logger.Infof("Enable ipv6 setting in container.")
 		nuc := networkutils.NewNetworkUtils(nl, plc)
 		if err = nuc.UpdateIPV6Setting(0); err != nil {
 			return nil, fmt.Errorf("Enable ipv6 in container failed:%w", err)
@@ -211,6 +222,14 @@ func (nw *network) newEndpointImpl(_ apipaClient, nl netlink.NetlinkInterface, p
 		return nil, err
 	}
 
+	containerIfName := contIfName
+	if epInfo.IfName != "" {
+		containerIfName = epInfo.IfName
+	}
+	if err = setLinkOffloads(nl, containerIfName, nw.Offload); err != nil {
+		return nil, err
+	}
+
 	// Create the endpoint object.
 	ep = &endpoint{
 		Id:                       epInfo.Id,
@@ -248,12 +267,14 @@ func (nw *network) deleteEndpointImpl(nl netlink.NetlinkInterface, plc platform.
 	if ep.VlanID != 0 {
 		epInfo := ep.getInfo()
 		if nw.Mode == opModeTransparentVlan {
-			log.Printf("Transparent vlan client")
+			logger.Infof("Transparent vlan client")
 			epClient = NewTransparentVlanEndpointClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, nl, plc)
 
 		} else {
-			epClient = NewOVSEndpointClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, nl, ovsctl.NewOvsctl(), plc)
+			epClient = NewOVSEndpointClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, nl, ovsctl.New(), plc)
 		}
+	} else if isIPVlanMode(nw.Mode) {
+		epClient = NewIPVlanEndpointClient(nw.extIf, "", nw.Mode, nl, plc)
 	} else if nw.Mode != opModeTransparent {
 		epClient = NewLinuxBridgeEndpointClient(nw.extIf, ep.HostIfName, "", nw.Mode, nl, plc)
 	} else {
@@ -274,7 +295,7 @@ func addRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, inte
 	ifIndex := 0
 
 	for _, route := range routes {
-		log.Printf("[net] Adding IP route %+v to link %v.", route, interfaceName)
+		logger.Infof("Adding IP route %+v to link %v.", route, interfaceName)
 
 		if route.DevName != "" {
 			devIf, _ := netioshim.GetNetworkInterfaceByName(route.DevName)
@@ -282,7 +303,7 @@ func addRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, inte
 		} else {
 			interfaceIf, err := netioshim.GetNetworkInterfaceByName(interfaceName)
 			if err != nil {
-				log.Errorf("Interface not found:%v", err)
+				logger.Errorf("Interface not found:%v", err)
 				return fmt.Errorf("addRoutes failed: %w", err)
 			}
 			ifIndex = interfaceIf.Index
@@ -308,7 +329,7 @@ func addRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, inte
 			if !strings.Contains(strings.ToLower(err.Error()), "file exists") {
 				return err
 			} else {
-				log.Printf("[net] route already exists")
+				logger.Infof("route already exists")
 			}
 		}
 	}
@@ -320,12 +341,12 @@ func deleteRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, i
 	ifIndex := 0
 
 	for _, route := range routes {
-		log.Printf("[net] Deleting IP route %+v from link %v.", route, interfaceName)
+		logger.Infof("Deleting IP route %+v from link %v.", route, interfaceName)
 
 		if route.DevName != "" {
 			devIf, _ := netioshim.GetNetworkInterfaceByName(route.DevName)
 			if devIf == nil {
-				log.Printf("[net] Not deleting route. Interface %v doesn't exist", interfaceName)
+				logger.Infof("Not deleting route. Interface %v doesn't exist", interfaceName)
 				continue
 			}
 
@@ -333,7 +354,7 @@ func deleteRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, i
 		} else {
 			interfaceIf, _ := netioshim.GetNetworkInterfaceByName(interfaceName)
 			if interfaceIf == nil {
-				log.Printf("[net] Not deleting route. Interface %v doesn't exist", interfaceName)
+				logger.Infof("Not deleting route. Interface %v doesn't exist", interfaceName)
 				continue
 			}
 
@@ -361,6 +382,64 @@ func deleteRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, i
 	return nil
 }
 
+// setVethMTU sets the MTU of both ends of a veth pair, unless the endpoint keeps the default one.
+func setVethMTU(nl netlink.NetlinkInterface, mtu int, hostVethName, containerVethName string) error {
+	if mtu <= 0 {
+		return nil
+	}
+
+	for _, name := range []string{hostVethName, containerVethName} {
+		logger.Infof("Setting link %v mtu %d.", name, mtu)
+		if err := nl.SetLinkMTU(name, mtu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setContainerVethMac sets the MAC address of the container end of a veth pair, unless the endpoint keeps a random one.
+func setContainerVethMac(nl netlink.NetlinkInterface, mac net.HardwareAddr, containerVethName string) error {
+	if len(mac) == 0 {
+		return nil
+	}
+
+	logger.Infof("Setting link %v address %v.", containerVethName, mac)
+	return nl.SetLinkAddress(containerVethName, mac)
+}
+
+// setLinkOffloads applies the offload settings of a network to a link of the current namespace.
+func setLinkOffloads(nl netlink.NetlinkInterface, ifName string, settings OffloadSettings) error {
+	offloads := []struct {
+		feature netlink.OffloadFeature
+		on      *bool
+	}{
+		{netlink.OffloadTSO, settings.TSO},
+		{netlink.OffloadGSO, settings.GSO},
+		{netlink.OffloadGRO, settings.GRO},
+	}
+
+	for _, offload := range offloads {
+		if offload.on == nil {
+			continue
+		}
+		if err := nl.SetLinkOffload(ifName, offload.feature, *offload.on); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteConntrackEntries flushes the conntrack entries of the endpoint IP addresses so that connections
+// of a deleted endpoint, including the SNAT ones, do not steer the traffic of a new endpoint reusing its IPs.
+// It is best effort, a failure only leaves stale entries which expire on their own.
+func deleteConntrackEntries(nl netlink.NetlinkInterface, ipAddresses []net.IPNet) {
+	for _, ipAddr := range ipAddresses {
+		if _, err := nl.DeleteConntrackEntries(ipAddr.IP); err != nil {
+			logger.Infof("Failed to delete conntrack entries of %v: %v", ipAddr.IP, err)
+		}
+	}
+}
+
 // updateEndpointImpl updates an existing endpoint in the network.
 func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *EndpointInfo, targetEpInfo *EndpointInfo) (*endpoint, error) {
 	var ns *Namespace
@@ -368,9 +447,9 @@ func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *Endpoi
 	var err error
 
 	existingEpFromRepository := nw.Endpoints[existingEpInfo.Id]
-	log.Printf("[updateEndpointImpl] Going to retrieve endpoint with Id %+v to update.", existingEpInfo.Id)
+	logger.Infof("[updateEndpointImpl] Going to retrieve endpoint with Id %+v to update.", existingEpInfo.Id)
 	if existingEpFromRepository == nil {
-		log.Printf("[updateEndpointImpl] Endpoint cannot be updated as it does not exist.")
+		logger.Infof("[updateEndpointImpl] Endpoint cannot be updated as it does not exist.")
 		err = errEndpointNotFound
 		return nil, err
 	}
@@ -379,7 +458,7 @@ func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *Endpoi
 	// Network namespace for the container interface has to be specified
 	if netns != "" {
 		// Open the network namespace.
-		log.Printf("[updateEndpointImpl] Opening netns %v.", netns)
+		logger.Infof("[updateEndpointImpl] Opening netns %v.", netns)
 		ns, err = OpenNamespace(netns)
 		if err != nil {
 			return nil, err
@@ -387,25 +466,25 @@ func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *Endpoi
 		defer ns.Close()
 
 		// Enter the container network namespace.
-		log.Printf("[updateEndpointImpl] Entering netns %v.", netns)
+		logger.Infof("[updateEndpointImpl] Entering netns %v.", netns)
 		if err = ns.Enter(); err != nil {
 			return nil, err
 		}
 
 		// Return to host network namespace.
 		defer func() {
-			log.Printf("[updateEndpointImpl] Exiting netns %v.", netns)
+			logger.Infof("[updateEndpointImpl] Exiting netns %v.", netns)
 			if err := ns.Exit(); err != nil {
-				log.Printf("[updateEndpointImpl] Failed to exit netns, err:%v.", err)
+				logger.Infof("[updateEndpointImpl] Failed to exit netns, err:%v.", err)
 			}
 		}()
 	} else {
-		log.Printf("[updateEndpointImpl] Endpoint cannot be updated as the network namespace does not exist: Epid: %v", existingEpInfo.Id)
+		logger.Infof("[updateEndpointImpl] Endpoint cannot be updated as the network namespace does not exist: Epid: %v", existingEpInfo.Id)
 		err = errNamespaceNotFound
 		return nil, err
 	}
 
-	log.Printf("[updateEndpointImpl] Going to update routes in netns %v.", netns)
+	logger.Infof("[updateEndpointImpl] Going to update routes in netns %v.", netns)
 	if err = nm.updateRoutes(existingEpInfo, targetEpInfo); err != nil {
 		return nil, err
 	}
@@ -422,8 +501,8 @@ func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *Endpoi
 }
 
 func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *EndpointInfo) error {
-	log.Printf("Updating routes for the endpoint %+v.", existingEp)
-	log.Printf("Target endpoint is %+v", targetEp)
+	logger.Infof("Updating routes for the endpoint %+v.", existingEp)
+	logger.Infof("Target endpoint is %+v", targetEp)
 
 	existingRoutes := make(map[string]RouteInfo)
 	targetRoutes := make(map[string]RouteInfo)
@@ -434,8 +513,8 @@ func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *Endpo
 	// we do not support enable/disable snat for now
 	defaultDst := net.ParseIP("0.0.0.0")
 
-	log.Printf("Going to collect routes and skip default and infravnet routes if applicable.")
-	log.Printf("Key for default route: %+v", defaultDst.String())
+	logger.Infof("Going to collect routes and skip default and infravnet routes if applicable.")
+	logger.Infof("Key for default route: %+v", defaultDst.String())
 
 	infraVnetKey := ""
 	if targetEp.EnableInfraVnet {
@@ -445,15 +524,15 @@ func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *Endpo
 		}
 	}
 
-	log.Printf("Key for route to infra vnet: %+v", infraVnetKey)
+	logger.Infof("Key for route to infra vnet: %+v", infraVnetKey)
 	for _, route := range existingEp.Routes {
 		destination := route.Dst.IP.String()
-		log.Printf("Checking destination as %+v to skip or not", destination)
+		logger.Infof("Checking destination as %+v to skip or not", destination)
 		isDefaultRoute := destination == defaultDst.String()
 		isInfraVnetRoute := targetEp.EnableInfraVnet && (destination == infraVnetKey)
 		if !isDefaultRoute && !isInfraVnetRoute {
 			existingRoutes[route.Dst.String()] = route
-			log.Printf("%+v was skipped", destination)
+			logger.Infof("%+v was skipped", destination)
 		}
 	}
 
@@ -465,7 +544,7 @@ func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *Endpo
 		dst := existingRoute.Dst.String()
 		if _, ok := targetRoutes[dst]; !ok {
 			tobeDeletedRoutes = append(tobeDeletedRoutes, existingRoute)
-			log.Printf("Adding following route to the tobeDeleted list: %+v", existingRoute)
+			logger.Infof("Adding following route to the tobeDeleted list: %+v", existingRoute)
 		}
 	}
 
@@ -473,7 +552,7 @@ func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *Endpo
 		dst := targetRoute.Dst.String()
 		if _, ok := existingRoutes[dst]; !ok {
 			tobeAddedRoutes = append(tobeAddedRoutes, targetRoute)
-			log.Printf("Adding following route to the tobeAdded list: %+v", targetRoute)
+			logger.Infof("Adding following route to the tobeAdded list: %+v", targetRoute)
 		}
 
 	}
@@ -488,7 +567,7 @@ func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *Endpo
 		return err
 	}
 
-	log.Printf("Successfully updated routes for the endpoint %+v using target: %+v", existingEp, targetEp)
+	logger.Infof("Successfully updated routes for the endpoint %+v using target: %+v", existingEp, targetEp)
 
 	return nil
 }
@@ -3,7 +3,6 @@ package network
 import (
 	"fmt"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
 	"github.com/Azure/azure-container-networking/network/snat"
@@ -87,14 +86,14 @@ func DeleteSnatEndpointRules(snatClient *snat.Client, hostToNC, ncToHost bool) {
 	if hostToNC {
 		err := snatClient.DeleteInboundFromHostToNC()
 		if err != nil {
-			log.Errorf("failed to delete inbound from host to nc rules")
+			logger.Errorf("failed to delete inbound from host to nc rules")
 		}
 	}
 
 	if ncToHost {
 		err := snatClient.DeleteInboundFromNCToHost()
 		if err != nil {
-			log.Errorf("failed to delete inbound from nc to host rules")
+			logger.Errorf("failed to delete inbound from nc to host rules")
 		}
 	}
 }
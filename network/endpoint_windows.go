@@ -9,7 +9,6 @@ import (
 	"net"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/policy"
 	"github.com/Azure/azure-container-networking/platform"
@@ -120,21 +119,21 @@ func (nw *network) newEndpointImplHnsV1(epInfo *EndpointInfo) (*endpoint, error)
 
 	defer func() {
 		if err != nil {
-			log.Printf("[net] HNSEndpointRequest DELETE id:%v", hnsResponse.Id)
+			logger.Infof("HNSEndpointRequest DELETE id:%v", hnsResponse.Id)
 			hnsResponse, err := Hnsv1.DeleteEndpoint(hnsResponse.Id)
-			log.Printf("[net] HNSEndpointRequest DELETE response:%+v err:%v.", hnsResponse, err)
+			logger.Infof("HNSEndpointRequest DELETE response:%+v err:%v.", hnsResponse, err)
 		}
 	}()
 
 	if epInfo.SkipHotAttachEp {
-		log.Printf("[net] Skipping attaching the endpoint %v to container %v.",
+		logger.Infof("Skipping attaching the endpoint %v to container %v.",
 			hnsResponse.Id, epInfo.ContainerID)
 	} else {
 		// Attach the endpoint.
-		log.Printf("[net] Attaching endpoint %v to container %v.", hnsResponse.Id, epInfo.ContainerID)
+		logger.Infof("Attaching endpoint %v to container %v.", hnsResponse.Id, epInfo.ContainerID)
 		err = Hnsv1.HotAttachEndpoint(epInfo.ContainerID, hnsResponse.Id)
 		if err != nil {
-			log.Printf("[net] Failed to attach endpoint: %v.", err)
+			logger.Infof("Failed to attach endpoint: %v.", err)
 			return nil, err
 		}
 	}
@@ -182,7 +181,7 @@ func (nw *network) addIPv6NeighborEntryForGateway(epInfo *EndpointInfo) error {
 		cmd := fmt.Sprintf("New-NetNeighbor -IPAddress %s -InterfaceAlias \"%s (%s)\" -LinkLayerAddress \"%s\"",
 			nw.Subnets[1].Gateway.String(), containerIfNamePrefix, epInfo.Id, defaultGwMac)
 		if out, err = platform.ExecutePowershellCommand(cmd); err != nil {
-			log.Errorf("[net] Adding ipv6 gw neigh entry failed %v:%v", out, err)
+			logger.Errorf("Adding ipv6 gw neigh entry failed %v:%v", out, err)
 			return err
 		}
 	}
@@ -214,7 +213,7 @@ func (nw *network) configureHcnEndpoint(epInfo *EndpointInfo) (*hcn.HostComputeE
 			hcnEndpoint.Policies = append(hcnEndpoint.Policies, epPolicy)
 		}
 	} else {
-		log.Printf("[net] Failed to get endpoint policies due to error: %v", err)
+		logger.Infof("Failed to get endpoint policies due to error: %v", err)
 		return nil, err
 	}
 
@@ -247,7 +246,7 @@ func (nw *network) deleteHostNCApipaEndpoint(networkContainerID string) error {
 
 	// HostNCApipaEndpoint name is derived from NC ID
 	endpointName := fmt.Sprintf("%s-%s", hostNCApipaEndpointNamePrefix, networkContainerID)
-	log.Printf("[net] Deleting HostNCApipaEndpoint: %s for NC: %s", endpointName, networkContainerID)
+	logger.Infof("Deleting HostNCApipaEndpoint: %s for NC: %s", endpointName, networkContainerID)
 
 	// Check if the endpoint exists
 	endpoint, err := Hnsv2.GetEndpointByName(endpointName)
@@ -258,7 +257,7 @@ func (nw *network) deleteHostNCApipaEndpoint(networkContainerID string) error {
 			return fmt.Errorf("[net] deleteEndpointByNameHnsV2 failed due to error with GetEndpointByName: %w", err)
 		}
 
-		log.Printf("[net] Delete called on the Endpoint: %s which doesn't exist. Error: %v", endpointName, err)
+		logger.Infof("Delete called on the Endpoint: %s which doesn't exist. Error: %v", endpointName, err)
 		return nil
 	}
 
@@ -266,7 +265,7 @@ func (nw *network) deleteHostNCApipaEndpoint(networkContainerID string) error {
 		return fmt.Errorf("failed to delete HostNCApipa endpoint: %+v: %w", endpoint, err)
 	}
 
-	log.Printf("[net] Successfully deleted HostNCApipa endpoint: %+v", endpoint)
+	logger.Infof("Successfully deleted HostNCApipa endpoint: %+v", endpoint)
 
 	return nil
 }
@@ -285,7 +284,7 @@ func (nw *network) createHostNCApipaEndpoint(cli apipaClient, epInfo *EndpointIn
 			" due to error: %v", epInfo.NetNsPath, err)
 	}
 
-	log.Printf("[net] Creating HostNCApipaEndpoint for host container connectivity for NC: %s",
+	logger.Infof("Creating HostNCApipaEndpoint for host container connectivity for NC: %s",
 		epInfo.NetworkContainerID)
 
 	if hostNCApipaEndpointID, err = cli.CreateHostNCApipaEndpoint(context.TODO(), epInfo.NetworkContainerID); err != nil {
@@ -310,24 +309,24 @@ func (nw *network) createHostNCApipaEndpoint(cli apipaClient, epInfo *EndpointIn
 func (nw *network) newEndpointImplHnsV2(cli apipaClient, epInfo *EndpointInfo) (*endpoint, error) {
 	hcnEndpoint, err := nw.configureHcnEndpoint(epInfo)
 	if err != nil {
-		log.Printf("[net] Failed to configure hcn endpoint due to error: %v", err)
+		logger.Infof("Failed to configure hcn endpoint due to error: %v", err)
 		return nil, err
 	}
 
 	// Create the HCN endpoint.
-	log.Printf("[net] Creating hcn endpoint: %s computenetwork:%s", hcnEndpoint.Name, hcnEndpoint.HostComputeNetwork)
+	logger.Infof("Creating hcn endpoint: %s computenetwork:%s", hcnEndpoint.Name, hcnEndpoint.HostComputeNetwork)
 	hnsResponse, err := Hnsv2.CreateEndpoint(hcnEndpoint)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create endpoint: %s due to error: %v", hcnEndpoint.Name, err)
 	}
 
-	log.Printf("[net] Successfully created hcn endpoint with response: %+v", hnsResponse)
+	logger.Infof("Successfully created hcn endpoint with response: %+v", hnsResponse)
 
 	defer func() {
 		if err != nil {
-			log.Printf("[net] Deleting hcn endpoint with id: %s", hnsResponse.Id)
+			logger.Infof("Deleting hcn endpoint with id: %s", hnsResponse.Id)
 			err = Hnsv2.DeleteEndpoint(hnsResponse)
-			log.Printf("[net] Completed hcn endpoint deletion for id: %s with error: %v", hnsResponse.Id, err)
+			logger.Infof("Completed hcn endpoint deletion for id: %s with error: %v", hnsResponse.Id, err)
 		}
 	}()
 
@@ -344,7 +343,7 @@ func (nw *network) newEndpointImplHnsV2(cli apipaClient, epInfo *EndpointInfo) (
 	defer func() {
 		if err != nil {
 			if errRemoveNsEp := Hnsv2.RemoveNamespaceEndpoint(namespace.Id, hnsResponse.Id); errRemoveNsEp != nil {
-				log.Printf("[net] Failed to remove endpoint: %s from namespace: %s due to error: %v",
+				logger.Infof("Failed to remove endpoint: %s from namespace: %s due to error: %v",
 					hnsResponse.Id, hnsResponse.Id, errRemoveNsEp)
 			}
 		}
@@ -410,16 +409,16 @@ func (nw *network) deleteEndpointImpl(_ netlink.NetlinkInterface, _ platform.Exe
 
 // deleteEndpointImplHnsV1 deletes an existing endpoint from the network using HNS v1.
 func (nw *network) deleteEndpointImplHnsV1(ep *endpoint) error {
-	log.Printf("[net] HNSEndpointRequest DELETE id:%v", ep.HnsId)
+	logger.Infof("HNSEndpointRequest DELETE id:%v", ep.HnsId)
 	hnsResponse, err := Hnsv1.DeleteEndpoint(ep.HnsId)
-	log.Printf("[net] HNSEndpointRequest DELETE response:%+v err:%v.", hnsResponse, err)
+	logger.Infof("HNSEndpointRequest DELETE response:%+v err:%v.", hnsResponse, err)
 
 	// todo: may need to improve error handling if hns or hcsshim change their error bubbling.
 	// hcsshim bubbles up a generic error when delete fails with message "The endpoint was not found".
 	// the best we can do at the moment is string comparison, which is never great for error checking
 	if err != nil {
 		if strings.Contains(strings.ToLower(err.Error()), "not found") {
-			log.Printf("[net] HNS endpoint id %s not found", ep.HnsId)
+			logger.Infof("HNS endpoint id %s not found", ep.HnsId)
 			return nil
 		}
 	}
@@ -436,12 +435,12 @@ func (nw *network) deleteEndpointImplHnsV2(ep *endpoint) error {
 
 	if ep.AllowInboundFromHostToNC || ep.AllowInboundFromNCToHost {
 		if err = nw.deleteHostNCApipaEndpoint(ep.NetworkContainerID); err != nil {
-			log.Errorf("[net] Failed to delete HostNCApipaEndpoint due to error: %v", err)
+			logger.Errorf("Failed to delete HostNCApipaEndpoint due to error: %v", err)
 			return err
 		}
 	}
 
-	log.Printf("[net] Deleting hcn endpoint with id: %s", ep.HnsId)
+	logger.Infof("Deleting hcn endpoint with id: %s", ep.HnsId)
 
 	hcnEndpoint, err = Hnsv2.GetEndpointByID(ep.HnsId)
 	if err != nil {
@@ -451,13 +450,13 @@ func (nw *network) deleteEndpointImplHnsV2(ep *endpoint) error {
 			return fmt.Errorf("Failed to get hcn endpoint with id: %s due to err: %w", ep.HnsId, err)
 		}
 
-		log.Printf("[net] Delete called on the Endpoint: %s which doesn't exist. Error: %v", ep.HnsId, err)
+		logger.Infof("Delete called on the Endpoint: %s which doesn't exist. Error: %v", ep.HnsId, err)
 		return nil
 	}
 
 	// Remove this endpoint from the namespace
 	if err = Hnsv2.RemoveNamespaceEndpoint(hcnEndpoint.HostComputeNamespace, hcnEndpoint.Id); err != nil {
-		log.Errorf("Failed to remove hcn endpoint: %s from namespace: %s due to error: %v", ep.HnsId,
+		logger.Errorf("Failed to remove hcn endpoint: %s from namespace: %s due to error: %v", ep.HnsId,
 			hcnEndpoint.HostComputeNamespace, err)
 	}
 
@@ -465,7 +464,7 @@ func (nw *network) deleteEndpointImplHnsV2(ep *endpoint) error {
 		return fmt.Errorf("Failed to delete hcn endpoint: %s due to error: %v", ep.HnsId, err)
 	}
 
-	log.Printf("[net] Successfully deleted hcn endpoint with id: %s", ep.HnsId)
+	logger.Infof("Successfully deleted hcn endpoint with id: %s", ep.HnsId)
 
 	return nil
 }
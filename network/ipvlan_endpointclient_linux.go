@@ -0,0 +1,215 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/network/networkutils"
+	"github.com/Azure/azure-container-networking/platform"
+)
+
+var errorIPVlanEndpointClient = errors.New("IPVlanEndpointClient Error")
+
+func newErrorIPVlanEndpointClient(errStr string) error {
+	return fmt.Errorf("%w : %s", errorIPVlanEndpointClient, errStr)
+}
+
+// IPVlanEndpointClient connects a container to the master interface with an ipvlan slave. The slave shares
+// the MAC address of the master, so the pod IPs must be secondary IPs of the master NIC in the VNET.
+type IPVlanEndpointClient struct {
+	hostPrimaryIfName string
+	// ipvlanIfName is the name of the slave in the host namespace, containerIfName its name in the container.
+	ipvlanIfName    string
+	containerIfName string
+	mode            netlink.IPVlanMode
+	netlink         netlink.NetlinkInterface
+	netioshim       netio.NetIOInterface
+	netUtilsClient  networkutils.NetworkUtils
+}
+
+func NewIPVlanEndpointClient(
+	extIf *externalInterface,
+	ipvlanIfName string,
+	mode string,
+	nl netlink.NetlinkInterface,
+	plc platform.ExecClient,
+) *IPVlanEndpointClient {
+	return &IPVlanEndpointClient{
+		hostPrimaryIfName: extIf.Name,
+		ipvlanIfName:      ipvlanIfName,
+		containerIfName:   ipvlanIfName,
+		mode:              ipvlanModeOf(mode),
+		netlink:           nl,
+		netioshim:         &netio.NetIO{},
+		netUtilsClient:    networkutils.NewNetworkUtils(nl, plc),
+	}
+}
+
+// hostIfName returns the name of the host slave which the network client created on the master.
+func (client *IPVlanEndpointClient) hostIfName() (string, error) {
+	primaryIf, err := client.netioshim.GetNetworkInterfaceByName(client.hostPrimaryIfName)
+	if err != nil {
+		return "", newErrorIPVlanEndpointClient(err.Error())
+	}
+	return ipvlanHostIfName(primaryIf.Index), nil
+}
+
+// AddEndpoints creates the ipvlan slave of the container directly in the container netns, so no link of the
+// container is ever left in the host namespace. A slave takes the MTU of the master unless the endpoint has a lower one.
+func (client *IPVlanEndpointClient) AddEndpoints(epInfo *EndpointInfo) error {
+	primaryIf, err := client.netioshim.GetNetworkInterfaceByName(client.hostPrimaryIfName)
+	if err != nil {
+		return newErrorIPVlanEndpointClient(err.Error())
+	}
+
+	link := netlink.IPVlanLink{
+		LinkInfo: netlink.LinkInfo{
+			Type:        netlink.LINK_TYPE_IPVLAN,
+			Name:        client.ipvlanIfName,
+			ParentIndex: primaryIf.Index,
+		},
+		Mode: client.mode,
+	}
+	if epInfo.MTU > 0 && epInfo.MTU < primaryIf.MTU {
+		link.MTU = uint(epInfo.MTU)
+	}
+
+	if epInfo.NetNsPath != "" {
+		ns, err := OpenNamespace(epInfo.NetNsPath)
+		if err != nil {
+			return newErrorIPVlanEndpointClient(err.Error())
+		}
+		defer ns.Close()
+		link.NetNsFd = ns.GetFd()
+	} else if _, err := client.netioshim.GetNetworkInterfaceByName(client.ipvlanIfName); err == nil {
+		// without a container netns the slave stays in the host namespace, replace one left by an earlier ADD
+		logger.Infof("Deleting old ipvlan interface %v", client.ipvlanIfName)
+		if err = client.netlink.DeleteLink(client.ipvlanIfName); err != nil {
+			return newErrorIPVlanEndpointClient(err.Error())
+		}
+	}
+
+	logger.Infof("Creating ipvlan interface %v on %v in netns %v.", client.ipvlanIfName, client.hostPrimaryIfName, epInfo.NetNsPath)
+	if err := client.netlink.AddLink(&link); err != nil {
+		return newErrorIPVlanEndpointClient(err.Error())
+	}
+
+	return nil
+}
+
+// endpointHostRoutes returns the routes of the host namespace to the endpoint IPs through the host slave.
+func endpointHostRoutes(ipAddresses []net.IPNet) []RouteInfo {
+	routes := make([]RouteInfo, 0, len(ipAddresses))
+	for _, ipAddr := range ipAddresses {
+		ipNet := net.IPNet{IP: ipAddr.IP, Mask: net.CIDRMask(ipv6FullMask, ipv6Bits)}
+		if ipAddr.IP.To4() != nil {
+			ipNet = net.IPNet{IP: ipAddr.IP, Mask: net.CIDRMask(ipv4FullMask, ipv4Bits)}
+		}
+		routes = append(routes, RouteInfo{Dst: ipNet})
+	}
+	return routes
+}
+
+func (client *IPVlanEndpointClient) AddEndpointRules(epInfo *EndpointInfo) error {
+	hostIfName, err := client.hostIfName()
+	if err != nil {
+		return err
+	}
+
+	// ip route add <podip> dev <host slave>
+	// The route of the master subnet would send the packets of the host to the pod out of the master.
+	if err := addRoutes(client.netlink, client.netioshim, hostIfName, endpointHostRoutes(epInfo.IPAddresses)); err != nil {
+		return newErrorIPVlanEndpointClient(err.Error())
+	}
+
+	return nil
+}
+
+func (client *IPVlanEndpointClient) DeleteEndpointRules(ep *endpoint) {
+	hostIfName, err := client.hostIfName()
+	if err != nil {
+		logger.Infof("Failed to delete routes of endpoint %v: %v", ep.Id, err)
+		return
+	}
+
+	if err := deleteRoutes(client.netlink, client.netioshim, hostIfName, endpointHostRoutes(ep.IPAddresses)); err != nil {
+		logger.Infof("Failed to delete routes of endpoint %v: %v", ep.Id, err)
+	}
+}
+
+// MoveEndpointsToContainerNS does nothing, AddEndpoints creates the slave in the container netns.
+func (client *IPVlanEndpointClient) MoveEndpointsToContainerNS(*EndpointInfo, uintptr) error {
+	return nil
+}
+
+func (client *IPVlanEndpointClient) SetupContainerInterfaces(epInfo *EndpointInfo) error {
+	if err := client.netUtilsClient.SetupContainerInterface(client.containerIfName, epInfo.IfName); err != nil {
+		return err
+	}
+
+	client.containerIfName = epInfo.IfName
+
+	return nil
+}
+
+// ConfigureContainerInterfacesAndRoutes assigns the IPs and adds the routes of the endpoint. An L3 slave has
+// no neighbors since the master routes its packets, so a default route needs no gateway when none is given.
+func (client *IPVlanEndpointClient) ConfigureContainerInterfacesAndRoutes(epInfo *EndpointInfo) error {
+	if err := client.netUtilsClient.AssignIPToInterface(client.containerIfName, epInfo.IPAddresses); err != nil {
+		return newErrorIPVlanEndpointClient(err.Error())
+	}
+
+	routes := append([]RouteInfo{}, epInfo.Routes...)
+	for _, cidr := range []string{defaultGwCidr, defaultv6Cidr} {
+		_, defaultIPNet, _ := net.ParseCIDR(cidr)
+		if !hasIPOfFamily(epInfo.IPAddresses, defaultIPNet.IP) || hasRouteTo(routes, defaultIPNet) {
+			continue
+		}
+		// ip route add default dev eth0
+		routes = append(routes, RouteInfo{Dst: *defaultIPNet, Scope: netlink.RT_SCOPE_LINK})
+	}
+
+	if err := addRoutes(client.netlink, client.netioshim, client.containerIfName, routes); err != nil {
+		return newErrorIPVlanEndpointClient(err.Error())
+	}
+
+	return nil
+}
+
+func hasIPOfFamily(ipAddresses []net.IPNet, ip net.IP) bool {
+	for _, ipAddr := range ipAddresses {
+		if (ipAddr.IP.To4() != nil) == (ip.To4() != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRouteTo(routes []RouteInfo, dst *net.IPNet) bool {
+	for i := range routes {
+		if routes[i].Dst.String() == dst.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteEndpoints deletes the slave if it is in the host namespace, which only happens for an endpoint without
+// a container netns. Otherwise it goes away with the container netns.
+func (client *IPVlanEndpointClient) DeleteEndpoints(ep *endpoint) error {
+	if client.ipvlanIfName != "" {
+		if _, err := client.netioshim.GetNetworkInterfaceByName(client.ipvlanIfName); err == nil {
+			logger.Infof("Deleting ipvlan interface %v.", client.ipvlanIfName)
+			if err = client.netlink.DeleteLink(client.ipvlanIfName); err != nil {
+				logger.Infof("Failed to delete ipvlan interface %v: %v.", client.ipvlanIfName, err)
+				return newErrorIPVlanEndpointClient(err.Error())
+			}
+		}
+	}
+
+	deleteConntrackEntries(client.netlink, ep.IPAddresses)
+	return nil
+}
@@ -0,0 +1,212 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/network/networkutils"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNetlink records the links, addresses and the destinations of the routes added through a MockNetlink.
+type recordingNetlink struct {
+	*netlink.MockNetlink
+	links  []netlink.Link
+	addrs  []string
+	routes []string
+}
+
+func (nl *recordingNetlink) AddLink(link netlink.Link) error {
+	nl.links = append(nl.links, link)
+	return nl.MockNetlink.AddLink(link)
+}
+
+func (nl *recordingNetlink) AddIPAddress(ifName string, ipAddress net.IP, ipNet *net.IPNet) error {
+	nl.addrs = append(nl.addrs, ifName+" "+ipNet.String())
+	return nl.MockNetlink.AddIPAddress(ifName, ipAddress, ipNet)
+}
+
+func (nl *recordingNetlink) AddIPRoute(route *netlink.Route) error {
+	nl.routes = append(nl.routes, route.Dst.String())
+	return nl.MockNetlink.AddIPRoute(route)
+}
+
+func newTestIPVlanEndpointClient(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, mode string) *IPVlanEndpointClient {
+	client := NewIPVlanEndpointClient(&externalInterface{Name: "eth0"}, "azv1234567-2", mode, nl, platform.NewMockExecClient(false))
+	client.netioshim = netioshim
+	client.netUtilsClient = networkutils.NewNetworkUtils(nl, platform.NewMockExecClient(false))
+	return client
+}
+
+func TestIPVlanAddEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		nl       *recordingNetlink
+		netNs    bool
+		wantMode netlink.IPVlanMode
+		wantErr  bool
+	}{
+		{
+			name:     "Add L3 endpoint",
+			mode:     opModeIPVlan,
+			nl:       &recordingNetlink{MockNetlink: netlink.NewMockNetlink(false, "")},
+			wantMode: netlink.IPVLAN_MODE_L3,
+		},
+		{
+			name:     "Add endpoint in container netns",
+			mode:     opModeIPVlan,
+			nl:       &recordingNetlink{MockNetlink: netlink.NewMockNetlink(false, "")},
+			netNs:    true,
+			wantMode: netlink.IPVLAN_MODE_L3,
+		},
+		{
+			name:     "Add L3S endpoint",
+			mode:     opModeIPVlanL3S,
+			nl:       &recordingNetlink{MockNetlink: netlink.NewMockNetlink(false, "")},
+			wantMode: netlink.IPVLAN_MODE_L3S,
+		},
+		{
+			name:    "Add endpoint netlink fail",
+			mode:    opModeIPVlan,
+			nl:      &recordingNetlink{MockNetlink: netlink.NewMockNetlink(true, "netlink fail")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// the master exists, the slave doesn't yet
+			client := newTestIPVlanEndpointClient(tt.nl, netio.NewMockNetIO(true, 2), tt.mode)
+			epInfo := &EndpointInfo{}
+			if tt.netNs {
+				// any file stands in for the netns, the mock doesn't use the fd
+				epInfo.NetNsPath = filepath.Join(t.TempDir(), "netns")
+				require.NoError(t, os.WriteFile(epInfo.NetNsPath, nil, 0o600))
+			}
+			err := client.AddEndpoints(epInfo)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errorIPVlanEndpointClient)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, tt.nl.links, 1)
+			link := tt.nl.links[0].(*netlink.IPVlanLink)
+			require.Equal(t, "azv1234567-2", link.Name)
+			require.Equal(t, netlink.LINK_TYPE_IPVLAN, link.Type)
+			require.Equal(t, 2, link.ParentIndex)
+			require.Equal(t, tt.wantMode, link.Mode)
+			// the slave is created in the container netns rather than moved there
+			require.Equal(t, tt.netNs, link.NetNsFd != 0)
+			require.NoError(t, client.MoveEndpointsToContainerNS(epInfo, 0))
+		})
+	}
+}
+
+func TestIPVlanEndpointRules(t *testing.T) {
+	nl := &recordingNetlink{MockNetlink: netlink.NewMockNetlink(false, "")}
+	client := newTestIPVlanEndpointClient(nl, netio.NewMockNetIO(false, 0), opModeIPVlan)
+	epInfo := &EndpointInfo{
+		IPAddresses: []net.IPNet{
+			{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+			{IP: net.ParseIP("fc00::5"), Mask: net.CIDRMask(subnetv6Mask, ipv6Bits)},
+		},
+	}
+
+	require.NoError(t, client.AddEndpointRules(epInfo))
+	require.Equal(t, []string{"10.240.0.5/32", "fc00::5/128"}, nl.routes)
+
+	client.DeleteEndpointRules(&endpoint{IPAddresses: epInfo.IPAddresses})
+}
+
+func TestIPVlanConfigureContainerInterfacesAndRoutes(t *testing.T) {
+	_, defaultIPNet, _ := net.ParseCIDR(defaultGwCidr)
+	tests := []struct {
+		name       string
+		epInfo     *EndpointInfo
+		wantRoutes []string
+	}{
+		{
+			name: "Default routes through the slave",
+			epInfo: &EndpointInfo{
+				IPAddresses: []net.IPNet{
+					{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+					{IP: net.ParseIP("fc00::5"), Mask: net.CIDRMask(subnetv6Mask, ipv6Bits)},
+				},
+			},
+			wantRoutes: []string{"0.0.0.0/0", "::/0"},
+		},
+		{
+			name: "Default route of the endpoint",
+			epInfo: &EndpointInfo{
+				IPAddresses: []net.IPNet{
+					{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+				},
+				Routes: []RouteInfo{{Dst: *defaultIPNet, Gw: net.ParseIP("10.240.0.1")}},
+			},
+			wantRoutes: []string{"0.0.0.0/0"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			nl := &recordingNetlink{MockNetlink: netlink.NewMockNetlink(false, "")}
+			client := newTestIPVlanEndpointClient(nl, netio.NewMockNetIO(false, 0), opModeIPVlan)
+			require.NoError(t, client.ConfigureContainerInterfacesAndRoutes(tt.epInfo))
+			require.Equal(t, tt.wantRoutes, nl.routes)
+		})
+	}
+}
+
+func TestIPVlanDeleteEndpoints(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	ep := &endpoint{
+		IPAddresses: []net.IPNet{{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)}},
+	}
+
+	// the slave left in the host namespace by a failed ADD is deleted
+	client := newTestIPVlanEndpointClient(nl, netio.NewMockNetIO(false, 0), opModeIPVlan)
+	client.containerIfName = "eth0"
+	require.NoError(t, client.DeleteEndpoints(ep))
+	require.Equal(t, []net.IP{ep.IPAddresses[0].IP}, nl.ConntrackDeletedIPs)
+
+	client.netlink = netlink.NewMockNetlink(true, "netlink fail")
+	require.ErrorIs(t, client.DeleteEndpoints(ep), errorIPVlanEndpointClient)
+
+	// a DEL doesn't know the slave, which went away with the container netns
+	client = newTestIPVlanEndpointClient(netlink.NewMockNetlink(true, "netlink fail"), netio.NewMockNetIO(false, 0), opModeIPVlan)
+	client.ipvlanIfName = ""
+	require.NoError(t, client.DeleteEndpoints(ep))
+}
+
+func TestIPVlanNetworkClientCreateBridge(t *testing.T) {
+	nl := &recordingNetlink{MockNetlink: netlink.NewMockNetlink(false, "")}
+
+	// the host slave exists
+	client := NewIPVlanNetworkClient("eth0", opModeIPVlanL3S, nl, netio.NewMockNetIO(false, 0))
+	require.NoError(t, client.CreateBridge())
+	require.Empty(t, nl.links)
+
+	client = NewIPVlanNetworkClient("eth0", opModeIPVlanL3S, nl, netio.NewMockNetIO(true, 2))
+	require.NoError(t, client.CreateBridge())
+	require.Len(t, nl.links, 1)
+	link := nl.links[0].(*netlink.IPVlanLink)
+	require.Equal(t, ipvlanHostIfName(2), link.Name)
+	require.Equal(t, netlink.IPVLAN_MODE_L3S, link.Mode)
+	// the host slave doesn't take the addresses of the master
+	require.Empty(t, nl.addrs)
+	require.NoError(t, client.DeleteBridge())
+
+	client = NewIPVlanNetworkClient("eth0", opModeIPVlan, netlink.NewMockNetlink(true, "netlink fail"), netio.NewMockNetIO(true, 2))
+	require.ErrorIs(t, client.CreateBridge(), errorIPVlanNetworkClient)
+}
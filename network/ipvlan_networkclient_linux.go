@@ -0,0 +1,133 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+)
+
+const (
+	// Prefix of the ipvlan slave which gives the host namespace access to the pods of a master interface.
+	ipvlanHostIfPrefix = "azipvl"
+)
+
+var errorIPVlanNetworkClient = errors.New("IPVlanNetworkClient Error")
+
+func newErrorIPVlanNetworkClient(errStr string) error {
+	return fmt.Errorf("%w : %s", errorIPVlanNetworkClient, errStr)
+}
+
+// isIPVlanMode returns whether the network mode puts the endpoints on ipvlan slaves of the master interface.
+func isIPVlanMode(mode string) bool {
+	return mode == opModeIPVlan || mode == opModeIPVlanL3S
+}
+
+// ipvlanModeOf returns the ipvlan mode of a network mode. L3S runs the traffic of the slaves through
+// the netfilter hooks of the host namespace, which kube-proxy needs, at some throughput cost.
+func ipvlanModeOf(mode string) netlink.IPVlanMode {
+	if mode == opModeIPVlanL3S {
+		return netlink.IPVLAN_MODE_L3S
+	}
+	return netlink.IPVLAN_MODE_L3
+}
+
+// ipvlanHostIfName returns the name of the host slave of the master interface with the index.
+func ipvlanHostIfName(masterIndex int) string {
+	return fmt.Sprintf("%s%d", ipvlanHostIfPrefix, masterIndex)
+}
+
+// IPVlanNetworkClient connects a network in ipvlan mode. There is no bridge: the pods get ipvlan slaves
+// of the master interface, and the host reaches them through an ipvlan slave of its own with a route per pod.
+// The host slave has no addresses, so the host routes and source addresses stay those of the master; the
+// replies of the pods to a host IP, which no slave holds, are routed by the host namespace and delivered locally.
+type IPVlanNetworkClient struct {
+	hostInterfaceName string
+	mode              netlink.IPVlanMode
+	netlink           netlink.NetlinkInterface
+	netioshim         netio.NetIOInterface
+}
+
+func NewIPVlanNetworkClient(
+	hostInterfaceName string,
+	mode string,
+	nl netlink.NetlinkInterface,
+	netioCli netio.NetIOInterface,
+) *IPVlanNetworkClient {
+	return &IPVlanNetworkClient{
+		hostInterfaceName: hostInterfaceName,
+		mode:              ipvlanModeOf(mode),
+		netlink:           nl,
+		netioshim:         netioCli,
+	}
+}
+
+// CreateBridge creates the host slave of the master interface, if it doesn't exist yet.
+func (client *IPVlanNetworkClient) CreateBridge() error {
+	hostIf, err := client.netioshim.GetNetworkInterfaceByName(client.hostInterfaceName)
+	if err != nil {
+		return newErrorIPVlanNetworkClient(err.Error())
+	}
+
+	slaveName := ipvlanHostIfName(hostIf.Index)
+	if _, err = client.netioshim.GetNetworkInterfaceByName(slaveName); err == nil {
+		logger.Infof("Found existing ipvlan host interface %v.", slaveName)
+		return nil
+	}
+
+	logger.Infof("Creating ipvlan host interface %v on %v.", slaveName, client.hostInterfaceName)
+	link := netlink.IPVlanLink{
+		LinkInfo: netlink.LinkInfo{
+			Type:        netlink.LINK_TYPE_IPVLAN,
+			Name:        slaveName,
+			ParentIndex: hostIf.Index,
+		},
+		Mode: client.mode,
+	}
+	if err = client.netlink.AddLink(&link); err != nil {
+		return newErrorIPVlanNetworkClient(err.Error())
+	}
+
+	if err = client.netlink.SetLinkState(slaveName, true); err != nil {
+		if delErr := client.netlink.DeleteLink(slaveName); delErr != nil {
+			logger.Errorf("Failed to delete ipvlan host interface %v: %v", slaveName, delErr)
+		}
+		return newErrorIPVlanNetworkClient(err.Error())
+	}
+
+	return nil
+}
+
+// DeleteBridge deletes the host slave of the master interface.
+func (client *IPVlanNetworkClient) DeleteBridge() error {
+	hostIf, err := client.netioshim.GetNetworkInterfaceByName(client.hostInterfaceName)
+	if err != nil {
+		return newErrorIPVlanNetworkClient(err.Error())
+	}
+
+	slaveName := ipvlanHostIfName(hostIf.Index)
+	logger.Infof("Deleting ipvlan host interface %v.", slaveName)
+	if err = client.netlink.DeleteLink(slaveName); err != nil {
+		return newErrorIPVlanNetworkClient(err.Error())
+	}
+
+	return nil
+}
+
+// The master interface keeps its configuration in ipvlan mode, so there are no L2 rules to program
+// and nothing to enslave.
+
+func (*IPVlanNetworkClient) AddL2Rules(*externalInterface) error {
+	return nil
+}
+
+func (*IPVlanNetworkClient) DeleteL2Rules(*externalInterface) {}
+
+func (*IPVlanNetworkClient) SetBridgeMasterToHostInterface() error {
+	return nil
+}
+
+func (*IPVlanNetworkClient) SetHairpinOnHostInterface(bool) error {
+	return nil
+}
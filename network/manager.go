@@ -8,13 +8,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-container-networking/cni/api"
+	cnilog "github.com/Azure/azure-container-networking/cni/log"
 	cnms "github.com/Azure/azure-container-networking/cnms/cnmspackage"
 	"github.com/Azure/azure-container-networking/common"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/platform"
 	"github.com/Azure/azure-container-networking/store"
+	"go.uber.org/zap"
 )
 
 const (
@@ -28,6 +30,8 @@ const (
 	genericData = "com.docker.network.generic"
 )
 
+var logger = cnilog.CNILogger.With(zap.String(cnilog.ComponentKey, "net")).Sugar()
+
 var Ipv4DefaultRouteDstPrefix = net.IPNet{
 	IP:   net.IPv4zero,
 	Mask: net.IPv4Mask(0, 0, 0, 0),
@@ -87,6 +91,9 @@ type NetworkManager interface {
 	UpdateEndpoint(networkID string, existingEpInfo *EndpointInfo, targetEpInfo *EndpointInfo) error
 	GetNumberOfEndpoints(ifName string, networkID string) int
 	SetupNetworkUsingState(networkMonitor *cnms.NetworkMonitor) error
+	// ReconcileOrphans reports the host links and namespaces which belong to no endpoint in the state,
+	// and removes them if deleteOrphans is set.
+	ReconcileOrphans(deleteOrphans bool) (*api.OrphanReport, error)
 }
 
 // Creates a new network manager.
@@ -119,7 +126,7 @@ func (nm *networkManager) Uninitialize() {
 func (nm *networkManager) restore(isRehydrationRequired bool) error {
 	// Skip if a store is not provided.
 	if nm.store == nil {
-		log.Printf("[net] network store is nil")
+		logger.Infof("network store is nil")
 		return nil
 	}
 
@@ -131,14 +138,14 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 	err := nm.store.Read(storeKey, nm)
 	if err != nil {
 		if err == store.ErrKeyNotFound {
-			log.Printf("[net] network store key not found")
+			logger.Infof("network store key not found")
 			// Considered successful.
 			return nil
 		} else if err == store.ErrStoreEmpty {
-			log.Printf("[net] network store empty")
+			logger.Infof("network store empty")
 			return nil
 		} else {
-			log.Printf("[net] Failed to restore state, err:%v\n", err)
+			logger.Infof("Failed to restore state, err:%v", err)
 			return err
 		}
 	}
@@ -147,20 +154,20 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 		modTime, err := nm.store.GetModificationTime()
 		if err == nil {
 			rebootTime, err := platform.GetLastRebootTime()
-			log.Printf("[net] reboot time %v store mod time %v", rebootTime, modTime)
+			logger.Infof("reboot time %v store mod time %v", rebootTime, modTime)
 			if err == nil && rebootTime.After(modTime) {
-				log.Printf("[net] Detected Reboot")
+				logger.Infof("Detected Reboot")
 				rebooted = true
 				if clearNwConfig, err := platform.ClearNetworkConfiguration(); clearNwConfig {
 					if err != nil {
-						log.Printf("[net] Failed to clear network configuration, err:%v\n", err)
+						logger.Infof("Failed to clear network configuration, err:%v", err)
 						return err
 					}
 
 					// Delete the networks left behind after reboot
 					for _, extIf := range nm.ExternalInterfaces {
 						for _, nw := range extIf.Networks {
-							log.Printf("[net] Deleting the network %s on reboot\n", nw.Id)
+							logger.Infof("Deleting the network %s on reboot", nw.Id)
 							_ = nm.deleteNetwork(nw.Id)
 						}
 					}
@@ -185,12 +192,12 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 
 	// if rebooted recreate the network that existed before reboot.
 	if rebooted {
-		log.Printf("[net] Rehydrating network state from persistent store")
+		logger.Infof("Rehydrating network state from persistent store")
 		for _, extIf := range nm.ExternalInterfaces {
 			for _, nw := range extIf.Networks {
 				nwInfo, err := nm.GetNetworkInfo(nw.Id)
 				if err != nil {
-					log.Printf("[net] Failed to fetch network info for network %v extif %v err %v. This should not happen", nw, extIf, err)
+					logger.Infof("Failed to fetch network info for network %v extif %v err %v. This should not happen", nw, extIf, err)
 					return err
 				}
 
@@ -198,17 +205,17 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 
 				_, err = nm.newNetworkImpl(&nwInfo, extIf)
 				if err != nil {
-					log.Printf("[net] Restoring network failed for nwInfo %v extif %v. This should not happen %v", nwInfo, extIf, err)
+					logger.Infof("Restoring network failed for nwInfo %v extif %v. This should not happen %v", nwInfo, extIf, err)
 					return err
 				}
 			}
 		}
 	}
 
-	log.Printf("[net] Restored state")
+	logger.Infof("Restored state")
 	for _, extIf := range nm.ExternalInterfaces {
 		for _, nw := range extIf.Networks {
-			log.Printf("Number of endpoints: %d", len(nw.Endpoints))
+			logger.Infof("Number of endpoints: %d", len(nw.Endpoints))
 		}
 	}
 
@@ -227,9 +234,9 @@ func (nm *networkManager) save() error {
 
 	err := nm.store.Write(storeKey, nm)
 	if err == nil {
-		log.Printf("[net] Save succeeded.\n")
+		logger.Infof("Save succeeded.")
 	} else {
-		log.Printf("[net] Save failed, err:%v\n", err)
+		logger.Infof("Save failed, err:%v", err)
 	}
 	return err
 }
@@ -311,6 +318,8 @@ func (nm *networkManager) GetNetworkInfo(networkId string) (NetworkInfo, error)
 		EnableSnatOnHost: nw.EnableSnatOnHost,
 		DNS:              nw.DNS,
 		Options:          make(map[string]interface{}),
+		MTU:              nw.MTU,
+		Offload:          nw.Offload,
 	}
 
 	getNetworkInfoImpl(&nwInfo, nw)
@@ -334,7 +343,7 @@ func (nm *networkManager) CreateEndpoint(cli apipaClient, networkID string, epIn
 
 	if nw.VlanId != 0 {
 		if epInfo.Data[VlanIDKey] == nil {
-			log.Printf("overriding endpoint vlanid with network vlanid")
+			logger.Infof("overriding endpoint vlanid with network vlanid")
 			epInfo.Data[VlanIDKey] = nw.VlanId
 		}
 	}
@@ -401,7 +410,7 @@ func (nm *networkManager) GetAllEndpoints(networkId string) (map[string]*Endpoin
 
 	// Special case when CNS invokes CNI, but there is no state, but return gracefully
 	if len(nm.ExternalInterfaces) == 0 {
-		log.Printf("Network manager has no external interfaces, is the state file populated?")
+		logger.Infof("Network manager has no external interfaces, is the state file populated?")
 		return eps, store.ErrStoreEmpty
 	}
 
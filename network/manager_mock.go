@@ -1,6 +1,7 @@
 package network
 
 import (
+	"github.com/Azure/azure-container-networking/cni/api"
 	cnms "github.com/Azure/azure-container-networking/cnms/cnmspackage"
 	"github.com/Azure/azure-container-networking/common"
 )
@@ -114,3 +115,8 @@ func (nm *MockNetworkManager) FindNetworkIDFromNetNs(netNs string) (string, erro
 
 	return "", errNetworkNotFound
 }
+
+// ReconcileOrphans mock
+func (nm *MockNetworkManager) ReconcileOrphans(deleteOrphans bool) (*api.OrphanReport, error) {
+	return &api.OrphanReport{}, nil
+}
@@ -5,7 +5,6 @@ import (
 
 	cnms "github.com/Azure/azure-container-networking/cnms/cnmspackage"
 	"github.com/Azure/azure-container-networking/ebtables"
-	"github.com/Azure/azure-container-networking/log"
 )
 
 const (
@@ -16,7 +15,7 @@ const (
 func (nm *networkManager) monitorNetworkState(networkMonitor *cnms.NetworkMonitor) error {
 	currentEbtableRulesMap, err := cnms.GetEbTableRulesInMap()
 	if err != nil {
-		log.Printf("GetEbTableRulesInMap failed with error %v", err)
+		logger.Infof("GetEbTableRulesInMap failed with error %v", err)
 		return err
 	}
 
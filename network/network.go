@@ -8,7 +8,6 @@ import (
 	"net"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/network/policy"
 	"github.com/Azure/azure-container-networking/platform"
 )
@@ -19,6 +18,8 @@ const (
 	opModeTunnel          = "tunnel"
 	opModeTransparent     = "transparent"
 	opModeTransparentVlan = "transparent-vlan"
+	opModeIPVlan          = "ipvlan"
+	opModeIPVlanL3S       = "ipvlan-l3s"
 	opModeDefault         = opModeTunnel
 )
 
@@ -54,6 +55,8 @@ type network struct {
 	EnableSnatOnHost bool
 	NetNs            string
 	SnatBridgeIP     string
+	MTU              int
+	Offload          OffloadSettings
 }
 
 // NetworkInfo contains read-only information about a container network.
@@ -74,6 +77,16 @@ type NetworkInfo struct {
 	IPV6Mode                      string
 	IPAMType                      string
 	ServiceCidrs                  string
+	MTU                           int
+	Offload                       OffloadSettings
+}
+
+// OffloadSettings turns offloads of the container interfaces of a network on or off.
+// The kernel default is kept for the offloads which are not set.
+type OffloadSettings struct {
+	TSO *bool
+	GSO *bool
+	GRO *bool
 }
 
 // SubnetInfo contains subnet information for a container network.
@@ -121,7 +134,7 @@ func (nm *networkManager) newExternalInterface(ifName string, subnet string) err
 
 	nm.ExternalInterfaces[ifName] = &extIf
 
-	log.Printf("[net] Added ExternalInterface %v for subnet %v.", ifName, subnet)
+	logger.Infof("Added ExternalInterface %v for subnet %v.", ifName, subnet)
 
 	return nil
 }
@@ -130,7 +143,7 @@ func (nm *networkManager) newExternalInterface(ifName string, subnet string) err
 func (nm *networkManager) deleteExternalInterface(ifName string) error {
 	delete(nm.ExternalInterfaces, ifName)
 
-	log.Printf("[net] Deleted ExternalInterface %v.", ifName)
+	logger.Infof("Deleted ExternalInterface %v.", ifName)
 
 	return nil
 }
@@ -163,10 +176,10 @@ func (nm *networkManager) newNetwork(nwInfo *NetworkInfo) (*network, error) {
 	var nw *network
 	var err error
 
-	log.Printf("[net] Creating network %s.", nwInfo.PrettyString())
+	logger.Infof("Creating network %s.", nwInfo.PrettyString())
 	defer func() {
 		if err != nil {
-			log.Printf("[net] Failed to create network %v, err:%v.", nwInfo.Id, err)
+			logger.Infof("Failed to create network %v, err:%v.", nwInfo.Id, err)
 		}
 	}()
 
@@ -204,7 +217,7 @@ func (nm *networkManager) newNetwork(nwInfo *NetworkInfo) (*network, error) {
 	nw.Subnets = nwInfo.Subnets
 	extIf.Networks[nwInfo.Id] = nw
 
-	log.Printf("[net] Created network %v on interface %v.", nwInfo.Id, extIf.Name)
+	logger.Infof("Created network %v on interface %v.", nwInfo.Id, extIf.Name)
 	return nw, nil
 }
 
@@ -212,10 +225,10 @@ func (nm *networkManager) newNetwork(nwInfo *NetworkInfo) (*network, error) {
 func (nm *networkManager) deleteNetwork(networkID string) error {
 	var err error
 
-	log.Printf("[net] Deleting network %v.", networkID)
+	logger.Infof("Deleting network %v.", networkID)
 	defer func() {
 		if err != nil {
-			log.Printf("[net] Failed to delete network %v, err:%v.", networkID, err)
+			logger.Infof("Failed to delete network %v, err:%v.", networkID, err)
 		}
 	}()
 
@@ -236,7 +249,7 @@ func (nm *networkManager) deleteNetwork(networkID string) error {
 		delete(nw.extIf.Networks, networkID)
 	}
 
-	log.Printf("[net] Deleted network %+v.", nw)
+	logger.Infof("Deleted network %+v.", nw)
 	return nil
 }
 
@@ -255,7 +268,7 @@ func (nm *networkManager) getNetwork(networkId string) (*network, error) {
 // getNetworkIDForNetNs finds the network that contains the endpoint that was created for this netNs. Returns
 // and errNetworkNotFound if the netNs is not found in any network
 func (nm *networkManager) FindNetworkIDFromNetNs(netNs string) (string, error) {
-	log.Printf("Querying state for network for NetNs [%s]", netNs)
+	logger.Infof("Querying state for network for NetNs [%s]", netNs)
 
 	// Look through the external interfaces
 	for _, iface := range nm.ExternalInterfaces {
@@ -265,7 +278,7 @@ func (nm *networkManager) FindNetworkIDFromNetNs(netNs string) (string, error) {
 			for _, endpoint := range network.Endpoints {
 				// If the netNs matches for this endpoint, return the network ID (which is the name)
 				if endpoint.NetNs == netNs {
-					log.Printf("Found network [%s] for NetNS [%s]", network.Id, netNs)
+					logger.Infof("Found network [%s] for NetNS [%s]", network.Id, netNs)
 					return network.Id, nil
 				}
 			}
@@ -11,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-container-networking/iptables"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -25,6 +24,7 @@ const (
 	bridgePrefix = "azure"
 	// Virtual MAC address used by Azure VNET.
 	virtualMacAddress = "12:34:56:78:9a:bc"
+	vlanHeaderLength  = 4
 	versionID         = "VERSION_ID"
 	distroID          = "ID"
 	ubuntuStr         = "ubuntu"
@@ -64,13 +64,13 @@ func (nm *networkManager) newNetworkImpl(nwInfo *NetworkInfo, extIf *externalInt
 		ifName string
 	)
 	opt, _ := nwInfo.Options[genericData].(map[string]interface{})
-	log.Printf("opt %+v options %+v", opt, nwInfo.Options)
+	logger.Infof("opt %+v options %+v", opt, nwInfo.Options)
 
 	switch nwInfo.Mode {
 	case opModeTunnel:
 		fallthrough
 	case opModeBridge:
-		log.Printf("create bridge")
+		logger.Infof("create bridge")
 		ifName = extIf.BridgeName
 		if err := nm.connectExternalInterface(extIf, nwInfo); err != nil {
 			return nil, err
@@ -80,7 +80,7 @@ func (nm *networkManager) newNetworkImpl(nwInfo *NetworkInfo, extIf *externalInt
 			vlanid, _ = strconv.Atoi(opt[VlanIDKey].(string))
 		}
 	case opModeTransparent:
-		log.Printf("Transparent mode")
+		logger.Infof("Transparent mode")
 		ifName = extIf.Name
 		if nwInfo.IPV6Mode != "" {
 			nu := networkutils.NewNetworkUtils(nm.netlink, nm.plClient)
@@ -89,15 +89,21 @@ func (nm *networkManager) newNetworkImpl(nwInfo *NetworkInfo, extIf *externalInt
 			}
 		}
 	case opModeTransparentVlan:
-		log.Printf("Transparent vlan mode")
+		logger.Infof("Transparent vlan mode")
 		ifName = extIf.Name
+	case opModeIPVlan, opModeIPVlanL3S:
+		logger.Infof("IPVlan mode")
+		ifName = extIf.Name
+		if err := NewIPVlanNetworkClient(extIf.Name, nwInfo.Mode, nm.netlink, nm.netio).CreateBridge(); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, errNetworkModeInvalid
 	}
 
 	err := nm.handleCommonOptions(ifName, nwInfo)
 	if err != nil {
-		log.Printf("handleCommonOptions failed with error %s", err.Error())
+		logger.Infof("handleCommonOptions failed with error %s", err.Error())
 		return nil, err
 	}
 
@@ -110,11 +116,34 @@ func (nm *networkManager) newNetworkImpl(nwInfo *NetworkInfo, extIf *externalInt
 		VlanId:           vlanid,
 		DNS:              nwInfo.DNS,
 		EnableSnatOnHost: nwInfo.EnableSnatOnHost,
+		MTU:              nm.networkMTU(nwInfo, extIf, vlanid),
+		Offload:          nwInfo.Offload,
 	}
 
 	return nw, nil
 }
 
+// networkMTU returns the MTU of the endpoints of a network: the configured one, or else the MTU of the
+// master interface minus the overhead of the VLAN tag which the vlan modes add to the packets of the endpoints.
+func (nm *networkManager) networkMTU(nwInfo *NetworkInfo, extIf *externalInterface, vlanid int) int {
+	if nwInfo.MTU > 0 {
+		return nwInfo.MTU
+	}
+
+	hostIf, err := nm.netio.GetNetworkInterfaceByName(extIf.Name)
+	if err != nil {
+		logger.Infof("Failed to get the MTU of %v, keeping the default MTU: %v", extIf.Name, err)
+		return 0
+	}
+
+	mtu := hostIf.MTU
+	if vlanid != 0 || nwInfo.Mode == opModeTransparentVlan {
+		mtu -= vlanHeaderLength
+	}
+	logger.Infof("Using MTU %d for network %v on %v with MTU %d.", mtu, nwInfo.Id, extIf.Name, hostIf.MTU)
+	return mtu
+}
+
 func (nm *networkManager) handleCommonOptions(ifName string, nwInfo *NetworkInfo) error {
 	var err error
 	if routes, exists := nwInfo.Options[RoutesKey]; exists {
@@ -138,8 +167,18 @@ func (nm *networkManager) handleCommonOptions(ifName string, nwInfo *NetworkInfo
 func (nm *networkManager) deleteNetworkImpl(nw *network) error {
 	var networkClient NetworkClient
 
+	if isIPVlanMode(nw.Mode) {
+		// the master interface was never disconnected, only the host slave needs to go
+		if len(nw.extIf.Networks) == 1 {
+			if err := NewIPVlanNetworkClient(nw.extIf.Name, nw.Mode, nm.netlink, nm.netio).DeleteBridge(); err != nil {
+				logger.Infof("Failed to delete ipvlan host interface: %v", err)
+			}
+		}
+		return nil
+	}
+
 	if nw.VlanId != 0 {
-		networkClient = NewOVSClient(nw.extIf.BridgeName, nw.extIf.Name, ovsctl.NewOvsctl(), nm.netlink, nm.plClient)
+		networkClient = NewOVSClient(nw.extIf.BridgeName, nw.extIf.Name, ovsctl.New(), nm.netlink, nm.plClient)
 	} else {
 		networkClient = NewLinuxBridgeClient(nw.extIf.BridgeName, nw.extIf.Name, NetworkInfo{}, nm.netlink, nm.plClient)
 	}
@@ -157,7 +196,7 @@ func (nm *networkManager) saveIPConfig(hostIf *net.Interface, extIf *externalInt
 	// Save the default routes on the interface.
 	routes, err := nm.netlink.GetIPRoute(&netlink.Route{Dst: &net.IPNet{}, LinkIndex: hostIf.Index})
 	if err != nil {
-		log.Printf("[net] Failed to query routes: %v.", err)
+		logger.Infof("Failed to query routes: %v.", err)
 		return err
 	}
 
@@ -188,7 +227,7 @@ func (nm *networkManager) saveIPConfig(hostIf *net.Interface, extIf *externalInt
 
 		extIf.IPAddresses = append(extIf.IPAddresses, ipNet)
 
-		log.Printf("[net] Deleting IP address %v from interface %v.", ipNet, hostIf.Name)
+		logger.Infof("Deleting IP address %v from interface %v.", ipNet, hostIf.Name)
 
 		err = nm.netlink.DeleteIPAddress(hostIf.Name, ipAddr, ipNet)
 		if err != nil {
@@ -196,7 +235,7 @@ func (nm *networkManager) saveIPConfig(hostIf *net.Interface, extIf *externalInt
 		}
 	}
 
-	log.Printf("[net] Saved interface IP configuration %+v.", extIf)
+	logger.Infof("Saved interface IP configuration %+v.", extIf)
 
 	return err
 }
@@ -218,11 +257,11 @@ func getMajorVersion(version string) (int, error) {
 func isGreaterOrEqaulUbuntuVersion(versionToMatch int) bool {
 	osInfo, err := platform.GetOSDetails()
 	if err != nil {
-		log.Printf("[net] Unable to get OS Details: %v", err)
+		logger.Infof("Unable to get OS Details: %v", err)
 		return false
 	}
 
-	log.Printf("[net] OSInfo: %+v", osInfo)
+	logger.Infof("OSInfo: %+v", osInfo)
 
 	version := osInfo[versionID]
 	distro := osInfo[distroID]
@@ -231,7 +270,7 @@ func isGreaterOrEqaulUbuntuVersion(versionToMatch int) bool {
 		version = strings.Trim(version, "\"")
 		retrieved_version, err := getMajorVersion(version)
 		if err != nil {
-			log.Printf("[net] Not setting dns. Unable to retrieve major version: %v", err)
+			logger.Infof("Not setting dns. Unable to retrieve major version: %v", err)
 			return false
 		}
 
@@ -253,7 +292,7 @@ func readDnsInfo(ifName string) (DNSInfo, error) {
 		return dnsInfo, err
 	}
 
-	log.Printf("[net] console output for above cmd: %s", out)
+	logger.Infof("console output for above cmd: %s", out)
 
 	lineArr := strings.Split(out, lineDelimiter)
 	if len(lineArr) <= 0 {
@@ -292,12 +331,12 @@ func readDnsInfo(ifName string) (DNSInfo, error) {
 func saveDnsConfig(extIf *externalInterface) error {
 	dnsInfo, err := readDnsInfo(extIf.Name)
 	if err != nil || len(dnsInfo.Servers) == 0 || dnsInfo.Suffix == "" {
-		log.Printf("[net] Failed to read dns info %+v from interface %v: %v", dnsInfo, extIf.Name, err)
+		logger.Infof("Failed to read dns info %+v from interface %v: %v", dnsInfo, extIf.Name, err)
 		return err
 	}
 
 	extIf.DNSInfo = dnsInfo
-	log.Printf("[net] Saved DNS Info %v from %v", extIf.DNSInfo, extIf.Name)
+	logger.Infof("Saved DNS Info %v from %v", extIf.DNSInfo, extIf.Name)
 
 	return nil
 }
@@ -306,11 +345,11 @@ func saveDnsConfig(extIf *externalInterface) error {
 func (nm *networkManager) applyIPConfig(extIf *externalInterface, targetIf *net.Interface) error {
 	// Add IP addresses.
 	for _, addr := range extIf.IPAddresses {
-		log.Printf("[net] Adding IP address %v to interface %v.", addr, targetIf.Name)
+		logger.Infof("Adding IP address %v to interface %v.", addr, targetIf.Name)
 
 		err := nm.netlink.AddIPAddress(targetIf.Name, addr.IP, addr)
 		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "file exists") {
-			log.Printf("[net] Failed to add IP address %v: %v.", addr, err)
+			logger.Infof("Failed to add IP address %v: %v.", addr, err)
 			return err
 		}
 	}
@@ -319,11 +358,11 @@ func (nm *networkManager) applyIPConfig(extIf *externalInterface, targetIf *net.
 	for _, route := range extIf.Routes {
 		route.LinkIndex = targetIf.Index
 
-		log.Printf("[net] Adding IP route %+v.", route)
+		logger.Infof("Adding IP route %+v.", route)
 
 		err := nm.netlink.AddIPRoute((*netlink.Route)(route))
 		if err != nil {
-			log.Printf("[net] Failed to add IP route %v: %v.", route, err)
+			logger.Infof("Failed to add IP route %v: %v.", route, err)
 			return err
 		}
 	}
@@ -341,7 +380,7 @@ func applyDnsConfig(extIf *externalInterface, ifName string) error {
 	if extIf != nil {
 		for _, server := range extIf.DNSInfo.Servers {
 			if net.ParseIP(server).To4() == nil {
-				log.Errorf("[net] Invalid dns ip %s.", server)
+				logger.Errorf("Invalid dns ip %s.", server)
 				continue
 			}
 
@@ -374,12 +413,12 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 		networkClient NetworkClient
 	)
 
-	log.Printf("[net] Connecting interface %v.", extIf.Name)
-	defer func() { log.Printf("[net] Connecting interface %v completed with err:%v.", extIf.Name, err) }()
+	logger.Infof("Connecting interface %v.", extIf.Name)
+	defer func() { logger.Infof("Connecting interface %v completed with err:%v.", extIf.Name, err) }()
 
 	// Check whether this interface is already connected.
 	if extIf.BridgeName != "" {
-		log.Printf("[net] Interface is already connected to bridge %v.", extIf.BridgeName)
+		logger.Infof("Interface is already connected to bridge %v.", extIf.BridgeName)
 		return nil
 	}
 
@@ -397,7 +436,7 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 
 	opt, _ := nwInfo.Options[genericData].(map[string]interface{})
 	if opt != nil && opt[VlanIDKey] != nil {
-		networkClient = NewOVSClient(bridgeName, extIf.Name, ovsctl.NewOvsctl(), nm.netlink, nm.plClient)
+		networkClient = NewOVSClient(bridgeName, extIf.Name, ovsctl.New(), nm.netlink, nm.plClient)
 	} else {
 		networkClient = NewLinuxBridgeClient(bridgeName, extIf.Name, *nwInfo, nm.netlink, nm.plClient)
 	}
@@ -408,7 +447,7 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 	if err != nil {
 		// Create the bridge.
 		if err = networkClient.CreateBridge(); err != nil {
-			log.Printf("Error while creating bridge %+v", err)
+			logger.Infof("Error while creating bridge %+v", err)
 			return err
 		}
 
@@ -418,12 +457,12 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 		}
 	} else {
 		// Use the existing bridge.
-		log.Printf("[net] Found existing bridge %v.", bridgeName)
+		logger.Infof("Found existing bridge %v.", bridgeName)
 	}
 
 	defer func() {
 		if err != nil {
-			log.Printf("[net] cleanup network")
+			logger.Infof("cleanup network")
 			nm.disconnectExternalInterface(extIf, networkClient)
 		}
 	}()
@@ -431,7 +470,7 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 	// Save host IP configuration.
 	err = nm.saveIPConfig(hostIf, extIf)
 	if err != nil {
-		log.Printf("[net] Failed to save IP configuration for interface %v: %v.", hostIf.Name, err)
+		logger.Infof("Failed to save IP configuration for interface %v: %v.", hostIf.Name, err)
 	}
 
 	/*
@@ -444,36 +483,36 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 		// Don't copy dns servers if systemd-resolved isn't available
 		if _, cmderr := p.ExecuteCommand("systemctl status systemd-resolved"); cmderr == nil {
 			isSystemdResolvedActive = true
-			log.Printf("[net] Saving dns config from %v", extIf.Name)
+			logger.Infof("Saving dns config from %v", extIf.Name)
 			if err = saveDnsConfig(extIf); err != nil {
-				log.Printf("[net] Failed to save dns config: %v", err)
+				logger.Infof("Failed to save dns config: %v", err)
 				return err
 			}
 		}
 	}
 
 	// External interface down.
-	log.Printf("[net] Setting link %v state down.", hostIf.Name)
+	logger.Infof("Setting link %v state down.", hostIf.Name)
 	err = nm.netlink.SetLinkState(hostIf.Name, false)
 	if err != nil {
 		return err
 	}
 
 	// Connect the external interface to the bridge.
-	log.Printf("[net] Setting link %v master %v.", hostIf.Name, bridgeName)
+	logger.Infof("Setting link %v master %v.", hostIf.Name, bridgeName)
 	if err = networkClient.SetBridgeMasterToHostInterface(); err != nil {
 		return err
 	}
 
 	// External interface up.
-	log.Printf("[net] Setting link %v state up.", hostIf.Name)
+	logger.Infof("Setting link %v state up.", hostIf.Name)
 	err = nm.netlink.SetLinkState(hostIf.Name, true)
 	if err != nil {
 		return err
 	}
 
 	// Bridge up.
-	log.Printf("[net] Setting link %v state up.", bridgeName)
+	logger.Infof("Setting link %v state up.", bridgeName)
 	err = nm.netlink.SetLinkState(bridgeName, true)
 	if err != nil {
 		return err
@@ -487,7 +526,7 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 
 	// External interface hairpin on.
 	if !nwInfo.DisableHairpinOnHostInterface {
-		log.Printf("[net] Setting link %v hairpin on.", hostIf.Name)
+		logger.Infof("Setting link %v hairpin on.", hostIf.Name)
 		if err = networkClient.SetHairpinOnHostInterface(true); err != nil {
 			return err
 		}
@@ -496,90 +535,87 @@ func (nm *networkManager) connectExternalInterface(extIf *externalInterface, nwI
 	// Apply IP configuration to the bridge for host traffic.
 	err = nm.applyIPConfig(extIf, bridge)
 	if err != nil {
-		log.Printf("[net] Failed to apply interface IP configuration: %v.", err)
+		logger.Infof("Failed to apply interface IP configuration: %v.", err)
 		return err
 	}
 
 	if isGreaterOrEqualUbuntu17 && isSystemdResolvedActive {
-		log.Printf("[net] Applying dns config on %v", bridgeName)
+		logger.Infof("Applying dns config on %v", bridgeName)
 
 		if err = applyDnsConfig(extIf, bridgeName); err != nil {
-			log.Printf("[net] Failed to apply DNS configuration: %v.", err)
+			logger.Infof("Failed to apply DNS configuration: %v.", err)
 			return err
 		}
 
-		log.Printf("[net] Applied dns config %v on %v", extIf.DNSInfo, bridgeName)
+		logger.Infof("Applied dns config %v on %v", extIf.DNSInfo, bridgeName)
 	}
 
 	if nwInfo.IPV6Mode == IPV6Nat {
 		// adds pod cidr gateway ip to bridge
 		if err = nm.addIpv6NatGateway(nwInfo); err != nil {
-			log.Errorf("[net] Adding IPv6 Nat Gateway failed:%v", err)
+			logger.Errorf("Adding IPv6 Nat Gateway failed:%v", err)
 			return err
 		}
 
 		if err = nm.addIpv6SnatRule(extIf, nwInfo); err != nil {
-			log.Errorf("[net] Adding IPv6 Snat Rule failed:%v", err)
+			logger.Errorf("Adding IPv6 Snat Rule failed:%v", err)
 			return err
 		}
 
 		// unmark packet if set by kube-proxy to skip kube-postrouting rule and processed
 		// by cni snat rule
-		if err = iptables.InsertIptableRule(iptables.V6, iptables.Mangle, iptables.Postrouting, "", "MARK --set-mark 0x0"); err != nil {
-			log.Errorf("[net] Adding Iptable mangle rule failed:%v", err)
+		if err = iptables.NewTransaction().InsertRule(iptables.V6, iptables.Mangle, iptables.Postrouting, "", "MARK --set-mark 0x0").Apply(); err != nil {
+			logger.Errorf("Adding Iptable mangle rule failed:%v", err)
 			return err
 		}
 	}
 
 	extIf.BridgeName = bridgeName
-	log.Printf("[net] Connected interface %v to bridge %v.", extIf.Name, extIf.BridgeName)
+	logger.Infof("Connected interface %v to bridge %v.", extIf.Name, extIf.BridgeName)
 
 	return nil
 }
 
 // DisconnectExternalInterface disconnects a host interface from its bridge.
 func (nm *networkManager) disconnectExternalInterface(extIf *externalInterface, networkClient NetworkClient) {
-	log.Printf("[net] Disconnecting interface %v.", extIf.Name)
+	logger.Infof("Disconnecting interface %v.", extIf.Name)
 
-	log.Printf("[net] Deleting bridge rules")
+	logger.Infof("Deleting bridge rules")
 	// Delete bridge rules set on the external interface.
 	networkClient.DeleteL2Rules(extIf)
 
-	log.Printf("[net] Deleting bridge")
+	logger.Infof("Deleting bridge")
 	// Delete Bridge
 	networkClient.DeleteBridge()
 
 	extIf.BridgeName = ""
-	log.Printf("Restoring ipconfig with primary interface %v", extIf.Name)
+	logger.Infof("Restoring ipconfig with primary interface %v", extIf.Name)
 
 	// Restore IP configuration.
 	hostIf, _ := net.InterfaceByName(extIf.Name)
 	err := nm.applyIPConfig(extIf, hostIf)
 	if err != nil {
-		log.Printf("[net] Failed to apply IP configuration: %v.", err)
+		logger.Infof("Failed to apply IP configuration: %v.", err)
 	}
 
 	extIf.IPAddresses = nil
 	extIf.Routes = nil
 
-	log.Printf("[net] Disconnected interface %v.", extIf.Name)
+	logger.Infof("Disconnected interface %v.", extIf.Name)
 }
 
 func (*networkManager) addToIptables(cmds []iptables.IPTableEntry) error {
-	log.Printf("Adding additional iptable rules...")
-	for _, cmd := range cmds {
-		err := iptables.RunCmd(cmd.Version, cmd.Params)
-		if err != nil {
-			return err
-		}
-		log.Printf("Succesfully run iptables rule %v", cmd)
+	logger.Infof("Adding additional iptable rules...")
+	if err := iptables.NewTransaction().Add(cmds...).Apply(); err != nil {
+		return err
 	}
+	logger.Infof("Succesfully run iptables rules %v", cmds)
 	return nil
 }
 
 // Add ipv6 nat gateway IP on bridge
 func (nm *networkManager) addIpv6NatGateway(nwInfo *NetworkInfo) error {
-	log.Printf("[net] Adding ipv6 nat gateway on azure bridge")
+	logger.Infof("Adding ipv6 nat gateway on azure bridge")
 	for _, subnetInfo := range nwInfo.Subnets {
 		if subnetInfo.Family == platform.AfINET6 {
 			ipAddr := []net.IPNet{{
@@ -617,7 +653,7 @@ func (*networkManager) addIpv6SnatRule(extIf *externalInterface, nwInfo *Network
 
 	for _, ipAddr := range extIf.IPAddresses {
 		if ipAddr.IP.To4() == nil {
-			log.Printf("[net] Adding ipv6 snat rule")
+			logger.Infof("Adding ipv6 snat rule")
 			matchSrcPrefix := fmt.Sprintf("-s %s", ipv6SubnetPrefix.String())
 			if err := networkutils.AddSnatRule(matchSrcPrefix, ipAddr.IP); err != nil {
 				return fmt.Errorf("Adding iptable snat rule failed:%w", err)
@@ -643,7 +679,7 @@ func getNetworkInfoImpl(nwInfo *NetworkInfo, nw *network) {
 
 // AddStaticRoute adds a static route to the interface.
 func AddStaticRoute(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, ip, interfaceName string) error {
-	log.Printf("[ovs] Adding %v static route", ip)
+	logger.Infof("[ovs] Adding %v static route", ip)
 	var routes []RouteInfo
 	_, ipNet, _ := net.ParseCIDR(ip)
 	gwIP := net.ParseIP("0.0.0.0")
@@ -651,7 +687,7 @@ func AddStaticRoute(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface,
 	routes = append(routes, route)
 	if err := addRoutes(nl, netioshim, interfaceName, routes); err != nil {
 		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "file exists") {
-			log.Printf("addroutes failed with error %v", err)
+			logger.Infof("addroutes failed with error %v", err)
 			return err
 		}
 	}
@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkMTU(t *testing.T) {
+	extIf := &externalInterface{Name: "eth0"}
+
+	tests := []struct {
+		name    string
+		nwInfo  *NetworkInfo
+		vlanid  int
+		netio   netio.NetIOInterface
+		wantMTU int
+	}{
+		{
+			name:    "Configured MTU",
+			nwInfo:  &NetworkInfo{Mode: opModeTransparentVlan, MTU: 1400},
+			netio:   netio.NewMockNetIO(false, 0),
+			wantMTU: 1400,
+		},
+		{
+			name:    "MTU of the master interface",
+			nwInfo:  &NetworkInfo{Mode: opModeTransparent},
+			netio:   netio.NewMockNetIO(false, 0),
+			wantMTU: 1000,
+		},
+		{
+			name:    "MTU of the master interface minus the VLAN tag in transparent vlan mode",
+			nwInfo:  &NetworkInfo{Mode: opModeTransparentVlan},
+			netio:   netio.NewMockNetIO(false, 0),
+			wantMTU: 1000 - vlanHeaderLength,
+		},
+		{
+			name:    "MTU of the master interface minus the VLAN tag with a network VLAN",
+			nwInfo:  &NetworkInfo{Mode: opModeBridge},
+			vlanid:  10,
+			netio:   netio.NewMockNetIO(false, 0),
+			wantMTU: 1000 - vlanHeaderLength,
+		},
+		{
+			name:    "Default MTU when the master interface is not found",
+			nwInfo:  &NetworkInfo{Mode: opModeBridge},
+			netio:   netio.NewMockNetIO(true, 1),
+			wantMTU: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			nm := &networkManager{netio: tt.netio}
+			require.Equal(t, tt.wantMTU, nm.networkMTU(tt.nwInfo, extIf, tt.vlanid))
+		})
+	}
+}
+
+// offloadNetlink records the offloads set through a MockNetlink.
+type offloadNetlink struct {
+	*netlink.MockNetlink
+	offloads map[netlink.OffloadFeature]bool
+}
+
+func (nl *offloadNetlink) SetLinkOffload(name string, feature netlink.OffloadFeature, on bool) error {
+	nl.offloads[feature] = on
+	return nl.MockNetlink.SetLinkOffload(name, feature, on)
+}
+
+func TestSetLinkOffloads(t *testing.T) {
+	on, off := true, false
+	nl := &offloadNetlink{MockNetlink: netlink.NewMockNetlink(false, ""), offloads: map[netlink.OffloadFeature]bool{}}
+
+	require.NoError(t, setLinkOffloads(nl, "eth0", OffloadSettings{}))
+	require.Empty(t, nl.offloads)
+
+	require.NoError(t, setLinkOffloads(nl, "eth0", OffloadSettings{GSO: &off, GRO: &on}))
+	require.Equal(t, map[netlink.OffloadFeature]bool{netlink.OffloadGSO: false, netlink.OffloadGRO: true}, nl.offloads)
+
+	require.Error(t, setLinkOffloads(netlink.NewMockNetlink(true, "ethtool fail"), "eth0", OffloadSettings{TSO: &off}))
+}
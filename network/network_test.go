@@ -4,6 +4,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/platform"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -165,6 +166,7 @@ var _ = Describe("Test Network", func() {
 				nm := &networkManager{
 					ExternalInterfaces: map[string]*externalInterface{},
 					plClient:           platform.NewMockExecClient(false),
+					netio:              netio.NewMockNetIO(false, 0),
 				}
 				nm.ExternalInterfaces["eth0"] = &externalInterface{
 					Networks: map[string]*network{},
@@ -13,7 +13,6 @@ import (
 
 	"github.com/Azure/azure-container-networking/network/hnswrapper"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/network/policy"
 	"github.com/Microsoft/hcsshim"
 	"github.com/Microsoft/hcsshim/hcn"
@@ -50,7 +49,7 @@ func UseHnsV2(netNs string) (bool, error) {
 	if _, err = uuid.Parse(netNs); err == nil {
 		useHnsV2 = true
 		if err = hcn.V2ApiSupported(); err != nil {
-			log.Printf("HNSV2 is not supported on this windows platform")
+			logger.Infof("HNSV2 is not supported on this windows platform")
 		}
 	}
 
@@ -95,11 +94,11 @@ func (nm *networkManager) newNetworkImplHnsV1(nwInfo *NetworkInfo, extIf *extern
 	// FixMe: Find a better way to check if a nic that is selected is not part of a vSwitch
 	// per hns team, the hns calls fails if passed a vSwitch interface
 	if strings.HasPrefix(networkAdapterName, vEthernetAdapterPrefix) {
-		log.Printf("[net] vSwitch detected, setting adapter name to empty")
+		logger.Infof("vSwitch detected, setting adapter name to empty")
 		networkAdapterName = ""
 	}
 
-	log.Printf("[net] Adapter name used with HNS is : %s", networkAdapterName)
+	logger.Infof("Adapter name used with HNS is : %s", networkAdapterName)
 
 	// Initialize HNS network.
 	hnsNetwork := &hcsshim.HNSNetwork{
@@ -151,9 +150,9 @@ func (nm *networkManager) newNetworkImplHnsV1(nwInfo *NetworkInfo, extIf *extern
 
 	defer func() {
 		if err != nil {
-			log.Printf("[net] HNSNetworkRequest DELETE id:%v", hnsResponse.Id)
+			logger.Infof("HNSNetworkRequest DELETE id:%v", hnsResponse.Id)
 			hnsResponse, err := Hnsv1.DeleteNetwork(hnsResponse.Id)
-			log.Printf("[net] HNSNetworkRequest DELETE response:%+v err:%v.", hnsResponse, err)
+			logger.Infof("HNSNetworkRequest DELETE response:%+v err:%v.", hnsResponse, err)
 		}
 	}()
 
@@ -205,13 +204,13 @@ func (nm *networkManager) appIPV6RouteEntry(nwInfo *NetworkInfo) error {
 		cmd := fmt.Sprintf(routeCmd, "delete", nwInfo.Subnets[1].Prefix.String(),
 			ifName, ipv6DefaultHop)
 		if out, err = nm.plClient.ExecuteCommand(cmd); err != nil {
-			log.Printf("[net] Deleting ipv6 route failed: %v:%v", out, err)
+			logger.Infof("Deleting ipv6 route failed: %v:%v", out, err)
 		}
 
 		cmd = fmt.Sprintf(routeCmd, "add", nwInfo.Subnets[1].Prefix.String(),
 			ifName, ipv6DefaultHop)
 		if out, err = nm.plClient.ExecuteCommand(cmd); err != nil {
-			log.Printf("[net] Adding ipv6 route failed: %v:%v", out, err)
+			logger.Infof("Adding ipv6 route failed: %v:%v", out, err)
 		}
 	}
 
@@ -251,11 +250,11 @@ func (nm *networkManager) configureHcnNetwork(nwInfo *NetworkInfo, extIf *extern
 			adapterName = extIf.Name
 		}
 
-		log.Printf("[net] Adapter name used with HNS is : %s", adapterName)
+		logger.Infof("Adapter name used with HNS is : %s", adapterName)
 
 		netAdapterNamePolicy, err := policy.GetHcnNetAdapterPolicy(adapterName)
 		if err != nil {
-			log.Printf("[net] Failed to serialize network adapter policy due to error: %v", err)
+			logger.Infof("Failed to serialize network adapter policy due to error: %v", err)
 			return nil, err
 		}
 
@@ -274,7 +273,7 @@ func (nm *networkManager) configureHcnNetwork(nwInfo *NetworkInfo, extIf *extern
 		vlanID, _ := strconv.ParseUint(opt[VlanIDKey].(string), baseDecimal, bitSize)
 		subnetPolicy, err = policy.SerializeHcnSubnetVlanPolicy((uint32)(vlanID))
 		if err != nil {
-			log.Printf("[net] Failed to serialize subnet vlan policy due to error: %v", err)
+			logger.Infof("Failed to serialize subnet vlan policy due to error: %v", err)
 			return nil, err
 		}
 
@@ -319,7 +318,7 @@ func (nm *networkManager) configureHcnNetwork(nwInfo *NetworkInfo, extIf *extern
 func (nm *networkManager) newNetworkImplHnsV2(nwInfo *NetworkInfo, extIf *externalInterface) (*network, error) {
 	hcnNetwork, err := nm.configureHcnNetwork(nwInfo, extIf)
 	if err != nil {
-		log.Printf("[net] Failed to configure hcn network due to error: %v", err)
+		logger.Infof("Failed to configure hcn network due to error: %v", err)
 		return nil, err
 	}
 
@@ -329,20 +328,20 @@ func (nm *networkManager) newNetworkImplHnsV2(nwInfo *NetworkInfo, extIf *extern
 	if err != nil {
 		// if network not found, create the HNS network.
 		if errors.As(err, &hcn.NetworkNotFoundError{}) {
-			log.Printf("[net] Creating hcn network: %+v", hcnNetwork)
+			logger.Infof("Creating hcn network: %+v", hcnNetwork)
 			hnsResponse, err = Hnsv2.CreateNetwork(hcnNetwork)
 
 			if err != nil {
 				return nil, fmt.Errorf("Failed to create hcn network: %s due to error: %v", hcnNetwork.Name, err)
 			}
 
-			log.Printf("[net] Successfully created hcn network with response: %+v", hnsResponse)
+			logger.Infof("Successfully created hcn network with response: %+v", hnsResponse)
 		} else {
 			// we can't validate if the network already exists, don't continue
 			return nil, fmt.Errorf("Failed to create hcn network: %s, failed to query for existing network with error: %v", hcnNetwork.Name, err)
 		}
 	} else {
-		log.Printf("[net] Network with name %s already exists", hcnNetwork.Name)
+		logger.Infof("Network with name %s already exists", hcnNetwork.Name)
 	}
 
 	var vlanid int
@@ -391,9 +390,9 @@ func (nm *networkManager) deleteNetworkImpl(nw *network) error {
 
 // DeleteNetworkImplHnsV1 deletes an existing container network using HnsV1.
 func (nm *networkManager) deleteNetworkImplHnsV1(nw *network) error {
-	log.Printf("[net] HNSNetworkRequest DELETE id:%v", nw.HnsId)
+	logger.Infof("HNSNetworkRequest DELETE id:%v", nw.HnsId)
 	hnsResponse, err := Hnsv1.DeleteNetwork(nw.HnsId)
-	log.Printf("[net] HNSNetworkRequest DELETE response:%+v err:%v.", hnsResponse, err)
+	logger.Infof("HNSNetworkRequest DELETE response:%+v err:%v.", hnsResponse, err)
 
 	return err
 }
@@ -402,7 +401,7 @@ func (nm *networkManager) deleteNetworkImplHnsV1(nw *network) error {
 func (nm *networkManager) deleteNetworkImplHnsV2(nw *network) error {
 	var hcnNetwork *hcn.HostComputeNetwork
 	var err error
-	log.Printf("[net] Deleting hcn network with id: %s", nw.HnsId)
+	logger.Infof("Deleting hcn network with id: %s", nw.HnsId)
 
 	if hcnNetwork, err = Hnsv2.GetNetworkByID(nw.HnsId); err != nil {
 		return fmt.Errorf("Failed to get hcn network with id: %s due to err: %v", nw.HnsId, err)
@@ -412,7 +411,7 @@ func (nm *networkManager) deleteNetworkImplHnsV2(nw *network) error {
 		return fmt.Errorf("Failed to delete hcn network: %s due to error: %v", nw.HnsId, err)
 	}
 
-	log.Printf("[net] Successfully deleted hcn network with id: %s", nw.HnsId)
+	logger.Infof("Successfully deleted hcn network with id: %s", nw.HnsId)
 
 	return err
 }
@@ -126,8 +126,7 @@ func (nu NetworkUtils) AssignIPToInterface(interfaceName string, ipAddresses []n
 	return nil
 }
 
-func addOrDeleteFilterRule(bridgeName, action, ipAddress, chainName, target string) error {
-	var err error
+func addOrDeleteFilterRule(tx *iptables.Transaction, bridgeName, action, ipAddress, chainName, target string) {
 	option := "i"
 
 	if chainName == iptables.Output {
@@ -138,62 +137,45 @@ func addOrDeleteFilterRule(bridgeName, action, ipAddress, chainName, target stri
 
 	switch action {
 	case iptables.Insert:
-		err = iptables.InsertIptableRule(iptables.V4, iptables.Filter, chainName, matchCondition, target)
+		tx.InsertRule(iptables.V4, iptables.Filter, chainName, matchCondition, target)
 	case iptables.Append:
-		err = iptables.AppendIptableRule(iptables.V4, iptables.Filter, chainName, matchCondition, target)
+		tx.AppendRule(iptables.V4, iptables.Filter, chainName, matchCondition, target)
 	case iptables.Delete:
-		err = iptables.DeleteIptableRule(iptables.V4, iptables.Filter, chainName, matchCondition, target)
+		tx.DeleteRule(iptables.V4, iptables.Filter, chainName, matchCondition, target)
 	}
-
-	return err
 }
 
 func AllowIPAddresses(bridgeName string, skipAddresses []string, action string) error {
 	chains := getFilterChains()
 	target := getFilterchainTarget()
+	tx := iptables.NewTransaction()
 
 	log.Printf("[net] Addresses to allow %v", skipAddresses)
 
 	for _, address := range skipAddresses {
-		if err := addOrDeleteFilterRule(bridgeName, action, address, chains[0], target[0]); err != nil {
-			return err
-		}
-
-		if err := addOrDeleteFilterRule(bridgeName, action, address, chains[1], target[0]); err != nil {
-			return err
-		}
-
-		if err := addOrDeleteFilterRule(bridgeName, action, address, chains[2], target[0]); err != nil {
-			return err
-		}
-
+		addOrDeleteFilterRule(tx, bridgeName, action, address, chains[0], target[0])
+		addOrDeleteFilterRule(tx, bridgeName, action, address, chains[1], target[0])
+		addOrDeleteFilterRule(tx, bridgeName, action, address, chains[2], target[0])
 	}
 
-	return nil
+	return tx.Apply()
 }
 
 func BlockIPAddresses(bridgeName, action string) error {
 	privateIPAddresses := getPrivateIPSpace()
 	chains := getFilterChains()
 	target := getFilterchainTarget()
+	tx := iptables.NewTransaction()
 
 	log.Printf("[net] Addresses to block %v", privateIPAddresses)
 
 	for _, ipAddress := range privateIPAddresses {
-		if err := addOrDeleteFilterRule(bridgeName, action, ipAddress, chains[0], target[1]); err != nil {
-			return err
-		}
-
-		if err := addOrDeleteFilterRule(bridgeName, action, ipAddress, chains[1], target[1]); err != nil {
-			return err
-		}
-
-		if err := addOrDeleteFilterRule(bridgeName, action, ipAddress, chains[2], target[1]); err != nil {
-			return err
-		}
+		addOrDeleteFilterRule(tx, bridgeName, action, ipAddress, chains[0], target[1])
+		addOrDeleteFilterRule(tx, bridgeName, action, ipAddress, chains[1], target[1])
+		addOrDeleteFilterRule(tx, bridgeName, action, ipAddress, chains[2], target[1])
 	}
 
-	return nil
+	return tx.Apply()
 }
 
 // This fucntion enables ip forwarding in VM and allow forwarding packets from the interface
@@ -208,7 +190,7 @@ func (nu NetworkUtils) EnableIPForwarding(ifName string) error {
 	}
 
 	// Append a rule in forward chain to allow forwarding from bridge
-	if err := iptables.AppendIptableRule(iptables.V4, iptables.Filter, iptables.Forward, "", iptables.Accept); err != nil {
+	if err := iptables.NewTransaction().AppendRule(iptables.V4, iptables.Filter, iptables.Forward, "", iptables.Accept).Apply(); err != nil {
 		log.Printf("[net] Appending forward chain rule: allow traffic coming from snatbridge failed with: %v", err)
 		return err
 	}
@@ -247,7 +229,7 @@ func AddSnatRule(match string, ip net.IP) error {
 	}
 
 	target := fmt.Sprintf("SNAT --to %s", ip.String())
-	return iptables.InsertIptableRule(version, iptables.Nat, iptables.Postrouting, match, target)
+	return iptables.NewTransaction().InsertRule(version, iptables.Nat, iptables.Postrouting, match, target).Apply()
 }
 
 func (nu NetworkUtils) DisableRAForInterface(ifName string) error {
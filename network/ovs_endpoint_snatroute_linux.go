@@ -3,7 +3,6 @@ package network
 import (
 	"net"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
 	"github.com/Azure/azure-container-networking/network/snat"
@@ -29,6 +28,7 @@ func (client *OVSEndpointClient) NewSnatClient(snatBridgeIP, localIP string, epI
 			snatBridgeIP,
 			client.hostPrimaryMac,
 			epInfo.DNS.Servers,
+			epInfo.Id,
 			client.netlink,
 			client.plClient,
 		)
@@ -44,7 +44,7 @@ func (client *OVSEndpointClient) AddSnatEndpoint() error {
 
 		snatClient := client.snatClient
 
-		log.Printf("Drop ARP for snat bridge ip: %s", snatClient.SnatBridgeIP)
+		logger.Infof("Drop ARP for snat bridge ip: %s", snatClient.SnatBridgeIP)
 		if err := client.snatClient.DropArpForSnatBridgeApipaRange(snatClient.SnatBridgeIP, azureSnatVeth0); err != nil {
 			return err
 		}
@@ -53,7 +53,7 @@ func (client *OVSEndpointClient) AddSnatEndpoint() error {
 		// of veth will be attached to linux bridge
 		_, err := net.InterfaceByName(azureSnatVeth0)
 		if err == nil {
-			log.Printf("Azure snat veth already exists")
+			logger.Infof("Azure snat veth already exists")
 			return nil
 		}
 
@@ -67,7 +67,7 @@ func (client *OVSEndpointClient) AddSnatEndpoint() error {
 
 		err = client.netlink.AddLink(&vethLink)
 		if err != nil {
-			log.Printf("[net] Failed to create veth pair, err:%v.", err)
+			logger.Infof("Failed to create veth pair, err:%v.", err)
 			return errors.Wrap(err, "failed to create veth pair")
 		}
 		nuc := networkutils.NewNetworkUtils(client.netlink, client.plClient)
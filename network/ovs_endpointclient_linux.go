@@ -6,7 +6,6 @@ package network
 import (
 	"net"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -83,9 +82,17 @@ func (client *OVSEndpointClient) AddEndpoints(epInfo *EndpointInfo) error {
 		return err
 	}
 
+	if err := setVethMTU(client.netlink, epInfo.MTU, client.hostVethName, client.containerVethName); err != nil {
+		return err
+	}
+
+	if err := setContainerVethMac(client.netlink, epInfo.MacAddress, client.containerVethName); err != nil {
+		return err
+	}
+
 	containerIf, err := net.InterfaceByName(client.containerVethName)
 	if err != nil {
-		log.Printf("InterfaceByName returns error for ifname %v with error %v", client.containerVethName, err)
+		logger.Infof("InterfaceByName returns error for ifname %v with error %v", client.containerVethName, err)
 		return err
 	}
 
@@ -103,22 +110,22 @@ func (client *OVSEndpointClient) AddEndpoints(epInfo *EndpointInfo) error {
 }
 
 func (client *OVSEndpointClient) AddEndpointRules(epInfo *EndpointInfo) error {
-	log.Printf("[ovs] Setting link %v master %v.", client.hostVethName, client.bridgeName)
+	logger.Infof("[ovs] Setting link %v master %v.", client.hostVethName, client.bridgeName)
 	if err := client.ovsctlClient.AddPortOnOVSBridge(client.hostVethName, client.bridgeName, client.vlanID); err != nil {
 		return err
 	}
 
-	log.Printf("[ovs] Get ovs port for interface %v.", client.hostVethName)
+	logger.Infof("[ovs] Get ovs port for interface %v.", client.hostVethName)
 	containerOVSPort, err := client.ovsctlClient.GetOVSPortNumber(client.hostVethName)
 	if err != nil {
-		log.Printf("[ovs] Get ofport failed with error %v", err)
+		logger.Infof("[ovs] Get ofport failed with error %v", err)
 		return err
 	}
 
-	log.Printf("[ovs] Get ovs port for interface %v.", client.hostPrimaryIfName)
+	logger.Infof("[ovs] Get ovs port for interface %v.", client.hostPrimaryIfName)
 	hostPort, err := client.ovsctlClient.GetOVSPortNumber(client.hostPrimaryIfName)
 	if err != nil {
-		log.Printf("[ovs] Get ofport failed with error %v", err)
+		logger.Infof("[ovs] Get ofport failed with error %v", err)
 		return err
 	}
 
@@ -132,14 +139,14 @@ func (client *OVSEndpointClient) AddEndpointRules(epInfo *EndpointInfo) error {
 		// IP SNAT Rule - Change src mac to VM Mac for packets coming from container host veth port.
 		// This rule also checks if packets coming from right source ip based on the ovs port to prevent ip spoofing.
 		// Otherwise it drops the packet.
-		log.Printf("[ovs] Adding IP SNAT rule for egress traffic on %v.", containerOVSPort)
+		logger.Infof("[ovs] Adding IP SNAT rule for egress traffic on %v.", containerOVSPort)
 		if err := client.ovsctlClient.AddIPSnatRule(client.bridgeName, ipAddr.IP, client.vlanID, containerOVSPort, client.hostPrimaryMac, hostPort); err != nil {
 			return err
 		}
 
 		// Add IP DNAT rule based on dst ip and vlanid - This rule changes the destination mac to corresponding container mac based on the ip and
 		// forwards the packet to corresponding container hostveth port
-		log.Printf("[ovs] Adding MAC DNAT rule for IP address %v on hostport %v, containerport: %v", ipAddr.IP.String(), hostPort, containerOVSPort)
+		logger.Infof("[ovs] Adding MAC DNAT rule for IP address %v on hostport %v, containerport: %v", ipAddr.IP.String(), hostPort, containerOVSPort)
 		if err := client.ovsctlClient.AddMacDnatRule(client.bridgeName, hostPort, ipAddr.IP, client.containerMac, client.vlanID, containerOVSPort); err != nil {
 			return err
 		}
@@ -153,34 +160,34 @@ func (client *OVSEndpointClient) AddEndpointRules(epInfo *EndpointInfo) error {
 }
 
 func (client *OVSEndpointClient) DeleteEndpointRules(ep *endpoint) {
-	log.Printf("[ovs] Get ovs port for interface %v.", ep.HostIfName)
+	logger.Infof("[ovs] Get ovs port for interface %v.", ep.HostIfName)
 	containerPort, err := client.ovsctlClient.GetOVSPortNumber(client.hostVethName)
 	if err != nil {
-		log.Printf("[ovs] Get portnum failed with error %v", err)
+		logger.Infof("[ovs] Get portnum failed with error %v", err)
 	}
 
-	log.Printf("[ovs] Get ovs port for interface %v.", client.hostPrimaryIfName)
+	logger.Infof("[ovs] Get ovs port for interface %v.", client.hostPrimaryIfName)
 	hostPort, err := client.ovsctlClient.GetOVSPortNumber(client.hostPrimaryIfName)
 	if err != nil {
-		log.Printf("[ovs] Get portnum failed with error %v", err)
+		logger.Infof("[ovs] Get portnum failed with error %v", err)
 	}
 
 	// Delete IP SNAT
-	log.Printf("[ovs] Deleting IP SNAT for port %v", containerPort)
+	logger.Infof("[ovs] Deleting IP SNAT for port %v", containerPort)
 	client.ovsctlClient.DeleteIPSnatRule(client.bridgeName, containerPort)
 
 	// Delete Arp Reply Rules for container
-	log.Printf("[ovs] Deleting ARP reply rule for ip %v vlanid %v for container port %v", ep.IPAddresses[0].IP.String(), ep.VlanID, containerPort)
+	logger.Infof("[ovs] Deleting ARP reply rule for ip %v vlanid %v for container port %v", ep.IPAddresses[0].IP.String(), ep.VlanID, containerPort)
 	client.ovsctlClient.DeleteArpReplyRule(client.bridgeName, containerPort, ep.IPAddresses[0].IP, ep.VlanID)
 
 	// Delete MAC address translation rule.
-	log.Printf("[ovs] Deleting MAC DNAT rule for IP address %v and vlan %v.", ep.IPAddresses[0].IP.String(), ep.VlanID)
+	logger.Infof("[ovs] Deleting MAC DNAT rule for IP address %v and vlan %v.", ep.IPAddresses[0].IP.String(), ep.VlanID)
 	client.ovsctlClient.DeleteMacDnatRule(client.bridgeName, hostPort, ep.IPAddresses[0].IP, ep.VlanID)
 
 	// Delete port from ovs bridge
-	log.Printf("[ovs] Deleting interface %v from bridge %v", client.hostVethName, client.bridgeName)
+	logger.Infof("[ovs] Deleting interface %v from bridge %v", client.hostVethName, client.bridgeName)
 	if err := client.ovsctlClient.DeletePortFromOVS(client.bridgeName, client.hostVethName); err != nil {
-		log.Printf("[ovs] Deletion of interface %v from bridge %v failed", client.hostVethName, client.bridgeName)
+		logger.Infof("[ovs] Deletion of interface %v from bridge %v failed", client.hostVethName, client.bridgeName)
 	}
 
 	client.DeleteSnatEndpointRules()
@@ -189,7 +196,7 @@ func (client *OVSEndpointClient) DeleteEndpointRules(ep *endpoint) {
 
 func (client *OVSEndpointClient) MoveEndpointsToContainerNS(epInfo *EndpointInfo, nsID uintptr) error {
 	// Move the container interface to container's network namespace.
-	log.Printf("[ovs] Setting link %v netns %v.", client.containerVethName, epInfo.NetNsPath)
+	logger.Infof("[ovs] Setting link %v netns %v.", client.containerVethName, epInfo.NetNsPath)
 	if err := client.netlink.SetLinkNetNs(client.containerVethName, nsID); err != nil {
 		return err
 	}
@@ -234,13 +241,14 @@ func (client *OVSEndpointClient) ConfigureContainerInterfacesAndRoutes(epInfo *E
 }
 
 func (client *OVSEndpointClient) DeleteEndpoints(ep *endpoint) error {
-	log.Printf("[ovs] Deleting veth pair %v %v.", ep.HostIfName, ep.IfName)
+	logger.Infof("[ovs] Deleting veth pair %v %v.", ep.HostIfName, ep.IfName)
 	err := client.netlink.DeleteLink(ep.HostIfName)
 	if err != nil {
-		log.Printf("[ovs] Failed to delete veth pair %v: %v.", ep.HostIfName, err)
+		logger.Infof("[ovs] Failed to delete veth pair %v: %v.", ep.HostIfName, err)
 		return err
 	}
 
+	deleteConntrackEntries(client.netlink, ep.IPAddresses)
 	if err := client.DeleteSnatEndpoint(); err != nil {
 		return err
 	}
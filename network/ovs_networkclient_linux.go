@@ -10,7 +10,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
 	"github.com/Azure/azure-container-networking/ovsctl"
@@ -40,7 +39,7 @@ const (
 func updateOVSConfig(option string) error {
 	f, err := os.OpenFile(ovsConfigFile, os.O_APPEND|os.O_RDWR, 0o666)
 	if err != nil {
-		log.Printf("Error while opening ovs config %v", err)
+		logger.Infof("Error while opening ovs config %v", err)
 		return err
 	}
 
@@ -53,15 +52,15 @@ func updateOVSConfig(option string) error {
 	conSplit := strings.Split(contents, "\n")
 	for _, existingOption := range conSplit {
 		if option == existingOption {
-			log.Printf("Not updating ovs config. Found option already written")
+			logger.Infof("Not updating ovs config. Found option already written")
 			return nil
 		}
 	}
 
-	log.Printf("writing ovsconfig option %v", option)
+	logger.Infof("writing ovsconfig option %v", option)
 
 	if _, err = f.WriteString(option); err != nil {
-		log.Printf("Error while writing ovs config %v", err)
+		logger.Infof("Error while writing ovs config %v", err)
 		return err
 	}
 
@@ -109,7 +108,7 @@ func (client *OVSNetworkClient) CreateBridge() error {
 
 func (client *OVSNetworkClient) DeleteBridge() error {
 	if err := client.ovsctlClient.DeleteOVSBridge(client.bridgeName); err != nil {
-		log.Printf("Deleting ovs bridge failed with error %v", err)
+		logger.Infof("Deleting ovs bridge failed with error %v", err)
 	}
 
 	return nil
@@ -125,12 +124,12 @@ func (client *OVSNetworkClient) AddL2Rules(extIf *externalInterface) error {
 	}
 
 	// Arp SNAT Rule
-	log.Printf("[ovs] Adding ARP SNAT rule for egress traffic on interface %v", client.hostInterfaceName)
+	logger.Infof("[ovs] Adding ARP SNAT rule for egress traffic on interface %v", client.hostInterfaceName)
 	if err := client.ovsctlClient.AddArpSnatRule(client.bridgeName, mac, macHex, ofport); err != nil {
 		return err
 	}
 
-	log.Printf("[ovs] Adding DNAT rule for ingress ARP traffic on interface %v.", client.hostInterfaceName)
+	logger.Infof("[ovs] Adding DNAT rule for ingress ARP traffic on interface %v.", client.hostInterfaceName)
 	err = client.ovsctlClient.AddArpDnatRule(client.bridgeName, ofport, macHex)
 	if err != nil {
 		return newErrorOVSNetworkClient(err.Error())
@@ -141,7 +140,7 @@ func (client *OVSNetworkClient) AddL2Rules(extIf *externalInterface) error {
 
 func (client *OVSNetworkClient) DeleteL2Rules(extIf *externalInterface) {
 	if err := client.ovsctlClient.DeletePortFromOVS(client.bridgeName, client.hostInterfaceName); err != nil {
-		log.Printf("[ovs] Deletion of interface %v from bridge %v failed", client.hostInterfaceName, client.bridgeName)
+		logger.Infof("[ovs] Deletion of interface %v from bridge %v failed", client.hostInterfaceName, client.bridgeName)
 	}
 }
 
@@ -47,7 +47,7 @@ func NewInfraVnetClient(hostIfName, contIfName string, nl netlink.NetlinkInterfa
 }
 
 func (client *OVSInfraVnetClient) CreateInfraVnetEndpoint(bridgeName string) error {
-	ovs := ovsctl.NewOvsctl()
+	ovs := ovsctl.New()
 	epc := networkutils.NewNetworkUtils(client.netlink, client.plClient)
 	if err := epc.CreateEndpoint(client.hostInfraVethName, client.ContainerInfraVethName, nil); err != nil {
 		log.Printf("Creating infraep failed with error %v", err)
@@ -77,7 +77,7 @@ func (client *OVSInfraVnetClient) CreateInfraVnetRules(
 	hostPrimaryMac string,
 	hostPort string,
 ) error {
-	ovs := ovsctl.NewOvsctl()
+	ovs := ovsctl.New()
 
 	infraContainerPort, err := ovs.GetOVSPortNumber(client.hostInfraVethName)
 	if err != nil {
@@ -134,7 +134,7 @@ func (client *OVSInfraVnetClient) DeleteInfraVnetRules(
 	infraIP net.IPNet,
 	hostPort string,
 ) {
-	ovs := ovsctl.NewOvsctl()
+	ovs := ovsctl.New()
 
 	log.Printf("[ovs] Deleting MAC DNAT rule for infravnet IP address %v", infraIP.IP.String())
 	ovs.DeleteMacDnatRule(bridgeName, hostPort, infraIP.IP, 0)
@@ -0,0 +1,219 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cni/api"
+	"github.com/Azure/azure-container-networking/netns"
+	"github.com/pkg/errors"
+	vishnetlink "github.com/vishvananda/netlink"
+)
+
+const (
+	// Directory of the bind mounts of named network namespaces.
+	namedNetnsPath = "/var/run/netns"
+)
+
+// Names of the per-NC namespaces created by the transparent vlan endpoint client.
+var vnetNSNameRegex = regexp.MustCompile(`^az_ns_[0-9]+$`)
+
+// hostLink is a link in the host namespace.
+type hostLink struct {
+	name     string
+	linkType string
+	// vlanParent and vlanID are only set for vlan links.
+	vlanParent string
+	vlanID     int
+}
+
+// orphanHost lists and removes the host resources checked by ReconcileOrphans.
+type orphanHost interface {
+	listLinks() ([]hostLink, error)
+	listRoutes(ifName string) ([]string, error)
+	listNamedNetns() ([]string, error)
+	deleteLink(name string) error
+	deleteNamedNetns(name string) error
+}
+
+type netlinkOrphanHost struct {
+	netnsClient netnsClient
+}
+
+func (netlinkOrphanHost) listLinks() ([]hostLink, error) {
+	links, err := vishnetlink.LinkList()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list links")
+	}
+	names := make(map[int]string, len(links))
+	for _, link := range links {
+		names[link.Attrs().Index] = link.Attrs().Name
+	}
+
+	hostLinks := make([]hostLink, 0, len(links))
+	for _, link := range links {
+		hl := hostLink{name: link.Attrs().Name, linkType: link.Type()}
+		if vlan, ok := link.(*vishnetlink.Vlan); ok {
+			hl.vlanParent = names[vlan.ParentIndex]
+			hl.vlanID = vlan.VlanId
+		}
+		hostLinks = append(hostLinks, hl)
+	}
+	return hostLinks, nil
+}
+
+func (netlinkOrphanHost) listRoutes(ifName string) ([]string, error) {
+	link, err := vishnetlink.LinkByName(ifName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get link %s", ifName)
+	}
+	routes, err := vishnetlink.RouteList(link, vishnetlink.FAMILY_ALL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list routes of %s", ifName)
+	}
+	descs := make([]string, 0, len(routes))
+	for i := range routes {
+		dst := "default"
+		if routes[i].Dst != nil {
+			dst = routes[i].Dst.String()
+		}
+		descs = append(descs, fmt.Sprintf("%s dev %s table %d", dst, ifName, routes[i].Table))
+	}
+	return descs, nil
+}
+
+func (netlinkOrphanHost) listNamedNetns() ([]string, error) {
+	entries, err := os.ReadDir(namedNetnsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", namedNetnsPath)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+func (netlinkOrphanHost) deleteLink(name string) error {
+	link, err := vishnetlink.LinkByName(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get link %s", name)
+	}
+	return errors.Wrapf(vishnetlink.LinkDel(link), "failed to delete link %s", name)
+}
+
+func (h netlinkOrphanHost) deleteNamedNetns(name string) error {
+	return errors.Wrapf(h.netnsClient.DeleteNamed(name), "failed to delete namespace %s", name)
+}
+
+// ReconcileOrphans reports the host veths, transparent vlan links and per-NC namespaces which belong to no endpoint
+// in the state, e.g. because an ADD failed midway or the state was lost, and removes them if deleteOrphans is set.
+// Routes of removed links go away with them.
+// Since a lost state makes the links of running pods look orphaned, removing them has to be asked for explicitly.
+func (nm *networkManager) ReconcileOrphans(deleteOrphans bool) (*api.OrphanReport, error) {
+	nm.Lock()
+	defer nm.Unlock()
+
+	return nm.reconcileOrphans(netlinkOrphanHost{netnsClient: netns.New()}, deleteOrphans)
+}
+
+func (nm *networkManager) reconcileOrphans(host orphanHost, deleteOrphans bool) (*api.OrphanReport, error) {
+	links, err := host.listLinks()
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := host.listNamedNetns()
+	if err != nil {
+		return nil, err
+	}
+
+	knownLinks, knownNamespaces := nm.getEndpointHostResources()
+	report := &api.OrphanReport{
+		Links:      make([]string, 0),
+		Routes:     make([]string, 0),
+		Namespaces: make([]string, 0),
+	}
+
+	for _, link := range links {
+		if _, ok := knownLinks[link.name]; ok || !isEndpointHostLink(link) {
+			continue
+		}
+		report.Links = append(report.Links, link.name)
+		routes, err := host.listRoutes(link.name)
+		if err != nil {
+			logger.Infof("Failed to list routes of orphaned link %s: %v", link.name, err)
+			continue
+		}
+		report.Routes = append(report.Routes, routes...)
+	}
+	for _, name := range namespaces {
+		if _, ok := knownNamespaces[name]; ok || !vnetNSNameRegex.MatchString(name) {
+			continue
+		}
+		report.Namespaces = append(report.Namespaces, name)
+	}
+
+	if !deleteOrphans {
+		return report, nil
+	}
+
+	var firstErr error
+	for _, name := range report.Links {
+		logger.Infof("Deleting orphaned link %s", name)
+		if err := host.deleteLink(name); err != nil {
+			logger.Errorf("%v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	for _, name := range report.Namespaces {
+		logger.Infof("Deleting orphaned namespace %s", name)
+		if err := host.deleteNamedNetns(name); err != nil {
+			logger.Errorf("%v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return report, firstErr
+}
+
+// getEndpointHostResources returns the names of the host links and namespaces used by the endpoints in the state.
+func (nm *networkManager) getEndpointHostResources() (links, namespaces map[string]struct{}) {
+	links = make(map[string]struct{})
+	namespaces = make(map[string]struct{})
+	for _, extIf := range nm.ExternalInterfaces {
+		for _, nw := range extIf.Networks {
+			for _, ep := range nw.Endpoints {
+				links[ep.HostIfName] = struct{}{}
+				if len(ep.Id) >= 7 {
+					links[snatVethInterfacePrefix+ep.Id[:7]] = struct{}{}
+				}
+				if ep.VlanID != 0 && nw.Mode == opModeTransparentVlan {
+					links[fmt.Sprintf("%s_%d", extIf.Name, ep.VlanID)] = struct{}{}
+					namespaces[fmt.Sprintf("az_ns_%d", ep.VlanID)] = struct{}{}
+				}
+			}
+		}
+	}
+	return links, namespaces
+}
+
+// isEndpointHostLink returns whether a link is named like the host veths or transparent vlan links of endpoints.
+func isEndpointHostLink(link hostLink) bool {
+	switch link.linkType {
+	case "veth":
+		return strings.HasPrefix(link.name, hostVEthInterfacePrefix)
+	case "vlan":
+		// the transparent vlan client creates the vlan link in the host namespace before moving it to the NC namespace
+		return link.vlanParent != "" && link.name == fmt.Sprintf("%s_%d", link.vlanParent, link.vlanID)
+	default:
+		return false
+	}
+}
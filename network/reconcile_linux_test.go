@@ -0,0 +1,115 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOrphanHost struct {
+	links             []hostLink
+	routes            map[string][]string
+	namespaces        []string
+	deletedLinks      []string
+	deletedNamespaces []string
+}
+
+func (h *fakeOrphanHost) listLinks() ([]hostLink, error) {
+	return h.links, nil
+}
+
+func (h *fakeOrphanHost) listRoutes(ifName string) ([]string, error) {
+	return h.routes[ifName], nil
+}
+
+func (h *fakeOrphanHost) listNamedNetns() ([]string, error) {
+	return h.namespaces, nil
+}
+
+func (h *fakeOrphanHost) deleteLink(name string) error {
+	h.deletedLinks = append(h.deletedLinks, name)
+	return nil
+}
+
+func (h *fakeOrphanHost) deleteNamedNetns(name string) error {
+	h.deletedNamespaces = append(h.deletedNamespaces, name)
+	return nil
+}
+
+func newReconcileTestManager() *networkManager {
+	return &networkManager{
+		ExternalInterfaces: map[string]*externalInterface{
+			"eth0": {
+				Name: "eth0",
+				Networks: map[string]*network{
+					"azure": {
+						Mode: opModeTransparentVlan,
+						Endpoints: map[string]*endpoint{
+							"a1b2c3d4-eth0": {Id: "a1b2c3d4-eth0", HostIfName: "azva1b2c3d", VlanID: 1},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newReconcileTestHost() *fakeOrphanHost {
+	return &fakeOrphanHost{
+		links: []hostLink{
+			{name: "lo", linkType: "device"},
+			{name: "eth0", linkType: "device"},
+			{name: "azure0", linkType: "bridge"},
+			{name: "azvinta1b2c3d", linkType: "veth"},
+			{name: "azvdeadbee", linkType: "veth"},
+			{name: "eth0_1", linkType: "vlan", vlanParent: "eth0", vlanID: 1},
+			{name: "eth0_2", linkType: "vlan", vlanParent: "eth0", vlanID: 2},
+			// not named like the links of the transparent vlan client
+			{name: "eth0.3", linkType: "vlan", vlanParent: "eth0", vlanID: 3},
+		},
+		routes: map[string][]string{
+			"azvdeadbee": {"10.241.0.17/32 dev azvdeadbee table 254"},
+		},
+		namespaces: []string{"az_ns_1", "az_ns_2", "cni-1234"},
+	}
+}
+
+func TestReconcileOrphans(t *testing.T) {
+	nm := newReconcileTestManager()
+	host := newReconcileTestHost()
+
+	report, err := nm.reconcileOrphans(host, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"azvdeadbee", "eth0_2"}, report.Links)
+	require.Equal(t, []string{"10.241.0.17/32 dev azvdeadbee table 254"}, report.Routes)
+	require.Equal(t, []string{"az_ns_2"}, report.Namespaces)
+	require.Equal(t, report.Links, host.deletedLinks)
+	require.Equal(t, report.Namespaces, host.deletedNamespaces)
+}
+
+func TestReconcileOrphansReportOnly(t *testing.T) {
+	nm := newReconcileTestManager()
+	host := newReconcileTestHost()
+
+	report, err := nm.reconcileOrphans(host, false)
+	require.NoError(t, err)
+	require.Len(t, report.Links, 2)
+	require.Len(t, report.Namespaces, 1)
+	require.Empty(t, host.deletedLinks)
+	require.Empty(t, host.deletedNamespaces)
+}
+
+func TestReconcileOrphansWithoutState(t *testing.T) {
+	nm := &networkManager{ExternalInterfaces: map[string]*externalInterface{}}
+	host := newReconcileTestHost()
+
+	report, err := nm.reconcileOrphans(host, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"azvinta1b2c3d", "azvdeadbee", "eth0_1", "eth0_2"}, report.Links)
+	require.Equal(t, []string{"az_ns_1", "az_ns_2"}, report.Namespaces)
+	require.Empty(t, host.deletedLinks)
+	require.Empty(t, host.deletedNamespaces)
+}
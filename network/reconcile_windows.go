@@ -0,0 +1,14 @@
+package network
+
+import (
+	"github.com/Azure/azure-container-networking/cni/api"
+)
+
+// ReconcileOrphans is a no-op on Windows, where HNS owns the endpoint resources.
+func (nm *networkManager) ReconcileOrphans(_ bool) (*api.OrphanReport, error) {
+	return &api.OrphanReport{
+		Links:      make([]string, 0),
+		Routes:     make([]string, 0),
+		Namespaces: make([]string, 0),
+	}, nil
+}
@@ -0,0 +1,131 @@
+// Package rulegc removes the iptables rules and ebtables chains of endpoints which no longer exist,
+// e.g. because a CNI DEL crashed before cleaning them up.
+package rulegc
+
+import (
+	"github.com/Azure/azure-container-networking/cni/client"
+	"github.com/Azure/azure-container-networking/ebtables"
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	utilexec "k8s.io/utils/exec"
+)
+
+// EndpointIDsFunc returns the IDs of the endpoints which exist.
+type EndpointIDsFunc func() ([]string, error)
+
+// Result lists the orphaned rules found by a GarbageCollector.
+type Result struct {
+	IPTablesRules  []iptables.EndpointRule
+	EbtablesChains []string
+}
+
+// ebtablesClient lists and removes the ebtables chains of endpoints.
+type ebtablesClient interface {
+	ListEndpointChains() ([]string, error)
+	RemoveEndpointChain(chain string) error
+}
+
+type hostEbtables struct{}
+
+func (hostEbtables) ListEndpointChains() ([]string, error) {
+	return ebtables.ListEndpointChains() //nolint:wrapcheck // the collector wraps errors
+}
+
+func (hostEbtables) RemoveEndpointChain(chain string) error {
+	return ebtables.RemoveEndpointChain(chain) //nolint:wrapcheck // the collector wraps errors
+}
+
+// GarbageCollector finds the rules marked with an endpoint ID which isn't returned by an EndpointIDsFunc.
+type GarbageCollector struct {
+	exec     utilexec.Interface
+	ebtables ebtablesClient
+}
+
+// New creates a GarbageCollector for the rules of the host.
+func New() *GarbageCollector {
+	return &GarbageCollector{
+		exec:     utilexec.New(),
+		ebtables: hostEbtables{},
+	}
+}
+
+// CNIEndpointIDs returns the endpoint IDs in the state of the azure-vnet plugin.
+// The plugin reads its state under the same lock as ADD and DEL.
+func CNIEndpointIDs(exec utilexec.Interface) EndpointIDsFunc {
+	return func() ([]string, error) {
+		state, err := client.New(exec).GetEndpointState()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get CNI endpoint state")
+		}
+		ids := make([]string, 0, len(state.ContainerInterfaces))
+		for id := range state.ContainerInterfaces {
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+}
+
+// Run finds the orphaned rules and removes them, unless dryRun is set.
+// Rules are listed before the endpoint IDs are read, so the rules of an endpoint added in between are never orphans.
+// Removal continues past failures and returns the first error.
+func (gc *GarbageCollector) Run(endpointIDs EndpointIDsFunc, dryRun bool) (*Result, error) {
+	rules, err := iptables.ListEndpointRules(gc.exec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list endpoint iptables rules")
+	}
+	chains, err := gc.ebtables.ListEndpointChains()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list endpoint ebtables chains")
+	}
+
+	ids, err := endpointIDs()
+	if err != nil {
+		return nil, err
+	}
+	liveIDs := make(map[string]struct{}, len(ids))
+	liveChains := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		liveIDs[id] = struct{}{}
+		liveChains[ebtables.EndpointChainName(id)] = struct{}{}
+	}
+
+	result := &Result{
+		IPTablesRules:  make([]iptables.EndpointRule, 0),
+		EbtablesChains: make([]string, 0),
+	}
+	tx := iptables.NewTransactionWithExec(gc.exec)
+	for _, rule := range rules {
+		if _, ok := liveIDs[rule.EndpointID]; ok {
+			continue
+		}
+		result.IPTablesRules = append(result.IPTablesRules, rule)
+		tx.Add(rule.GetDeleteCmd())
+	}
+	for _, chain := range chains {
+		if _, ok := liveChains[chain]; ok {
+			continue
+		}
+		result.EbtablesChains = append(result.EbtablesChains, chain)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	var firstErr error
+	log.Printf("[rulegc] Removing %d orphaned iptables rules", len(result.IPTablesRules))
+	if err := tx.Apply(); err != nil {
+		firstErr = errors.Wrap(err, "failed to remove orphaned iptables rules")
+	}
+	for _, chain := range result.EbtablesChains {
+		log.Printf("[rulegc] Removing orphaned ebtables chain %s", chain)
+		if err := gc.ebtables.RemoveEndpointChain(chain); err != nil {
+			log.Errorf("[rulegc] Failed to remove ebtables chain %s: %v", chain, err)
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "failed to remove orphaned ebtables chain %s", chain)
+			}
+		}
+	}
+	return result, firstErr
+}
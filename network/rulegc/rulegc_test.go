@@ -0,0 +1,126 @@
+package rulegc
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/ebtables"
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/stretchr/testify/require"
+	utilexec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+const testSave = `*filter
+:INPUT ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:AZURECNIINPUT - [0:0]
+:AZURECNIOUTPUT - [0:0]
+-A INPUT -j AZURECNIINPUT
+-A AZURECNIINPUT -s 169.254.0.4/32 -d 169.254.0.1/32 -m comment --comment "azure-cni-endpoint:live-eth0" -j ACCEPT
+-A AZURECNIINPUT -s 169.254.0.5/32 -d 169.254.0.1/32 -m comment --comment "azure-cni-endpoint:dead-eth0" -j ACCEPT
+-A AZURECNIOUTPUT -s 169.254.0.1/32 -d 169.254.0.6/32 -j ACCEPT
+COMMIT
+`
+
+type fakeEbtables struct {
+	chains  []string
+	removed []string
+}
+
+func (f *fakeEbtables) ListEndpointChains() ([]string, error) {
+	return f.chains, nil
+}
+
+func (f *fakeEbtables) RemoveEndpointChain(chain string) error {
+	f.removed = append(f.removed, chain)
+	return nil
+}
+
+// newFakeExec returns iptables-save output twice (listing and transaction), fails ip6tables-save,
+// and records the iptables-restore input
+func newFakeExec(t *testing.T, restored *string) *testingexec.FakeExec {
+	save := func(out string, err error) testingexec.FakeCommandAction {
+		return func(cmd string, args ...string) utilexec.Cmd {
+			return testingexec.InitFakeCmd(&testingexec.FakeCmd{
+				OutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(out), nil, err },
+				},
+			}, cmd, args...)
+		}
+	}
+	restore := func(cmd string, args ...string) utilexec.Cmd {
+		fcmd := &testingexec.FakeCmd{}
+		fcmd.CombinedOutputScript = []testingexec.FakeAction{
+			func() ([]byte, []byte, error) {
+				require.Equal(t, "iptables-restore", cmd)
+				stdin, err := io.ReadAll(fcmd.Stdin)
+				require.NoError(t, err)
+				*restored = string(stdin)
+				return nil, nil, nil
+			},
+		}
+		return testingexec.InitFakeCmd(fcmd, cmd, args...)
+	}
+	return &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			save(testSave, nil),
+			save("", errors.New("ip6tables-save: not found")),
+			save(testSave, nil),
+			restore,
+		},
+	}
+}
+
+func TestRunRemovesOrphanedRules(t *testing.T) {
+	var restored string
+	fexec := newFakeExec(t, &restored)
+	eb := &fakeEbtables{
+		chains: []string{ebtables.EndpointChainName("live-eth0"), ebtables.EndpointChainName("dead-eth1")},
+	}
+	gc := &GarbageCollector{exec: fexec, ebtables: eb}
+
+	result, err := gc.Run(func() ([]string, error) { return []string{"live-eth0"}, nil }, false)
+	require.NoError(t, err)
+	require.Equal(t, []iptables.EndpointRule{
+		{
+			Version:    iptables.V4,
+			Table:      iptables.Filter,
+			Chain:      iptables.CNIInputChain,
+			EndpointID: "dead-eth0",
+			RuleSpec:   "-s 169.254.0.5/32 -d 169.254.0.1/32 -m comment --comment azure-cni-endpoint:dead-eth0 -j ACCEPT",
+		},
+	}, result.IPTablesRules)
+	require.Equal(t, []string{ebtables.EndpointChainName("dead-eth1")}, result.EbtablesChains)
+	require.Equal(t, result.EbtablesChains, eb.removed)
+	require.Equal(t, "*filter\n-D AZURECNIINPUT -s 169.254.0.5/32 -d 169.254.0.1/32 -m comment --comment azure-cni-endpoint:dead-eth0 -j ACCEPT\nCOMMIT\n", restored)
+	require.Equal(t, 4, fexec.CommandCalls)
+}
+
+func TestRunDryRun(t *testing.T) {
+	var restored string
+	fexec := newFakeExec(t, &restored)
+	eb := &fakeEbtables{chains: []string{ebtables.EndpointChainName("dead-eth1")}}
+	gc := &GarbageCollector{exec: fexec, ebtables: eb}
+
+	result, err := gc.Run(func() ([]string, error) { return nil, nil }, true)
+	require.NoError(t, err)
+	require.Len(t, result.IPTablesRules, 2)
+	require.Len(t, result.EbtablesChains, 1)
+	require.Empty(t, eb.removed)
+	require.Empty(t, restored)
+	require.Equal(t, 2, fexec.CommandCalls)
+}
+
+func TestRunKeepsRulesWhenEndpointStateFails(t *testing.T) {
+	var restored string
+	fexec := newFakeExec(t, &restored)
+	eb := &fakeEbtables{chains: []string{ebtables.EndpointChainName("dead-eth1")}}
+	gc := &GarbageCollector{exec: fexec, ebtables: eb}
+
+	_, err := gc.Run(func() ([]string, error) { return nil, errors.New("lock timeout") }, false)
+	require.Error(t, err)
+	require.Empty(t, eb.removed)
+	require.Empty(t, restored)
+}
@@ -40,7 +40,9 @@ type Client struct {
 	localIP                string
 	SnatBridgeIP           string
 	SkipAddressesFromBlock []string
-	netlink                netlink.NetlinkInterface
+	// endpointID marks the iptables rules of the endpoint as owned by it
+	endpointID string
+	netlink    netlink.NetlinkInterface
 
 	plClient platform.ExecClient
 }
@@ -51,6 +53,7 @@ func NewSnatClient(hostIfName string,
 	snatBridgeIP string,
 	hostPrimaryMac string,
 	skipAddressesFromBlock []string,
+	endpointID string,
 	nl netlink.NetlinkInterface,
 
 	plClient platform.ExecClient,
@@ -62,6 +65,7 @@ func NewSnatClient(hostIfName string,
 		localIP:               localIP,
 		SnatBridgeIP:          snatBridgeIP,
 		hostPrimaryMac:        hostPrimaryMac,
+		endpointID:            endpointID,
 		netlink:               nl,
 
 		plClient: plClient,
@@ -164,44 +168,26 @@ func getNCLocalAndGatewayIP(client *Client) (brIP, contIP net.IP) {
 **/
 func (client *Client) AllowInboundFromHostToNC() error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
+	tx := iptables.NewTransaction()
 
-	// Create CNI Output chain
-	if err := iptables.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Creating %v failed with error: %v", iptables.CNIOutputChain, err)
-		return newErrorSnatClient(err.Error())
-	}
-
-	// Forward traffic from Ouptut chain to CNI Output chain
-	if err := iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting forward rule to %v failed with error: %v", iptables.CNIOutputChain, err)
-		return newErrorSnatClient(err.Error())
-	}
+	// Create CNI Output chain and forward traffic from Ouptut chain to it
+	tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain)
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain)
 
 	// Allow connection from Host to NC
-	matchCondition := fmt.Sprintf("-s %s -d %s", bridgeIP.String(), containerIP.String())
-	err := iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
-	if err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting output rule failed: %v", err)
-		return newErrorSnatClient(err.Error())
-	}
-
-	// Create cniinput chain
-	if err := iptables.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Creating %v failed with error: %v", iptables.CNIInputChain, err)
-		return newErrorSnatClient(err.Error())
-	}
+	matchCondition := fmt.Sprintf("-s %s -d %s %s", bridgeIP.String(), containerIP.String(), iptables.GetEndpointComment(client.endpointID))
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
 
-	// Forward from Input to cniinput chain
-	if err := iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting forward rule to %v failed with error: %v", iptables.CNIInputChain, err)
-		return newErrorSnatClient(err.Error())
-	}
+	// Create cniinput chain and forward from Input to it
+	tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain)
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain)
 
 	// Accept packets from NC only if established connection
 	matchCondition = fmt.Sprintf(" -i %s -m state --state %s,%s", SnatBridgeName, iptables.Established, iptables.Related)
-	err = iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
-	if err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting input rule failed: %v", err)
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
+
+	if err := tx.Apply(); err != nil {
+		log.Printf("AllowInboundFromHostToNC: Adding iptables rules failed with error: %v", err)
 		return newErrorSnatClient(err.Error())
 	}
 
@@ -215,7 +201,7 @@ func (client *Client) AllowInboundFromHostToNC() error {
 		MacAddress: snatContainerVeth.HardwareAddr,
 	}
 
-	err = client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT)
+	err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT)
 	if err != nil {
 		log.Printf("AllowInboundFromHostToNC: Error adding static arp entry for ip %s mac %s: %v", containerIP, snatContainerVeth.HardwareAddr.String(), err)
 		return newErrorSnatClient(err.Error())
@@ -227,9 +213,12 @@ func (client *Client) AllowInboundFromHostToNC() error {
 func (client *Client) DeleteInboundFromHostToNC() error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
 
-	// Delete allow connection from Host to NC
+	// Delete allow connection from Host to NC, with or without the endpoint comment of newer rules
 	matchCondition := fmt.Sprintf("-s %s -d %s", bridgeIP.String(), containerIP.String())
-	err := iptables.DeleteIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
+	err := iptables.NewTransaction().
+		DeleteRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept).
+		DeleteRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition+" "+iptables.GetEndpointComment(client.endpointID), iptables.Accept).
+		Apply()
 	if err != nil {
 		log.Printf("DeleteInboundFromHostToNC: Error removing output rule %v", err)
 	}
@@ -255,44 +244,26 @@ func (client *Client) DeleteInboundFromHostToNC() error {
 **/
 func (client *Client) AllowInboundFromNCToHost() error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
+	tx := iptables.NewTransaction()
 
-	// Create CNI Input chain
-	if err := iptables.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Creating %v failed with error: %v", iptables.CNIInputChain, err)
-		return err
-	}
-
-	// Forward traffic from Input to cniinput chain
-	if err := iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting forward rule to %v failed with error: %v", iptables.CNIInputChain, err)
-		return err
-	}
+	// Create CNI Input chain and forward traffic from Input to it
+	tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain)
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain)
 
 	// Allow NC to Host connection
-	matchCondition := fmt.Sprintf("-s %s -d %s", containerIP.String(), bridgeIP.String())
-	err := iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
-	if err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting output rule failed: %v", err)
-		return err
-	}
-
-	// Create CNI output chain
-	if err := iptables.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Creating %v failed with error: %v", iptables.CNIOutputChain, err)
-		return err
-	}
+	matchCondition := fmt.Sprintf("-s %s -d %s %s", containerIP.String(), bridgeIP.String(), iptables.GetEndpointComment(client.endpointID))
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
 
-	// Forward traffic from Output to CNI Output chain
-	if err := iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain); err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting forward rule to %v failed with error: %v", iptables.CNIOutputChain, err)
-		return err
-	}
+	// Create CNI output chain and forward traffic from Output to it
+	tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain)
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain)
 
 	// Accept packets from Host only if established connection
 	matchCondition = fmt.Sprintf(" -o %s -m state --state %s,%s", SnatBridgeName, iptables.Established, iptables.Related)
-	err = iptables.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
-	if err != nil {
-		log.Printf("AllowInboundFromHostToNC: Inserting input rule failed: %v", err)
+	tx.InsertRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
+
+	if err := tx.Apply(); err != nil {
+		log.Printf("AllowInboundFromNCToHost: Adding iptables rules failed with error: %v", err)
 		return err
 	}
 
@@ -306,7 +277,7 @@ func (client *Client) AllowInboundFromNCToHost() error {
 		MacAddress: snatContainerVeth.HardwareAddr,
 	}
 
-	err = client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT)
+	err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT)
 	if err != nil {
 		log.Printf("AllowInboundFromNCToHost: Error adding static arp entry for ip %s mac %s: %v", containerIP, snatContainerVeth.HardwareAddr.String(), err)
 	}
@@ -317,9 +288,12 @@ func (client *Client) AllowInboundFromNCToHost() error {
 func (client *Client) DeleteInboundFromNCToHost() error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
 
-	// Delete allow NC to Host connection
+	// Delete allow NC to Host connection, with or without the endpoint comment of newer rules
 	matchCondition := fmt.Sprintf("-s %s -d %s", containerIP.String(), bridgeIP.String())
-	err := iptables.DeleteIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
+	err := iptables.NewTransaction().
+		DeleteRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept).
+		DeleteRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition+" "+iptables.GetEndpointComment(client.endpointID), iptables.Accept).
+		Apply()
 	if err != nil {
 		log.Printf("DeleteInboundFromNCToHost: Error removing output rule %v", err)
 	}
@@ -443,7 +417,9 @@ func (client *Client) createSnatBridge(snatBridgeIP, hostPrimaryMac string) erro
 func (client *Client) addMasqueradeRule(snatBridgeIPWithPrefix string) error {
 	_, ipNet, _ := net.ParseCIDR(snatBridgeIPWithPrefix)
 	matchCondition := fmt.Sprintf("-s %s", ipNet.String())
-	return iptables.InsertIptableRule(iptables.V4, iptables.Nat, iptables.Postrouting, matchCondition, iptables.Masquerade)
+	return iptables.NewTransaction().
+		InsertRule(iptables.V4, iptables.Nat, iptables.Postrouting, matchCondition, iptables.Masquerade).
+		Apply()
 }
 
 /**
@@ -0,0 +1,100 @@
+package network
+
+import (
+	"net"
+	"sort"
+
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+)
+
+// State is the network manager state persisted by the plugin, loaded without the host networking so
+// it can be inspected and repaired offline. Changes only edit the state, the host resources of a
+// removed endpoint are left to be garbage collected.
+type State struct {
+	nm *networkManager
+}
+
+// NetworkSummary describes a network in the state.
+type NetworkSummary struct {
+	ExternalInterface string
+	ID                string
+	Mode              string
+	Subnets           []SubnetInfo
+	Endpoints         []EndpointSummary
+}
+
+// EndpointSummary describes an endpoint in the state.
+type EndpointSummary struct {
+	ID           string
+	ContainerID  string
+	IfName       string
+	HostIfName   string
+	PodName      string
+	PodNamespace string
+	NetNs        string
+	VlanID       int
+	IPAddresses  []net.IPNet
+	// NetworkContainerID is set on the endpoints of multitenant pods, whose IPs belong to a network container.
+	NetworkContainerID string
+}
+
+// LoadState reads the network manager state from st the way the plugin does, without rehydrating it
+// after a reboot. An empty store is an empty state.
+func LoadState(st store.KeyValueStore) (*State, error) {
+	nm := &networkManager{
+		ExternalInterfaces: make(map[string]*externalInterface),
+		store:              st,
+	}
+	if err := nm.restore(false); err != nil {
+		return nil, errors.Wrap(err, "failed to restore network state")
+	}
+	return &State{nm: nm}, nil
+}
+
+// Networks lists the networks in the state and their endpoints, sorted by ID.
+func (s *State) Networks() []NetworkSummary {
+	var networks []NetworkSummary
+	for ifName, extIf := range s.nm.ExternalInterfaces {
+		for _, nw := range extIf.Networks {
+			summary := NetworkSummary{ExternalInterface: ifName, ID: nw.Id, Mode: nw.Mode, Subnets: nw.Subnets}
+			for _, ep := range nw.Endpoints {
+				summary.Endpoints = append(summary.Endpoints, EndpointSummary{
+					ID:           ep.Id,
+					ContainerID:  ep.ContainerID,
+					IfName:       ep.IfName,
+					HostIfName:   ep.HostIfName,
+					PodName:      ep.PODName,
+					PodNamespace: ep.PODNameSpace,
+					NetNs:        ep.NetworkNameSpace,
+					VlanID:       ep.VlanID,
+					IPAddresses:  ep.IPAddresses,
+
+					NetworkContainerID: ep.NetworkContainerID,
+				})
+			}
+			sort.Slice(summary.Endpoints, func(i, j int) bool { return summary.Endpoints[i].ID < summary.Endpoints[j].ID })
+			networks = append(networks, summary)
+		}
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].ID < networks[j].ID })
+	return networks
+}
+
+// RemoveEndpoint removes an endpoint from the state.
+func (s *State) RemoveEndpoint(networkID, endpointID string) error {
+	nw, err := s.nm.getNetwork(networkID)
+	if err != nil {
+		return err
+	}
+	if _, err := nw.getEndpoint(endpointID); err != nil {
+		return errors.Wrapf(err, "%s in network %s", endpointID, networkID)
+	}
+	delete(nw.Endpoints, endpointID)
+	return nil
+}
+
+// Save writes the state back to the store it was loaded from.
+func (s *State) Save() error {
+	return s.nm.save()
+}
@@ -0,0 +1,69 @@
+package network
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newStateStore(t *testing.T) store.KeyValueStore {
+	st, err := store.NewJsonFileStore(filepath.Join(t.TempDir(), "azure-vnet.json"), processlock.NewMockFileLock(false))
+	require.NoError(t, err)
+	return st
+}
+
+func TestState(t *testing.T) {
+	st := newStateStore(t)
+	ip := net.IPNet{IP: net.ParseIP("10.240.0.5").To4(), Mask: net.CIDRMask(16, 32)}
+	nm := &networkManager{
+		store: st,
+		ExternalInterfaces: map[string]*externalInterface{
+			"eth0": {
+				Name: "eth0",
+				Networks: map[string]*network{
+					"azure": {
+						Id:   "azure",
+						Mode: "transparent",
+						Endpoints: map[string]*endpoint{
+							"b-eth0": {Id: "b-eth0", ContainerID: "b", PODName: "pod-b", PODNameSpace: "default", IPAddresses: []net.IPNet{ip}},
+							"a-eth0": {Id: "a-eth0", ContainerID: "a", PODName: "pod-a", PODNameSpace: "default"},
+						},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, nm.save())
+
+	state, err := LoadState(st)
+	require.NoError(t, err)
+	networks := state.Networks()
+	require.Len(t, networks, 1)
+	require.Equal(t, "eth0", networks[0].ExternalInterface)
+	require.Equal(t, "transparent", networks[0].Mode)
+	require.Len(t, networks[0].Endpoints, 2)
+	require.Equal(t, "a-eth0", networks[0].Endpoints[0].ID)
+	require.Equal(t, "pod-b", networks[0].Endpoints[1].PodName)
+	require.Equal(t, ip.String(), networks[0].Endpoints[1].IPAddresses[0].String())
+
+	require.Error(t, state.RemoveEndpoint("azure", "missing-eth0"))
+	require.Error(t, state.RemoveEndpoint("missing", "a-eth0"))
+	require.NoError(t, state.RemoveEndpoint("azure", "a-eth0"))
+	require.NoError(t, state.Save())
+
+	reloaded, err := LoadState(st)
+	require.NoError(t, err)
+	endpoints := reloaded.Networks()[0].Endpoints
+	require.Len(t, endpoints, 1)
+	require.Equal(t, "b-eth0", endpoints[0].ID)
+}
+
+func TestLoadStateEmpty(t *testing.T) {
+	state, err := LoadState(newStateStore(t))
+	require.NoError(t, err)
+	require.Empty(t, state.Networks())
+}
@@ -273,6 +273,23 @@ func TestTransDeleteEndpointsRules(t *testing.T) {
 	}
 }
 
+func TestTransDeleteEndpointsConntrack(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	client := &TransparentEndpointClient{netlink: nl}
+	ep := &endpoint{
+		IPAddresses: []net.IPNet{
+			{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+			{IP: net.ParseIP("fc00::4"), Mask: net.CIDRMask(subnetv6Mask, ipv6FullMask)},
+		},
+	}
+	require.NoError(t, client.DeleteEndpoints(ep))
+	require.Equal(t, []net.IP{ep.IPAddresses[0].IP, ep.IPAddresses[1].IP}, nl.ConntrackDeletedIPs)
+
+	// failing to flush conntrack does not fail the deletion
+	client.netlink = netlink.NewMockNetlink(true, "conntrack fail")
+	require.NoError(t, client.DeleteEndpoints(ep))
+}
+
 func TestTransConfigureContainerInterfacesAndRoutes(t *testing.T) {
 	nl := netlink.NewMockNetlink(false, "")
 	plc := platform.NewMockExecClient(false)
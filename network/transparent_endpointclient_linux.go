@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net"
 
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -78,9 +77,9 @@ func (client *TransparentEndpointClient) setArpProxy(ifName string) error {
 
 func (client *TransparentEndpointClient) AddEndpoints(epInfo *EndpointInfo) error {
 	if _, err := client.netioshim.GetNetworkInterfaceByName(client.hostVethName); err == nil {
-		log.Printf("Deleting old host veth %v", client.hostVethName)
+		logger.Infof("Deleting old host veth %v", client.hostVethName)
 		if err = client.netlink.DeleteLink(client.hostVethName); err != nil {
-			log.Printf("[net] Failed to delete old hostveth %v: %v.", client.hostVethName, err)
+			logger.Infof("Failed to delete old hostveth %v: %v.", client.hostVethName, err)
 			return newErrorTransparentEndpointClient(err.Error())
 		}
 	}
@@ -92,7 +91,7 @@ func (client *TransparentEndpointClient) AddEndpoints(epInfo *EndpointInfo) erro
 
 	mac, err := net.ParseMAC(defaultHostVethHwAddr)
 	if err != nil {
-		log.Printf("[net] Failed to parse the mac addrress %v", defaultHostVethHwAddr)
+		logger.Infof("Failed to parse the mac addrress %v", defaultHostVethHwAddr)
 	}
 
 	if err = client.netUtilsClient.CreateEndpoint(client.hostVethName, client.containerVethName, mac); err != nil {
@@ -102,11 +101,15 @@ func (client *TransparentEndpointClient) AddEndpoints(epInfo *EndpointInfo) erro
 	defer func() {
 		if err != nil {
 			if delErr := client.netlink.DeleteLink(client.hostVethName); delErr != nil {
-				log.Errorf("Deleting veth failed on addendpoint failure:%v", delErr)
+				logger.Errorf("Deleting veth failed on addendpoint failure:%v", delErr)
 			}
 		}
 	}()
 
+	if err = setContainerVethMac(client.netlink, epInfo.MacAddress, client.containerVethName); err != nil {
+		return newErrorTransparentEndpointClient(err.Error())
+	}
+
 	containerIf, err := client.netioshim.GetNetworkInterfaceByName(client.containerVethName)
 	if err != nil {
 		return newErrorTransparentEndpointClient(err.Error())
@@ -121,13 +124,19 @@ func (client *TransparentEndpointClient) AddEndpoints(epInfo *EndpointInfo) erro
 
 	client.hostVethMac = hostVethIf.HardwareAddr
 
-	log.Printf("Setting mtu %d on veth interface %s", primaryIf.MTU, client.hostVethName)
-	if err := client.netlink.SetLinkMTU(client.hostVethName, primaryIf.MTU); err != nil {
-		log.Errorf("Setting mtu failed for hostveth %s:%v", client.hostVethName, err)
+	// endpoints of networks created without an MTU take the one of the primary interface
+	mtu := epInfo.MTU
+	if mtu <= 0 {
+		mtu = primaryIf.MTU
+	}
+
+	logger.Infof("Setting mtu %d on veth interface %s", mtu, client.hostVethName)
+	if err := client.netlink.SetLinkMTU(client.hostVethName, mtu); err != nil {
+		logger.Errorf("Setting mtu failed for hostveth %s:%v", client.hostVethName, err)
 	}
 
-	if err := client.netlink.SetLinkMTU(client.containerVethName, primaryIf.MTU); err != nil {
-		log.Errorf("Setting mtu failed for containerveth %s:%v", client.containerVethName, err)
+	if err := client.netlink.SetLinkMTU(client.containerVethName, mtu); err != nil {
+		logger.Errorf("Setting mtu failed for containerveth %s:%v", client.containerVethName, err)
 	}
 
 	return nil
@@ -149,7 +158,7 @@ func (client *TransparentEndpointClient) AddEndpointRules(epInfo *EndpointInfo)
 		} else {
 			ipNet = net.IPNet{IP: ipAddr.IP, Mask: net.CIDRMask(ipv6FullMask, ipv6Bits)}
 		}
-		log.Printf("[net] Adding route for the ip %v", ipNet.String())
+		logger.Infof("Adding route for the ip %v", ipNet.String())
 		routeInfo.Dst = ipNet
 		routeInfoList = append(routeInfoList, routeInfo)
 		if err := addRoutes(client.netlink, client.netioshim, client.hostVethName, routeInfoList); err != nil {
@@ -157,9 +166,9 @@ func (client *TransparentEndpointClient) AddEndpointRules(epInfo *EndpointInfo)
 		}
 	}
 
-	log.Printf("calling setArpProxy for %v", client.hostVethName)
+	logger.Infof("calling setArpProxy for %v", client.hostVethName)
 	if err := client.setArpProxy(client.hostVethName); err != nil {
-		log.Printf("setArpProxy failed with: %v", err)
+		logger.Infof("setArpProxy failed with: %v", err)
 		return err
 	}
 
@@ -181,17 +190,17 @@ func (client *TransparentEndpointClient) DeleteEndpointRules(ep *endpoint) {
 			ipNet = net.IPNet{IP: ipAddr.IP, Mask: net.CIDRMask(ipv6FullMask, ipv6Bits)}
 		}
 
-		log.Printf("[net] Deleting route for the ip %v", ipNet.String())
+		logger.Infof("Deleting route for the ip %v", ipNet.String())
 		routeInfo.Dst = ipNet
 		if err := deleteRoutes(client.netlink, client.netioshim, client.hostVethName, []RouteInfo{routeInfo}); err != nil {
-			log.Printf("[net] Failed to delete route on VM for the ip %v: %v", ipNet.String(), err)
+			logger.Infof("Failed to delete route on VM for the ip %v: %v", ipNet.String(), err)
 		}
 	}
 }
 
 func (client *TransparentEndpointClient) MoveEndpointsToContainerNS(epInfo *EndpointInfo, nsID uintptr) error {
 	// Move the container interface to container's network namespace.
-	log.Printf("[net] Setting link %v netns %v.", client.containerVethName, epInfo.NetNsPath)
+	logger.Infof("Setting link %v netns %v.", client.containerVethName, epInfo.NetNsPath)
 	if err := client.netlink.SetLinkNetNs(client.containerVethName, nsID); err != nil {
 		return newErrorTransparentEndpointClient(err.Error())
 	}
@@ -250,7 +259,7 @@ func (client *TransparentEndpointClient) ConfigureContainerInterfacesAndRoutes(e
 	}
 
 	// arp -s 169.254.1.1 e3:45:f4:ac:34:12 - add static arp entry for virtualgwip to hostveth interface mac
-	log.Printf("[net] Adding static arp for IP address %v and MAC %v in Container namespace",
+	logger.Infof("Adding static arp for IP address %v and MAC %v in Container namespace",
 		virtualGwNet.String(), client.hostVethMac)
 	linkInfo := netlink.LinkInfo{
 		Name:       client.containerVethName,
@@ -276,7 +285,7 @@ func (client *TransparentEndpointClient) ConfigureContainerInterfacesAndRoutes(e
 }
 
 func (client *TransparentEndpointClient) setupIPV6Routes() error {
-	log.Printf("Setting up ipv6 routes in container")
+	logger.Infof("Setting up ipv6 routes in container")
 
 	// add route for virtualgwip
 	// ip -6 route add fe80::1234:5678:9abc/128 dev eth0
@@ -288,7 +297,7 @@ func (client *TransparentEndpointClient) setupIPV6Routes() error {
 
 	// ip -6 route add default via fe80::1234:5678:9abc dev eth0
 	_, defaultIPNet, _ := net.ParseCIDR(defaultv6Cidr)
-	log.Printf("defaultv6ipnet :%+v", defaultIPNet)
+	logger.Infof("defaultv6ipnet :%+v", defaultIPNet)
 	defaultRoute := RouteInfo{
 		Dst: *defaultIPNet,
 		Gw:  virtualGwIP,
@@ -298,7 +307,7 @@ func (client *TransparentEndpointClient) setupIPV6Routes() error {
 }
 
 func (client *TransparentEndpointClient) setIPV6NeighEntry() error {
-	log.Printf("[net] Add v6 neigh entry for default gw ip")
+	logger.Infof("Add v6 neigh entry for default gw ip")
 	hostGwIP, _, _ := net.ParseCIDR(virtualv6GwString)
 	linkInfo := netlink.LinkInfo{
 		Name:       client.containerVethName,
@@ -307,7 +316,7 @@ func (client *TransparentEndpointClient) setIPV6NeighEntry() error {
 	}
 
 	if err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT); err != nil {
-		log.Printf("Failed setting neigh entry in container: %+v", err)
+		logger.Infof("Failed setting neigh entry in container: %+v", err)
 		return fmt.Errorf("Failed setting neigh entry in container: %w", err)
 	}
 
@@ -315,5 +324,6 @@ func (client *TransparentEndpointClient) setIPV6NeighEntry() error {
 }
 
 func (client *TransparentEndpointClient) DeleteEndpoints(ep *endpoint) error {
+	deleteConntrackEntries(client.netlink, ep.IPAddresses)
 	return nil
 }
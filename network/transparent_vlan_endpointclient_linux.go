@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-container-networking/iptables"
-	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/netns"
@@ -119,13 +118,13 @@ func (client *TransparentVlanEndpointClient) PopulateVM(epInfo *EndpointInfo) er
 		return errors.Wrap(err, "failed to get vm ns handle")
 	}
 
-	log.Printf("[transparent vlan] Checking if NS exists...")
+	logger.Infof("[transparent vlan] Checking if NS exists...")
 	vnetNS, existingErr := client.netnsClient.GetFromName(client.vnetNSName)
 	// If the ns does not exist, the below code will trigger to create it
 	// This will also (we assume) mean the vlan veth does not exist
 	if existingErr != nil {
 		// We assume the only possible error is that the namespace doesn't exist
-		log.Printf("[transparent vlan] No existing NS detected. Creating the vnet namespace and switching to it")
+		logger.Infof("[transparent vlan] No existing NS detected. Creating the vnet namespace and switching to it")
 		vnetNS, err = client.netnsClient.NewNamed(client.vnetNSName)
 		if err != nil {
 			return errors.Wrap(err, "failed to create vnet ns")
@@ -135,10 +134,10 @@ func (client *TransparentVlanEndpointClient) PopulateVM(epInfo *EndpointInfo) er
 		// Any failure will trigger removing the namespace created
 		defer func() {
 			if deleteNSIfNotNilErr != nil {
-				log.Logf("[transparent vlan] removing vnet ns due to failure...")
+				logger.Infof("[transparent vlan] removing vnet ns due to failure...")
 				err = client.netnsClient.DeleteNamed(client.vnetNSName)
 				if err != nil {
-					log.Errorf("failed to cleanup/delete ns after failing to create vlan veth")
+					logger.Errorf("failed to cleanup/delete ns after failing to create vlan veth")
 				}
 			}
 		}()
@@ -147,7 +146,7 @@ func (client *TransparentVlanEndpointClient) PopulateVM(epInfo *EndpointInfo) er
 		}
 
 		// Now create vlan veth
-		log.Printf("[transparent vlan] Create the host vlan link after getting eth0: %s", client.primaryHostIfName)
+		logger.Infof("[transparent vlan] Create the host vlan link after getting eth0: %s", client.primaryHostIfName)
 		// Get parent interface index. Index is consistent across libraries.
 		eth0, deleteNSIfNotNilErr := client.netioshim.GetNetworkInterfaceByName(client.primaryHostIfName)
 		if deleteNSIfNotNilErr != nil {
@@ -161,7 +160,7 @@ func (client *TransparentVlanEndpointClient) PopulateVM(epInfo *EndpointInfo) er
 			LinkAttrs: linkAttrs,
 			VlanId:    client.vlanID,
 		}
-		log.Printf("[transparent vlan] Attempting to create %s link in VM NS", client.vlanIfName)
+		logger.Infof("[transparent vlan] Attempting to create %s link in VM NS", client.vlanIfName)
 		// Create vlan veth
 		deleteNSIfNotNilErr = vishnetlink.LinkAdd(link)
 		if deleteNSIfNotNilErr != nil {
@@ -170,9 +169,9 @@ func (client *TransparentVlanEndpointClient) PopulateVM(epInfo *EndpointInfo) er
 		}
 		defer func() {
 			if deleteNSIfNotNilErr != nil {
-				log.Logf("[transparent vlan] removing vlan veth due to failure...")
+				logger.Infof("[transparent vlan] removing vlan veth due to failure...")
 				if delErr := client.netlink.DeleteLink(client.vlanIfName); delErr != nil {
-					log.Errorf("deleting vlan veth failed on addendpoint failure")
+					logger.Errorf("deleting vlan veth failed on addendpoint failure")
 				}
 			}
 		}()
@@ -181,36 +180,42 @@ func (client *TransparentVlanEndpointClient) PopulateVM(epInfo *EndpointInfo) er
 			return errors.Wrap(deleteNSIfNotNilErr, "failed to disable router advertisements for vlan vnet link")
 		}
 		// vlan veth was created successfully, so move the vlan veth you created
-		log.Printf("[transparent vlan] Move vlan link (%s) to vnet NS: %d", client.vlanIfName, uintptr(client.vnetNSFileDescriptor))
+		logger.Infof("[transparent vlan] Move vlan link (%s) to vnet NS: %d", client.vlanIfName, uintptr(client.vnetNSFileDescriptor))
 		deleteNSIfNotNilErr = client.netlink.SetLinkNetNs(client.vlanIfName, uintptr(client.vnetNSFileDescriptor))
 		if deleteNSIfNotNilErr != nil {
 			return errors.Wrap(deleteNSIfNotNilErr, "deleting vlan veth in vm ns due to addendpoint failure")
 		}
 	} else {
-		log.Printf("[transparent vlan] Existing NS (%s) detected. Assuming %s exists too", client.vnetNSName, client.vlanIfName)
+		logger.Infof("[transparent vlan] Existing NS (%s) detected. Assuming %s exists too", client.vnetNSName, client.vlanIfName)
 	}
 	client.vnetNSFileDescriptor = vnetNS
 
 	if err = client.netUtilsClient.CreateEndpoint(client.vnetVethName, client.containerVethName, nil); err != nil {
 		return errors.Wrap(err, "failed to create veth pair")
 	}
+	if err = setVethMTU(client.netlink, epInfo.MTU, client.vnetVethName, client.containerVethName); err != nil {
+		if delErr := client.netlink.DeleteLink(client.vnetVethName); delErr != nil {
+			logger.Errorf("Deleting vnet veth failed on addendpoint failure:%v", delErr)
+		}
+		return errors.Wrap(err, "failed to set mtu of veth pair, deleting")
+	}
 	// Disable RA for veth pair, and delete if any failure
 	if err = client.netUtilsClient.DisableRAForInterface(client.vnetVethName); err != nil {
 		if delErr := client.netlink.DeleteLink(client.vnetVethName); delErr != nil {
-			log.Errorf("Deleting vnet veth failed on addendpoint failure:%v", delErr)
+			logger.Errorf("Deleting vnet veth failed on addendpoint failure:%v", delErr)
 		}
 		return errors.Wrap(err, "failed to disable RA on vnet veth, deleting")
 	}
 	if err = client.netUtilsClient.DisableRAForInterface(client.containerVethName); err != nil {
 		if delErr := client.netlink.DeleteLink(client.containerVethName); delErr != nil {
-			log.Errorf("Deleting container veth failed on addendpoint failure:%v", delErr)
+			logger.Errorf("Deleting container veth failed on addendpoint failure:%v", delErr)
 		}
 		return errors.Wrap(err, "failed to disable RA on container veth, deleting")
 	}
 
 	if err = client.netlink.SetLinkNetNs(client.vnetVethName, uintptr(client.vnetNSFileDescriptor)); err != nil {
 		if delErr := client.netlink.DeleteLink(client.vnetVethName); delErr != nil {
-			log.Errorf("Deleting vnet veth failed on addendpoint failure:%v", delErr)
+			logger.Errorf("Deleting vnet veth failed on addendpoint failure:%v", delErr)
 		}
 		return errors.Wrap(err, "failed to move vnetVethName into vnet ns, deleting")
 	}
@@ -251,7 +256,7 @@ func (client *TransparentVlanEndpointClient) AddEndpointRules(epInfo *EndpointIn
 	if err := client.AddSnatEndpointRules(); err != nil {
 		return errors.Wrap(err, "failed to add snat endpoint rules")
 	}
-	log.Printf("[transparent vlan] Adding tunneling rules in vnet namespace")
+	logger.Infof("[transparent vlan] Adding tunneling rules in vnet namespace")
 	err := ExecuteInNS(client.vnetNSName, func() error {
 		return client.AddVnetRules(epInfo)
 	})
@@ -260,15 +265,15 @@ func (client *TransparentVlanEndpointClient) AddEndpointRules(epInfo *EndpointIn
 
 // Add rules related to tunneling the packet outside of the VM, assumes all calls are idempotent. Namespace: vnet
 func (client *TransparentVlanEndpointClient) AddVnetRules(epInfo *EndpointInfo) error {
+	tx := iptables.NewTransaction()
 	// iptables -t mangle -I PREROUTING -j MARK --set-mark <TUNNELING MARK>
 	markOption := fmt.Sprintf("MARK --set-mark %d", tunnelingMark)
-	if err := iptables.InsertIptableRule(iptables.V4, "mangle", "PREROUTING", "", markOption); err != nil {
-		return errors.Wrap(err, "unable to insert iptables rule mark all packets not entering on vlan interface")
-	}
+	tx.InsertRule(iptables.V4, iptables.Mangle, iptables.Prerouting, "", markOption)
 	// iptables -t mangle -I PREROUTING -j ACCEPT -i <VLAN IF>
 	match := fmt.Sprintf("-i %s", client.vlanIfName)
-	if err := iptables.InsertIptableRule(iptables.V4, "mangle", "PREROUTING", match, "ACCEPT"); err != nil {
-		return errors.Wrap(err, "unable to insert iptables rule accept all incoming from vlan interface")
+	tx.InsertRule(iptables.V4, iptables.Mangle, iptables.Prerouting, match, iptables.Accept)
+	if err := tx.Apply(); err != nil {
+		return errors.Wrap(err, "unable to insert iptables rules to mark all packets not entering on vlan interface")
 	}
 	// Packets that are marked should go to the tunneling table
 	newRule := vishnetlink.NewRule()
@@ -411,7 +416,7 @@ func (client *TransparentVlanEndpointClient) GetVnetRoutes(ipAddresses []net.IPN
 		} else {
 			ipNet = net.IPNet{IP: ipAddr.IP, Mask: net.CIDRMask(ipv6FullMask, ipv6Bits)}
 		}
-		log.Printf("[net] transparent vlan client adding route for the ip %v", ipNet.String())
+		logger.Infof("transparent vlan client adding route for the ip %v", ipNet.String())
 		routeInfo.Dst = ipNet
 		routeInfoList = append(routeInfoList, routeInfo)
 
@@ -456,7 +461,7 @@ func (client *TransparentVlanEndpointClient) AddDefaultRoutes(linkToName string,
 // Example: (169.254.1.1) at 12:34:56:78:9a:bc [ether] PERM on <interfaceName>
 func (client *TransparentVlanEndpointClient) AddDefaultArp(interfaceName, destMac string) error {
 	_, virtualGwNet, _ := net.ParseCIDR(virtualGwIPString)
-	log.Printf("[net] Adding static arp for IP address %v and MAC %v in namespace",
+	logger.Infof("Adding static arp for IP address %v and MAC %v in namespace",
 		virtualGwNet.String(), destMac)
 	hardwareAddr, err := net.ParseMAC(destMac)
 	if err != nil {
@@ -496,6 +501,8 @@ func (client *TransparentVlanEndpointClient) DeleteEndpoints(ep *endpoint) error
 	if err := client.DeleteSnatEndpoint(); err != nil {
 		return errors.Wrap(err, "failed to delete snat endpoint")
 	}
+	// the host namespace keeps the entries of connections SNATed to the node
+	deleteConntrackEntries(client.netlink, ep.IPAddresses)
 	return nil
 }
 
@@ -505,18 +512,19 @@ func (client *TransparentVlanEndpointClient) DeleteEndpointsImpl(ep *endpoint, g
 	if err := deleteRoutes(client.netlink, client.netioshim, client.vnetVethName, routeInfoList); err != nil {
 		return errors.Wrap(err, "failed to remove routes")
 	}
+	deleteConntrackEntries(client.netlink, ep.IPAddresses)
 
 	routesLeft, err := getNumRoutesLeft()
 	if err != nil {
 		return err
 	}
 
-	log.Printf("[transparent vlan] There are %d routes remaining after deletion", routesLeft)
+	logger.Infof("[transparent vlan] There are %d routes remaining after deletion", routesLeft)
 
 	if routesLeft <= numDefaultRoutes {
 		// Deletes default arp, default routes, vlan veth; there are two default routes
 		// so when we have <= numDefaultRoutes routes left, no containers use this namespace
-		log.Printf("[transparent vlan] Deleting namespace %s as no containers occupy it", client.vnetNSName)
+		logger.Infof("[transparent vlan] Deleting namespace %s as no containers occupy it", client.vnetNSName)
 		delErr := client.netnsClient.DeleteNamed(client.vnetNSName)
 		if delErr != nil {
 			return errors.Wrap(delErr, "failed to delete namespace")
@@ -531,35 +539,35 @@ func ExecuteInNS(nsName string, f func() error) error {
 	// Current namespace
 	returnedTo, err := GetCurrentThreadNamespace()
 	if err != nil {
-		log.Errorf("[ExecuteInNS] Could not get NS we are in: %v", err)
+		logger.Errorf("[ExecuteInNS] Could not get NS we are in: %v", err)
 	} else {
-		log.Printf("[ExecuteInNS] In NS before switch: %s", returnedTo.file.Name())
+		logger.Infof("[ExecuteInNS] In NS before switch: %s", returnedTo.file.Name())
 	}
 
 	// Open the network namespace
-	log.Printf("[ExecuteInNS] Opening ns %v.", fmt.Sprintf("/var/run/netns/%s", nsName))
+	logger.Infof("[ExecuteInNS] Opening ns %v.", fmt.Sprintf("/var/run/netns/%s", nsName))
 	ns, err := OpenNamespace(fmt.Sprintf("/var/run/netns/%s", nsName))
 	if err != nil {
 		return err
 	}
 	defer ns.Close()
 	// Enter the network namespace
-	log.Printf("[ExecuteInNS] Entering ns %s.", ns.file.Name())
+	logger.Infof("[ExecuteInNS] Entering ns %s.", ns.file.Name())
 	if err := ns.Enter(); err != nil {
 		return err
 	}
 
 	// Exit network namespace
 	defer func() {
-		log.Printf("[ExecuteInNS] Exiting ns %s.", ns.file.Name())
+		logger.Infof("[ExecuteInNS] Exiting ns %s.", ns.file.Name())
 		if err := ns.Exit(); err != nil {
-			log.Errorf("[ExecuteInNS] Could not exit ns, err:%v.", err)
+			logger.Errorf("[ExecuteInNS] Could not exit ns, err:%v.", err)
 		}
 		returnedTo, err := GetCurrentThreadNamespace()
 		if err != nil {
-			log.Errorf("[ExecuteInNS] Could not get NS we returned to: %v", err)
+			logger.Errorf("[ExecuteInNS] Could not get NS we returned to: %v", err)
 		} else {
-			log.Printf("[ExecuteInNS] Returned to NS: %s", returnedTo.file.Name())
+			logger.Infof("[ExecuteInNS] Returned to NS: %s", returnedTo.file.Name())
 		}
 	}()
 	return f()
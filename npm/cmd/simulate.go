@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/simulator"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errManifestNotSpecified = errors.New("at least one manifest file must be specified")
+	errInvalidExpectation   = errors.New("expect must be either allow or deny")
+	errUnexpectedVerdict    = errors.New("simulated verdict differs from the expected verdict")
+)
+
+const (
+	expectAllow = "allow"
+	expectDeny  = "deny"
+)
+
+func newSimulateCmd() *cobra.Command {
+	simulateCmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Evaluate NetworkPolicies from manifest files offline for traffic between a source and destination",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, _ := cmd.Flags().GetStringArray("file")
+			if len(files) == 0 {
+				return fmt.Errorf("%w", errManifestNotSpecified)
+			}
+			src, _ := cmd.Flags().GetString("src")
+			if src == "" {
+				return fmt.Errorf("%w", npmerrors.ErrSrcNotSpecified)
+			}
+			dst, _ := cmd.Flags().GetString("dst")
+			if dst == "" {
+				return fmt.Errorf("%w", npmerrors.ErrDstNotSpecified)
+			}
+			port, _ := cmd.Flags().GetInt32("port")
+			protocol, _ := cmd.Flags().GetString("protocol")
+			expect, _ := cmd.Flags().GetString("expect")
+			expect = strings.ToLower(expect)
+			if expect != "" && expect != expectAllow && expect != expectDeny {
+				return fmt.Errorf("%w: %s", errInvalidExpectation, expect)
+			}
+
+			manifests, err := simulator.LoadManifestFiles(files...)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			s, err := simulator.New(manifests)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			result, err := s.Query(&simulator.Query{Src: src, Dst: dst, Protocol: protocol, Port: port})
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			fmt.Print(result.PrettyString())
+
+			if expect != "" && (expect == expectAllow) != result.Allowed {
+				return fmt.Errorf("%w: expected %s", errUnexpectedVerdict, expect)
+			}
+			return nil
+		},
+	}
+
+	simulateCmd.Flags().StringArrayP("file", "f", nil, "Set a manifest file with namespaces, pods, and NetworkPolicies (can be repeated)")
+	simulateCmd.Flags().StringP("src", "s", "", "set the source (namespace/pod, pod in the default namespace, or IP)")
+	simulateCmd.Flags().StringP("dst", "d", "", "set the destination (namespace/pod, pod in the default namespace, or IP)")
+	simulateCmd.Flags().Int32P("port", "p", 0, "set the destination port")
+	simulateCmd.Flags().String("protocol", "TCP", "set the protocol (TCP, UDP, or SCTP)")
+	simulateCmd.Flags().String("expect", "", "fail unless the verdict is the expected one (allow or deny)")
+
+	return simulateCmd
+}
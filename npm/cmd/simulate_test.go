@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+const (
+	simulateCmdString   = "simulate"
+	clusterManifest     = "../pkg/simulator/testdata/cluster.yaml"
+	policiesManifest    = "../pkg/simulator/testdata/policies.yaml"
+	fileFlag            = "-f"
+	portFlag            = "-p"
+	expectFlag          = "--expect"
+	simulateSrcPod      = "frontend"
+	simulateDstPod      = "backend"
+	simulateAllowedPort = "8080"
+)
+
+func TestSimulateCmd(t *testing.T) {
+	baseArgs := []string{debugCmdString, simulateCmdString}
+	standardArgs := concatArgs(baseArgs, fileFlag, clusterManifest, fileFlag, policiesManifest, srcFlag, simulateSrcPod, dstFlag, simulateDstPod)
+
+	tests := []*testCases{
+		{
+			name:    "no files",
+			args:    concatArgs(baseArgs, srcFlag, simulateSrcPod, dstFlag, simulateDstPod, portFlag, simulateAllowedPort),
+			wantErr: true,
+		},
+		{
+			name:    "no src",
+			args:    concatArgs(baseArgs, fileFlag, clusterManifest, dstFlag, simulateDstPod, portFlag, simulateAllowedPort),
+			wantErr: true,
+		},
+		{
+			name:    "no dst",
+			args:    concatArgs(baseArgs, fileFlag, clusterManifest, srcFlag, simulateSrcPod, portFlag, simulateAllowedPort),
+			wantErr: true,
+		},
+		{
+			name:    "no port",
+			args:    standardArgs,
+			wantErr: true,
+		},
+		{
+			name:    "non-existing file",
+			args:    concatArgs(baseArgs, fileFlag, nonExistingFile, srcFlag, simulateSrcPod, dstFlag, simulateDstPod, portFlag, simulateAllowedPort),
+			wantErr: true,
+		},
+		{
+			name:    "unknown pod",
+			args:    concatArgs(baseArgs, fileFlag, clusterManifest, srcFlag, "unknown", dstFlag, simulateDstPod, portFlag, simulateAllowedPort),
+			wantErr: true,
+		},
+		{
+			name:    "invalid expectation",
+			args:    concatArgs(standardArgs, portFlag, simulateAllowedPort, expectFlag, "maybe"),
+			wantErr: true,
+		},
+		{
+			name:    "allowed",
+			args:    concatArgs(standardArgs, portFlag, simulateAllowedPort),
+			wantErr: false,
+		},
+		{
+			name:    "expected allow",
+			args:    concatArgs(standardArgs, portFlag, simulateAllowedPort, expectFlag, "allow"),
+			wantErr: false,
+		},
+		{
+			name:    "unexpected allow",
+			args:    concatArgs(standardArgs, portFlag, simulateAllowedPort, expectFlag, "deny"),
+			wantErr: true,
+		},
+		{
+			name:    "expected deny",
+			args:    concatArgs(standardArgs, portFlag, "8081", expectFlag, "deny"),
+			wantErr: false,
+		},
+	}
+
+	testCommand(t, tests)
+}
@@ -14,14 +14,18 @@ import (
 	restserver "github.com/Azure/azure-container-networking/npm/http/server"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/droplog"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/rulehits"
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/apimachinery/pkg/util/wait"
 	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -125,22 +129,39 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	if config.Toggles.EnableV2NPM {
 		// update the dataplane config
 		npmV2DataplaneCfg.PlaceAzureChainFirst = config.Toggles.PlaceAzureChainFirst
+		npmV2DataplaneCfg.EnableDropLogging = config.Toggles.EnableDropLogging
+		npmV2DataplaneCfg.EnableAdminNetworkPolicies = config.Toggles.EnableAdminNetworkPolicies && !util.IsWindowsDP()
 		if config.Toggles.ApplyIPSetsOnNeed {
 			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyOnNeed
 		} else {
 			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyAllIPSets
 		}
 
-		dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
+		var v2dp *dataplane.DataPlane
+		v2dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
 		if err != nil {
 			return fmt.Errorf("failed to create dataplane with error %w", err)
 		}
+		dp = v2dp
 		dp.RunPeriodicTasks()
+		if !util.IsWindowsDP() {
+			startDropLogCollectorWhenNeeded(v2dp, config.Toggles.EnableDropLogging, stopChannel)
+		}
+		if config.Toggles.EnableRuleHitCounters && !util.IsWindowsDP() {
+			startRuleHitCollector(config.RuleHitCounters, stopChannel)
+		}
 	}
 	npMgr := npm.NewNetworkPolicyManager(config, factory, dp, exec.New(), version, k8sServerVersion)
-	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
+	if config.Toggles.EnableV2NPM && npmV2DataplaneCfg.EnableAdminNetworkPolicies {
+		dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create dynamic client with error %w", err)
+		}
+		npMgr.WatchAdminNetworkPolicies(dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resyncPeriod))
+	}
+	err = createTelemetryHandle(config)
 	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+		klog.Infof("CreateTelemetryHandle failed with error %v. Telemetry is not initialized.", err)
 	}
 
 	go restserver.NPMRestServerListenAndServe(config, npMgr)
@@ -154,6 +175,52 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	select {}
 }
 
+// startDropLogCollector listens for the NFLOG entries of policy drop rules and audit rules.
+// NPM keeps running if the collector can't start.
+func startDropLogCollector(dp *dataplane.DataPlane, stopChannel <-chan struct{}) {
+	source, err := droplog.NewNFLogSource(util.IptablesNFLogGroup)
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to start drop log collector: %s", err.Error())
+		return
+	}
+	klog.Infof("collecting dropped packets from NFLOG group %d", util.IptablesNFLogGroup)
+	go droplog.NewCollector(source, dp).Run(stopChannel)
+}
+
+// createTelemetryHandle initializes telemetry with the exporter selected in the NPM config.
+func createTelemetryHandle(config npmconfig.Config) error {
+	if config.Telemetry.Exporter == npmconfig.TelemetryExporterOTLP {
+		return metrics.CreateOTLPTelemetryHandle(config.NPMVersion(), version, config.Telemetry.OTLP) //nolint:wrapcheck // logged by the caller
+	}
+	return metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata()) //nolint:wrapcheck // logged by the caller
+}
+
+// startDropLogCollectorWhenNeeded starts the drop log collector right away with drop logging,
+// else once the first policy in audit mode is added, since audit rules log even without drop logging.
+func startDropLogCollectorWhenNeeded(dp *dataplane.DataPlane, enableDropLogging bool, stopChannel <-chan struct{}) {
+	if enableDropLogging {
+		startDropLogCollector(dp, stopChannel)
+		return
+	}
+	go func() {
+		select {
+		case <-dp.AuditedPolicyAdded():
+			startDropLogCollector(dp, stopChannel)
+		case <-stopChannel:
+		}
+	}()
+}
+
+// startRuleHitCollector periodically exports the packet and byte counters of the policy rules.
+func startRuleHitCollector(cfg npmconfig.RuleHitCountersConfig, stopChannel <-chan struct{}) {
+	collectorCfg := rulehits.Config{
+		Interval:    time.Duration(cfg.IntervalInSeconds) * time.Second,
+		MaxPolicies: cfg.MaxPolicies,
+	}
+	klog.Infof("exporting policy rule hit counters with config: %+v", collectorCfg)
+	go rulehits.NewCollector(common.NewIOShim(), collectorCfg).Run(stopChannel)
+}
+
 func initLogging() error {
 	log.SetName("azure-npm")
 	log.SetLevel(log.LevelInfo)
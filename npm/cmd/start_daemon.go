@@ -7,7 +7,6 @@ import (
 	"strconv"
 
 	"github.com/Azure/azure-container-networking/common"
-	"github.com/Azure/azure-container-networking/npm"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/daemon"
 	restserver "github.com/Azure/azure-container-networking/npm/http/server"
@@ -66,13 +65,21 @@ func startDaemon(config npmconfig.Config) error {
 
 	var dp dataplane.GenericDataplane
 
-	dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, wait.NeverStop)
+	npmV2DataplaneCfg.EnableDropLogging = config.Toggles.EnableDropLogging
+	v2dp, err := dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, wait.NeverStop)
 	if err != nil {
 		klog.Errorf("failed to create dataplane: %v", err)
 		return fmt.Errorf("failed to create dataplane with error %w", err)
 	}
+	dp = v2dp
 
 	dp.RunPeriodicTasks()
+	if !util.IsWindowsDP() {
+		startDropLogCollectorWhenNeeded(v2dp, config.Toggles.EnableDropLogging, wait.NeverStop)
+	}
+	if config.Toggles.EnableRuleHitCounters && !util.IsWindowsDP() {
+		startRuleHitCollector(config.RuleHitCounters, wait.NeverStop)
+	}
 	// TODO Daemon should implement cache encoder
 	go restserver.NPMRestServerListenAndServe(config, nil)
 
@@ -94,9 +101,9 @@ func startDaemon(config npmconfig.Config) error {
 		return fmt.Errorf("failed to create dataplane: %w", err)
 	}
 
-	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
+	err = createTelemetryHandle(config)
 	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+		klog.Infof("CreateTelemetryHandle failed with error %v. Telemetry is not initialized.", err)
 	}
 
 	err = n.Start(config, wait.NeverStop)
@@ -6,7 +6,6 @@ import (
 	"math/rand"
 	"time"
 
-	"github.com/Azure/azure-container-networking/npm"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/controller"
 	restserver "github.com/Azure/azure-container-networking/npm/http/server"
@@ -113,9 +112,9 @@ func startControlplane(config npmconfig.Config, flags npmconfig.Flags) error {
 		return fmt.Errorf("failed to create NPM controlplane manager: %w", err)
 	}
 
-	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
+	err = createTelemetryHandle(config)
 	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+		klog.Infof("CreateTelemetryHandle failed with error %v. Telemetry is not initialized.", err)
 	}
 
 	go restserver.NPMRestServerListenAndServe(config, npMgr)
@@ -1,6 +1,9 @@
 package npmconfig
 
-import "github.com/Azure/azure-container-networking/npm/util"
+import (
+	"github.com/Azure/azure-container-networking/aitelemetry/otlp"
+	"github.com/Azure/azure-container-networking/npm/util"
+)
 
 const (
 	defaultResyncPeriod    = 15
@@ -10,6 +13,9 @@ const (
 	// ConfigEnvPath is what's used by viper to load config path
 	ConfigEnvPath = "NPM_CONFIG"
 
+	// TelemetryExporterOTLP selects the OpenTelemetry exporter in TelemetryConfig.Exporter
+	TelemetryExporterOTLP = "otlp"
+
 	v1 = 1
 	v2 = 2
 )
@@ -34,6 +40,10 @@ var DefaultConfig = Config{
 		EnableV2NPM:             true,
 		PlaceAzureChainFirst:    util.PlaceAzureChainFirst,
 		ApplyIPSetsOnNeed:       false,
+		EnableDropLogging:       false,
+		EnableRuleHitCounters:   false,
+
+		EnableAdminNetworkPolicies: false,
 	},
 }
 
@@ -46,6 +56,15 @@ type GrpcServerConfig struct {
 	ServicePort int `json:"ServicePort,omitempty"`
 }
 
+// RuleHitCountersConfig configures the export of per-policy rule hit counters.
+// Zero values use the defaults of the rulehits package.
+type RuleHitCountersConfig struct {
+	// IntervalInSeconds is how often iptables counters are read
+	IntervalInSeconds int `json:"IntervalInSeconds,omitempty"`
+	// MaxPolicies limits how many policies are exported with their own policy_key label
+	MaxPolicies int `json:"MaxPolicies,omitempty"`
+}
+
 type Config struct {
 	ResyncPeriodInMinutes int `json:"ResyncPeriodInMinutes,omitempty"`
 
@@ -54,7 +73,19 @@ type Config struct {
 
 	Transport GrpcServerConfig `json:"Transport,omitempty"`
 
+	RuleHitCounters RuleHitCountersConfig `json:"RuleHitCounters,omitempty"`
+
 	Toggles Toggles `json:"Toggles,omitempty"`
+
+	Telemetry TelemetryConfig `json:"Telemetry,omitempty"`
+}
+
+// TelemetryConfig selects where NPM sends its telemetry.
+type TelemetryConfig struct {
+	// Exporter is appinsights (default) or otlp
+	Exporter string `json:"Exporter,omitempty"`
+	// OTLP configures the OpenTelemetry collector telemetry is exported to when Exporter is otlp
+	OTLP otlp.Config `json:"OTLP,omitempty"`
 }
 
 type Toggles struct {
@@ -64,6 +95,12 @@ type Toggles struct {
 	EnableV2NPM             bool
 	PlaceAzureChainFirst    bool
 	ApplyIPSetsOnNeed       bool
+	// EnableDropLogging logs packets dropped by network policies (Linux only)
+	EnableDropLogging bool
+	// EnableRuleHitCounters exports packet and byte counters of each network policy's rules (Linux only)
+	EnableRuleHitCounters bool
+	// EnableAdminNetworkPolicies watches AdminNetworkPolicy and BaselineAdminNetworkPolicy CRDs (Linux only)
+	EnableAdminNetworkPolicies bool
 }
 
 type Flags struct {
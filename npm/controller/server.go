@@ -92,6 +92,7 @@ func NewNetworkPolicyServer(
 	n.PodControllerV2 = controllersv2.NewPodController(n.PodInformer, dp, n.NpmNamespaceCacheV2)
 	n.NamespaceControllerV2 = controllersv2.NewNamespaceController(n.NsInformer, dp, n.NpmNamespaceCacheV2)
 	n.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(n.NpInformer, dp)
+	n.NetPolControllerV2.WatchNamespaceAuditMode(n.NsInformer)
 
 	return n, nil
 }
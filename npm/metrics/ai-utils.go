@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/Azure/azure-container-networking/aitelemetry/otlp"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"k8s.io/klog"
@@ -54,6 +55,22 @@ func CreateTelemetryHandle(npmVersionNum int, imageVersion, aiMetadata string) e
 	return nil
 }
 
+// CreateOTLPTelemetryHandle creates a handler exporting telemetry to the OpenTelemetry collector in cfg
+func CreateOTLPTelemetryHandle(npmVersionNum int, imageVersion string, cfg otlp.Config) error {
+	npmVersion = npmVersionNum
+	cfg.AppName = util.AzureNpmFlag
+	cfg.AppVersion = imageVersion
+
+	otlpHandle, err := otlp.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP telemetry handle: %w", err)
+	}
+
+	th = otlpHandle
+	log.Logf("Initialized OTLP telemetry handle for %s", cfg.Endpoint)
+	return nil
+}
+
 // SendErrorLogAndMetric sends a metric through AI telemetry and sends a log to the Kusto Messages table
 func SendErrorLogAndMetric(operationID int, format string, args ...interface{}) {
 	// Send error metrics
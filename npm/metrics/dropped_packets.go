@@ -0,0 +1,29 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// IncDroppedPackets increments the number of packets logged as dropped by the policy in the direction ("ingress" or "egress").
+func IncDroppedPackets(policyKey, direction string) {
+	droppedPackets.With(getDroppedPacketsLabels(policyKey, direction)).Inc()
+}
+
+// GetDroppedPackets returns the number of packets logged as dropped by the policy in the direction.
+// This function is slow.
+func GetDroppedPackets(policyKey, direction string) (int, error) {
+	return getCounterVecValue(droppedPackets, getDroppedPacketsLabels(policyKey, direction))
+}
+
+// IncWouldBeDroppedPackets increments the number of packets logged as would-be drops by the policy in audit mode.
+func IncWouldBeDroppedPackets(policyKey, direction string) {
+	wouldBeDroppedPackets.With(getDroppedPacketsLabels(policyKey, direction)).Inc()
+}
+
+// GetWouldBeDroppedPackets returns the number of packets logged as would-be drops by the policy in the direction.
+// This function is slow.
+func GetWouldBeDroppedPackets(policyKey, direction string) (int, error) {
+	return getCounterVecValue(wouldBeDroppedPackets, getDroppedPacketsLabels(policyKey, direction))
+}
+
+func getDroppedPacketsLabels(policyKey, direction string) prometheus.Labels {
+	return prometheus.Labels{policyKeyLabel: policyKey, directionLabel: direction}
+}
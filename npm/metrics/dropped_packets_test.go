@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncDroppedPackets(t *testing.T) {
+	IncDroppedPackets("x/policy", "ingress")
+	IncDroppedPackets("x/policy", "ingress")
+	IncDroppedPackets("x/policy", "egress")
+
+	val, err := GetDroppedPackets("x/policy", "ingress")
+	require.NoError(t, err)
+	require.Equal(t, 2, val)
+
+	val, err = GetDroppedPackets("x/policy", "egress")
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+
+	val, err = GetDroppedPackets("y/other-policy", "ingress")
+	require.NoError(t, err)
+	require.Equal(t, 0, val)
+}
+
+func TestIncWouldBeDroppedPackets(t *testing.T) {
+	IncWouldBeDroppedPackets("x/audited-policy", "egress")
+
+	val, err := GetWouldBeDroppedPackets("x/audited-policy", "egress")
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+
+	val, err = GetDroppedPackets("x/audited-policy", "egress")
+	require.NoError(t, err)
+	require.Equal(t, 0, val)
+}
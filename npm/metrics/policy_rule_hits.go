@@ -0,0 +1,33 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// AddPolicyRuleHits adds to the packets and bytes matched by the policy's rules with the verdict in the direction ("ingress" or "egress").
+func AddPolicyRuleHits(policyKey, direction, verdict string, packets, bytes uint64) {
+	labels := getPolicyRuleHitLabels(policyKey, direction, verdict)
+	policyRuleHitPackets.With(labels).Add(float64(packets))
+	policyRuleHitBytes.With(labels).Add(float64(bytes))
+}
+
+// RemovePolicyRuleHits stops exporting the rule hits of the policy's rules with the verdict in the direction.
+func RemovePolicyRuleHits(policyKey, direction, verdict string) {
+	labels := getPolicyRuleHitLabels(policyKey, direction, verdict)
+	policyRuleHitPackets.Delete(labels)
+	policyRuleHitBytes.Delete(labels)
+}
+
+// GetPolicyRuleHitPackets returns the number of packets matched by the policy's rules with the verdict in the direction.
+// This function is slow.
+func GetPolicyRuleHitPackets(policyKey, direction, verdict string) (int, error) {
+	return getCounterVecValue(policyRuleHitPackets, getPolicyRuleHitLabels(policyKey, direction, verdict))
+}
+
+// GetPolicyRuleHitBytes returns the number of bytes matched by the policy's rules with the verdict in the direction.
+// This function is slow.
+func GetPolicyRuleHitBytes(policyKey, direction, verdict string) (int, error) {
+	return getCounterVecValue(policyRuleHitBytes, getPolicyRuleHitLabels(policyKey, direction, verdict))
+}
+
+func getPolicyRuleHitLabels(policyKey, direction, verdict string) prometheus.Labels {
+	return prometheus.Labels{policyKeyLabel: policyKey, directionLabel: direction, verdictLabel: verdict}
+}
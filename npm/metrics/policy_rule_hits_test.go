@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddPolicyRuleHits(t *testing.T) {
+	AddPolicyRuleHits("x/policy", "ingress", "allow", 2, 100)
+	AddPolicyRuleHits("x/policy", "ingress", "allow", 3, 50)
+	AddPolicyRuleHits("x/policy", "egress", "drop", 1, 60)
+
+	val, err := GetPolicyRuleHitPackets("x/policy", "ingress", "allow")
+	require.NoError(t, err)
+	require.Equal(t, 5, val)
+	val, err = GetPolicyRuleHitBytes("x/policy", "ingress", "allow")
+	require.NoError(t, err)
+	require.Equal(t, 150, val)
+
+	RemovePolicyRuleHits("x/policy", "ingress", "allow")
+	val, err = GetPolicyRuleHitPackets("x/policy", "ingress", "allow")
+	require.NoError(t, err)
+	require.Equal(t, 0, val)
+	val, err = GetPolicyRuleHitPackets("x/policy", "egress", "drop")
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+}
@@ -40,6 +40,18 @@ const (
 	setNameLabel       = "set_name"
 	setHashLabel       = "set_hash"
 
+	droppedPacketsName        = "dropped_packets"
+	droppedPacketsHelp        = "The number of packets logged as dropped by each network policy. Drop logs are rate limited, so this is a lower bound"
+	wouldBeDroppedPacketsName = "would_be_dropped_packets"
+	wouldBeDroppedPacketsHelp = "The number of packets logged as would-be drops by each network policy in audit mode. Audit logs are rate limited, so this is a lower bound"
+	policyRuleHitPacketsName  = "policy_rule_hit_packets"
+	policyRuleHitPacketsHelp  = "The number of packets matched by the iptables rules of each network policy, by verdict of the rules"
+	policyRuleHitBytesName    = "policy_rule_hit_bytes"
+	policyRuleHitBytesHelp    = "The number of bytes matched by the iptables rules of each network policy, by verdict of the rules"
+	verdictLabel              = "verdict"
+	policyKeyLabel            = "policy_key"
+	directionLabel            = "direction"
+
 	// perf metrics added after v1.4.16
 	// all these metrics have "npm_controller_" prepended to their name
 	operationLabel = "operation"
@@ -67,7 +79,8 @@ const (
 // Gauge metrics have the methods Inc(), Dec(), and Set(float64)
 // Summary metrics have the method Observe(float64)
 // For any Vector metric, you can call With(prometheus.Labels) before the above methods
-//   e.g. SomeGaugeVec.With(prometheus.Labels{label1: val1, label2: val2, ...).Dec()
+//
+//	e.g. SomeGaugeVec.With(prometheus.Labels{label1: val1, label2: val2, ...).Dec()
 var (
 	nodeRegistry    = prometheus.NewRegistry()
 	clusterRegistry = prometheus.NewRegistry()
@@ -76,14 +89,20 @@ var (
 	// quantiles e.g. the "0.5 quantile" with delta 0.05 will actually be the phi quantile for some phi in [0.5 - 0.05, 0.5 + 0.05]
 	execTimeQuantiles = map[float64]float64{quantileMedian: deltaMedian, quantile90th: delta90th, quantil99th: delta99th}
 
-	numPolicies          prometheus.Gauge
-	numACLRules          prometheus.Gauge
-	addACLRuleExecTime   prometheus.Summary
-	numIPSets            prometheus.Gauge
-	addIPSetExecTime     prometheus.Summary
-	numIPSetEntries      prometheus.Gauge
-	ipsetInventory       *prometheus.GaugeVec
-	ipsetInventoryLabels = []string{setNameLabel, setHashLabel}
+	numPolicies           prometheus.Gauge
+	numACLRules           prometheus.Gauge
+	addACLRuleExecTime    prometheus.Summary
+	numIPSets             prometheus.Gauge
+	addIPSetExecTime      prometheus.Summary
+	numIPSetEntries       prometheus.Gauge
+	ipsetInventory        *prometheus.GaugeVec
+	ipsetInventoryLabels  = []string{setNameLabel, setHashLabel}
+	droppedPackets        *prometheus.CounterVec
+	droppedPacketsLabels  = []string{policyKeyLabel, directionLabel}
+	wouldBeDroppedPackets *prometheus.CounterVec
+	policyRuleHitPackets  *prometheus.CounterVec
+	policyRuleHitBytes    *prometheus.CounterVec
+	policyRuleHitLabels   = []string{policyKeyLabel, directionLabel, verdictLabel}
 
 	// controller perf metrics
 	// used to be a regular Summary in v1.4.16 and below
@@ -171,6 +190,10 @@ func initializeDaemonMetrics() {
 	// NODE METRICS
 	addACLRuleExecTime = createNodeSummary(addACLRuleExecTimeName, addACLRuleExecTimeHelp)
 	addIPSetExecTime = createNodeSummary(addIPSetExecTimeName, addIPSetExecTimeHelp)
+	droppedPackets = createNodeCounterVec(droppedPacketsName, droppedPacketsHelp, droppedPacketsLabels)
+	wouldBeDroppedPackets = createNodeCounterVec(wouldBeDroppedPacketsName, wouldBeDroppedPacketsHelp, droppedPacketsLabels)
+	policyRuleHitPackets = createNodeCounterVec(policyRuleHitPacketsName, policyRuleHitPacketsHelp, policyRuleHitLabels)
+	policyRuleHitBytes = createNodeCounterVec(policyRuleHitBytesName, policyRuleHitBytesHelp, policyRuleHitLabels)
 }
 
 // initializeControllerMetrics creates metrics modified by the controller
@@ -228,6 +251,19 @@ func createClusterGaugeVec(name, helpMessage string, labels []string) *prometheu
 	return gaugeVec
 }
 
+func createNodeCounterVec(name, helpMessage string, labels []string) *prometheus.CounterVec {
+	counterVec := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      helpMessage,
+		},
+		labels,
+	)
+	register(counterVec, name, NodeMetrics)
+	return counterVec
+}
+
 func createNodeSummary(name, helpMessage string) prometheus.Summary {
 	// uses default observation TTL of 10 minutes
 	summary := prometheus.NewSummary(
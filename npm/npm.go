@@ -8,6 +8,7 @@ import (
 
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	"github.com/Azure/azure-container-networking/npm/ipsm"
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
 	controllersv1 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v1"
 	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
@@ -15,6 +16,7 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
@@ -37,6 +39,9 @@ type NetworkPolicyManager struct {
 	// https://pkg.go.dev/k8s.io/client-go/informers
 	models.Informers
 
+	// AdminInformers are only set when AdminNetworkPolicies are watched
+	models.AdminInformers
+
 	// Legacy controllers for handling Kubernetes resource watcher events
 	// To be deprecated
 	models.K8SControllersV1
@@ -81,6 +86,7 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 		npMgr.NamespaceControllerV2 = controllersv2.NewNamespaceController(npMgr.NsInformer, dp, npMgr.NpmNamespaceCacheV2)
 		// Question(jungukcho): Is config.Toggles.PlaceAzureChainFirst needed for v2?
 		npMgr.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(npMgr.NpInformer, dp)
+		npMgr.NetPolControllerV2.WatchNamespaceAuditMode(npMgr.NsInformer)
 		return npMgr
 	}
 
@@ -94,6 +100,22 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 	return npMgr
 }
 
+// WatchAdminNetworkPolicies creates the AdminNetworkPolicy and BaselineAdminNetworkPolicy
+// informers and controller. It must be called before Start and is only supported with v2 NPM.
+func (npMgr *NetworkPolicyManager) WatchAdminNetworkPolicies(factory dynamicinformer.DynamicSharedInformerFactory) {
+	if !npMgr.config.Toggles.EnableV2NPM {
+		klog.Warning("AdminNetworkPolicies are only supported with v2 NPM")
+		return
+	}
+
+	npMgr.AdminInformers = models.AdminInformers{
+		DynamicInformerFactory: factory,
+		AnpInformer:            factory.ForResource(v1alpha1.AdminNetworkPolicyResource),
+		BanpInformer:           factory.ForResource(v1alpha1.BaselineAdminNetworkPolicyResource),
+	}
+	npMgr.AdminNetPolControllerV2 = controllersv2.NewAdminNetworkPolicyController(npMgr.AnpInformer, npMgr.BanpInformer, npMgr.Dataplane)
+}
+
 // Dear Time Traveler:
 // This is the server end of the debug dragons den. Several of these properties of the
 // npMgr struct have overridden methods which override the MarshalJson, just as this one
@@ -198,6 +220,11 @@ func (npMgr *NetworkPolicyManager) Start(config npmconfig.Config, stopCh <-chan
 		go npMgr.PodControllerV2.Run(stopCh)
 		go npMgr.NamespaceControllerV2.Run(stopCh)
 		go npMgr.NetPolControllerV2.Run(stopCh)
+		if npMgr.AdminNetPolControllerV2 != nil {
+			// the CRDs may not be installed, so the admin controller waits for its own cache sync
+			npMgr.DynamicInformerFactory.Start(stopCh)
+			go npMgr.AdminNetPolControllerV2.Run(stopCh)
+		}
 		return nil
 	}
 
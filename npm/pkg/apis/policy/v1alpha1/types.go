@@ -0,0 +1,170 @@
+// Package v1alpha1 mirrors the subset of the policy.networking.k8s.io/v1alpha1 API
+// (AdminNetworkPolicy and BaselineAdminNetworkPolicy) which NPM translates.
+// The upstream module requires a newer Kubernetes client than NPM uses, so these objects are watched
+// with the dynamic client and converted from unstructured objects.
+package v1alpha1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const GroupName = "policy.networking.k8s.io"
+
+var (
+	SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+	AdminNetworkPolicyResource         = SchemeGroupVersion.WithResource("adminnetworkpolicies")
+	BaselineAdminNetworkPolicyResource = SchemeGroupVersion.WithResource("baselineadminnetworkpolicies")
+)
+
+// AdminNetworkPolicyRuleAction is the action of a rule.
+type AdminNetworkPolicyRuleAction string
+
+const (
+	// AdminNetworkPolicyRuleActionAllow allows the traffic regardless of lower priority policies.
+	AdminNetworkPolicyRuleActionAllow AdminNetworkPolicyRuleAction = "Allow"
+	// AdminNetworkPolicyRuleActionDeny denies the traffic regardless of lower priority policies.
+	AdminNetworkPolicyRuleActionDeny AdminNetworkPolicyRuleAction = "Deny"
+	// AdminNetworkPolicyRuleActionPass skips lower priority AdminNetworkPolicies so that NetworkPolicies decide.
+	AdminNetworkPolicyRuleActionPass AdminNetworkPolicyRuleAction = "Pass"
+)
+
+// BaselineAdminNetworkPolicyRuleAction is the action of a baseline rule, which can't pass.
+type BaselineAdminNetworkPolicyRuleAction string
+
+const (
+	BaselineAdminNetworkPolicyRuleActionAllow BaselineAdminNetworkPolicyRuleAction = "Allow"
+	BaselineAdminNetworkPolicyRuleActionDeny  BaselineAdminNetworkPolicyRuleAction = "Deny"
+)
+
+// AdminNetworkPolicy is a cluster-scoped policy which is evaluated before NetworkPolicies.
+type AdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AdminNetworkPolicySpec `json:"spec"`
+}
+
+type AdminNetworkPolicySpec struct {
+	// Priority is between 0 and 1000, where policies with lower values are evaluated first.
+	Priority int32                           `json:"priority"`
+	Subject  AdminNetworkPolicySubject       `json:"subject"`
+	Ingress  []AdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress   []AdminNetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+// AdminNetworkPolicySubject selects the pods which the policy applies to. Exactly one field is set.
+type AdminNetworkPolicySubject struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPodSubject `json:"pods,omitempty"`
+}
+
+type NamespacedPodSubject struct {
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	PodSelector       metav1.LabelSelector `json:"podSelector"`
+}
+
+type AdminNetworkPolicyIngressRule struct {
+	Name   string                       `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction `json:"action"`
+	From   []AdminNetworkPolicyPeer     `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort    `json:"ports,omitempty"`
+}
+
+type AdminNetworkPolicyEgressRule struct {
+	Name   string                       `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction `json:"action"`
+	To     []AdminNetworkPolicyPeer     `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort    `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyPeer selects the pods on the other side of the traffic. Exactly one field is set.
+type AdminNetworkPolicyPeer struct {
+	Namespaces *NamespacedPeer    `json:"namespaces,omitempty"`
+	Pods       *NamespacedPodPeer `json:"pods,omitempty"`
+}
+
+// NamespacedPeer selects namespaces. Exactly one field is set.
+type NamespacedPeer struct {
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// SameLabels selects the namespaces with the same values for these labels as the subject's namespace.
+	SameLabels []string `json:"sameLabels,omitempty"`
+	// NotSameLabels selects the namespaces with different values for these labels than the subject's namespace.
+	NotSameLabels []string `json:"notSameLabels,omitempty"`
+}
+
+type NamespacedPodPeer struct {
+	Namespaces  NamespacedPeer       `json:"namespaces"`
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+}
+
+// AdminNetworkPolicyPort selects destination ports. Exactly one field is set.
+type AdminNetworkPolicyPort struct {
+	PortNumber *Port      `json:"portNumber,omitempty"`
+	NamedPort  *string    `json:"namedPort,omitempty"`
+	PortRange  *PortRange `json:"portRange,omitempty"`
+}
+
+type Port struct {
+	Protocol corev1.Protocol `json:"protocol"`
+	Port     int32           `json:"port"`
+}
+
+type PortRange struct {
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+	Start    int32           `json:"start"`
+	End      int32           `json:"end"`
+}
+
+// BaselineAdminNetworkPolicy is a cluster-scoped singleton named "default"
+// which is evaluated when no NetworkPolicy applies.
+type BaselineAdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BaselineAdminNetworkPolicySpec `json:"spec"`
+}
+
+type BaselineAdminNetworkPolicySpec struct {
+	Subject AdminNetworkPolicySubject               `json:"subject"`
+	Ingress []BaselineAdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress  []BaselineAdminNetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+type BaselineAdminNetworkPolicyIngressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	From   []AdminNetworkPolicyPeer             `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+type BaselineAdminNetworkPolicyEgressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	To     []AdminNetworkPolicyPeer             `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyFromUnstructured converts an object from the dynamic client.
+func AdminNetworkPolicyFromUnstructured(obj *unstructured.Unstructured) (*AdminNetworkPolicy, error) {
+	anp := &AdminNetworkPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), anp); err != nil {
+		return nil, fmt.Errorf("failed to convert AdminNetworkPolicy %s: %w", obj.GetName(), err)
+	}
+	return anp, nil
+}
+
+// BaselineAdminNetworkPolicyFromUnstructured converts an object from the dynamic client.
+func BaselineAdminNetworkPolicyFromUnstructured(obj *unstructured.Unstructured) (*BaselineAdminNetworkPolicy, error) {
+	banp := &BaselineAdminNetworkPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), banp); err != nil {
+		return nil, fmt.Errorf("failed to convert BaselineAdminNetworkPolicy %s: %w", obj.GetName(), err)
+	}
+	return banp, nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+)
+
+var (
+	errAdminPolicyKeyFormat          = errors.New("invalid admin network policy key format")
+	errAdminPolicyTranslationFailure = errors.New("failed to translate admin network policy")
+)
+
+// AdminNetworkPolicyController handles both AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+// Both are cluster-scoped, so the workqueue holds NPM policy keys of the form <tier>/<name> to tell them apart.
+type AdminNetworkPolicyController struct {
+	sync.RWMutex
+	anpInformer  informers.GenericInformer
+	banpInformer informers.GenericInformer
+	workqueue    workqueue.RateLimitingInterface
+	// rawSpecMap holds the last applied *v1alpha1.AdminNetworkPolicySpec or *v1alpha1.BaselineAdminNetworkPolicySpec.
+	// Key is the NPM policy key.
+	rawSpecMap map[string]interface{}
+	dp         dataplane.GenericDataplane
+}
+
+func NewAdminNetworkPolicyController(anpInformer, banpInformer informers.GenericInformer, dp dataplane.GenericDataplane) *AdminNetworkPolicyController {
+	c := &AdminNetworkPolicyController{
+		anpInformer:  anpInformer,
+		banpInformer: banpInformer,
+		workqueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AdminNetworkPolicy"),
+		rawSpecMap:   make(map[string]interface{}),
+		dp:           dp,
+	}
+
+	anpInformer.Informer().AddEventHandler(c.eventHandler(policies.AdminTier))
+	banpInformer.Informer().AddEventHandler(c.eventHandler(policies.BaselineAdminTier))
+	return c
+}
+
+func (c *AdminNetworkPolicyController) LengthOfRawSpecMap() int {
+	return len(c.rawSpecMap)
+}
+
+func (c *AdminNetworkPolicyController) eventHandler(tier policies.Tier) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueue(tier, obj)
+		},
+		UpdateFunc: func(old, newObj interface{}) {
+			oldPolicy, oldOK := old.(*unstructured.Unstructured)
+			newPolicy, newOK := newObj.(*unstructured.Unstructured)
+			if oldOK && newOK && oldPolicy.GetResourceVersion() == newPolicy.GetResourceVersion() {
+				// Periodic resync will send update events for all known policies.
+				return
+			}
+			c.enqueue(tier, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			// DeleteFunc gets an object of type DeletedFinalStateUnknown if the watch missed the delete event
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.enqueue(tier, obj)
+		},
+	}
+}
+
+func (c *AdminNetworkPolicyController) enqueue(tier policies.Tier, obj interface{}) {
+	policyObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		metrics.SendErrorLogAndMetric(util.NetpolID, "[ADMIN NETPOL EVENT] Received unexpected object type: %v", obj)
+		return
+	}
+	c.workqueue.Add(adminPolicyKey(tier, policyObj.GetName()))
+}
+
+// adminPolicyKey matches the PolicyKey of the translated NPMNetworkPolicy.
+func adminPolicyKey(tier policies.Tier, name string) string {
+	return fmt.Sprintf("%s/%s", tier, name)
+}
+
+func splitAdminPolicyKey(key string) (policies.Tier, string, error) {
+	tier, name, ok := strings.Cut(key, "/")
+	if !ok || name == "" || (policies.Tier(tier) != policies.AdminTier && policies.Tier(tier) != policies.BaselineAdminTier) {
+		return "", "", fmt.Errorf("invalid resource key: %s err: %w", key, errAdminPolicyKeyFormat)
+	}
+	return policies.Tier(tier), name, nil
+}
+
+func (c *AdminNetworkPolicyController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	// The CRDs may not be installed, so wait here instead of blocking the other controllers.
+	klog.Infof("Waiting for AdminNetworkPolicy and BaselineAdminNetworkPolicy informers to sync")
+	if !cache.WaitForCacheSync(stopCh, c.anpInformer.Informer().HasSynced, c.banpInformer.Informer().HasSynced) {
+		metrics.SendErrorLogAndMetric(util.NetpolID, "error: AdminNetworkPolicy informers failed to sync")
+		return
+	}
+
+	klog.Infof("Starting Admin Network Policy worker")
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	klog.Infof("Started Admin Network Policy worker")
+	<-stopCh
+	klog.Info("Shutting down Admin Network Policy workers")
+}
+
+func (c *AdminNetworkPolicyController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *AdminNetworkPolicyController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.workqueue.Done(obj)
+		key, ok := obj.(string)
+		if !ok {
+			c.workqueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v, err %w", obj, errWorkqueueFormatting))
+			return nil
+		}
+		if err := c.syncAdminPolicy(key); err != nil {
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %w, requeuing", key, err)
+		}
+		c.workqueue.Forget(obj)
+		klog.Infof("Successfully synced '%s'", key)
+		return nil
+	}(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		metrics.SendErrorLogAndMetric(util.NetpolID, "syncAdminPolicy error due to %v", err)
+		return true
+	}
+
+	return true
+}
+
+// syncAdminPolicy compares the actual state with the desired, and attempts to converge the two.
+func (c *AdminNetworkPolicyController) syncAdminPolicy(key string) error {
+	tier, name, err := splitAdminPolicyKey(key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return nil //nolint HandleError is used instead of returning error to caller
+	}
+
+	lister := c.anpInformer.Lister()
+	if tier == policies.BaselineAdminTier {
+		lister = c.banpInformer.Lister()
+	}
+	obj, err := lister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Infof("%s tier policy %s is not found, may be it is deleted", tier, name)
+			return c.cleanUpAdminPolicy(key)
+		}
+		return err
+	}
+
+	policyObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("unexpected object type %T for %s", obj, key))
+		return nil
+	}
+	if policyObj.GetDeletionTimestamp() != nil {
+		return c.cleanUpAdminPolicy(key)
+	}
+
+	spec, npmNetPol, err := translateAdminObject(tier, policyObj)
+	if err != nil {
+		if errors.Is(err, translation.ErrUnsupportedSameLabels) || errors.Is(err, translation.ErrUnsupportedSubjectNamespaces) {
+			// re-queuing would result in the same error
+			klog.Warningf("%s tier policy %s is not translated because it has unsupported features: %s", tier, name, err.Error())
+			return nil
+		}
+		klog.Errorf("Failed to translate %s tier policy %s: %s", tier, name, err.Error())
+		return errAdminPolicyTranslationFailure
+	}
+
+	if cachedSpec, ok := c.rawSpecMap[key]; ok && reflect.DeepEqual(cachedSpec, spec) {
+		return nil
+	}
+
+	// DP update policy call will remove the old rules if the policy already exists
+	if err := c.dp.UpdatePolicy(npmNetPol); err != nil {
+		return fmt.Errorf("[syncAdminPolicy] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
+	}
+	c.rawSpecMap[key] = spec
+	return nil
+}
+
+// translateAdminObject converts the object from the dynamic informer and translates it.
+// It returns the spec to cache along with the translated policy.
+func translateAdminObject(tier policies.Tier, policyObj *unstructured.Unstructured) (interface{}, *policies.NPMNetworkPolicy, error) {
+	if tier == policies.BaselineAdminTier {
+		banp, err := v1alpha1.BaselineAdminNetworkPolicyFromUnstructured(policyObj)
+		if err != nil {
+			return nil, nil, err
+		}
+		npmNetPol, err := translation.TranslateBaselineAdminNetworkPolicy(banp)
+		return &banp.Spec, npmNetPol, err
+	}
+
+	anp, err := v1alpha1.AdminNetworkPolicyFromUnstructured(policyObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	npmNetPol, err := translation.TranslateAdminNetworkPolicy(anp)
+	return &anp.Spec, npmNetPol, err
+}
+
+func (c *AdminNetworkPolicyController) cleanUpAdminPolicy(key string) error {
+	if _, ok := c.rawSpecMap[key]; !ok {
+		return nil
+	}
+
+	if err := c.dp.RemovePolicy(key); err != nil {
+		return fmt.Errorf("[cleanUpAdminPolicy] Error: failed to remove policy due to %w", err)
+	}
+	delete(c.rawSpecMap, key)
+	return nil
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type adminNetPolFixture struct {
+	t          *testing.T
+	factory    dynamicinformer.DynamicSharedInformerFactory
+	controller *AdminNetworkPolicyController
+}
+
+func newAdminNetPolFixture(t *testing.T, dp *dpmocks.MockGenericDataplane) *adminNetPolFixture {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		v1alpha1.AdminNetworkPolicyResource:         "AdminNetworkPolicyList",
+		v1alpha1.BaselineAdminNetworkPolicyResource: "BaselineAdminNetworkPolicyList",
+	})
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	controller := NewAdminNetworkPolicyController(
+		factory.ForResource(v1alpha1.AdminNetworkPolicyResource),
+		factory.ForResource(v1alpha1.BaselineAdminNetworkPolicyResource),
+		dp,
+	)
+	// Do not start informers to avoid unnecessary event triggers
+	return &adminNetPolFixture{t: t, factory: factory, controller: controller}
+}
+
+func (f *adminNetPolFixture) add(resource schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	require.NoError(f.t, f.factory.ForResource(resource).Informer().GetIndexer().Add(obj))
+	f.controller.eventHandler(tierForResource(resource)).OnAdd(obj)
+	f.controller.processNextWorkItem()
+}
+
+func (f *adminNetPolFixture) update(resource schema.GroupVersionResource, oldObj, newObj *unstructured.Unstructured) {
+	require.NoError(f.t, f.factory.ForResource(resource).Informer().GetIndexer().Update(newObj))
+	f.controller.eventHandler(tierForResource(resource)).OnUpdate(oldObj, newObj)
+	if f.controller.workqueue.Len() == 0 {
+		return
+	}
+	f.controller.processNextWorkItem()
+}
+
+func (f *adminNetPolFixture) delete(resource schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	require.NoError(f.t, f.factory.ForResource(resource).Informer().GetIndexer().Delete(obj))
+	f.controller.eventHandler(tierForResource(resource)).OnDelete(obj)
+	f.controller.processNextWorkItem()
+}
+
+func tierForResource(resource schema.GroupVersionResource) policies.Tier {
+	if resource == v1alpha1.BaselineAdminNetworkPolicyResource {
+		return policies.BaselineAdminTier
+	}
+	return policies.AdminTier
+}
+
+func toUnstructured(t *testing.T, obj interface{}) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: content}
+}
+
+func createANP(t *testing.T, resourceVersion string, priority int32) *unstructured.Unstructured {
+	return toUnstructured(t, &v1alpha1.AdminNetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "AdminNetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-monitoring", ResourceVersion: resourceVersion},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: priority,
+			Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+					From: []v1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kind": "monitoring"}}}},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestAddUpdateDeleteAdminNetworkPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminNetPolFixture(t, dp)
+
+	anp := createANP(t, "1", 10)
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		require.Equal(t, "ADMIN/deny-monitoring", policy.PolicyKey)
+		require.Equal(t, int32(10), policy.Priority)
+		return nil
+	}).Times(1)
+	f.add(v1alpha1.AdminNetworkPolicyResource, anp)
+	require.Equal(t, 1, f.controller.LengthOfRawSpecMap())
+
+	// same resource version is a resync
+	f.update(v1alpha1.AdminNetworkPolicyResource, anp, anp)
+
+	// a new priority is applied
+	updatedANP := createANP(t, "2", 20)
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		require.Equal(t, int32(20), policy.Priority)
+		return nil
+	}).Times(1)
+	f.update(v1alpha1.AdminNetworkPolicyResource, anp, updatedANP)
+
+	dp.EXPECT().RemovePolicy("ADMIN/deny-monitoring").Return(nil).Times(1)
+	f.delete(v1alpha1.AdminNetworkPolicyResource, updatedANP)
+	require.Equal(t, 0, f.controller.LengthOfRawSpecMap())
+}
+
+func TestAddBaselineAdminNetworkPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminNetPolFixture(t, dp)
+
+	banp := toUnstructured(t, &v1alpha1.BaselineAdminNetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "BaselineAdminNetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "default", ResourceVersion: "1"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					To: []v1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}},
+					},
+				},
+			},
+		},
+	})
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		require.Equal(t, "BASELINE/default", policy.PolicyKey)
+		require.Equal(t, policies.BaselineAdminTier, policy.Tier)
+		return nil
+	}).Times(1)
+	f.add(v1alpha1.BaselineAdminNetworkPolicyResource, banp)
+	require.Equal(t, 1, f.controller.LengthOfRawSpecMap())
+}
+
+func TestUnsupportedAdminNetworkPolicyIsNotRequeued(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminNetPolFixture(t, dp)
+
+	anp := toUnstructured(t, &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "same-labels", ResourceVersion: "1"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+					From: []v1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &v1alpha1.NamespacedPeer{SameLabels: []string{"tenant"}}},
+					},
+				},
+			},
+		},
+	})
+	f.add(v1alpha1.AdminNetworkPolicyResource, anp)
+	require.Equal(t, 0, f.controller.workqueue.Len())
+	require.Equal(t, 0, f.controller.LengthOfRawSpecMap())
+}
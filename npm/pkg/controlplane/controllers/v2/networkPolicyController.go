@@ -13,11 +13,15 @@ import (
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	"github.com/Azure/azure-container-networking/npm/util"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformer "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	netpollister "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -34,7 +38,11 @@ type NetworkPolicyController struct {
 	netPolLister netpollister.NetworkPolicyLister
 	workqueue    workqueue.RateLimitingInterface
 	rawNpSpecMap map[string]*networkingv1.NetworkPolicySpec // Key is <nsname>/<policyname>
-	dp           dataplane.GenericDataplane
+	// auditedPolicies holds the keys of applied policies in audit mode, since the mode is an annotation rather than part of the spec
+	auditedPolicies map[string]struct{}
+	// nsLister is set by WatchNamespaceAuditMode to put the policies of namespaces in audit mode
+	nsLister corelisters.NamespaceLister
+	dp       dataplane.GenericDataplane
 }
 
 func (c *NetworkPolicyController) GetCache() map[string]*networkingv1.NetworkPolicySpec {
@@ -45,10 +53,11 @@ func (c *NetworkPolicyController) GetCache() map[string]*networkingv1.NetworkPol
 
 func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInformer, dp dataplane.GenericDataplane) *NetworkPolicyController {
 	netPolController := &NetworkPolicyController{
-		netPolLister: npInformer.Lister(),
-		workqueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NetworkPolicy"),
-		rawNpSpecMap: make(map[string]*networkingv1.NetworkPolicySpec),
-		dp:           dp,
+		netPolLister:    npInformer.Lister(),
+		workqueue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NetworkPolicy"),
+		rawNpSpecMap:    make(map[string]*networkingv1.NetworkPolicySpec),
+		auditedPolicies: make(map[string]struct{}),
+		dp:              dp,
 	}
 
 	npInformer.Informer().AddEventHandler(
@@ -61,6 +70,54 @@ func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInfo
 	return netPolController
 }
 
+// WatchNamespaceAuditMode puts the policies of a namespace with the AuditModeAnnotation in audit mode,
+// and resyncs them when the annotation of their namespace changes.
+func (c *NetworkPolicyController) WatchNamespaceAuditMode(nsInformer coreinformer.NamespaceInformer) {
+	c.nsLister = nsInformer.Lister()
+	nsInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if nsObj, ok := obj.(*corev1.Namespace); ok && translation.IsAuditMode(nsObj) {
+					c.enqueueNamespacePolicies(nsObj.Name)
+				}
+			},
+			UpdateFunc: func(old, newns interface{}) {
+				oldNsObj, ok := old.(*corev1.Namespace)
+				if !ok {
+					return
+				}
+				newNsObj, ok := newns.(*corev1.Namespace)
+				if ok && translation.IsAuditMode(oldNsObj) != translation.IsAuditMode(newNsObj) {
+					c.enqueueNamespacePolicies(newNsObj.Name)
+				}
+			},
+		},
+	)
+}
+
+func (c *NetworkPolicyController) enqueueNamespacePolicies(namespace string) {
+	netPolObjs, err := c.netPolLister.NetworkPolicies(namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, netPolObj := range netPolObjs {
+		c.addNetworkPolicy(netPolObj)
+	}
+}
+
+// isAuditMode returns whether the policy, or its namespace, has the AuditModeAnnotation.
+func (c *NetworkPolicyController) isAuditMode(netPolObj *networkingv1.NetworkPolicy) bool {
+	if translation.IsAuditMode(netPolObj) {
+		return true
+	}
+	if c.nsLister == nil {
+		return false
+	}
+	nsObj, err := c.nsLister.Get(netPolObj.Namespace)
+	return err == nil && translation.IsAuditMode(nsObj)
+}
+
 func (c *NetworkPolicyController) LengthOfRawNpMap() int {
 	return len(c.rawNpSpecMap)
 }
@@ -260,7 +317,8 @@ func (c *NetworkPolicyController) syncNetPol(key string) error {
 		// netPolController does not need to reconcile this update.
 		// In this updateNetworkPolicy event,
 		// newNetPol was updated with states which netPolController does not need to reconcile.
-		if reflect.DeepEqual(cachedNetPolSpecObj, &netPolObj.Spec) {
+		_, wasAudited := c.auditedPolicies[key]
+		if reflect.DeepEqual(cachedNetPolSpecObj, &netPolObj.Spec) && wasAudited == c.isAuditMode(netPolObj) {
 			return nil
 		}
 	}
@@ -299,6 +357,11 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 		return metrics.NoOp, errNetPolTranslationFailure
 	}
 
+	audited := c.isAuditMode(netPolObj)
+	if audited {
+		translation.Audit(npmNetPolObj)
+	}
+
 	_, policyExisted := c.rawNpSpecMap[netpolKey]
 	var operationKind metrics.OperationKind
 	if policyExisted {
@@ -324,6 +387,11 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 	}
 
 	c.rawNpSpecMap[netpolKey] = &netPolObj.Spec
+	if audited {
+		c.auditedPolicies[netpolKey] = struct{}{}
+	} else {
+		delete(c.auditedPolicies, netpolKey)
+	}
 	return operationKind, nil
 }
 
@@ -342,6 +410,7 @@ func (c *NetworkPolicyController) cleanUpNetworkPolicy(netPolKey string) error {
 
 	// Success to clean up ipset and iptables operations in kernel and delete the cached network policy from RawNpMap
 	delete(c.rawNpSpecMap, netPolKey)
+	delete(c.auditedPolicies, netPolKey)
 	metrics.DecNumPolicies()
 	return nil
 }
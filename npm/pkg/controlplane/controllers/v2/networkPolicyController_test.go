@@ -9,8 +9,10 @@ import (
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/metrics/promutil"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
@@ -391,6 +393,77 @@ func TestUpdateNetworkPolicy(t *testing.T) {
 	checkNetPolTestResult("TestUpdateNetPol", f, testCases)
 }
 
+func TestAuditModeUpdateNetworkPolicy(t *testing.T) {
+	oldNetPolObj := createNetPol()
+
+	f := newNetPolFixture(t)
+	f.netPolLister = append(f.netPolLister, oldNetPolObj)
+	f.kubeobjects = append(f.kubeobjects, oldNetPolObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newNetPolController(stopCh, dp)
+
+	newNetPolObj := oldNetPolObj.DeepCopy()
+	// only the annotation changes, so the spec is the same
+	newNetPolObj.Annotations = map[string]string{translation.AuditModeAnnotation: "true"}
+	// oldNetPolObj.ResourceVersion value is "0"
+	newRV, _ := strconv.Atoi(oldNetPolObj.ResourceVersion)
+	newNetPolObj.ResourceVersion = fmt.Sprintf("%d", newRV+1)
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Times(2)
+
+	updateNetPol(t, f, oldNetPolObj, newNetPolObj)
+
+	testCases := []expectedNetPolValues{
+		{1, 0, netPolPromVals{1, 1, 1, 0}},
+	}
+	checkNetPolTestResult("TestAuditModeUpdateNetPol", f, testCases)
+	require.Contains(t, f.netPolController.auditedPolicies, "test-nwpolicy/"+newNetPolObj.Name)
+}
+
+func TestNamespaceAuditModeNetworkPolicy(t *testing.T) {
+	netPolObj := createNetPol()
+
+	f := newNetPolFixture(t)
+	f.netPolLister = append(f.netPolLister, netPolObj)
+	f.kubeobjects = append(f.kubeobjects, netPolObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newNetPolController(stopCh, dp)
+	nsInformer := f.kubeInformer.Core().V1().Namespaces()
+	f.netPolController.WatchNamespaceAuditMode(nsInformer)
+
+	var auditModes []bool
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Times(2).DoAndReturn(func(npmNetPol *policies.NPMNetworkPolicy) error {
+		auditModes = append(auditModes, npmNetPol.AuditMode)
+		return nil
+	})
+
+	addNetPol(f, netPolObj)
+
+	// annotating the namespace resyncs its policies in audit mode
+	nsObj := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        netPolObj.Namespace,
+			Annotations: map[string]string{translation.AuditModeAnnotation: "true"},
+		},
+	}
+	require.NoError(t, nsInformer.Informer().GetIndexer().Add(nsObj))
+	f.netPolController.enqueueNamespacePolicies(nsObj.Name)
+	require.Equal(t, 1, f.netPolController.workqueue.Len())
+	f.netPolController.processNextWorkItem()
+
+	require.Equal(t, []bool{false, true}, auditModes)
+	require.Contains(t, f.netPolController.auditedPolicies, "test-nwpolicy/"+netPolObj.Name)
+}
+
 func TestLabelUpdateNetworkPolicy(t *testing.T) {
 	oldNetPolObj := createNetPol()
 
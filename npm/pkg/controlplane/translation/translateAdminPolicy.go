@@ -0,0 +1,213 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	// ErrUnsupportedSameLabels is returned when an AdminNetworkPolicy peer uses sameLabels or notSameLabels,
+	// which depend on the namespace of each subject pod.
+	ErrUnsupportedSameLabels = errors.New("unsupported sameLabels or notSameLabels in AdminNetworkPolicy peer")
+	// ErrUnsupportedSubjectNamespaces is returned when the namespace selector of a subject has a match expression with multiple values.
+	// Unlike for peers, the subject's selector can't be flattened into multiple rules.
+	ErrUnsupportedSubjectNamespaces = errors.New("unsupported match expression with multiple values in namespace selector of AdminNetworkPolicy subject")
+
+	errInvalidAdminSubject = errors.New("AdminNetworkPolicy subject must have exactly one of namespaces or pods")
+	errInvalidAdminPeer    = errors.New("AdminNetworkPolicy peer must have exactly one of namespaces or pods, and a namespace selector")
+	errInvalidAdminPort    = errors.New("AdminNetworkPolicy port must have one of portNumber, namedPort, or portRange")
+	errUnknownAdminAction  = errors.New("unknown AdminNetworkPolicy rule action")
+)
+
+// TranslateAdminNetworkPolicy translates an AdminNetworkPolicy to an NPMNetworkPolicy in the admin tier.
+func TranslateAdminNetworkPolicy(anp *v1alpha1.AdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	npmNetPol := policies.NewAdminNPMNetworkPolicy(policies.AdminTier, anp.Name, anp.Spec.Priority)
+	if err := adminSubject(npmNetPol, &anp.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range anp.Spec.Ingress {
+		if err := adminRule(npmNetPol, policies.Ingress, policies.SrcMatch, rule.Action, rule.From, rule.Ports); err != nil {
+			return nil, fmt.Errorf("failed to translate ingress rule %d (%s): %w", i, rule.Name, err)
+		}
+	}
+	for i, rule := range anp.Spec.Egress {
+		if err := adminRule(npmNetPol, policies.Egress, policies.DstMatch, rule.Action, rule.To, rule.Ports); err != nil {
+			return nil, fmt.Errorf("failed to translate egress rule %d (%s): %w", i, rule.Name, err)
+		}
+	}
+	return npmNetPol, nil
+}
+
+// TranslateBaselineAdminNetworkPolicy translates a BaselineAdminNetworkPolicy to an NPMNetworkPolicy in the baseline admin tier.
+func TranslateBaselineAdminNetworkPolicy(banp *v1alpha1.BaselineAdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	npmNetPol := policies.NewAdminNPMNetworkPolicy(policies.BaselineAdminTier, banp.Name, 0)
+	if err := adminSubject(npmNetPol, &banp.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	// baseline actions are a subset of the admin actions
+	for i, rule := range banp.Spec.Ingress {
+		action := v1alpha1.AdminNetworkPolicyRuleAction(rule.Action)
+		if err := adminRule(npmNetPol, policies.Ingress, policies.SrcMatch, action, rule.From, rule.Ports); err != nil {
+			return nil, fmt.Errorf("failed to translate ingress rule %d (%s): %w", i, rule.Name, err)
+		}
+	}
+	for i, rule := range banp.Spec.Egress {
+		action := v1alpha1.AdminNetworkPolicyRuleAction(rule.Action)
+		if err := adminRule(npmNetPol, policies.Egress, policies.DstMatch, action, rule.To, rule.Ports); err != nil {
+			return nil, fmt.Errorf("failed to translate egress rule %d (%s): %w", i, rule.Name, err)
+		}
+	}
+	return npmNetPol, nil
+}
+
+// adminSubject translates the subject of the policy into its pod selector IPSets.
+func adminSubject(npmNetPol *policies.NPMNetworkPolicy, subject *v1alpha1.AdminNetworkPolicySubject) error {
+	var nsSelector *metav1.LabelSelector
+	switch {
+	case subject.Namespaces != nil && subject.Pods == nil:
+		nsSelector = subject.Namespaces
+	case subject.Pods != nil && subject.Namespaces == nil:
+		nsSelector = &subject.Pods.NamespaceSelector
+		psResult, err := podSelector(npmNetPol.PolicyKey, policies.EitherMatch, &subject.Pods.PodSelector)
+		if err != nil {
+			return err
+		}
+		npmNetPol.PodSelectorIPSets = append(npmNetPol.PodSelectorIPSets, psResult.psSets...)
+		npmNetPol.ChildPodSelectorIPSets = append(npmNetPol.ChildPodSelectorIPSets, psResult.childPSSets...)
+		npmNetPol.PodSelectorList = append(npmNetPol.PodSelectorList, psResult.psList...)
+	default:
+		return errInvalidAdminSubject
+	}
+
+	flattenNSSelector := flattenNameSpaceSelector(nsSelector)
+	if len(flattenNSSelector) != 1 {
+		return ErrUnsupportedSubjectNamespaces
+	}
+	nsSelectorIPSets, nsSelectorList := nameSpaceSelector(policies.EitherMatch, &flattenNSSelector[0])
+	npmNetPol.PodSelectorIPSets = append(npmNetPol.PodSelectorIPSets, nsSelectorIPSets...)
+	npmNetPol.PodSelectorList = append(npmNetPol.PodSelectorList, nsSelectorList...)
+	return nil
+}
+
+// adminRule translates the peers and ports of a rule into ACLs with the rule's action.
+func adminRule(npmNetPol *policies.NPMNetworkPolicy, direction policies.Direction, matchType policies.MatchType,
+	action v1alpha1.AdminNetworkPolicyRuleAction, peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) error {
+	target, err := adminVerdict(action)
+	if err != nil {
+		return err
+	}
+	npPorts, err := adminPorts(ports)
+	if err != nil {
+		return err
+	}
+
+	firstRuleACL := len(npmNetPol.ACLs)
+	for _, peer := range peers {
+		var namespaces *v1alpha1.NamespacedPeer
+		var podSetInfos []policies.SetInfo
+		switch {
+		case peer.Namespaces != nil && peer.Pods == nil:
+			namespaces = peer.Namespaces
+		case peer.Pods != nil && peer.Namespaces == nil:
+			namespaces = &peer.Pods.Namespaces
+			psResult, err := podSelector(npmNetPol.PolicyKey, matchType, &peer.Pods.PodSelector)
+			if err != nil {
+				return err
+			}
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.psSets...)
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.childPSSets...)
+			podSetInfos = psResult.psList
+		default:
+			return errInvalidAdminPeer
+		}
+
+		if len(namespaces.SameLabels) > 0 || len(namespaces.NotSameLabels) > 0 {
+			return ErrUnsupportedSameLabels
+		}
+		if namespaces.NamespaceSelector == nil {
+			return errInvalidAdminPeer
+		}
+
+		// like for NetworkPolicies, each flattened namespace selector becomes its own ACLs to preserve the OR condition
+		flattenNSSelector := flattenNameSpaceSelector(namespaces.NamespaceSelector)
+		for i := range flattenNSSelector {
+			nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, nsSelectorIPSets...)
+			nsSelectorList = append(nsSelectorList, podSetInfos...)
+			if err := peerAndPortRule(npmNetPol, direction, npPorts, nsSelectorList); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, acl := range npmNetPol.ACLs[firstRuleACL:] {
+		acl.Target = target
+		// a named port has no protocol in AdminNetworkPolicies, so match the protocol of the named port
+		for _, setInfo := range acl.DstList {
+			if setInfo.IPSet.Type == ipsets.NamedPorts {
+				acl.Protocol = policies.UnspecifiedProtocol
+			}
+		}
+	}
+	return nil
+}
+
+func adminVerdict(action v1alpha1.AdminNetworkPolicyRuleAction) (policies.Verdict, error) {
+	switch action {
+	case v1alpha1.AdminNetworkPolicyRuleActionAllow:
+		return policies.Allowed, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionDeny:
+		return policies.Dropped, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionPass:
+		return policies.Passed, nil
+	default:
+		return "", fmt.Errorf("%w: %s", errUnknownAdminAction, action)
+	}
+}
+
+// adminPorts converts the ports of a rule to NetworkPolicyPorts so that they're translated like NetworkPolicy ports.
+// Nil ports select all ports.
+func adminPorts(ports *[]v1alpha1.AdminNetworkPolicyPort) ([]networkingv1.NetworkPolicyPort, error) {
+	if ports == nil {
+		return nil, nil
+	}
+
+	npPorts := make([]networkingv1.NetworkPolicyPort, 0, len(*ports))
+	for _, port := range *ports {
+		npPort := networkingv1.NetworkPolicyPort{}
+		switch {
+		case port.PortNumber != nil:
+			portNumber := intstr.FromInt(int(port.PortNumber.Port))
+			npPort.Port = &portNumber
+			if port.PortNumber.Protocol != "" {
+				protocol := port.PortNumber.Protocol
+				npPort.Protocol = &protocol
+			}
+		case port.NamedPort != nil:
+			namedPort := intstr.FromString(*port.NamedPort)
+			npPort.Port = &namedPort
+		case port.PortRange != nil:
+			start := intstr.FromInt(int(port.PortRange.Start))
+			end := port.PortRange.End
+			npPort.Port = &start
+			npPort.EndPort = &end
+			if port.PortRange.Protocol != "" {
+				protocol := port.PortRange.Protocol
+				npPort.Protocol = &protocol
+			}
+		default:
+			return nil, errInvalidAdminPort
+		}
+		npPorts = append(npPorts, npPort)
+	}
+	return npPorts, nil
+}
@@ -0,0 +1,193 @@
+package translation
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/policy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTranslateAdminNetworkPolicy(t *testing.T) {
+	serve := "serve"
+	anp := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "guardrails"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 10,
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Pods: &v1alpha1.NamespacedPodSubject{
+					NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+					PodSelector:       metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Name:   "deny-monitoring",
+					Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+					From: []v1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kind": "monitoring"}}}},
+					},
+					Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+						{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolUDP, Port: 53}},
+						{NamedPort: &serve},
+					},
+				},
+			},
+			Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+				{
+					Name:   "pass-to-db",
+					Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+					To: []v1alpha1.AdminNetworkPolicyPeer{
+						{
+							Pods: &v1alpha1.NamespacedPodPeer{
+								Namespaces:  v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}},
+								PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+							},
+						},
+					},
+					Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+						{PortRange: &v1alpha1.PortRange{Protocol: v1.ProtocolTCP, Start: 5432, End: 5440}},
+					},
+				},
+			},
+		},
+	}
+
+	expected := &policies.NPMNetworkPolicy{
+		PolicyKey: "ADMIN/guardrails",
+		Tier:      policies.AdminTier,
+		Priority:  10,
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet("app:web", ipsets.KeyValueLabelOfPod),
+			ipsets.NewTranslatedIPSet("team:a", ipsets.KeyValueLabelOfNamespace),
+		},
+		PodSelectorList: []policies.SetInfo{
+			policies.NewSetInfo("app:web", ipsets.KeyValueLabelOfPod, included, policies.EitherMatch),
+			policies.NewSetInfo("team:a", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+		},
+		RuleIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet("kind:monitoring", ipsets.KeyValueLabelOfNamespace),
+			ipsets.NewTranslatedIPSet(serve, ipsets.NamedPorts),
+			ipsets.NewTranslatedIPSet("app:db", ipsets.KeyValueLabelOfPod),
+			ipsets.NewTranslatedIPSet("all-namespaces", ipsets.KeyLabelOfNamespace),
+		},
+		ACLs: []*policies.ACLPolicy{
+			{
+				Target:    policies.Dropped,
+				Direction: policies.Ingress,
+				SrcList: []policies.SetInfo{
+					policies.NewSetInfo("kind:monitoring", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+				},
+				DstPorts: policies.Ports{Port: 53},
+				Protocol: "UDP",
+			},
+			{
+				Target:    policies.Dropped,
+				Direction: policies.Ingress,
+				SrcList: []policies.SetInfo{
+					policies.NewSetInfo("kind:monitoring", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+				},
+				DstList: []policies.SetInfo{
+					policies.NewSetInfo(serve, ipsets.NamedPorts, included, policies.DstDstMatch),
+				},
+				Protocol: policies.UnspecifiedProtocol,
+			},
+			{
+				Target:    policies.Passed,
+				Direction: policies.Egress,
+				DstList: []policies.SetInfo{
+					policies.NewSetInfo("all-namespaces", ipsets.KeyLabelOfNamespace, included, policies.DstMatch),
+					policies.NewSetInfo("app:db", ipsets.KeyValueLabelOfPod, included, policies.DstMatch),
+				},
+				DstPorts: policies.Ports{Port: 5432, EndPort: 5440},
+				Protocol: "TCP",
+			},
+		},
+	}
+
+	npmNetPol, err := TranslateAdminNetworkPolicy(anp)
+	require.NoError(t, err)
+	require.Equal(t, expected, npmNetPol)
+}
+
+func TestTranslateBaselineAdminNetworkPolicy(t *testing.T) {
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{},
+			},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					From: []v1alpha1.AdminNetworkPolicyPeer{
+						{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}},
+					},
+				},
+			},
+		},
+	}
+
+	npmNetPol, err := TranslateBaselineAdminNetworkPolicy(banp)
+	require.NoError(t, err)
+	require.Equal(t, "BASELINE/default", npmNetPol.PolicyKey)
+	require.Equal(t, policies.BaselineAdminTier, npmNetPol.Tier)
+	require.Equal(t, []policies.SetInfo{
+		policies.NewSetInfo("all-namespaces", ipsets.KeyLabelOfNamespace, included, policies.EitherMatch),
+	}, npmNetPol.PodSelectorList)
+	require.Len(t, npmNetPol.ACLs, 1)
+	require.Equal(t, policies.Dropped, npmNetPol.ACLs[0].Target)
+	require.Equal(t, policies.Ingress, npmNetPol.ACLs[0].Direction)
+}
+
+func TestTranslateAdminNetworkPolicyUnsupported(t *testing.T) {
+	tests := []struct {
+		name        string
+		subject     v1alpha1.AdminNetworkPolicySubject
+		peer        v1alpha1.AdminNetworkPolicyPeer
+		expectedErr error
+	}{
+		{
+			name:    "same labels",
+			subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			peer: v1alpha1.AdminNetworkPolicyPeer{
+				Namespaces: &v1alpha1.NamespacedPeer{SameLabels: []string{"tenant"}},
+			},
+			expectedErr: ErrUnsupportedSameLabels,
+		},
+		{
+			name: "subject namespace selector with multiple values",
+			subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+					},
+				},
+			},
+			peer: v1alpha1.AdminNetworkPolicyPeer{
+				Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}},
+			},
+			expectedErr: ErrUnsupportedSubjectNamespaces,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			anp := &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "unsupported"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: tt.subject,
+					Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+						{Action: v1alpha1.AdminNetworkPolicyRuleActionAllow, From: []v1alpha1.AdminNetworkPolicyPeer{tt.peer}},
+					},
+				},
+			}
+			_, err := TranslateAdminNetworkPolicy(anp)
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
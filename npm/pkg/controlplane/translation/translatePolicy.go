@@ -45,6 +45,10 @@ const (
 	ipBlocksetNameFormat                = "%s-in-ns-%s-%d-%d%s"
 )
 
+// AuditModeAnnotation puts a NetworkPolicy, or all NetworkPolicies of a Namespace, in audit (dry-run) mode when set to "true".
+// Traffic which the policy would drop is logged and counted instead of being denied.
+const AuditModeAnnotation = "npm.azure.com/audit-mode"
+
 // portType returns type of ports (e.g., numeric port or namedPort) given NetworkPolicyPort object.
 func portType(portRule networkingv1.NetworkPolicyPort) (netpolPortType, error) {
 	if portRule.Port == nil || portRule.Port.IntValue() != 0 {
@@ -600,5 +604,25 @@ func TranslatePolicy(npObj *networkingv1.NetworkPolicy) (*policies.NPMNetworkPol
 			}
 		}
 	}
+
+	if IsAuditMode(npObj) {
+		Audit(npmNetPol)
+	}
 	return npmNetPol, nil
 }
+
+// Audit puts a translated policy in audit mode, where its drop ACLs log would-be drops
+// and its allow ACLs don't allow anything the other policies deny.
+func Audit(npmNetPol *policies.NPMNetworkPolicy) {
+	npmNetPol.AuditMode = true
+	for _, acl := range npmNetPol.ACLs {
+		if acl.Target == policies.Dropped {
+			acl.Target = policies.Audited
+		}
+	}
+}
+
+// IsAuditMode returns whether the NetworkPolicy, or the Namespace of NetworkPolicies, has the AuditModeAnnotation set to "true".
+func IsAuditMode(obj metav1.Object) bool {
+	return obj.GetAnnotations()[AuditModeAnnotation] == "true"
+}
@@ -2536,3 +2536,54 @@ func TestEgressPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestTranslatePolicyAuditMode(t *testing.T) {
+	tcp := v1.ProtocolTCP
+	port := intstr.FromInt(8000)
+	npObj := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "allow-port",
+			Namespace: defaultNS,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		expectedVerdict policies.Verdict
+	}{
+		{
+			name:            "no annotation",
+			expectedVerdict: policies.Dropped,
+		},
+		{
+			name:            "audit mode disabled",
+			annotations:     map[string]string{AuditModeAnnotation: "false"},
+			expectedVerdict: policies.Dropped,
+		},
+		{
+			name:            "audit mode enabled",
+			annotations:     map[string]string{AuditModeAnnotation: "true"},
+			expectedVerdict: policies.Audited,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			npObj.Annotations = tt.annotations
+			npmNetPol, err := TranslatePolicy(npObj)
+			require.NoError(t, err)
+			require.Len(t, npmNetPol.ACLs, 2)
+			require.Equal(t, policies.Allowed, npmNetPol.ACLs[0].Target)
+			require.Equal(t, tt.expectedVerdict, npmNetPol.ACLs[1].Target)
+			require.Equal(t, tt.expectedVerdict == policies.Audited, npmNetPol.AuditMode)
+		})
+	}
+}
@@ -305,6 +305,21 @@ func (dp *DataPlane) GetAllPolicies() []string {
 	return dp.policyMgr.GetAllPolicies()
 }
 
+// GetPolicyKeyByDropLogPrefix returns the key of the policy whose drop rules log with the NFLOG prefix.
+func (dp *DataPlane) GetPolicyKeyByDropLogPrefix(prefix string) (string, bool) {
+	return dp.policyMgr.GetPolicyKeyByDropLogPrefix(prefix)
+}
+
+// AuditedPolicyAdded returns a channel which is closed once the first policy in audit mode is added.
+func (dp *DataPlane) AuditedPolicyAdded() <-chan struct{} {
+	return dp.policyMgr.AuditedPolicyAdded()
+}
+
+// GetPodKeyByIP returns the key of the pod with the IP.
+func (dp *DataPlane) GetPodKeyByIP(ip string) (string, bool) {
+	return dp.ipsetMgr.GetPodKeyByIP(ip)
+}
+
 func (dp *DataPlane) createIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, netpolName string, referenceType ipsets.ReferenceType) error {
 	// Create IPSets first along with reference updates
 	npmErrorString := npmerrors.AddSelectorReference
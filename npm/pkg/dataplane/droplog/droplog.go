@@ -0,0 +1,135 @@
+// Package droplog collects the NFLOG entries which NPM's Linux policy chains emit before dropping a packet,
+// or for policies in audit mode, instead of dropping it.
+// Entries are mapped back to the policy and pods involved, then logged and counted in the NPM metrics.
+package droplog
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"k8s.io/klog"
+)
+
+const (
+	directionIngress = "ingress"
+	directionEgress  = "egress"
+
+	// unknownPolicyKey is used when a prefix belongs to a policy which was removed before the log was read
+	unknownPolicyKey = "unknown"
+	unknownPod       = "none"
+
+	readErrorBackoff = time.Second
+)
+
+var ErrUnsupportedPlatform = errors.New("drop logging is only supported on Linux")
+
+// DroppedPacket is a packet logged by the NFLOG rule in front of a policy's drop rule.
+type DroppedPacket struct {
+	Prefix   string
+	Protocol string
+	SrcIP    string
+	DstIP    string
+	SrcPort  uint16
+	DstPort  uint16
+}
+
+// Source provides dropped packets, e.g. by listening to an NFLOG group.
+type Source interface {
+	// Read blocks until packets are received or a read timeout passes.
+	// On timeout, it returns no packets and no error.
+	Read() ([]*DroppedPacket, error)
+	Close() error
+}
+
+// Resolver maps NFLOG prefixes and IPs back to NPM's state. The DataPlane implements it.
+type Resolver interface {
+	GetPolicyKeyByDropLogPrefix(prefix string) (string, bool)
+	GetPodKeyByIP(ip string) (string, bool)
+}
+
+type Collector struct {
+	source   Source
+	resolver Resolver
+}
+
+func NewCollector(source Source, resolver Resolver) *Collector {
+	return &Collector{
+		source:   source,
+		resolver: resolver,
+	}
+}
+
+// Run collects dropped packets until the stop channel is closed, then closes the source.
+func (c *Collector) Run(stopCh <-chan struct{}) {
+	defer func() {
+		if err := c.source.Close(); err != nil {
+			klog.Errorf("[DropLog] failed to close drop log source: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		packets, err := c.source.Read()
+		if err != nil {
+			klog.Errorf("[DropLog] failed to read dropped packets: %v", err)
+			time.Sleep(readErrorBackoff)
+			continue
+		}
+		for _, packet := range packets {
+			c.handle(packet)
+		}
+	}
+}
+
+func (c *Collector) handle(packet *DroppedPacket) {
+	var direction string
+	var audited bool
+	switch {
+	case strings.HasPrefix(packet.Prefix, util.IptablesDropLogIngressPrefix):
+		direction = directionIngress
+	case strings.HasPrefix(packet.Prefix, util.IptablesDropLogEgressPrefix):
+		direction = directionEgress
+	case strings.HasPrefix(packet.Prefix, util.IptablesAuditLogIngressPrefix):
+		direction = directionIngress
+		audited = true
+	case strings.HasPrefix(packet.Prefix, util.IptablesAuditLogEgressPrefix):
+		direction = directionEgress
+		audited = true
+	default:
+		// someone else's NFLOG rule is using the same group
+		return
+	}
+
+	policyKey, ok := c.resolver.GetPolicyKeyByDropLogPrefix(packet.Prefix)
+	if !ok {
+		policyKey = unknownPolicyKey
+	}
+	srcPod, ok := c.resolver.GetPodKeyByIP(packet.SrcIP)
+	if !ok {
+		srcPod = unknownPod
+	}
+	dstPod, ok := c.resolver.GetPodKeyByIP(packet.DstIP)
+	if !ok {
+		dstPod = unknownPod
+	}
+
+	event := "dropped packet"
+	if audited {
+		event = "packet would be dropped"
+	}
+	klog.Infof("[DropLog] %s. policy=%s direction=%s protocol=%s src=%s srcPort=%d srcPod=%s dst=%s dstPort=%d dstPod=%s",
+		event, policyKey, direction, packet.Protocol, packet.SrcIP, packet.SrcPort, srcPod, packet.DstIP, packet.DstPort, dstPod)
+	if audited {
+		metrics.IncWouldBeDroppedPackets(policyKey, direction)
+		return
+	}
+	metrics.IncDroppedPackets(policyKey, direction)
+}
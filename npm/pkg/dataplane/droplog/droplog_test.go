@@ -0,0 +1,100 @@
+package droplog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+var errFakeRead = errors.New("fake read error")
+
+type fakeSource struct {
+	reads  [][]*DroppedPacket
+	stopCh chan struct{}
+	closed bool
+}
+
+func (s *fakeSource) Read() ([]*DroppedPacket, error) {
+	if len(s.reads) == 0 {
+		close(s.stopCh)
+		return nil, nil
+	}
+	packets := s.reads[0]
+	s.reads = s.reads[1:]
+	if packets == nil {
+		return nil, errFakeRead
+	}
+	return packets, nil
+}
+
+func (s *fakeSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+type fakeResolver struct {
+	policyKeys map[string]string
+	podKeys    map[string]string
+}
+
+func (r *fakeResolver) GetPolicyKeyByDropLogPrefix(prefix string) (string, bool) {
+	policyKey, ok := r.policyKeys[prefix]
+	return policyKey, ok
+}
+
+func (r *fakeResolver) GetPodKeyByIP(ip string) (string, bool) {
+	podKey, ok := r.podKeys[ip]
+	return podKey, ok
+}
+
+func TestCollector(t *testing.T) {
+	metrics.InitializeAll()
+	resolver := &fakeResolver{
+		policyKeys: map[string]string{
+			"NPM-DROP-IN-1":  "x/deny-ingress",
+			"NPM-DROP-OUT-1": "x/deny-ingress",
+			"NPM-AUDIT-IN-3": "x/audited",
+		},
+		podKeys: map[string]string{
+			"10.0.0.1": "x/a",
+			"10.0.0.2": "x/b",
+		},
+	}
+	source := &fakeSource{
+		reads: [][]*DroppedPacket{
+			{
+				{Prefix: "NPM-DROP-IN-1", Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 1234, DstPort: 80},
+				{Prefix: "NPM-DROP-IN-1", Protocol: "TCP", SrcIP: "1.1.1.1", DstIP: "10.0.0.2", SrcPort: 1234, DstPort: 80},
+			},
+			nil,
+			{
+				{Prefix: "NPM-DROP-OUT-1", Protocol: "UDP", SrcIP: "10.0.0.2", DstIP: "8.8.8.8", SrcPort: 1234, DstPort: 53},
+				{Prefix: "NPM-DROP-IN-2", Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 1234, DstPort: 80},
+				{Prefix: "SOMEONE-ELSE", Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 1234, DstPort: 80},
+				{Prefix: "NPM-AUDIT-IN-3", Protocol: "TCP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 1234, DstPort: 443},
+			},
+		},
+		stopCh: make(chan struct{}),
+	}
+
+	NewCollector(source, resolver).Run(source.stopCh)
+	require.True(t, source.closed)
+
+	val, err := metrics.GetDroppedPackets("x/deny-ingress", directionIngress)
+	require.NoError(t, err)
+	require.Equal(t, 2, val)
+	val, err = metrics.GetDroppedPackets("x/deny-ingress", directionEgress)
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+	val, err = metrics.GetDroppedPackets(unknownPolicyKey, directionIngress)
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+	val, err = metrics.GetWouldBeDroppedPackets("x/audited", directionIngress)
+	require.NoError(t, err)
+	require.Equal(t, 1, val)
+	val, err = metrics.GetDroppedPackets("x/audited", directionIngress)
+	require.NoError(t, err)
+	require.Equal(t, 0, val)
+}
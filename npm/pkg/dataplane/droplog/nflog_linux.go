@@ -0,0 +1,245 @@
+package droplog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// constants from linux/netfilter/nfnetlink_log.h which x/sys/unix doesn't define
+const (
+	nfulnlMsgPacket = 0
+	nfulnlMsgConfig = 1
+
+	nfulaPayload = 9
+	nfulaPrefix  = 10
+
+	nfulaCfgCmd  = 1
+	nfulaCfgMode = 2
+
+	nfulnlCfgCmdBind  = 1
+	nfulnlCopyPacket  = 2
+	nlaTypeMask       = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+	nfgenmsgLen       = 4
+	nlattrHeaderLen   = 4
+	ipv4HeaderMinLen  = 20
+	transportPortsLen = 4
+
+	// only the IP header and ports are needed
+	copyRange = 128
+
+	readTimeoutSeconds = 1
+)
+
+var (
+	errNotIPv4          = errors.New("payload is not an IPv4 packet")
+	errTruncatedPayload = errors.New("payload is truncated")
+
+	nativeEndian binary.ByteOrder
+)
+
+func init() {
+	var x uint16 = 1
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		nativeEndian = binary.LittleEndian
+	} else {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+type nflogSource struct {
+	fd     int
+	buffer []byte
+}
+
+// NewNFLogSource binds a netlink socket to the NFLOG group.
+// Only one process can listen to a group at a time.
+func NewNFLogSource(group uint16) (Source, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create netfilter netlink socket: %w", err)
+	}
+	source := &nflogSource{
+		fd:     fd,
+		buffer: make([]byte, unix.Getpagesize()),
+	}
+
+	if err := source.configure(group); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	return source, nil
+}
+
+func (s *nflogSource) configure(group uint16) error {
+	if err := unix.Bind(s.fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to bind netfilter netlink socket: %w", err)
+	}
+	timeout := &unix.Timeval{Sec: readTimeoutSeconds}
+	if err := unix.SetsockoptTimeval(s.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, timeout); err != nil {
+		return fmt.Errorf("failed to set read timeout on netfilter netlink socket: %w", err)
+	}
+
+	if err := s.sendConfig(1, group, nfulaCfgCmd, []byte{nfulnlCfgCmdBind}); err != nil {
+		return fmt.Errorf("failed to bind to NFLOG group %d: %w", group, err)
+	}
+
+	mode := make([]byte, 6)
+	binary.BigEndian.PutUint32(mode[0:4], copyRange)
+	mode[4] = nfulnlCopyPacket
+	if err := s.sendConfig(2, group, nfulaCfgMode, mode); err != nil {
+		return fmt.Errorf("failed to set copy mode of NFLOG group %d: %w", group, err)
+	}
+	return nil
+}
+
+// sendConfig sends an NFULNL_MSG_CONFIG message and waits for the kernel's ack.
+func (s *nflogSource) sendConfig(seq uint32, group, attrType uint16, attrValue []byte) error {
+	msg := newConfigMessage(seq, group, attrType, attrValue)
+	if err := unix.Sendto(s.fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to send config message: %w", err)
+	}
+
+	n, _, err := unix.Recvfrom(s.fd, s.buffer, 0)
+	if err != nil {
+		return fmt.Errorf("failed to receive ack: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(s.buffer[:n])
+	if err != nil {
+		return fmt.Errorf("failed to parse ack: %w", err)
+	}
+	for i := range msgs {
+		if msgs[i].Header.Type != unix.NLMSG_ERROR || len(msgs[i].Data) < 4 {
+			continue
+		}
+		if errCode := int32(nativeEndian.Uint32(msgs[i].Data[0:4])); errCode != 0 {
+			return syscall.Errno(-errCode)
+		}
+	}
+	return nil
+}
+
+func (s *nflogSource) Read() ([]*DroppedPacket, error) {
+	n, _, err := unix.Recvfrom(s.fd, s.buffer, 0)
+	if err != nil {
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to receive from netfilter netlink socket: %w", err)
+	}
+	return parseNFLogMessages(s.buffer[:n])
+}
+
+func (s *nflogSource) Close() error {
+	return unix.Close(s.fd)
+}
+
+func newConfigMessage(seq uint32, group, attrType uint16, attrValue []byte) []byte {
+	attrLen := nlattrHeaderLen + len(attrValue)
+	msgLen := unix.NLMSG_HDRLEN + nfgenmsgLen + nlaAlign(attrLen)
+	msg := make([]byte, msgLen)
+
+	nativeEndian.PutUint32(msg[0:4], uint32(msgLen))
+	nativeEndian.PutUint16(msg[4:6], unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgConfig)
+	nativeEndian.PutUint16(msg[6:8], unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	nativeEndian.PutUint32(msg[8:12], seq)
+
+	genmsg := msg[unix.NLMSG_HDRLEN:]
+	genmsg[0] = unix.AF_INET
+	genmsg[1] = unix.NFNETLINK_V0
+	binary.BigEndian.PutUint16(genmsg[2:4], group)
+
+	attr := genmsg[nfgenmsgLen:]
+	nativeEndian.PutUint16(attr[0:2], uint16(attrLen))
+	nativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[nlattrHeaderLen:], attrValue)
+	return msg
+}
+
+// parseNFLogMessages parses the NFULNL_MSG_PACKET messages in a netlink datagram.
+// Packets which aren't IPv4 or are truncated are skipped.
+func parseNFLogMessages(b []byte) ([]*DroppedPacket, error) {
+	msgs, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse netlink messages: %w", err)
+	}
+
+	packets := make([]*DroppedPacket, 0, len(msgs))
+	for i := range msgs {
+		if msgs[i].Header.Type != unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgPacket || len(msgs[i].Data) < nfgenmsgLen {
+			continue
+		}
+
+		packet := &DroppedPacket{}
+		var payload []byte
+		attrs := msgs[i].Data[nfgenmsgLen:]
+		for len(attrs) >= nlattrHeaderLen {
+			attrLen := int(nativeEndian.Uint16(attrs[0:2]))
+			attrType := nativeEndian.Uint16(attrs[2:4]) & nlaTypeMask
+			if attrLen < nlattrHeaderLen || attrLen > len(attrs) {
+				break
+			}
+			value := attrs[nlattrHeaderLen:attrLen]
+			switch attrType {
+			case nfulaPrefix:
+				packet.Prefix = strings.TrimRight(string(value), "\x00")
+			case nfulaPayload:
+				payload = value
+			}
+			if nlaAlign(attrLen) >= len(attrs) {
+				break
+			}
+			attrs = attrs[nlaAlign(attrLen):]
+		}
+
+		if err := parseIPv4(payload, packet); err != nil {
+			continue
+		}
+		packets = append(packets, packet)
+	}
+	return packets, nil
+}
+
+func parseIPv4(payload []byte, packet *DroppedPacket) error {
+	if len(payload) < ipv4HeaderMinLen || payload[0]>>4 != 4 {
+		return errNotIPv4
+	}
+	headerLen := int(payload[0]&0x0f) * 4
+	if headerLen < ipv4HeaderMinLen || headerLen > len(payload) {
+		return errTruncatedPayload
+	}
+
+	packet.SrcIP = net.IP(payload[12:16]).String()
+	packet.DstIP = net.IP(payload[16:20]).String()
+	switch payload[9] {
+	case unix.IPPROTO_TCP:
+		packet.Protocol = "TCP"
+	case unix.IPPROTO_UDP:
+		packet.Protocol = "UDP"
+	case unix.IPPROTO_SCTP:
+		packet.Protocol = "SCTP"
+	case unix.IPPROTO_ICMP:
+		packet.Protocol = "ICMP"
+		return nil
+	default:
+		packet.Protocol = fmt.Sprint(payload[9])
+		return nil
+	}
+
+	if len(payload) < headerLen+transportPortsLen {
+		return errTruncatedPayload
+	}
+	packet.SrcPort = binary.BigEndian.Uint16(payload[headerLen : headerLen+2])
+	packet.DstPort = binary.BigEndian.Uint16(payload[headerLen+2 : headerLen+4])
+	return nil
+}
+
+func nlaAlign(length int) int {
+	return (length + unix.NLA_ALIGNTO - 1) & ^(unix.NLA_ALIGNTO - 1)
+}
@@ -0,0 +1,92 @@
+package droplog
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func newAttr(attrType uint16, value []byte) []byte {
+	attrLen := nlattrHeaderLen + len(value)
+	attr := make([]byte, nlaAlign(attrLen))
+	nativeEndian.PutUint16(attr[0:2], uint16(attrLen))
+	nativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[nlattrHeaderLen:], value)
+	return attr
+}
+
+func newPacketMessage(attrs ...[]byte) []byte {
+	body := []byte{unix.AF_INET, unix.NFNETLINK_V0, 0, 100}
+	for _, attr := range attrs {
+		body = append(body, attr...)
+	}
+	msg := make([]byte, unix.NLMSG_HDRLEN, unix.NLMSG_HDRLEN+len(body))
+	nativeEndian.PutUint32(msg[0:4], uint32(unix.NLMSG_HDRLEN+len(body)))
+	nativeEndian.PutUint16(msg[4:6], unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgPacket)
+	return append(msg, body...)
+}
+
+func newIPv4Payload(protocol byte, src, dst [4]byte, srcPort, dstPort uint16) []byte {
+	payload := make([]byte, ipv4HeaderMinLen+transportPortsLen)
+	payload[0] = 0x45
+	payload[9] = protocol
+	copy(payload[12:16], src[:])
+	copy(payload[16:20], dst[:])
+	binary.BigEndian.PutUint16(payload[20:22], srcPort)
+	binary.BigEndian.PutUint16(payload[22:24], dstPort)
+	return payload
+}
+
+func TestParseNFLogMessages(t *testing.T) {
+	tcpPayload := newIPv4Payload(unix.IPPROTO_TCP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 34567, 80)
+	udpPayload := newIPv4Payload(unix.IPPROTO_UDP, [4]byte{10, 0, 0, 3}, [4]byte{10, 0, 0, 4}, 5353, 53)
+	ipv6Payload := make([]byte, 40)
+	ipv6Payload[0] = 0x60
+
+	datagram := newPacketMessage(newAttr(nfulaPrefix, []byte("NPM-DROP-IN-123\x00")), newAttr(nfulaPayload, tcpPayload))
+	datagram = append(datagram, newPacketMessage(newAttr(nfulaPrefix, []byte("NPM-DROP-OUT-456\x00")), newAttr(nfulaPayload, ipv6Payload))...)
+	datagram = append(datagram, newPacketMessage(newAttr(nfulaPayload, udpPayload), newAttr(nfulaPrefix, []byte("NPM-DROP-OUT-456\x00")))...)
+
+	packets, err := parseNFLogMessages(datagram)
+	require.NoError(t, err)
+	require.Equal(t, []*DroppedPacket{
+		{
+			Prefix:   "NPM-DROP-IN-123",
+			Protocol: "TCP",
+			SrcIP:    "10.0.0.1",
+			DstIP:    "10.0.0.2",
+			SrcPort:  34567,
+			DstPort:  80,
+		},
+		{
+			Prefix:   "NPM-DROP-OUT-456",
+			Protocol: "UDP",
+			SrcIP:    "10.0.0.3",
+			DstIP:    "10.0.0.4",
+			SrcPort:  5353,
+			DstPort:  53,
+		},
+	}, packets)
+}
+
+func TestParseIPv4Truncated(t *testing.T) {
+	payload := newIPv4Payload(unix.IPPROTO_TCP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 1, 2)
+	require.ErrorIs(t, parseIPv4(payload[:ipv4HeaderMinLen+2], &DroppedPacket{}), errTruncatedPayload)
+	require.ErrorIs(t, parseIPv4(payload[:10], &DroppedPacket{}), errNotIPv4)
+
+	icmpPayload := newIPv4Payload(unix.IPPROTO_ICMP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 0, 0)
+	packet := &DroppedPacket{}
+	require.NoError(t, parseIPv4(icmpPayload[:ipv4HeaderMinLen], packet))
+	require.Equal(t, "ICMP", packet.Protocol)
+}
+
+func TestNewConfigMessage(t *testing.T) {
+	msg := newConfigMessage(1, 100, nfulaCfgCmd, []byte{nfulnlCfgCmdBind})
+	require.Len(t, msg, unix.NLMSG_HDRLEN+nfgenmsgLen+8)
+	require.Equal(t, uint32(len(msg)), nativeEndian.Uint32(msg[0:4]))
+	require.Equal(t, uint16(unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgConfig), nativeEndian.Uint16(msg[4:6]))
+	require.Equal(t, uint16(100), binary.BigEndian.Uint16(msg[unix.NLMSG_HDRLEN+2:unix.NLMSG_HDRLEN+4]))
+	require.Equal(t, byte(nfulnlCfgCmdBind), msg[unix.NLMSG_HDRLEN+nfgenmsgLen+nlattrHeaderLen])
+}
@@ -0,0 +1,6 @@
+package droplog
+
+// NewNFLogSource is unsupported on Windows, where policies are enforced with HNS ACLs.
+func NewNFLogSource(_ uint16) (Source, error) {
+	return nil, ErrUnsupportedPlatform
+}
@@ -169,6 +169,22 @@ func (iMgr *IPSetManager) GetIPSet(name string) *IPSet {
 	return iMgr.setMap[name]
 }
 
+// GetPodKeyByIP returns the key of the pod with the IP.
+// Every pod is in the set for its namespace, so only namespace sets are searched.
+func (iMgr *IPSetManager) GetPodKeyByIP(ip string) (string, bool) {
+	iMgr.RLock()
+	defer iMgr.RUnlock()
+	for _, set := range iMgr.setMap {
+		if set.Type != Namespace {
+			continue
+		}
+		if podKey, ok := set.IPPodKey[ip]; ok {
+			return podKey, true
+		}
+	}
+	return "", false
+}
+
 // AddReference creates the set if necessary and adds relevant reference
 // it throws an error if the set and reference type are an invalid combination
 func (iMgr *IPSetManager) AddReference(setMetadata *IPSetMetadata, referenceName string, referenceType ReferenceType) error {
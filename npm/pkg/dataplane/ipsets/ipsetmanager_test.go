@@ -1053,6 +1053,23 @@ func TestRemoveFromSets(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestGetPodKeyByIP(t *testing.T) {
+	iMgr := NewIPSetManager(applyOnNeedCfg, common.NewMockIOShim([]testutils.TestCmd{}))
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{keyLabelOfPodSet}, "10.0.0.1", "other-ns/other-pod"))
+	_, ok := iMgr.GetPodKeyByIP("10.0.0.1")
+	require.False(t, ok, "only namespace sets should be searched")
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{namespaceSet}, testPodIP, testPodKey))
+	podKey, ok := iMgr.GetPodKeyByIP(testPodIP)
+	require.True(t, ok)
+	require.Equal(t, testPodKey, podKey)
+
+	require.NoError(t, iMgr.RemoveFromSets([]*IPSetMetadata{namespaceSet}, testPodIP, testPodKey))
+	_, ok = iMgr.GetPodKeyByIP(testPodIP)
+	require.False(t, ok)
+}
+
 func TestRemoveFromSetMissing(t *testing.T) {
 	iMgr := NewIPSetManager(applyOnNeedCfg, common.NewMockIOShim([]testutils.TestCmd{}))
 	setMetadata := NewIPSetMetadata(testSetName, Namespace)
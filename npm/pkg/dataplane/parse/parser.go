@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/Azure/azure-container-networking/common"
@@ -24,6 +25,8 @@ var (
 	SpaceBytes = []byte(" ")
 	// MinOptionLength indicates the minimum length of an option
 	MinOptionLength = 2
+
+	errInvalidCounters = errors.New("invalid rule counters")
 )
 
 type IPTablesParser struct {
@@ -63,6 +66,19 @@ func (i *IPTablesParser) Iptables(tableName string) (*NPMIPtable.Table, error) {
 	return &NPMIPtable.Table{Name: tableName, Chains: chains}, nil
 }
 
+// IptablesWithCounters is like Iptables, but also parses the packet and byte counters of each rule by calling iptables-save -c.
+func (i *IPTablesParser) IptablesWithCounters(tableName string) (*NPMIPtable.Table, error) {
+	cmdArgs := []string{util.IptablesCountersFlag, util.IptablesTableFlag, tableName}
+
+	output, err := i.runCommand(util.IptablesSave, cmdArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	chains := parseIptablesChainObject(tableName, output)
+	return &NPMIPtable.Table{Name: tableName, Chains: chains}, nil
+}
+
 // Iptables creates a Go object from specified iptable by calling iptables-save within node.
 func Iptables(tableName string) (*NPMIPtable.Table, error) {
 	iptableBuffer := bytes.NewBuffer(nil)
@@ -122,6 +138,16 @@ func parseIptablesChainObject(tableName string, iptableBuffer []byte) map[string
 		if bytes.HasPrefix(line, CommitBytes) || line[0] == '*' {
 			break
 		}
+		var packets, byteCount uint64
+		if line[0] == '[' {
+			// with iptables-save -c, rule lines start with [packets:bytes]
+			var err error
+			packets, byteCount, line, err = parseCounters(line)
+			if err != nil {
+				klog.Warningf("skipping rule line in iptables-save output: %v. line: %s", err, string(line))
+				continue
+			}
+		}
 		if line[0] == ':' && len(line) > 1 {
 			// We assume that the <line> contains space - chain lines have 3 fields,
 			// space delimited. If there is no space, this line will panic.
@@ -142,7 +168,10 @@ func parseIptablesChainObject(tableName string, iptableBuffer []byte) map[string
 			if !ok {
 				iptableChain = &NPMIPtable.Chain{Name: chainName, Data: []byte{}, Rules: make([]*NPMIPtable.Rule, 0)}
 			}
-			iptableChain.Rules = append(iptableChain.Rules, parseRuleFromLine(line[ruleStartIndex:]))
+			rule := parseRuleFromLine(line[ruleStartIndex:])
+			rule.Packets = packets
+			rule.Bytes = byteCount
+			iptableChain.Rules = append(iptableChain.Rules, rule)
 		}
 	}
 	return chainMap
@@ -189,6 +218,27 @@ func Line(readIndex int, iptableBuffer []byte) ([]byte, int) {
 	return iptableBuffer[leftLineIndex : lastNonWhiteSpaceIndex+1], curReadIndex
 }
 
+// parseCounters parses the "[packets:bytes] " prefix of a rule line and returns the rest of the line.
+func parseCounters(line []byte) (packets, byteCount uint64, rest []byte, err error) {
+	end := bytes.IndexByte(line, ']')
+	if end == -1 || end+2 > len(line) {
+		return 0, 0, line, errInvalidCounters
+	}
+	packetsString, bytesString, ok := strings.Cut(string(line[1:end]), ":")
+	if !ok {
+		return 0, 0, line, errInvalidCounters
+	}
+	packets, err = strconv.ParseUint(packetsString, 10, 64)
+	if err != nil {
+		return 0, 0, line, fmt.Errorf("%w: %s", errInvalidCounters, err.Error())
+	}
+	byteCount, err = strconv.ParseUint(bytesString, 10, 64)
+	if err != nil {
+		return 0, 0, line, fmt.Errorf("%w: %s", errInvalidCounters, err.Error())
+	}
+	return packets, byteCount, bytes.TrimLeft(line[end+1:], " "), nil
+}
+
 // parseChainNameFromRuleLine  gets the chain name from given rule line.
 func parseChainNameFromRuleLine(ruleLine []byte) (chainName string, ruleReadIndex int) {
 	spaceIndex := bytes.Index(ruleLine, SpaceBytes)
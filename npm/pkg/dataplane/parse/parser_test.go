@@ -11,6 +11,7 @@ import (
 	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
 	"github.com/Azure/azure-container-networking/npm/util"
 	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseIptablesObjectFile(t *testing.T) {
@@ -43,6 +44,37 @@ func TestParseIptablesObject(t *testing.T) {
 	}
 }
 
+func TestParseIptablesObjectWithCounters(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{
+			Cmd: []string{"iptables-save", "-c", "-t", "filter"},
+			Stdout: `# Generated by iptables-save
+*filter
+:AZURE-NPM - [0:0]
+:AZURE-NPM-ACCEPT - [0:0]
+[12:3456] -A AZURE-NPM -m mark --mark 0x3000 -m comment --comment ACCEPT-on-INGRESS-and-EGRESS-mark-0x3000 -j AZURE-NPM-ACCEPT
+[0:0] -A AZURE-NPM-ACCEPT -j ACCEPT
+[bad] -A AZURE-NPM-ACCEPT -j ACCEPT
+COMMIT
+`,
+		},
+	}
+
+	parser := IPTablesParser{
+		IOShim: common.NewMockIOShim(calls),
+	}
+
+	table, err := parser.IptablesWithCounters(util.IptablesFilterTable)
+	require.NoError(t, err)
+	require.Len(t, table.Chains[util.IptablesAzureChain].Rules, 1)
+	rule := table.Chains[util.IptablesAzureChain].Rules[0]
+	require.Equal(t, uint64(12), rule.Packets)
+	require.Equal(t, uint64(3456), rule.Bytes)
+	require.Equal(t, util.IptablesAzureAcceptChain, rule.Target.Name)
+	require.Len(t, table.Chains[util.IptablesAzureAcceptChain].Rules, 1)
+	require.Equal(t, uint64(0), table.Chains[util.IptablesAzureAcceptChain].Rules[0].Packets)
+}
+
 func TestParseLine(t *testing.T) {
 	type test struct {
 		input    string
@@ -36,6 +36,13 @@ var (
 	}
 	// Should not be used directly. Initialized from iptablesAzureChains on first use of isAzureChain().
 	iptablesAzureChainsMap map[string]struct{}
+	// Base chains which only exist when AdminNetworkPolicies are enabled.
+	iptablesAzureAdminChains = []string{
+		util.IptablesAzureAdminIngressChain,
+		util.IptablesAzureAdminEgressChain,
+		util.IptablesAzureBaselineAdminIngressChain,
+		util.IptablesAzureBaselineAdminEgressChain,
+	}
 
 	jumpToAzureChainArgs = []string{
 		util.IptablesJumpFlag,
@@ -135,6 +142,15 @@ func (s *staleChains) empty() {
 	s.chainsToCleanup = make(map[string]struct{})
 }
 
+func isAdminChain(chain string) bool {
+	for _, adminChain := range iptablesAzureAdminChains {
+		if chain == adminChain {
+			return true
+		}
+	}
+	return false
+}
+
 func isBaseChain(chain string) bool {
 	if iptablesAzureChainsMap == nil {
 		iptablesAzureChainsMap = make(map[string]struct{})
@@ -297,8 +313,12 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 // Writes the restore file for bootup, and marks the following as stale: deprecated chains and old v2 policy chains.
 // This is a separate function to help with UTs.
 func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *ioutil.FileCreator {
-	chainsToCreate := make([]string, 0, len(iptablesAzureChains))
-	for _, chain := range iptablesAzureChains {
+	baseChains := iptablesAzureChains
+	if pMgr.EnableAdminNetworkPolicies {
+		baseChains = append(append([]string{}, iptablesAzureChains...), iptablesAzureAdminChains...)
+	}
+	chainsToCreate := make([]string, 0, len(baseChains))
+	for _, chain := range baseChains {
 		_, exists := currentChains[chain]
 		if !exists {
 			chainsToCreate = append(chainsToCreate, chain)
@@ -311,15 +331,26 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	pMgr.staleChains.empty()
 	for chain := range currentChains {
 		creator.AddLine("", nil, fmt.Sprintf("-F %s", chain))
+		if pMgr.EnableAdminNetworkPolicies && isAdminChain(chain) {
+			continue
+		}
 		// Step 2.2 in bootup() comment: delete deprecated chains and old v2 policy chains in the background
 		pMgr.staleChains.add(chain) // won't add base chains
 	}
 
 	// add AZURE-NPM-INGRESS chain rules
+	// AdminNetworkPolicies are evaluated before the jumps to NetworkPolicy chains (which are inserted at the top later).
+	// BaselineAdminNetworkPolicies are only reached if no NetworkPolicy marked the packet to be dropped or allowed it.
+	if pMgr.EnableAdminNetworkPolicies {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureAdminIngressChain)
+	}
 	ingressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	ingressDropSpecs = append(ingressDropSpecs, onMarkSpecs(util.IptablesAzureIngressDropMarkHex)...)
 	ingressDropSpecs = append(ingressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
 	creator.AddLine("", nil, ingressDropSpecs...)
+	if pMgr.EnableAdminNetworkPolicies {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureBaselineAdminIngressChain)
+	}
 
 	// add AZURE-NPM-INGRESS-ALLOW-MARK chain
 	markIngressAllowSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain}
@@ -329,10 +360,16 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
 
 	// add AZURE-NPM-EGRESS chain rules
+	if pMgr.EnableAdminNetworkPolicies {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAdminEgressChain)
+	}
 	egressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	egressDropSpecs = append(egressDropSpecs, onMarkSpecs(util.IptablesAzureEgressDropMarkHex)...)
 	egressDropSpecs = append(egressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-EGRESS-DROP-MARK-%s", util.IptablesAzureEgressDropMarkHex))...)
 	creator.AddLine("", nil, egressDropSpecs...)
+	if pMgr.EnableAdminNetworkPolicies {
+		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureBaselineAdminEgressChain)
+	}
 
 	jumpOnIngressMatchSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, onMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
@@ -330,6 +330,56 @@ func TestCreatorForBootup(t *testing.T) {
 	}
 }
 
+func TestCreatorForBootupWithAdminNetworkPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	cfg := &PolicyManagerCfg{
+		PolicyMode:                 IPSetPolicyMode,
+		EnableAdminNetworkPolicies: true,
+	}
+	pMgr := NewPolicyManager(ioshim, cfg)
+	currentChains := []string{
+		"AZURE-NPM",
+		"AZURE-NPM-INGRESS",
+		"AZURE-NPM-INGRESS-ALLOW-MARK",
+		"AZURE-NPM-EGRESS",
+		"AZURE-NPM-ACCEPT",
+		"AZURE-NPM-ANP-INGRESS",
+		"AZURE-NPM-ANP-EGRESS",
+		"AZURE-NPM-INGRESS-123456",
+	}
+	creator := pMgr.creatorForBootup(stringsToMap(currentChains))
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		":AZURE-NPM-BANP-INGRESS - -",
+		":AZURE-NPM-BANP-EGRESS - -",
+		"-F AZURE-NPM",
+		"-F AZURE-NPM-INGRESS",
+		"-F AZURE-NPM-INGRESS-ALLOW-MARK",
+		"-F AZURE-NPM-EGRESS",
+		"-F AZURE-NPM-ACCEPT",
+		"-F AZURE-NPM-ANP-INGRESS",
+		"-F AZURE-NPM-ANP-EGRESS",
+		"-F AZURE-NPM-INGRESS-123456",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-ANP-INGRESS",
+		"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
+		"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+		"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-ANP-EGRESS",
+		"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
+		"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+		"-A AZURE-NPM-ACCEPT -j ACCEPT",
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, sortFlushes(expectedLines), sortFlushes(actualLines))
+	// the admin chains are base chains, so only the old policy chain is stale
+	assertStaleChainsContain(t, pMgr.staleChains, "AZURE-NPM-INGRESS-123456")
+}
+
 func sortFlushes(lines []string) []string {
 	result := make([]string, len(lines))
 	copy(result, lines)
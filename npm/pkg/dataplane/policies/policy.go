@@ -13,8 +13,14 @@ import (
 type NPMNetworkPolicy struct {
 	// Namespace is only used by Linux to construct an iptables comment
 	Namespace string
-	// PolicyKey is a unique combination of "namespace/name" of network policy
+	// PolicyKey is a unique combination of "namespace/name" of network policy,
+	// or "<tier>/name" for the cluster-scoped policies of the admin tiers
 	PolicyKey string
+	// Tier is NetworkPolicyTier for a NetworkPolicy, or the tier of an AdminNetworkPolicy or BaselineAdminNetworkPolicy
+	Tier Tier
+	// Priority orders the policies within AdminTier, where a lower value is evaluated first.
+	// It is unused for the other tiers.
+	Priority int32
 	// ACLPolicyID is only used in Windows. See aclPolicyID() in policy_windows.go for more info
 	ACLPolicyID string
 	// TODO get rid of PodSelectorIPSets in favor of PodSelectorList (exact same except need to add members field to SetInfo)
@@ -29,6 +35,9 @@ type NPMNetworkPolicy struct {
 	// and not from pod selector IPSets, including children of a NestedLabelOfPod ipset
 	RuleIPSets []*ipsets.TranslatedIPSet
 	ACLs       []*ACLPolicy
+	// AuditMode is set for a policy in audit mode, which must not change the verdict of any packet.
+	// Its drop ACLs have the Audited verdict, and its allow ACLs only end the evaluation of the policy.
+	AuditMode bool
 	// podIP is key and endpoint ID as value
 	// Will be populated by dataplane and policy manager
 	PodEndpoints map[string]string
@@ -42,6 +51,21 @@ func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
 	}
 }
 
+// NewAdminNPMNetworkPolicy creates a policy for an AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+// Namespaces can't have uppercase letters, so the policy key never collides with a NetworkPolicy's.
+func NewAdminNPMNetworkPolicy(tier Tier, name string, priority int32) *NPMNetworkPolicy {
+	return &NPMNetworkPolicy{
+		PolicyKey: fmt.Sprintf("%s/%s", tier, name),
+		Tier:      tier,
+		Priority:  priority,
+	}
+}
+
+// IsAdminTier returns whether the policy is an AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+func (netPol *NPMNetworkPolicy) IsAdminTier() bool {
+	return netPol.Tier != NetworkPolicyTier
+}
+
 func (netPol *NPMNetworkPolicy) AllPodSelectorIPSets() []*ipsets.TranslatedIPSet {
 	return append(netPol.PodSelectorIPSets, netPol.ChildPodSelectorIPSets...)
 }
@@ -61,6 +85,11 @@ func (netPol *NPMNetworkPolicy) numACLRulesProducedInKernel() int {
 		}
 	}
 
+	// admin tiers share their chains, so they have no jump rules
+	if netPol.IsAdminTier() {
+		return numRules
+	}
+
 	// both Windows and Linux have an extra ACL rule for ingress and an extra rule for egress
 	if hasIngress {
 		numRules++
@@ -136,6 +165,9 @@ func ValidatePolicy(networkPolicy *NPMNetworkPolicy) error {
 		if !aclPolicy.hasKnownTarget() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown target [%s]", networkPolicy.PolicyKey, aclPolicy.Target))
 		}
+		if aclPolicy.Target == Passed && networkPolicy.Tier != AdminTier {
+			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has target [%s] which is only valid for AdminNetworkPolicies", networkPolicy.PolicyKey, aclPolicy.Target))
+		}
 		if !aclPolicy.hasKnownDirection() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown direction [%s]", networkPolicy.PolicyKey, aclPolicy.Direction))
 		}
@@ -224,7 +256,7 @@ func (aclPolicy *ACLPolicy) hasKnownProtocol() bool {
 }
 
 func (aclPolicy *ACLPolicy) hasKnownTarget() bool {
-	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped
+	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped || aclPolicy.Target == Audited || aclPolicy.Target == Passed
 }
 
 func (aclPolicy *ACLPolicy) satisifiesPortAndProtocolConstraints() bool {
@@ -330,6 +362,21 @@ const (
 	Allowed Verdict = "ALLOW"
 	// Dropped is denying a flow
 	Dropped Verdict = "DROP"
+	// Audited replaces Dropped for policies in audit mode: the flow is logged as a would-be drop, but not denied
+	Audited Verdict = "AUDIT"
+	// Passed skips the rest of the AdminNetworkPolicies so that NetworkPolicies decide the flow
+	Passed Verdict = "PASS"
+)
+
+type Tier string
+
+const (
+	// NetworkPolicyTier holds the namespaced NetworkPolicies
+	NetworkPolicyTier Tier = ""
+	// AdminTier holds the AdminNetworkPolicies, which are evaluated before NetworkPolicies
+	AdminTier Tier = "ADMIN"
+	// BaselineAdminTier holds the BaselineAdminNetworkPolicies, which are evaluated when no NetworkPolicy selects the pod
+	BaselineAdminTier Tier = "BASELINE"
 )
 
 // Protocol can be TCP, UDP, SCTP, or unspecified since they are currently supported in networkpolicy.
@@ -44,11 +44,41 @@ func (networkPolicy *NPMNetworkPolicy) ingressChainName() string {
 	return networkPolicy.chainName(util.IptablesAzureIngressPolicyChainPrefix)
 }
 
+// adminChainNames returns the ingress and egress chains which hold the rules of every policy in the admin tier.
+func (tier Tier) adminChainNames() (ingressChain, egressChain string) {
+	if tier == BaselineAdminTier {
+		return util.IptablesAzureBaselineAdminIngressChain, util.IptablesAzureBaselineAdminEgressChain
+	}
+	return util.IptablesAzureAdminIngressChain, util.IptablesAzureAdminEgressChain
+}
+
+// commentForAdminRule prefixes the ACL comment with the policy since a chain is shared by all policies in the tier.
+func (networkPolicy *NPMNetworkPolicy) commentForAdminRule(aclPolicy *ACLPolicy) string {
+	return fmt.Sprintf("%s-POLICY-%s-PRIORITY-%d-%s", networkPolicy.Tier, networkPolicy.PolicyKey, networkPolicy.Priority, aclPolicy.comment())
+}
+
 func (networkPolicy *NPMNetworkPolicy) chainName(prefix string) string {
 	policyHash := util.Hash(networkPolicy.PolicyKey)
 	return joinWithDash(prefix, policyHash)
 }
 
+// logPrefix is the NFLOG prefix of the policy's drop rules, or of its audit rules for the Audited verdict.
+// NFLOG prefixes are limited to 64 characters, so the policy key is hashed like in chain names.
+func (networkPolicy *NPMNetworkPolicy) logPrefix(verdict Verdict, direction UniqueDirection) string {
+	var prefix string
+	switch {
+	case verdict == Audited && direction == forIngress:
+		prefix = util.IptablesAuditLogIngressPrefix
+	case verdict == Audited:
+		prefix = util.IptablesAuditLogEgressPrefix
+	case direction == forIngress:
+		prefix = util.IptablesDropLogIngressPrefix
+	default:
+		prefix = util.IptablesDropLogEgressPrefix
+	}
+	return prefix + util.Hash(networkPolicy.PolicyKey)
+}
+
 func (networkPolicy *NPMNetworkPolicy) commentForJumpToIngress() string {
 	return networkPolicy.commentForJump(forIngress)
 }
@@ -128,9 +158,14 @@ func (aclPolicy *ACLPolicy) comment() string {
 	}
 
 	builder := strings.Builder{}
-	if aclPolicy.Target == Allowed {
+	switch aclPolicy.Target {
+	case Allowed:
 		builder.WriteString("ALLOW")
-	} else {
+	case Audited:
+		builder.WriteString("AUDIT")
+	case Passed:
+		builder.WriteString("PASS")
+	default:
 		builder.WriteString("DROP")
 	}
 
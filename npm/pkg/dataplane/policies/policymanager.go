@@ -26,6 +26,8 @@ const (
 	// it represents the number of rules unrelated to policies
 	// it's technically 3 off when there are no policies since we flush the AZURE-NPM chain then
 	numLinuxBaseACLRules = 11
+	// the jumps to the chains of AdminNetworkPolicies and BaselineAdminNetworkPolicies
+	numLinuxAdminBaseACLRules = 4
 )
 
 type PolicyManagerCfg struct {
@@ -33,12 +35,25 @@ type PolicyManagerCfg struct {
 	PolicyMode PolicyManagerMode
 	// PlaceAzureChainFirst only affects Linux
 	PlaceAzureChainFirst bool
+	// EnableDropLogging only affects Linux.
+	// When enabled, packets dropped by a policy are logged with NFLOG to util.IptablesNFLogGroup.
+	EnableDropLogging bool
+	// EnableAdminNetworkPolicies only affects Linux.
+	// When enabled, bootup creates the chains for AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+	EnableAdminNetworkPolicies bool
 }
 
 type PolicyMap struct {
 	cache map[string]*NPMNetworkPolicy
 }
 
+// dropLogPrefixes maps the NFLOG prefix of each policy's drop and audit rules to its policy key.
+// It is read by the drop log collector, so unlike the PolicyMap, it's safe for concurrent use.
+type dropLogPrefixes struct {
+	sync.RWMutex
+	policyKeys map[string]string
+}
+
 type reconcileManager struct {
 	sync.Mutex
 	releaseLockSignal chan struct{}
@@ -49,6 +64,10 @@ type PolicyManager struct {
 	ioShim           *common.IOShim
 	staleChains      *staleChains
 	reconcileManager *reconcileManager
+	dropLogPrefixes  *dropLogPrefixes
+	// auditedPolicyAdded is closed once the first policy in audit mode is added
+	auditedPolicyAdded     chan struct{}
+	closeAuditedPolicyOnce sync.Once
 	*PolicyManagerCfg
 }
 
@@ -62,7 +81,11 @@ func NewPolicyManager(ioShim *common.IOShim, cfg *PolicyManagerCfg) *PolicyManag
 		reconcileManager: &reconcileManager{
 			releaseLockSignal: make(chan struct{}, 1),
 		},
-		PolicyManagerCfg: cfg,
+		dropLogPrefixes: &dropLogPrefixes{
+			policyKeys: make(map[string]string),
+		},
+		auditedPolicyAdded: make(chan struct{}),
+		PolicyManagerCfg:   cfg,
 	}
 }
 
@@ -77,6 +100,9 @@ func (pMgr *PolicyManager) Bootup(epIDs []string) error {
 	if !util.IsWindowsDP() {
 		// update Prometheus metrics on success
 		metrics.IncNumACLRulesBy(numLinuxBaseACLRules)
+		if pMgr.EnableAdminNetworkPolicies {
+			metrics.IncNumACLRulesBy(numLinuxAdminBaseACLRules)
+		}
 	}
 	return nil
 }
@@ -105,6 +131,19 @@ func (pMgr *PolicyManager) GetPolicy(policyKey string) (*NPMNetworkPolicy, bool)
 	return policy, ok
 }
 
+// GetPolicyKeyByDropLogPrefix returns the key of the policy whose drop or audit rules log with the NFLOG prefix.
+func (pMgr *PolicyManager) GetPolicyKeyByDropLogPrefix(prefix string) (string, bool) {
+	pMgr.dropLogPrefixes.RLock()
+	defer pMgr.dropLogPrefixes.RUnlock()
+	policyKey, ok := pMgr.dropLogPrefixes.policyKeys[prefix]
+	return policyKey, ok
+}
+
+// AuditedPolicyAdded returns a channel which is closed once the first policy in audit mode is added.
+func (pMgr *PolicyManager) AuditedPolicyAdded() <-chan struct{} {
+	return pMgr.auditedPolicyAdded
+}
+
 func (pMgr *PolicyManager) AddPolicy(policy *NPMNetworkPolicy, endpointList map[string]string) error {
 	if len(policy.ACLs) == 0 {
 		klog.Infof("[DataPlane] No ACLs in policy %s to apply", policy.PolicyKey)
@@ -133,6 +172,9 @@ func (pMgr *PolicyManager) AddPolicy(policy *NPMNetworkPolicy, endpointList map[
 	metrics.IncNumACLRulesBy(policy.numACLRulesProducedInKernel())
 
 	pMgr.policyMap.cache[policy.PolicyKey] = policy
+	if policy.AuditMode {
+		pMgr.closeAuditedPolicyOnce.Do(func() { close(pMgr.auditedPolicyAdded) })
+	}
 	return nil
 }
 
@@ -4,6 +4,7 @@ package policies
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -41,7 +42,13 @@ Known errors that we should retry on:
     Another app is currently holding the xtables lock. Stopped waiting after 60s.
 */
 
+var errAdminNetworkPoliciesDisabled = npmerrors.SimpleError("AdminNetworkPolicies are not enabled")
+
 func (pMgr *PolicyManager) addPolicy(networkPolicy *NPMNetworkPolicy, _ map[string]string) error {
+	if networkPolicy.IsAdminTier() {
+		return pMgr.addAdminPolicy(networkPolicy)
+	}
+
 	// 1. Add rules for the network policies and activate NPM (if necessary).
 	chainsToCreate := chainNames([]*NPMNetworkPolicy{networkPolicy})
 	creator := pMgr.creatorForNewNetworkPolicies(chainsToCreate, []*NPMNetworkPolicy{networkPolicy})
@@ -59,10 +66,18 @@ func (pMgr *PolicyManager) addPolicy(networkPolicy *NPMNetworkPolicy, _ map[stri
 	for _, chain := range chainsToCreate {
 		pMgr.staleChains.remove(chain)
 	}
+
+	// 3. Let the drop log collector map NFLOG prefixes back to the policy.
+	// Audit rules log regardless of EnableDropLogging, so prefixes are always tracked.
+	pMgr.addDropLogPrefixes(networkPolicy)
 	return nil
 }
 
 func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[string]string) error {
+	if networkPolicy.IsAdminTier() {
+		return pMgr.removeAdminPolicy(networkPolicy)
+	}
+
 	chainsToDelete := chainNames([]*NPMNetworkPolicy{networkPolicy})
 	creator := pMgr.creatorForRemovingPolicies(chainsToDelete)
 
@@ -87,9 +102,144 @@ func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[s
 	for _, chain := range chainsToDelete {
 		pMgr.staleChains.add(chain)
 	}
+
+	// 4. Forget the NFLOG prefixes of the policy.
+	pMgr.removeDropLogPrefixes(networkPolicy)
 	return nil
 }
 
+// addAdminPolicy rewrites the chains of the policy's tier with the rules of every policy in the tier, ordered by priority.
+func (pMgr *PolicyManager) addAdminPolicy(networkPolicy *NPMNetworkPolicy) error {
+	if !pMgr.EnableAdminNetworkPolicies {
+		return errAdminNetworkPoliciesDisabled
+	}
+
+	tierPolicies := append(pMgr.cachedTierPolicies(networkPolicy.Tier, networkPolicy.PolicyKey), networkPolicy)
+	creator := pMgr.creatorForAdminTier(networkPolicy.Tier, tierPolicies, pMgr.isFirstPolicy(), false)
+
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	if err := restore(creator); err != nil {
+		return npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to restore iptables with updated %s tier policies", networkPolicy.Tier), err)
+	}
+	return nil
+}
+
+func (pMgr *PolicyManager) removeAdminPolicy(networkPolicy *NPMNetworkPolicy) error {
+	if !pMgr.EnableAdminNetworkPolicies {
+		return errAdminNetworkPoliciesDisabled
+	}
+
+	tierPolicies := pMgr.cachedTierPolicies(networkPolicy.Tier, networkPolicy.PolicyKey)
+	creator := pMgr.creatorForAdminTier(networkPolicy.Tier, tierPolicies, false, pMgr.isLastPolicy())
+
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	if err := restore(creator); err != nil {
+		return npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to restore iptables with remaining %s tier policies", networkPolicy.Tier), err)
+	}
+	return nil
+}
+
+// cachedTierPolicies returns the cached policies of the tier except for the policy with the excluded key.
+func (pMgr *PolicyManager) cachedTierPolicies(tier Tier, excludedKey string) []*NPMNetworkPolicy {
+	tierPolicies := make([]*NPMNetworkPolicy, 0)
+	for policyKey, policy := range pMgr.policyMap.cache {
+		if policy.Tier == tier && policyKey != excludedKey {
+			tierPolicies = append(tierPolicies, policy)
+		}
+	}
+	return tierPolicies
+}
+
+// creatorForAdminTier flushes the chains of the tier and writes the rules of all its policies.
+// Policies are ordered by priority, and by policy key for equal priorities so that the order is deterministic.
+// NPM is activated or deactivated like for NetworkPolicies since all tiers share the AZURE-NPM chain.
+func (pMgr *PolicyManager) creatorForAdminTier(tier Tier, tierPolicies []*NPMNetworkPolicy, activate, deactivate bool) *ioutil.FileCreator {
+	sort.Slice(tierPolicies, func(i, j int) bool {
+		if tierPolicies[i].Priority != tierPolicies[j].Priority {
+			return tierPolicies[i].Priority < tierPolicies[j].Priority
+		}
+		return tierPolicies[i].PolicyKey < tierPolicies[j].PolicyKey
+	})
+
+	creator := pMgr.newCreatorWithChains(nil)
+	if activate {
+		addActivationRules(creator)
+	}
+	if deactivate {
+		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain)
+	}
+
+	ingressChain, egressChain := tier.adminChainNames()
+	creator.AddLine("", nil, util.IptablesFlushFlag, ingressChain)
+	creator.AddLine("", nil, util.IptablesFlushFlag, egressChain)
+	for _, networkPolicy := range tierPolicies {
+		writeAdminPolicyRules(creator, networkPolicy)
+	}
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+// writeAdminPolicyRules writes the rules of an admin tier policy into the chains of its tier.
+// Each rule also matches the policy's subject, which is the destination for ingress and the source for egress.
+// Allowed traffic is handled like for NetworkPolicies, denied traffic is dropped right away,
+// and passed traffic returns to the AZURE-NPM-INGRESS or AZURE-NPM-EGRESS chain to be evaluated by NetworkPolicies.
+func writeAdminPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+	ingressChain, egressChain := networkPolicy.Tier.adminChainNames()
+	for _, aclPolicy := range networkPolicy.ACLs {
+		var chainName string
+		var subjectSpecs []string
+		var actionSpecs []string
+		if aclPolicy.hasIngress() {
+			chainName = ingressChain
+			subjectSpecs = matchSetSpecsForNetworkPolicy(networkPolicy, DstMatch)
+			actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureIngressAllowMarkChain}
+		} else {
+			chainName = egressChain
+			subjectSpecs = matchSetSpecsForNetworkPolicy(networkPolicy, SrcMatch)
+			actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
+		}
+		switch aclPolicy.Target {
+		case Dropped:
+			actionSpecs = []string{util.IptablesJumpFlag, util.IptablesDrop}
+		case Passed:
+			actionSpecs = []string{util.IptablesJumpFlag, util.IptablesReturn}
+		}
+
+		line := []string{"-A", chainName}
+		line = append(line, actionSpecs...)
+		line = append(line, subjectSpecs...)
+		line = append(line, ruleSpecsWithComment(aclPolicy, networkPolicy.commentForAdminRule(aclPolicy))...)
+		creator.AddLine("", nil, line...) // TODO add error handler
+	}
+}
+
+func (pMgr *PolicyManager) addDropLogPrefixes(networkPolicy *NPMNetworkPolicy) {
+	pMgr.dropLogPrefixes.Lock()
+	defer pMgr.dropLogPrefixes.Unlock()
+	hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+	for _, verdict := range []Verdict{Dropped, Audited} {
+		if hasIngress {
+			pMgr.dropLogPrefixes.policyKeys[networkPolicy.logPrefix(verdict, forIngress)] = networkPolicy.PolicyKey
+		}
+		if hasEgress {
+			pMgr.dropLogPrefixes.policyKeys[networkPolicy.logPrefix(verdict, forEgress)] = networkPolicy.PolicyKey
+		}
+	}
+}
+
+func (pMgr *PolicyManager) removeDropLogPrefixes(networkPolicy *NPMNetworkPolicy) {
+	pMgr.dropLogPrefixes.Lock()
+	defer pMgr.dropLogPrefixes.Unlock()
+	for _, verdict := range []Verdict{Dropped, Audited} {
+		delete(pMgr.dropLogPrefixes.policyKeys, networkPolicy.logPrefix(verdict, forIngress))
+		delete(pMgr.dropLogPrefixes.policyKeys, networkPolicy.logPrefix(verdict, forEgress))
+	}
+}
+
 func restore(creator *ioutil.FileCreator) error {
 	err := creator.RunCommandWithFile(util.IptablesRestore, util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
 	if err != nil {
@@ -178,7 +328,7 @@ func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction Un
 	// if this actually happens (don't think it should), could use ignoreErrorsAndRunIPTablesCommand instead with: "Bad rule (does a matching rule exist in that chain?)"
 	if err != nil && errCode != doesNotExistErrorCode {
 		errorString := fmt.Sprintf("failed to delete jump from %s chain to %s chain for policy %s with exit code %d", baseChainName, chainName, policy.PolicyKey, errCode)
-		log.Errorf("%s: %w", errorString, err)
+		log.Errorf("%s: %v", errorString, err)
 		return npmerrors.SimpleErrorWrapper(errorString, err)
 	}
 	return nil
@@ -205,18 +355,20 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 
 	// 1. Activate NPM if necessary
 	if pMgr.isFirstPolicy() {
-		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain) // flush just in case there are old rules
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressChain)
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain)
+		addActivationRules(creator)
 	}
 
 	// 2. Add all rules for the network policies
 	ingressJumpLineNumber := 1
 	egressJumpLineNumber := 1
+	if pMgr.EnableAdminNetworkPolicies {
+		// keep the jumps to the AdminNetworkPolicy chains first
+		ingressJumpLineNumber = 2
+		egressJumpLineNumber = 2
+	}
 	for _, networkPolicy := range networkPolicies {
 		// 2.1 add all rules for the policy chain(s)
-		writeNetworkPolicyRules(creator, networkPolicy)
+		writeNetworkPolicyRules(creator, networkPolicy, pMgr.EnableDropLogging)
 
 		// 2.2 add jump rule(s) to the policy chain(s)
 		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
@@ -235,13 +387,26 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 	return creator
 }
 
+func addActivationRules(creator *ioutil.FileCreator) {
+	creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain) // flush just in case there are old rules
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressChain)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain)
+}
+
 // write rules for the policy chain(s)
-func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+// With drop logging, each drop rule is preceded by a rate-limited NFLOG rule with the same matches.
+// An audit rule is only the NFLOG rule, so the packet continues as if the rule weren't there.
+// An allow rule of a policy in audit mode returns from the policy chain without marking or accepting the packet,
+// so it skips the policy's audit rules without changing the verdict of the other policies.
+func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy, dropLogging bool) {
 	for _, aclPolicy := range networkPolicy.ACLs {
 		var chainName string
 		var actionSpecs []string
+		direction := forEgress
 		if aclPolicy.hasIngress() {
 			chainName = networkPolicy.ingressChainName()
+			direction = forIngress
 			if aclPolicy.Target == Allowed {
 				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureIngressAllowMarkChain}
 			} else {
@@ -255,6 +420,18 @@ func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetw
 				actionSpecs = setMarkSpecs(util.IptablesAzureEgressDropMarkHex)
 			}
 		}
+		if aclPolicy.Target == Audited || (dropLogging && aclPolicy.Target == Dropped) {
+			logLine := []string{"-A", chainName}
+			logLine = append(logLine, nflogSpecs(networkPolicy.logPrefix(aclPolicy.Target, direction))...)
+			logLine = append(logLine, iptablesRuleSpecs(aclPolicy)...)
+			creator.AddLine("", nil, logLine...) // TODO add error handler
+		}
+		if aclPolicy.Target == Audited {
+			continue
+		}
+		if aclPolicy.Target == Allowed && networkPolicy.AuditMode {
+			actionSpecs = []string{util.IptablesJumpFlag, util.IptablesReturn}
+		}
 		line := []string{"-A", chainName}
 		line = append(line, actionSpecs...)
 		line = append(line, iptablesRuleSpecs(aclPolicy)...)
@@ -263,6 +440,10 @@ func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetw
 }
 
 func iptablesRuleSpecs(aclPolicy *ACLPolicy) []string {
+	return ruleSpecsWithComment(aclPolicy, aclPolicy.comment())
+}
+
+func ruleSpecsWithComment(aclPolicy *ACLPolicy, comment string) []string {
 	specs := make([]string, 0)
 	if aclPolicy.Protocol != UnspecifiedProtocol {
 		specs = append(specs, util.IptablesProtFlag, string(aclPolicy.Protocol))
@@ -270,7 +451,7 @@ func iptablesRuleSpecs(aclPolicy *ACLPolicy) []string {
 	specs = append(specs, dstPortSpecs(aclPolicy.DstPorts)...)
 	specs = append(specs, matchSetSpecsFromSetInfo(aclPolicy.SrcList)...)
 	specs = append(specs, matchSetSpecsFromSetInfo(aclPolicy.DstList)...)
-	specs = append(specs, commentSpecs(aclPolicy.comment())...)
+	specs = append(specs, commentSpecs(comment)...)
 	return specs
 }
 
@@ -308,6 +489,23 @@ func setMarkSpecs(mark string) []string {
 	}
 }
 
+func nflogSpecs(prefix string) []string {
+	return []string{
+		util.IptablesJumpFlag,
+		util.IptablesNFLogTarget,
+		util.IptablesNFLogGroupFlag,
+		fmt.Sprint(util.IptablesNFLogGroup),
+		util.IptablesNFLogPrefixFlag,
+		prefix,
+		util.IptablesModuleFlag,
+		util.IptablesLimitModuleFlag,
+		util.IptablesLimitFlag,
+		util.IptablesDropLogLimit,
+		util.IptablesLimitBurstFlag,
+		util.IptablesDropLogLimitBurst,
+	}
+}
+
 func commentSpecs(comment string) []string {
 	return []string{
 		util.IptablesModuleFlag,
@@ -281,6 +281,163 @@ func TestCreatorForAddPolicies(t *testing.T) {
 	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
 }
 
+func TestCreatorForAddPoliciesWithDropLogging(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	cfg := &PolicyManagerCfg{
+		PolicyMode:        IPSetPolicyMode,
+		EnableDropLogging: true,
+	}
+	pMgr := NewPolicyManager(ioshim, cfg)
+
+	policies := []*NPMNetworkPolicy{bothDirectionsNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	ingressLogPrefix := "NPM-DROP-IN-" + util.Hash(bothDirectionsNetPol.PolicyKey)
+	egressLogPrefix := "NPM-DROP-OUT-" + util.Hash(bothDirectionsNetPol.PolicyKey)
+	nflogSpecs := "-j NFLOG --nflog-group 100 --nflog-prefix %s -m limit --limit 10/second --limit-burst 20"
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf("-A %s %s %s", bothDirectionsNetPolIngressChain, fmt.Sprintf(nflogSpecs, ingressLogPrefix), strings.TrimPrefix(ingressDropRule, "-j MARK --set-mark 0x400/0x400 ")),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		fmt.Sprintf("-A %s %s %s", bothDirectionsNetPolEgressChain, fmt.Sprintf(nflogSpecs, egressLogPrefix), strings.TrimPrefix(egressDropRule, "-j MARK --set-mark 0x800/0x800 ")),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 1 %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 1 %s", ingressEgressNetPolEgressJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestDropLogPrefixes(t *testing.T) {
+	calls := GetAddPolicyTestCalls(bothDirectionsNetPol)
+	calls = append(calls, GetRemovePolicyTestCalls(bothDirectionsNetPol)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	cfg := &PolicyManagerCfg{
+		PolicyMode:        IPSetPolicyMode,
+		EnableDropLogging: true,
+	}
+	pMgr := NewPolicyManager(ioshim, cfg)
+
+	require.NoError(t, pMgr.AddPolicy(bothDirectionsNetPol, nil))
+	policyKey, ok := pMgr.GetPolicyKeyByDropLogPrefix("NPM-DROP-IN-" + util.Hash(bothDirectionsNetPol.PolicyKey))
+	require.True(t, ok)
+	require.Equal(t, bothDirectionsNetPol.PolicyKey, policyKey)
+	policyKey, ok = pMgr.GetPolicyKeyByDropLogPrefix("NPM-DROP-OUT-" + util.Hash(bothDirectionsNetPol.PolicyKey))
+	require.True(t, ok)
+	require.Equal(t, bothDirectionsNetPol.PolicyKey, policyKey)
+	policyKey, ok = pMgr.GetPolicyKeyByDropLogPrefix("NPM-AUDIT-OUT-" + util.Hash(bothDirectionsNetPol.PolicyKey))
+	require.True(t, ok)
+	require.Equal(t, bothDirectionsNetPol.PolicyKey, policyKey)
+
+	require.NoError(t, pMgr.RemovePolicy(bothDirectionsNetPol.PolicyKey, nil))
+	_, ok = pMgr.GetPolicyKeyByDropLogPrefix("NPM-DROP-IN-" + util.Hash(bothDirectionsNetPol.PolicyKey))
+	require.False(t, ok)
+	_, ok = pMgr.GetPolicyKeyByDropLogPrefix("NPM-AUDIT-OUT-" + util.Hash(bothDirectionsNetPol.PolicyKey))
+	require.False(t, ok)
+}
+
+func TestCreatorForAddPoliciesWithAuditedACL(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	auditedACL := *ingressDeniedACL
+	auditedACL.Target = Audited
+	auditedNetPol := *bothDirectionsNetPol
+	auditedNetPol.ACLs = []*ACLPolicy{&auditedACL, ingressAllowedACL, egressDeniedACL, egressAllowedACL}
+
+	policies := []*NPMNetworkPolicy{&auditedNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	auditRule := strings.Replace(strings.TrimPrefix(ingressDropRule, "-j MARK --set-mark 0x400/0x400 "), "DROP-FROM", "AUDIT-FROM", 1)
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf("-A %s -j NFLOG --nflog-group 100 --nflog-prefix NPM-AUDIT-IN-%s -m limit --limit 10/second --limit-burst 20 %s",
+			bothDirectionsNetPolIngressChain, util.Hash(auditedNetPol.PolicyKey), auditRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 1 %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 1 %s", ingressEgressNetPolEgressJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestCreatorForAddPoliciesInAuditMode(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	auditedACL := *ingressDeniedACL
+	auditedACL.Target = Audited
+	auditedNetPol := *bothDirectionsNetPol
+	auditedNetPol.ACLs = []*ACLPolicy{&auditedACL, ingressAllowedACL}
+	auditedNetPol.AuditMode = true
+
+	policies := []*NPMNetworkPolicy{&auditedNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	auditRule := strings.Replace(strings.TrimPrefix(ingressDropRule, "-j MARK --set-mark 0x400/0x400 "), "DROP-FROM", "AUDIT-FROM", 1)
+	// the allow rule returns from the policy chain instead of marking the packet as allowed
+	returnRule := strings.Replace(ingressAllowRule, "-j AZURE-NPM-INGRESS-ALLOW-MARK", "-j RETURN", 1)
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf("-A %s -j NFLOG --nflog-group 100 --nflog-prefix NPM-AUDIT-IN-%s -m limit --limit 10/second --limit-burst 20 %s",
+			bothDirectionsNetPolIngressChain, util.Hash(auditedNetPol.PolicyKey), auditRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, returnRule),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 1 %s", ingressEgressNetPolIngressJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestAuditedPolicyAdded(t *testing.T) {
+	calls := GetAddPolicyTestCalls(bothDirectionsNetPol)
+	calls = append(calls, GetAddPolicyTestCalls(bothDirectionsNetPol)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	require.NoError(t, pMgr.AddPolicy(bothDirectionsNetPol, nil))
+	select {
+	case <-pMgr.AuditedPolicyAdded():
+		require.FailNow(t, "closed before a policy in audit mode was added")
+	default:
+	}
+
+	auditedNetPol := *bothDirectionsNetPol
+	auditedNetPol.PolicyKey = "x/audited"
+	auditedNetPol.AuditMode = true
+	require.NoError(t, pMgr.AddPolicy(&auditedNetPol, nil))
+	select {
+	case <-pMgr.AuditedPolicyAdded():
+	default:
+		require.FailNow(t, "not closed after a policy in audit mode was added")
+	}
+}
+
 func TestCreatorForRemovePolicies(t *testing.T) {
 	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
 	ioshim := common.NewMockIOShim(calls)
@@ -434,3 +591,107 @@ func TestUpdatingStaleChains(t *testing.T) {
 	require.NoError(t, pMgr.AddPolicy(bothDirectionsNetPol, nil))
 	assertStaleChainsContain(t, pMgr.staleChains, egressNetPolChain)
 }
+
+func TestCreatorForAddPoliciesWithAdminNetworkPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	cfg := &PolicyManagerCfg{
+		PolicyMode:                 IPSetPolicyMode,
+		EnableAdminNetworkPolicies: true,
+	}
+	pMgr := NewPolicyManager(ioshim, cfg)
+
+	policies := []*NPMNetworkPolicy{bothDirectionsNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		// jumps go after the jumps to the AdminNetworkPolicy chains
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 2 %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 2 %s", ingressEgressNetPolEgressJump),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestAdminTierPolicies(t *testing.T) {
+	metrics.ReinitializeAll()
+	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand, fakeIPTablesRestoreCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	cfg := &PolicyManagerCfg{
+		PolicyMode:                 IPSetPolicyMode,
+		EnableAdminNetworkPolicies: true,
+	}
+	pMgr := NewPolicyManager(ioshim, cfg)
+
+	passACL := &ACLPolicy{
+		SrcList:   []SetInfo{{ipsets.TestCIDRSet.Metadata, true, SrcMatch}},
+		Target:    Passed,
+		Direction: Ingress,
+		Protocol:  UnspecifiedProtocol,
+	}
+	lowPriority := NewAdminNPMNetworkPolicy(AdminTier, "low", 20)
+	lowPriority.PodSelectorList = []SetInfo{{ipsets.TestNSSet.Metadata, true, EitherMatch}}
+	lowPriority.ACLs = []*ACLPolicy{ingressAllowedACL, egressDeniedACL}
+	highPriority := NewAdminNPMNetworkPolicy(AdminTier, "high", 10)
+	highPriority.PodSelectorList = []SetInfo{{ipsets.TestKeyPodSet.Metadata, true, EitherMatch}}
+	highPriority.ACLs = []*ACLPolicy{passACL}
+
+	// the first policy activates NPM
+	require.NoError(t, pMgr.AddPolicy(lowPriority, nil))
+	require.Equal(t, "ADMIN/low", lowPriority.PolicyKey)
+
+	// the chains hold the rules of both policies, ordered by priority
+	creator := pMgr.creatorForAdminTier(AdminTier, []*NPMNetworkPolicy{lowPriority, highPriority}, false, false)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		"-F AZURE-NPM-ANP-INGRESS",
+		"-F AZURE-NPM-ANP-EGRESS",
+		fmt.Sprintf("-A AZURE-NPM-ANP-INGRESS -j RETURN -m set --match-set %s dst -m set --match-set %s src -m comment --comment ADMIN-POLICY-ADMIN/high-PRIORITY-10-PASS-FROM-cidr-test-cidr-set",
+			ipsets.TestKeyPodSet.HashedName, ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-A AZURE-NPM-ANP-INGRESS -j AZURE-NPM-INGRESS-ALLOW-MARK -m set --match-set %s dst -m set --match-set %s src -m comment --comment ADMIN-POLICY-ADMIN/low-PRIORITY-20-%s",
+			ipsets.TestNSSet.HashedName, ipsets.TestCIDRSet.HashedName, ingressAllowComment),
+		fmt.Sprintf("-A AZURE-NPM-ANP-EGRESS -j DROP -m set --match-set %s src -p UDP --dport 144 -m set --match-set %s dst -m comment --comment ADMIN-POLICY-ADMIN/low-PRIORITY-20-%s",
+			ipsets.TestNSSet.HashedName, ipsets.TestCIDRSet.HashedName, egressDropComment),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// removing the last policy deactivates NPM
+	creator = pMgr.creatorForAdminTier(AdminTier, pMgr.cachedTierPolicies(AdminTier, lowPriority.PolicyKey), false, pMgr.isLastPolicy())
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"*filter",
+		"-F AZURE-NPM",
+		"-F AZURE-NPM-ANP-INGRESS",
+		"-F AZURE-NPM-ANP-EGRESS",
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+	require.NoError(t, pMgr.RemovePolicy(lowPriority.PolicyKey, nil))
+	require.False(t, pMgr.PolicyExists(lowPriority.PolicyKey))
+}
+
+func TestAdminTierPoliciesDisabled(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	policy := NewAdminNPMNetworkPolicy(BaselineAdminTier, "default", 0)
+	policy.ACLs = []*ACLPolicy{ingressAllowedACL}
+	require.Error(t, pMgr.AddPolicy(policy, nil))
+}
@@ -15,6 +15,7 @@ import (
 var (
 	ErrFailedMarshalACLSettings                      = errors.New("failed to marshal ACL settings")
 	ErrFailedUnMarshalACLSettings                    = errors.New("failed to unmarshal ACL settings")
+	ErrUnsupportedAdminTier                          = errors.New("AdminNetworkPolicies and BaselineAdminNetworkPolicies are not supported on windows")
 	resetAllACLs                  shouldResetAllACLs = true
 	removeOnlyGivenPolicy         shouldResetAllACLs = false
 )
@@ -59,6 +60,9 @@ func (pMgr *PolicyManager) reconcile() {
 // addPolicy will add the policy for each specified endpoint if the policy doesn't exist on the endpoint yet,
 // and will add the endpoint to the PodEndpoints of the policy if successful.
 func (pMgr *PolicyManager) addPolicy(policy *NPMNetworkPolicy, endpointList map[string]string) error {
+	if policy.IsAdminTier() {
+		return ErrUnsupportedAdminTier
+	}
 	if len(endpointList) == 0 {
 		klog.Infof("[PolicyManagerWindows] No Endpoints to apply policy %s on", policy.PolicyKey)
 		return nil
@@ -150,6 +154,13 @@ func (pMgr *PolicyManager) removePolicy(policy *NPMNetworkPolicy, endpointList m
 	// then apply remaining policies onto the endpoint
 	var aggregateErr error
 	numOfRulesToRemove := len(rulesToRemove)
+	if numOfRulesToRemove == 0 {
+		// e.g. every ACL of the policy is audited, so nothing was applied to the endpoints
+		for epIPAddr := range endpointList {
+			delete(policy.PodEndpoints, epIPAddr)
+		}
+		return nil
+	}
 	for epIPAddr, epID := range endpointList {
 		err := pMgr.removePolicyByEndpointID(rulesToRemove[0].Id, epID, numOfRulesToRemove, removeOnlyGivenPolicy)
 		if err != nil {
@@ -261,14 +272,20 @@ func getEPPolicyReqFromACLSettings(settings []*NPMACLPolSettings) (hcn.PolicyEnd
 }
 
 func getSettingsFromACL(policy *NPMNetworkPolicy) ([]*NPMACLPolSettings, error) {
-	hnsRules := make([]*NPMACLPolSettings, len(policy.ACLs))
-	for i, acl := range policy.ACLs {
+	hnsRules := make([]*NPMACLPolSettings, 0, len(policy.ACLs))
+	for _, acl := range policy.ACLs {
+		if policy.AuditMode {
+			// HNS ACLs can't log, so the ACLs of a policy in audit mode are left out.
+			// Its drop ACLs would block traffic and its allow ACLs would override the drop ACLs of other policies.
+			klog.Infof("[PolicyManagerWindows] skipping ACL of policy %s since audit mode isn't supported in HNS", policy.PolicyKey)
+			continue
+		}
 		rule, err := acl.convertToAclSettings(policy.ACLPolicyID)
 		if err != nil {
 			// TODO need some retry mechanism to check why the translations failed
 			return hnsRules, err
 		}
-		hnsRules[i] = rule
+		hnsRules = append(hnsRules, rule)
 	}
 	return hnsRules, nil
 }
@@ -0,0 +1,259 @@
+// Package rulehits periodically reads the packet and byte counters of NPM's Linux policy chains
+// and exports them per policy in the NPM metrics.
+package rulehits
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"k8s.io/klog"
+)
+
+const (
+	DefaultInterval    = time.Minute
+	DefaultMaxPolicies = 100
+
+	// otherPolicyKey labels the rule hits of policies past the MaxPolicies limit
+	otherPolicyKey = "other"
+
+	directionIngress = "ingress"
+	directionEgress  = "egress"
+
+	verdictAllow = "allow"
+	verdictDrop  = "drop"
+	verdictAudit = "audit"
+
+	ingressJumpCommentPrefix = "INGRESS-POLICY-"
+	ingressJumpCommentSep    = "-TO-"
+	egressJumpCommentPrefix  = "EGRESS-POLICY-"
+	egressJumpCommentSep     = "-FROM-"
+)
+
+type Config struct {
+	Interval time.Duration
+	// MaxPolicies limits the cardinality of the metrics.
+	// Once this many policies are exported, other policies are aggregated under the "other" policy key.
+	MaxPolicies int
+}
+
+// series identifies one exported counter
+type series struct {
+	policyKey string
+	direction string
+	verdict   string
+}
+
+type counters struct {
+	packets uint64
+	bytes   uint64
+}
+
+type Collector struct {
+	parser      *parse.IPTablesParser
+	interval    time.Duration
+	maxPolicies int
+	// lastCounters holds the counters of each rule as of the previous read, keyed by ruleID
+	lastCounters map[string]counters
+	// exported holds every series which has been exported
+	exported map[series]struct{}
+	// trackedPolicies holds the policy keys which are exported with their own label
+	trackedPolicies map[string]struct{}
+}
+
+func NewCollector(ioShim *common.IOShim, cfg Config) *Collector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.MaxPolicies <= 0 {
+		cfg.MaxPolicies = DefaultMaxPolicies
+	}
+	return &Collector{
+		parser:          &parse.IPTablesParser{IOShim: ioShim},
+		interval:        cfg.Interval,
+		maxPolicies:     cfg.MaxPolicies,
+		lastCounters:    make(map[string]counters),
+		exported:        make(map[series]struct{}),
+		trackedPolicies: make(map[string]struct{}),
+	}
+}
+
+// Run reads the counters every interval until the stop channel is closed.
+func (c *Collector) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := c.collect(); err != nil {
+				klog.Errorf("[RuleHits] failed to read policy rule counters: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Collector) collect() error {
+	table, err := c.parser.IptablesWithCounters(util.IptablesFilterTable)
+	if err != nil {
+		return err //nolint:wrapcheck // the parser's error has context
+	}
+	c.update(table)
+	return nil
+}
+
+// policyRules are the rules of one policy chain
+type policyRules struct {
+	policyKey string
+	direction string
+	chain     *NPMIPtable.Chain
+}
+
+// update exports the increase of each rule's counters since the previous read.
+// A rule whose counters decreased was recreated, e.g. when its policy was updated, so all of its counters are new.
+func (c *Collector) update(table *NPMIPtable.Table) {
+	policies := findPolicyChains(table)
+	presentPolicies := make(map[string]struct{}, len(policies))
+	for _, p := range policies {
+		presentPolicies[p.policyKey] = struct{}{}
+	}
+	c.untrackRemovedPolicies(presentPolicies)
+
+	deltas := make(map[series]counters)
+	currentCounters := make(map[string]counters, len(c.lastCounters))
+	for _, p := range policies {
+		policyKey := c.policyKeyLabel(p.policyKey)
+		for i, rule := range p.chain.Rules {
+			verdict := ruleVerdict(rule)
+			if verdict == "" {
+				continue
+			}
+			id := ruleID(p.chain.Name, i, rule)
+			current := counters{packets: rule.Packets, bytes: rule.Bytes}
+			currentCounters[id] = current
+
+			delta := current
+			if last, ok := c.lastCounters[id]; ok && current.packets >= last.packets && current.bytes >= last.bytes {
+				delta = counters{packets: current.packets - last.packets, bytes: current.bytes - last.bytes}
+			}
+			s := series{policyKey: policyKey, direction: p.direction, verdict: verdict}
+			total := deltas[s]
+			deltas[s] = counters{packets: total.packets + delta.packets, bytes: total.bytes + delta.bytes}
+		}
+	}
+	c.lastCounters = currentCounters
+
+	for s, delta := range deltas {
+		metrics.AddPolicyRuleHits(s.policyKey, s.direction, s.verdict, delta.packets, delta.bytes)
+		c.exported[s] = struct{}{}
+	}
+}
+
+// untrackRemovedPolicies stops exporting policies which no longer have chains, freeing their spot within MaxPolicies.
+func (c *Collector) untrackRemovedPolicies(presentPolicies map[string]struct{}) {
+	for s := range c.exported {
+		if s.policyKey == otherPolicyKey {
+			continue
+		}
+		if _, ok := presentPolicies[s.policyKey]; !ok {
+			metrics.RemovePolicyRuleHits(s.policyKey, s.direction, s.verdict)
+			delete(c.exported, s)
+		}
+	}
+	for policyKey := range c.trackedPolicies {
+		if _, ok := presentPolicies[policyKey]; !ok {
+			delete(c.trackedPolicies, policyKey)
+		}
+	}
+}
+
+func (c *Collector) policyKeyLabel(policyKey string) string {
+	if _, ok := c.trackedPolicies[policyKey]; ok {
+		return policyKey
+	}
+	if len(c.trackedPolicies) >= c.maxPolicies {
+		return otherPolicyKey
+	}
+	c.trackedPolicies[policyKey] = struct{}{}
+	return policyKey
+}
+
+// findPolicyChains maps the policy chains to policy keys using the comments of the jump rules to them.
+// Policies are sorted by key so that the same policies are tracked on every read.
+func findPolicyChains(table *NPMIPtable.Table) []*policyRules {
+	policies := make([]*policyRules, 0)
+	jumps := []struct {
+		chain         string
+		direction     string
+		commentPrefix string
+		commentSep    string
+	}{
+		{util.IptablesAzureIngressChain, directionIngress, ingressJumpCommentPrefix, ingressJumpCommentSep},
+		{util.IptablesAzureEgressChain, directionEgress, egressJumpCommentPrefix, egressJumpCommentSep},
+	}
+	for _, jump := range jumps {
+		chain, ok := table.Chains[jump.chain]
+		if !ok {
+			continue
+		}
+		for _, rule := range chain.Rules {
+			if rule.Target == nil {
+				continue
+			}
+			policyChain, ok := table.Chains[rule.Target.Name]
+			if !ok {
+				continue
+			}
+			comment := ruleComment(rule)
+			if !strings.HasPrefix(comment, jump.commentPrefix) {
+				continue
+			}
+			policyKey, _, ok := strings.Cut(strings.TrimPrefix(comment, jump.commentPrefix), jump.commentSep)
+			if !ok {
+				continue
+			}
+			policies = append(policies, &policyRules{policyKey: policyKey, direction: jump.direction, chain: policyChain})
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].policyKey < policies[j].policyKey
+	})
+	return policies
+}
+
+// ruleVerdict returns the verdict of an ACL rule based on its comment, or "" if the rule shouldn't be counted.
+func ruleVerdict(rule *NPMIPtable.Rule) string {
+	comment := ruleComment(rule)
+	switch {
+	case strings.HasPrefix(comment, "AUDIT"):
+		return verdictAudit
+	case rule.Target != nil && rule.Target.Name == util.IptablesNFLogTarget:
+		// the drop log rule in front of a drop rule matches the same packets
+		return ""
+	case strings.HasPrefix(comment, "ALLOW"):
+		return verdictAllow
+	case strings.HasPrefix(comment, "DROP"):
+		return verdictDrop
+	}
+	return ""
+}
+
+func ruleComment(rule *NPMIPtable.Rule) string {
+	for _, module := range rule.Modules {
+		if module.Verb == util.IptablesCommentModuleFlag {
+			return strings.Trim(strings.Join(module.OptionValueMap[util.IptablesCommentModuleFlag], " "), "\"")
+		}
+	}
+	return ""
+}
+
+func ruleID(chainName string, index int, rule *NPMIPtable.Rule) string {
+	return strings.Join([]string{chainName, strconv.Itoa(index), ruleComment(rule)}, "/")
+}
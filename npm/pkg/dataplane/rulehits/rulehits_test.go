@@ -0,0 +1,111 @@
+package rulehits
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+const iptablesSaveFormat = `*filter
+:AZURE-NPM-INGRESS - [0:0]
+:AZURE-NPM-EGRESS - [0:0]
+:AZURE-NPM-INGRESS-111 - [0:0]
+:AZURE-NPM-EGRESS-111 - [0:0]
+:AZURE-NPM-INGRESS-222 - [0:0]
+[%[1]d:%[2]d] -A AZURE-NPM-INGRESS -m set --match-set azure-npm-1 dst -m comment --comment INGRESS-POLICY-x/allow-web-TO-podlabel-app:web-IN-ns-x -j AZURE-NPM-INGRESS-111
+[0:0] -A AZURE-NPM-INGRESS -m set --match-set azure-npm-2 dst -m comment --comment "INGRESS-POLICY-y/deny-TO-all-IN-ns-y" -j AZURE-NPM-INGRESS-222
+[0:0] -A AZURE-NPM-INGRESS -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400 -j AZURE-NPM-INGRESS-DROPS
+[0:0] -A AZURE-NPM-EGRESS -m set --match-set azure-npm-1 src -m comment --comment EGRESS-POLICY-x/allow-web-FROM-podlabel-app:web-IN-ns-x -j AZURE-NPM-EGRESS-111
+[%[1]d:%[2]d] -A AZURE-NPM-INGRESS-111 -p TCP --dport 80 -m comment --comment ALLOW-ALL-ON-TCP-TO-PORT-80 -j AZURE-NPM-INGRESS-ALLOW-MARK
+[3:300] -A AZURE-NPM-INGRESS-111 -m comment --comment DROP-ALL -j NFLOG --nflog-group 100 --nflog-prefix NPM-DROP-IN-111
+[3:300] -A AZURE-NPM-INGRESS-111 -m comment --comment DROP-ALL -j MARK --set-mark 0x400/0x400
+[5:500] -A AZURE-NPM-EGRESS-111 -m comment --comment ALLOW-ALL -j AZURE-NPM-ACCEPT
+[7:700] -A AZURE-NPM-INGRESS-222 -m comment --comment AUDIT-ALL -j NFLOG --nflog-group 100 --nflog-prefix NPM-AUDIT-IN-222
+COMMIT
+`
+
+func iptablesSaveCall(packets, bytes int) testutils.TestCmd {
+	return testutils.TestCmd{
+		Cmd:    []string{"iptables-save", "-c", "-t", "filter"},
+		Stdout: fmt.Sprintf(iptablesSaveFormat, packets, bytes),
+	}
+}
+
+func requireRuleHits(t *testing.T, policyKey, direction, verdict string, expectedPackets, expectedBytes int) {
+	t.Helper()
+	packets, err := metrics.GetPolicyRuleHitPackets(policyKey, direction, verdict)
+	require.NoError(t, err)
+	require.Equal(t, expectedPackets, packets, "packets of %s %s %s", policyKey, direction, verdict)
+	bytes, err := metrics.GetPolicyRuleHitBytes(policyKey, direction, verdict)
+	require.NoError(t, err)
+	require.Equal(t, expectedBytes, bytes, "bytes of %s %s %s", policyKey, direction, verdict)
+}
+
+func TestCollect(t *testing.T) {
+	metrics.InitializeAll()
+	calls := []testutils.TestCmd{
+		iptablesSaveCall(10, 1000),
+		iptablesSaveCall(15, 1500),
+		// the policy was updated, so its chain's counters were reset
+		iptablesSaveCall(2, 200),
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	c := NewCollector(ioshim, Config{})
+
+	require.NoError(t, c.collect())
+	requireRuleHits(t, "x/allow-web", directionIngress, verdictAllow, 10, 1000)
+	requireRuleHits(t, "x/allow-web", directionIngress, verdictDrop, 3, 300)
+	requireRuleHits(t, "x/allow-web", directionEgress, verdictAllow, 5, 500)
+	requireRuleHits(t, "y/deny", directionIngress, verdictAudit, 7, 700)
+
+	require.NoError(t, c.collect())
+	requireRuleHits(t, "x/allow-web", directionIngress, verdictAllow, 15, 1500)
+	requireRuleHits(t, "x/allow-web", directionIngress, verdictDrop, 3, 300)
+
+	require.NoError(t, c.collect())
+	requireRuleHits(t, "x/allow-web", directionIngress, verdictAllow, 17, 1700)
+}
+
+func TestCollectMaxPolicies(t *testing.T) {
+	metrics.InitializeAll()
+	// other tests also export x/allow-web and y/deny, so use different namespaces
+	renamed := strings.NewReplacer("x/", "a/", "y/", "b/")
+	calls := []testutils.TestCmd{
+		iptablesSaveCall(10, 1000),
+		{
+			Cmd: []string{"iptables-save", "-c", "-t", "filter"},
+			Stdout: `*filter
+:AZURE-NPM-INGRESS - [0:0]
+:AZURE-NPM-INGRESS-222 - [0:0]
+[0:0] -A AZURE-NPM-INGRESS -m comment --comment INGRESS-POLICY-y/deny-TO-all-IN-ns-y -j AZURE-NPM-INGRESS-222
+[9:900] -A AZURE-NPM-INGRESS-222 -m comment --comment AUDIT-ALL -j NFLOG --nflog-group 100 --nflog-prefix NPM-AUDIT-IN-222
+COMMIT
+`,
+		},
+	}
+	for i := range calls {
+		calls[i].Stdout = renamed.Replace(calls[i].Stdout)
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	c := NewCollector(ioshim, Config{MaxPolicies: 1})
+
+	require.NoError(t, c.collect())
+	_, tracked := c.trackedPolicies["a/allow-web"]
+	require.True(t, tracked)
+	requireRuleHits(t, otherPolicyKey, directionIngress, verdictAudit, 7, 700)
+
+	// a/allow-web was removed, so b/deny gets its own label
+	require.NoError(t, c.collect())
+	require.NotContains(t, c.trackedPolicies, "a/allow-web")
+	require.NotContains(t, c.exported, series{policyKey: "a/allow-web", direction: directionEgress, verdict: verdictAllow})
+	require.Contains(t, c.trackedPolicies, "b/deny")
+	requireRuleHits(t, "b/deny", directionIngress, verdictAudit, 2, 200)
+	requireRuleHits(t, otherPolicyKey, directionIngress, verdictAudit, 7, 700)
+}
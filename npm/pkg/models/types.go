@@ -7,6 +7,7 @@ import (
 	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
@@ -34,6 +35,8 @@ type K8SControllersV2 struct {
 	NamespaceControllerV2 *controllersv2.NamespaceController     //nolint:structcheck // false lint error
 	NpmNamespaceCacheV2   *controllersv2.NpmNamespaceCache       //nolint:structcheck // false lint error
 	NetPolControllerV2    *controllersv2.NetworkPolicyController //nolint:structcheck // false lint error
+	// AdminNetPolControllerV2 is only set when AdminNetworkPolicies are watched
+	AdminNetPolControllerV2 *controllersv2.AdminNetworkPolicyController //nolint:structcheck // false lint error
 }
 
 // Informers are the informers for the k8s controllers
@@ -44,6 +47,13 @@ type Informers struct {
 	NpInformer      networkinginformers.NetworkPolicyInformer //nolint:structcheck // false lint error
 }
 
+// AdminInformers are the dynamic informers for the AdminNetworkPolicy CRDs
+type AdminInformers struct {
+	DynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory //nolint:structcheck // false lint error
+	AnpInformer            informers.GenericInformer                    //nolint:structcheck // false lint error
+	BanpInformer           informers.GenericInformer                    //nolint:structcheck // false lint error
+}
+
 // AzureConfig captures the Azure specific configurations and fields
 type AzureConfig struct {
 	K8sServerVersion *version.Info
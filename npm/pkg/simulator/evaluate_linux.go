@@ -0,0 +1,191 @@
+package simulator
+
+import (
+	"strconv"
+	"strings"
+
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"k8s.io/klog"
+)
+
+// maxJumpDepth guards against jump loops in the simulated rules.
+const maxJumpDepth = 32
+
+const (
+	iptablesReject = "REJECT"
+	iptablesReturn = "RETURN"
+)
+
+// packet is the first packet of a new connection.
+type packet struct {
+	srcIP    string
+	dstIP    string
+	protocol string
+	dstPort  int32
+	mark     uint32
+}
+
+type chainResult int
+
+const (
+	fallThrough chainResult = iota
+	accepted
+	dropped
+)
+
+// evaluator walks a packet through the filter table, starting at the FORWARD chain.
+type evaluator struct {
+	table        *NPMIPtable.Table
+	kernel       *kernel
+	policyChains map[string]string
+	trace        []*RuleHit
+}
+
+// evaluate returns whether the packet is accepted. The FORWARD chain has an ACCEPT policy, as on AKS nodes.
+func (e *evaluator) evaluate(p *packet) bool {
+	e.trace = make([]*RuleHit, 0)
+	e.kernel.Lock()
+	defer e.kernel.Unlock()
+	return e.walk(util.IptablesForwardChain, p, 0) != dropped
+}
+
+func (e *evaluator) walk(chainName string, p *packet, depth int) chainResult {
+	chain, ok := e.table.Chains[chainName]
+	if !ok || depth > maxJumpDepth {
+		return fallThrough
+	}
+	for _, rule := range chain.Rules {
+		if !e.matches(rule, p) {
+			continue
+		}
+		if rule.Target == nil {
+			continue
+		}
+		hit := &RuleHit{
+			Chain:     chainName,
+			Target:    rule.Target.Name,
+			Comment:   ruleComment(rule),
+			PolicyKey: e.policyChains[chainName],
+		}
+		if hit.PolicyKey == "" {
+			hit.PolicyKey = e.policyChains[rule.Target.Name]
+		}
+		e.trace = append(e.trace, hit)
+
+		switch rule.Target.Name {
+		case util.IptablesAccept:
+			return accepted
+		case util.IptablesDrop, iptablesReject:
+			return dropped
+		case iptablesReturn:
+			return fallThrough
+		case util.IptablesMark:
+			p.mark = applyMark(p.mark, rule.Target.OptionValueMap)
+		default:
+			if _, isChain := e.table.Chains[rule.Target.Name]; !isChain {
+				// e.g. LOG or NFLOG, which don't end traversal
+				continue
+			}
+			if result := e.walk(rule.Target.Name, p, depth+1); result != fallThrough {
+				return result
+			}
+		}
+	}
+	return fallThrough
+}
+
+func (e *evaluator) matches(rule *NPMIPtable.Rule, p *packet) bool {
+	if rule.Protocol != "" && !strings.EqualFold(rule.Protocol, p.protocol) {
+		return false
+	}
+	for _, module := range rule.Modules {
+		switch module.Verb {
+		case util.IptablesSetModuleFlag:
+			for option, values := range module.OptionValueMap {
+				if len(values) < 2 {
+					continue
+				}
+				flags := strings.Split(values[1], ",")
+				inSet := e.kernel.testSet(values[0], flags, p)
+				switch option {
+				case "match-set":
+					if !inSet {
+						return false
+					}
+				case util.NegationPrefix + "match-set":
+					if inSet {
+						return false
+					}
+				}
+			}
+		case "tcp", "udp", "sctp":
+			if dports, ok := module.OptionValueMap["dport"]; ok && len(dports) > 0 && !portInRange(p.dstPort, dports[0]) {
+				return false
+			}
+		case util.IptablesMarkVerb:
+			if marks, ok := module.OptionValueMap["mark"]; ok && len(marks) > 0 && !markMatches(p.mark, marks[0]) {
+				return false
+			}
+		case util.IptablesCommentModuleFlag, "conntrack", "state":
+			// every simulated packet starts a new connection
+		default:
+			klog.Infof("[simulator] treating unsupported match module %s as a match", module.Verb)
+		}
+	}
+	return true
+}
+
+func ruleComment(rule *NPMIPtable.Rule) string {
+	for _, module := range rule.Modules {
+		if module.Verb == util.IptablesCommentModuleFlag {
+			return strings.Trim(strings.Join(module.OptionValueMap[util.IptablesCommentModuleFlag], " "), "\"")
+		}
+	}
+	return ""
+}
+
+func portInRange(port int32, portRange string) bool {
+	start, end, isRange := strings.Cut(portRange, ":")
+	startPort, err := strconv.Atoi(start)
+	if err != nil {
+		return false
+	}
+	if !isRange {
+		return int(port) == startPort
+	}
+	endPort, err := strconv.Atoi(end)
+	if err != nil {
+		return false
+	}
+	return int(port) >= startPort && int(port) <= endPort
+}
+
+// parseMark parses "value[/mask]". Without a mask, all bits are used.
+func parseMark(s string) (value, mask uint32) {
+	valueString, maskString, hasMask := strings.Cut(s, "/")
+	v, _ := strconv.ParseUint(valueString, 0, 32)
+	m := uint64(0xffffffff)
+	if hasMask {
+		m, _ = strconv.ParseUint(maskString, 0, 32)
+	}
+	return uint32(v), uint32(m)
+}
+
+func markMatches(mark uint32, s string) bool {
+	value, mask := parseMark(s)
+	return mark&mask == value
+}
+
+// applyMark handles the MARK target's --set-mark (zero the mask bits, then OR the value) and --set-xmark (zero the mask bits, then XOR the value).
+func applyMark(mark uint32, options map[string][]string) uint32 {
+	if values, ok := options["set-mark"]; ok && len(values) > 0 {
+		value, mask := parseMark(values[0])
+		return (mark &^ mask) | value
+	}
+	if values, ok := options["set-xmark"]; ok && len(values) > 0 {
+		value, mask := parseMark(values[0])
+		return (mark &^ mask) ^ value
+	}
+	return mark
+}
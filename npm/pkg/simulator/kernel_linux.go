@@ -0,0 +1,656 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	utilexec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+const (
+	ipsetCommand     = "ipset"
+	ipsetHashNet     = "hash:net"
+	ipsetHashIPPort  = "hash:ip,port"
+	ipsetListSet     = "list:set"
+	ipsetNoMatchFlag = "nomatch"
+
+	errorExitCode = 1
+)
+
+var ipsetTypes = map[string]string{
+	"nethash":       ipsetHashNet,
+	ipsetHashNet:    ipsetHashNet,
+	ipsetHashIPPort: ipsetHashIPPort,
+	"setlist":       ipsetListSet,
+	ipsetListSet:    ipsetListSet,
+}
+
+// kernel is an in-memory stand-in for the iptables filter table and the ipsets of a Linux node.
+// It implements utilexec.Interface so the real dataplane can program it through a common.IOShim.
+// Only the commands issued by the NPM dataplane are understood. Anything else succeeds without output.
+type kernel struct {
+	sync.Mutex
+	chainOrder []string
+	chains     map[string][]string
+	sets       map[string]*kernelSet
+}
+
+type kernelSet struct {
+	name    string
+	setType string
+	members map[string]struct{}
+}
+
+var _ utilexec.Interface = &kernel{}
+
+func newKernel() *kernel {
+	k := &kernel{
+		chains: make(map[string][]string),
+		sets:   make(map[string]*kernelSet),
+	}
+	for _, chain := range []string{util.IptablesForwardChain, "INPUT", "OUTPUT"} {
+		k.ensureChain(chain)
+	}
+	return k
+}
+
+func (k *kernel) Command(cmd string, args ...string) utilexec.Cmd {
+	return &kernelCmd{kernel: k, name: cmd, args: args}
+}
+
+func (k *kernel) CommandContext(_ context.Context, cmd string, args ...string) utilexec.Cmd {
+	return k.Command(cmd, args...)
+}
+
+func (k *kernel) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+// run executes a command against the kernel state and returns its combined output.
+func (k *kernel) run(name string, args []string, stdin []byte) ([]byte, error) {
+	switch name {
+	case util.IptablesRestore:
+		return k.iptablesRestore(stdin)
+	case util.Iptables:
+		return k.iptables(args)
+	case util.IptablesSave:
+		return k.iptablesSave(), nil
+	case ipsetCommand:
+		return k.ipset(args, stdin)
+	case util.BashCommand:
+		// the ipset manager flushes and destroys all sets at once when resetting
+		if len(args) == 2 && strings.Contains(args[1], "ipset flush") {
+			k.Lock()
+			k.sets = make(map[string]*kernelSet)
+			k.Unlock()
+		}
+		return nil, nil
+	case ioutil.Grep:
+		return grep(args, stdin)
+	default:
+		return nil, nil
+	}
+}
+
+func exitError(format string, a ...interface{}) ([]byte, error) {
+	return []byte(fmt.Sprintf(format, a...)), &testingexec.FakeExitError{Status: errorExitCode}
+}
+
+func (k *kernel) ensureChain(chain string) {
+	if _, ok := k.chains[chain]; !ok {
+		k.chains[chain] = make([]string, 0)
+		k.chainOrder = append(k.chainOrder, chain)
+	}
+}
+
+func (k *kernel) deleteChain(chain string) {
+	delete(k.chains, chain)
+	for i, c := range k.chainOrder {
+		if c == chain {
+			k.chainOrder = append(k.chainOrder[:i], k.chainOrder[i+1:]...)
+			return
+		}
+	}
+}
+
+// iptablesRestore applies an iptables-restore --noflush file for the filter table.
+func (k *kernel) iptablesRestore(stdin []byte) ([]byte, error) {
+	k.Lock()
+	defer k.Unlock()
+	for i, line := range strings.Split(string(stdin), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == '*' || line == util.IptablesRestoreCommit {
+			continue
+		}
+		if line[0] == ':' {
+			// a chain header creates the chain or flushes it when using --noflush
+			chain := strings.Fields(line[1:])[0]
+			k.ensureChain(chain)
+			k.chains[chain] = make([]string, 0)
+			continue
+		}
+		if err := k.applyRule(strings.Fields(line)); err != nil {
+			return exitError("iptables-restore: line %d failed: %s", i+1, err.Error())
+		}
+	}
+	return nil, nil
+}
+
+// iptables handles single iptables commands (without iptables-restore).
+func (k *kernel) iptables(args []string) ([]byte, error) {
+	k.Lock()
+	defer k.Unlock()
+	specs := make([]string, 0, len(args))
+	list, lineNumbers := false, false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case util.IptablesWaitFlag, util.IptablesTableFlag:
+			i++ // skip the flag's value
+		case util.IptablesNumericFlag:
+		case util.IptablesListFlag:
+			list = true
+		case util.IptablesLineNumbersFlag:
+			lineNumbers = true
+		default:
+			specs = append(specs, args[i])
+		}
+	}
+	if list {
+		return k.list(specs, lineNumbers), nil
+	}
+	if err := k.applyRule(specs); err != nil {
+		return exitError("iptables: %s", err.Error())
+	}
+	return nil, nil
+}
+
+// applyRule applies a rule operation such as "-A CHAIN specs..." or "-X CHAIN".
+func (k *kernel) applyRule(fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("bad rule %v", fields)
+	}
+	operation, chain, specs := fields[0], fields[1], fields[2:]
+	switch operation {
+	case util.IptablesChainCreationFlag:
+		k.ensureChain(chain)
+		return nil
+	case util.IptablesDestroyFlag:
+		if _, ok := k.chains[chain]; !ok {
+			return fmt.Errorf("No chain/target/match by that name")
+		}
+		k.deleteChain(chain)
+		return nil
+	case util.IptablesFlushFlag:
+		if _, ok := k.chains[chain]; !ok {
+			return fmt.Errorf("No chain/target/match by that name")
+		}
+		k.chains[chain] = make([]string, 0)
+		return nil
+	}
+
+	rules, ok := k.chains[chain]
+	if !ok {
+		return fmt.Errorf("Couldn't load target `%s':No such file or directory", chain)
+	}
+	switch operation {
+	case util.IptablesAppendFlag:
+		k.chains[chain] = append(rules, normalizeRule(specs))
+	case util.IptablesInsertionFlag:
+		index := 1
+		if len(specs) > 0 {
+			if n, err := strconv.Atoi(specs[0]); err == nil {
+				index = n
+				specs = specs[1:]
+			}
+		}
+		if index < 1 || index > len(rules)+1 {
+			return fmt.Errorf("Index of insertion too big")
+		}
+		rule := normalizeRule(specs)
+		rules = append(rules, "")
+		copy(rules[index:], rules[index-1:])
+		rules[index-1] = rule
+		k.chains[chain] = rules
+	case util.IptablesDeletionFlag:
+		rule := normalizeRule(specs)
+		for i, r := range rules {
+			if r == rule {
+				k.chains[chain] = append(rules[:i], rules[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("Bad rule (does a matching rule exist in that chain?)")
+	default:
+		return fmt.Errorf("unknown operation %s", operation)
+	}
+	return nil
+}
+
+// normalizeRule formats rule specs the way iptables-save prints them,
+// e.g. "-p TCP --dport 80" becomes "-p tcp -m tcp --dport 80".
+func normalizeRule(specs []string) string {
+	normalized := make([]string, 0, len(specs))
+	protocol := ""
+	hasProtocolModule := false
+	for i := 0; i < len(specs); i++ {
+		spec := specs[i]
+		switch {
+		case spec == util.IptablesProtFlag && i+1 < len(specs):
+			protocol = strings.ToLower(specs[i+1])
+			normalized = append(normalized, spec, protocol)
+			i++
+			continue
+		case spec == util.IptablesModuleFlag && i+1 < len(specs) && specs[i+1] == protocol:
+			hasProtocolModule = true
+		case (spec == util.IptablesDstPortFlag || spec == util.IptablesSrcPortFlag) && protocol != "" && !hasProtocolModule:
+			normalized = append(normalized, util.IptablesModuleFlag, protocol)
+			hasProtocolModule = true
+		}
+		normalized = append(normalized, spec)
+	}
+	return strings.Join(normalized, " ")
+}
+
+func (k *kernel) iptablesSave() []byte {
+	k.Lock()
+	defer k.Unlock()
+	var b bytes.Buffer
+	b.WriteString("*" + util.IptablesFilterTable + "\n")
+	for _, chain := range k.chainOrder {
+		fmt.Fprintf(&b, ":%s - [0:0]\n", chain)
+	}
+	for _, chain := range k.chainOrder {
+		for _, rule := range k.chains[chain] {
+			fmt.Fprintf(&b, "-A %s %s\n", chain, rule)
+		}
+	}
+	b.WriteString(util.IptablesRestoreCommit + "\n")
+	return b.Bytes()
+}
+
+// list mimics the output of "iptables -n -L [chain] [--line-numbers]", which the dataplane greps for chain names and line numbers.
+func (k *kernel) list(specs []string, lineNumbers bool) []byte {
+	chains := k.chainOrder
+	if len(specs) > 0 {
+		chains = specs[:1]
+	}
+	var b bytes.Buffer
+	for _, chain := range chains {
+		rules, ok := k.chains[chain]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "Chain %s (0 references)\n", chain)
+		for i, rule := range rules {
+			target := ""
+			fields := strings.Fields(rule)
+			for j := 0; j < len(fields)-1; j++ {
+				if fields[j] == util.IptablesJumpFlag {
+					target = fields[j+1]
+				}
+			}
+			if lineNumbers {
+				fmt.Fprintf(&b, "%d    %s  all  --  0.0.0.0/0  0.0.0.0/0\n", i+1, target)
+			} else {
+				fmt.Fprintf(&b, "%s  all  --  0.0.0.0/0  0.0.0.0/0\n", target)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+func (k *kernel) ipset(args []string, stdin []byte) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	k.Lock()
+	defer k.Unlock()
+	switch args[0] {
+	case "restore":
+		for i, line := range strings.Split(string(stdin), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if err := k.applyIPSetOperation(fields); err != nil {
+				return exitError("ipset v7.5: Error in line %d: %s", i+1, err.Error())
+			}
+		}
+		return nil, nil
+	case "list":
+		names := k.sortedSetNames()
+		var b bytes.Buffer
+		for _, name := range names {
+			if len(args) > 1 && args[1] == "--name" {
+				b.WriteString(name + "\n")
+				continue
+			}
+			set := k.sets[name]
+			fmt.Fprintf(&b, "Name: %s\nType: %s\nReferences: 0\nMembers:\n", name, set.setType)
+			for _, member := range sortedKeys(set.members) {
+				b.WriteString(member + "\n")
+			}
+			b.WriteString("\n")
+		}
+		return b.Bytes(), nil
+	case "save":
+		var b bytes.Buffer
+		for _, name := range k.sortedSetNames() {
+			set := k.sets[name]
+			fmt.Fprintf(&b, "create %s %s\n", name, set.setType)
+			for _, member := range sortedKeys(set.members) {
+				fmt.Fprintf(&b, "add %s %s\n", name, member)
+			}
+		}
+		return b.Bytes(), nil
+	default:
+		if err := k.applyIPSetOperation(args); err != nil {
+			return exitError("ipset v7.5: %s", err.Error())
+		}
+		return nil, nil
+	}
+}
+
+func (k *kernel) applyIPSetOperation(fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("Syntax error: missing set name")
+	}
+	operation, name := fields[0], fields[1]
+	switch operation {
+	case "-N", "create":
+		setType := ""
+		for _, field := range fields[2:] {
+			if t, ok := ipsetTypes[field]; ok {
+				setType = t
+			}
+		}
+		if setType == "" {
+			return fmt.Errorf("Syntax error: unknown set type for %s", name)
+		}
+		if existing, ok := k.sets[name]; ok {
+			if existing.setType != setType {
+				return fmt.Errorf("Set cannot be created: set with the same name already exists")
+			}
+			return nil
+		}
+		k.sets[name] = &kernelSet{name: name, setType: setType, members: make(map[string]struct{})}
+		return nil
+	case "-X", "destroy":
+		if _, ok := k.sets[name]; !ok {
+			return fmt.Errorf("The set with the given name does not exist")
+		}
+		delete(k.sets, name)
+		return nil
+	}
+
+	set, ok := k.sets[name]
+	if !ok {
+		return fmt.Errorf("The set with the given name does not exist")
+	}
+	switch operation {
+	case "-F", "flush":
+		set.members = make(map[string]struct{})
+	case "-A", "add":
+		if len(fields) < 3 {
+			return fmt.Errorf("Syntax error: missing member")
+		}
+		member := strings.Join(fields[2:], " ")
+		if set.setType == ipsetListSet {
+			if _, ok := k.sets[member]; !ok {
+				return fmt.Errorf("Set to be added/deleted/tested as element does not exist")
+			}
+		}
+		set.members[normalizeMember(set.setType, member)] = struct{}{}
+	case "-D", "del":
+		if len(fields) < 3 {
+			return fmt.Errorf("Syntax error: missing member")
+		}
+		delete(set.members, normalizeMember(set.setType, strings.Join(fields[2:], " ")))
+	default:
+		return fmt.Errorf("unknown ipset operation %s", operation)
+	}
+	return nil
+}
+
+// normalizeMember formats members the way ipset stores them, e.g. "10.0.0.1,TCP:80" becomes "10.0.0.1,tcp:80".
+func normalizeMember(setType, member string) string {
+	if setType != ipsetHashIPPort {
+		return member
+	}
+	ip, port, found := strings.Cut(member, ",")
+	if !found {
+		return member
+	}
+	if !strings.Contains(port, ":") {
+		port = "tcp:" + port
+	}
+	return ip + "," + strings.ToLower(port)
+}
+
+func (k *kernel) sortedSetNames() []string {
+	names := make([]string, 0, len(k.sets))
+	for name := range k.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// testSet reports whether the packet matches the set for the given direction flags (e.g. "src", "dst", or "dst,dst").
+func (k *kernel) testSet(name string, flags []string, p *packet) bool {
+	set, ok := k.sets[name]
+	if !ok || len(flags) == 0 {
+		return false
+	}
+	ip := p.dstIP
+	if flags[0] == util.IptablesSrcFlag {
+		ip = p.srcIP
+	}
+	switch set.setType {
+	case ipsetListSet:
+		for member := range set.members {
+			if k.testSet(member, flags, p) {
+				return true
+			}
+		}
+		return false
+	case ipsetHashIPPort:
+		port := p.dstPort
+		if len(flags) > 1 && flags[1] == util.IptablesSrcFlag {
+			// source ports are not simulated
+			return false
+		}
+		_, ok := set.members[fmt.Sprintf("%s,%s:%d", ip, strings.ToLower(p.protocol), port)]
+		return ok
+	default:
+		return hashNetContains(set, ip)
+	}
+}
+
+// hashNetContains applies the most specific matching entry, so "nomatch" entries carve exceptions out of larger CIDRs.
+func hashNetContains(set *kernelSet, ip string) bool {
+	parsedIP := net.ParseIP(ip)
+	bestPrefix := -1
+	matched := false
+	for member := range set.members {
+		fields := strings.Fields(member)
+		cidr := fields[0]
+		if !strings.Contains(cidr, "/") {
+			cidr += "/32"
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || !ipNet.Contains(parsedIP) {
+			continue
+		}
+		prefix, _ := ipNet.Mask.Size()
+		if prefix > bestPrefix {
+			bestPrefix = prefix
+			matched = len(fields) == 1 || fields[1] != ipsetNoMatchFlag
+		}
+	}
+	return matched
+}
+
+// grep supports the flags the dataplane uses: -q, -v, -o, -P, and -B.
+func grep(args []string, stdin []byte) ([]byte, error) {
+	quiet, invert, onlyMatching := false, false, false
+	pattern := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case ioutil.GrepQuietFlag:
+			quiet = true
+		case ioutil.GrepAntiMatchFlag:
+			invert = true
+		case ioutil.GrepOnlyMatchingFlag:
+			onlyMatching = true
+		case ioutil.GrepRegexFlag:
+		case ioutil.GrepBeforeFlag:
+			i++ // context lines don't matter to the callers
+		default:
+			pattern = args[i]
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return exitError("grep: %s", err.Error())
+	}
+	var b bytes.Buffer
+	found := false
+	for _, line := range strings.Split(strings.TrimSuffix(string(stdin), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if re.MatchString(line) == invert {
+			continue
+		}
+		found = true
+		if onlyMatching && !invert {
+			b.WriteString(re.FindString(line) + "\n")
+		} else {
+			b.WriteString(line + "\n")
+		}
+	}
+	if !found {
+		return nil, &testingexec.FakeExitError{Status: errorExitCode}
+	}
+	if quiet {
+		return nil, nil
+	}
+	return b.Bytes(), nil
+}
+
+// kernelCmd is a command run against the kernel. Its output is computed once, when first needed,
+// so that piping one command into another works regardless of the order in which they are started.
+type kernelCmd struct {
+	kernel *kernel
+	name   string
+	args   []string
+	stdin  io.Reader
+	stdout io.Writer
+	once   sync.Once
+	output []byte
+	err    error
+}
+
+func (c *kernelCmd) execute() ([]byte, error) {
+	c.once.Do(func() {
+		var stdin []byte
+		if c.stdin != nil {
+			stdin, c.err = io.ReadAll(c.stdin)
+			if c.err != nil {
+				return
+			}
+		}
+		c.output, c.err = c.kernel.run(c.name, c.args, stdin)
+	})
+	return c.output, c.err
+}
+
+func (c *kernelCmd) Run() error {
+	output, err := c.execute()
+	if c.stdout != nil {
+		_, _ = c.stdout.Write(output)
+	}
+	return err
+}
+
+func (c *kernelCmd) CombinedOutput() ([]byte, error) {
+	return c.execute()
+}
+
+func (c *kernelCmd) Output() ([]byte, error) {
+	return c.execute()
+}
+
+func (c *kernelCmd) SetDir(string) {}
+
+func (c *kernelCmd) SetStdin(in io.Reader) {
+	c.stdin = in
+}
+
+func (c *kernelCmd) SetStdout(out io.Writer) {
+	c.stdout = out
+}
+
+func (c *kernelCmd) SetStderr(io.Writer) {}
+
+func (c *kernelCmd) SetEnv([]string) {}
+
+func (c *kernelCmd) StdoutPipe() (io.ReadCloser, error) {
+	return &lazyReader{cmd: c}, nil
+}
+
+func (c *kernelCmd) StderrPipe() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+
+func (c *kernelCmd) Start() error {
+	_, err := c.execute()
+	return err
+}
+
+func (c *kernelCmd) Wait() error {
+	_, err := c.execute()
+	return err
+}
+
+func (c *kernelCmd) Stop() {}
+
+// lazyReader runs its command on the first read.
+type lazyReader struct {
+	cmd    *kernelCmd
+	reader io.Reader
+}
+
+func (r *lazyReader) Read(p []byte) (int, error) {
+	if r.reader == nil {
+		output, _ := r.cmd.execute()
+		r.reader = bytes.NewReader(output)
+	}
+	return r.reader.Read(p) //nolint:wrapcheck // io.EOF must not be wrapped
+}
+
+func (r *lazyReader) Close() error {
+	return nil
+}
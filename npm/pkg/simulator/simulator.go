@@ -0,0 +1,234 @@
+// Package simulator evaluates NetworkPolicies offline.
+// Pods, namespaces and NetworkPolicies are read from plain Kubernetes manifests, translated with the v2 translation package,
+// and programmed through the real dataplane into an in-memory kernel. Traffic between two endpoints is then
+// evaluated against the resulting iptables rules and ipsets, so policies can be tested without a cluster.
+package simulator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	ErrUnsupportedPlatform = errors.New("the NetworkPolicy simulator is only supported on Linux")
+	ErrUnknownEndpoint     = errors.New("endpoint is neither a known pod nor an IP address")
+	ErrPodWithoutIP        = errors.New("pod has no IP in status.podIP")
+	ErrInvalidProtocol     = errors.New("protocol must be TCP, UDP, or SCTP")
+	ErrInvalidPort         = errors.New("port must be between 1 and 65535")
+)
+
+// Manifests holds the Kubernetes objects which are fed to the simulator.
+type Manifests struct {
+	Namespaces      []*corev1.Namespace
+	Pods            []*corev1.Pod
+	NetworkPolicies []*networkingv1.NetworkPolicy
+}
+
+// LoadManifestFiles reads YAML or JSON manifests from the given files.
+// Each file may contain multiple documents and List objects.
+func LoadManifestFiles(files ...string) (*Manifests, error) {
+	m := &Manifests{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest file %s: %w", file, err)
+		}
+		if err := m.add(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to load manifest file %s: %w", file, err)
+		}
+	}
+	return m, nil
+}
+
+// LoadManifests reads YAML or JSON manifests from r.
+func LoadManifests(r io.Reader) (*Manifests, error) {
+	m := &Manifests{}
+	if err := m.add(r); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Manifests) add(r io.Reader) error {
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	decoder := scheme.Codecs.UniversalDeserializer()
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read document: %w", err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to decode document: %w", err)
+		}
+		if err := m.addObject(obj); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *Manifests) addObject(obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.Namespace:
+		m.Namespaces = append(m.Namespaces, o)
+	case *corev1.Pod:
+		if o.Namespace == "" {
+			o.Namespace = corev1.NamespaceDefault
+		}
+		m.Pods = append(m.Pods, o)
+	case *networkingv1.NetworkPolicy:
+		if o.Namespace == "" {
+			o.Namespace = corev1.NamespaceDefault
+		}
+		m.NetworkPolicies = append(m.NetworkPolicies, o)
+	case *corev1.List:
+		for i := range o.Items {
+			item, _, err := scheme.Codecs.UniversalDeserializer().Decode(o.Items[i].Raw, nil, nil)
+			if err != nil {
+				return fmt.Errorf("failed to decode list item %d: %w", i, err)
+			}
+			if err := m.addObject(item); err != nil {
+				return err
+			}
+		}
+	default:
+		// other kinds (deployments, services, etc.) don't affect NetworkPolicy enforcement
+	}
+	return nil
+}
+
+// Query describes a connection to evaluate.
+// Src and Dst are either "namespace/name" of a pod (or just "name" for the default namespace) or an IP address.
+type Query struct {
+	Src      string
+	Dst      string
+	Protocol string
+	Port     int32
+}
+
+// RuleHit is a rule which matched the packet while it traversed the iptables chains.
+type RuleHit struct {
+	Chain   string
+	Target  string
+	Comment string
+	// PolicyKey is the "namespace/name" of the NetworkPolicy which produced the rule (empty for base NPM rules)
+	PolicyKey string
+}
+
+func (hit *RuleHit) String() string {
+	s := fmt.Sprintf("chain %s -j %s", hit.Chain, hit.Target)
+	if hit.Comment != "" {
+		s += fmt.Sprintf(" (%s)", hit.Comment)
+	}
+	if hit.PolicyKey != "" {
+		s += fmt.Sprintf(" [policy %s]", hit.PolicyKey)
+	}
+	return s
+}
+
+// Result is the outcome of a Query.
+type Result struct {
+	Allowed bool
+	// Reason summarizes why the traffic was allowed or denied
+	Reason string
+	// PolicyRules are the rules generated from NetworkPolicies which decided the outcome
+	PolicyRules []*RuleHit
+	// Trace contains every rule matched by the packet in order of traversal
+	Trace []*RuleHit
+}
+
+// PrettyString returns a multi-line, human readable form of the result.
+func (r *Result) PrettyString() string {
+	var b strings.Builder
+	if r.Allowed {
+		b.WriteString("ALLOWED")
+	} else {
+		b.WriteString("DENIED")
+	}
+	fmt.Fprintf(&b, ": %s\n", r.Reason)
+	if len(r.PolicyRules) > 0 {
+		b.WriteString("Policy rules:\n")
+		for _, hit := range r.PolicyRules {
+			fmt.Fprintf(&b, "\t%s\n", hit.String())
+		}
+	}
+	b.WriteString("Trace:\n")
+	for _, hit := range r.Trace {
+		fmt.Fprintf(&b, "\t%s\n", hit.String())
+	}
+	return b.String()
+}
+
+// endpoint is a resolved source or destination of a Query.
+type endpoint struct {
+	ip  string
+	pod *corev1.Pod
+}
+
+func (e *endpoint) String() string {
+	if e.pod != nil {
+		return fmt.Sprintf("pod %s/%s (%s)", e.pod.Namespace, e.pod.Name, e.ip)
+	}
+	return e.ip
+}
+
+// resolveEndpoint finds the pod referred to by s, or treats s as an IP outside of the cluster.
+func resolveEndpoint(pods []*corev1.Pod, s string) (*endpoint, error) {
+	namespace, name := corev1.NamespaceDefault, s
+	if i := strings.Index(s, "/"); i >= 0 {
+		namespace, name = s[:i], s[i+1:]
+	}
+	for _, pod := range pods {
+		if pod.Namespace == namespace && pod.Name == name {
+			if pod.Status.PodIP == "" {
+				return nil, fmt.Errorf("%w: %s/%s", ErrPodWithoutIP, namespace, name)
+			}
+			return &endpoint{ip: pod.Status.PodIP, pod: pod}, nil
+		}
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		for _, pod := range pods {
+			if pod.Status.PodIP == s {
+				return &endpoint{ip: s, pod: pod}, nil
+			}
+		}
+		return &endpoint{ip: s}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownEndpoint, s)
+}
+
+// normalizeProtocol validates the protocol and port of a query.
+// The protocol defaults to TCP.
+func (q *Query) normalizeProtocol() (string, error) {
+	protocol := strings.ToUpper(q.Protocol)
+	if protocol == "" {
+		protocol = string(corev1.ProtocolTCP)
+	}
+	switch corev1.Protocol(protocol) {
+	case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidProtocol, q.Protocol)
+	}
+	if q.Port < 1 || q.Port > 65535 {
+		return "", fmt.Errorf("%w: %d", ErrInvalidPort, q.Port)
+	}
+	return protocol, nil
+}
@@ -0,0 +1,232 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const simulatedNodeName = "simulated-node"
+
+var kubeAllNamespaces = &ipsets.IPSetMetadata{Name: util.KubeAllNamespacesFlag, Type: ipsets.KeyLabelOfNamespace}
+
+// Simulator programs the manifests into an in-memory Linux kernel through the real dataplane and answers queries against it.
+type Simulator struct {
+	manifests *Manifests
+	kernel    *kernel
+	dp        *dataplane.DataPlane
+	// policyChains maps the ingress/egress chain of each policy to its policy key
+	policyChains map[string]string
+}
+
+// New translates and programs the manifests.
+func New(manifests *Manifests) (*Simulator, error) {
+	k := newKernel()
+	cfg := &dataplane.Config{
+		IPSetManagerCfg: &ipsets.IPSetManagerCfg{
+			IPSetMode:   ipsets.ApplyAllIPSets,
+			NetworkName: "azure",
+		},
+		PolicyManagerCfg: &policies.PolicyManagerCfg{
+			PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		},
+	}
+	dp, err := dataplane.NewDataPlane(simulatedNodeName, &common.IOShim{Exec: k}, cfg, make(chan struct{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to boot up simulated dataplane: %w", err)
+	}
+
+	s := &Simulator{
+		manifests:    manifests,
+		kernel:       k,
+		dp:           dp,
+		policyChains: make(map[string]string),
+	}
+	if err := s.program(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// program mirrors what the v2 namespace, pod, and NetworkPolicy controllers do for add events.
+func (s *Simulator) program() error {
+	knownNamespaces := make(map[string]struct{})
+	for _, nsObj := range s.manifests.Namespaces {
+		knownNamespaces[nsObj.Name] = struct{}{}
+		if err := s.addNamespace(nsObj.Name, nsObj.Labels); err != nil {
+			return err
+		}
+	}
+
+	for _, podObj := range s.manifests.Pods {
+		if _, ok := knownNamespaces[podObj.Namespace]; !ok {
+			knownNamespaces[podObj.Namespace] = struct{}{}
+			if err := s.addNamespace(podObj.Namespace, nil); err != nil {
+				return err
+			}
+		}
+		if err := s.addPod(podObj); err != nil {
+			return err
+		}
+	}
+
+	for _, netPolObj := range s.manifests.NetworkPolicies {
+		npmNetPol, err := translation.TranslatePolicy(netPolObj)
+		if err != nil {
+			return fmt.Errorf("failed to translate NetworkPolicy %s/%s: %w", netPolObj.Namespace, netPolObj.Name, err)
+		}
+		if err := s.dp.UpdatePolicy(npmNetPol); err != nil {
+			return fmt.Errorf("failed to program NetworkPolicy %s/%s: %w", netPolObj.Namespace, netPolObj.Name, err)
+		}
+		policyHash := util.Hash(npmNetPol.PolicyKey)
+		s.policyChains[util.IptablesAzureIngressPolicyChainPrefix+"-"+policyHash] = npmNetPol.PolicyKey
+		s.policyChains[util.IptablesAzureEgressPolicyChainPrefix+"-"+policyHash] = npmNetPol.PolicyKey
+	}
+	return nil
+}
+
+func (s *Simulator) addNamespace(name string, labels map[string]string) error {
+	namespaceSets := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(name, ipsets.Namespace)}
+	setsToAddNamespaceTo := []*ipsets.IPSetMetadata{kubeAllNamespaces}
+	for key, val := range labels {
+		setsToAddNamespaceTo = append(setsToAddNamespaceTo,
+			ipsets.NewIPSetMetadata(key, ipsets.KeyLabelOfNamespace),
+			ipsets.NewIPSetMetadata(util.GetIpSetFromLabelKV(key, val), ipsets.KeyValueLabelOfNamespace),
+		)
+	}
+	if err := s.dp.AddToLists(setsToAddNamespaceTo, namespaceSets); err != nil {
+		return fmt.Errorf("failed to add namespace %s: %w", name, err)
+	}
+	if err := s.dp.ApplyDataPlane(); err != nil {
+		return fmt.Errorf("failed to apply namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *Simulator) addPod(podObj *corev1.Pod) error {
+	// the pod controller ignores host network pods and pods which don't have an IP yet
+	if podObj.Spec.HostNetwork || !util.IsIPV4(podObj.Status.PodIP) {
+		return nil
+	}
+	podKey, _ := cache.MetaNamespaceKeyFunc(podObj)
+	podMetadata := dataplane.NewPodMetadata(podKey, podObj.Status.PodIP, simulatedNodeName)
+
+	setsToAddPodTo := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(podObj.Namespace, ipsets.Namespace)}
+	for key, val := range podObj.Labels {
+		setsToAddPodTo = append(setsToAddPodTo,
+			ipsets.NewIPSetMetadata(key, ipsets.KeyLabelOfPod),
+			ipsets.NewIPSetMetadata(util.GetIpSetFromLabelKV(key, val), ipsets.KeyValueLabelOfPod),
+		)
+	}
+	if err := s.dp.AddToSets(setsToAddPodTo, podMetadata); err != nil {
+		return fmt.Errorf("failed to add pod %s: %w", podKey, err)
+	}
+
+	for i := range podObj.Spec.Containers {
+		for _, port := range podObj.Spec.Containers[i].Ports {
+			if port.Name == "" {
+				continue
+			}
+			var protocol string
+			if port.Protocol != "" {
+				protocol = fmt.Sprintf("%s:", port.Protocol)
+			}
+			namedPortMetadata := dataplane.NewPodMetadata(podKey, fmt.Sprintf("%s,%s%d", podObj.Status.PodIP, protocol, port.ContainerPort), simulatedNodeName)
+			if err := s.dp.AddToSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, namedPortMetadata); err != nil {
+				return fmt.Errorf("failed to add named port %s of pod %s: %w", port.Name, podKey, err)
+			}
+		}
+	}
+
+	if err := s.dp.ApplyDataPlane(); err != nil {
+		return fmt.Errorf("failed to apply pod %s: %w", podKey, err)
+	}
+	return nil
+}
+
+// Query evaluates whether a new connection from q.Src to q.Dst on q.Protocol/q.Port is allowed.
+func (s *Simulator) Query(q *Query) (*Result, error) {
+	protocol, err := q.normalizeProtocol()
+	if err != nil {
+		return nil, err
+	}
+	src, err := resolveEndpoint(s.manifests.Pods, q.Src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %w", err)
+	}
+	dst, err := resolveEndpoint(s.manifests.Pods, q.Dst)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+
+	parser := &parse.IPTablesParser{IOShim: &common.IOShim{Exec: s.kernel}}
+	table, err := parser.Iptables(util.IptablesFilterTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read simulated iptables: %w", err)
+	}
+
+	p := &packet{
+		srcIP:    src.ip,
+		dstIP:    dst.ip,
+		protocol: protocol,
+		dstPort:  q.Port,
+	}
+	e := &evaluator{table: table, kernel: s.kernel, policyChains: s.policyChains}
+	allowed := e.evaluate(p)
+	return s.newResult(allowed, e.trace, src, dst), nil
+}
+
+// IPTablesSave returns the simulated filter table in iptables-save format.
+func (s *Simulator) IPTablesSave() string {
+	return string(s.kernel.iptablesSave())
+}
+
+func (s *Simulator) newResult(allowed bool, trace []*RuleHit, src, dst *endpoint) *Result {
+	result := &Result{
+		Allowed:     allowed,
+		PolicyRules: make([]*RuleHit, 0),
+		Trace:       trace,
+	}
+	for _, hit := range trace {
+		// rules within policy chains (not the jumps into them) are the translated ACLs
+		if _, ok := s.policyChains[hit.Chain]; ok {
+			result.PolicyRules = append(result.PolicyRules, hit)
+		}
+	}
+
+	switch {
+	case len(result.PolicyRules) == 0 && allowed:
+		result.Reason = fmt.Sprintf("no NetworkPolicy selects traffic from %s to %s", src, dst)
+	case len(result.PolicyRules) == 0:
+		result.Reason = fmt.Sprintf("traffic from %s to %s is dropped by NPM base rules", src, dst)
+	case allowed:
+		keys := policyKeys(result.PolicyRules)
+		result.Reason = fmt.Sprintf("traffic from %s to %s is allowed by %s", src, dst, strings.Join(keys, ", "))
+	default:
+		last := result.PolicyRules[len(result.PolicyRules)-1]
+		result.Reason = fmt.Sprintf("traffic from %s to %s is denied by %s rule %s", src, dst, last.PolicyKey, last.Comment)
+	}
+	return result
+}
+
+func policyKeys(hits []*RuleHit) []string {
+	keys := make([]string, 0, len(hits))
+	seen := make(map[string]struct{})
+	for _, hit := range hits {
+		if _, ok := seen[hit.PolicyKey]; ok {
+			continue
+		}
+		seen[hit.PolicyKey] = struct{}{}
+		keys = append(keys, hit.PolicyKey)
+	}
+	return keys
+}
@@ -0,0 +1,145 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSimulator(t *testing.T) *Simulator {
+	t.Helper()
+	manifests, err := LoadManifestFiles("testdata/cluster.yaml", "testdata/policies.yaml")
+	require.NoError(t, err)
+	s, err := New(manifests)
+	require.NoError(t, err)
+	return s
+}
+
+func TestQuery(t *testing.T) {
+	s := newTestSimulator(t)
+
+	tests := map[string]struct {
+		query            *Query
+		allowed          bool
+		expectedPolicies []string
+	}{
+		"unselected pods can talk": {
+			query:   &Query{Src: "monitoring/prometheus", Dst: "default/frontend", Port: 80},
+			allowed: true,
+		},
+		"allowed by pod selector and named port": {
+			query:            &Query{Src: "frontend", Dst: "backend", Port: 8080},
+			allowed:          true,
+			expectedPolicies: []string{"default/backend-allow-frontend", "default/frontend-egress"},
+		},
+		"named port doesn't match other ports": {
+			query:   &Query{Src: "frontend", Dst: "backend", Port: 8081},
+			allowed: false,
+		},
+		"named port doesn't match other protocols": {
+			query:   &Query{Src: "frontend", Dst: "backend", Protocol: "udp", Port: 8080},
+			allowed: false,
+		},
+		"ingress from unselected pod denied": {
+			query:   &Query{Src: "monitoring/prometheus", Dst: "backend", Port: 8080},
+			allowed: false,
+		},
+		"deny all ingress": {
+			query:   &Query{Src: "backend", Dst: "db", Port: 5432},
+			allowed: false,
+		},
+		"deny all egress": {
+			query:   &Query{Src: "db", Dst: "monitoring/prometheus", Port: 9090},
+			allowed: false,
+		},
+		"egress by namespace selector and port": {
+			query:   &Query{Src: "frontend", Dst: "monitoring/prometheus", Port: 9090},
+			allowed: true,
+		},
+		"egress by namespace selector wrong port": {
+			query:   &Query{Src: "frontend", Dst: "monitoring/prometheus", Port: 9091},
+			allowed: false,
+		},
+		"egress to ipBlock": {
+			query:   &Query{Src: "frontend", Dst: "20.2.3.4", Port: 443},
+			allowed: true,
+		},
+		"egress to ipBlock except": {
+			query:   &Query{Src: "frontend", Dst: "20.1.3.4", Port: 443},
+			allowed: false,
+		},
+		"egress outside ipBlock": {
+			query:   &Query{Src: "frontend", Dst: "8.8.8.8", Port: 53, Protocol: "UDP"},
+			allowed: false,
+		},
+		"pod IP as endpoint": {
+			query:   &Query{Src: "10.240.0.10", Dst: "10.240.0.20", Port: 8080},
+			allowed: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			result, err := s.Query(tt.query)
+			require.NoError(t, err)
+			require.Equal(t, tt.allowed, result.Allowed, result.PrettyString())
+			if tt.expectedPolicies != nil {
+				require.Equal(t, tt.expectedPolicies, policyKeys(result.PolicyRules), result.PrettyString())
+			}
+			if !tt.allowed {
+				require.NotEmpty(t, result.PolicyRules, result.PrettyString())
+			}
+		})
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	s := newTestSimulator(t)
+
+	tests := map[string]struct {
+		query *Query
+		err   error
+	}{
+		"unknown pod":      {query: &Query{Src: "nope", Dst: "backend", Port: 80}, err: ErrUnknownEndpoint},
+		"invalid protocol": {query: &Query{Src: "frontend", Dst: "backend", Protocol: "icmp", Port: 80}, err: ErrInvalidProtocol},
+		"invalid port":     {query: &Query{Src: "frontend", Dst: "backend"}, err: ErrInvalidPort},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Query(tt.query)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestIPTablesSave(t *testing.T) {
+	s := newTestSimulator(t)
+	rules := s.IPTablesSave()
+	require.True(t, strings.HasPrefix(rules, "*filter\n"), rules)
+	require.Contains(t, rules, ":AZURE-NPM-INGRESS-ALLOW-MARK")
+}
+
+func TestLoadManifestsList(t *testing.T) {
+	manifests, err := LoadManifests(strings.NewReader(`
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: a
+  status:
+    podIP: 10.0.0.1
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: ignored
+`))
+	require.NoError(t, err)
+	require.Len(t, manifests.Pods, 1)
+	require.Equal(t, "default", manifests.Pods[0].Namespace)
+}
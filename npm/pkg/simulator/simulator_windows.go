@@ -0,0 +1,19 @@
+package simulator
+
+// Simulator is not supported on Windows, where policies are programmed as HNS ACLs instead of iptables rules.
+type Simulator struct{}
+
+// New always returns ErrUnsupportedPlatform on Windows.
+func New(_ *Manifests) (*Simulator, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// Query always returns ErrUnsupportedPlatform on Windows.
+func (s *Simulator) Query(_ *Query) (*Result, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// IPTablesSave returns an empty string on Windows.
+func (s *Simulator) IPTablesSave() string {
+	return ""
+}
@@ -81,6 +81,7 @@ const (
 	IptablesListFlag        string = "-L"
 	IptablesNumericFlag     string = "-n"
 	IptablesLineNumbersFlag string = "--line-numbers"
+	IptablesCountersFlag    string = "-c"
 
 	IptablesKubeServicesChain          string = "KUBE-SERVICES"
 	IptablesForwardChain               string = "FORWARD"
@@ -103,6 +104,11 @@ const (
 	// NPM v2 Chains
 	IptablesAzureIngressPolicyChainPrefix string = "AZURE-NPM-INGRESS"
 	IptablesAzureEgressPolicyChainPrefix  string = "AZURE-NPM-EGRESS"
+	// AdminNetworkPolicies are evaluated before NetworkPolicies, and BaselineAdminNetworkPolicies after
+	IptablesAzureAdminIngressChain         string = "AZURE-NPM-ANP-INGRESS"
+	IptablesAzureAdminEgressChain          string = "AZURE-NPM-ANP-EGRESS"
+	IptablesAzureBaselineAdminIngressChain string = "AZURE-NPM-BANP-INGRESS"
+	IptablesAzureBaselineAdminEgressChain  string = "AZURE-NPM-BANP-EGRESS"
 
 	// Below chain exists only in NPM before v1.2.6
 	IptablesAzureTargetSetsChain string = "AZURE-NPM-TARGET-SETS"
@@ -138,6 +144,21 @@ const (
 	// IptablesAzureEgressMarkHex is for checking the absolute value of the mark
 	IptablesAzureEgressMarkHex string = "0x1000"
 	IptablesAzureAcceptMarkHex string = "0x3000"
+
+	// NFLOG rules for logging packets dropped by NPM v2 policies, or which policies in audit mode would drop
+	IptablesNFLogTarget           string = "NFLOG"
+	IptablesNFLogGroupFlag        string = "--nflog-group"
+	IptablesNFLogPrefixFlag       string = "--nflog-prefix"
+	IptablesLimitModuleFlag       string = "limit"
+	IptablesLimitFlag             string = "--limit"
+	IptablesLimitBurstFlag        string = "--limit-burst"
+	IptablesNFLogGroup            uint16 = 100
+	IptablesDropLogLimit          string = "10/second"
+	IptablesDropLogLimitBurst     string = "20"
+	IptablesDropLogIngressPrefix  string = "NPM-DROP-IN-"
+	IptablesDropLogEgressPrefix   string = "NPM-DROP-OUT-"
+	IptablesAuditLogIngressPrefix string = "NPM-AUDIT-IN-"
+	IptablesAuditLogEgressPrefix  string = "NPM-AUDIT-OUT-"
 )
 
 // ipset related constants.
@@ -0,0 +1,371 @@
+package ovsctl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOVSDB is an in-process ovsdb-server which also applies the configuration like ovs-vswitchd:
+// it garbage collects unreferenced rows, assigns ofports and advances cur_cfg after each transaction.
+type fakeOVSDB struct {
+	sync.Mutex
+	tables     map[string]map[string]map[string]interface{}
+	nextUUID   int
+	nextOFPort int
+}
+
+func newFakeOVSDB(t *testing.T, socket string) *fakeOVSDB {
+	db := &fakeOVSDB{
+		tables: map[string]map[string]map[string]interface{}{
+			"Open_vSwitch": {"root": {
+				"_uuid": uuidRef("root"), "bridges": ovsdbSet(), "next_cfg": float64(0), "cur_cfg": float64(0),
+			}},
+			"Bridge":    {},
+			"Port":      {},
+			"Interface": {},
+		},
+		nextOFPort: 1,
+	}
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go db.serve(conn)
+		}
+	}()
+	return db
+}
+
+func (db *fakeOVSDB) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	// check that the client answers echo requests while it waits for a reply
+	if err := enc.Encode(jsonrpcMessage{Method: "echo", Params: json.RawMessage(`[]`), ID: "echo"}); err != nil {
+		return
+	}
+	for {
+		var msg jsonrpcMessage
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if msg.Method != "transact" {
+			continue
+		}
+		var params []json.RawMessage
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return
+		}
+		ops := make([]map[string]interface{}, 0, len(params)-1)
+		for _, p := range params[1:] {
+			var op map[string]interface{}
+			if err := json.Unmarshal(p, &op); err != nil {
+				return
+			}
+			ops = append(ops, op)
+		}
+		result, _ := json.Marshal(db.transact(ops))
+		if err := enc.Encode(jsonrpcMessage{Result: result, ID: msg.ID}); err != nil {
+			return
+		}
+	}
+}
+
+func (db *fakeOVSDB) transact(ops []map[string]interface{}) []map[string]interface{} {
+	db.Lock()
+	defer db.Unlock()
+
+	named := map[string]string{}
+	results := make([]map[string]interface{}, 0, len(ops))
+	for _, op := range ops {
+		table := db.tables[op["table"].(string)]
+		if table == nil {
+			return append(results, map[string]interface{}{"error": "unknown table", "details": op["table"]})
+		}
+		switch op["op"] {
+		case "insert":
+			db.nextUUID++
+			uuid := fmt.Sprintf("uuid-%d", db.nextUUID)
+			row := map[string]interface{}{"_uuid": uuidRef(uuid)}
+			for col, v := range op["row"].(map[string]interface{}) {
+				row[col] = resolveNamed(v, named)
+			}
+			table[uuid] = row
+			if name, ok := op["uuid-name"].(string); ok {
+				named[name] = uuid
+			}
+			results = append(results, map[string]interface{}{"uuid": uuidRef(uuid)})
+		case "select":
+			rows := []map[string]interface{}{}
+			for _, row := range db.matching(table, op["where"]) {
+				selected := map[string]interface{}{}
+				for _, col := range op["columns"].([]interface{}) {
+					selected[col.(string)] = row[col.(string)]
+				}
+				rows = append(rows, selected)
+			}
+			results = append(results, map[string]interface{}{"rows": rows})
+		case "mutate":
+			rows := db.matching(table, op["where"])
+			for _, row := range rows {
+				for _, m := range op["mutations"].([]interface{}) {
+					mutation := m.([]interface{})
+					col, value := mutation[0].(string), resolveNamed(mutation[2], named)
+					switch mutation[1] {
+					case "+=":
+						row[col] = row[col].(float64) + value.(float64)
+					case "insert":
+						row[col] = ovsdbSet(append(ovsdbSetValues(row[col]), ovsdbSetValues(value)...)...)
+					case "delete":
+						kept := []interface{}{}
+						for _, member := range ovsdbSetValues(row[col]) {
+							if !containsValue(ovsdbSetValues(value), member) {
+								kept = append(kept, member)
+							}
+						}
+						row[col] = ovsdbSet(kept...)
+					}
+				}
+			}
+			results = append(results, map[string]interface{}{"count": len(rows)})
+		default:
+			return append(results, map[string]interface{}{"error": "not supported", "details": op["op"]})
+		}
+	}
+
+	db.garbageCollect()
+	root := db.tables["Open_vSwitch"]["root"]
+	root["cur_cfg"] = root["next_cfg"]
+	for _, iface := range db.tables["Interface"] {
+		if _, ok := iface["ofport"]; !ok {
+			iface["ofport"] = float64(db.nextOFPort)
+			db.nextOFPort++
+		}
+	}
+	return results
+}
+
+func (db *fakeOVSDB) matching(table map[string]map[string]interface{}, where interface{}) []map[string]interface{} {
+	rows := []map[string]interface{}{}
+	for _, row := range table {
+		matches := true
+		for _, c := range where.([]interface{}) {
+			cond := c.([]interface{})
+			if cond[1] != "==" || !reflect.DeepEqual(row[cond[0].(string)], cond[2]) {
+				matches = false
+			}
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// garbageCollect removes the rows of the non-root tables which are not referenced.
+func (db *fakeOVSDB) garbageCollect() {
+	refs := []struct{ parent, column, child string }{
+		{"Open_vSwitch", "bridges", "Bridge"},
+		{"Bridge", "ports", "Port"},
+		{"Port", "interfaces", "Interface"},
+	}
+	for _, ref := range refs {
+		referenced := map[string]bool{}
+		for _, row := range db.tables[ref.parent] {
+			for _, member := range ovsdbSetValues(row[ref.column]) {
+				referenced[ovsdbUUID(member)] = true
+			}
+		}
+		for uuid := range db.tables[ref.child] {
+			if !referenced[uuid] {
+				delete(db.tables[ref.child], uuid)
+			}
+		}
+	}
+}
+
+func (db *fakeOVSDB) rowByName(table, name string) map[string]interface{} {
+	db.Lock()
+	defer db.Unlock()
+	for _, row := range db.tables[table] {
+		if row["name"] == name {
+			return row
+		}
+	}
+	return nil
+}
+
+func resolveNamed(v interface{}, named map[string]string) interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+	if len(list) == 2 && list[0] == "named-uuid" {
+		return uuidRef(named[list[1].(string)])
+	}
+	resolved := make([]interface{}, len(list))
+	for i := range list {
+		resolved[i] = resolveNamed(list[i], named)
+	}
+	return resolved
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, value := range values {
+		if reflect.DeepEqual(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeFlow is a flow in the table of a fakeSwitch.
+type fakeFlow struct {
+	table        uint8
+	priority     uint16
+	cookie       uint64
+	match        map[uint8][]byte
+	instructions []byte
+}
+
+// fakeSwitch is an in-process OpenFlow 1.3 switch which keeps the flows added through a bridge management socket.
+type fakeSwitch struct {
+	sync.Mutex
+	flows []fakeFlow
+	// errorCode makes the switch reply to flow mods with an error, if set.
+	errorCode uint16
+}
+
+func newFakeSwitch(t *testing.T, runDir, bridgeName string) *fakeSwitch {
+	sw := &fakeSwitch{}
+	l, err := net.Listen("unix", filepath.Join(runDir, bridgeName+".mgmt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go sw.serve(conn)
+		}
+	}()
+	return sw
+}
+
+func (sw *fakeSwitch) serve(conn net.Conn) {
+	defer conn.Close()
+	// OVS sends the highest version it supports
+	if _, err := conn.Write([]byte{0x06, ofptHello, 0, ofHeaderLen, 0, 0, 0, 0}); err != nil {
+		return
+	}
+	for {
+		header := make([]byte, ofHeaderLen)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, int(binary.BigEndian.Uint16(header[2:]))-ofHeaderLen)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		switch header[1] {
+		case ofptFlowMod:
+			if sw.errorCode != 0 {
+				reply := append([]byte{ofVersion13, ofptError, 0, 12}, header[4:8]...)
+				reply = append(reply, be16(4)...) // OFPET_BAD_MATCH
+				reply = append(reply, be16(sw.errorCode)...)
+				if _, err := conn.Write(reply); err != nil {
+					return
+				}
+				continue
+			}
+			sw.flowMod(body)
+		case ofptBarrierRequest:
+			reply := append([]byte{ofVersion13, ofptBarrierReply, 0, ofHeaderLen}, header[4:8]...)
+			if _, err := conn.Write(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (sw *fakeSwitch) flowMod(body []byte) {
+	sw.Lock()
+	defer sw.Unlock()
+
+	cookie := binary.BigEndian.Uint64(body)
+	cookieMask := binary.BigEndian.Uint64(body[8:])
+	table, command := body[16], body[17]
+	priority := binary.BigEndian.Uint16(body[22:])
+	matchLen := int(binary.BigEndian.Uint16(body[42:]))
+	match := decodeOXM(body[44 : 40+matchLen])
+	instructions := body[40+pad8(matchLen):]
+
+	switch command {
+	case ofpfcAdd:
+		f := fakeFlow{table: table, priority: priority, cookie: cookie, match: match, instructions: instructions}
+		for i := range sw.flows {
+			if sw.flows[i].table == table && sw.flows[i].priority == priority && reflect.DeepEqual(sw.flows[i].match, match) {
+				sw.flows[i] = f
+				return
+			}
+		}
+		sw.flows = append(sw.flows, f)
+	case ofpfcDelete:
+		kept := sw.flows[:0]
+		for _, f := range sw.flows {
+			deleted := (table == ofpttAll || table == f.table) && f.cookie&cookieMask == cookie&cookieMask
+			for field, value := range match {
+				if !bytes.Equal(f.match[field], value) {
+					deleted = false
+				}
+			}
+			if !deleted {
+				kept = append(kept, f)
+			}
+		}
+		sw.flows = kept
+	}
+}
+
+func (sw *fakeSwitch) getFlows() []fakeFlow {
+	sw.Lock()
+	defer sw.Unlock()
+	return append([]fakeFlow(nil), sw.flows...)
+}
+
+func decodeOXM(b []byte) map[uint8][]byte {
+	fields := map[uint8][]byte{}
+	for len(b) >= 4 {
+		length := int(b[3])
+		fields[b[2]>>1] = b[4 : 4+length]
+		b = b[4+length:]
+	}
+	return fields
+}
+
+func newTestNativeOvsctl(t *testing.T) (NativeOvsctl, *fakeOVSDB) {
+	dir := t.TempDir()
+	o := NativeOvsctl{
+		dbSocket: filepath.Join(dir, "db.sock"),
+		runDir:   dir,
+		timeout:  time.Second,
+	}
+	return o, newFakeOVSDB(t, o.dbSocket)
+}
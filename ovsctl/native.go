@@ -0,0 +1,568 @@
+package ovsctl
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Directory of the management sockets of the bridges.
+	defaultOVSRunDir = "/var/run/openvswitch"
+	defaultTimeout   = 10 * time.Second
+)
+
+// Flow cookies are 0xAC, the kind of flow, the vlan ID and the IPv4 address or ofport of the endpoint.
+// Flows of an endpoint are deleted by cookie, so they can be told apart from flows of other endpoints
+// with overlapping matches.
+const cookieMarker = uint64(0xac) << 56
+
+const (
+	cookieVMIPAccept = iota + 1
+	cookieArpSnat
+	cookieArpDnat
+	cookieFakeArpReply
+	cookieArpReply
+	cookieIPSnat
+	cookieMacDnat
+)
+
+var (
+	errOVSNotFound   = errors.New("not found")
+	errInvalidMacHex = errors.New("invalid MAC address")
+)
+
+func flowCookie(kind int, vlanID int, key uint32) uint64 {
+	return cookieMarker | uint64(kind)<<48 | uint64(uint16(vlanID))<<32 | uint64(key)
+}
+
+func ipKey(ip net.IP) uint32 {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0
+	}
+	return uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
+}
+
+// NativeOvsctl configures OVS through the OVSDB protocol for bridges and ports, and OpenFlow 1.3 for flows,
+// instead of running ovs-vsctl and ovs-ofctl.
+type NativeOvsctl struct {
+	dbSocket string
+	runDir   string
+	timeout  time.Duration
+}
+
+var _ OvsInterface = NativeOvsctl{}
+
+func NewNativeOvsctl() NativeOvsctl {
+	return NativeOvsctl{
+		dbSocket: defaultOVSDBSocket,
+		runDir:   defaultOVSRunDir,
+		timeout:  defaultTimeout,
+	}
+}
+
+func (o NativeOvsctl) withDB(f func(db *ovsdbClient) error) error {
+	db, err := dialOVSDB(o.dbSocket, o.timeout)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	defer db.close()
+	if err := f(db); err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	return nil
+}
+
+// sendFlows adds the flows and runs the deletes on the bridge, deletes first, and waits until the switch has applied them.
+func (o NativeOvsctl) sendFlows(bridgeName string, deletes []flowDelete, flows []flow) error {
+	conn, err := dialOpenFlow(filepath.Join(o.runDir, bridgeName+".mgmt"), o.timeout)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	defer conn.close()
+
+	msgs := make([][]byte, 0, len(deletes)+len(flows))
+	for i := range deletes {
+		msgs = append(msgs, deletes[i].encode())
+	}
+	for i := range flows {
+		msgs = append(msgs, flows[i].encode())
+	}
+	if err := conn.send(msgs...); err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	return nil
+}
+
+// CreateOVSBridge creates the bridge with OpenFlow 1.0 and 1.3 enabled, unless it exists.
+func (o NativeOvsctl) CreateOVSBridge(bridgeName string) error {
+	log.Printf("[ovs] Creating OVS Bridge %v", bridgeName)
+
+	err := o.withDB(func(db *ovsdbClient) error {
+		uuid, err := db.selectUUID("Bridge", bridgeName)
+		if err != nil || uuid != "" {
+			return err
+		}
+		_, err = db.transactAndWait(
+			ovsdbOp{
+				"op":        "insert",
+				"table":     "Interface",
+				"row":       map[string]interface{}{"name": bridgeName, "type": "internal"},
+				"uuid-name": "iface",
+			},
+			ovsdbOp{
+				"op":        "insert",
+				"table":     "Port",
+				"row":       map[string]interface{}{"name": bridgeName, "interfaces": namedUUID("iface")},
+				"uuid-name": "port",
+			},
+			ovsdbOp{
+				"op":    "insert",
+				"table": "Bridge",
+				"row": map[string]interface{}{
+					"name":      bridgeName,
+					"ports":     namedUUID("port"),
+					"protocols": ovsdbSet("OpenFlow10", "OpenFlow13"),
+				},
+				"uuid-name": "bridge",
+			},
+			ovsdbOp{
+				"op":        "mutate",
+				"table":     ovsdbDatabase,
+				"where":     []interface{}{},
+				"mutations": []interface{}{[]interface{}{"bridges", "insert", namedUUID("bridge")}},
+			},
+		)
+		return err
+	})
+	if err != nil {
+		log.Printf("[ovs] Error while creating OVS bridge %v", err)
+	}
+	return err
+}
+
+// DeleteOVSBridge deletes the bridge with its ports, if it exists.
+func (o NativeOvsctl) DeleteOVSBridge(bridgeName string) error {
+	log.Printf("[ovs] Deleting OVS Bridge %v", bridgeName)
+
+	err := o.withDB(func(db *ovsdbClient) error {
+		uuid, err := db.selectUUID("Bridge", bridgeName)
+		if err != nil || uuid == "" {
+			return err
+		}
+		// ports and interfaces are garbage collected with the bridge
+		_, err = db.transactAndWait(ovsdbOp{
+			"op":        "mutate",
+			"table":     ovsdbDatabase,
+			"where":     []interface{}{},
+			"mutations": []interface{}{[]interface{}{"bridges", "delete", uuidRef(uuid)}},
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("[ovs] Error while deleting OVS bridge %v", err)
+	}
+	return err
+}
+
+// AddPortOnOVSBridge adds the interface to the bridge, unless it already is a port of the bridge.
+func (o NativeOvsctl) AddPortOnOVSBridge(hostIfName, bridgeName string, _ int) error {
+	err := o.withDB(func(db *ovsdbClient) error {
+		results, err := db.transact(
+			selectByName("Port", hostIfName, "_uuid"),
+			selectByName("Bridge", bridgeName, "ports"),
+		)
+		if err != nil {
+			return err
+		}
+		if len(results[1].Rows) == 0 {
+			return fmt.Errorf("bridge %s %w", bridgeName, errOVSNotFound)
+		}
+		if len(results[0].Rows) > 0 {
+			portUUID := ovsdbUUID(results[0].Rows[0]["_uuid"])
+			for _, p := range ovsdbSetValues(results[1].Rows[0]["ports"]) {
+				if ovsdbUUID(p) == portUUID {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: port %s is on another bridge", ErrOVSDBTransaction, hostIfName)
+		}
+
+		_, err = db.transactAndWait(
+			ovsdbOp{
+				"op":        "insert",
+				"table":     "Interface",
+				"row":       map[string]interface{}{"name": hostIfName},
+				"uuid-name": "iface",
+			},
+			ovsdbOp{
+				"op":        "insert",
+				"table":     "Port",
+				"row":       map[string]interface{}{"name": hostIfName, "interfaces": namedUUID("iface")},
+				"uuid-name": "port",
+			},
+			ovsdbOp{
+				"op":        "mutate",
+				"table":     "Bridge",
+				"where":     []interface{}{[]interface{}{"name", "==", bridgeName}},
+				"mutations": []interface{}{[]interface{}{"ports", "insert", namedUUID("port")}},
+			},
+		)
+		return err
+	})
+	if err != nil {
+		log.Printf("[ovs] Error while setting OVS as master to primary interface %v", err)
+	}
+	return err
+}
+
+// GetOVSPortNumber returns the ofport of the interface.
+func (o NativeOvsctl) GetOVSPortNumber(interfaceName string) (string, error) {
+	var ofport string
+	err := o.withDB(func(db *ovsdbClient) error {
+		results, err := db.transact(selectByName("Interface", interfaceName, "ofport"))
+		if err != nil {
+			return err
+		}
+		if len(results[0].Rows) == 0 {
+			return fmt.Errorf("interface %s %w", interfaceName, errOVSNotFound)
+		}
+		// ofport is an empty set until ovs-vswitchd has added the interface
+		values := ovsdbSetValues(results[0].Rows[0]["ofport"])
+		if len(values) == 0 {
+			return fmt.Errorf("ofport of interface %s %w", interfaceName, errOVSNotFound)
+		}
+		port, ok := values[0].(float64)
+		if !ok || port < 0 {
+			return fmt.Errorf("%w: invalid ofport %v of interface %s", ErrOVSDBTransaction, values[0], interfaceName)
+		}
+		ofport = strconv.Itoa(int(port))
+		return nil
+	})
+	if err != nil {
+		log.Printf("[ovs] Get ofport failed with error %v", err)
+		return "", err
+	}
+	return ofport, nil
+}
+
+func (o NativeOvsctl) AddVMIpAcceptRule(bridgeName, primaryIP, mac string) error {
+	ip := net.ParseIP(primaryIP)
+	hwAddr, err := net.ParseMAC(mac)
+	if ip == nil || err != nil {
+		return newErrorOvsctl(fmt.Sprintf("invalid IP %q or MAC %q", primaryIP, mac))
+	}
+
+	err = o.sendFlows(bridgeName, nil, []flow{{
+		priority: high,
+		cookie:   flowCookie(cookieVMIPAccept, 0, 0),
+		match:    [][]byte{matchEthType(ethTypeIPv4), matchIPv4Dst(ip), matchEthDst(hwAddr)},
+		actions:  [][]byte{actionOutput(ofppNormal)},
+	}})
+	if err != nil {
+		log.Printf("[ovs] Adding SNAT rule failed with error %v", err)
+	}
+	return err
+}
+
+func (o NativeOvsctl) AddArpSnatRule(bridgeName, mac, macHex, ofport string) error {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	sha, err := parseMacHex(macHex)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	port, err := parseOFPort(ofport)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+
+	err = o.sendFlows(bridgeName, nil, []flow{{
+		table:    1,
+		priority: low,
+		cookie:   flowCookie(cookieArpSnat, 0, 0),
+		match:    [][]byte{matchEthType(ethTypeARP), matchARPOp(1)},
+		actions: [][]byte{
+			actionSetField(oxm(oxmEthSrc, hwAddr)),
+			actionSetField(oxm(oxmARPSHA, sha)),
+			actionOutput(port),
+		},
+	}})
+	if err != nil {
+		log.Printf("[ovs] Adding ARP SNAT rule failed with error %v", err)
+	}
+	return err
+}
+
+// AddIPSnatRule changes the src mac to the VM mac for packets coming from the container host veth port,
+// and drops other IP packets from the port to prevent IP spoofing.
+func (o NativeOvsctl) AddIPSnatRule(bridgeName string, ip net.IP, vlanID int, port, mac, outport string) error {
+	inPort, err := parseOFPort(port)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	if outport == "" {
+		outport = "normal"
+	}
+	outPort, err := parseOFPort(outport)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+
+	// the flow only matches untagged packets
+	actions := [][]byte{actionSetField(oxm(oxmEthSrc, hwAddr))}
+	if vlanID != 0 {
+		actions = append(actions, actionSetVlan(vlanID)...)
+	}
+	actions = append(actions, actionOutput(outPort))
+
+	cookie := flowCookie(cookieIPSnat, 0, inPort)
+	err = o.sendFlows(bridgeName, nil, []flow{
+		{
+			priority: high,
+			cookie:   cookie,
+			match:    [][]byte{matchInPort(inPort), matchEthType(ethTypeIPv4), matchVlanVID(ofpvidNone), matchIPv4Src(ip)},
+			actions:  actions,
+		},
+		{
+			priority: low,
+			cookie:   cookie,
+			match:    [][]byte{matchInPort(inPort), matchEthType(ethTypeIPv4)},
+		},
+	})
+	if err != nil {
+		log.Printf("[ovs] Adding IP SNAT rule failed with error %v", err)
+	}
+	return err
+}
+
+// AddArpDnatRule forwards ARP replies to container interfaces.
+func (o NativeOvsctl) AddArpDnatRule(bridgeName, port, mac string) error {
+	inPort, err := parseOFPort(port)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	tha, err := parseMacHex(mac)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+
+	err = o.sendFlows(bridgeName, nil, []flow{{
+		priority: ofpDefaultPriority,
+		cookie:   flowCookie(cookieArpDnat, 0, inPort),
+		match:    [][]byte{matchInPort(inPort), matchEthType(ethTypeARP), matchARPOp(2)},
+		actions: [][]byte{
+			actionSetField(oxm(oxmEthDst, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})),
+			actionSetField(oxm(oxmARPTHA, tha)),
+			actionOutput(ofppNormal),
+		},
+	}})
+	if err != nil {
+		log.Printf("[ovs] Adding DNAT rule failed with error %v", err)
+	}
+	return err
+}
+
+// AddFakeArpReply replies to all ARP requests with defaultMacForArpResponse.
+func (o NativeOvsctl) AddFakeArpReply(bridgeName string, ip net.IP) error {
+	defaultMac, _ := net.ParseMAC(defaultMacForArpResponse)
+
+	log.Printf("[ovs] Adding ARP reply rule for IP address %v ", ip.String())
+	err := o.sendFlows(bridgeName, nil, []flow{{
+		priority: high,
+		cookie:   flowCookie(cookieFakeArpReply, 0, ipKey(ip)),
+		match:    [][]byte{matchEthType(ethTypeARP), matchARPOp(1)},
+		actions: [][]byte{
+			actionSetField(oxm(oxmARPOp, be16(2))),
+			actionMove(nxmOfEthSrc, nxmOfEthDst, 48),
+			actionSetField(oxm(oxmEthSrc, defaultMac)),
+			actionMove(nxmNxARPSHA, nxmNxARPTHA, 48),
+			actionMove(nxmOfARPTPA, nxmOfARPSPA, 32),
+			actionSetField(oxm(oxmARPSHA, defaultMac)),
+			actionSetField(oxm(oxmARPTPA, ip.To4())),
+			actionOutput(ofppInPort),
+		},
+	}})
+	if err != nil {
+		log.Printf("[ovs] Adding ARP reply rule failed with error %v", err)
+	}
+	return err
+}
+
+// AddArpReplyRule tags ARP requests from the container port with its vlan and replies to them in table 1.
+func (o NativeOvsctl) AddArpReplyRule(bridgeName, port string, ip net.IP, mac string, vlanid int, _ string) error {
+	inPort, err := parseOFPort(port)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+
+	log.Printf("[ovs] Adding ARP reply rule to add vlan %v and forward packet to table 1 for port %v", vlanid, port)
+	log.Printf("[ovs] Adding ARP reply rule for IP address %v and vlanid %v.", ip, vlanid)
+	cookie := flowCookie(cookieArpReply, vlanid, ipKey(ip))
+	replyTable := uint8(1)
+	err = o.sendFlows(bridgeName, nil, []flow{
+		{
+			priority:  ofpDefaultPriority,
+			cookie:    cookie,
+			match:     [][]byte{matchInPort(inPort), matchEthType(ethTypeARP), matchARPOp(1)},
+			actions:   actionSetVlan(vlanid),
+			gotoTable: &replyTable,
+		},
+		{
+			table:    replyTable,
+			priority: high,
+			cookie:   cookie,
+			match:    [][]byte{matchEthType(ethTypeARP), matchVlan(vlanid), matchARPOp(1), matchARPTPA(ip)},
+			actions: [][]byte{
+				actionSetField(oxm(oxmARPOp, be16(2))),
+				actionMove(nxmOfEthSrc, nxmOfEthDst, 48),
+				actionSetField(oxm(oxmEthSrc, hwAddr)),
+				actionMove(nxmNxARPSHA, nxmNxARPTHA, 48),
+				actionMove(nxmOfARPSPA, nxmOfARPTPA, 32),
+				actionSetField(oxm(oxmARPSHA, hwAddr)),
+				actionSetField(oxm(oxmARPSPA, ip.To4())),
+				actionPopVlan(),
+				actionOutput(ofppInPort),
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("[ovs] Adding ARP reply rule failed with error %v", err)
+	}
+	return err
+}
+
+// AddMacDnatRule changes the destination mac to the container mac based on the ip and vlan,
+// and forwards the packet to the container host veth port. Without a vlan, it only matches untagged packets.
+func (o NativeOvsctl) AddMacDnatRule(bridgeName, port string, ip net.IP, mac string, vlanid int, containerPort string) error {
+	inPort, err := parseOFPort(port)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	outPort, err := parseOFPort(containerPort)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return newErrorOvsctl(err.Error())
+	}
+
+	f := flow{
+		priority: ofpDefaultPriority,
+		cookie:   flowCookie(cookieMacDnat, vlanid, ipKey(ip)),
+		match:    [][]byte{matchInPort(inPort), matchEthType(ethTypeIPv4), matchIPv4Dst(ip)},
+		actions:  [][]byte{actionSetField(oxm(oxmEthDst, hwAddr))},
+	}
+	if vlanid != 0 {
+		f.match = append(f.match, matchVlan(vlanid))
+		f.actions = append(f.actions, actionPopVlan())
+	} else {
+		f.match = append(f.match, matchVlanVID(ofpvidNone))
+	}
+	f.actions = append(f.actions, actionOutput(outPort))
+
+	if err = o.sendFlows(bridgeName, nil, []flow{f}); err != nil {
+		log.Printf("[ovs] Adding MAC DNAT rule failed with error %v", err)
+	}
+	return err
+}
+
+// legacyDelete deletes the flows added by ovs-ofctl, which have no cookie.
+func legacyDelete(table uint8, match ...[]byte) flowDelete {
+	return flowDelete{table: table, cookie: 0, cookieMask: ^uint64(0), match: match}
+}
+
+func cookieDelete(cookie uint64) flowDelete {
+	return flowDelete{table: ofpttAll, cookie: cookie, cookieMask: ^uint64(0)}
+}
+
+func (o NativeOvsctl) DeleteArpReplyRule(bridgeName, port string, ip net.IP, vlanid int) {
+	deletes := []flowDelete{
+		cookieDelete(flowCookie(cookieArpReply, vlanid, ipKey(ip))),
+		legacyDelete(1, matchEthType(ethTypeARP), matchVlan(vlanid), matchARPOp(1), matchARPTPA(ip)),
+	}
+	if inPort, err := parseOFPort(port); err == nil {
+		deletes = append(deletes, legacyDelete(ofpttAll, matchInPort(inPort), matchEthType(ethTypeARP), matchARPOp(1)))
+	}
+	if err := o.sendFlows(bridgeName, deletes, nil); err != nil {
+		log.Printf("[net] Deleting ARP reply rule failed with error %v", err)
+	}
+}
+
+func (o NativeOvsctl) DeleteIPSnatRule(bridgeName, port string) {
+	inPort, err := parseOFPort(port)
+	if err != nil {
+		log.Printf("Error while deleting ovs rule of port %s error %v", port, err)
+		return
+	}
+	deletes := []flowDelete{
+		cookieDelete(flowCookie(cookieIPSnat, 0, inPort)),
+		legacyDelete(ofpttAll, matchInPort(inPort), matchEthType(ethTypeIPv4)),
+	}
+	if err := o.sendFlows(bridgeName, deletes, nil); err != nil {
+		log.Printf("Error while deleting ovs rule of port %s error %v", port, err)
+	}
+}
+
+func (o NativeOvsctl) DeleteMacDnatRule(bridgeName, port string, ip net.IP, vlanid int) {
+	deletes := []flowDelete{cookieDelete(flowCookie(cookieMacDnat, vlanid, ipKey(ip)))}
+	if inPort, err := parseOFPort(port); err == nil {
+		match := [][]byte{matchInPort(inPort), matchEthType(ethTypeIPv4), matchIPv4Dst(ip)}
+		if vlanid != 0 {
+			match = append(match, matchVlan(vlanid))
+		}
+		deletes = append(deletes, legacyDelete(ofpttAll, match...))
+	}
+	if err := o.sendFlows(bridgeName, deletes, nil); err != nil {
+		log.Printf("[net] Deleting MAC DNAT rule failed with error %v", err)
+	}
+}
+
+// DeletePortFromOVS removes the interface from the bridge, if it is a port of it.
+func (o NativeOvsctl) DeletePortFromOVS(bridgeName, interfaceName string) error {
+	err := o.withDB(func(db *ovsdbClient) error {
+		uuid, err := db.selectUUID("Port", interfaceName)
+		if err != nil || uuid == "" {
+			return err
+		}
+		_, err = db.transactAndWait(ovsdbOp{
+			"op":        "mutate",
+			"table":     "Bridge",
+			"where":     []interface{}{[]interface{}{"name", "==", bridgeName}},
+			"mutations": []interface{}{[]interface{}{"ports", "delete", uuidRef(uuid)}},
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("[ovs] Failed to disconnect interface %v from bridge, err:%v.", interfaceName, err)
+	}
+	return err
+}
+
+// parseMacHex parses a MAC address written as 12 hex digits, as passed to the ARP rules.
+func parseMacHex(macHex string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(macHex, "0x"))
+	if err != nil || len(b) != 6 {
+		return nil, fmt.Errorf("%w %q", errInvalidMacHex, macHex)
+	}
+	return b, nil
+}
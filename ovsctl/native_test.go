@@ -0,0 +1,143 @@
+package ovsctl
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUsesCLIByDefault(t *testing.T) {
+	require.IsType(t, Ovsctl{}, New())
+
+	UseNativeClient = true
+	defer func() { UseNativeClient = false }()
+	require.IsType(t, NativeOvsctl{}, New())
+}
+
+func TestNativeBridgeAndPorts(t *testing.T) {
+	o, db := newTestNativeOvsctl(t)
+
+	require.NoError(t, o.CreateOVSBridge("azure0"))
+	// creating an existing bridge is a no-op
+	require.NoError(t, o.CreateOVSBridge("azure0"))
+	require.Len(t, db.tables["Bridge"], 1)
+	require.Equal(t, ovsdbSet("OpenFlow10", "OpenFlow13"), db.rowByName("Bridge", "azure0")["protocols"])
+
+	require.NoError(t, o.AddPortOnOVSBridge("eth0", "azure0", 0))
+	require.NoError(t, o.AddPortOnOVSBridge("azv1234567", "azure0", 0))
+	require.NoError(t, o.AddPortOnOVSBridge("azv1234567", "azure0", 0))
+	require.Len(t, ovsdbSetValues(db.rowByName("Bridge", "azure0")["ports"]), 3)
+	require.Error(t, o.AddPortOnOVSBridge("azv7654321", "missing", 0))
+
+	port, err := o.GetOVSPortNumber("azv1234567")
+	require.NoError(t, err)
+	require.Equal(t, "3", port)
+	_, err = o.GetOVSPortNumber("missing")
+	require.Error(t, err)
+
+	require.NoError(t, o.DeletePortFromOVS("azure0", "azv1234567"))
+	require.NoError(t, o.DeletePortFromOVS("azure0", "azv1234567"))
+	require.Nil(t, db.rowByName("Port", "azv1234567"))
+	require.Nil(t, db.rowByName("Interface", "azv1234567"))
+	require.Len(t, ovsdbSetValues(db.rowByName("Bridge", "azure0")["ports"]), 2)
+
+	require.NoError(t, o.DeleteOVSBridge("azure0"))
+	require.NoError(t, o.DeleteOVSBridge("azure0"))
+	require.Empty(t, db.tables["Bridge"])
+	require.Empty(t, db.tables["Port"])
+	require.Empty(t, db.tables["Interface"])
+}
+
+func TestNativeOVSDBUnavailable(t *testing.T) {
+	o, _ := newTestNativeOvsctl(t)
+	o.dbSocket += ".missing"
+	require.ErrorIs(t, o.CreateOVSBridge("azure0"), errorMockOvsctl)
+}
+
+func TestNativeEndpointFlows(t *testing.T) {
+	o, _ := newTestNativeOvsctl(t)
+	sw := newFakeSwitch(t, o.runDir, "azure0")
+
+	ip1, ip2 := net.ParseIP("10.240.0.5"), net.ParseIP("10.240.0.6")
+	for _, ep := range []struct {
+		ip   net.IP
+		port string
+		mac  string
+	}{{ip1, "3", "12:34:56:78:9a:01"}, {ip2, "4", "12:34:56:78:9a:02"}} {
+		require.NoError(t, o.AddIPSnatRule("azure0", ep.ip, 10, ep.port, "00:0d:3a:00:00:01", "1"))
+		require.NoError(t, o.AddArpReplyRule("azure0", ep.port, ep.ip, ep.mac, 10, ""))
+		require.NoError(t, o.AddMacDnatRule("azure0", "1", ep.ip, ep.mac, 10, ep.port))
+	}
+	// adding the same flows again replaces them
+	require.NoError(t, o.AddMacDnatRule("azure0", "1", ip1, "12:34:56:78:9a:01", 10, "3"))
+	require.Len(t, sw.getFlows(), 10)
+
+	var snat fakeFlow
+	for _, f := range sw.getFlows() {
+		if f.cookie == flowCookie(cookieIPSnat, 0, 3) && f.priority == high {
+			snat = f
+		}
+	}
+	require.Equal(t, map[uint8][]byte{
+		oxmInPort:  be32(3),
+		oxmEthType: be16(ethTypeIPv4),
+		oxmVlanVID: be16(ofpvidNone),
+		oxmIPv4Src: ip1.To4(),
+	}, snat.match)
+
+	o.DeleteIPSnatRule("azure0", "3")
+	o.DeleteArpReplyRule("azure0", "3", ip1, 10)
+	o.DeleteMacDnatRule("azure0", "1", ip1, 10)
+
+	flows := sw.getFlows()
+	require.Len(t, flows, 5)
+	for _, f := range flows {
+		require.NotEqual(t, ipKey(ip1), uint32(f.cookie), "flow of deleted endpoint %+v", f)
+		require.NotEqual(t, uint32(3), uint32(f.cookie), "flow of deleted endpoint %+v", f)
+	}
+}
+
+func TestNativeFlowError(t *testing.T) {
+	o, _ := newTestNativeOvsctl(t)
+	sw := newFakeSwitch(t, o.runDir, "azure0")
+	sw.errorCode = 3
+
+	err := o.AddFakeArpReply("azure0", net.ParseIP("169.254.0.1"))
+	require.ErrorIs(t, err, errorMockOvsctl)
+	require.Contains(t, err.Error(), "error type 4 code 3")
+
+	require.Error(t, o.AddVMIpAcceptRule("missing", "10.240.0.4", "00:0d:3a:00:00:01"))
+	require.Error(t, o.AddArpSnatRule("azure0", "00:0d:3a:00:00:01", "not hex", "1"))
+}
+
+func TestEncodeFlow(t *testing.T) {
+	table := uint8(1)
+	f := flow{
+		priority:  ofpDefaultPriority,
+		cookie:    flowCookie(cookieArpReply, 10, 0x0af00005),
+		match:     [][]byte{matchInPort(3), matchEthType(ethTypeARP)},
+		actions:   [][]byte{actionPopVlan()},
+		gotoTable: &table,
+	}
+	require.Equal(t, []byte{
+		// cookie, cookie mask
+		0xac, 0x05, 0x00, 0x0a, 0x0a, 0xf0, 0x00, 0x05,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		// table, command, timeouts, priority
+		0, ofpfcAdd, 0, 0, 0, 0, 0x80, 0x00,
+		// buffer, out port, out group, flags
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0,
+		// OXM match of 4+8+6 bytes padded to 24
+		0, 1, 0, 18,
+		0x80, 0x00, 0x00, 4, 0, 0, 0, 3,
+		0x80, 0x00, 0x0a, 2, 0x08, 0x06, 0, 0,
+		0, 0, 0, 0,
+		// apply actions: pop vlan
+		0, ofpitApplyActions, 0, 16, 0, 0, 0, 0,
+		0, ofpatPopVlan, 0, 8, 0, 0, 0, 0,
+		// goto table 1
+		0, ofpitGotoTable, 0, 8, 1, 0, 0, 0,
+	}, f.encode())
+}
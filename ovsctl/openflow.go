@@ -0,0 +1,399 @@
+package ovsctl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OpenFlow 1.3 message types.
+const (
+	ofVersion13 = 0x04
+
+	ofptHello          = 0
+	ofptError          = 1
+	ofptEchoRequest    = 2
+	ofptEchoReply      = 3
+	ofptFlowMod        = 14
+	ofptBarrierRequest = 20
+	ofptBarrierReply   = 21
+
+	ofHeaderLen = 8
+)
+
+// Flow mod commands, ports and defaults.
+const (
+	ofpfcAdd    = 0
+	ofpfcDelete = 3
+
+	ofppInPort = 0xfffffff8
+	ofppNormal = 0xfffffffa
+	ofppAny    = 0xffffffff
+	ofpgAny    = 0xffffffff
+	ofpNoBuf   = 0xffffffff
+	ofpttAll   = 0xff
+
+	ofpDefaultPriority = 0x8000
+)
+
+// OXM match fields of the OpenFlow basic class.
+const (
+	oxmClassBasic = 0x8000
+
+	oxmInPort  = 0
+	oxmEthDst  = 3
+	oxmEthSrc  = 4
+	oxmEthType = 5
+	oxmVlanVID = 6
+	oxmIPv4Src = 11
+	oxmIPv4Dst = 12
+	oxmARPOp   = 21
+	oxmARPSPA  = 22
+	oxmARPTPA  = 23
+	oxmARPSHA  = 24
+	oxmARPTHA  = 25
+
+	ethTypeIPv4 = 0x0800
+	ethTypeARP  = 0x0806
+	ethTypeVlan = 0x8100
+
+	// ofpvidPresent is set in vlan_vid matches and set fields of tagged packets.
+	ofpvidPresent = 0x1000
+	// ofpvidNone matches packets without a vlan tag.
+	ofpvidNone = 0x0000
+)
+
+// Action and instruction types.
+const (
+	ofpatOutput       = 0
+	ofpatPushVlan     = 17
+	ofpatPopVlan      = 18
+	ofpatSetField     = 25
+	ofpatExperimenter = 0xffff
+
+	ofpitGotoTable    = 1
+	ofpitApplyActions = 4
+
+	nxVendorID   = 0x00002320
+	nxastRegMove = 6
+)
+
+// NXM headers of the fields moved by the ARP reply flows.
+const (
+	nxmOfEthDst = 0x00000206
+	nxmOfEthSrc = 0x00000406
+	nxmOfARPSPA = 0x00002004
+	nxmOfARPTPA = 0x00002204
+	nxmNxARPSHA = 0x00012206
+	nxmNxARPTHA = 0x00012406
+)
+
+var ErrOpenFlow = errors.New("openflow request failed")
+
+// flow is an OpenFlow 1.3 flow entry.
+type flow struct {
+	table    uint8
+	priority uint16
+	cookie   uint64
+	match    [][]byte
+	actions  [][]byte
+	// gotoTable continues processing in the table after the actions, if set.
+	gotoTable *uint8
+}
+
+// flowDelete removes the flows which match and whose cookie equals cookie in the bits of cookieMask.
+type flowDelete struct {
+	table      uint8
+	cookie     uint64
+	cookieMask uint64
+	match      [][]byte
+}
+
+func oxm(field uint8, value []byte) []byte {
+	b := make([]byte, 4, 4+len(value))
+	binary.BigEndian.PutUint16(b, oxmClassBasic)
+	b[2] = field << 1
+	b[3] = uint8(len(value))
+	return append(b, value...)
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func matchInPort(port uint32) []byte { return oxm(oxmInPort, be32(port)) }
+func matchEthType(t uint16) []byte   { return oxm(oxmEthType, be16(t)) }
+func matchEthDst(mac []byte) []byte  { return oxm(oxmEthDst, mac) }
+func matchVlanVID(vid uint16) []byte { return oxm(oxmVlanVID, be16(vid)) }
+func matchIPv4Src(ip net.IP) []byte  { return oxm(oxmIPv4Src, ip.To4()) }
+func matchIPv4Dst(ip net.IP) []byte  { return oxm(oxmIPv4Dst, ip.To4()) }
+func matchARPOp(op uint16) []byte    { return oxm(oxmARPOp, be16(op)) }
+func matchARPTPA(ip net.IP) []byte   { return oxm(oxmARPTPA, ip.To4()) }
+
+// matchVlan matches packets tagged with vlanID.
+func matchVlan(vlanID int) []byte {
+	return matchVlanVID(ofpvidPresent | uint16(vlanID))
+}
+
+func actionOutput(port uint32) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint16(b, ofpatOutput)
+	binary.BigEndian.PutUint16(b[2:], 16)
+	binary.BigEndian.PutUint32(b[4:], port)
+	// max_len only applies to the controller port
+	binary.BigEndian.PutUint16(b[8:], 0xffff)
+	return b
+}
+
+func actionSetField(field []byte) []byte {
+	length := pad8(4 + len(field))
+	b := make([]byte, length)
+	binary.BigEndian.PutUint16(b, ofpatSetField)
+	binary.BigEndian.PutUint16(b[2:], uint16(length))
+	copy(b[4:], field)
+	return b
+}
+
+// actionSetVlan tags untagged packets with vlanID.
+func actionSetVlan(vlanID int) [][]byte {
+	push := make([]byte, 8)
+	binary.BigEndian.PutUint16(push, ofpatPushVlan)
+	binary.BigEndian.PutUint16(push[2:], 8)
+	binary.BigEndian.PutUint16(push[4:], ethTypeVlan)
+	return [][]byte{push, actionSetField(oxm(oxmVlanVID, be16(ofpvidPresent|uint16(vlanID))))}
+}
+
+func actionPopVlan() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b, ofpatPopVlan)
+	binary.BigEndian.PutUint16(b[2:], 8)
+	return b
+}
+
+// actionMove copies nBits of the src field to the dst field with the Nicira reg_move extension.
+func actionMove(src, dst uint32, nBits uint16) []byte {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint16(b, ofpatExperimenter)
+	binary.BigEndian.PutUint16(b[2:], 24)
+	binary.BigEndian.PutUint32(b[4:], nxVendorID)
+	binary.BigEndian.PutUint16(b[8:], nxastRegMove)
+	binary.BigEndian.PutUint16(b[10:], nBits)
+	// src_ofs and dst_ofs are 0
+	binary.BigEndian.PutUint32(b[16:], src)
+	binary.BigEndian.PutUint32(b[20:], dst)
+	return b
+}
+
+func pad8(n int) int {
+	return (n + 7) / 8 * 8
+}
+
+func encodeMatch(fields [][]byte) []byte {
+	length := 4
+	for _, f := range fields {
+		length += len(f)
+	}
+	b := make([]byte, 4, pad8(length))
+	binary.BigEndian.PutUint16(b, 1) // OFPMT_OXM
+	binary.BigEndian.PutUint16(b[2:], uint16(length))
+	for _, f := range fields {
+		b = append(b, f...)
+	}
+	return b[:pad8(length)]
+}
+
+func encodeInstructions(f *flow) []byte {
+	var b []byte
+	if len(f.actions) > 0 {
+		length := 8
+		for _, a := range f.actions {
+			length += len(a)
+		}
+		inst := make([]byte, 8, length)
+		binary.BigEndian.PutUint16(inst, ofpitApplyActions)
+		binary.BigEndian.PutUint16(inst[2:], uint16(length))
+		for _, a := range f.actions {
+			inst = append(inst, a...)
+		}
+		b = append(b, inst...)
+	}
+	if f.gotoTable != nil {
+		inst := make([]byte, 8)
+		binary.BigEndian.PutUint16(inst, ofpitGotoTable)
+		binary.BigEndian.PutUint16(inst[2:], 8)
+		inst[4] = *f.gotoTable
+		b = append(b, inst...)
+	}
+	return b
+}
+
+func encodeFlowMod(command uint8, table uint8, priority uint16, cookie, cookieMask uint64, match, instructions []byte) []byte {
+	body := make([]byte, 40)
+	binary.BigEndian.PutUint64(body, cookie)
+	binary.BigEndian.PutUint64(body[8:], cookieMask)
+	body[16] = table
+	body[17] = command
+	// idle and hard timeouts are 0
+	binary.BigEndian.PutUint16(body[22:], priority)
+	binary.BigEndian.PutUint32(body[24:], ofpNoBuf)
+	binary.BigEndian.PutUint32(body[28:], ofppAny)
+	binary.BigEndian.PutUint32(body[32:], ofpgAny)
+	body = append(body, match...)
+	return append(body, instructions...)
+}
+
+func (f *flow) encode() []byte {
+	return encodeFlowMod(ofpfcAdd, f.table, f.priority, f.cookie, 0, encodeMatch(f.match), encodeInstructions(f))
+}
+
+func (d *flowDelete) encode() []byte {
+	return encodeFlowMod(ofpfcDelete, d.table, 0, d.cookie, d.cookieMask, encodeMatch(d.match), nil)
+}
+
+// parseOFPort parses an ofport as returned by GetOVSPortNumber, or "normal".
+func parseOFPort(port string) (uint32, error) {
+	if strings.EqualFold(port, "normal") {
+		return ofppNormal, nil
+	}
+	p, err := strconv.ParseUint(strings.TrimSpace(port), 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid ofport %q", port)
+	}
+	return uint32(p), nil
+}
+
+// ofConn is an OpenFlow 1.3 connection to the management socket of a bridge.
+type ofConn struct {
+	conn    net.Conn
+	timeout time.Duration
+	xid     uint32
+}
+
+func dialOpenFlow(socket string, timeout time.Duration) (*ofConn, error) {
+	conn, err := net.DialTimeout("unix", socket, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", socket)
+	}
+	c := &ofConn{conn: conn, timeout: timeout}
+	if err := c.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ofConn) close() {
+	c.conn.Close()
+}
+
+func (c *ofConn) write(msgType uint8, body []byte) (uint32, error) {
+	c.xid++
+	b := make([]byte, ofHeaderLen, ofHeaderLen+len(body))
+	b[0] = ofVersion13
+	b[1] = msgType
+	binary.BigEndian.PutUint16(b[2:], uint16(ofHeaderLen+len(body)))
+	binary.BigEndian.PutUint32(b[4:], c.xid)
+	b = append(b, body...)
+	_, err := c.conn.Write(b)
+	return c.xid, errors.Wrap(err, "failed to write openflow message")
+}
+
+func (c *ofConn) read() (version, msgType uint8, xid uint32, body []byte, err error) {
+	header := make([]byte, ofHeaderLen)
+	if _, err = io.ReadFull(c.conn, header); err != nil {
+		return 0, 0, 0, nil, errors.Wrap(err, "failed to read openflow message")
+	}
+	length := int(binary.BigEndian.Uint16(header[2:]))
+	if length < ofHeaderLen {
+		return 0, 0, 0, nil, fmt.Errorf("%w: invalid message length %d", ErrOpenFlow, length)
+	}
+	body = make([]byte, length-ofHeaderLen)
+	if _, err = io.ReadFull(c.conn, body); err != nil {
+		return 0, 0, 0, nil, errors.Wrap(err, "failed to read openflow message")
+	}
+	return header[0], header[1], binary.BigEndian.Uint32(header[4:]), body, nil
+}
+
+func (c *ofConn) handshake() error {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return errors.Wrap(err, "failed to set openflow deadline")
+	}
+	if _, err := c.write(ofptHello, nil); err != nil {
+		return err
+	}
+	version, msgType, _, body, err := c.read()
+	if err != nil {
+		return err
+	}
+	switch msgType {
+	case ofptHello:
+		// the switch sends the highest version it supports
+		if version < ofVersion13 {
+			return fmt.Errorf("%w: bridge doesn't support OpenFlow 1.3 (version %d)", ErrOpenFlow, version)
+		}
+		return nil
+	case ofptError:
+		return ofError(body)
+	default:
+		return fmt.Errorf("%w: expected hello, got message type %d", ErrOpenFlow, msgType)
+	}
+}
+
+// send writes the flow mods followed by a barrier and returns the first error the switch replied with.
+func (c *ofConn) send(flowMods ...[]byte) error {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return errors.Wrap(err, "failed to set openflow deadline")
+	}
+	for _, fm := range flowMods {
+		if _, err := c.write(ofptFlowMod, fm); err != nil {
+			return err
+		}
+	}
+	barrier, err := c.write(ofptBarrierRequest, nil)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for {
+		_, msgType, xid, body, err := c.read()
+		if err != nil {
+			return err
+		}
+		switch msgType {
+		case ofptError:
+			if firstErr == nil {
+				firstErr = ofError(body)
+			}
+		case ofptEchoRequest:
+			if _, err := c.write(ofptEchoReply, body); err != nil {
+				return err
+			}
+		case ofptBarrierReply:
+			if xid == barrier {
+				return firstErr
+			}
+		}
+	}
+}
+
+func ofError(body []byte) error {
+	if len(body) < 4 {
+		return fmt.Errorf("%w: truncated error message", ErrOpenFlow)
+	}
+	return fmt.Errorf("%w: error type %d code %d", ErrOpenFlow, binary.BigEndian.Uint16(body), binary.BigEndian.Uint16(body[2:]))
+}
@@ -50,6 +50,19 @@ type OvsInterface interface {
 	DeletePortFromOVS(bridgeName string, interfaceName string) error
 }
 
+// UseNativeClient makes New return the client talking OVSDB and OpenFlow to OVS
+// instead of the one running ovs-vsctl and ovs-ofctl.
+var UseNativeClient bool
+
+// New returns the OVS client selected by UseNativeClient.
+func New() OvsInterface {
+	if UseNativeClient {
+		return NewNativeOvsctl()
+	}
+	return NewOvsctl()
+}
+
+// Ovsctl configures OVS by running ovs-vsctl and ovs-ofctl.
 type Ovsctl struct {
 	execcli platform.ExecClient
 }
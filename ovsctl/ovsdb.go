@@ -0,0 +1,223 @@
+package ovsctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ovsdbDatabase = "Open_vSwitch"
+
+	// Default unix socket of ovsdb-server.
+	defaultOVSDBSocket = "/var/run/openvswitch/db.sock"
+)
+
+var (
+	ErrOVSDBTransaction = errors.New("ovsdb transaction failed")
+	ErrOVSDBTimeout     = errors.New("timed out waiting for ovs-vswitchd to apply the configuration")
+)
+
+// ovsdbOp is an operation of an OVSDB transact request (RFC 7047 section 5.2).
+type ovsdbOp map[string]interface{}
+
+type ovsdbResult struct {
+	UUID    []interface{}            `json:"uuid"`
+	Rows    []map[string]interface{} `json:"rows"`
+	Count   int                      `json:"count"`
+	Error   string                   `json:"error"`
+	Details string                   `json:"details"`
+}
+
+type jsonrpcMessage struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  interface{}     `json:"error,omitempty"`
+	ID     interface{}     `json:"id"`
+}
+
+// ovsdbClient sends JSON-RPC requests to ovsdb-server over a unix socket.
+type ovsdbClient struct {
+	conn    net.Conn
+	dec     *json.Decoder
+	enc     *json.Encoder
+	timeout time.Duration
+	nextID  int
+}
+
+func dialOVSDB(socket string, timeout time.Duration) (*ovsdbClient, error) {
+	conn, err := net.DialTimeout("unix", socket, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to ovsdb-server at %s", socket)
+	}
+	return &ovsdbClient{
+		conn:    conn,
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		timeout: timeout,
+	}, nil
+}
+
+func (c *ovsdbClient) close() {
+	c.conn.Close()
+}
+
+// transact runs the operations in one transaction and returns their results.
+func (c *ovsdbClient) transact(ops ...ovsdbOp) ([]ovsdbResult, error) {
+	params := make([]interface{}, 0, len(ops)+1)
+	params = append(params, ovsdbDatabase)
+	for _, op := range ops {
+		params = append(params, op)
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal ovsdb transaction")
+	}
+
+	c.nextID++
+	id := c.nextID
+	if err = c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, errors.Wrap(err, "failed to set ovsdb deadline")
+	}
+	if err = c.enc.Encode(jsonrpcMessage{Method: "transact", Params: rawParams, ID: id}); err != nil {
+		return nil, errors.Wrap(err, "failed to send ovsdb transaction")
+	}
+
+	for {
+		var msg jsonrpcMessage
+		if err = c.dec.Decode(&msg); err != nil {
+			return nil, errors.Wrap(err, "failed to read ovsdb reply")
+		}
+		// ovsdb-server checks that idle clients are alive with echo requests
+		if msg.Method == "echo" {
+			if err = c.enc.Encode(jsonrpcMessage{Result: msg.Params, ID: msg.ID}); err != nil {
+				return nil, errors.Wrap(err, "failed to reply to ovsdb echo")
+			}
+			continue
+		}
+		if replyID, ok := msg.ID.(float64); !ok || int(replyID) != id {
+			continue
+		}
+		if msg.Error != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOVSDBTransaction, msg.Error)
+		}
+
+		var results []ovsdbResult
+		if err = json.Unmarshal(msg.Result, &results); err != nil {
+			return nil, errors.Wrap(err, "failed to decode ovsdb reply")
+		}
+		// a failed commit adds a result after those of the operations
+		for i := range results {
+			if results[i].Error != "" {
+				return nil, fmt.Errorf("%w: %s: %s", ErrOVSDBTransaction, results[i].Error, results[i].Details)
+			}
+		}
+		if len(results) < len(ops) {
+			return nil, fmt.Errorf("%w: got %d results for %d operations", ErrOVSDBTransaction, len(results), len(ops))
+		}
+		return results, nil
+	}
+}
+
+// transactAndWait runs the operations and waits until ovs-vswitchd has applied them, like ovs-vsctl does.
+func (c *ovsdbClient) transactAndWait(ops ...ovsdbOp) ([]ovsdbResult, error) {
+	ops = append(ops,
+		ovsdbOp{
+			"op":        "mutate",
+			"table":     ovsdbDatabase,
+			"where":     []interface{}{},
+			"mutations": []interface{}{[]interface{}{"next_cfg", "+=", 1}},
+		},
+		ovsdbOp{
+			"op":      "select",
+			"table":   ovsdbDatabase,
+			"where":   []interface{}{},
+			"columns": []string{"next_cfg"},
+		})
+	results, err := c.transact(ops...)
+	if err != nil {
+		return nil, err
+	}
+	selected := results[len(ops)-1].Rows
+	if len(selected) != 1 {
+		return nil, fmt.Errorf("%w: expected one %s row", ErrOVSDBTransaction, ovsdbDatabase)
+	}
+	nextCfg, _ := selected[0]["next_cfg"].(float64)
+
+	deadline := time.Now().Add(c.timeout)
+	for {
+		cur, err := c.transact(ovsdbOp{
+			"op":      "select",
+			"table":   ovsdbDatabase,
+			"where":   []interface{}{},
+			"columns": []string{"cur_cfg"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if curCfg, _ := cur[0].Rows[0]["cur_cfg"].(float64); curCfg >= nextCfg {
+			return results[:len(ops)-2], nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrOVSDBTimeout
+		}
+		time.Sleep(50 * time.Millisecond) //nolint:gomnd // poll interval
+	}
+}
+
+// selectUUID returns the UUID of the row of table named name, or "" if there is none.
+func (c *ovsdbClient) selectUUID(table, name string) (string, error) {
+	results, err := c.transact(selectByName(table, name, "_uuid"))
+	if err != nil {
+		return "", err
+	}
+	if len(results[0].Rows) == 0 {
+		return "", nil
+	}
+	return ovsdbUUID(results[0].Rows[0]["_uuid"]), nil
+}
+
+func selectByName(table, name string, columns ...string) ovsdbOp {
+	return ovsdbOp{
+		"op":      "select",
+		"table":   table,
+		"where":   []interface{}{[]interface{}{"name", "==", name}},
+		"columns": columns,
+	}
+}
+
+// ovsdbUUID returns the UUID of a ["uuid", <uuid>] value, or "" for other values.
+func ovsdbUUID(v interface{}) string {
+	pair, ok := v.([]interface{})
+	if !ok || len(pair) != 2 || pair[0] != "uuid" {
+		return ""
+	}
+	uuid, _ := pair[1].(string)
+	return uuid
+}
+
+// ovsdbSetValues returns the members of a set, which OVSDB sends as the member itself if it has exactly one.
+func ovsdbSetValues(v interface{}) []interface{} {
+	pair, ok := v.([]interface{})
+	if ok && len(pair) == 2 && pair[0] == "set" {
+		members, _ := pair[1].([]interface{})
+		return members
+	}
+	return []interface{}{v}
+}
+
+func ovsdbSet(members ...interface{}) []interface{} {
+	return []interface{}{"set", members}
+}
+
+func namedUUID(name string) []interface{} {
+	return []interface{}{"named-uuid", name}
+}
+
+func uuidRef(uuid string) []interface{} {
+	return []interface{}{"uuid", uuid}
+}
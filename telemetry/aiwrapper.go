@@ -27,14 +27,20 @@ func CreateAITelemetryHandle(aiConfig aitelemetry.AIConfig, disableAll, disableM
 		return fmt.Errorf("Telmetry disabled")
 	}
 
-	th, err = aitelemetry.NewAITelemetry("", aiMetadata, aiConfig)
+	handle, err := aitelemetry.NewAITelemetry("", aiMetadata, aiConfig)
 	if err != nil {
 		return err
 	}
 
+	SetTelemetryHandle(handle, disableMetric, disableTrace)
+	return nil
+}
+
+// SetTelemetryHandle sends the CNI reports and metrics through handle, which may be any TelemetryHandle implementation.
+func SetTelemetryHandle(handle aitelemetry.TelemetryHandle, disableMetric, disableTrace bool) {
+	th = handle
 	gDisableMetric = disableMetric
 	gDisableTrace = disableTrace
-	return nil
 }
 
 func SendAITelemetry(cnireport CNIReport) {
@@ -46,3 +46,35 @@ func TestCreateAITelemetryHandle(t *testing.T) {
 		})
 	}
 }
+
+// fakeTelemetryHandle records what is sent through it.
+type fakeTelemetryHandle struct {
+	logs    []aitelemetry.Report
+	metrics []aitelemetry.Metric
+}
+
+func (f *fakeTelemetryHandle) TrackLog(report aitelemetry.Report) { f.logs = append(f.logs, report) }
+func (f *fakeTelemetryHandle) TrackMetric(metric aitelemetry.Metric) {
+	f.metrics = append(f.metrics, metric)
+}
+func (f *fakeTelemetryHandle) TrackEvent(aitelemetry.Event) {}
+func (f *fakeTelemetryHandle) Close(int)                    {}
+func (f *fakeTelemetryHandle) Flush()                       {}
+
+func TestSetTelemetryHandle(t *testing.T) {
+	handle := &fakeTelemetryHandle{}
+	SetTelemetryHandle(handle, false, false)
+	defer SetTelemetryHandle(nil, false, false)
+
+	SendAITelemetry(CNIReport{EventMessage: "added", ContainerName: "c1"})
+	SendAIMetric(AIMetric{Metric: aitelemetry.Metric{Name: "latency", Value: 1}})
+	require.Len(t, handle.logs, 1)
+	require.Equal(t, "added", handle.logs[0].Message)
+	require.Len(t, handle.metrics, 1)
+
+	SetTelemetryHandle(handle, true, true)
+	SendAITelemetry(CNIReport{EventMessage: "dropped"})
+	SendAIMetric(AIMetric{Metric: aitelemetry.Metric{Name: "dropped"}})
+	require.Len(t, handle.logs, 1)
+	require.Len(t, handle.metrics, 1)
+}
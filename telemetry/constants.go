@@ -10,6 +10,11 @@ const (
 	CNIUpdateTimeMetricStr = "CNIUpdateTimeMs"
 	CNILockTimeoutStr      = "CNILockTimeoutError"
 
+	// TelemetryBufferDroppedStr and TelemetryBufferSpooledStr count the reports the telemetry
+	// service lost and the ones it received late from a client spool.
+	TelemetryBufferDroppedStr = "TelemetryBufferDropped"
+	TelemetryBufferSpooledStr = "TelemetryBufferSpooled"
+
 	// Dimension Names
 	ContextStr        = "Context"
 	SubContextStr     = "SubContext"
@@ -0,0 +1,157 @@
+// Copyright Microsoft. All rights reserved.
+// MIT License
+
+package telemetry
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Messages on the telemetry socket are sent in frames, a fixed size header followed by the payload:
+//
+//	| length (4 bytes, big endian) | version (1 byte) | type (1 byte) | flags (1 byte) | payload (length bytes) |
+//
+// The service answers every frame with an ack frame whose one byte payload says whether the message was
+// accepted. Clients spool the messages the service is too busy to take and replay them later, so a slow
+// or stopped service pushes back on the clients instead of losing their reports.
+//
+// The service and the plugins are upgraded separately, so both sides still speak the legacy format of
+// newline delimited JSON to older peers. The service sends a hello frame as soon as a client connects,
+// and clients which don't get it in time fall back to the legacy format. A legacy client is recognized
+// by its first byte: a JSON object starts with '{', while a frame starts with the high byte of a length
+// which is at most MaxPayloadSize, so always zero.
+const (
+	frameVersion    = 1
+	frameHeaderSize = 7
+	legacyDelimiter = '\n'
+)
+
+// MessageType identifies the payload of a frame.
+type MessageType uint8
+
+const (
+	// MessageCNIReport carries a JSON encoded CNIReport.
+	MessageCNIReport MessageType = 1
+	// MessageAIMetric carries a JSON encoded AIMetric.
+	MessageAIMetric MessageType = 2
+	// MessageSpoolStats carries the JSON encoded SpoolStats of a client.
+	MessageSpoolStats MessageType = 3
+	// messageAck is the answer of the service to every other message.
+	messageAck MessageType = 4
+	// messageHello is sent by the service when a client connects, to tell it the service reads frames.
+	messageHello MessageType = 5
+)
+
+// flagReplayed marks a message replayed from the spool of a client.
+const flagReplayed = 1 << 0
+
+type ackStatus uint8
+
+const (
+	ackAccepted ackStatus = iota
+	// ackBusy asks the client to spool the message and retry it later.
+	ackBusy
+	// ackRejected tells the client the message will never be accepted, so it must not be retried.
+	ackRejected
+)
+
+var (
+	errFrameTooLarge   = errors.New("frame payload exceeds the maximum size")
+	errUnexpectedFrame = errors.New("unexpected frame")
+)
+
+type frame struct {
+	version uint8
+	typ     MessageType
+	flags   uint8
+	payload []byte
+}
+
+func newFrame(typ MessageType, payload []byte) frame {
+	return frame{version: frameVersion, typ: typ, payload: payload}
+}
+
+func ackFrame(status ackStatus) frame {
+	return newFrame(messageAck, []byte{byte(status)})
+}
+
+// encode returns the frame as sent on the wire.
+func (f *frame) encode() []byte {
+	b := make([]byte, frameHeaderSize+len(f.payload))
+	binary.BigEndian.PutUint32(b, uint32(len(f.payload)))
+	b[4] = f.version
+	b[5] = byte(f.typ)
+	b[6] = f.flags
+	copy(b[frameHeaderSize:], f.payload)
+	return b
+}
+
+// writeFrame writes f in a single write, so frames of concurrent writers never interleave.
+func writeFrame(w io.Writer, f frame) error {
+	if len(f.payload) > MaxPayloadSize {
+		return errFrameTooLarge
+	}
+	_, err := w.Write(f.encode())
+	return errors.Wrap(err, "failed to write frame")
+}
+
+// readFrame reads the next frame from r. Frames of any version are returned, it is up to the caller
+// to reject the versions it doesn't understand.
+func readFrame(r io.Reader) (frame, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err //nolint:wrapcheck // callers check for io.EOF
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > MaxPayloadSize {
+		return frame{}, errFrameTooLarge
+	}
+
+	f := frame{
+		version: header[4],
+		typ:     MessageType(header[5]),
+		flags:   header[6],
+		payload: make([]byte, length),
+	}
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, errors.Wrap(err, "failed to read frame payload")
+	}
+	return f, nil
+}
+
+// isLegacyMessage reports whether first, the first byte sent by a client, starts a legacy JSON message.
+func isLegacyMessage(first byte) bool {
+	return first == '{'
+}
+
+// legacyMessageType returns the type of a legacy JSON message, which older clients identify by its fields.
+func legacyMessageType(payload []byte) (MessageType, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return 0, errors.Wrap(err, "failed to decode legacy message")
+	}
+	if _, ok := fields["CniSucceeded"]; ok {
+		return MessageCNIReport, nil
+	}
+	if _, ok := fields["Metric"]; ok {
+		return MessageAIMetric, nil
+	}
+	return 0, errors.Wrap(errUnexpectedFrame, "legacy message is neither a report nor a metric")
+}
+
+// readAck reads the ack of the service for the last frame sent.
+func readAck(r io.Reader) (ackStatus, error) {
+	f, err := readFrame(r)
+	if err != nil {
+		return 0, err
+	}
+	if f.typ != messageAck || len(f.payload) != 1 {
+		return 0, errors.Wrapf(errUnexpectedFrame, "expected an ack, got message type %d", f.typ)
+	}
+	return ackStatus(f.payload[0]), nil
+}
@@ -0,0 +1,158 @@
+// Copyright Microsoft. All rights reserved.
+// MIT License
+
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	spoolFileExtension = ".frame"
+	claimedSuffix      = ".claimed-"
+	droppedFileName    = "dropped"
+	// MaxSpooledReports bounds the spool, reports sent while it is full are dropped and counted.
+	MaxSpooledReports = 1000
+	// a claim older than this was left by a client which exited while replaying, and can be taken over.
+	staleClaimAge = time.Minute
+)
+
+var errSpoolFull = errors.New("telemetry spool is full")
+
+// SpoolStats are sent by a client to report the messages it dropped because its spool was full.
+type SpoolStats struct {
+	Dropped uint64
+}
+
+// spool stores messages on disk while the telemetry service can't take them. Each message is a file
+// holding its frame, so the concurrent plugin processes sharing the spool never write the same file,
+// and a file is claimed by renaming it before it is replayed, so it is replayed by a single process.
+type spool struct {
+	dir      string
+	maxFiles int
+}
+
+func newSpool(dir string) *spool {
+	return &spool{dir: dir, maxFiles: MaxSpooledReports}
+}
+
+// add stores f in the spool, or counts it as dropped if the spool is full.
+func (s *spool) add(f frame) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil { //nolint:gomnd // standard directory permissions
+		return errors.Wrapf(err, "failed to create spool directory %s", s.dir)
+	}
+
+	names, err := s.list()
+	if err != nil {
+		return err
+	}
+	if len(names) >= s.maxFiles {
+		s.recordDropped(1)
+		return errSpoolFull
+	}
+
+	// the name sorts by time, so messages are replayed in the order they were spooled.
+	name := fmt.Sprintf("%020d-%d%s", time.Now().UnixNano(), os.Getpid(), spoolFileExtension)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, f.encode(), 0o600); err != nil { //nolint:gomnd // owner only
+		return errors.Wrap(err, "failed to write spool file")
+	}
+	// rename so replaying clients never see a partially written file.
+	return errors.Wrap(os.Rename(tmp, filepath.Join(s.dir, name)), "failed to commit spool file")
+}
+
+// list returns the names of the spooled messages, oldest first, including stale claims.
+func (s *spool) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read spool directory %s", s.dir)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case strings.HasSuffix(name, spoolFileExtension):
+			names = append(names, name)
+		case strings.Contains(name, spoolFileExtension+claimedSuffix):
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > staleClaimAge {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// claim renames the spool file name so no other client replays it, and returns its frame and claimed path.
+// It returns false if another client claimed it first.
+func (s *spool) claim(name string) (frame, string, bool) {
+	base := name
+	if i := strings.Index(name, claimedSuffix); i >= 0 {
+		base = name[:i]
+	}
+	claimed := filepath.Join(s.dir, fmt.Sprintf("%s%s%d", base, claimedSuffix, os.Getpid()))
+	if err := os.Rename(filepath.Join(s.dir, name), claimed); err != nil {
+		return frame{}, "", false
+	}
+	// refresh the modification time, so the claim isn't taken over as stale while being replayed.
+	now := time.Now()
+	_ = os.Chtimes(claimed, now, now)
+
+	b, err := os.ReadFile(claimed)
+	if err == nil {
+		var f frame
+		if f, err = readFrame(bytes.NewReader(b)); err == nil {
+			return f, claimed, true
+		}
+	}
+	// a file which can't be read would never replay, drop it.
+	_ = os.Remove(claimed)
+	s.recordDropped(1)
+	return frame{}, "", false
+}
+
+// release returns a claimed file to the spool after it failed to replay.
+func (s *spool) release(claimed string) {
+	base := claimed[:strings.LastIndex(claimed, claimedSuffix)]
+	_ = os.Rename(claimed, base)
+}
+
+// recordDropped counts n dropped messages. The count is the size of the dropped file, so concurrent
+// clients can increment it with appends, which don't need a lock.
+func (s *spool) recordDropped(n uint64) {
+	f, err := os.OpenFile(filepath.Join(s.dir, droppedFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600) //nolint:gomnd // owner only
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(make([]byte, n))
+}
+
+// takeDropped returns the count of dropped messages and resets it.
+func (s *spool) takeDropped() uint64 {
+	path := filepath.Join(s.dir, droppedFileName)
+	// rename first, so appends racing with the reset are counted in a new file.
+	taken := fmt.Sprintf("%s.%d", path, os.Getpid())
+	if err := os.Rename(path, taken); err != nil {
+		return 0
+	}
+	defer os.Remove(taken)
+
+	info, err := os.Stat(taken)
+	if err != nil {
+		return 0
+	}
+	return uint64(info.Size())
+}
@@ -108,13 +108,11 @@ func (reportMgr *ReportManager) SendReport(tb *TelemetryBuffer) error {
 	var err error
 	var report []byte
 
-	if tb != nil && tb.Connected {
+	if tb != nil {
 		report, err = reportMgr.ReportToBytes()
 		if err == nil {
-			// If write fails, try to re-establish connections as server/client
-			if _, err = tb.Write(report); err != nil {
+			if err = tb.Send(reportMgr.messageType(), report); err != nil {
 				log.Printf("telemetry write failed:%v", err)
-				tb.Cancel()
 			}
 		}
 	}
@@ -122,6 +120,14 @@ func (reportMgr *ReportManager) SendReport(tb *TelemetryBuffer) error {
 	return err
 }
 
+// messageType returns the type of the telemetry socket message carrying the report.
+func (reportMgr *ReportManager) messageType() MessageType {
+	if _, ok := reportMgr.Report.(*AIMetric); ok {
+		return MessageAIMetric
+	}
+	return MessageCNIReport
+}
+
 // ReportToBytes - returns the report bytes
 func (reportMgr *ReportManager) ReportToBytes() ([]byte, error) {
 
@@ -141,14 +147,11 @@ func SendCNIMetric(cniMetric *AIMetric, tb *TelemetryBuffer) error {
 	var err error
 	var report []byte
 
-	if tb != nil && tb.Connected {
+	if tb != nil {
 		reportMgr := &ReportManager{Report: cniMetric}
 		report, err = reportMgr.ReportToBytes()
 		if err == nil {
-			// If write fails, try to re-establish connections as server/client
-			if _, err = tb.Write(report); err != nil {
-				tb.Cancel()
-			}
+			err = tb.Send(MessageAIMetric, report)
 		}
 	}
 
@@ -156,14 +159,12 @@ func SendCNIMetric(cniMetric *AIMetric, tb *TelemetryBuffer) error {
 }
 
 func SendCNIEvent(tb *TelemetryBuffer, report *CNIReport) {
-	if tb != nil && tb.Connected {
+	if tb != nil {
 		reportMgr := &ReportManager{Report: report}
 		reportBytes, err := reportMgr.ReportToBytes()
 		if err == nil {
-			// If write fails, try to re-establish connections as server/client
-			if _, err = tb.Write(reportBytes); err != nil {
+			if err = tb.Send(MessageCNIReport, reportBytes); err != nil {
 				log.Printf("Error writing to telemetry socket:%v", err)
-				tb.Cancel()
 			}
 		}
 	}
@@ -8,16 +8,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
 )
 
 // TelemetryConfig - telemetry config read by telemetry service
@@ -37,15 +41,26 @@ type TelemetryConfig struct {
 }
 
 // FdName - file descriptor name
-// Delimiter - delimiter for socket reads/writes
-// MaxPayloadSize - max buffer size in bytes
+// MaxPayloadSize - max frame payload size in bytes
+// MaxNumReports - max reports queued by the service, it asks clients to spool when full
 const (
 	FdName         = "azure-vnet-telemetry"
-	Delimiter      = '\n'
-	MaxPayloadSize = 4096
+	MaxPayloadSize = 64 * 1024
 	MaxNumReports  = 1000
 )
 
+const (
+	// ackTimeout bounds how long a client waits for the service to take a message before spooling it.
+	ackTimeout = 2 * time.Second
+	// statsReportInterval is how often the service reports its dropped and spooled counters.
+	statsReportInterval = 5 * time.Minute
+	// helloTimeout bounds how long a client waits for the hello of the service before it assumes an
+	// older service, which only reads legacy messages.
+	helloTimeout = 500 * time.Millisecond
+)
+
+var errMessageRejected = errors.New("telemetry service rejected the message")
+
 // TelemetryBuffer object
 type TelemetryBuffer struct {
 	client      net.Conn
@@ -56,6 +71,22 @@ type TelemetryBuffer struct {
 	data        chan interface{}
 	cancel      chan bool
 	mutex       sync.Mutex
+	sendMutex   sync.Mutex
+	// legacy is set when the service doesn't read frames, so messages are sent in the legacy format.
+	legacy bool
+	// spool is created by Connect, so only clients using the service spool the messages it can't take.
+	spool   *spool
+	dropped atomic.Uint64
+	spooled atomic.Uint64
+}
+
+// BufferStats are the counters of the telemetry service.
+type BufferStats struct {
+	// Dropped is the number of reports lost, either rejected by the service or dropped by
+	// clients whose spool was full.
+	Dropped uint64
+	// Spooled is the number of reports spooled by clients and delivered late.
+	Spooled uint64
 }
 
 // Buffer object holds the different types of reports
@@ -99,69 +130,157 @@ func (tb *TelemetryBuffer) StartServer() error {
 		for {
 			// Spawn worker goroutines to communicate with client
 			conn, err := tb.listener.Accept()
-			if err == nil {
-				tb.mutex.Lock()
-				tb.connections = append(tb.connections, conn)
-				tb.mutex.Unlock()
-				go func() {
-					for {
-						reportStr, err := read(conn)
-						if err == nil {
-							var tmp map[string]interface{}
-							err = json.Unmarshal(reportStr, &tmp)
-							if err != nil {
-								log.Logf("StartServer: unmarshal error:%v", err)
-								return
-							}
-							if _, ok := tmp["CniSucceeded"]; ok {
-								var cniReport CNIReport
-								json.Unmarshal([]byte(reportStr), &cniReport)
-								tb.data <- cniReport
-							} else if _, ok := tmp["Metric"]; ok {
-								var aiMetric AIMetric
-								json.Unmarshal([]byte(reportStr), &aiMetric)
-								tb.data <- aiMetric
-							} else {
-								log.Logf("StartServer: default case:%+v...", tmp)
-							}
-						} else {
-							var index int
-							var value net.Conn
-							var found bool
-
-							tb.mutex.Lock()
-							defer tb.mutex.Unlock()
-
-							for index, value = range tb.connections {
-								if value == conn {
-									conn.Close()
-									found = true
-									break
-								}
-							}
-
-							if found {
-								tb.connections = remove(tb.connections, index)
-							}
-
-							return
-						}
-					}
-				}()
-			} else {
+			if err != nil {
 				log.Logf("Telemetry Server accept error %v", err)
 				return
 			}
+
+			tb.mutex.Lock()
+			tb.connections = append(tb.connections, conn)
+			tb.mutex.Unlock()
+			go tb.serve(conn)
 		}
 	}()
 
 	return nil
 }
 
+// serve reads the frames sent on conn and acks each one, until the client disconnects. Clients which
+// send legacy messages are served by serveLegacy.
+func (tb *TelemetryBuffer) serve(conn net.Conn) {
+	defer tb.closeConnection(conn)
+
+	if err := writeFrame(conn, newFrame(messageHello, nil)); err != nil {
+		log.Logf("[Telemetry] hello error, closing connection: %v", err)
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	if first, err := r.Peek(1); err == nil && isLegacyMessage(first[0]) {
+		tb.serveLegacy(r)
+		return
+	}
+	for {
+		f, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Logf("[Telemetry] read error, closing connection: %v", err)
+			}
+			return
+		}
+
+		if err := writeFrame(conn, ackFrame(tb.accept(&f))); err != nil {
+			log.Logf("[Telemetry] ack error, closing connection: %v", err)
+			return
+		}
+	}
+}
+
+// serveLegacy reads the newline delimited JSON messages of an older client, until it disconnects.
+// Such clients don't read acks and can't spool, so reports which don't fit in the queue are dropped.
+func (tb *TelemetryBuffer) serveLegacy(r *bufio.Reader) {
+	for {
+		line, err := r.ReadBytes(legacyDelimiter)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Logf("[Telemetry] read error, closing connection: %v", err)
+			}
+			return
+		}
+
+		typ, err := legacyMessageType(line[:len(line)-1])
+		if err != nil {
+			log.Logf("[Telemetry] rejecting message: %v", err)
+			tb.dropped.Add(1)
+			continue
+		}
+		f := newFrame(typ, line[:len(line)-1])
+		if tb.accept(&f) == ackBusy {
+			tb.dropped.Add(1)
+		}
+	}
+}
+
+// accept queues the report in f, or tells the client to spool it if the queue is full.
+func (tb *TelemetryBuffer) accept(f *frame) ackStatus {
+	if f.version != frameVersion {
+		log.Logf("[Telemetry] unsupported frame version %d", f.version)
+		tb.dropped.Add(1)
+		return ackRejected
+	}
+
+	var report interface{}
+	var err error
+	switch f.typ {
+	case MessageCNIReport:
+		var cniReport CNIReport
+		err = json.Unmarshal(f.payload, &cniReport)
+		report = cniReport
+	case MessageAIMetric:
+		var aiMetric AIMetric
+		err = json.Unmarshal(f.payload, &aiMetric)
+		report = aiMetric
+	case MessageSpoolStats:
+		var stats SpoolStats
+		if err = json.Unmarshal(f.payload, &stats); err == nil {
+			tb.dropped.Add(stats.Dropped)
+			return ackAccepted
+		}
+	default:
+		err = errors.Errorf("unknown message type %d", f.typ)
+	}
+	if err != nil {
+		log.Logf("[Telemetry] rejecting message: %v", err)
+		tb.dropped.Add(1)
+		return ackRejected
+	}
+
+	select {
+	case tb.data <- report:
+		if f.flags&flagReplayed != 0 {
+			tb.spooled.Add(1)
+		}
+		return ackAccepted
+	default:
+		return ackBusy
+	}
+}
+
+func (tb *TelemetryBuffer) closeConnection(conn net.Conn) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	for index, value := range tb.connections {
+		if value == conn {
+			conn.Close()
+			tb.connections = remove(tb.connections, index)
+			return
+		}
+	}
+}
+
+// Stats returns the dropped and spooled counters of the service.
+func (tb *TelemetryBuffer) Stats() BufferStats {
+	return BufferStats{
+		Dropped: tb.dropped.Load(),
+		Spooled: tb.spooled.Load(),
+	}
+}
+
+// Connect connects to the telemetry service and replays the reports spooled while it was unavailable.
+// From then on, the messages the service can't take are spooled, even if it couldn't be reached.
 func (tb *TelemetryBuffer) Connect() error {
+	if tb.spool == nil {
+		tb.spool = newSpool(defaultSpoolDir)
+	}
+
 	err := tb.Dial(FdName)
 	if err == nil {
 		tb.Connected = true
+		tb.legacy = !tb.readHello()
+		if !tb.legacy {
+			tb.replaySpool()
+		}
 	} else if tb.FdExists {
 		tb.Cleanup(FdName)
 	}
@@ -173,12 +292,18 @@ func (tb *TelemetryBuffer) Connect() error {
 func (tb *TelemetryBuffer) PushData(ctx context.Context) {
 	defer tb.Close()
 
+	ticker := time.NewTicker(statsReportInterval)
+	defer ticker.Stop()
+	var reported BufferStats
+
 	for {
 		select {
 		case report := <-tb.data:
 			tb.mutex.Lock()
 			push(report)
 			tb.mutex.Unlock()
+		case <-ticker.C:
+			reported = tb.reportStats(reported)
 		case <-tb.cancel:
 			log.Logf("[Telemetry] server cancel event")
 			return
@@ -189,29 +314,155 @@ func (tb *TelemetryBuffer) PushData(ctx context.Context) {
 	}
 }
 
-// read - read from the file descriptor
-func read(conn net.Conn) (b []byte, err error) {
-	b, err = bufio.NewReader(conn).ReadBytes(Delimiter)
-	if err == nil {
-		b = b[:len(b)-1]
+// reportStats sends the counters which changed since the last report as metrics, and returns the
+// counters reported.
+func (tb *TelemetryBuffer) reportStats(last BufferStats) BufferStats {
+	stats := tb.Stats()
+	for name, delta := range map[string]uint64{
+		TelemetryBufferDroppedStr: stats.Dropped - last.Dropped,
+		TelemetryBufferSpooledStr: stats.Spooled - last.Spooled,
+	} {
+		if delta == 0 {
+			continue
+		}
+		SendAIMetric(AIMetric{Metric: aitelemetry.Metric{
+			Name:             name,
+			Value:            float64(delta),
+			CustomDimensions: make(map[string]string),
+		}})
 	}
 
-	return
+	return stats
 }
 
-// Write - write to the file descriptor.
-func (tb *TelemetryBuffer) Write(b []byte) (c int, err error) {
-	buf := make([]byte, len(b))
-	copy(buf, b)
-	//nolint:makezero //keeping old code
-	buf = append(buf, Delimiter)
-	w := bufio.NewWriter(tb.client)
-	c, err = w.Write(buf)
-	if err == nil {
-		err = w.Flush()
+// readHello reads the hello the service sends when a client connects, and reports whether it got it.
+func (tb *TelemetryBuffer) readHello() bool {
+	if err := tb.client.SetReadDeadline(time.Now().Add(helloTimeout)); err != nil {
+		return false
 	}
+	defer tb.client.SetReadDeadline(time.Time{}) //nolint:errcheck // the next send sets a deadline again
 
-	return
+	f, err := readFrame(tb.client)
+	if err != nil || f.typ != messageHello {
+		log.Logf("[Telemetry] no hello from the telemetry service, sending legacy messages: %v", err)
+		return false
+	}
+	return true
+}
+
+// Send sends payload to the telemetry service as a message of type typ. Messages the service can't
+// take, because it is busy or not running, are spooled and replayed the next time a client connects.
+// Clients which never called Connect don't use the service, their messages are dropped.
+// An error is returned only if the message was neither delivered nor spooled.
+func (tb *TelemetryBuffer) Send(typ MessageType, payload []byte) error {
+	if len(payload) > MaxPayloadSize {
+		return errors.Wrapf(errFrameTooLarge, "message of %d bytes", len(payload))
+	}
+	if tb.spool == nil {
+		return nil
+	}
+
+	f := newFrame(typ, payload)
+	switch {
+	case tb.client == nil:
+	case tb.legacy:
+		// older services only read reports and metrics
+		if typ == MessageSpoolStats {
+			return nil
+		}
+		err := tb.sendLegacy(payload)
+		if err == nil {
+			return nil
+		}
+		log.Logf("[Telemetry] send failed, spooling: %v", err)
+		tb.closeClient()
+	default:
+		status, err := tb.sendFrame(&f)
+		switch {
+		case err != nil:
+			log.Logf("[Telemetry] send failed, spooling: %v", err)
+			tb.closeClient()
+		case status == ackAccepted:
+			return nil
+		case status == ackRejected:
+			return errMessageRejected
+		}
+	}
+
+	return errors.Wrap(tb.spool.add(f), "failed to spool telemetry message")
+}
+
+// sendFrame sends f and waits for the service to ack it.
+func (tb *TelemetryBuffer) sendFrame(f *frame) (ackStatus, error) {
+	tb.sendMutex.Lock()
+	defer tb.sendMutex.Unlock()
+
+	if err := tb.client.SetDeadline(time.Now().Add(ackTimeout)); err != nil {
+		return 0, errors.Wrap(err, "failed to set deadline")
+	}
+	if err := writeFrame(tb.client, *f); err != nil {
+		return 0, err
+	}
+	return readAck(tb.client)
+}
+
+// sendLegacy sends payload as a legacy newline delimited message, which the service doesn't ack.
+func (tb *TelemetryBuffer) sendLegacy(payload []byte) error {
+	tb.sendMutex.Lock()
+	defer tb.sendMutex.Unlock()
+
+	if err := tb.client.SetDeadline(time.Now().Add(ackTimeout)); err != nil {
+		return errors.Wrap(err, "failed to set deadline")
+	}
+	b := make([]byte, 0, len(payload)+1)
+	b = append(b, payload...)
+	_, err := tb.client.Write(append(b, legacyDelimiter))
+	return errors.Wrap(err, "failed to write legacy message")
+}
+
+// replaySpool sends the spooled reports to the service, oldest first, until it is busy.
+func (tb *TelemetryBuffer) replaySpool() {
+	if dropped := tb.spool.takeDropped(); dropped > 0 {
+		payload, _ := json.Marshal(SpoolStats{Dropped: dropped})
+		f := newFrame(MessageSpoolStats, payload)
+		if status, err := tb.sendFrame(&f); err != nil || status != ackAccepted {
+			tb.spool.recordDropped(dropped)
+		}
+	}
+
+	names, err := tb.spool.list()
+	if err != nil {
+		log.Logf("[Telemetry] failed to list spooled reports: %v", err)
+		return
+	}
+
+	for _, name := range names {
+		f, claimed, ok := tb.spool.claim(name)
+		if !ok {
+			continue
+		}
+
+		f.flags |= flagReplayed
+		status, err := tb.sendFrame(&f)
+		if err != nil || status == ackBusy {
+			tb.spool.release(claimed)
+			if err != nil {
+				log.Logf("[Telemetry] replay failed: %v", err)
+				tb.closeClient()
+			}
+			return
+		}
+		os.Remove(claimed)
+	}
+}
+
+func (tb *TelemetryBuffer) closeClient() {
+	if tb.client != nil {
+		tb.client.Close()
+		tb.client = nil
+	}
+	tb.Connected = false
+	tb.legacy = false
 }
 
 // Cancel - signal to tear down telemetry buffer
@@ -14,6 +14,7 @@ const (
 	TelemetryServiceProcessName = "azure-vnet-telemetry"
 	CniInstallDir               = "/opt/cni/bin"
 	metadataFile                = "/tmp/azuremetadata.json"
+	defaultSpoolDir             = "/var/run/azure-vnet-telemetry-spool"
 )
 
 // Dial - try to connect to/create a socket with 'name'
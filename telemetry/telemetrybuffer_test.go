@@ -1,9 +1,14 @@
 package telemetry
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,6 +26,19 @@ func createTBServer(t *testing.T) (*TelemetryBuffer, func()) {
 	}
 }
 
+// newTestClient creates a client spooling to a temporary directory.
+func newTestClient(t *testing.T) *TelemetryBuffer {
+	tbClient := NewTelemetryBuffer()
+	tbClient.spool = newSpool(t.TempDir())
+	return tbClient
+}
+
+func spooledNames(t *testing.T, tb *TelemetryBuffer) []string {
+	names, err := tb.spool.list()
+	require.NoError(t, err)
+	return names
+}
+
 func TestStartServer(t *testing.T) {
 	_, closeTBServer := createTBServer(t)
 	defer closeTBServer()
@@ -45,16 +63,18 @@ func TestServerConnClose(t *testing.T) {
 	tbServer, closeTBServer := createTBServer(t)
 	defer closeTBServer()
 
-	tbClient := NewTelemetryBuffer()
+	tbClient := newTestClient(t)
 	err := tbClient.Connect()
 	require.NoError(t, err)
 	defer tbClient.Close()
 
 	tbServer.Close()
 
-	b := []byte("testdata")
-	_, err = tbClient.Write(b)
-	require.Error(t, err)
+	// the report is spooled rather than lost.
+	err = tbClient.Send(MessageCNIReport, []byte(`{"Name":"test"}`))
+	require.NoError(t, err)
+	require.False(t, tbClient.Connected)
+	require.Len(t, spooledNames(t, tbClient), 1)
 }
 
 func TestClientConnClose(t *testing.T) {
@@ -67,47 +87,237 @@ func TestClientConnClose(t *testing.T) {
 	tbClient.Close()
 }
 
-func TestWrite(t *testing.T) {
-	_, closeTBServer := createTBServer(t)
+func TestSend(t *testing.T) {
+	tbServer, closeTBServer := createTBServer(t)
 	defer closeTBServer()
 
-	tbClient := NewTelemetryBuffer()
+	tbClient := newTestClient(t)
 	err := tbClient.Connect()
 	require.NoError(t, err)
 	defer tbClient.Close()
 
+	report, err := json.Marshal(CNIReport{Name: "test"})
+	require.NoError(t, err)
+	metric, err := json.Marshal(AIMetric{})
+	require.NoError(t, err)
+
 	tests := []struct {
 		name    string
+		typ     MessageType
 		data    []byte
-		want    int
+		want    interface{}
 		wantErr bool
 	}{
 		{
-			name:    "write",
+			name: "cni report",
+			typ:  MessageCNIReport,
+			data: report,
+			want: CNIReport{Name: "test"},
+		},
+		{
+			name: "metric",
+			typ:  MessageAIMetric,
+			data: metric,
+			want: AIMetric{},
+		},
+		{
+			name:    "invalid payload",
+			typ:     MessageCNIReport,
 			data:    []byte("testdata"),
-			want:    len("testdata") + 1, // +1 due to Delimiter('\n)
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name:    "write zero data",
-			data:    []byte(""),
-			want:    1, // +1 due to Delimiter('\n)
-			wantErr: false,
+			name:    "unknown type",
+			typ:     MessageType(100),
+			data:    []byte("{}"),
+			wantErr: true,
+		},
+		{
+			name:    "payload too large",
+			typ:     MessageCNIReport,
+			data:    make([]byte, MaxPayloadSize+1),
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tbClient.Write(tt.data)
+			err := tbClient.Send(tt.typ, tt.data)
 			if tt.wantErr {
 				require.Error(t, err)
 				return
 			}
 			require.NoError(t, err)
-			require.Equal(t, tt.want, got, "Expected:%d but got:%d", tt.want, got)
+			require.Equal(t, tt.want, <-tbServer.data)
 		})
 	}
+
+	require.Equal(t, uint64(2), tbServer.Stats().Dropped)
+	require.Empty(t, spooledNames(t, tbClient))
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	in := newFrame(MessageAIMetric, []byte("payload"))
+	in.flags = flagReplayed
+	require.NoError(t, writeFrame(&buf, in))
+	require.Equal(t, frameHeaderSize+len("payload"), buf.Len())
+
+	out, err := readFrame(&buf)
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+
+	// a newline delimited message of an older client is not mistaken for a frame.
+	_, err = readFrame(bytes.NewReader([]byte(`{"CniSucceeded":true}` + "\n")))
+	require.Error(t, err)
+}
+
+func TestSendUnsupportedVersion(t *testing.T) {
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+
+	tbClient := newTestClient(t)
+	require.NoError(t, tbClient.Connect())
+	defer tbClient.Close()
+
+	f := newFrame(MessageCNIReport, []byte("{}"))
+	f.version = frameVersion + 1
+	status, err := tbClient.sendFrame(&f)
+	require.NoError(t, err)
+	require.Equal(t, ackRejected, status)
+	require.Equal(t, uint64(1), tbServer.Stats().Dropped)
+}
+
+func TestSendSpoolsWhenServiceBusy(t *testing.T) {
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+	// fill the queue, so the service asks the client to spool.
+	for i := 0; i < MaxNumReports; i++ {
+		tbServer.data <- AIMetric{}
+	}
+
+	tbClient := newTestClient(t)
+	require.NoError(t, tbClient.Connect())
+	defer tbClient.Close()
+
+	require.NoError(t, tbClient.Send(MessageCNIReport, []byte(`{"Name":"spooled"}`)))
+	require.True(t, tbClient.Connected)
+	require.Len(t, spooledNames(t, tbClient), 1)
+
+	// once the queue drains, a new client replays the spool on connect.
+	for i := 0; i < MaxNumReports; i++ {
+		<-tbServer.data
+	}
+	replayClient := NewTelemetryBuffer()
+	replayClient.spool = tbClient.spool
+	require.NoError(t, replayClient.Connect())
+	defer replayClient.Close()
+
+	require.Equal(t, CNIReport{Name: "spooled"}, <-tbServer.data)
+	require.Empty(t, spooledNames(t, replayClient))
+	require.Equal(t, BufferStats{Spooled: 1}, tbServer.Stats())
+}
+
+func TestSendSpoolsWhenServiceDown(t *testing.T) {
+	tbClient := newTestClient(t)
+	tbClient.spool.maxFiles = 2
+
+	for i := 0; i < 3; i++ {
+		err := tbClient.Send(MessageAIMetric, []byte("{}"))
+		if i < 2 {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, errSpoolFull)
+		}
+	}
+	require.Len(t, spooledNames(t, tbClient), 2)
+
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+	require.NoError(t, tbClient.Connect())
+	defer tbClient.Close()
+
+	require.Equal(t, AIMetric{}, <-tbServer.data)
+	require.Equal(t, AIMetric{}, <-tbServer.data)
+	require.Empty(t, spooledNames(t, tbClient))
+	// the report dropped by the full spool is counted by the service.
+	require.Equal(t, BufferStats{Dropped: 1, Spooled: 2}, tbServer.Stats())
+}
+
+func TestSendWithoutConnect(t *testing.T) {
+	// a client which never connected doesn't use the service, so it neither sends nor spools.
+	var tbClient TelemetryBuffer
+	require.NoError(t, tbClient.Send(MessageCNIReport, []byte(`{"Name":"test"}`)))
+	require.Nil(t, tbClient.spool)
+}
+
+func TestServeLegacyClient(t *testing.T) {
+	tbServer, closeTBServer := createTBServer(t)
+	defer closeTBServer()
+
+	// an older client writes newline delimited JSON and never reads the hello or acks.
+	var legacyClient TelemetryBuffer
+	require.NoError(t, legacyClient.Dial(FdName))
+	defer legacyClient.Close()
+	_, err := legacyClient.client.Write([]byte(`{"Name":"legacy","CniSucceeded":true}` + "\n" + `{"Name":"unknown"}` + "\n" + `{"Metric":{"Name":"metric"}}` + "\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, CNIReport{Name: "legacy", CniSucceeded: true}, <-tbServer.data)
+	require.Equal(t, AIMetric{Metric: aitelemetry.Metric{Name: "metric"}}, <-tbServer.data)
+	require.Equal(t, uint64(1), tbServer.Stats().Dropped)
+}
+
+func TestSendToLegacyService(t *testing.T) {
+	// an older service reads newline delimited JSON and sends nothing back.
+	var legacyServer TelemetryBuffer
+	require.NoError(t, legacyServer.Listen(FdName))
+	defer legacyServer.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := legacyServer.listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	tbClient := newTestClient(t)
+	require.NoError(t, tbClient.Connect())
+	defer tbClient.Close()
+	require.True(t, tbClient.legacy)
+
+	require.NoError(t, tbClient.Send(MessageCNIReport, []byte(`{"Name":"test"}`)))
+	require.Equal(t, `{"Name":"test"}`+"\n", <-lines)
+	require.Empty(t, spooledNames(t, tbClient))
+}
+
+func TestSpoolStaleClaim(t *testing.T) {
+	s := newSpool(t.TempDir())
+	require.NoError(t, s.add(newFrame(MessageAIMetric, []byte("{}"))))
+	names, err := s.list()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+
+	f, claimed, ok := s.claim(names[0])
+	require.True(t, ok)
+	require.Equal(t, MessageAIMetric, f.typ)
+
+	// a fresh claim belongs to the client replaying it.
+	names, err = s.list()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	// a claim left by a client which exited is taken over.
+	old := time.Now().Add(-2 * staleClaimAge)
+	require.NoError(t, os.Chtimes(claimed, old, old))
+	names, err = s.list()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+	_, _, ok = s.claim(names[0])
+	require.True(t, ok)
 }
 
 func TestReadConfigFile(t *testing.T) {
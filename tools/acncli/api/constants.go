@@ -29,6 +29,34 @@ const (
 	FlagFollow      = "follow"
 	FlagLogFilePath = "log-file"
 
+	// CNI GC Flags
+	FlagDryRun      = "dry-run"
+	FlagDeleteLinks = "delete-links"
+
+	// CNI Conflist Flags
+	FlagScenario     = "scenario"
+	FlagOutput       = "output"
+	FlagSkipCNSCheck = "skip-cns-check"
+
+	// Collect Flags
+	FlagNPMUrl       = "npmurl"
+	FlagLogFiles     = "log-files"
+	FlagLogTailBytes = "log-tail-bytes"
+
+	// State Flags
+	FlagStateFile        = "state-file"
+	FlagCNSEndpointState = "cns-endpoint-state"
+	FlagBackupDir        = "backup-dir"
+	FlagConflist         = "conflist"
+
+	// conflist scenarios
+	ScenarioDefault         = "default"
+	ScenarioSwift           = "swift"
+	ScenarioOverlay         = "overlay"
+	ScenarioMultitenancy    = "multitenancy"
+	ScenarioTransparentVlan = "transparent-vlan"
+	ScenarioAzureIPAM       = "azure-ipam"
+
 	// tenancy flags
 	Singletenancy = "singletenancy"
 	Multitenancy  = "multitenancy"
@@ -61,14 +89,27 @@ const (
 	AzureTelemetryConfig = "azure-vnet-telemetry.config"
 	AzureCNSIPAM         = "azure-cns"
 	AzureVNETIPAM        = "azure-vnet-ipam"
+	AzureIPAM            = "azure-ipam"
+	CiliumCNIBin         = "cilium-cni"
+	PortmapBin           = "portmap"
 	ConflistExtension    = ".conflist"
 
 	DefaultSrcDirLinux      = "/output/"
 	DefaultBinDirLinux      = "/opt/cni/bin/"
 	DefaultConflistDirLinux = "/etc/cni/net.d/"
+	DefaultConflist         = DefaultConflistDirLinux + "10-azure.conflist"
 	DefaultLogFile          = "/var/log/azure-vnet.log"
+	DefaultIPAMLogFile      = "/var/log/azure-vnet-ipam.log"
+	DefaultCNSLogFile       = "/var/log/azure-cns.log"
+	DefaultNPMUrl           = "http://localhost:10091"
+	DefaultLogTailBytes     = 10 << 20
+	DefaultCNSEndpointState = "/var/run/azure-cns/azure-endpoints.json"
 	Transparent             = "transparent"
 	Bridge                  = "bridge"
+	Tunnel                  = "tunnel"
+	TransparentVlan         = "transparent-vlan"
+	IPVlan                  = "ipvlan"
+	IPVlanL3S               = "ipvlan-l3s"
 	Azure0                  = "azure0"
 
 	// Multitenancy defaults
@@ -103,6 +144,7 @@ var (
 		FlagLogFilePath:                DefaultLogFile,
 		FlagCNSUrl:                     DefaultCNSUrl,
 		FlagEnableExactMatchForPodName: DefaultEnableExactMatchForPodName,
+		FlagScenario:                   ScenarioDefault,
 		EnvCNILogFile:                  EnvCNILogFile,
 		EnvCNISourceDir:                DefaultSrcDirLinux,
 		EnvCNIDestinationBinDir:        DefaultBinDirLinux,
@@ -110,7 +152,10 @@ var (
 	}
 
 	DefaultToggles = map[string]bool{
-		FlagFollow: false,
+		FlagFollow:       false,
+		FlagDryRun:       false,
+		FlagDeleteLinks:  false,
+		FlagSkipCNSCheck: false,
 	}
 )
 
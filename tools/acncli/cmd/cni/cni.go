@@ -23,5 +23,7 @@ func CNICmd() *cobra.Command {
 	cmd.AddCommand(InstallCmd())
 	cmd.AddCommand(LogsCmd())
 	cmd.AddCommand(ManagerCmd())
+	cmd.AddCommand(GCCmd())
+	cmd.AddCommand(ConflistCmd())
 	return cmd
 }
@@ -0,0 +1,101 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package cni
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	i "github.com/Azure/azure-container-networking/tools/acncli/installer"
+	"github.com/spf13/cobra"
+)
+
+// ConflistCmd groups the commands which build and check conflists
+func ConflistCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "conflist",
+		Short: "Generates and validates Azure CNI conflists",
+	}
+	cmd.AddCommand(GenerateConflistCmd())
+	cmd.AddCommand(ValidateConflistCmd())
+	return cmd
+}
+
+func GenerateConflistCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generates the conflist of a scenario",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// flags are read from the command rather than viper, as install binds flags of the same name.
+			conf := i.GenerateConfig{}
+			conf.Scenario, _ = cmd.Flags().GetString(c.FlagScenario)
+			conf.OS, _ = cmd.Flags().GetString(c.FlagOS)
+			conf.CNSURL, _ = cmd.Flags().GetString(c.FlagCNSUrl)
+			conf.EnableExactMatchForPodName, _ = cmd.Flags().GetBool(c.FlagEnableExactMatchForPodName)
+			output, _ := cmd.Flags().GetString(c.FlagOutput)
+
+			conflist, err := i.GenerateConflist(conf)
+			if err != nil {
+				return err
+			}
+			filebytes, err := json.MarshalIndent(conflist, "", "\t")
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				fmt.Println(string(filebytes))
+				return nil
+			}
+			fmt.Printf("🚛 - Writing %s conflist to %v...\n", conf.Scenario, output)
+			return os.WriteFile(output, filebytes, 0o644) //nolint:gomnd // conflists are world readable
+		},
+	}
+
+	cmd.Flags().String(c.FlagScenario, c.Defaults[c.FlagScenario], fmt.Sprintf("Scenario of the conflist, options are %s", strings.Join(i.Scenarios, ", ")))
+	cmd.Flags().String(c.FlagOS, c.Defaults[c.FlagOS], fmt.Sprintf("Operating system of the conflist, options are %s and %s", c.Linux, c.Windows))
+	cmd.Flags().String(c.FlagCNSUrl, "", "CNS URL if multitenancy, the CNI default is used when unset")
+	cmd.Flags().Bool(c.FlagEnableExactMatchForPodName, false, "Enable exact match for pod name if multitenancy")
+	cmd.Flags().StringP(c.FlagOutput, "o", "", "File to write the conflist to, it is printed when unset")
+
+	return cmd
+}
+
+func ValidateConflistCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate <conflist>",
+		Short: "Checks a conflist would be accepted by the CNI",
+		Long: "The validate command parses a conflist the way the CNI does, checks its mode, ipam and executionMode " +
+			"are supported together, and that CNS is reachable when the conflist needs it",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			osType, _ := cmd.Flags().GetString(c.FlagOS)
+			skipCNSCheck, _ := cmd.Flags().GetBool(c.FlagSkipCNSCheck)
+
+			b, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			problems, err := i.ValidateConflist(b, i.ValidateConfig{OS: osType, CheckCNS: !skipCNSCheck})
+			if err != nil {
+				return err
+			}
+			for _, p := range problems {
+				fmt.Printf("❌ - %s\n", p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%s has %d problem(s)", args[0], len(problems))
+			}
+			fmt.Printf("✅ - %s is valid\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().String(c.FlagOS, c.Defaults[c.FlagOS], fmt.Sprintf("Operating system the conflist is for, options are %s and %s", c.Linux, c.Windows))
+	cmd.Flags().Bool(c.FlagSkipCNSCheck, c.DefaultToggles[c.FlagSkipCNSCheck], "Skip checking CNS is reachable, e.g. when validating off the node")
+
+	return cmd
+}
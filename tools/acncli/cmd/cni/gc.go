@@ -0,0 +1,75 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package cni
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/cni/client"
+	"github.com/Azure/azure-container-networking/network/rulegc"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/utils/exec"
+)
+
+// GCCmd removes the iptables rules and ebtables chains of endpoints which are not in the CNI state, and lists
+// their host links and namespaces, which it only removes with --delete-links
+func GCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: fmt.Sprintf("Removes the rules of endpoints which are not in the %s state", c.AzureCNIBin),
+		Long: "The gc command lists the iptables rules and ebtables chains marked with an endpoint ID, " +
+			"and removes those of endpoints which no longer exist, e.g. after a failed delete. " +
+			"It then has the CNI list the host veths, vlan links and namespaces which belong to none of its endpoints, " +
+			"and remove them with --delete-links. Links of running pods look orphaned when the state is lost, so check the list first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun := viper.GetBool(c.FlagDryRun)
+			verb := "removed"
+			if dryRun {
+				verb = "would remove"
+			}
+
+			result, err := rulegc.New().Run(rulegc.CNIEndpointIDs(exec.New()), dryRun)
+			if result != nil {
+				for _, rule := range result.IPTablesRules {
+					fmt.Printf("🧹 - %s iptables rule of endpoint %s: -t %s -A %s %s\n", verb, rule.EndpointID, rule.Table, rule.Chain, rule.RuleSpec)
+				}
+				for _, chain := range result.EbtablesChains {
+					fmt.Printf("🧹 - %s ebtables chain %s\n", verb, chain)
+				}
+				fmt.Printf("✅ - %d iptables rules and %d ebtables chains %s\n", len(result.IPTablesRules), len(result.EbtablesChains), verb)
+			}
+			if err != nil {
+				return err
+			}
+
+			deleteLinks := viper.GetBool(c.FlagDeleteLinks) && !dryRun
+			linkVerb := "found"
+			if deleteLinks {
+				linkVerb = "removed"
+			}
+			report, err := client.New(exec.New()).ReconcileOrphans(deleteLinks)
+			if err != nil {
+				return err //nolint:wrapcheck // the client error names the CNI command
+			}
+			for _, link := range report.Links {
+				fmt.Printf("🧹 - %s orphaned link %s\n", linkVerb, link)
+			}
+			for _, route := range report.Routes {
+				fmt.Printf("🧹 - %s orphaned route %s\n", linkVerb, route)
+			}
+			for _, ns := range report.Namespaces {
+				fmt.Printf("🧹 - %s orphaned namespace %s\n", linkVerb, ns)
+			}
+			fmt.Printf("✅ - %d links and %d namespaces %s\n", len(report.Links), len(report.Namespaces), linkVerb)
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool(c.FlagDryRun, c.DefaultToggles[c.FlagDryRun], "List the orphaned rules without removing them")
+	cmd.Flags().Bool(c.FlagDeleteLinks, c.DefaultToggles[c.FlagDeleteLinks], "Also remove the orphaned host links and namespaces, which are only listed otherwise")
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/Azure/azure-container-networking/tools/acncli/collect"
+	"github.com/spf13/cobra"
+	"k8s.io/utils/exec"
+)
+
+// CollectCmd gathers the network state of the node in to a support bundle
+func CollectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collect",
+		Short: "Collects the network state of the node in to a support bundle",
+		Long: "The collect command gathers the CNI state, CNS and NPM state, the iptables, ebtables and ipset rules, " +
+			"links, routes and log tails in to a gzipped tarball, with secrets redacted and a manifest of what was collected",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := collect.Options{Exec: exec.New()}
+			opts.CNSURL, _ = cmd.Flags().GetString(c.FlagCNSUrl)
+			opts.NPMURL, _ = cmd.Flags().GetString(c.FlagNPMUrl)
+			opts.LogFiles, _ = cmd.Flags().GetStringSlice(c.FlagLogFiles)
+			opts.LogTailBytes, _ = cmd.Flags().GetInt64(c.FlagLogTailBytes)
+			output, _ := cmd.Flags().GetString(c.FlagOutput)
+			if output == "" {
+				hostname, _ := os.Hostname()
+				output = fmt.Sprintf("acn-bundle-%s-%s.tar.gz", hostname, time.Now().UTC().Format("20060102T150405Z"))
+			}
+
+			collectors, err := collect.Defaults(opts)
+			if err != nil {
+				return err
+			}
+			f, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600) //nolint:gomnd // the bundle may hold node secrets we failed to redact
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			fmt.Printf("📦 - Collecting to %s...\n", output)
+			manifest, err := collect.Write(context.Background(), f, collectors)
+			if err != nil {
+				return err
+			}
+			for _, r := range manifest.Collectors {
+				if r.Error != "" {
+					fmt.Printf("⚠️ - %s: collected %d files, %s\n", r.Name, len(r.Files), r.Error)
+					continue
+				}
+				fmt.Printf("✅ - %s: collected %d files\n", r.Name, len(r.Files))
+			}
+			return f.Close()
+		},
+	}
+
+	cmd.Flags().StringP(c.FlagOutput, "o", "", "Path of the bundle, named after the node and time when unset")
+	cmd.Flags().String(c.FlagCNSUrl, c.DefaultCNSUrl, "URL of CNS")
+	cmd.Flags().String(c.FlagNPMUrl, c.DefaultNPMUrl, "URL of NPM")
+	cmd.Flags().StringSlice(c.FlagLogFiles, []string{c.DefaultLogFile, c.DefaultIPAMLogFile, c.DefaultCNSLogFile}, "Log files to collect the tails of")
+	cmd.Flags().Int64(c.FlagLogTailBytes, c.DefaultLogTailBytes, "Bytes collected from the end of each log file")
+
+	return cmd
+}
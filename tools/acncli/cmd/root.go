@@ -40,6 +40,8 @@ func NewRootCmd(version string) *cobra.Command {
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(cni.CNICmd())
+	rootCmd.AddCommand(CollectCmd())
+	rootCmd.AddCommand(StateCmd())
 	rootCmd.AddCommand(npm.NPMRootCmd())
 	rootCmd.SetVersionTemplate(version)
 	return rootCmd
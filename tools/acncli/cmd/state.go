@@ -0,0 +1,288 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	cnsclient "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/network"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/Azure/azure-container-networking/store"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/Azure/azure-container-networking/tools/acncli/installer"
+	"github.com/Azure/azure-container-networking/tools/acncli/state"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const cnsRequestTimeout = 10 * time.Second
+
+// StateCmd inspects and repairs the CNI and CNS state of the node
+func StateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspects and repairs the CNI and CNS state of the node",
+		Long: "The state command loads the CNI state the way the CNI does, lists its networks and endpoints, " +
+			"cross-checks them with the IPs CNS has assigned, and edits it with a backup and a dry-run diff",
+	}
+	cmd.PersistentFlags().String(c.FlagStateFile, platform.CNIStateFilePath, "Path of the CNI state file")
+
+	cmd.AddCommand(stateShowCmd())
+	cmd.AddCommand(stateCheckCmd())
+	cmd.AddCommand(stateRemoveEndpointCmd())
+	cmd.AddCommand(stateReleaseIPCmd())
+	return cmd
+}
+
+// openCNIState opens the CNI state file with the lock the CNI takes, so the state isn't changed under
+// a running CNI command, or a copy of it with a lock next to it.
+func openCNIState(cmd *cobra.Command) (string, store.KeyValueStore, error) {
+	path, _ := cmd.Flags().GetString(c.FlagStateFile)
+	lockPath := path + store.LockExtension
+	if path == platform.CNIStateFilePath {
+		lockPath = platform.CNILockPath + c.AzureCNIBin + store.LockExtension
+	}
+	st, err := state.OpenStore(path, lockPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := st.Lock(store.DefaultLockTimeout); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to lock %s, is a CNI command running?", path)
+	}
+	return path, st, nil
+}
+
+func loadCNIState(cmd *cobra.Command) (*network.State, error) {
+	_, st, err := openCNIState(cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Unlock() //nolint:errcheck // the state is only read
+	return network.LoadState(st)
+}
+
+func formatIPs(ips []net.IPNet) string {
+	s := make([]string, len(ips))
+	for i := range ips {
+		s[i] = ips[i].String()
+	}
+	return strings.Join(s, ",")
+}
+
+func stateShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Lists the networks, endpoints and IPs in the CNI state, and the CNS endpoint state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cniState, err := loadCNIState(cmd)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // column padding
+			for _, nw := range cniState.Networks() {
+				fmt.Fprintf(w, "🌐 - network %s\tmode %s\tinterface %s\t%d endpoints\n", nw.ID, nw.Mode, nw.ExternalInterface, len(nw.Endpoints))
+				for _, ep := range nw.Endpoints {
+					fmt.Fprintf(w, "\t%s\t%s/%s\t%s\t%s\n", ep.ID, ep.PodNamespace, ep.PodName, formatIPs(ep.IPAddresses), ep.HostIfName)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			cnsPath, _ := cmd.Flags().GetString(c.FlagCNSEndpointState)
+			if _, err := os.Stat(cnsPath); os.IsNotExist(err) {
+				fmt.Printf("ℹ️ - no CNS endpoint state at %s, CNS doesn't manage the endpoint state\n", cnsPath)
+				return nil
+			}
+			st, err := state.OpenStore(cnsPath, platform.CNILockPath+strings.TrimSuffix(filepath.Base(cnsPath), ".json")+store.LockExtension)
+			if err != nil {
+				return err
+			}
+			endpoints, err := state.ReadCNSEndpoints(st)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("📒 - CNS endpoint state %s, %d endpoints\n", cnsPath, len(endpoints))
+			for containerID, ep := range endpoints {
+				for ifName, ips := range ep.IfnameToIPMap {
+					fmt.Fprintf(w, "\t%s\t%s/%s\t%s\t%s\n", containerID, ep.PodNamespace, ep.PodName, ifName, formatIPs(append(ips.IPv4, ips.IPv6...)))
+				}
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().String(c.FlagCNSEndpointState, c.DefaultCNSEndpointState, "Path of the CNS endpoint state file")
+	return cmd
+}
+
+func assignedIPs(cmd *cobra.Command) ([]cns.IPConfigurationStatus, error) {
+	url, _ := cmd.Flags().GetString(c.FlagCNSUrl)
+	client, err := cnsclient.New(url, cnsRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := client.GetIPAddressesMatchingStates(context.Background(), types.Assigned)
+	return ips, errors.Wrap(err, "failed to get the assigned IPs from CNS")
+}
+
+func stateCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Cross-checks the CNI endpoints with the IPs CNS has assigned",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cniState, err := loadCNIState(cmd)
+			if err != nil {
+				return err
+			}
+			conflist, _ := cmd.Flags().GetString(c.FlagConflist)
+			nwCfg, _, _, err := installer.LoadConf(conflist)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read the network config from %s", conflist)
+			}
+			cnsNetworks := map[string]bool{}
+			if nwCfg.Ipam.Type == c.AzureCNSIPAM {
+				cnsNetworks[nwCfg.Name] = true
+			} else {
+				fmt.Printf("ℹ️ - network %s uses %s, its IPs are not assigned by CNS and are not checked\n", nwCfg.Name, nwCfg.Ipam.Type)
+			}
+			assigned, err := assignedIPs(cmd)
+			if err != nil {
+				return err
+			}
+
+			mismatches := state.CrossCheck(cniState.Networks(), assigned, cnsNetworks)
+			for _, m := range mismatches {
+				switch m.Kind {
+				case state.NoEndpoint:
+					fmt.Printf("❌ - %s: %s, assigned to %s, release it with 'acncli state release-ip %s'\n", m.IP, m.Kind, m.CNSPod, m.IP)
+				case state.NotAssignedInCNS:
+					fmt.Printf("❌ - %s: endpoint %s of %s is %s, remove it with 'acncli state remove-endpoint %s %s'\n",
+						m.IP, m.EndpointID, m.Pod, m.Kind, m.NetworkID, m.EndpointID)
+				default:
+					fmt.Printf("❌ - %s: endpoint %s of %s is %s, %s\n", m.IP, m.EndpointID, m.Pod, m.Kind, m.CNSPod)
+				}
+			}
+			if len(mismatches) > 0 {
+				return fmt.Errorf("%d mismatches between the CNI state and CNS", len(mismatches))
+			}
+			fmt.Printf("✅ - the CNI state matches the %d IPs assigned by CNS\n", len(assigned))
+			return nil
+		},
+	}
+	cmd.Flags().String(c.FlagCNSUrl, c.DefaultCNSUrl, "URL of CNS")
+	cmd.Flags().String(c.FlagConflist, c.DefaultConflist, "Path of the conflist, to skip the networks which don't get their IPs from CNS")
+	return cmd
+}
+
+func stateRemoveEndpointCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove-endpoint <network> <endpoint>",
+		Short: "Removes an endpoint from the CNI state, the host links and rules are left to 'acncli cni gc'",
+		Args:  cobra.ExactArgs(2), //nolint:gomnd // network and endpoint
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool(c.FlagDryRun)
+			path, st, err := openCNIState(cmd)
+			if err != nil {
+				return err
+			}
+			defer st.Unlock() //nolint:errcheck // best effort, the lock is released on exit
+
+			diff, backup, err := state.Edit(path, dryRun, func(s *network.State) error {
+				return s.RemoveEndpoint(args[0], args[1])
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Print(diff)
+			if dryRun {
+				fmt.Printf("🔍 - dry run, %s is unchanged\n", path)
+				return nil
+			}
+			fmt.Printf("✅ - removed endpoint %s from %s, the previous state is in %s\n", args[1], path, backup)
+			return nil
+		},
+	}
+	cmd.Flags().Bool(c.FlagDryRun, c.DefaultToggles[c.FlagDryRun], "Print the change without making it")
+	return cmd
+}
+
+func stateReleaseIPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release-ip <ip>",
+		Short: "Releases an IP CNS has assigned to a pod, e.g. one leaked by a failed delete",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool(c.FlagDryRun)
+			backupDir, _ := cmd.Flags().GetString(c.FlagBackupDir)
+
+			assigned, err := assignedIPs(cmd)
+			if err != nil {
+				return err
+			}
+			var ip *cns.IPConfigurationStatus
+			for i := range assigned {
+				if assigned[i].IPAddress == args[0] {
+					ip = &assigned[i]
+				}
+			}
+			if ip == nil || ip.PodInfo == nil {
+				return fmt.Errorf("%s is not assigned to a pod by CNS", args[0])
+			}
+			orchestratorContext, err := ip.PodInfo.OrchestratorContext()
+			if err != nil {
+				return err
+			}
+			// name the IP, the pod may have been assigned another one since this one leaked
+			req := cns.IPConfigRequest{
+				DesiredIPAddress:    ip.IPAddress,
+				PodInterfaceID:      ip.PodInfo.InterfaceID(),
+				InfraContainerID:    ip.PodInfo.InfraContainerID(),
+				OrchestratorContext: orchestratorContext,
+			}
+
+			fmt.Printf("- %s\t%s\tassigned to %s/%s\tinterface %s\n", ip.IPAddress, ip.NCID, ip.PodInfo.Namespace(), ip.PodInfo.Name(), req.PodInterfaceID)
+			if dryRun {
+				fmt.Println("🔍 - dry run, the IP is not released")
+				return nil
+			}
+
+			// keep the assignment, so the release can be traced back and the pod info is not lost.
+			b, err := json.MarshalIndent(ip, "", "  ")
+			if err != nil {
+				return err
+			}
+			backup := filepath.Join(backupDir, fmt.Sprintf("cns-release-%s-%s.json", ip.IPAddress, time.Now().UTC().Format("20060102T150405Z")))
+			if err := os.WriteFile(backup, b, 0o600); err != nil { //nolint:gomnd // owner only
+				return err
+			}
+
+			url, _ := cmd.Flags().GetString(c.FlagCNSUrl)
+			client, err := cnsclient.New(url, cnsRequestTimeout)
+			if err != nil {
+				return err
+			}
+			if err := client.ReleaseIPAddress(context.Background(), req); err != nil {
+				return errors.Wrapf(err, "failed to release %s", ip.IPAddress)
+			}
+			fmt.Printf("✅ - released %s, the assignment is in %s\n", ip.IPAddress, backup)
+			return nil
+		},
+	}
+	cmd.Flags().String(c.FlagCNSUrl, c.DefaultCNSUrl, "URL of CNS")
+	cmd.Flags().Bool(c.FlagDryRun, c.DefaultToggles[c.FlagDryRun], "Print the IP to release without releasing it")
+	cmd.Flags().String(c.FlagBackupDir, ".", "Directory the released assignment is saved in")
+	return cmd
+}
@@ -0,0 +1,108 @@
+// Package collect gathers the network state of a node in to a support bundle.
+package collect
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ManifestName is the name of the manifest at the root of the bundle.
+const ManifestName = "manifest.json"
+
+// File is a file gathered by a collector.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Collector gathers one part of the node state. It returns the files it gathered even when it fails,
+// so a bundle holds as much of the state as could be read.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context) ([]File, error)
+}
+
+// Manifest lists what each collector added to the bundle, and why it failed if it did.
+type Manifest struct {
+	Created    time.Time `json:"created"`
+	Hostname   string    `json:"hostname"`
+	Collectors []Result  `json:"collectors"`
+}
+
+// Result is the outcome of a collector.
+type Result struct {
+	Name     string  `json:"name"`
+	Files    []Entry `json:"files,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration string  `json:"duration"`
+}
+
+// Entry is a file in the bundle.
+type Entry struct {
+	Path       string `json:"path"`
+	Size       int    `json:"size"`
+	Redactions int    `json:"redactions,omitempty"`
+}
+
+// Write runs the collectors in order and writes the files they gather, with secrets redacted, to w as
+// a gzipped tarball. Each collector's files are under a directory named after it. A failing collector
+// is recorded in the manifest and doesn't stop the others, only failing to write the bundle is an error.
+func Write(ctx context.Context, w io.Writer, collectors []Collector) (*Manifest, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	hostname, _ := os.Hostname()
+	manifest := &Manifest{Created: time.Now().UTC(), Hostname: hostname}
+	for _, c := range collectors {
+		start := time.Now()
+		files, err := c.Collect(ctx)
+		result := Result{Name: c.Name()}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		for _, f := range files {
+			data, redactions := Redact(f.Data)
+			entry := Entry{Path: path.Join(c.Name(), f.Name), Size: len(data), Redactions: redactions}
+			if err := writeFile(tw, entry.Path, data, manifest.Created); err != nil {
+				return nil, err
+			}
+			result.Files = append(result.Files, entry)
+		}
+		result.Duration = time.Since(start).String()
+		manifest.Collectors = append(manifest.Collectors, result)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode manifest")
+	}
+	if err := writeFile(tw, ManifestName, b, manifest.Created); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close tarball")
+	}
+	return manifest, errors.Wrap(gz.Close(), "failed to close gzip stream")
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644, //nolint:gomnd // bundle files are world readable
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.Wrapf(err, "failed to write header of %s", name)
+	}
+	_, err := tw.Write(data)
+	return errors.Wrapf(err, "failed to write %s", name)
+}
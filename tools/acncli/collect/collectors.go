@@ -0,0 +1,173 @@
+package collect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"k8s.io/utils/exec"
+)
+
+// joinErrors returns an error listing errs, or nil if there are none.
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// FileCollector gathers files from the node, or the last TailBytes of them when it is set.
+type FileCollector struct {
+	CollectorName string
+	Paths         []string
+	TailBytes     int64
+}
+
+func (c *FileCollector) Name() string { return c.CollectorName }
+
+func (c *FileCollector) Collect(context.Context) ([]File, error) {
+	var (
+		files []File
+		errs  []string
+	)
+	for _, p := range c.Paths {
+		data, err := c.read(p)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		files = append(files, File{Name: filepath.Base(p), Data: data})
+	}
+	return files, joinErrors(errs)
+}
+
+func (c *FileCollector) read(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", p)
+	}
+	defer f.Close()
+
+	if c.TailBytes > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to stat %s", p)
+		}
+		if offset := info.Size() - c.TailBytes; offset > 0 {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				return nil, errors.Wrapf(err, "failed to seek %s", p)
+			}
+		}
+	}
+	data, err := io.ReadAll(f)
+	return data, errors.Wrapf(err, "failed to read %s", p)
+}
+
+// CommandCollector gathers the output of commands, each in a file named after the command line.
+type CommandCollector struct {
+	CollectorName string
+	Exec          exec.Interface
+	Commands      [][]string
+}
+
+func (c *CommandCollector) Name() string { return c.CollectorName }
+
+func (c *CommandCollector) Collect(ctx context.Context) ([]File, error) {
+	var (
+		files []File
+		errs  []string
+	)
+	for _, command := range c.Commands {
+		out, err := c.Exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			// keep what the command printed, it usually says why it failed.
+			errs = append(errs, fmt.Sprintf("%s: %v", strings.Join(command, " "), err))
+		}
+		if len(out) > 0 || err == nil {
+			files = append(files, File{Name: strings.Join(command, "_") + ".txt", Data: out})
+		}
+	}
+	return files, joinErrors(errs)
+}
+
+// CNSClient is the part of the CNS client used to gather CNS state.
+type CNSClient interface {
+	GetHTTPServiceData(ctx context.Context) (*restserver.GetHTTPServiceDataResponse, error)
+	GetIPAddressesMatchingStates(ctx context.Context, stateFilter ...types.IPState) ([]cns.IPConfigurationStatus, error)
+}
+
+// CNSCollector gathers the in-memory state of CNS and its IP addresses in every state.
+type CNSCollector struct {
+	Client CNSClient
+}
+
+func (c *CNSCollector) Name() string { return "cns" }
+
+func (c *CNSCollector) Collect(ctx context.Context) ([]File, error) {
+	var (
+		files []File
+		errs  []string
+	)
+	add := func(name string, v interface{}, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		files = append(files, File{Name: name, Data: b})
+	}
+
+	data, err := c.Client.GetHTTPServiceData(ctx)
+	add("restdata.json", data, err)
+	ips, err := c.Client.GetIPAddressesMatchingStates(ctx, types.Available, types.Assigned, types.PendingRelease, types.PendingProgramming)
+	add("ipaddresses.json", ips, err)
+	return files, joinErrors(errs)
+}
+
+// HTTPCollector gathers the response to a GET of URL, e.g. a debug endpoint.
+type HTTPCollector struct {
+	CollectorName string
+	URL           string
+	FileName      string
+	Client        *http.Client
+}
+
+func (c *HTTPCollector) Name() string { return c.CollectorName }
+
+func (c *HTTPCollector) Collect(ctx context.Context) ([]File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+	return []File{{Name: c.FileName, Data: b}}, nil
+}
@@ -0,0 +1,72 @@
+package collect
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/client"
+	npmapi "github.com/Azure/azure-container-networking/npm/http/api"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	"k8s.io/utils/exec"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Options configure the default collectors.
+type Options struct {
+	CNSURL       string
+	NPMURL       string
+	LogFiles     []string
+	LogTailBytes int64
+	Exec         exec.Interface
+}
+
+// Defaults returns the collectors of a node support bundle: the CNI state, CNS and NPM state, the
+// iptables, ebtables and ipset rules, links and routes, and the tails of the logs.
+func Defaults(opts Options) ([]Collector, error) {
+	cnsClient, err := client.New(opts.CNSURL, requestTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create CNS client")
+	}
+	return []Collector{
+		&FileCollector{
+			CollectorName: "cni",
+			Paths:         []string{platform.CNIStateFilePath, platform.CNIIpamStatePath},
+		},
+		&CNSCollector{Client: cnsClient},
+		&HTTPCollector{
+			CollectorName: "npm",
+			URL:           opts.NPMURL + npmapi.NPMMgrPath,
+			FileName:      "manager.json",
+			Client:        &http.Client{Timeout: requestTimeout},
+		},
+		&CommandCollector{
+			CollectorName: "rules",
+			Exec:          opts.Exec,
+			Commands: [][]string{
+				{"iptables-save"},
+				{"ip6tables-save"},
+				{"ebtables", "-t", "filter", "-L", "--Lc"},
+				{"ebtables", "-t", "nat", "-L", "--Lc"},
+				{"ipset", "save"},
+			},
+		},
+		&CommandCollector{
+			CollectorName: "links",
+			Exec:          opts.Exec,
+			Commands: [][]string{
+				{"ip", "-d", "link"},
+				{"ip", "-d", "addr"},
+				{"ip", "route", "show", "table", "all"},
+				{"ip", "rule"},
+				{"ip", "netns", "list"},
+			},
+		},
+		&FileCollector{
+			CollectorName: "logs",
+			Paths:         opts.LogFiles,
+			TailBytes:     opts.LogTailBytes,
+		},
+	}, nil
+}
@@ -0,0 +1,38 @@
+package collect
+
+import "regexp"
+
+const redacted = "REDACTED"
+
+var redactions = []struct {
+	re   *regexp.Regexp
+	repl []byte
+}{
+	// JSON values of keys naming a secret, e.g. "password": "..." or "InstrumentationKey": "...".
+	{
+		re:   regexp.MustCompile(`(?i)("[^"]*(?:password|passwd|secret|token|credential|connectionstring|privatekey|apikey|instrumentationkey|authorization)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`),
+		repl: []byte(`${1}"` + redacted + `"`),
+	},
+	// credentials in logged headers.
+	{
+		re:   regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9\-._~+/]+=*`),
+		repl: []byte(`${1} ` + redacted),
+	},
+	// secrets in logged query strings and key=value pairs, including SAS signatures.
+	{
+		re:   regexp.MustCompile(`(?i)\b(password|secret|token|sig)=[^\s&"']+`),
+		repl: []byte(`${1}=` + redacted),
+	},
+}
+
+// Redact replaces the secrets in b, and returns the result and how many secrets were replaced.
+func Redact(b []byte) ([]byte, int) {
+	count := 0
+	for _, r := range redactions {
+		b = r.re.ReplaceAllFunc(b, func(match []byte) []byte {
+			count++
+			return r.re.Expand(nil, r.repl, match, r.re.FindSubmatchIndex(match))
+		})
+	}
+	return b, count
+}
@@ -0,0 +1,125 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package installer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cni/util"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+)
+
+const (
+	conflistName       = "azure"
+	conflistCNIVersion = "0.3.0"
+	ciliumName         = "cilium"
+	ciliumCNIVersion   = "0.3.1"
+	ciliumLogFile      = "/var/log/cilium-cni.log"
+	// node local dns, which pods reach through the host
+	nodeLocalDNS              = "169.254.20.10"
+	hnsTimeoutDurationSeconds = 120
+)
+
+// Scenarios are the conflists GenerateConflist can build.
+var Scenarios = []string{
+	c.ScenarioDefault,
+	c.ScenarioSwift,
+	c.ScenarioOverlay,
+	c.ScenarioMultitenancy,
+	c.ScenarioTransparentVlan,
+	c.ScenarioAzureIPAM,
+}
+
+// GenerateConfig selects the conflist to generate.
+type GenerateConfig struct {
+	Scenario                   string
+	OS                         string
+	CNSURL                     string
+	EnableExactMatchForPodName bool
+}
+
+type plugin map[string]interface{}
+
+// GenerateConflist builds the conflist of a scenario, as shipped in cni/ and azure-ipam/ for the OS.
+func GenerateConflist(conf GenerateConfig) (rawConflist, error) {
+	osType := strings.ToLower(conf.OS)
+	if osType != c.Linux && osType != c.Windows {
+		return rawConflist{}, fmt.Errorf("Invalid target OS supplied: %s", conf.OS)
+	}
+
+	if conf.Scenario == c.ScenarioAzureIPAM {
+		if osType != c.Linux {
+			return rawConflist{}, fmt.Errorf("%s is only supported on %s, chained behind %s", c.AzureIPAM, c.Linux, c.CiliumCNIBin)
+		}
+		return rawConflist{
+			Name:       ciliumName,
+			CniVersion: ciliumCNIVersion,
+			Plugins: []interface{}{plugin{
+				"type":     c.CiliumCNIBin,
+				"ipam":     plugin{"type": c.AzureIPAM},
+				"log-file": ciliumLogFile,
+			}},
+		}, nil
+	}
+
+	ipam := plugin{"type": c.AzureCNSIPAM}
+	vnet := plugin{"type": c.AzureCNIBin, "ipam": ipam}
+	multitenant := false
+	switch conf.Scenario {
+	case c.ScenarioDefault:
+		ipam["type"] = c.AzureVNETIPAM
+	case c.ScenarioSwift:
+		vnet["executionMode"] = string(util.V4Swift)
+	case c.ScenarioOverlay:
+		vnet["executionMode"] = string(util.V4Swift)
+		ipam["mode"] = string(util.V4Overlay)
+	case c.ScenarioMultitenancy:
+		multitenant = true
+		vnet["mode"] = c.Bridge
+	case c.ScenarioTransparentVlan:
+		if osType != c.Linux {
+			return rawConflist{}, fmt.Errorf("%s is only supported on %s", c.ScenarioTransparentVlan, c.Linux)
+		}
+		multitenant = true
+		vnet["mode"] = c.TransparentVlan
+	default:
+		return rawConflist{}, fmt.Errorf("Unknown scenario %q, please use one of %s and try again", conf.Scenario, strings.Join(Scenarios, ", "))
+	}
+
+	if multitenant {
+		vnet["bridge"] = c.Azure0
+		vnet["multiTenancy"] = true
+		vnet["enableSnatOnHost"] = true
+		vnet["enableExactMatchForPodName"] = conf.EnableExactMatchForPodName
+		if conf.CNSURL != "" {
+			vnet["cnsurl"] = conf.CNSURL
+		}
+	}
+
+	conflist := rawConflist{Name: conflistName, CniVersion: conflistCNIVersion}
+	if osType == c.Windows {
+		// windows has no transparent mode, and no portmap plugin, port mappings are set up by the CNI.
+		if _, ok := vnet["mode"]; !ok {
+			vnet["mode"] = c.Bridge
+			vnet["bridge"] = c.Azure0
+		}
+		vnet["capabilities"] = plugin{"portMappings": true, "dns": true}
+		vnet["windowsSettings"] = plugin{"hnsTimeoutDurationInSeconds": hnsTimeoutDurationSeconds}
+		conflist.Plugins = []interface{}{vnet}
+		return conflist, nil
+	}
+
+	if _, ok := vnet["mode"]; !ok {
+		vnet["mode"] = c.Transparent
+	}
+	if !multitenant {
+		vnet["ipsToRouteViaHost"] = []string{nodeLocalDNS}
+	}
+	conflist.Plugins = []interface{}{
+		vnet,
+		plugin{"type": c.PortmapBin, "capabilities": plugin{"portMappings": true}, "snat": true},
+	}
+	return conflist, nil
+}
@@ -0,0 +1,131 @@
+package installer
+
+import (
+	"encoding/json"
+	"testing"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateConflist(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     GenerateConfig
+		wantErr  bool
+		wantName string
+		// wantVNET are fields the azure-vnet or cilium plugin must have.
+		wantVNET map[string]interface{}
+		wantLen  int
+	}{
+		{
+			name:     "default linux",
+			conf:     GenerateConfig{Scenario: c.ScenarioDefault, OS: c.Linux},
+			wantName: conflistName,
+			wantVNET: map[string]interface{}{"mode": c.Transparent, "ipam": map[string]interface{}{"type": c.AzureVNETIPAM}},
+			wantLen:  2,
+		},
+		{
+			name:     "default windows",
+			conf:     GenerateConfig{Scenario: c.ScenarioDefault, OS: "Windows"},
+			wantName: conflistName,
+			wantVNET: map[string]interface{}{"mode": c.Bridge, "bridge": c.Azure0},
+			wantLen:  1,
+		},
+		{
+			name:     "swift",
+			conf:     GenerateConfig{Scenario: c.ScenarioSwift, OS: c.Linux},
+			wantName: conflistName,
+			wantVNET: map[string]interface{}{"executionMode": "v4swift", "ipam": map[string]interface{}{"type": c.AzureCNSIPAM}},
+			wantLen:  2,
+		},
+		{
+			name:     "overlay",
+			conf:     GenerateConfig{Scenario: c.ScenarioOverlay, OS: c.Linux},
+			wantName: conflistName,
+			wantVNET: map[string]interface{}{"ipam": map[string]interface{}{"type": c.AzureCNSIPAM, "mode": "v4overlay"}},
+			wantLen:  2,
+		},
+		{
+			name:     "multitenancy",
+			conf:     GenerateConfig{Scenario: c.ScenarioMultitenancy, OS: c.Linux, CNSURL: "http://10.0.0.1:10090"},
+			wantName: conflistName,
+			wantVNET: map[string]interface{}{"mode": c.Bridge, "multiTenancy": true, "cnsurl": "http://10.0.0.1:10090"},
+			wantLen:  2,
+		},
+		{
+			name:     "transparent vlan",
+			conf:     GenerateConfig{Scenario: c.ScenarioTransparentVlan, OS: c.Linux},
+			wantName: conflistName,
+			wantVNET: map[string]interface{}{"mode": c.TransparentVlan, "multiTenancy": true},
+			wantLen:  2,
+		},
+		{
+			name:    "transparent vlan on windows",
+			conf:    GenerateConfig{Scenario: c.ScenarioTransparentVlan, OS: c.Windows},
+			wantErr: true,
+		},
+		{
+			name:     "azure-ipam",
+			conf:     GenerateConfig{Scenario: c.ScenarioAzureIPAM, OS: c.Linux},
+			wantName: ciliumName,
+			wantVNET: map[string]interface{}{"type": c.CiliumCNIBin, "ipam": map[string]interface{}{"type": c.AzureIPAM}},
+			wantLen:  1,
+		},
+		{
+			name:    "azure-ipam on windows",
+			conf:    GenerateConfig{Scenario: c.ScenarioAzureIPAM, OS: c.Windows},
+			wantErr: true,
+		},
+		{
+			name:    "unknown scenario",
+			conf:    GenerateConfig{Scenario: "unknown", OS: c.Linux},
+			wantErr: true,
+		},
+		{
+			name:    "unknown os",
+			conf:    GenerateConfig{Scenario: c.ScenarioDefault, OS: "darwin"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conflist, err := GenerateConflist(tt.conf)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantName, conflist.Name)
+			require.Len(t, conflist.Plugins, tt.wantLen)
+
+			// compare as the conflist is written, so nested plugin maps compare as JSON objects.
+			b, err := json.Marshal(conflist.Plugins[0])
+			require.NoError(t, err)
+			var vnet map[string]interface{}
+			require.NoError(t, json.Unmarshal(b, &vnet))
+			for k, v := range tt.wantVNET {
+				require.Equal(t, v, vnet[k], k)
+			}
+		})
+	}
+}
+
+func TestGeneratedConflistsAreValid(t *testing.T) {
+	for _, osType := range []string{c.Linux, c.Windows} {
+		for _, scenario := range Scenarios {
+			conflist, err := GenerateConflist(GenerateConfig{Scenario: scenario, OS: osType})
+			if err != nil {
+				// not every scenario exists on every OS
+				continue
+			}
+			b, err := json.Marshal(conflist)
+			require.NoError(t, err)
+
+			problems, err := ValidateConflist(b, ValidateConfig{OS: osType})
+			require.NoError(t, err)
+			require.Empty(t, problems, "%s on %s", scenario, osType)
+		}
+	}
+}
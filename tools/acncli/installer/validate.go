@@ -0,0 +1,221 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package installer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ccn "github.com/Azure/azure-container-networking/cni"
+	"github.com/Azure/azure-container-networking/cni/util"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+)
+
+const cnsCheckTimeout = 5 * time.Second
+
+// Problem is an issue found in a conflist, with how to fix it.
+type Problem struct {
+	Plugin  string
+	Message string
+	Fix     string
+}
+
+func (p Problem) String() string {
+	s := p.Message
+	if p.Plugin != "" {
+		s = p.Plugin + ": " + s
+	}
+	if p.Fix != "" {
+		s += ", " + p.Fix
+	}
+	return s
+}
+
+// ValidateConfig selects the checks ValidateConflist runs.
+type ValidateConfig struct {
+	OS       string
+	CheckCNS bool
+}
+
+// ValidateConflist parses the conflist b the way the plugins do, and returns the problems which would
+// make them fail: unsupported mode, ipam and executionMode combinations, or an unreachable CNS.
+// It only returns an error if b isn't a conflist at all.
+func ValidateConflist(b []byte, conf ValidateConfig) ([]Problem, error) {
+	var conflist rawConflist
+	if err := json.Unmarshal(b, &conflist); err != nil {
+		return nil, fmt.Errorf("failed to parse conflist: %w", err)
+	}
+
+	var problems []Problem
+	if conflist.Name == "" {
+		problems = append(problems, Problem{Message: "name is not set", Fix: fmt.Sprintf("set \"name\" to %q", conflistName)})
+	}
+	if conflist.CniVersion == "" {
+		problems = append(problems, Problem{Message: "cniVersion is not set", Fix: fmt.Sprintf("set \"cniVersion\" to %q", conflistCNIVersion)})
+	}
+
+	found := false
+	cnsURL := ""
+	for i := range conflist.Plugins {
+		pluginBytes, err := json.Marshal(conflist.Plugins[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode plugin %d: %w", i, err)
+		}
+		var p rawPlugin
+		if err := json.Unmarshal(pluginBytes, &p); err != nil {
+			problems = append(problems, Problem{Message: fmt.Sprintf("plugin %d is not an object", i)})
+			continue
+		}
+		if p.Type != c.AzureCNIBin && p.Type != c.CiliumCNIBin {
+			continue
+		}
+		found = true
+
+		nwCfg, err := ccn.ParseNetworkConfig(pluginBytes)
+		if err != nil {
+			problems = append(problems, Problem{Plugin: p.Type, Message: fmt.Sprintf("failed to parse network config: %v", err)})
+			continue
+		}
+
+		var pluginProblems []Problem
+		if p.Type == c.AzureCNIBin {
+			pluginProblems = validateAzureVNET(nwCfg, strings.ToLower(conf.OS))
+		} else {
+			pluginProblems = validateCilium(nwCfg, strings.ToLower(conf.OS))
+		}
+		problems = append(problems, pluginProblems...)
+
+		if nwCfg.Ipam.Type == c.AzureCNSIPAM || nwCfg.Ipam.Type == c.AzureIPAM || nwCfg.MultiTenancy {
+			cnsURL = nwCfg.CNSUrl
+			if cnsURL == "" {
+				cnsURL = c.DefaultCNSUrl
+			}
+		}
+	}
+	if !found {
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("no %s or %s plugin found", c.AzureCNIBin, c.CiliumCNIBin),
+			Fix:     "add one to \"plugins\", or generate the conflist with 'acncli cni conflist generate'",
+		})
+	}
+
+	if conf.CheckCNS && cnsURL != "" {
+		if err := checkCNS(cnsURL); err != nil {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("CNS is not reachable at %s: %v", cnsURL, err),
+				Fix:     "start CNS on the node, or set \"cnsurl\" to the address it listens on",
+			})
+		}
+	}
+	return problems, nil
+}
+
+func validateAzureVNET(nwCfg *ccn.NetworkConfig, osType string) []Problem {
+	var problems []Problem
+	add := func(message, fix string) {
+		problems = append(problems, Problem{Plugin: c.AzureCNIBin, Message: message, Fix: fix})
+	}
+
+	// the modes the network manager of the OS can create a network in
+	modes := []string{c.Transparent, c.Bridge, c.Tunnel, c.TransparentVlan, c.IPVlan, c.IPVlanL3S}
+	if osType == c.Windows {
+		modes = []string{c.Bridge, c.Tunnel}
+	}
+	switch {
+	case nwCfg.Mode == "":
+		add("mode is not set", fmt.Sprintf("set \"mode\" to one of %s", strings.Join(modes, ", ")))
+	case !contains(modes, nwCfg.Mode):
+		add(fmt.Sprintf("mode %q is not supported on %s", nwCfg.Mode, osType), fmt.Sprintf("set \"mode\" to one of %s", strings.Join(modes, ", ")))
+	}
+
+	cns := nwCfg.Ipam.Type == c.AzureCNSIPAM
+	switch nwCfg.Ipam.Type {
+	case c.AzureCNSIPAM, c.AzureVNETIPAM:
+	case "":
+		add("ipam.type is not set", fmt.Sprintf("set \"ipam\": {\"type\": %q} or %q", c.AzureCNSIPAM, c.AzureVNETIPAM))
+	case c.AzureIPAM:
+		add(fmt.Sprintf("%s is not an ipam of %s", c.AzureIPAM, c.AzureCNIBin),
+			fmt.Sprintf("use %s for swift, or chain %s behind %s", c.AzureCNSIPAM, c.AzureIPAM, c.CiliumCNIBin))
+	default:
+		add(fmt.Sprintf("ipam.type %q is not supported", nwCfg.Ipam.Type), fmt.Sprintf("use %s or %s", c.AzureCNSIPAM, c.AzureVNETIPAM))
+	}
+
+	switch util.ExecutionMode(nwCfg.ExecutionMode) {
+	case "", util.Default:
+	case util.V4Swift:
+		if !cns {
+			add(fmt.Sprintf("executionMode %s allocates IPs from CNS", util.V4Swift), fmt.Sprintf("set \"ipam\": {\"type\": %q}", c.AzureCNSIPAM))
+		}
+		if nwCfg.MultiTenancy {
+			add(fmt.Sprintf("executionMode %s does not support multiTenancy", util.V4Swift), "remove \"executionMode\" or \"multiTenancy\"")
+		}
+	case util.Baremetal:
+		if osType != c.Windows {
+			add(fmt.Sprintf("executionMode %s is only supported on %s", util.Baremetal, c.Windows), "remove \"executionMode\"")
+		}
+	default:
+		add(fmt.Sprintf("executionMode %q is not supported", nwCfg.ExecutionMode),
+			fmt.Sprintf("set it to %s or %s, or remove it", util.V4Swift, util.Baremetal))
+	}
+
+	switch util.IpamMode(nwCfg.Ipam.Mode) {
+	case "":
+	case util.V4Overlay:
+		if !cns {
+			add(fmt.Sprintf("ipam.mode %s allocates IPs from CNS", util.V4Overlay), fmt.Sprintf("set \"ipam\": {\"type\": %q}", c.AzureCNSIPAM))
+		}
+		if util.ExecutionMode(nwCfg.ExecutionMode) != util.V4Swift {
+			add(fmt.Sprintf("ipam.mode %s needs executionMode %s", util.V4Overlay, util.V4Swift), fmt.Sprintf("set \"executionMode\" to %s", util.V4Swift))
+		}
+	default:
+		add(fmt.Sprintf("ipam.mode %q is not supported", nwCfg.Ipam.Mode), fmt.Sprintf("set it to %s, or remove it", util.V4Overlay))
+	}
+
+	if nwCfg.MultiTenancy && !cns {
+		add("multiTenancy gets the network containers of pods from CNS", fmt.Sprintf("set \"ipam\": {\"type\": %q}", c.AzureCNSIPAM))
+	}
+	if nwCfg.Mode == c.TransparentVlan && !nwCfg.MultiTenancy {
+		add(fmt.Sprintf("mode %s is only supported with multiTenancy", c.TransparentVlan), "set \"multiTenancy\" to true")
+	}
+	return problems
+}
+
+func validateCilium(nwCfg *ccn.NetworkConfig, osType string) []Problem {
+	if nwCfg.Ipam.Type != c.AzureIPAM {
+		// cilium has ipams of its own, only the chained azure-ipam is checked.
+		return nil
+	}
+	if osType == c.Windows {
+		return []Problem{{Plugin: c.CiliumCNIBin, Message: fmt.Sprintf("%s is not supported on %s", c.CiliumCNIBin, c.Windows)}}
+	}
+	return nil
+}
+
+// checkCNS checks an HTTP server answers at url. Any response will do, CNS answers unknown paths with 404.
+func checkCNS(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cnsCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
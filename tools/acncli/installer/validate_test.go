@@ -0,0 +1,158 @@
+package installer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/stretchr/testify/require"
+)
+
+// vnetConflist returns a conflist with an azure-vnet plugin configured by fields, a JSON object body.
+func vnetConflist(fields string) []byte {
+	return []byte(fmt.Sprintf(`{"name":"azure","cniVersion":"0.3.0","plugins":[{"type":"azure-vnet",%s}]}`, fields))
+}
+
+func TestValidateConflist(t *testing.T) {
+	tests := []struct {
+		name         string
+		conflist     []byte
+		os           string
+		wantErr      bool
+		wantProblems []string
+	}{
+		{
+			name:     "transparent",
+			conflist: vnetConflist(`"mode":"transparent","ipam":{"type":"azure-vnet-ipam"}`),
+			os:       c.Linux,
+		},
+		{
+			name:     "ipvlan",
+			conflist: vnetConflist(`"mode":"ipvlan","ipam":{"type":"azure-cns"},"executionMode":"v4swift"`),
+			os:       c.Linux,
+		},
+		{
+			name:     "ipvlan l3s",
+			conflist: vnetConflist(`"mode":"ipvlan-l3s","ipam":{"type":"azure-vnet-ipam"}`),
+			os:       c.Linux,
+		},
+		{
+			name:         "ipvlan on windows",
+			conflist:     vnetConflist(`"mode":"ipvlan","ipam":{"type":"azure-vnet-ipam"}`),
+			os:           c.Windows,
+			wantProblems: []string{`azure-vnet: mode "ipvlan" is not supported on windows, set "mode" to one of bridge, tunnel`},
+		},
+		{
+			name:         "transparent vlan on windows",
+			conflist:     vnetConflist(`"mode":"transparent-vlan","multiTenancy":true,"ipam":{"type":"azure-cns"}`),
+			os:           c.Windows,
+			wantProblems: []string{`azure-vnet: mode "transparent-vlan" is not supported on windows, set "mode" to one of bridge, tunnel`},
+		},
+		{
+			name:     "tunnel",
+			conflist: vnetConflist(`"mode":"tunnel","ipam":{"type":"azure-vnet-ipam"}`),
+			os:       c.Linux,
+		},
+		{
+			name:     "tunnel on windows",
+			conflist: vnetConflist(`"mode":"tunnel","ipam":{"type":"azure-vnet-ipam"}`),
+			os:       c.Windows,
+		},
+		{
+			name:     "bridge without bridge name",
+			conflist: vnetConflist(`"mode":"bridge","ipam":{"type":"azure-vnet-ipam"}`),
+			os:       c.Linux,
+		},
+		{
+			name:         "mode not set",
+			conflist:     vnetConflist(`"ipam":{"type":"azure-vnet-ipam"}`),
+			os:           c.Linux,
+			wantProblems: []string{`azure-vnet: mode is not set, set "mode" to one of transparent, bridge, tunnel, transparent-vlan, ipvlan, ipvlan-l3s`},
+		},
+		{
+			name:     "azure-ipam behind azure-vnet",
+			conflist: vnetConflist(`"mode":"transparent","ipam":{"type":"azure-ipam"}`),
+			os:       c.Linux,
+			wantProblems: []string{
+				"azure-vnet: azure-ipam is not an ipam of azure-vnet, use azure-cns for swift, or chain azure-ipam behind cilium-cni",
+			},
+		},
+		{
+			name:     "overlay without swift",
+			conflist: vnetConflist(`"mode":"transparent","ipam":{"type":"azure-cns","mode":"v4overlay"}`),
+			os:       c.Linux,
+			wantProblems: []string{
+				`azure-vnet: ipam.mode v4overlay needs executionMode v4swift, set "executionMode" to v4swift`,
+			},
+		},
+		{
+			name:     "transparent vlan without multitenancy",
+			conflist: vnetConflist(`"mode":"transparent-vlan","ipam":{"type":"azure-cns"}`),
+			os:       c.Linux,
+			wantProblems: []string{
+				`azure-vnet: mode transparent-vlan is only supported with multiTenancy, set "multiTenancy" to true`,
+			},
+		},
+		{
+			name:     "no name or version",
+			conflist: []byte(`{"plugins":[{"type":"azure-vnet","mode":"transparent","ipam":{"type":"azure-vnet-ipam"}}]}`),
+			os:       c.Linux,
+			wantProblems: []string{
+				`name is not set, set "name" to "azure"`,
+				`cniVersion is not set, set "cniVersion" to "0.3.0"`,
+			},
+		},
+		{
+			name:         "no azure plugin",
+			conflist:     []byte(`{"name":"azure","cniVersion":"0.3.0","plugins":[{"type":"portmap"}]}`),
+			os:           c.Linux,
+			wantProblems: []string{"no azure-vnet or cilium-cni plugin found, add one to \"plugins\", or generate the conflist with 'acncli cni conflist generate'"},
+		},
+		{
+			name:         "cilium on windows",
+			conflist:     []byte(`{"name":"cilium","cniVersion":"0.3.1","plugins":[{"type":"cilium-cni","ipam":{"type":"azure-ipam"}}]}`),
+			os:           c.Windows,
+			wantProblems: []string{"cilium-cni: cilium-cni is not supported on windows"},
+		},
+		{
+			name:     "not a conflist",
+			conflist: []byte(`[]`),
+			os:       c.Linux,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := ValidateConflist(tt.conflist, ValidateConfig{OS: tt.os})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			got := make([]string, 0, len(problems))
+			for _, p := range problems {
+				got = append(got, p.String())
+			}
+			require.ElementsMatch(t, tt.wantProblems, got)
+		})
+	}
+}
+
+func TestValidateConflistChecksCNS(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	cnsURL := srv.URL
+
+	conflist := vnetConflist(fmt.Sprintf(`"mode":"transparent","ipam":{"type":"azure-cns"},"cnsurl":%q`, cnsURL))
+	problems, err := ValidateConflist(conflist, ValidateConfig{OS: c.Linux, CheckCNS: true})
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	srv.Close()
+	problems, err = ValidateConflist(conflist, ValidateConfig{OS: c.Linux, CheckCNS: true})
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].Message, "CNS is not reachable at "+cnsURL)
+}
@@ -4,6 +4,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/Azure/azure-container-networking/tools/acncli/cmd"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -18,7 +20,10 @@ var (
 )
 
 func main() {
-	rootCmd.Execute()
+	// cobra prints the error, exit non-zero so scripts can tell the command failed.
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
 
 func init() {
@@ -0,0 +1,202 @@
+// Package state inspects and repairs the CNI and CNS state files of a node offline.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/network"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Mismatch kinds reported by CrossCheck.
+const (
+	// NotAssignedInCNS is an IP of a CNI endpoint which CNS doesn't have assigned, e.g. an endpoint left
+	// behind after CNS released its IP.
+	NotAssignedInCNS = "not assigned in CNS"
+	// NoEndpoint is an IP CNS has assigned to a pod with no CNI endpoint, e.g. leaked by a failed delete.
+	NoEndpoint = "no CNI endpoint"
+	// PodMismatch is an IP of a CNI endpoint which CNS has assigned to another pod.
+	PodMismatch = "assigned to another pod"
+)
+
+// Mismatch is a disagreement between the CNI state and the IPs CNS has assigned.
+type Mismatch struct {
+	IP         string
+	Kind       string
+	NetworkID  string
+	EndpointID string
+	// Pod is the pod of the endpoint, or of the CNS assignment when there is no endpoint.
+	Pod    string
+	CNSPod string
+}
+
+// CrossCheck compares the IPs of the endpoints in networks with the IPs CNS has assigned, and returns
+// the mismatches sorted by IP. Only the networks in cnsNetworks get their IPs from CNS, the others, and
+// the endpoints of network containers, are skipped. CNS assignments without an endpoint are only reported
+// if some network gets its IPs from CNS, else they belong to other plugins, like azure-ipam.
+func CrossCheck(networks []network.NetworkSummary, assigned []cns.IPConfigurationStatus, cnsNetworks map[string]bool) []Mismatch {
+	cnsPods := map[string]string{}
+	for i := range assigned {
+		pod := ""
+		if assigned[i].PodInfo != nil {
+			pod = assigned[i].PodInfo.Namespace() + "/" + assigned[i].PodInfo.Name()
+		}
+		cnsPods[assigned[i].IPAddress] = pod
+	}
+
+	var mismatches []Mismatch
+	seen := map[string]bool{}
+	for _, nw := range networks {
+		for _, ep := range nw.Endpoints {
+			pod := ep.PodNamespace + "/" + ep.PodName
+			for _, ipnet := range ep.IPAddresses {
+				ip := ipnet.IP.String()
+				seen[ip] = true
+				if !cnsNetworks[nw.ID] || ep.NetworkContainerID != "" {
+					continue
+				}
+				cnsPod, ok := cnsPods[ip]
+				switch {
+				case !ok:
+					mismatches = append(mismatches, Mismatch{IP: ip, Kind: NotAssignedInCNS, NetworkID: nw.ID, EndpointID: ep.ID, Pod: pod})
+				case ep.PodName != "" && cnsPod != "" && cnsPod != pod:
+					mismatches = append(mismatches, Mismatch{IP: ip, Kind: PodMismatch, NetworkID: nw.ID, EndpointID: ep.ID, Pod: pod, CNSPod: cnsPod})
+				}
+			}
+		}
+	}
+	for ip, pod := range cnsPods {
+		if !seen[ip] && len(cnsNetworks) > 0 {
+			mismatches = append(mismatches, Mismatch{IP: ip, Kind: NoEndpoint, CNSPod: pod})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].IP < mismatches[j].IP })
+	return mismatches
+}
+
+// OpenStore opens the JSON store at path with the lock the plugin uses for it, lockPath.
+func OpenStore(path, lockPath string) (store.KeyValueStore, error) {
+	lock, err := processlock.NewFileLock(lockPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create lock %s", lockPath)
+	}
+	st, err := store.NewJsonFileStore(path, lock)
+	return st, errors.Wrapf(err, "failed to open store %s", path)
+}
+
+// ReadCNSEndpoints reads the endpoint state CNS keeps when it manages the endpoints, by container ID.
+func ReadCNSEndpoints(st store.KeyValueStore) (map[string]*restserver.EndpointInfo, error) {
+	endpoints := map[string]*restserver.EndpointInfo{}
+	err := st.Read(restserver.EndpointStoreKey, &endpoints)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) && !errors.Is(err, store.ErrStoreEmpty) {
+		return nil, errors.Wrap(err, "failed to read CNS endpoint state")
+	}
+	return endpoints, nil
+}
+
+// Edit applies edit to the CNI state in path, and returns a unified diff of the change. The edit is
+// made on a copy, so the state is unchanged if it fails or dryRun is set. Otherwise, the state is
+// backed up and atomically replaced by the copy. The caller must hold the state lock.
+func Edit(path string, dryRun bool, edit func(*network.State) error) (diff, backup string, err error) {
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to read %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to stat %s", path)
+	}
+
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+base+".edit-*")
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to create the edited copy")
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+	if err := os.WriteFile(tmpPath, before, info.Mode().Perm()); err != nil {
+		return "", "", errors.Wrap(err, "failed to copy the state")
+	}
+
+	// the copy is only visible to this process, so it needs no lock.
+	st, err := store.NewJsonFileStore(tmpPath, processlock.NewMockFileLock(false))
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to open the edited copy")
+	}
+	state, err := network.LoadState(st)
+	if err != nil {
+		return "", "", err
+	}
+	if err := edit(state); err != nil {
+		return "", "", err
+	}
+	if err := state.Save(); err != nil {
+		return "", "", errors.Wrap(err, "failed to save the edited copy")
+	}
+	after, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to read the edited copy")
+	}
+
+	diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(indent(before)),
+		B:        difflib.SplitLines(indent(after)),
+		FromFile: path,
+		ToFile:   path + " (edited)",
+		Context:  3, //nolint:gomnd // lines of context, as diff -u
+	})
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to diff the state")
+	}
+	if dryRun {
+		return diff, "", nil
+	}
+
+	backup, err = Backup(path)
+	if err != nil {
+		return "", "", err
+	}
+	return diff, backup, errors.Wrapf(os.Rename(tmpPath, path), "failed to replace %s", path)
+}
+
+// indent formats the JSON in b the way the store writes it, so a state written by hand or by another
+// version diffs by its content rather than its layout.
+func indent(b []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "\t"); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// Backup copies the file at path next to it, suffixed with the time, and returns the copy's path.
+func Backup(path string) (string, error) {
+	backup := fmt.Sprintf("%s.bak-%s", path, time.Now().UTC().Format("20060102T150405Z"))
+	src, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(backup, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600) //nolint:gomnd // owner only
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create backup")
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", errors.Wrapf(err, "failed to write %s", backup)
+	}
+	return backup, errors.Wrapf(dst.Close(), "failed to write %s", backup)
+}
@@ -0,0 +1,152 @@
+package state
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cniState is a CNI state file with one network and two endpoints, trimmed down from one the plugin writes.
+const cniState = `{
+    "Network": {
+        "Version": "v1.4.39",
+        "TimeStamp": "2022-10-19T08:00:00Z",
+        "ExternalInterfaces": {
+            "eth0": {
+                "Name": "eth0",
+                "Networks": {
+                    "azure": {
+                        "Id": "azure",
+                        "Mode": "transparent",
+                        "Endpoints": {
+                            "a-eth0": {"Id": "a-eth0", "ContainerID": "a", "PODName": "pod-a", "PODNameSpace": "default",
+                                "IPAddresses": [{"IP": "10.240.0.4", "Mask": "//8AAA=="}]},
+                            "b-eth0": {"Id": "b-eth0", "ContainerID": "b", "PODName": "pod-b", "PODNameSpace": "default",
+                                "IPAddresses": [{"IP": "10.240.0.5", "Mask": "//8AAA=="}]}
+                        }
+                    }
+                }
+            }
+        }
+    }
+}`
+
+func writeState(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "azure-vnet.json")
+	require.NoError(t, os.WriteFile(path, []byte(cniState), 0o600))
+	return path
+}
+
+func assignedIP(ip, namespace, name string) cns.IPConfigurationStatus {
+	return cns.IPConfigurationStatus{IPAddress: ip, PodInfo: cns.NewPodInfo("", "", name, namespace)}
+}
+
+func TestCrossCheck(t *testing.T) {
+	networks := []network.NetworkSummary{{
+		ID: "azure",
+		Endpoints: []network.EndpointSummary{
+			{ID: "a-eth0", PodName: "pod-a", PodNamespace: "default", IPAddresses: []net.IPNet{{IP: net.ParseIP("10.240.0.4")}}},
+			{ID: "b-eth0", PodName: "pod-b", PodNamespace: "default", IPAddresses: []net.IPNet{{IP: net.ParseIP("10.240.0.5")}}},
+			{ID: "c-eth0", PodName: "pod-c", PodNamespace: "default", IPAddresses: []net.IPNet{{IP: net.ParseIP("10.240.0.6")}}},
+		},
+	}}
+	assigned := []cns.IPConfigurationStatus{
+		assignedIP("10.240.0.4", "default", "pod-a"),
+		assignedIP("10.240.0.5", "kube-system", "coredns"),
+		assignedIP("10.240.0.7", "default", "pod-d"),
+	}
+
+	assert.Equal(t, []Mismatch{
+		{IP: "10.240.0.5", Kind: PodMismatch, NetworkID: "azure", EndpointID: "b-eth0", Pod: "default/pod-b", CNSPod: "kube-system/coredns"},
+		{IP: "10.240.0.6", Kind: NotAssignedInCNS, NetworkID: "azure", EndpointID: "c-eth0", Pod: "default/pod-c"},
+		{IP: "10.240.0.7", Kind: NoEndpoint, CNSPod: "default/pod-d"},
+	}, CrossCheck(networks, assigned, map[string]bool{"azure": true}))
+	assert.Empty(t, CrossCheck(networks[:0], nil, nil))
+}
+
+func TestCrossCheckSkipsIPsNotFromCNS(t *testing.T) {
+	networks := []network.NetworkSummary{
+		{
+			ID: "azure",
+			Endpoints: []network.EndpointSummary{
+				{ID: "a-eth0", PodName: "pod-a", PodNamespace: "default", IPAddresses: []net.IPNet{{IP: net.ParseIP("10.240.0.4")}}},
+				// the IP of a multitenant pod belongs to its network container.
+				{
+					ID: "nc-eth0", PodName: "pod-nc", PodNamespace: "default", NetworkContainerID: "nc",
+					IPAddresses: []net.IPNet{{IP: net.ParseIP("192.168.0.4")}},
+				},
+			},
+		},
+		{
+			ID: "vnet-ipam",
+			Endpoints: []network.EndpointSummary{
+				{ID: "b-eth0", PodName: "pod-b", PodNamespace: "default", IPAddresses: []net.IPNet{{IP: net.ParseIP("10.241.0.4")}}},
+			},
+		},
+	}
+	assigned := []cns.IPConfigurationStatus{
+		assignedIP("10.240.0.4", "default", "pod-a"),
+		assignedIP("10.240.0.7", "default", "pod-d"),
+	}
+
+	assert.Equal(t, []Mismatch{
+		{IP: "10.240.0.7", Kind: NoEndpoint, CNSPod: "default/pod-d"},
+	}, CrossCheck(networks, assigned, map[string]bool{"azure": true}))
+
+	// without a network getting its IPs from CNS, the assigned IPs belong to other plugins.
+	assert.Empty(t, CrossCheck(networks, assigned, map[string]bool{}))
+}
+
+func TestEditDryRun(t *testing.T) {
+	path := writeState(t)
+
+	diff, backup, err := Edit(path, true, func(s *network.State) error {
+		return s.RemoveEndpoint("azure", "a-eth0")
+	})
+	require.NoError(t, err)
+	assert.Empty(t, backup)
+	assert.Contains(t, diff, "-\t\t\t\t\t\t\t\"a-eth0\": {\n")
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, cniState, string(b))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the edited copy is removed")
+}
+
+func TestEdit(t *testing.T) {
+	path := writeState(t)
+
+	_, _, err := Edit(path, false, func(s *network.State) error {
+		return s.RemoveEndpoint("azure", "missing-eth0")
+	})
+	require.Error(t, err)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, cniState, string(b), "a failed edit leaves the state unchanged")
+
+	diff, backup, err := Edit(path, false, func(s *network.State) error {
+		return s.RemoveEndpoint("azure", "a-eth0")
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, diff)
+
+	b, err = os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, cniState, string(b))
+
+	st, err := OpenStore(path, path+".lock")
+	require.NoError(t, err)
+	s, err := network.LoadState(st)
+	require.NoError(t, err)
+	endpoints := s.Networks()[0].Endpoints
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "b-eth0", endpoints[0].ID)
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+var _ Exporter = (*LogExporter)(nil)
+
+// LogExporter writes finished spans to a zap logger, one entry per span. It is useful where no collector
+// is available, since the spans of an invocation can still be correlated by trace id in the logs.
+type LogExporter struct {
+	logger *zap.Logger
+}
+
+// NewLogExporter creates an exporter writing spans to logger.
+func NewLogExporter(logger *zap.Logger) *LogExporter {
+	return &LogExporter{logger: logger}
+}
+
+func (e *LogExporter) ExportSpans(_ context.Context, spans []SpanData) error {
+	for i := range spans {
+		s := &spans[i]
+		fields := []zap.Field{
+			zap.String("traceID", s.SpanContext.TraceID.String()),
+			zap.String("spanID", s.SpanContext.SpanID.String()),
+			zap.Duration("duration", s.End.Sub(s.Start)),
+			zap.Any("attributes", s.Attributes),
+		}
+		if s.ParentSpanID.IsValid() {
+			fields = append(fields, zap.String("parentSpanID", s.ParentSpanID.String()))
+		}
+		if s.Err != "" {
+			fields = append(fields, zap.String("error", s.Err))
+		}
+		e.logger.Info("span "+s.Name, fields...)
+	}
+	return nil
+}
+
+func (e *LogExporter) Shutdown(context.Context) error {
+	_ = e.logger.Sync()
+	return nil
+}
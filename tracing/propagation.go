@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the span context between processes.
+const TraceparentHeader = "traceparent"
+
+const (
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// Inject sets the traceparent header from the span context in ctx. It does nothing if ctx carries no
+// span context.
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, FormatTraceparent(sc))
+}
+
+// Extract returns a copy of ctx carrying the remote span context from the traceparent header, if it
+// holds a valid one, so that spans started from it join the caller's trace.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// FormatTraceparent formats sc as a version 00 traceparent value.
+func FormatTraceparent(sc SpanContext) string {
+	var flags byte
+	if sc.Sampled {
+		flags |= flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a traceparent value into a remote span context. Values from future versions
+// are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", s)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || (version == traceparentVersion && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("unsupported traceparent version in %q", s)
+	}
+
+	var sc SpanContext
+	if err := decodeHex(sc.TraceID[:], traceID); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace id in traceparent %q: %w", s, err)
+	}
+	if err := decodeHex(sc.SpanID[:], spanID); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid span id in traceparent %q: %w", s, err)
+	}
+	var f [1]byte
+	if err := decodeHex(f[:], flags); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid flags in traceparent %q: %w", s, err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("traceparent %q has an all-zero id", s)
+	}
+
+	sc.Sampled = f[0]&flagSampled != 0
+	sc.Remote = true
+	return sc, nil
+}
+
+// decodeHex decodes s into dst, which it must fill exactly. Only lowercase hex is valid in a traceparent.
+func decodeHex(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return fmt.Errorf("expected %d lowercase hex characters, got %q", hex.EncodedLen(len(dst)), s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err //nolint:wrapcheck // wrapped by the caller
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware wraps handler in a server span named name, continuing the trace of the caller if the request
+// carries a traceparent header. The handler can start child spans from the request context. When tracing
+// is disabled handler is called directly.
+func Middleware(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled() {
+			handler(w, r)
+			return
+		}
+
+		ctx, span := StartSpan(Extract(r.Context(), r.Header), name, WithKind(SpanKindServer))
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(rec, r.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", fmt.Sprint(rec.status))
+		var err error
+		if rec.status >= http.StatusInternalServerError {
+			err = fmt.Errorf("http status %d", rec.status)
+		}
+		span.End(err)
+	}
+}
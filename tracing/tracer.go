@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultExportInterval = 5 * time.Second
+	defaultExportTimeout  = 10 * time.Second
+	// maxQueueSizeMultiplier bounds the queue to a few batches, so a slow exporter drops spans
+	// instead of growing memory without bound.
+	maxQueueSizeMultiplier = 4
+)
+
+// tracer queues finished spans and exports them in batches.
+type tracer struct {
+	exporter Exporter
+
+	mu      sync.Mutex
+	queue   []SpanData
+	dropped uint64
+
+	batchFull chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+var (
+	globalMu sync.RWMutex
+	// the disabled tracer, which has no exporter and never records a span.
+	globalTracer = &tracer{}
+)
+
+func global() *tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+
+	return globalTracer
+}
+
+// Init enables tracing, exporting finished spans to exp in the background. Any previously set exporter
+// is shut down first.
+func Init(exp Exporter) {
+	t := &tracer{
+		exporter:  exp,
+		batchFull: make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go t.run()
+
+	globalMu.Lock()
+	prev := globalTracer
+	globalTracer = t
+	globalMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultExportTimeout)
+	defer cancel()
+	_ = prev.shutdown(ctx)
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	return global().exporter != nil
+}
+
+// Flush exports the spans ended so far.
+func Flush(ctx context.Context) error {
+	return global().export(ctx)
+}
+
+// Shutdown exports the queued spans, shuts the exporter down and disables tracing.
+func Shutdown(ctx context.Context) error {
+	globalMu.Lock()
+	t := globalTracer
+	globalTracer = &tracer{}
+	globalMu.Unlock()
+
+	return t.shutdown(ctx)
+}
+
+// Dropped returns the number of spans dropped because the export queue was full.
+func Dropped() uint64 {
+	t := global()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.dropped
+}
+
+func (t *tracer) enqueue(data SpanData) {
+	if t.exporter == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if len(t.queue) >= defaultBatchSize*maxQueueSizeMultiplier {
+		t.dropped++
+		t.mu.Unlock()
+		return
+	}
+	t.queue = append(t.queue, data)
+	full := len(t.queue) >= defaultBatchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.batchFull <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(defaultExportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		case <-t.batchFull:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultExportTimeout)
+		_ = t.export(ctx)
+		cancel()
+	}
+}
+
+// export sends the queued spans to the exporter. Spans which fail to export are dropped rather than requeued.
+func (t *tracer) export(ctx context.Context) error {
+	if t.exporter == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	spans := t.queue
+	t.queue = nil
+	t.mu.Unlock()
+
+	var lastErr error
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > defaultBatchSize {
+			n = defaultBatchSize
+		}
+		if err := t.exporter.ExportSpans(ctx, spans[:n]); err != nil {
+			lastErr = errors.Wrap(err, "failed to export spans")
+		}
+		spans = spans[n:]
+	}
+	return lastErr
+}
+
+func (t *tracer) shutdown(ctx context.Context) error {
+	if t.exporter == nil {
+		return nil
+	}
+
+	close(t.stop)
+	<-t.done
+
+	exportErr := t.export(ctx)
+	if err := t.exporter.Shutdown(ctx); err != nil {
+		return errors.Wrap(err, "failed to shut down span exporter")
+	}
+	return exportErr
+}
@@ -0,0 +1,208 @@
+// Package tracing provides lightweight distributed tracing for the CNI plugin and CNS. Trace context is
+// propagated between processes with W3C traceparent headers and finished spans are handed to a pluggable
+// Exporter. Until an exporter is set with Init, spans are not recorded and cost almost nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const defaultBatchSize = 256
+
+// TraceID identifies a trace across processes.
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the ID is non-zero.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether the ID is non-zero.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext is the part of a span propagated to children and to other processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind is the role of a span in a request.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+// SpanData is a finished span as handed to the Exporter.
+type SpanData struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Kind         SpanKind
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+	// Err is the error the span ended with, empty if it succeeded.
+	Err string
+}
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Option configures a span at start.
+type Option func(*SpanData)
+
+// WithKind sets the kind of the span.
+func WithKind(kind SpanKind) Option {
+	return func(d *SpanData) {
+		d.Kind = kind
+	}
+}
+
+// WithAttributes adds attributes to the span.
+func WithAttributes(attrs map[string]string) Option {
+	return func(d *SpanData) {
+		for k, v := range attrs {
+			d.Attributes[k] = v
+		}
+	}
+}
+
+// Span is an operation being traced. A nil *Span is valid and records nothing, so callers don't need
+// to check whether tracing is enabled.
+type Span struct {
+	tracer *tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the span context of s.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttribute sets an attribute on the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Attributes[key] = value
+}
+
+// End finishes the span, recording err if it is not nil. Only the first call has an effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if err != nil {
+		s.data.Err = err.Error()
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.enqueue(data)
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc as the parent of spans started from it.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context in ctx, either of the current span or of a remote parent.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// StartSpan starts a span as a child of the span context in ctx, or as the root of a new trace, and
+// returns a context carrying it. It returns a nil span when tracing is disabled or the parent was not sampled.
+func StartSpan(ctx context.Context, name string, opts ...Option) (context.Context, *Span) {
+	t := global()
+	if t.exporter == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() && !parent.Sampled {
+		return ctx, nil
+	}
+
+	data := SpanData{
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: map[string]string{},
+		SpanContext: SpanContext{
+			TraceID: parent.TraceID,
+			SpanID:  newSpanID(),
+			Sampled: true,
+		},
+	}
+	if parent.IsValid() {
+		data.ParentSpanID = parent.SpanID
+	} else {
+		data.SpanContext.TraceID = newTraceID()
+	}
+	for _, opt := range opts {
+		opt(&data)
+	}
+
+	span := &Span{tracer: t, data: data}
+	return ContextWithSpanContext(ctx, data.SpanContext), span
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error { return nil }
+
+func (e *recordingExporter) byName() map[string]SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := map[string]SpanData{}
+	for _, s := range e.spans {
+		m[s.Name] = s
+	}
+	return m
+}
+
+func enable(t *testing.T) *recordingExporter {
+	exp := &recordingExporter{}
+	Init(exp)
+	t.Cleanup(func() { _ = Shutdown(context.Background()) })
+	return exp
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		sampled bool
+		wantErr bool
+	}{
+		{name: "sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sampled: true},
+		{name: "not sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{name: "future version", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", sampled: true},
+		{name: "version ff", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "extra fields in version 00", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantErr: true},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{name: "uppercase", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "short span id", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+			assert.Equal(t, tt.sampled, sc.Sampled)
+			assert.True(t, sc.Remote)
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	enable(t)
+	ctx, span := StartSpan(context.Background(), "client")
+	require.NotNil(t, span)
+
+	h := http.Header{}
+	Inject(ctx, h)
+	assert.Equal(t, FormatTraceparent(span.SpanContext()), h.Get(TraceparentHeader))
+
+	remote := SpanContextFromContext(Extract(context.Background(), h))
+	assert.Equal(t, span.SpanContext().TraceID, remote.TraceID)
+	assert.Equal(t, span.SpanContext().SpanID, remote.SpanID)
+	assert.True(t, remote.Remote)
+}
+
+func TestStartSpanDisabled(t *testing.T) {
+	ctx, span := StartSpan(context.Background(), "noop")
+	assert.Nil(t, span)
+	assert.False(t, SpanContextFromContext(ctx).IsValid())
+
+	// a nil span is safe to use.
+	span.SetAttribute("k", "v")
+	span.End(errors.New("ignored"))
+}
+
+func TestSpanHierarchy(t *testing.T) {
+	exp := enable(t)
+
+	ctx, root := StartSpan(context.Background(), "root")
+	_, child := StartSpan(ctx, "child", WithAttributes(map[string]string{"k": "v"}))
+	child.End(errors.New("failed"))
+	root.End(nil)
+	root.End(errors.New("ignored"))
+	require.NoError(t, Flush(context.Background()))
+
+	spans := exp.byName()
+	require.Len(t, spans, 2)
+	assert.Equal(t, spans["root"].SpanContext.TraceID, spans["child"].SpanContext.TraceID)
+	assert.Equal(t, spans["root"].SpanContext.SpanID, spans["child"].ParentSpanID)
+	assert.False(t, spans["root"].ParentSpanID.IsValid())
+	assert.Equal(t, "v", spans["child"].Attributes["k"])
+	assert.Equal(t, "failed", spans["child"].Err)
+	assert.Empty(t, spans["root"].Err)
+}
+
+func TestStartSpanRespectsUnsampledParent(t *testing.T) {
+	enable(t)
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+
+	_, span := StartSpan(ContextWithSpanContext(context.Background(), sc), "child")
+	assert.Nil(t, span)
+}
+
+func TestMiddleware(t *testing.T) {
+	exp := enable(t)
+
+	handler := Middleware("server", func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartSpan(r.Context(), "work")
+		span.End(nil)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, client := StartSpan(context.Background(), "client", WithKind(SpanKindClient))
+	req := httptest.NewRequest(http.MethodPost, "/network/requestipconfigs", http.NoBody)
+	Inject(ctx, req.Header)
+	handler(httptest.NewRecorder(), req)
+	client.End(nil)
+	require.NoError(t, Flush(context.Background()))
+
+	spans := exp.byName()
+	require.Len(t, spans, 3)
+	server := spans["server"]
+	assert.Equal(t, SpanKindServer, server.Kind)
+	assert.Equal(t, client.SpanContext().TraceID, server.SpanContext.TraceID)
+	assert.Equal(t, client.SpanContext().SpanID, server.ParentSpanID)
+	assert.Equal(t, server.SpanContext.SpanID, spans["work"].ParentSpanID)
+	assert.Equal(t, "/network/requestipconfigs", server.Attributes["http.target"])
+	assert.Equal(t, "503", server.Attributes["http.status_code"])
+	assert.NotEmpty(t, server.Err)
+}